@@ -13,12 +13,44 @@ import (
 type API struct {
 	settingEngine *SettingEngine
 	mediaEngine   *MediaEngine
+	interceptors  InterceptorRegistry
+
+	rtpKeepAlive    *rtpKeepAliveScheduler
+	peerConnections *peerConnectionRegistry
 }
 
 // NewAPI Creates a new API object for keeping semi-global settings to WebRTC objects
 func NewAPI(options ...func(*API)) *API {
-	a := &API{}
+	return newAPI(&API{}, options...)
+}
+
+// Derive creates a new API that inherits the SettingEngine, MediaEngine and
+// interceptors of api, which the options can then override, e.g. a server
+// configures its defaults once and derives the API of a single
+// PeerConnection with different settings. The inherited engines are copies,
+// the options don't change api. The derived API sends the RTP keep-alives of
+// its senders from the goroutine of api.
+func (api *API) Derive(options ...func(*API)) *API {
+	settingEngine := *api.settingEngine
+	return newAPI(&API{
+		settingEngine: &settingEngine,
+		mediaEngine:   api.mediaEngine.clone(),
+		interceptors:  InterceptorRegistry{interceptors: append([]Interceptor{}, api.interceptors.interceptors...)},
+		rtpKeepAlive:  api.rtpKeepAlive,
+	}, options...)
+}
+
+// withLoggerFactory returns a copy of api whose objects log with f. Unlike
+// Derive the engines and the keepalive scheduler stay shared with api.
+func (api *API) withLoggerFactory(f logging.LoggerFactory) *API {
+	settingEngine := *api.settingEngine
+	settingEngine.LoggerFactory = f
+	a := *api
+	a.settingEngine = &settingEngine
+	return &a
+}
 
+func newAPI(a *API, options ...func(*API)) *API {
 	for _, o := range options {
 		o(a)
 	}
@@ -35,14 +67,21 @@ func NewAPI(options ...func(*API)) *API {
 		a.mediaEngine = &MediaEngine{}
 	}
 
+	// Derived APIs share the keep-alive goroutine of their parent
+	if a.rtpKeepAlive == nil {
+		a.rtpKeepAlive = newRTPKeepAliveScheduler(a.settingEngine.LoggerFactory.NewLogger("RTPSender"))
+	}
+	a.peerConnections = newPeerConnectionRegistry()
+
 	return a
 }
 
-// WithMediaEngine allows providing a MediaEngine to the API.
-// Settings should not be changed after passing the engine to an API.
+// WithMediaEngine allows providing a MediaEngine to the API. The API keeps
+// a snapshot of the codecs, registering codecs on m afterwards doesn't
+// change the API.
 func WithMediaEngine(m MediaEngine) func(a *API) {
 	return func(a *API) {
-		a.mediaEngine = &m
+		a.mediaEngine = m.clone()
 	}
 }
 
@@ -53,3 +92,31 @@ func WithSettingEngine(s SettingEngine) func(a *API) {
 		a.settingEngine = &s
 	}
 }
+
+// WithSettings allows changing the SettingEngine of the API in place, which
+// is useful with Derive to override some of the inherited settings.
+func WithSettings(f func(s *SettingEngine)) func(a *API) {
+	return func(a *API) {
+		if a.settingEngine == nil {
+			a.settingEngine = &SettingEngine{}
+		}
+		f(a.settingEngine)
+	}
+}
+
+// WithInterceptorRegistry allows providing the interceptors every
+// PeerConnection of the API uses. It replaces inherited interceptors.
+func WithInterceptorRegistry(r InterceptorRegistry) func(a *API) {
+	return func(a *API) {
+		a.interceptors = r
+	}
+}
+
+// WithInterceptors appends interceptors to the ones of the API
+func WithInterceptors(interceptors ...Interceptor) func(a *API) {
+	return func(a *API) {
+		for _, i := range interceptors {
+			a.interceptors.Add(i)
+		}
+	}
+}
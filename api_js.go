@@ -5,6 +5,7 @@ package webrtc
 // API bundles the global funcions of the WebRTC and ORTC API.
 type API struct {
 	settingEngine *SettingEngine
+	mediaEngine   *MediaEngine
 }
 
 // NewAPI Creates a new API object for keeping semi-global settings to WebRTC objects
@@ -19,9 +20,22 @@ func NewAPI(options ...func(*API)) *API {
 		a.settingEngine = &SettingEngine{}
 	}
 
+	if a.mediaEngine == nil {
+		a.mediaEngine = &MediaEngine{}
+	}
+
 	return a
 }
 
+// WithMediaEngine allows providing a MediaEngine to the API. Codecs are
+// negotiated by the browser, the MediaEngine is accepted so the same code
+// compiles for native and WASM builds.
+func WithMediaEngine(m MediaEngine) func(a *API) {
+	return func(a *API) {
+		a.mediaEngine = m.clone()
+	}
+}
+
 // WithSettingEngine allows providing a SettingEngine to the API.
 // Settings should not be changed after passing the engine to an API.
 func WithSettingEngine(s SettingEngine) func(a *API) {
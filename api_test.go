@@ -37,3 +37,38 @@ func TestNewAPI_Options(t *testing.T) {
 		t.Error("Failed to set media engine")
 	}
 }
+
+func TestAPI_Derive(t *testing.T) {
+	s := SettingEngine{}
+	s.DetachDataChannels()
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	parent := NewAPI(WithSettingEngine(s), WithMediaEngine(m), WithInterceptors(NoOpInterceptor{}))
+	child := parent.Derive(
+		WithSettings(func(s *SettingEngine) {
+			s.SetRTCPMuxOnly(true)
+		}),
+		WithInterceptors(NoOpInterceptor{}),
+	)
+
+	if !child.settingEngine.detach.DataChannels || !child.settingEngine.rtcpMux.Only {
+		t.Error("Failed to inherit and override settings engine")
+	}
+	if parent.settingEngine.rtcpMux.Only {
+		t.Error("Override changed the parent settings engine")
+	}
+
+	child.mediaEngine.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if len(child.mediaEngine.codecs) != len(parent.mediaEngine.codecs)+1 {
+		t.Error("Failed to copy media engine")
+	}
+
+	if len(parent.interceptors.interceptors) != 1 || len(child.interceptors.interceptors) != 2 {
+		t.Error("Failed to inherit interceptors")
+	}
+
+	if child.rtpKeepAlive != parent.rtpKeepAlive {
+		t.Error("Failed to share the RTP keep-alive scheduler")
+	}
+}
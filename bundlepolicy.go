@@ -4,6 +4,14 @@ package webrtc
 // endpoint is not bundle-aware, and what ICE candidates are gathered. If the
 // remote endpoint is bundle-aware, all media tracks and data channels are
 // bundled onto the same transport.
+//
+// A PeerConnection currently uses a single ICE transport for all media.
+// BundlePolicyMaxBundle marks all but the first media section of an offer as
+// bundle-only and answers a remote that isn't bundle-aware with a single media
+// section. BundlePolicyBalanced and BundlePolicyMaxCompat don't create
+// separate transports yet, they offer and answer like a PeerConnection without
+// a BundlePolicy. A remote that doesn't bundle can then only use the first
+// media section, the ICE credentials and candidates of the others are the same.
 type BundlePolicy int
 
 const (
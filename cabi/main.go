@@ -0,0 +1,425 @@
+// +build !js
+
+// Package main exports a C ABI for embedding pion/webrtc in other runtimes.
+// Build it as a shared library and use the generated header:
+//
+//	go build -buildmode=c-shared -o libpionwebrtc.so ./cabi
+//
+// Objects are referenced by int64 handles. Functions that can fail return an
+// error message that has to be released with PionFree, or NULL on success.
+// Session descriptions and ICE candidates are the JSON used by the browser API.
+// Callbacks are invoked from Go goroutines and receive the user_data pointer
+// that was passed when registering them. Strings passed to callbacks are only
+// valid for the duration of the call.
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef void (*pion_string_callback)(void *user_data, const char *value);
+typedef void (*pion_handle_callback)(void *user_data, int64_t handle);
+typedef void (*pion_message_callback)(void *user_data, const void *data, int len, int is_string);
+
+static inline void pion_call_string(pion_string_callback cb, void *user_data, const char *value) {
+	cb(user_data, value);
+}
+
+static inline void pion_call_handle(pion_handle_callback cb, void *user_data, int64_t handle) {
+	cb(user_data, handle);
+}
+
+static inline void pion_call_message(pion_message_callback cb, void *user_data, const void *data, int len, int is_string) {
+	cb(user_data, data, len, is_string);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"sync"
+	"unsafe"
+
+	"github.com/pion/webrtc/v2/pkg/mobile"
+)
+
+var errInvalidHandle = errors.New("invalid handle")
+
+var (
+	handlesMu  sync.Mutex
+	handles    = map[int64]interface{}{}
+	nextHandle int64
+)
+
+func newHandle(v interface{}) int64 {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+
+	nextHandle++
+	handles[nextHandle] = v
+	return nextHandle
+}
+
+func lookupHandle(h int64) interface{} {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+	return handles[h]
+}
+
+func deleteHandle(h int64) {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+	delete(handles, h)
+}
+
+func cError(err error) *C.char {
+	if err == nil {
+		return nil
+	}
+	return C.CString(err.Error())
+}
+
+// callback is a C function pointer with its user data
+type callback struct {
+	fn       unsafe.Pointer
+	userData unsafe.Pointer
+}
+
+// peerConnection is the object behind a PeerConnection handle, it forwards
+// the events of the mobile.PeerConnectionObserver to the registered callbacks
+type peerConnection struct {
+	pc *mobile.PeerConnection
+
+	mu                         sync.Mutex
+	onICECandidate             callback
+	onICEConnectionStateChange callback
+	onDataChannel              callback
+	onTrack                    callback
+}
+
+func (p *peerConnection) get(c *callback) callback {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return *c
+}
+
+func (p *peerConnection) set(c *callback, fn, userData unsafe.Pointer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*c = callback{fn: fn, userData: userData}
+}
+
+func callString(c callback, value string) {
+	if c.fn == nil {
+		return
+	}
+	cValue := C.CString(value)
+	defer C.free(unsafe.Pointer(cValue))
+	C.pion_call_string(C.pion_string_callback(c.fn), c.userData, cValue)
+}
+
+func callHandle(c callback, h int64) {
+	if c.fn == nil {
+		return
+	}
+	C.pion_call_handle(C.pion_handle_callback(c.fn), c.userData, C.int64_t(h))
+}
+
+func (p *peerConnection) OnICECandidate(candidate string) {
+	callString(p.get(&p.onICECandidate), candidate)
+}
+
+func (p *peerConnection) OnICEConnectionStateChange(state string) {
+	callString(p.get(&p.onICEConnectionStateChange), state)
+}
+
+func (p *peerConnection) OnDataChannel(dc *mobile.DataChannel) {
+	callHandle(p.get(&p.onDataChannel), newHandle(&dataChannel{dc: dc}))
+}
+
+func (p *peerConnection) OnTrack(track *mobile.RemoteTrack) {
+	callHandle(p.get(&p.onTrack), newHandle(track))
+}
+
+// dataChannel is the object behind a DataChannel handle
+type dataChannel struct {
+	dc *mobile.DataChannel
+
+	mu        sync.Mutex
+	onOpen    callback
+	onMessage callback
+}
+
+func (d *dataChannel) OnOpen() {
+	d.mu.Lock()
+	c := d.onOpen
+	d.mu.Unlock()
+	callString(c, d.dc.Label())
+}
+
+func (d *dataChannel) OnClose() {}
+
+func (d *dataChannel) OnMessage(data []byte, isString bool) {
+	d.mu.Lock()
+	c := d.onMessage
+	d.mu.Unlock()
+	if c.fn == nil {
+		return
+	}
+
+	cData := C.CBytes(data)
+	defer C.free(cData)
+	var cIsString C.int
+	if isString {
+		cIsString = 1
+	}
+	C.pion_call_message(C.pion_message_callback(c.fn), c.userData, cData, C.int(len(data)), cIsString)
+}
+
+func getPeerConnection(h int64) (*peerConnection, error) {
+	p, ok := lookupHandle(int64(h)).(*peerConnection)
+	if !ok {
+		return nil, errInvalidHandle
+	}
+	return p, nil
+}
+
+func getDataChannel(h int64) (*dataChannel, error) {
+	d, ok := lookupHandle(int64(h)).(*dataChannel)
+	if !ok {
+		return nil, errInvalidHandle
+	}
+	return d, nil
+}
+
+//export PionFree
+func PionFree(p unsafe.Pointer) {
+	C.free(p)
+}
+
+//export PionPeerConnectionNew
+func PionPeerConnectionNew(configuration *C.char, out *C.int64_t) *C.char {
+	p := &peerConnection{}
+	pc, err := mobile.NewPeerConnection(C.GoString(configuration), p)
+	if err != nil {
+		return cError(err)
+	}
+	p.pc = pc
+
+	*out = C.int64_t(newHandle(p))
+	return nil
+}
+
+//export PionPeerConnectionClose
+func PionPeerConnectionClose(h C.int64_t) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	deleteHandle(int64(h))
+	return cError(p.pc.Close())
+}
+
+//export PionPeerConnectionOnICECandidate
+func PionPeerConnectionOnICECandidate(h C.int64_t, cb C.pion_string_callback, userData unsafe.Pointer) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	p.set(&p.onICECandidate, unsafe.Pointer(cb), userData)
+	return nil
+}
+
+//export PionPeerConnectionOnICEConnectionStateChange
+func PionPeerConnectionOnICEConnectionStateChange(h C.int64_t, cb C.pion_string_callback, userData unsafe.Pointer) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	p.set(&p.onICEConnectionStateChange, unsafe.Pointer(cb), userData)
+	return nil
+}
+
+//export PionPeerConnectionOnDataChannel
+func PionPeerConnectionOnDataChannel(h C.int64_t, cb C.pion_handle_callback, userData unsafe.Pointer) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	p.set(&p.onDataChannel, unsafe.Pointer(cb), userData)
+	return nil
+}
+
+//export PionPeerConnectionOnTrack
+func PionPeerConnectionOnTrack(h C.int64_t, cb C.pion_handle_callback, userData unsafe.Pointer) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	p.set(&p.onTrack, unsafe.Pointer(cb), userData)
+	return nil
+}
+
+//export PionPeerConnectionCreateOffer
+func PionPeerConnectionCreateOffer(h C.int64_t, out **C.char) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	offer, err := p.pc.CreateOffer()
+	if err != nil {
+		return cError(err)
+	}
+	*out = C.CString(offer)
+	return nil
+}
+
+//export PionPeerConnectionCreateAnswer
+func PionPeerConnectionCreateAnswer(h C.int64_t, out **C.char) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	answer, err := p.pc.CreateAnswer()
+	if err != nil {
+		return cError(err)
+	}
+	*out = C.CString(answer)
+	return nil
+}
+
+//export PionPeerConnectionSetLocalDescription
+func PionPeerConnectionSetLocalDescription(h C.int64_t, description *C.char) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	return cError(p.pc.SetLocalDescription(C.GoString(description)))
+}
+
+//export PionPeerConnectionSetRemoteDescription
+func PionPeerConnectionSetRemoteDescription(h C.int64_t, description *C.char) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	return cError(p.pc.SetRemoteDescription(C.GoString(description)))
+}
+
+//export PionPeerConnectionAddICECandidate
+func PionPeerConnectionAddICECandidate(h C.int64_t, candidate *C.char) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	return cError(p.pc.AddICECandidate(C.GoString(candidate)))
+}
+
+//export PionPeerConnectionCreateDataChannel
+func PionPeerConnectionCreateDataChannel(h C.int64_t, label *C.char, out *C.int64_t) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	dc, err := p.pc.CreateDataChannel(C.GoString(label))
+	if err != nil {
+		return cError(err)
+	}
+	*out = C.int64_t(newHandle(&dataChannel{dc: dc}))
+	return nil
+}
+
+//export PionPeerConnectionAddTrack
+func PionPeerConnectionAddTrack(h C.int64_t, codecName, id, label *C.char, out *C.int64_t) *C.char {
+	p, err := getPeerConnection(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	track, err := p.pc.AddTrack(C.GoString(codecName), C.GoString(id), C.GoString(label))
+	if err != nil {
+		return cError(err)
+	}
+	*out = C.int64_t(newHandle(track))
+	return nil
+}
+
+// PionDataChannelSetCallbacks registers the callbacks of a DataChannel, on_open
+// receives the label of the DataChannel. Either callback may be NULL.
+//export PionDataChannelSetCallbacks
+func PionDataChannelSetCallbacks(h C.int64_t, onOpen C.pion_string_callback, onMessage C.pion_message_callback, userData unsafe.Pointer) *C.char {
+	d, err := getDataChannel(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+
+	d.mu.Lock()
+	d.onOpen = callback{fn: unsafe.Pointer(onOpen), userData: userData}
+	d.onMessage = callback{fn: unsafe.Pointer(onMessage), userData: userData}
+	d.mu.Unlock()
+
+	d.dc.SetObserver(d)
+	return nil
+}
+
+//export PionDataChannelSend
+func PionDataChannelSend(h C.int64_t, data unsafe.Pointer, length C.int) *C.char {
+	d, err := getDataChannel(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	return cError(d.dc.Send(C.GoBytes(data, length)))
+}
+
+//export PionDataChannelSendText
+func PionDataChannelSendText(h C.int64_t, text *C.char) *C.char {
+	d, err := getDataChannel(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	return cError(d.dc.SendText(C.GoString(text)))
+}
+
+//export PionDataChannelClose
+func PionDataChannelClose(h C.int64_t) *C.char {
+	d, err := getDataChannel(int64(h))
+	if err != nil {
+		return cError(err)
+	}
+	deleteHandle(int64(h))
+	return cError(d.dc.Close())
+}
+
+//export PionTrackWriteSample
+func PionTrackWriteSample(h C.int64_t, data unsafe.Pointer, length C.int, samples C.int) *C.char {
+	t, ok := lookupHandle(int64(h)).(*mobile.LocalTrack)
+	if !ok {
+		return cError(errInvalidHandle)
+	}
+	return cError(t.WriteSample(C.GoBytes(data, length), int(samples)))
+}
+
+// PionTrackReadRTP blocks until the next RTP packet of a remote track is
+// received and copies it to buf. Packets larger than length are truncated.
+//export PionTrackReadRTP
+func PionTrackReadRTP(h C.int64_t, buf unsafe.Pointer, length C.int, n *C.int) *C.char {
+	t, ok := lookupHandle(int64(h)).(*mobile.RemoteTrack)
+	if !ok {
+		return cError(errInvalidHandle)
+	}
+	packet, err := t.ReadRTP()
+	if err != nil {
+		return cError(err)
+	}
+	if len(packet) > int(length) {
+		packet = packet[:length]
+	}
+	*n = C.int(copy((*[1 << 30]byte)(buf)[:length:length], packet))
+	return nil
+}
+
+//export PionHandleRelease
+func PionHandleRelease(h C.int64_t) {
+	deleteHandle(int64(h))
+}
+
+func main() {}
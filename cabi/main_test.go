@@ -0,0 +1,108 @@
+// +build !js
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// goString copies the C string p returned by one of the exported functions
+// and releases it with PionFree, like a C caller would
+func goString(p unsafe.Pointer) string {
+	var b []byte
+	for i := uintptr(0); ; i++ {
+		c := *(*byte)(unsafe.Pointer(uintptr(p) + i))
+		if c == 0 {
+			break
+		}
+		b = append(b, c)
+	}
+	PionFree(p)
+	return string(b)
+}
+
+func TestHandles(t *testing.T) {
+	pc := &peerConnection{}
+	dc := &dataChannel{}
+	pcHandle := newHandle(pc)
+	dcHandle := newHandle(dc)
+	assert.NotEqual(t, pcHandle, dcHandle)
+
+	p, err := getPeerConnection(pcHandle)
+	assert.NoError(t, err)
+	assert.Equal(t, pc, p)
+	d, err := getDataChannel(dcHandle)
+	assert.NoError(t, err)
+	assert.Equal(t, dc, d)
+
+	// A handle of another type is as invalid as an unknown one
+	_, err = getPeerConnection(dcHandle)
+	assert.Equal(t, errInvalidHandle, err)
+	_, err = getDataChannel(pcHandle)
+	assert.Equal(t, errInvalidHandle, err)
+	_, err = getPeerConnection(0)
+	assert.Equal(t, errInvalidHandle, err)
+
+	// Released handles stay invalid, they aren't handed out again
+	deleteHandle(pcHandle)
+	_, err = getPeerConnection(pcHandle)
+	assert.Equal(t, errInvalidHandle, err)
+	next := newHandle(pc)
+	assert.True(t, next > dcHandle)
+
+	deleteHandle(dcHandle)
+	deleteHandle(next)
+}
+
+func TestHandlesConcurrent(t *testing.T) {
+	const goroutines, perGoroutine = 8, 100
+
+	var mu sync.Mutex
+	seen := map[int64]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				h := newHandle(j)
+				mu.Lock()
+				seen[h] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, goroutines*perGoroutine)
+	for h := range seen {
+		deleteHandle(h)
+	}
+}
+
+func TestCError(t *testing.T) {
+	assert.Nil(t, cError(nil))
+	assert.Equal(t, "invalid handle", goString(unsafe.Pointer(cError(errInvalidHandle))))
+	assert.Equal(t, "ümlaut", goString(unsafe.Pointer(cError(errors.New("ümlaut")))))
+	assert.Equal(t, "", goString(unsafe.Pointer(cError(errors.New("")))))
+}
+
+func TestInvalidHandle(t *testing.T) {
+	// Unknown handles fail with an error message, the out parameters aren't
+	// written to
+	for _, e := range []unsafe.Pointer{
+		unsafe.Pointer(PionPeerConnectionClose(0)),
+		unsafe.Pointer(PionPeerConnectionCreateOffer(0, nil)),
+		unsafe.Pointer(PionPeerConnectionCreateDataChannel(0, nil, nil)),
+		unsafe.Pointer(PionDataChannelSendText(0, nil)),
+		unsafe.Pointer(PionTrackWriteSample(0, nil, 0, 0)),
+		unsafe.Pointer(PionTrackReadRTP(0, nil, 0, nil)),
+	} {
+		assert.Equal(t, "invalid handle", goString(e))
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math/big"
 	"time"
 
@@ -29,20 +30,24 @@ type Certificate struct {
 // GenerateCertificate by allowing to specify a template x509.Certificate to
 // be used in order to define certificate parameters.
 func NewCertificate(key crypto.PrivateKey, tpl x509.Certificate) (*Certificate, error) {
+	return newCertificate(key, tpl, rand.Reader)
+}
+
+func newCertificate(key crypto.PrivateKey, tpl x509.Certificate, random io.Reader) (*Certificate, error) {
 	var err error
 	var certDER []byte
 	switch sk := key.(type) {
 	case *rsa.PrivateKey:
 		pk := sk.Public()
 		tpl.SignatureAlgorithm = x509.SHA256WithRSA
-		certDER, err = x509.CreateCertificate(rand.Reader, &tpl, &tpl, pk, sk)
+		certDER, err = x509.CreateCertificate(random, &tpl, &tpl, pk, sk)
 		if err != nil {
 			return nil, &rtcerr.UnknownError{Err: err}
 		}
 	case *ecdsa.PrivateKey:
 		pk := sk.Public()
 		tpl.SignatureAlgorithm = x509.ECDSAWithSHA256
-		certDER, err = x509.CreateCertificate(rand.Reader, &tpl, &tpl, pk, sk)
+		certDER, err = x509.CreateCertificate(random, &tpl, &tpl, pk, sk)
 		if err != nil {
 			return nil, &rtcerr.UnknownError{Err: err}
 		}
@@ -115,9 +120,13 @@ func (c Certificate) GetFingerprints() ([]DTLSFingerprint, error) {
 // GenerateCertificate causes the creation of an X.509 certificate and
 // corresponding private key.
 func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
+	return generateCertificate(secretKey, rand.Reader)
+}
+
+func generateCertificate(secretKey crypto.PrivateKey, random io.Reader) (*Certificate, error) {
 	origin := make([]byte, 16)
 	/* #nosec */
-	if _, err := rand.Read(origin); err != nil {
+	if _, err := io.ReadFull(random, origin); err != nil {
 		return nil, &rtcerr.UnknownError{Err: err}
 	}
 
@@ -126,12 +135,12 @@ func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
 	/* #nosec */
 	maxBigInt.Exp(big.NewInt(2), big.NewInt(130), nil).Sub(maxBigInt, big.NewInt(1))
 	/* #nosec */
-	serialNumber, err := rand.Int(rand.Reader, maxBigInt)
+	serialNumber, err := rand.Int(random, maxBigInt)
 	if err != nil {
 		return nil, &rtcerr.UnknownError{Err: err}
 	}
 
-	return NewCertificate(secretKey, x509.Certificate{
+	return newCertificate(secretKey, x509.Certificate{
 		ExtKeyUsage: []x509.ExtKeyUsage{
 			x509.ExtKeyUsageClientAuth,
 			x509.ExtKeyUsageServerAuth,
@@ -144,5 +153,5 @@ func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
 		Version:               2,
 		Subject:               pkix.Name{CommonName: hex.EncodeToString(origin)},
 		IsCA:                  true,
-	})
+	}, random)
 }
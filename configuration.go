@@ -15,7 +15,8 @@ type Configuration struct {
 	ICETransportPolicy ICETransportPolicy
 
 	// BundlePolicy indicates which media-bundling policy to use when gathering
-	// ICE candidates.
+	// ICE candidates. Only BundlePolicyMaxBundle changes the descriptions, all
+	// media shares one ICE transport with the other policies as well.
 	BundlePolicy BundlePolicy
 
 	// RTCPMuxPolicy indicates which rtcp-mux policy to use when gathering ICE
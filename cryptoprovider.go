@@ -0,0 +1,81 @@
+// +build !js
+
+package webrtc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+
+	"github.com/pion/dtls"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// CryptoProvider selects the cryptographic primitives used for DTLS and SRTP.
+// The primitives themselves are implemented by the Go standard library and
+// pion/dtls. A provider can only restrict the choices that are configurable,
+// e.g. the cipher suites, see NewAESGCMCryptoProvider.
+type CryptoProvider interface {
+	// Rand is the source of randomness for generated certificates and the
+	// SRTP master keys of SDES, see SettingEngine.SetInsecureSDES
+	Rand() io.Reader
+
+	// GenerateCertificateKey creates the private key of the certificate that
+	// is generated when no Certificates are configured
+	GenerateCertificateKey() (crypto.PrivateKey, error)
+
+	// DTLSCipherSuites are the cipher suites offered and accepted during the
+	// DTLS handshake, nil uses the defaults of pion/dtls
+	DTLSCipherSuites() []dtls.CipherSuiteID
+}
+
+// defaultCryptoProvider is used unless SettingEngine.SetCryptoProvider is
+// called
+type defaultCryptoProvider struct{}
+
+func (defaultCryptoProvider) Rand() io.Reader {
+	return rand.Reader
+}
+
+func (p defaultCryptoProvider) GenerateCertificateKey() (crypto.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), p.Rand())
+}
+
+func (defaultCryptoProvider) DTLSCipherSuites() []dtls.CipherSuiteID {
+	return nil
+}
+
+// aesGCMCryptoProvider restricts DTLS to the ECDHE AES-GCM cipher suites
+type aesGCMCryptoProvider struct {
+	defaultCryptoProvider
+}
+
+// NewAESGCMCryptoProvider returns a CryptoProvider that restricts the DTLS
+// handshake to the ECDHE AES-GCM cipher suites, certificates are generated
+// with ECDSA P-256 keys. It doesn't make DTLS FIPS 140-2 compliant:
+// pion/dtls always offers X25519 and prefers it for the key exchange, which
+// can't be configured.
+func NewAESGCMCryptoProvider() CryptoProvider {
+	return aesGCMCryptoProvider{}
+}
+
+func (aesGCMCryptoProvider) DTLSCipherSuites() []dtls.CipherSuiteID {
+	return []dtls.CipherSuiteID{
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		dtls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	}
+}
+
+// generateCertificate creates a certificate with the CryptoProvider of the
+// SettingEngine
+func (api *API) generateCertificate() (*Certificate, error) {
+	provider := api.settingEngine.getCryptoProvider()
+
+	sk, err := provider.GenerateCertificateKey()
+	if err != nil {
+		return nil, &rtcerr.UnknownError{Err: err}
+	}
+	return generateCertificate(sk, provider.Rand())
+}
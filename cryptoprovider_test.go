@@ -0,0 +1,67 @@
+// +build !js
+
+package webrtc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAESGCMCryptoProvider(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetCryptoProvider(NewAESGCMCryptoProvider())
+	pcOffer, pcAnswer, err := NewAPI(WithSettingEngine(s)).newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The generated certificate uses a P-256 key
+	key, ok := pcOffer.configuration.Certificates[0].privateKey.(*ecdsa.PrivateKey)
+	if assert.True(t, ok) {
+		assert.Equal(t, elliptic.P256(), key.Curve)
+	}
+
+	// DTLS completes with the restricted cipher suites
+	connected := make(chan struct{})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnOpen(func() {
+			close(connected)
+		})
+	})
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestGenerateCertificate_Rand(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// All randomness is taken from the given reader
+	random := &countingReader{}
+	_, err = generateCertificate(sk, random)
+	assert.NoError(t, err)
+	assert.NotZero(t, random.read)
+}
+
+type countingReader struct {
+	read int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	r.read += len(b)
+	return rand.Read(b)
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"math"
 	"sync"
+	"time"
 
 	"github.com/pion/datachannel"
 	"github.com/pion/logging"
@@ -53,6 +54,9 @@ type DataChannel struct {
 	sctpTransport *SCTPTransport
 	dataChannel   *datachannel.DataChannel
 
+	// readDeadline of the detached reads, see SetReadDeadline
+	readDeadline deadlineReader
+
 	// A reference to the associated api object used by this datachannel
 	api *API
 	log logging.LeveledLogger
@@ -391,7 +395,25 @@ func (d *DataChannel) Detach() (datachannel.ReadWriteCloser, error) {
 		return nil, fmt.Errorf("datachannel not opened yet, try calling Detach from OnOpen")
 	}
 
-	return d.dataChannel, nil
+	return &detachedDataChannel{DataChannel: d.dataChannel, dc: d}, nil
+}
+
+// SetReadDeadline sets the deadline of the reads of the detached
+// DataChannel. Once it passed, reads return ErrDeadlineExceeded instead of
+// waiting for a message, a waiting read included. A zero value disables the
+// deadline. The ReadWriteCloser returned by Detach has a SetReadDeadline
+// method as well.
+//
+// Reads that started before the first deadline was set can't be interrupted.
+// A read that timed out keeps waiting for the next message in the
+// background, the message is returned by the next read.
+func (d *DataChannel) SetReadDeadline(deadline time.Time) error {
+	if !d.api.settingEngine.detach.DataChannels {
+		return fmt.Errorf("enable detaching by calling webrtc.DetachDataChannels()")
+	}
+
+	d.readDeadline.setDeadline(deadline)
+	return nil
 }
 
 // Close Closes the DataChannel. It may be called regardless of whether
@@ -544,6 +566,22 @@ func (d *DataChannel) SetBufferedAmountLowThreshold(th uint64) {
 	}
 }
 
+// unhook removes the event handlers of d, no further events are delivered to
+// the code that set them
+func (d *DataChannel) unhook() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onMessageHandler = nil
+	d.onOpenHandler = nil
+	d.onCloseHandler = nil
+	d.onErrorHandler = nil
+	d.onBufferedAmountLow = nil
+	if d.dataChannel != nil {
+		d.dataChannel.OnBufferedAmountLow(nil)
+	}
+}
+
 // OnBufferedAmountLow sets an event handler which is invoked when
 // the number of bytes of outgoing data becomes lower than the
 // BufferedAmountLowThreshold.
@@ -0,0 +1,34 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/datachannel"
+)
+
+// detachedDataChannel is the datachannel.ReadWriteCloser returned by Detach,
+// it adds the read deadline of the DataChannel to the reads
+type detachedDataChannel struct {
+	*datachannel.DataChannel
+	dc *DataChannel
+}
+
+// Read reads a message into p, see ReadDataChannel
+func (d *detachedDataChannel) Read(p []byte) (int, error) {
+	n, _, err := d.ReadDataChannel(p)
+	return n, err
+}
+
+// ReadDataChannel reads a message into p and returns whether it is a string.
+// It returns ErrDeadlineExceeded once the read deadline passed.
+func (d *detachedDataChannel) ReadDataChannel(p []byte) (int, bool, error) {
+	return d.dc.readDeadline.read(p, dataChannelBufferSize, d.DataChannel.ReadDataChannel)
+}
+
+// SetReadDeadline sets the read deadline of the DataChannel, see
+// DataChannel.SetReadDeadline
+func (d *detachedDataChannel) SetReadDeadline(deadline time.Time) error {
+	return d.dc.SetReadDeadline(deadline)
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"io/ioutil"
 	"math/big"
+	"net"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -552,3 +553,158 @@ func TestEOF(t *testing.T) {
 		assert.Equal(t, int32(1), atomic.LoadInt32(&nDCbCbs), "dcb should be closed by now")
 	})
 }
+
+func TestDataChannelRequest(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	offerPC, answerPC, err := newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requestedMu sync.Mutex
+	var requested []string
+	answerPC.OnDataChannelRequest(func(params DataChannelParameters) bool {
+		requestedMu.Lock()
+		defer requestedMu.Unlock()
+		requested = append(requested, params.Label)
+		return params.Label != "refused"
+	})
+
+	accepted := make(chan string, 3)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		accepted <- d.Label()
+	})
+
+	refused, err := offerPC.CreateDataChannel("refused", nil)
+	assert.NoError(t, err)
+	refusedClosed := make(chan struct{})
+	refused.OnClose(func() {
+		close(refusedClosed)
+	})
+	_, err = offerPC.CreateDataChannel("accepted", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	// The remote sees the refused channel closed
+	<-refusedClosed
+
+	var labels []string
+	for len(labels) < 2 {
+		labels = append(labels, <-accepted)
+	}
+	assert.ElementsMatch(t, []string{"initial_data_channel", "accepted"}, labels)
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+
+	requestedMu.Lock()
+	defer requestedMu.Unlock()
+	assert.ElementsMatch(t, []string{"initial_data_channel", "refused", "accepted"}, requested)
+}
+
+func TestDataChannelQuota(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetQuotas(Quotas{MaxDataChannels: 1})
+	api := NewAPI(WithSettingEngine(s))
+
+	offerPC, answerPC, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan string, 2)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		accepted <- d.Label()
+	})
+
+	// The channel signalPair creates takes the only slot
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	assert.Equal(t, "initial_data_channel", <-accepted)
+
+	refused, err := offerPC.CreateDataChannel("refused", nil)
+	assert.NoError(t, err)
+	refusedClosed := make(chan struct{})
+	refused.OnClose(func() {
+		close(refusedClosed)
+	})
+	<-refusedClosed
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
+func TestDataChannel_ReadDeadline(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.DetachDataChannels()
+	api := NewAPI(WithSettingEngine(s))
+
+	offerPC, answerPC, err := api.newPair()
+	assert.NoError(t, err)
+
+	remote := make(chan *DataChannel, 1)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != "deadline" {
+			return
+		}
+		d.OnOpen(func() {
+			remote <- d
+		})
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	local, err := offerPC.CreateDataChannel("deadline", nil)
+	assert.NoError(t, err)
+	opened := make(chan struct{})
+	local.OnOpen(func() {
+		close(opened)
+	})
+	<-opened
+
+	writer, err := local.Detach()
+	assert.NoError(t, err)
+
+	d := <-remote
+	reader, err := d.Detach()
+	assert.NoError(t, err)
+
+	// Nothing was sent yet, the read times out
+	assert.NoError(t, d.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	buf := make([]byte, 64)
+	_, err = reader.Read(buf)
+	assert.Equal(t, ErrDeadlineExceeded, err)
+	netErr, ok := err.(net.Error)
+	assert.True(t, ok)
+	assert.True(t, netErr.Timeout())
+
+	// A waiting read is interrupted by a new deadline
+	interrupted := make(chan error)
+	assert.NoError(t, d.SetReadDeadline(time.Time{}))
+	go func() {
+		_, readErr := reader.Read(buf)
+		interrupted <- readErr
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, reader.(interface{ SetReadDeadline(time.Time) error }).SetReadDeadline(time.Now()))
+	assert.Equal(t, ErrDeadlineExceeded, <-interrupted)
+
+	// The message read in the background isn't lost
+	assert.NoError(t, d.SetReadDeadline(time.Time{}))
+	_, err = writer.Write([]byte("ping"))
+	assert.NoError(t, err)
+	n, err := reader.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
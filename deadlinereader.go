@@ -0,0 +1,144 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// deadlineReadFunc is a read that blocks until data arrives, isString is
+// only used by DataChannels
+type deadlineReadFunc func(b []byte) (n int, isString bool, err error)
+
+// deadlineReader adds a read deadline to reads that block until data arrives,
+// like the ones of the SRTP stream of a Track or of a detached DataChannel,
+// which can't be interrupted.
+//
+// Once a deadline was set, the reads are made by a goroutine and a read waits
+// for it until the deadline. A read that times out keeps running in the
+// background and its result is returned by the next read, so no data is lost
+// and at most one goroutine is left waiting until data arrives or the
+// underlying reader is closed. Reads before the first deadline are made
+// directly.
+type deadlineReader struct {
+	mu       sync.Mutex
+	enabled  bool
+	deadline time.Time
+
+	// changed is closed and replaced when the deadline changes, to wake up
+	// a waiting read
+	changed chan struct{}
+
+	// pending is closed once the background read finished, nil if none is
+	// running. Its result is kept until it is returned by a read.
+	pending   chan struct{}
+	buffer    []byte
+	n         int
+	isString  bool
+	err       error
+	hasResult bool
+}
+
+// setDeadline sets the deadline of the following reads and of a read that
+// is waiting. A zero deadline disables it.
+func (d *deadlineReader) setDeadline(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.enabled = true
+	d.deadline = t
+	if d.changed != nil {
+		close(d.changed)
+	}
+	d.changed = make(chan struct{})
+}
+
+// read reads into b with read, bufferSize is the size of the largest read
+// that is made in the background
+func (d *deadlineReader) read(b []byte, bufferSize int, read deadlineReadFunc) (int, bool, error) {
+	d.mu.Lock()
+	if !d.enabled {
+		d.mu.Unlock()
+		return read(b)
+	}
+
+	for {
+		if d.hasResult {
+			n, isString, err := d.takeResult(b)
+			d.mu.Unlock()
+			return n, isString, err
+		}
+
+		deadline := d.deadline
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			d.mu.Unlock()
+			return 0, false, ErrDeadlineExceeded
+		}
+
+		if d.pending == nil {
+			d.start(bufferSize, read)
+		}
+		pending, changed := d.pending, d.changed
+		d.mu.Unlock()
+
+		if !waitForRead(pending, changed, deadline) {
+			return 0, false, ErrDeadlineExceeded
+		}
+		d.mu.Lock()
+	}
+}
+
+// waitForRead waits until the background read finished or the deadline changed, it
+// returns false if the deadline passed first
+func waitForRead(pending, changed chan struct{}, deadline time.Time) bool {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-pending:
+	case <-changed:
+	case <-timeout:
+		return false
+	}
+	return true
+}
+
+// start starts a read in the background, d.mu has to be held
+func (d *deadlineReader) start(bufferSize int, read deadlineReadFunc) {
+	if len(d.buffer) < bufferSize {
+		d.buffer = make([]byte, bufferSize)
+	}
+	buffer := d.buffer[:bufferSize]
+	pending := make(chan struct{})
+	d.pending = pending
+
+	go func() {
+		n, isString, err := read(buffer)
+
+		d.mu.Lock()
+		d.n, d.isString, d.err = n, isString, err
+		d.hasResult = true
+		d.pending = nil
+		d.mu.Unlock()
+		close(pending)
+	}()
+}
+
+// takeResult copies the result of the background read into b, d.mu has to
+// be held
+func (d *deadlineReader) takeResult(b []byte) (int, bool, error) {
+	d.hasResult = false
+	if d.err != nil {
+		return 0, d.isString, d.err
+	}
+	if len(b) < d.n {
+		return 0, d.isString, io.ErrShortBuffer
+	}
+	return copy(b, d.buffer[:d.n]), d.isString, nil
+}
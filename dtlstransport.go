@@ -3,17 +3,18 @@
 package webrtc
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
+	"context"
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/dtls"
+	"github.com/pion/rtcp"
 	"github.com/pion/srtp"
 	"github.com/pion/webrtc/v2/internal/mux"
 	"github.com/pion/webrtc/v2/internal/util"
@@ -44,6 +45,23 @@ type DTLSTransport struct {
 
 	dtlsMatcher mux.MatchFunc
 
+	// rtcpCoalescer batches the RTCP written to the transport, it is nil
+	// unless SettingEngine.SetRTCPCoalescingInterval is used
+	rtcpCoalescer *rtcpCoalescer
+
+	// drops and remoteRTP account for the RTP that no receiver gets,
+	// remoteRTP is a *remoteRTP
+	drops     rtpDrops
+	remoteRTP atomic.Value
+	statsID   string
+
+	// policer enforces Quotas.MaxInboundBitrate, nil without a limit
+	policer *rtpPolicer
+
+	// mtuOverhead is the pathMTUOverhead of the selected candidate pair,
+	// accessed atomically
+	mtuOverhead int32
+
 	api *API
 }
 
@@ -56,6 +74,11 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 		api:          api,
 		state:        DTLSTransportStateNew,
 		dtlsMatcher:  mux.MatchDTLS,
+		statsID:      fmt.Sprintf("DTLSTransport-%d", time.Now().UnixNano()),
+		policer:      newRTPPolicer(api.settingEngine.quotas.MaxInboundBitrate),
+	}
+	if interval := api.settingEngine.timeout.RTCPCoalescing; interval != 0 {
+		t.rtcpCoalescer = newRTCPCoalescer(interval, t.MTU, t.writeRTCPNow, api.settingEngine.LoggerFactory.NewLogger("rtcp"))
 	}
 
 	if len(certificates) > 0 {
@@ -67,11 +90,7 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 			t.certificates = append(t.certificates, x509Cert)
 		}
 	} else {
-		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-		if err != nil {
-			return nil, &rtcerr.UnknownError{Err: err}
-		}
-		certificate, err := GenerateCertificate(sk)
+		certificate, err := api.generateCertificate()
 		if err != nil {
 			return nil, err
 		}
@@ -152,7 +171,7 @@ func (t *DTLSTransport) startSRTP() error {
 
 	srtpConfig := &srtp.Config{
 		Profile:       srtp.ProtectionProfileAes128CmHmacSha1_80,
-		LoggerFactory: t.api.settingEngine.LoggerFactory,
+		LoggerFactory: t.srtpLoggerFactory(),
 	}
 
 	err := srtpConfig.ExtractSessionKeysFromDTLS(t.conn, t.isClient())
@@ -160,7 +179,7 @@ func (t *DTLSTransport) startSRTP() error {
 		return fmt.Errorf("failed to extract sctp session keys: %v", err)
 	}
 
-	srtpSession, err := srtp.NewSessionSRTP(t.srtpEndpoint, srtpConfig)
+	srtpSession, err := srtp.NewSessionSRTP(&rtpDemuxConn{Conn: t.srtpEndpoint, transport: t}, srtpConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start srtp: %v", err)
 	}
@@ -205,13 +224,43 @@ func (t *DTLSTransport) getSRTCPSession() (*srtp.SessionSRTCP, error) {
 	return t.srtcpSession, nil
 }
 
+// writeRTCP sends pkts, or queues them if RTCP is coalesced
+func (t *DTLSTransport) writeRTCP(pkts []rtcp.Packet) error {
+	if t.rtcpCoalescer != nil {
+		return t.rtcpCoalescer.writeRTCP(pkts)
+	}
+
+	raw, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return err
+	}
+	return t.writeRTCPNow(raw)
+}
+
+func (t *DTLSTransport) writeRTCPNow(raw []byte) error {
+	srtcpSession, err := t.getSRTCPSession()
+	if err != nil {
+		return err
+	}
+
+	writeStream, err := srtcpSession.OpenWriteStream()
+	if err != nil {
+		return fmt.Errorf("failed to open the SRTCP write stream: %v", err)
+	}
+
+	_, err = writeStream.Write(raw)
+	return err
+}
+
+// isClient tells if the local side is the DTLS client. The role of the
+// remote decides if it is known, the remote client makes us the server.
 func (t *DTLSTransport) isClient() bool {
 	isClient := true
 	switch t.remoteParameters.Role {
 	case DTLSRoleClient:
-		isClient = true
-	case DTLSRoleServer:
 		isClient = false
+	case DTLSRoleServer:
+		isClient = true
 	default:
 		if t.iceTransport.Role() == ICERoleControlling {
 			isClient = false
@@ -223,6 +272,13 @@ func (t *DTLSTransport) isClient() bool {
 
 // Start DTLS transport negotiation with the parameters of the remote DTLS transport
 func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
+	return t.StartContext(context.Background(), remoteParameters)
+}
+
+// StartContext is Start bounded by ctx. If ctx is done before the handshake
+// completed, the handshake is aborted, the transport fails and the error of
+// ctx is returned.
+func (t *DTLSTransport) StartContext(ctx context.Context, remoteParameters DTLSParameters) error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
@@ -233,6 +289,7 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	if t.state != DTLSTransportStateNew {
 		return &rtcerr.InvalidStateError{Err: fmt.Errorf("attempted to start DTLSTransport that is not in new state: %s", t.state)}
 	}
+	t.remoteParameters = remoteParameters
 
 	dtlsEndpoint := t.iceTransport.NewEndpoint(mux.MatchDTLS)
 	t.srtpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTP)
@@ -244,6 +301,7 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	dtlsCofig := &dtls.Config{
 		Certificate:            cert.x509Cert,
 		PrivateKey:             cert.privateKey,
+		CipherSuites:           t.api.settingEngine.getCryptoProvider().DTLSCipherSuites(),
 		SRTPProtectionProfiles: []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80},
 		ClientAuth:             dtls.RequireAnyClientCert,
 		LoggerFactory:          t.api.settingEngine.LoggerFactory,
@@ -251,23 +309,28 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	}
 
 	t.onStateChange(DTLSTransportStateConnecting)
+	stopWatching := abortOnDone(ctx, dtlsEndpoint)
+	var dtlsConn *dtls.Conn
+	var err error
 	if t.isClient() {
 		// Assumes the peer offered to be passive and we accepted.
-		dtlsConn, err := dtls.Client(dtlsEndpoint, dtlsCofig)
-		if err != nil {
-			t.onStateChange(DTLSTransportStateFailed)
-			return err
-		}
-		t.conn = dtlsConn
+		dtlsConn, err = dtls.Client(dtlsEndpoint, dtlsCofig)
 	} else {
 		// Assumes we offer to be passive and this is accepted.
-		dtlsConn, err := dtls.Server(dtlsEndpoint, dtlsCofig)
-		if err != nil {
-			t.onStateChange(DTLSTransportStateFailed)
-			return err
+		dtlsConn, err = dtls.Server(dtlsEndpoint, dtlsCofig)
+	}
+	if stopWatching() {
+		if err == nil {
+			_ = dtlsConn.Close()
 		}
-		t.conn = dtlsConn
+		t.onStateChange(DTLSTransportStateFailed)
+		return ctx.Err()
 	}
+	if err != nil {
+		t.onStateChange(DTLSTransportStateFailed)
+		return err
+	}
+	t.conn = dtlsConn
 	t.onStateChange(DTLSTransportStateConnected)
 
 	// Check the fingerprint if a certificate was exchanged
@@ -280,8 +343,89 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	return t.validateFingerPrint(remoteParameters, remoteCert)
 }
 
+// abortOnDone closes conn if ctx is done before the returned function is
+// called, or by the time it is, which tells if conn was closed
+func abortOnDone(ctx context.Context, conn io.Closer) func() bool {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	stopped := make(chan struct{})
+	aborted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+			if ctx.Err() == nil {
+				aborted <- false
+				return
+			}
+		}
+		_ = conn.Close()
+		aborted <- true
+	}()
+
+	return func() bool {
+		close(stopped)
+		return <-aborted
+	}
+}
+
+// StartSRTP starts the SRTP sessions of the transport with keys that were
+// exchanged by external key management, e.g. SDES or MIKEY, instead of a
+// DTLS handshake. The transport is connected right away, it can't carry
+// SCTP. It requires SettingEngine.SetInsecureSDES.
+func (t *DTLSTransport) StartSRTP(keys SRTPKeys) error {
+	if !t.api.settingEngine.insecureSDES {
+		return &rtcerr.InvalidAccessError{Err: ErrSDESNotEnabled}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.ensureICEConn(); err != nil {
+		return err
+	}
+	if t.state != DTLSTransportStateNew {
+		return &rtcerr.InvalidStateError{Err: fmt.Errorf("attempted to start DTLSTransport that is not in new state: %s", t.state)}
+	}
+
+	t.srtpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTP)
+	t.srtcpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTCP)
+
+	srtpConfig := &srtp.Config{
+		Profile: srtp.ProtectionProfileAes128CmHmacSha1_80,
+		Keys: srtp.SessionKeys{
+			LocalMasterKey:   keys.LocalMasterKey,
+			LocalMasterSalt:  keys.LocalMasterSalt,
+			RemoteMasterKey:  keys.RemoteMasterKey,
+			RemoteMasterSalt: keys.RemoteMasterSalt,
+		},
+		LoggerFactory: t.srtpLoggerFactory(),
+	}
+
+	srtpSession, err := srtp.NewSessionSRTP(&rtpDemuxConn{Conn: t.srtpEndpoint, transport: t}, srtpConfig)
+	if err != nil {
+		return fmt.Errorf("failed to start srtp: %v", err)
+	}
+	srtcpSession, err := srtp.NewSessionSRTCP(t.srtcpEndpoint, srtpConfig)
+	if err != nil {
+		return util.FlattenErrs([]error{fmt.Errorf("failed to start srtp: %v", err), srtpSession.Close()})
+	}
+
+	t.srtpSession = srtpSession
+	t.srtcpSession = srtcpSession
+	t.onStateChange(DTLSTransportStateConnected)
+	return nil
+}
+
 // Stop stops and closes the DTLSTransport object.
 func (t *DTLSTransport) Stop() error {
+	// Pending RTCP is sent before the session is closed
+	if t.rtcpCoalescer != nil {
+		t.rtcpCoalescer.close()
+	}
+
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
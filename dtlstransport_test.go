@@ -0,0 +1,32 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type closeCounter int
+
+func (c *closeCounter) Close() error {
+	*c++
+	return nil
+}
+
+func TestAbortOnDone(t *testing.T) {
+	var conn closeCounter
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.False(t, abortOnDone(ctx, &conn)())
+	assert.Equal(t, closeCounter(0), conn)
+
+	stopWatching := abortOnDone(ctx, &conn)
+	cancel()
+	assert.True(t, stopWatching())
+	assert.Equal(t, closeCounter(1), conn)
+
+	// Without a context that can be done nothing is watched
+	assert.False(t, abortOnDone(context.Background(), &conn)())
+}
@@ -0,0 +1,20 @@
+// +build !js
+
+package webrtc
+
+// EncodedFrame is a frame of encoded media handed to a FrameTransform
+type EncodedFrame struct {
+	// Data is the complete frame, e.g. a VP8 frame or an H264 access unit in
+	// Annex-B format
+	Data        []byte
+	SSRC        uint32
+	PayloadType uint8
+	Kind        RTPCodecType
+}
+
+// FrameTransform rewrites an encoded frame and returns the new frame data.
+// Like the insertable streams of the browser it runs before packetization on
+// send and after depacketization on receive, which makes it the place for end
+// to end encryption: the RTP headers stay readable, so an SFU can forward the
+// packets without knowing the key.
+type FrameTransform func(frame EncodedFrame) ([]byte, error)
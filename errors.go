@@ -2,8 +2,11 @@ package webrtc
 
 import (
 	"errors"
+	"net"
 )
 
+// Errors are usually returned wrapped, e.g. in one of the rtcerr types or in
+// an *SDPValidationError. Use errors.Is and errors.As to check for them.
 var (
 	// ErrUnknownType indicates an error with Unknown info.
 	ErrUnknownType = errors.New("unknown")
@@ -83,4 +86,119 @@ var (
 	// ErrIncorrectSDPSemantics indicates that the PeerConnection was configured to
 	// generate SDP Answers with different SDP Semantics than the received Offer
 	ErrIncorrectSDPSemantics = errors.New("offer SDP semantics does not match configuration")
+
+	// ErrUnknownTrackKind indicates that a track was added whose codec is
+	// neither audio nor video
+	ErrUnknownTrackKind = errors.New("track kind is neither audio nor video")
+
+	// ErrTrackKindMismatch indicates that a track was bound to a transceiver
+	// of another kind, e.g. an audio track to a video transceiver
+	ErrTrackKindMismatch = errors.New("track kind does not match the transceiver")
+
+	// ErrIncompatibleAnswerDirection indicates that AnswerOptions forced a
+	// direction that the offer doesn't allow, e.g. sendonly for a sendonly offer
+	ErrIncompatibleAnswerDirection = errors.New("answer direction is not allowed by the offer")
+
+	// ErrIncompatibleAnswerDTLSRole indicates that AnswerOptions forced a
+	// DTLS role that the setup attribute of the offer doesn't allow, e.g. the
+	// client role for an offer with setup:active
+	ErrIncompatibleAnswerDTLSRole = errors.New("answer DTLS role is not allowed by the offer")
+
+	// ErrRenegotiationNotAdditive indicates that a remote description after
+	// the first one removes or changes negotiated media sections or restarts
+	// DTLS, only additions and ICE restarts are supported while a call is
+	// running.
+	ErrRenegotiationNotAdditive = errors.New("remote description update is not additive")
+
+	// ErrRTCPMuxRequired indicates that a remote media section doesn't support
+	// rtcp-mux while the RTCPMuxPolicy requires it.
+	ErrRTCPMuxRequired = errors.New("remote description does not support rtcp-mux")
+
+	// ErrProbeInProgress indicates that a ProbeCluster was requested while
+	// the RTPSender was still sending a previous one.
+	ErrProbeInProgress = errors.New("probe cluster already in progress")
+
+	// ErrSDESNotEnabled indicates that SRTP was keyed without DTLS, which
+	// requires SettingEngine.SetInsecureSDES
+	ErrSDESNotEnabled = errors.New("srtp keying without dtls is not enabled")
+
+	// ErrSDESInvalidCrypto indicates an a=crypto attribute with an
+	// unsupported crypto suite or malformed key parameters
+	ErrSDESInvalidCrypto = errors.New("invalid sdes crypto attribute")
+
+	// ErrInvalidSSRCGroup indicates that an SSRC group has no semantics, less
+	// than two SSRCs or an SSRC of zero.
+	ErrInvalidSSRCGroup = errors.New("invalid ssrc group")
+
+	// ErrInvalidRID indicates that the RID of an encoding is empty, longer
+	// than 16 characters, contains characters other than alphanumerics, '-'
+	// and '_', or is used by another encoding of the same track.
+	ErrInvalidRID = errors.New("invalid rid")
+
+	// ErrUnknownRID indicates that a track has no encoding with a given RID
+	ErrUnknownRID = errors.New("unknown rid")
+
+	// ErrNoFreeHeaderExtensionID indicates that a header extension was
+	// registered after all IDs of one-byte header extensions were taken
+	ErrNoFreeHeaderExtensionID = errors.New("no free header extension id")
+
+	// ErrInvalidFrameMarking indicates a frame marking header extension that
+	// is neither one nor three bytes long, or a temporal ID above 7
+	ErrInvalidFrameMarking = errors.New("invalid frame marking")
+
+	// ErrInvalidRedundancy indicates a RED redundancy distance that is
+	// negative or above the supported maximum of 8
+	ErrInvalidRedundancy = errors.New("invalid red redundancy distance")
+
+	// ErrInvalidMTU indicates an MTU below 576 or above the largest UDP
+	// payload
+	ErrInvalidMTU = errors.New("invalid mtu")
+
+	// ErrUDPPortInUse indicates that the static UDP port of ICE, see
+	// SettingEngine.SetUDPPort, is bound by another socket
+	ErrUDPPortInUse = errors.New("udp port in use")
+
+	// ErrCodecPayloaderNotSet indicates that samples were written to a Track
+	// whose codec has no Payloader, e.g. a passthrough codec. Such tracks
+	// only forward RTP.
+	ErrCodecPayloaderNotSet = errors.New("codec payloader not set")
+
+	// ErrRTPReceiverNotStarted indicates an operation on an RTPReceiver that
+	// isn't receiving a track yet.
+	ErrRTPReceiverNotStarted = errors.New("rtp receiver has not been started")
+
+	// ErrSSRCCollision indicates that a track was added to a PeerConnection
+	// with an SSRC that another of its tracks, local or remote, uses already
+	ErrSSRCCollision = errors.New("ssrc collision")
+
+	// ErrSDPMissingFingerprint indicates that a media section has no DTLS
+	// fingerprint and none is set on the session level.
+	ErrSDPMissingFingerprint = errors.New("no fingerprint")
+
+	// ErrSDPInvalidFingerprint indicates that a fingerprint isn't made of a
+	// hash function and a value.
+	ErrSDPInvalidFingerprint = errors.New("invalid fingerprint")
+
+	// ErrSDPMissingICECredentials indicates that a media section has no
+	// ice-ufrag or ice-pwd and none is set on the session level.
+	ErrSDPMissingICECredentials = errors.New("no ice-ufrag or ice-pwd")
+
+	// ErrSDPConflictingPayloadType indicates that a payload type is mapped to
+	// different codecs. Every media section shares the same transport, so a
+	// payload type has to mean the same in all of them.
+	ErrSDPConflictingPayloadType = errors.New("payload type mapped to multiple codecs")
+
+	// ErrSDPUnsupportedSetup indicates that the setup attribute has a value
+	// that can't be used to determine the DTLS role.
+	ErrSDPUnsupportedSetup = errors.New("unsupported setup attribute")
+
+	// ErrDeadlineExceeded indicates that the read deadline of a Track or a
+	// detached DataChannel passed. It is a net.Error whose Timeout is true.
+	ErrDeadlineExceeded net.Error = deadlineExceededError{}
 )
+
+type deadlineExceededError struct{}
+
+func (deadlineExceededError) Error() string   { return "read deadline exceeded" }
+func (deadlineExceededError) Timeout() bool   { return true }
+func (deadlineExceededError) Temporary() bool { return true }
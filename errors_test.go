@@ -0,0 +1,35 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorsIsAs(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing fingerprint is reported with the offending media section
+	err = pc.SetRemoteDescription(SessionDescription{
+		Type: SDPTypeOffer,
+		SDP:  "v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\nm=application 9 DTLS/SCTP 5000\r\na=ice-ufrag:ufrag\r\na=ice-pwd:pwd\r\n",
+	})
+	assert.True(t, errors.Is(err, ErrSDPMissingFingerprint))
+
+	var validationErr *SDPValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Equal(t, 0, validationErr.MediaIndex)
+	}
+
+	assert.NoError(t, pc.Close())
+
+	// rtcerr wrappers are transparent
+	_, err = pc.CreateOffer(nil)
+	assert.True(t, errors.Is(err, ErrConnectionClosed))
+}
@@ -78,16 +78,16 @@ func main() {
 
 	sctpCapabilities := sctp.GetCapabilities()
 
-	s := Signal{
+	s := webrtc.SignalingParameters{
 		ICECandidates:    iceCandidates,
 		ICEParameters:    iceParams,
 		DTLSParameters:   dtlsParams,
-		SCTPCapabilities: sctpCapabilities,
+		SCTPCapabilities: &sctpCapabilities,
 	}
 
 	// Exchange the information
 	fmt.Println(signal.Encode(s))
-	remoteSignal := Signal{}
+	remoteSignal := webrtc.SignalingParameters{}
 	signal.Decode(signal.MustReadStdin(), &remoteSignal)
 
 	iceRole := webrtc.ICERoleControlled
@@ -113,7 +113,7 @@ func main() {
 	}
 
 	// Start the SCTP transport
-	err = sctp.Start(remoteSignal.SCTPCapabilities)
+	err = sctp.Start(*remoteSignal.SCTPCapabilities)
 	if err != nil {
 		panic(err)
 	}
@@ -141,16 +141,6 @@ func main() {
 	select {}
 }
 
-// Signal is used to exchange signaling info.
-// This is not part of the ORTC spec. You are free
-// to exchange this information any way you want.
-type Signal struct {
-	ICECandidates    []webrtc.ICECandidate   `json:"iceCandidates"`
-	ICEParameters    webrtc.ICEParameters    `json:"iceParameters"`
-	DTLSParameters   webrtc.DTLSParameters   `json:"dtlsParameters"`
-	SCTPCapabilities webrtc.SCTPCapabilities `json:"sctpCapabilities"`
-}
-
 func handleOnOpen(channel *webrtc.DataChannel) func() {
 	return func() {
 		fmt.Printf("Data channel '%s'-'%d' open. Random messages will now be sent to any connected DataChannels every 5 seconds\n", channel.Label(), channel.ID())
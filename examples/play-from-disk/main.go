@@ -2,13 +2,11 @@ package main
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
-	"time"
 
 	"github.com/pion/webrtc/v2"
-	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/pion/webrtc/v2/pkg/media/ivfreader"
+	"github.com/pion/webrtc/v2/pkg/media/replay"
 
 	"github.com/pion/webrtc/v2/examples/internal/signal"
 )
@@ -53,7 +51,7 @@ func main() {
 	}
 
 	// Create a video track
-	videoTrack, err := peerConnection.NewTrack(payloadType, rand.Uint32(), "video", "pion")
+	videoTrack, err := peerConnection.NewTrack(payloadType, 0, "video", "pion")
 	if err != nil {
 		panic(err)
 	}
@@ -72,20 +70,15 @@ func main() {
 		if ivfErr != nil {
 			panic(ivfErr)
 		}
+		samples, ivfErr := replay.NewIVFSampleReader(ivf, header, 90000)
+		if ivfErr != nil {
+			panic(ivfErr)
+		}
 
 		// Send our video file frame at a time. Pace our sending so we send it at the same speed it should be played back as.
 		// This isn't required since the video is timestamped, but we will such much higher loss if we send all at once.
-		sleepTime := time.Millisecond * time.Duration((float32(header.TimebaseNumerator)/float32(header.TimebaseDenominator))*1000)
-		for {
-			frame, _, ivfErr := ivf.ParseNextFrame()
-			if ivfErr != nil {
-				panic(ivfErr)
-			}
-
-			time.Sleep(sleepTime)
-			if ivfErr = videoTrack.WriteSample(media.Sample{Data: frame, Samples: 90000}); ivfErr != nil {
-				panic(ivfErr)
-			}
+		if ivfErr = replay.Start(videoTrack, samples).Wait(); ivfErr != nil {
+			panic(ivfErr)
 		}
 	}()
 
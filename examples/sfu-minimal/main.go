@@ -2,19 +2,14 @@ package main
 
 import (
 	"fmt"
-	"io"
-	"time"
+	"sync"
 
-	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/room"
 
 	"github.com/pion/webrtc/v2/examples/internal/signal"
 )
 
-const (
-	rtcpPLIInterval = time.Second * 3
-)
-
 func main() {
 	sdpChan := signal.HTTPSDPServer()
 
@@ -23,125 +18,53 @@ func main() {
 	m := webrtc.MediaEngine{}
 
 	// Setup the codecs you want to use.
-	// Only support VP8, this makes our proxying code simpler
+	// Only support VP8, publishers and subscribers have to agree on it
 	m.RegisterCodec(webrtc.NewRTPVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000))
 
-	// Create the API object with the MediaEngine
-	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
-
-	offer := webrtc.SessionDescription{}
-	signal.Decode(<-sdpChan, &offer)
-	fmt.Println("")
-
-	peerConnectionConfig := webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{
-				URLs: []string{"stun:stun.l.google.com:19302"},
+	// The room forwards the tracks of every participant to all others
+	r := room.New(room.Config{
+		MediaEngine: m,
+		Configuration: webrtc.Configuration{
+			ICEServers: []webrtc.ICEServer{
+				{
+					URLs: []string{"stun:stun.l.google.com:19302"},
+				},
 			},
 		},
-	}
-
-	// Create a new RTCPeerConnection
-	peerConnection, err := api.NewPeerConnection(peerConnectionConfig)
-	if err != nil {
-		panic(err)
-	}
-
-	// Allow us to receive 1 video track
-	if _, err = peerConnection.AddTransceiver(webrtc.RTPCodecTypeVideo); err != nil {
-		panic(err)
-	}
-
-	localTrackChan := make(chan *webrtc.Track)
-	// Set a handler for when a new remote track starts, this just distributes all our packets
-	// to connected peers
-	peerConnection.OnTrack(func(remoteTrack *webrtc.Track, receiver *webrtc.RTPReceiver) {
-		// Send a PLI on an interval so that the publisher is pushing a keyframe every rtcpPLIInterval
-		// This can be less wasteful by processing incoming RTCP events, then we would emit a NACK/PLI when a viewer requests it
-		go func() {
-			ticker := time.NewTicker(rtcpPLIInterval)
-			for range ticker.C {
-				if rtcpSendErr := peerConnection.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: remoteTrack.SSRC()}}); rtcpSendErr != nil {
-					fmt.Println(rtcpSendErr)
-				}
-			}
-		}()
-
-		// Create a local track, all our SFU clients will be fed via this track
-		localTrack, newTrackErr := peerConnection.NewTrack(remoteTrack.PayloadType(), remoteTrack.SSRC(), "video", "pion")
-		if newTrackErr != nil {
-			panic(newTrackErr)
-		}
-		localTrackChan <- localTrack
-
-		rtpBuf := make([]byte, 1400)
-		for {
-			i, readErr := remoteTrack.Read(rtpBuf)
-			if readErr != nil {
-				panic(readErr)
-			}
-
-			// ErrClosedPipe means we don't have any subscribers, this is ok if no peers have connected yet
-			if _, err = localTrack.Write(rtpBuf[:i]); err != nil && err != io.ErrClosedPipe {
-				panic(err)
-			}
-		}
 	})
 
-	// Set the remote SessionDescription
-	err = peerConnection.SetRemoteDescription(offer)
-	if err != nil {
-		panic(err)
-	}
-
-	// Create answer
-	answer, err := peerConnection.CreateAnswer(nil)
-	if err != nil {
-		panic(err)
-	}
-
-	// Sets the LocalDescription, and starts our UDP listeners
-	err = peerConnection.SetLocalDescription(answer)
-	if err != nil {
-		panic(err)
-	}
-
-	// Get the LocalDescription and take it to base64 so we can paste in browser
-	fmt.Println(signal.Encode(answer))
-
-	localTrack := <-localTrackChan
-	for {
-		fmt.Println("")
-		fmt.Println("Curl an base64 SDP to start sendonly peer connection")
-
-		recvOnlyOffer := webrtc.SessionDescription{}
-		signal.Decode(<-sdpChan, &recvOnlyOffer)
+	published := make(chan struct{})
+	var publishedOnce sync.Once
+	r.OnTrackPublished(func(t *room.PublishedTrack) {
+		fmt.Printf("%s publishes track %s\n", t.Publisher().ID(), t.ID())
+		publishedOnce.Do(func() { close(published) })
+	})
+	r.OnParticipantLeft(func(p *room.Participant) {
+		fmt.Printf("%s left\n", p.ID())
+	})
 
-		// Create a new PeerConnection
-		peerConnection, err := api.NewPeerConnection(peerConnectionConfig)
-		if err != nil {
-			panic(err)
+	for i := 0; ; i++ {
+		if i == 1 {
+			// Subscribers can only receive once the broadcast started
+			<-published
 		}
-
-		_, err = peerConnection.AddTrack(localTrack)
-		if err != nil {
-			panic(err)
+		fmt.Println("")
+		if i == 0 {
+			fmt.Println("Curl an base64 SDP to start the broadcast")
+		} else {
+			fmt.Println("Curl an base64 SDP to start sendonly peer connection")
 		}
 
-		// Set the remote SessionDescription
-		err = peerConnection.SetRemoteDescription(recvOnlyOffer)
-		if err != nil {
-			panic(err)
-		}
+		offer := webrtc.SessionDescription{}
+		signal.Decode(<-sdpChan, &offer)
 
-		// Create answer
-		answer, err := peerConnection.CreateAnswer(nil)
+		p, err := r.Join(fmt.Sprintf("participant-%d", i))
 		if err != nil {
 			panic(err)
 		}
 
-		// Sets the LocalDescription, and starts our UDP listeners
-		err = peerConnection.SetLocalDescription(answer)
+		// Apply the offer and create the answer
+		answer, err := p.Answer(offer)
 		if err != nil {
 			panic(err)
 		}
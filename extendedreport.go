@@ -0,0 +1,250 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtcp"
+)
+
+// typeExtendedReport is the packet type of RTCP XR, RFC 3611 2
+const typeExtendedReport rtcp.PacketType = 207
+
+// Report block types of RTCP XR, RFC 3611 4
+const (
+	extendedReportBlockLossRLE               uint8 = 1
+	extendedReportBlockReceiverReferenceTime uint8 = 4
+	extendedReportBlockDLRR                  uint8 = 5
+)
+
+const (
+	extendedReportHeaderLength      = 8
+	extendedReportBlockHeaderLength = 4
+	lossRLEHeaderLength             = 8
+	dlrrSubBlockLength              = 12
+
+	// A run length chunk of a Loss RLE block counts up to 14 bits of packets,
+	// a bit vector chunk holds 15
+	lossRLEMaxRunLength = 1<<14 - 1
+	lossRLEVectorLength = 15
+)
+
+// LossRLEReportBlock tells which packets of a stream were received, RFC
+// 3611 4.1. Analytics can derive burst and gap statistics from it.
+type LossRLEReportBlock struct {
+	// SSRC of the stream the block reports on
+	SSRC uint32
+
+	// BeginSeq is the sequence number of the first packet covered, the
+	// block covers the packets up to EndSeq, exclusive
+	BeginSeq uint16
+	EndSeq   uint16
+
+	// Received tells for every packet covered, starting with BeginSeq, if
+	// it was received
+	Received []bool
+}
+
+// DLRRReportBlock answers a receiver reference time of the receiver with
+// SSRC, RFC 3611 4.5. The receiver computes the round trip time from the
+// time it receives the block at, minus LastRR and DelaySinceLastRR.
+type DLRRReportBlock struct {
+	SSRC uint32
+
+	// LastRR is the middle 32 bits of the NTP timestamp of the receiver
+	// reference time answered
+	LastRR uint32
+
+	// DelaySinceLastRR is the time between the receipt of the reference
+	// time and the answer in units of 1/65536 seconds
+	DelaySinceLastRR uint32
+}
+
+// ExtendedReport is an RTCP XR packet, RFC 3611, with the report blocks
+// this package generates. Blocks of other types are skipped when it is
+// unmarshaled. pion/rtcp doesn't implement XR yet, it reads them as
+// rtcp.RawPacket, which can be passed to Unmarshal.
+type ExtendedReport struct {
+	SenderSSRC uint32
+
+	LossRLE []LossRLEReportBlock
+
+	// ReceiverReferenceTime is the NTP timestamp the report was sent at, to
+	// be answered by the senders with a DLRR block. Zero if the report has
+	// none.
+	ReceiverReferenceTime uint64
+
+	DLRR []DLRRReportBlock
+}
+
+var _ rtcp.Packet = (*ExtendedReport)(nil) // assert is a Packet
+
+// Marshal encodes the ExtendedReport in binary
+func (x ExtendedReport) Marshal() ([]byte, error) {
+	rawPacket := make([]byte, extendedReportHeaderLength)
+	binary.BigEndian.PutUint32(rawPacket[4:], x.SenderSSRC)
+
+	for _, block := range x.LossRLE {
+		if int(block.EndSeq-block.BeginSeq) != len(block.Received) {
+			return nil, errors.New("loss RLE report block doesn't cover its sequence numbers")
+		}
+		chunks := lossRLEChunks(block.Received)
+		body := make([]byte, lossRLEHeaderLength+len(chunks)*2)
+		binary.BigEndian.PutUint32(body, block.SSRC)
+		binary.BigEndian.PutUint16(body[4:], block.BeginSeq)
+		binary.BigEndian.PutUint16(body[6:], block.EndSeq)
+		for i, chunk := range chunks {
+			binary.BigEndian.PutUint16(body[lossRLEHeaderLength+i*2:], chunk)
+		}
+		rawPacket = appendExtendedReportBlock(rawPacket, extendedReportBlockLossRLE, body)
+	}
+
+	if x.ReceiverReferenceTime != 0 {
+		body := make([]byte, 8)
+		binary.BigEndian.PutUint64(body, x.ReceiverReferenceTime)
+		rawPacket = appendExtendedReportBlock(rawPacket, extendedReportBlockReceiverReferenceTime, body)
+	}
+
+	if len(x.DLRR) != 0 {
+		body := make([]byte, len(x.DLRR)*dlrrSubBlockLength)
+		for i, block := range x.DLRR {
+			binary.BigEndian.PutUint32(body[i*dlrrSubBlockLength:], block.SSRC)
+			binary.BigEndian.PutUint32(body[i*dlrrSubBlockLength+4:], block.LastRR)
+			binary.BigEndian.PutUint32(body[i*dlrrSubBlockLength+8:], block.DelaySinceLastRR)
+		}
+		rawPacket = appendExtendedReportBlock(rawPacket, extendedReportBlockDLRR, body)
+	}
+
+	h := rtcp.Header{
+		Type:   typeExtendedReport,
+		Length: uint16(len(rawPacket)/4 - 1),
+	}
+	hData, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	copy(rawPacket, hData)
+	return rawPacket, nil
+}
+
+// appendExtendedReportBlock appends a report block of type blockType with
+// body, whose length is a multiple of 32 bits, to rawPacket
+func appendExtendedReportBlock(rawPacket []byte, blockType uint8, body []byte) []byte {
+	header := make([]byte, extendedReportBlockHeaderLength)
+	header[0] = blockType
+	binary.BigEndian.PutUint16(header[2:], uint16(len(body)/4))
+	return append(append(rawPacket, header...), body...)
+}
+
+// lossRLEChunks encodes received as run length chunks, padded with a null
+// chunk to 32 bits
+func lossRLEChunks(received []bool) []uint16 {
+	var chunks []uint16
+	for i := 0; i < len(received); {
+		run := 1
+		for i+run < len(received) && received[i+run] == received[i] && run < lossRLEMaxRunLength {
+			run++
+		}
+		chunk := uint16(run)
+		if received[i] {
+			chunk |= 1 << 14
+		}
+		chunks = append(chunks, chunk)
+		i += run
+	}
+	if len(chunks)%2 != 0 {
+		chunks = append(chunks, 0)
+	}
+	return chunks
+}
+
+// Unmarshal decodes an ExtendedReport from binary
+func (x *ExtendedReport) Unmarshal(rawPacket []byte) error {
+	var h rtcp.Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+	if h.Type != typeExtendedReport {
+		return errors.New("packet is not an extended report")
+	}
+	length := int(h.Length+1) * 4
+	if length < extendedReportHeaderLength || len(rawPacket) < length {
+		return errors.New("packet too short to be an extended report")
+	}
+
+	*x = ExtendedReport{SenderSSRC: binary.BigEndian.Uint32(rawPacket[4:])}
+	for b := rawPacket[extendedReportHeaderLength:length]; len(b) >= extendedReportBlockHeaderLength; {
+		blockLength := extendedReportBlockHeaderLength + int(binary.BigEndian.Uint16(b[2:]))*4
+		if blockLength > len(b) {
+			return errors.New("extended report block exceeds the packet")
+		}
+		body := b[extendedReportBlockHeaderLength:blockLength]
+
+		switch b[0] {
+		case extendedReportBlockLossRLE:
+			if len(body) < lossRLEHeaderLength {
+				return errors.New("loss RLE report block too short")
+			}
+			x.LossRLE = append(x.LossRLE, unmarshalLossRLE(body))
+		case extendedReportBlockReceiverReferenceTime:
+			if len(body) < 8 {
+				return errors.New("receiver reference time report block too short")
+			}
+			x.ReceiverReferenceTime = binary.BigEndian.Uint64(body)
+		case extendedReportBlockDLRR:
+			for i := 0; i+dlrrSubBlockLength <= len(body); i += dlrrSubBlockLength {
+				x.DLRR = append(x.DLRR, DLRRReportBlock{
+					SSRC:             binary.BigEndian.Uint32(body[i:]),
+					LastRR:           binary.BigEndian.Uint32(body[i+4:]),
+					DelaySinceLastRR: binary.BigEndian.Uint32(body[i+8:]),
+				})
+			}
+		}
+		b = b[blockLength:]
+	}
+	return nil
+}
+
+// unmarshalLossRLE decodes the body of a Loss RLE report block, both run
+// length and bit vector chunks
+func unmarshalLossRLE(body []byte) LossRLEReportBlock {
+	block := LossRLEReportBlock{
+		SSRC:     binary.BigEndian.Uint32(body),
+		BeginSeq: binary.BigEndian.Uint16(body[4:]),
+		EndSeq:   binary.BigEndian.Uint16(body[6:]),
+	}
+	covered := int(block.EndSeq - block.BeginSeq)
+
+	for i := lossRLEHeaderLength; i+2 <= len(body) && len(block.Received) < covered; i += 2 {
+		chunk := binary.BigEndian.Uint16(body[i:])
+		switch {
+		case chunk == 0:
+			continue
+		case chunk&(1<<15) == 0:
+			received := chunk&(1<<14) != 0
+			for run := int(chunk & lossRLEMaxRunLength); run > 0; run-- {
+				block.Received = append(block.Received, received)
+			}
+		default:
+			for bit := lossRLEVectorLength - 1; bit >= 0; bit-- {
+				block.Received = append(block.Received, chunk&(1<<uint(bit)) != 0)
+			}
+		}
+	}
+	if len(block.Received) > covered {
+		block.Received = block.Received[:covered]
+	}
+	return block
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (x *ExtendedReport) DestinationSSRC() []uint32 {
+	ssrcs := make([]uint32, 0, len(x.LossRLE)+len(x.DLRR))
+	for _, block := range x.LossRLE {
+		ssrcs = append(ssrcs, block.SSRC)
+	}
+	for _, block := range x.DLRR {
+		ssrcs = append(ssrcs, block.SSRC)
+	}
+	return ssrcs
+}
@@ -0,0 +1,64 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendedReport(t *testing.T) {
+	received := make([]bool, 20000)
+	for i := range received {
+		received[i] = i%7 != 3 || i > 100
+	}
+	report := ExtendedReport{
+		SenderSSRC: 1,
+		LossRLE: []LossRLEReportBlock{{
+			SSRC:     2,
+			BeginSeq: 65000,
+			EndSeq:   65000 + uint16(len(received)),
+			Received: received,
+		}},
+		ReceiverReferenceTime: 0x0102030405060708,
+		DLRR:                  []DLRRReportBlock{{SSRC: 3, LastRR: 4, DelaySinceLastRR: 5}, {SSRC: 6, LastRR: 7, DelaySinceLastRR: 8}},
+	}
+
+	data, err := report.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(data)%4)
+	assert.Equal(t, byte(207), data[1])
+
+	parsed := &ExtendedReport{}
+	assert.NoError(t, parsed.Unmarshal(data))
+	assert.Equal(t, report, *parsed)
+	assert.Equal(t, []uint32{2, 3, 6}, parsed.DestinationSSRC())
+
+	report.LossRLE[0].EndSeq++
+	_, err = report.Marshal()
+	assert.Error(t, err)
+}
+
+func TestExtendedReportBitVector(t *testing.T) {
+	// A Loss RLE block of 18 packets: a bit vector chunk of 15 packets with
+	// the third one lost, and a run length chunk of 3 received packets
+	data := []byte{
+		0x80, 0xcf, 0x00, 0x05, 0x00, 0x00, 0x00, 0x01,
+		0x01, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x0a, 0x00, 0x1c, 0xef, 0xff, 0x40, 0x03,
+	}
+
+	report := &ExtendedReport{}
+	assert.NoError(t, report.Unmarshal(data))
+	if !assert.Len(t, report.LossRLE, 1) {
+		return
+	}
+	block := report.LossRLE[0]
+	assert.Equal(t, uint16(10), block.BeginSeq)
+	assert.Equal(t, uint16(28), block.EndSeq)
+	assert.Len(t, block.Received, 18)
+	for i, r := range block.Received {
+		assert.Equal(t, i != 2, r, "packet %d", i)
+	}
+
+	assert.Error(t, report.Unmarshal(data[:12]))
+}
@@ -0,0 +1,63 @@
+package webrtc
+
+// FrameMarkingURI is the URI of the frame marking header extension,
+// draft-ietf-avtext-framemarking. It tells where frames start and end, if
+// they are independent or discardable and which layer they belong to, so an
+// SFU can forward layers and detect key frames without parsing the payload.
+const FrameMarkingURI = "urn:ietf:params:rtp-hdrext:framemarking"
+
+// FrameMarking is the content of the frame marking header extension. The
+// layer fields are only sent for scalable streams.
+type FrameMarking struct {
+	StartOfFrame bool
+	EndOfFrame   bool
+	Independent  bool
+	Discardable  bool
+
+	Scalable      bool
+	BaseLayerSync bool
+	TemporalID    uint8
+	LayerID       uint8
+	TL0PICIDX     uint8
+}
+
+// Marshal encodes the frame marking, in the one byte form of non-scalable
+// streams or the three byte form of scalable ones
+func (f FrameMarking) Marshal() ([]byte, error) {
+	if f.TemporalID > 7 {
+		return nil, ErrInvalidFrameMarking
+	}
+
+	var b byte
+	for i, flag := range []bool{f.StartOfFrame, f.EndOfFrame, f.Independent, f.Discardable, f.BaseLayerSync} {
+		if flag {
+			b |= 0x80 >> uint(i)
+		}
+	}
+	if !f.Scalable {
+		return []byte{b & 0xF0}, nil
+	}
+	return []byte{b | f.TemporalID, f.LayerID, f.TL0PICIDX}, nil
+}
+
+// Unmarshal decodes a frame marking of either form
+func (f *FrameMarking) Unmarshal(b []byte) error {
+	if len(b) != 1 && len(b) != 3 {
+		return ErrInvalidFrameMarking
+	}
+
+	*f = FrameMarking{
+		StartOfFrame: b[0]&0x80 != 0,
+		EndOfFrame:   b[0]&0x40 != 0,
+		Independent:  b[0]&0x20 != 0,
+		Discardable:  b[0]&0x10 != 0,
+	}
+	if len(b) == 3 {
+		f.Scalable = true
+		f.BaseLayerSync = b[0]&0x08 != 0
+		f.TemporalID = b[0] & 0x07
+		f.LayerID = b[1]
+		f.TL0PICIDX = b[2]
+	}
+	return nil
+}
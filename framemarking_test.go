@@ -0,0 +1,85 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameMarking(t *testing.T) {
+	marking := FrameMarking{StartOfFrame: true, Independent: true}
+	b, err := marking.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xA0}, b)
+
+	var parsed FrameMarking
+	assert.NoError(t, parsed.Unmarshal(b))
+	assert.Equal(t, marking, parsed)
+
+	marking = FrameMarking{EndOfFrame: true, Discardable: true, Scalable: true, BaseLayerSync: true, TemporalID: 2, LayerID: 1, TL0PICIDX: 200}
+	b, err = marking.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x5A, 1, 200}, b)
+	assert.NoError(t, parsed.Unmarshal(b))
+	assert.Equal(t, marking, parsed)
+
+	_, err = FrameMarking{Scalable: true, TemporalID: 8}.Marshal()
+	assert.Equal(t, ErrInvalidFrameMarking, err)
+	assert.Equal(t, ErrInvalidFrameMarking, parsed.Unmarshal([]byte{0x80, 0x00}))
+}
+
+func TestTrackFrameMarking(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: FrameMarkingURI}, RTPCodecTypeVideo))
+	api := NewAPI(WithMediaEngine(m))
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrack := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		onTrack <- remote
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				// A VP8 key frame that fits into a single packet
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00, 0x01}, Samples: 1})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	remote := <-onTrack
+	pkt, err := remote.ReadRTP()
+	assert.NoError(t, err)
+	marking, ok := remote.FrameMarking(pkt)
+	assert.True(t, ok)
+	assert.Equal(t, FrameMarking{StartOfFrame: true, EndOfFrame: true, Independent: true}, marking)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,70 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtcp"
+)
+
+// formatFIR is the feedback message type of a Full Intra Request, RFC 5104 4.3.1
+const formatFIR uint8 = 4
+
+const fullIntraRequestLength = 20
+
+// fullIntraRequest asks the sender of a single SSRC for a decoder refresh
+// point. pion/rtcp doesn't implement it yet.
+type fullIntraRequest struct {
+	SenderSSRC     uint32
+	MediaSSRC      uint32
+	SequenceNumber uint8
+}
+
+var _ rtcp.Packet = (*fullIntraRequest)(nil) // assert is a Packet
+
+// Marshal encodes the fullIntraRequest in binary
+func (f fullIntraRequest) Marshal() ([]byte, error) {
+	h := rtcp.Header{
+		Count:  formatFIR,
+		Type:   rtcp.TypePayloadSpecificFeedback,
+		Length: fullIntraRequestLength/4 - 1,
+	}
+	hData, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// The media SSRC of the common header is unused, the FCI entry names the
+	// stream that has to refresh
+	rawPacket := make([]byte, fullIntraRequestLength)
+	copy(rawPacket, hData)
+	binary.BigEndian.PutUint32(rawPacket[4:], f.SenderSSRC)
+	binary.BigEndian.PutUint32(rawPacket[12:], f.MediaSSRC)
+	rawPacket[16] = f.SequenceNumber
+	return rawPacket, nil
+}
+
+// Unmarshal decodes a fullIntraRequest with a single FCI entry from binary
+func (f *fullIntraRequest) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < fullIntraRequestLength {
+		return errors.New("packet too short to be a full intra request")
+	}
+
+	var h rtcp.Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+	if h.Type != rtcp.TypePayloadSpecificFeedback || h.Count != formatFIR {
+		return errors.New("packet is not a full intra request")
+	}
+
+	f.SenderSSRC = binary.BigEndian.Uint32(rawPacket[4:])
+	f.MediaSSRC = binary.BigEndian.Uint32(rawPacket[12:])
+	f.SequenceNumber = rawPacket[16]
+	return nil
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (f *fullIntraRequest) DestinationSSRC() []uint32 {
+	return []uint32{f.MediaSSRC}
+}
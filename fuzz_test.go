@@ -0,0 +1,73 @@
+// +build !js,go1.18
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/sdp/v2"
+)
+
+// Data received from the remote, descriptions as well as RTP and RTCP, must
+// never panic. The seeds run with every go test, to fuzz run e.g.
+//
+//   go test -run XXX -fuzz FuzzRemoteDescription
+
+func FuzzRemoteDescription(f *testing.F) {
+	s := SettingEngine{}
+	s.SetTrickle(true)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	f.Add("v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\na=fingerprint:sha-256 AA:BB\r\na=ice-ufrag:a\r\na=ice-pwd:b\r\na=group:BUNDLE 0 1\r\n" +
+		"m=video 9 UDP/TLS/RTP/SAVPF 96 97\r\nc=IN IP4 0.0.0.0\r\na=mid:0\r\na=rtpmap:96 VP8/90000\r\na=rtpmap:97 rtx/90000\r\na=fmtp:97 apt=96\r\na=rtcp-mux\r\na=sendrecv\r\n" +
+		"a=ssrc-group:FID 1 2\r\na=ssrc:1 msid:a b\r\na=ssrc:2 msid:a b\r\na=candidate:1 1 udp 1 127.0.0.1 5000 typ host\r\n" +
+		"m=application 9 DTLS/SCTP 5000\r\na=mid:1\r\na=sctpmap:5000 webrtc-datachannel 1024\r\n")
+	f.Fuzz(func(t *testing.T, data string) {
+		if err := (&sdp.SessionDescription{}).Unmarshal([]byte(data)); err != nil {
+			return
+		}
+
+		pc, err := api.NewPeerConnection(Configuration{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pc.Close() //nolint:errcheck
+
+		if err := pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: data}); err != nil {
+			return
+		}
+		_, _ = pc.CreateAnswer(nil)
+	})
+}
+
+func FuzzRTP(f *testing.F) {
+	api := NewAPI(WithInterceptors(NoOpInterceptor{}))
+
+	f.Add([]byte{0x80, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x7c, 0x85, 0x01})
+	f.Add([]byte{0x90, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0xbe, 0xde, 0x00, 0x01, 0x10, 0xff, 0x00, 0x00, 0x10, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+			(&RTPReceiver{kind: kind}).updateStats(data)
+		}
+
+		b := make([]byte, receiveMTU+len(data))
+		_, _ = api.interceptors.readRTP(b, copy(b, data))
+
+		_, _ = (&h264Depacketizer{}).Unmarshal(data)
+		_, _ = (&codecs.VP8Packet{}).Unmarshal(data)
+		isKeyFrameStart(H264, data)
+		isKeyFrameStart(VP8, data)
+	})
+}
+
+func FuzzRTCP(f *testing.F) {
+	f.Add([]byte{0x80, 0xc8, 0x00, 0x06, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		(&RTPReceiver{}).updateClockMapper(data)
+		_, _ = rtcp.Unmarshal(data)
+	})
+}
@@ -0,0 +1,24 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+)
+
+// GatheringCompletePromise returns a channel that is closed once candidate
+// gathering of the PeerConnection is complete. It allows non-trickle
+// deployments to wait for a LocalDescription that contains all candidates:
+//
+//	gatherComplete := webrtc.GatheringCompletePromise(pc)
+//	if err := pc.SetLocalDescription(offer); err != nil {
+//		panic(err)
+//	}
+//	<-gatherComplete
+//	fmt.Println(pc.LocalDescription())
+func GatheringCompletePromise(pc *PeerConnection) (gatherComplete <-chan struct{}) {
+	gatheringComplete, done := context.WithCancel(context.Background())
+	pc.iceGatherer.onGatheringComplete(done)
+
+	return gatheringComplete.Done()
+}
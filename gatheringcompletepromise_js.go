@@ -0,0 +1,41 @@
+// +build js,wasm
+
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"syscall/js"
+)
+
+// GatheringCompletePromise returns a channel that is closed once candidate
+// gathering of the PeerConnection is complete. See the native implementation
+// for an example.
+func GatheringCompletePromise(pc *PeerConnection) (gatherComplete <-chan struct{}) {
+	gatheringComplete, done := context.WithCancel(context.Background())
+
+	// An event listener is used so the handler of OnICEGatheringStateChange
+	// isn't replaced
+	var once sync.Once
+	var onStateChange js.Func
+	complete := func() {
+		once.Do(func() {
+			pc.underlying.Call("removeEventListener", "icegatheringstatechange", onStateChange)
+			onStateChange.Release()
+			done()
+		})
+	}
+	onStateChange = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if pc.ICEGatheringState() == ICEGatheringStateComplete {
+			go complete()
+		}
+		return js.Undefined()
+	})
+	pc.underlying.Call("addEventListener", "icegatheringstatechange", onStateChange)
+
+	if pc.ICEGatheringState() == ICEGatheringStateComplete {
+		complete()
+	}
+
+	return gatheringComplete.Done()
+}
@@ -0,0 +1,37 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// healthReport is the document served by API.HealthHandler
+type healthReport struct {
+	PeerConnections []PeerConnectionSummary `json:"peerConnections"`
+}
+
+// HealthHandler returns an http.Handler that serves a JSON summary of every
+// PeerConnection of the API that isn't closed, for quick operational
+// debugging of media servers, e.g.
+//
+//	http.Handle("/debug/webrtc", api.HealthHandler())
+//
+// The summaries include the states, the selected candidate pair, the
+// bitrates of the tracks and the number of data channels, see
+// PeerConnectionSummary. The handler exposes the addresses of the peers, it
+// shouldn't be served publicly.
+func (api *API) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := healthReport{PeerConnections: []PeerConnectionSummary{}}
+		for _, pc := range api.peerConnections.list() {
+			report.PeerConnections = append(report.PeerConnections, pc.Summary())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			api.settingEngine.LoggerFactory.NewLogger("api").Warnf("Failed to write health report: %v", err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPI_HealthHandler(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+	pcOffer.SetLabel("offerer")
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	received := make(chan struct{})
+	pcAnswer.OnTrack(func(*Track, *RTPReceiver) {
+		close(received)
+	})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 90})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-received
+	for pcOffer.iceTransport.GetSelectedCandidatePair() == nil {
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	report := func() healthReport {
+		w := httptest.NewRecorder()
+		api.HealthHandler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		r := healthReport{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+		return r
+	}
+
+	r := report()
+	assert.Len(t, r.PeerConnections, 2)
+	for _, summary := range r.PeerConnections {
+		if summary.ID != pcOffer.ID() {
+			continue
+		}
+		assert.Equal(t, "offerer", summary.Label)
+		assert.Equal(t, "connected", summary.ICEConnectionState)
+		assert.Equal(t, "stable", summary.SignalingState)
+		assert.NotNil(t, summary.SelectedCandidatePair)
+		// signalPair opens a data channel
+		assert.Equal(t, 1, summary.DataChannels)
+		if assert.Len(t, summary.Tracks, 1) {
+			assert.Equal(t, "video", summary.Tracks[0].ID)
+			assert.Equal(t, "outbound", summary.Tracks[0].Direction)
+			assert.Equal(t, VP8, summary.Tracks[0].Codec)
+			assert.Equal(t, uint32(1234), summary.Tracks[0].SSRC)
+		}
+	}
+
+	// Closed PeerConnections aren't reported
+	assert.NoError(t, pcOffer.Close())
+	r = report()
+	if assert.Len(t, r.PeerConnections, 1) {
+		assert.Equal(t, pcAnswer.ID(), r.PeerConnections[0].ID)
+	}
+
+	assert.NoError(t, pcAnswer.Close())
+	assert.Empty(t, report().PeerConnections)
+}
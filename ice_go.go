@@ -2,13 +2,16 @@
 
 package webrtc
 
-import "github.com/pion/sdp/v2"
+import (
+	"github.com/pion/ice"
+	"github.com/pion/sdp/v2"
+)
 
 // NewICEGatherer creates a new NewICEGatherer.
 // This constructor is part of the ORTC API. It is not
 // meant to be used together with the basic WebRTC API.
 func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
-	return NewICEGatherer(
+	g, err := NewICEGatherer(
 		api.settingEngine.ephemeralUDP.PortMin,
 		api.settingEngine.ephemeralUDP.PortMax,
 		api.settingEngine.timeout.ICEConnection,
@@ -23,6 +26,28 @@ func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
 		api.settingEngine.candidates.ICENetworkTypes,
 		opts,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	g.candidateFilter = api.settingEngine.candidates.Filter
+	g.multicastDNSMode = api.settingEngine.candidates.ICEMulticastDNSMode.toICE()
+	return g, nil
+}
+
+// toICE converts the mode to the one of the ICE agent, zero keeps the
+// default of the agent
+func (m ICEMulticastDNSMode) toICE() ice.MulticastDNSMode {
+	switch m {
+	case ICEMulticastDNSModeDisabled:
+		return ice.MulticastDNSModeDisabled
+	case ICEMulticastDNSModeQueryOnly:
+		return ice.MulticastDNSModeQueryOnly
+	case ICEMulticastDNSModeQueryAndGather:
+		return ice.MulticastDNSModeQueryAndGather
+	default:
+		return 0
+	}
 }
 
 // NewICETransport creates a new NewICETransport.
@@ -162,3 +162,19 @@ func (c ICECandidate) ToJSON() ICECandidateInit {
 		SDPMLineIndex: &sdpmLineIndex,
 	}
 }
+
+// ICECandidateError is returned when a remote candidate can't be parsed or
+// added. Candidate is the candidate as it was signaled.
+type ICECandidateError struct {
+	Candidate string
+	Err       error
+}
+
+func (e *ICECandidateError) Error() string {
+	return fmt.Sprintf("ice candidate %q: %v", e.Candidate, e.Err)
+}
+
+// Unwrap returns the underlying Err
+func (e *ICECandidateError) Unwrap() error {
+	return e.Err
+}
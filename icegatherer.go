@@ -3,11 +3,13 @@
 package webrtc
 
 import (
+	"net"
 	"sync"
 	"time"
 
 	"github.com/pion/ice"
 	"github.com/pion/logging"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
 // ICEGatherer gathers local host, server reflexive and relay
@@ -37,8 +39,18 @@ type ICEGatherer struct {
 	log                       logging.LeveledLogger
 	networkTypes              []NetworkType
 
+	// candidateFilter drops local and remote candidates, see
+	// SettingEngine.SetCandidateFilter
+	candidateFilter func(ICECandidate) bool
+
+	// multicastDNSMode of the agent, see SettingEngine.SetICEMulticastDNSMode
+	multicastDNSMode ice.MulticastDNSMode
+
 	onLocalCandidateHdlr func(candidate *ICECandidate)
 	onStateChangeHdlr    func(state ICEGathererState)
+
+	// Called once gathering is complete, see GatheringCompletePromise
+	onGatheringCompleteHdlrs []func()
 }
 
 // NewICEGatherer creates a new NewICEGatherer.
@@ -57,23 +69,12 @@ func NewICEGatherer(
 	networkTypes []NetworkType,
 	opts ICEGatherOptions,
 ) (*ICEGatherer, error) {
-	var validatedServers []*ice.URL
-	if len(opts.ICEServers) > 0 {
-		for _, server := range opts.ICEServers {
-			url, err := server.urls()
-			if err != nil {
-				return nil, err
-			}
-			validatedServers = append(validatedServers, url...)
-		}
-	}
-
-	candidateTypes := []ice.CandidateType{}
-	if opts.ICEGatherPolicy == ICETransportPolicyRelay {
-		candidateTypes = append(candidateTypes, ice.CandidateTypeRelay)
+	validatedServers, candidateTypes, err := gatherOptions(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return &ICEGatherer{
+	g := &ICEGatherer{
 		state:                     ICEGathererStateNew,
 		validatedServers:          validatedServers,
 		portMin:                   portMin,
@@ -90,7 +91,84 @@ func NewICEGatherer(
 		srflxAcceptanceMinWait:    srflxAcceptanceMinWait,
 		prflxAcceptanceMinWait:    prflxAcceptanceMinWait,
 		relayAcceptanceMinWait:    relayAcceptanceMinWait,
-	}, nil
+	}
+
+	g.warnUnsupportedServers(validatedServers)
+	if opts.ICEGatherPolicy == ICETransportPolicyRelay && !hasRelayServer(validatedServers) {
+		g.log.Warn("ICETransportPolicyRelay is used without a TURN server, no candidates will be gathered")
+	}
+
+	return g, nil
+}
+
+// gatherOptions returns the servers and candidate types opts gather with
+func gatherOptions(opts ICEGatherOptions) ([]*ice.URL, []ice.CandidateType, error) {
+	var validatedServers []*ice.URL
+	for _, server := range opts.ICEServers {
+		url, err := server.urls()
+		if err != nil {
+			return nil, nil, err
+		}
+		validatedServers = append(validatedServers, url...)
+	}
+
+	candidateTypes := []ice.CandidateType{}
+	if opts.ICEGatherPolicy == ICETransportPolicyRelay {
+		candidateTypes = append(candidateTypes, ice.CandidateTypeRelay)
+	}
+	return validatedServers, candidateTypes, nil
+}
+
+// setOptions replaces the servers and the gather policy. They are used once
+// the agent is created, an agent that already gathers keeps its options.
+func (g *ICEGatherer) setOptions(opts ICEGatherOptions) error {
+	validatedServers, candidateTypes, err := gatherOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	g.warnUnsupportedServers(validatedServers)
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.validatedServers = validatedServers
+	g.candidateTypes = candidateTypes
+	return nil
+}
+
+// udpPortAvailable tells if no other socket is bound to port
+func udpPortAvailable(port uint16) bool {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: int(port)})
+	if err != nil {
+		return false
+	}
+	return conn.Close() == nil
+}
+
+// withoutSTUNServers drops the STUN servers from urls. Server reflexive
+// candidates are gathered from another socket than host candidates, which
+// can't be bound when all candidates have to use the same port.
+func (g *ICEGatherer) withoutSTUNServers(urls []*ice.URL) []*ice.URL {
+	var filtered []*ice.URL
+	for _, u := range urls {
+		if u.Scheme == ice.SchemeTypeSTUN || u.Scheme == ice.SchemeTypeSTUNS {
+			g.log.Warnf("the UDP port is static, no server reflexive candidates will be gathered from %s", u)
+			continue
+		}
+		filtered = append(filtered, u)
+	}
+	return filtered
+}
+
+// warnUnsupportedServers logs the servers the ICE agent doesn't gather from.
+// It only allocates relays over UDP, turns: servers are skipped, so there is
+// no TLS to configure for them.
+func (g *ICEGatherer) warnUnsupportedServers(urls []*ice.URL) {
+	for _, u := range urls {
+		if u.Scheme == ice.SchemeTypeTURNS {
+			g.log.Warnf("TURN over TLS is not supported, no relay candidates will be gathered from %s", u)
+		}
+	}
 }
 
 func (g *ICEGatherer) createAgent() error {
@@ -101,9 +179,17 @@ func (g *ICEGatherer) createAgent() error {
 		return nil
 	}
 
+	urls := g.validatedServers
+	if g.portMin != 0 && g.portMin == g.portMax {
+		if !udpPortAvailable(g.portMin) {
+			return &rtcerr.OperationError{Err: ErrUDPPortInUse}
+		}
+		urls = g.withoutSTUNServers(urls)
+	}
+
 	config := &ice.AgentConfig{
 		Trickle:                   g.agentIsTrickle,
-		Urls:                      g.validatedServers,
+		Urls:                      urls,
 		PortMin:                   g.portMin,
 		PortMax:                   g.portMax,
 		ConnectionTimeout:         g.connectionTimeout,
@@ -115,6 +201,7 @@ func (g *ICEGatherer) createAgent() error {
 		SrflxAcceptanceMinWait:    g.srflxAcceptanceMinWait,
 		PrflxAcceptanceMinWait:    g.prflxAcceptanceMinWait,
 		RelayAcceptanceMinWait:    g.relayAcceptanceMinWait,
+		MulticastDNSMode:          g.multicastDNSMode,
 	}
 
 	requestedNetworkTypes := g.networkTypes
@@ -139,6 +226,31 @@ func (g *ICEGatherer) createAgent() error {
 	return nil
 }
 
+// restart replaces the agent by one with new credentials for an ICE restart
+// and returns the previous agent. It isn't closed, the ICETransport keeps
+// using it until the new agent is connected.
+func (g *ICEGatherer) restart() (*ice.Agent, error) {
+	g.lock.Lock()
+	previous := g.agent
+	g.agent = nil
+	g.state = ICEGathererStateNew
+	g.lock.Unlock()
+
+	return previous, g.createAgent()
+}
+
+// revert puts back agent, the one the gatherer had before restart, and
+// returns the agent of the restart
+func (g *ICEGatherer) revert(agent *ice.Agent) *ice.Agent {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	restarted := g.agent
+	g.agent = agent
+	g.state = ICEGathererStateComplete
+	return restarted
+}
+
 // Gather ICE candidates.
 func (g *ICEGatherer) Gather() error {
 	if err := g.createAgent(); err != nil {
@@ -162,11 +274,17 @@ func (g *ICEGatherer) Gather() error {
 	g.setState(ICEGathererStateGathering)
 	if err := agent.OnCandidate(func(candidate ice.Candidate) {
 		if candidate != nil {
+			if !g.candidateAllowed(candidate) {
+				return
+			}
 			c, err := newICECandidateFromICE(candidate)
 			if err != nil {
 				g.log.Warnf("Failed to convert ice.Candidate: %s", err)
 				return
 			}
+			if !g.candidateFiltered(c) {
+				return
+			}
 			onLocalCandidateHdlr(&c)
 		} else {
 			g.setState(ICEGathererStateComplete)
@@ -220,7 +338,53 @@ func (g *ICEGatherer) GetLocalCandidates() ([]ICECandidate, error) {
 		return nil, err
 	}
 
-	return newICECandidatesFromICE(iceCandidates)
+	var allowed []ice.Candidate
+	for _, c := range iceCandidates {
+		if g.candidateAllowed(c) {
+			allowed = append(allowed, c)
+		}
+	}
+
+	candidates, err := newICECandidatesFromICE(allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []ICECandidate
+	for _, c := range candidates {
+		if g.candidateFiltered(c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+// candidateFiltered returns false for candidates the candidate filter drops
+func (g *ICEGatherer) candidateFiltered(c ICECandidate) bool {
+	return g.candidateFilter == nil || g.candidateFilter(c)
+}
+
+// candidateAllowed returns false for candidates that must not be signaled
+// because of the gather policy, e.g. host candidates with ICETransportPolicyRelay
+func (g *ICEGatherer) candidateAllowed(c ice.Candidate) bool {
+	if len(g.candidateTypes) == 0 {
+		return true
+	}
+	for _, t := range g.candidateTypes {
+		if c.Type() == t {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRelayServer(urls []*ice.URL) bool {
+	for _, u := range urls {
+		if u.Scheme == ice.SchemeTypeTURN {
+			return true
+		}
+	}
+	return false
 }
 
 // OnLocalCandidate sets an event handler which fires when a new local ICE candidate is available
@@ -248,11 +412,33 @@ func (g *ICEGatherer) setState(s ICEGathererState) {
 	g.lock.Lock()
 	g.state = s
 	hdlr := g.onStateChangeHdlr
+	var completeHdlrs []func()
+	if s == ICEGathererStateComplete {
+		completeHdlrs = g.onGatheringCompleteHdlrs
+		g.onGatheringCompleteHdlrs = nil
+	}
 	g.lock.Unlock()
 
 	if hdlr != nil {
 		go hdlr(s)
 	}
+	for _, f := range completeHdlrs {
+		f()
+	}
+}
+
+// onGatheringComplete registers f to be called once gathering is complete.
+// f is called right away if gathering has already completed.
+func (g *ICEGatherer) onGatheringComplete(f func()) {
+	g.lock.Lock()
+	if g.state != ICEGathererStateComplete {
+		g.onGatheringCompleteHdlrs = append(g.onGatheringCompleteHdlrs, f)
+		g.lock.Unlock()
+		return
+	}
+	g.lock.Unlock()
+
+	f()
 }
 
 func (g *ICEGatherer) getAgent() *ice.Agent {
@@ -284,6 +470,21 @@ func (g *ICEGatherer) SignalCandidates() error {
 	return nil
 }
 
+// roundTripTime returns the round trip time of the nominated candidate
+// pair, zero if it isn't known
+func (g *ICEGatherer) roundTripTime() time.Duration {
+	agent := g.getAgent()
+	if agent == nil {
+		return 0
+	}
+	for _, pair := range agent.GetCandidatePairsStats() {
+		if pair.Nominated {
+			return time.Duration(pair.CurrentRoundTripTime * float64(time.Second))
+		}
+	}
+	return 0
+}
+
 func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 	collector.Collecting()
 
@@ -3,11 +3,14 @@
 package webrtc
 
 import (
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/pion/ice"
 	"github.com/pion/logging"
 	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewICEGatherer_Success(t *testing.T) {
@@ -60,3 +63,128 @@ func TestNewICEGatherer_Success(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestNewICEGatherer_RelayPolicy(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	opts := ICEGatherOptions{ICEGatherPolicy: ICETransportPolicyRelay}
+
+	gatherer, err := NewICEGatherer(0, 0, nil, nil, nil, nil, nil, nil, nil, logging.NewDefaultLoggerFactory(), false, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a TURN server there is nothing that may be exposed
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(candidates))
+
+	host, err := ice.NewCandidateHost(&ice.CandidateHostConfig{
+		Network:   "udp",
+		Address:   "192.168.0.1",
+		Port:      5000,
+		Component: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gatherer.candidateAllowed(host))
+
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestNewICEGatherer_TURNS(t *testing.T) {
+	var warnings []string
+	loggerFactory := testCatchAllLoggerFactory{callback: func(msg string) {
+		warnings = append(warnings, msg)
+	}}
+	opts := ICEGatherOptions{
+		ICEServers: []ICEServer{{
+			URLs:       []string{"turns:turn.example.com:5349"},
+			Username:   "unittest",
+			Credential: "placeholder",
+		}},
+		ICEGatherPolicy: ICETransportPolicyRelay,
+	}
+
+	gatherer, err := NewICEGatherer(0, 0, nil, nil, nil, nil, nil, nil, nil, loggerFactory, false, nil, opts)
+	assert.NoError(t, err)
+
+	// turns: servers aren't gathered from, they don't count as relays
+	if assert.Len(t, warnings, 2) {
+		assert.Contains(t, warnings[0], "TURN over TLS is not supported")
+		assert.Contains(t, warnings[1], "without a TURN server")
+	}
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestICEGatherer_CandidateFilter(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Only candidates of 10.0.0.0/8 are kept
+	s := SettingEngine{}
+	s.SetCandidateFilter(func(c ICECandidate) bool {
+		return strings.HasPrefix(c.Address, "10.")
+	})
+	api := NewAPI(WithSettingEngine(s))
+
+	gatherer, err := api.NewICEGatherer(ICEGatherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := api.NewICETransport(gatherer)
+
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	for _, c := range candidates {
+		assert.True(t, strings.HasPrefix(c.Address, "10."), c.Address)
+	}
+
+	remote := []ICECandidate{
+		{Foundation: "1", Priority: 1, Address: "10.0.0.2", Protocol: ICEProtocolUDP, Port: 5000, Component: 1, Typ: ICECandidateTypeHost},
+		{Foundation: "2", Priority: 1, Address: "169.254.0.2", Protocol: ICEProtocolUDP, Port: 5000, Component: 1, Typ: ICECandidateTypeHost},
+	}
+	assert.NoError(t, transport.SetRemoteCandidates(remote[:1]))
+	assert.NoError(t, transport.AddRemoteCandidate(remote[1]))
+
+	remoteStats := gatherer.getAgent().GetRemoteCandidatesStats()
+	if assert.Equal(t, 1, len(remoteStats)) {
+		assert.Equal(t, "10.0.0.2", remoteStats[0].IP)
+	}
+
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestICEGatherer_MulticastDNS(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	assert.NoError(t, s.SetICEMulticastDNSMode(ICEMulticastDNSModeQueryAndGather))
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	api := NewAPI(WithSettingEngine(s))
+
+	gatherer, err := api.NewICEGatherer(ICEGatherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, gatherer.Gather())
+
+	// Host candidates are signaled with the generated name instead of the
+	// address of the interface
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, candidates)
+	for _, c := range candidates {
+		if c.Typ == ICECandidateTypeHost {
+			assert.True(t, strings.HasSuffix(c.Address, ".local"), c.Address)
+		}
+	}
+
+	assert.NoError(t, gatherer.Close())
+}
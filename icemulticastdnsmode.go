@@ -0,0 +1,57 @@
+package webrtc
+
+// ICEMulticastDNSMode controls how the ICE agent uses mDNS, see
+// SettingEngine.SetICEMulticastDNSMode
+type ICEMulticastDNSMode int
+
+const (
+	// ICEMulticastDNSModeDisabled discards remote candidates with mDNS
+	// names and signals the IP addresses of the local host candidates
+	ICEMulticastDNSModeDisabled ICEMulticastDNSMode = iota + 1
+
+	// ICEMulticastDNSModeQueryOnly resolves remote candidates with mDNS
+	// names, as browsers signal them, and signals the IP addresses of the
+	// local host candidates. This is the default.
+	ICEMulticastDNSModeQueryOnly
+
+	// ICEMulticastDNSModeQueryAndGather resolves remote candidates with
+	// mDNS names and signals the local host candidates with a generated
+	// mDNS name instead of their IP addresses, the way browsers keep the
+	// addresses of the local network private
+	ICEMulticastDNSModeQueryAndGather
+)
+
+// This is done this way because of a linter.
+const (
+	iceMulticastDNSModeDisabledStr       = "disabled"
+	iceMulticastDNSModeQueryOnlyStr      = "query-only"
+	iceMulticastDNSModeQueryAndGatherStr = "query-and-gather"
+)
+
+// NewICEMulticastDNSMode takes a string and converts it to
+// ICEMulticastDNSMode
+func NewICEMulticastDNSMode(raw string) ICEMulticastDNSMode {
+	switch raw {
+	case iceMulticastDNSModeDisabledStr:
+		return ICEMulticastDNSModeDisabled
+	case iceMulticastDNSModeQueryOnlyStr:
+		return ICEMulticastDNSModeQueryOnly
+	case iceMulticastDNSModeQueryAndGatherStr:
+		return ICEMulticastDNSModeQueryAndGather
+	default:
+		return ICEMulticastDNSMode(Unknown)
+	}
+}
+
+func (m ICEMulticastDNSMode) String() string {
+	switch m {
+	case ICEMulticastDNSModeDisabled:
+		return iceMulticastDNSModeDisabledStr
+	case ICEMulticastDNSModeQueryOnly:
+		return iceMulticastDNSModeQueryOnlyStr
+	case ICEMulticastDNSModeQueryAndGather:
+		return iceMulticastDNSModeQueryAndGatherStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
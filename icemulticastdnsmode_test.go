@@ -0,0 +1,47 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewICEMulticastDNSMode(t *testing.T) {
+	testCases := []struct {
+		modeString   string
+		expectedMode ICEMulticastDNSMode
+	}{
+		{unknownStr, ICEMulticastDNSMode(Unknown)},
+		{"disabled", ICEMulticastDNSModeDisabled},
+		{"query-only", ICEMulticastDNSModeQueryOnly},
+		{"query-and-gather", ICEMulticastDNSModeQueryAndGather},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedMode,
+			NewICEMulticastDNSMode(testCase.modeString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestICEMulticastDNSMode_String(t *testing.T) {
+	testCases := []struct {
+		mode           ICEMulticastDNSMode
+		expectedString string
+	}{
+		{ICEMulticastDNSMode(Unknown), unknownStr},
+		{ICEMulticastDNSModeDisabled, "disabled"},
+		{ICEMulticastDNSModeQueryOnly, "query-only"},
+		{ICEMulticastDNSModeQueryAndGather, "query-and-gather"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.mode.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -0,0 +1,53 @@
+package webrtc
+
+// ICENominationStrategy decides when the controlling ICE agent nominates a
+// candidate pair, see SettingEngine.SetICENominationStrategy. Either way the
+// nominated pair is kept for the connection, the ICE agent doesn't
+// renominate.
+type ICENominationStrategy int
+
+const (
+	// ICENominationStrategyRegular waits, after the first check succeeded,
+	// for pairs of preferred candidate types: up to half a second for
+	// server reflexive, a second for peer reflexive and two seconds for
+	// relayed pairs. Setup takes longer when only the less preferred pairs
+	// work, the nominated path is the better one when several do.
+	ICENominationStrategyRegular ICENominationStrategy = iota + 1
+
+	// ICENominationStrategyAggressive nominates the best pair as soon as a
+	// check succeeded. Setup is as fast as the network allows, but a
+	// relayed pair may be nominated over a direct one whose check was
+	// still in flight. This isn't aggressive nomination of ICE, the checks
+	// don't carry USE-CANDIDATE.
+	ICENominationStrategyAggressive
+)
+
+// This is done this way because of a linter.
+const (
+	iceNominationStrategyRegularStr    = "regular"
+	iceNominationStrategyAggressiveStr = "aggressive"
+)
+
+// NewICENominationStrategy takes a string and converts it to
+// ICENominationStrategy
+func NewICENominationStrategy(raw string) ICENominationStrategy {
+	switch raw {
+	case iceNominationStrategyRegularStr:
+		return ICENominationStrategyRegular
+	case iceNominationStrategyAggressiveStr:
+		return ICENominationStrategyAggressive
+	default:
+		return ICENominationStrategy(Unknown)
+	}
+}
+
+func (s ICENominationStrategy) String() string {
+	switch s {
+	case ICENominationStrategyRegular:
+		return iceNominationStrategyRegularStr
+	case ICENominationStrategyAggressive:
+		return iceNominationStrategyAggressiveStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
@@ -0,0 +1,45 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewICENominationStrategy(t *testing.T) {
+	testCases := []struct {
+		strategyString   string
+		expectedStrategy ICENominationStrategy
+	}{
+		{unknownStr, ICENominationStrategy(Unknown)},
+		{"regular", ICENominationStrategyRegular},
+		{"aggressive", ICENominationStrategyAggressive},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedStrategy,
+			NewICENominationStrategy(testCase.strategyString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestICENominationStrategy_String(t *testing.T) {
+	testCases := []struct {
+		strategy       ICENominationStrategy
+		expectedString string
+	}{
+		{ICENominationStrategy(Unknown), unknownStr},
+		{ICENominationStrategyRegular, "regular"},
+		{ICENominationStrategyAggressive, "aggressive"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.strategy.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -66,3 +66,13 @@ func (s ICEServer) urls() ([]*ice.URL, error) {
 
 	return urls, nil
 }
+
+// cloneICEServers copies servers so that the copy doesn't share their URLs
+func cloneICEServers(servers []ICEServer) []ICEServer {
+	cloned := make([]ICEServer, len(servers))
+	for i, s := range servers {
+		s.URLs = append([]string{}, s.URLs...)
+		cloned[i] = s
+	}
+	return cloned
+}
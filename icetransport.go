@@ -5,6 +5,7 @@ package webrtc
 import (
 	"context"
 	"errors"
+	"net"
 	"sync"
 
 	"github.com/pion/ice"
@@ -25,12 +26,17 @@ type ICETransport struct {
 	onConnectionStateChangeHdlr       func(ICETransportState)
 	onSelectedCandidatePairChangeHdlr func(*ICECandidatePair)
 
-	state ICETransportState
+	state        ICETransportState
+	selectedPair *ICECandidatePair
 
 	gatherer *ICEGatherer
-	conn     *ice.Conn
+	conn     *restartableConn
 	mux      *mux.Mux
 
+	// agent the transport is connected through, a restart replaces it once
+	// the agent of the gatherer is connected
+	agent *ice.Agent
+
 	loggerFactory logging.LoggerFactory
 
 	log logging.LeveledLogger
@@ -44,10 +50,6 @@ type ICETransport struct {
 //
 // }
 //
-// func (t *ICETransport) GetSelectedCandidatePair() ICECandidatePair {
-//
-// }
-//
 // func (t *ICETransport) GetLocalParameters() ICEParameters {
 //
 // }
@@ -79,10 +81,83 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 		return err
 	}
 
-	agent := t.gatherer.agent
+	agent := t.gatherer.getAgent()
+	if agent == nil {
+		return errors.New("ICEAgent does not exist, the gatherer has been closed")
+	}
+	if err := t.watchAgent(agent); err != nil {
+		return err
+	}
+	t.agent = agent
+
+	if role == nil {
+		controlled := ICERoleControlled
+		role = &controlled
+	}
+	t.role = *role
+
+	// Drop the lock here to allow trickle-ICE candidates to be
+	// added so that the agent can complete a connection
+	t.lock.Unlock()
+
+	iceConn, err := connectAgent(agent, *role, params)
+
+	// Reacquire the lock to set the connection/mux
+	t.lock.Lock()
+	if err != nil {
+		return err
+	}
+
+	t.conn = &restartableConn{conn: iceConn}
+
+	config := mux.Config{
+		Conn:          t.conn,
+		BufferSize:    receiveMTU,
+		LoggerFactory: t.loggerFactory,
+	}
+	t.mux = mux.NewMux(config)
+
+	return nil
+}
+
+// StartWithConn starts the transport over a connection that has been
+// established without ICE, e.g. a UDP socket of a DTLS forwarder or a custom
+// packet transport. No candidates are gathered or checked, the DTLSTransport,
+// SCTPTransport and RTP layers run on top of conn as they would over ICE.
+// conn has to preserve packet boundaries. role decides the DTLS role the same
+// way as the ICE role does, the controlling side is the DTLS server unless the
+// remote parameters say otherwise.
+func (t *ICETransport) StartWithConn(conn net.Conn, role ICERole) error {
+	t.lock.Lock()
+	if t.mux != nil {
+		t.lock.Unlock()
+		return errors.New("ICETransport has already been started")
+	}
+
+	t.role = role
+	t.conn = &restartableConn{conn: conn}
+	t.mux = mux.NewMux(mux.Config{
+		Conn:          t.conn,
+		BufferSize:    receiveMTU,
+		LoggerFactory: t.loggerFactory,
+	})
+	t.state = ICETransportStateConnected
+	t.lock.Unlock()
+
+	t.onConnectionStateChange(ICETransportStateConnected)
+	return nil
+}
+
+// watchAgent forwards the events of agent. Connection state changes are only
+// forwarded while the transport is connected through the agent.
+func (t *ICETransport) watchAgent(agent *ice.Agent) error {
 	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
 		state := newICETransportStateFromICE(iceState)
 		t.lock.Lock()
+		if t.agent != agent {
+			t.lock.Unlock()
+			return
+		}
 		t.state = state
 		t.lock.Unlock()
 
@@ -90,60 +165,111 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	}); err != nil {
 		return err
 	}
-	if err := agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
+	return agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
 		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
 		if err != nil {
 			t.log.Warnf("Unable to convert ICE candidates to ICECandidates: %s", err)
 			return
 		}
 		t.onSelectedCandidatePairChange(NewICECandidatePair(&candidates[0], &candidates[1]))
-	}); err != nil {
-		return err
-	}
+	})
+}
 
-	if role == nil {
-		controlled := ICERoleControlled
-		role = &controlled
+// connectAgent starts the connectivity checks of agent and blocks until a
+// candidate pair is connected
+func connectAgent(agent *ice.Agent, role ICERole, params ICEParameters) (*ice.Conn, error) {
+	switch role {
+	case ICERoleControlling:
+		return agent.Dial(context.TODO(), params.UsernameFragment, params.Password)
+	case ICERoleControlled:
+		return agent.Accept(context.TODO(), params.UsernameFragment, params.Password)
+	default:
+		return nil, errors.New("unknown ICE Role")
 	}
-	t.role = *role
+}
 
-	// Drop the lock here to allow trickle-ICE candidates to be
-	// added so that the agent can complete a connection
-	t.lock.Unlock()
+// restartGatherer makes the gatherer create an agent with new credentials
+// for an ICE restart. The agent of a previous restart that isn't connected
+// yet is closed, the agent the transport is connected through keeps running.
+func (t *ICETransport) restartGatherer() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
 
-	var iceConn *ice.Conn
-	var err error
-	switch *role {
-	case ICERoleControlling:
-		iceConn, err = agent.Dial(context.TODO(),
-			params.UsernameFragment,
-			params.Password)
+	if t.gatherer == nil {
+		return errors.New("gatherer not started")
+	}
+	previous, err := t.gatherer.restart()
+	if previous != nil && previous != t.agent {
+		if closeErr := previous.Close(); closeErr != nil {
+			t.log.Warnf("Failed to close ICE agent of a previous restart: %v", closeErr)
+		}
+	}
+	return err
+}
 
-	case ICERoleControlled:
-		iceConn, err = agent.Accept(context.TODO(),
-			params.UsernameFragment,
-			params.Password)
+// cancelRestart closes the agent of an ICE restart that wasn't answered and
+// gives the gatherer back the agent the transport is connected through
+func (t *ICETransport) cancelRestart() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
 
-	default:
-		err = errors.New("unknown ICE Role")
+	if t.gatherer == nil || t.agent == nil || t.gatherer.getAgent() == t.agent {
+		return
+	}
+	if restarted := t.gatherer.revert(t.agent); restarted != nil {
+		if err := restarted.Close(); err != nil {
+			t.log.Warnf("Failed to close ICE agent of a canceled restart: %v", err)
+		}
 	}
+}
 
-	// Reacquire the lock to set the connection/mux
+// restart connects the agent created by restartGatherer to the remote with
+// the new remote params. The transport moves over to it once it is
+// connected and the previous agent is closed, the remote has to switch to
+// its new credentials by then. It blocks until the new agent is connected.
+func (t *ICETransport) restart(params ICEParameters) error {
 	t.lock.Lock()
-	if err != nil {
+	agent := t.gatherer.getAgent()
+	switch {
+	case t.agent == nil:
+		t.lock.Unlock()
+		return errors.New("ICETransport has not been connected through ICE")
+	case agent == nil:
+		t.lock.Unlock()
+		return errors.New("ICEAgent does not exist, the gatherer has been closed")
+	case agent == t.agent:
+		t.lock.Unlock()
+		return errors.New("the gatherer has not been restarted")
+	}
+	if err := t.watchAgent(agent); err != nil {
+		t.lock.Unlock()
 		return err
 	}
+	role := t.role
+	t.lock.Unlock()
 
-	t.conn = iceConn
+	iceConn, err := connectAgent(agent, role, params)
+	if err != nil {
+		return err
+	}
 
-	config := mux.Config{
-		Conn:          t.conn,
-		BufferSize:    receiveMTU,
-		LoggerFactory: t.loggerFactory,
+	t.lock.Lock()
+	if t.gatherer.getAgent() != agent {
+		// Another restart replaced the agent meanwhile
+		t.lock.Unlock()
+		return iceConn.Close()
 	}
-	t.mux = mux.NewMux(config)
+	previous := t.agent
+	t.agent = agent
+	t.conn.swap(iceConn)
+	changed := t.state != ICETransportStateConnected
+	t.state = ICETransportStateConnected
+	t.lock.Unlock()
 
-	return nil
+	if changed {
+		t.onConnectionStateChange(ICETransportStateConnected)
+	}
+	return previous.Close()
 }
 
 // Stop irreversibly stops the ICETransport.
@@ -152,6 +278,15 @@ func (t *ICETransport) Stop() error {
 	defer t.lock.Unlock()
 
 	if t.mux != nil {
+		// The agent of a restart that isn't connected yet isn't used by
+		// the mux
+		if t.gatherer != nil {
+			if agent := t.gatherer.getAgent(); agent != nil && agent != t.agent {
+				if err := agent.Close(); err != nil {
+					t.log.Warnf("Failed to close ICE agent of a restart: %v", err)
+				}
+			}
+		}
 		return t.mux.Close()
 	} else if t.gatherer != nil {
 		return t.gatherer.Close()
@@ -159,6 +294,14 @@ func (t *ICETransport) Stop() error {
 	return nil
 }
 
+// GetSelectedCandidatePair returns the candidate pair packets are sent
+// over, nil until a pair is selected
+func (t *ICETransport) GetSelectedCandidatePair() *ICECandidatePair {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.selectedPair
+}
+
 // OnSelectedCandidatePairChange sets a handler that is invoked when a new
 // ICE candidate pair is selected
 func (t *ICETransport) OnSelectedCandidatePairChange(f func(*ICECandidatePair)) {
@@ -168,9 +311,10 @@ func (t *ICETransport) OnSelectedCandidatePairChange(f func(*ICECandidatePair))
 }
 
 func (t *ICETransport) onSelectedCandidatePairChange(pair *ICECandidatePair) {
-	t.lock.RLock()
+	t.lock.Lock()
+	t.selectedPair = pair
 	hdlr := t.onSelectedCandidatePairChangeHdlr
-	t.lock.RUnlock()
+	t.lock.Unlock()
 	if hdlr != nil {
 		hdlr(pair)
 	}
@@ -211,11 +355,15 @@ func (t *ICETransport) SetRemoteCandidates(remoteCandidates []ICECandidate) erro
 	}
 
 	for _, c := range remoteCandidates {
+		if !t.gatherer.candidateFiltered(c) {
+			t.log.Debugf("candidate filter dropped remote candidate %s", c)
+			continue
+		}
 		i, err := c.toICE()
 		if err != nil {
 			return err
 		}
-		err = t.gatherer.agent.AddRemoteCandidate(i)
+		err = t.gatherer.getAgent().AddRemoteCandidate(i)
 		if err != nil {
 			return err
 		}
@@ -233,11 +381,16 @@ func (t *ICETransport) AddRemoteCandidate(remoteCandidate ICECandidate) error {
 		return err
 	}
 
+	if !t.gatherer.candidateFiltered(remoteCandidate) {
+		t.log.Debugf("candidate filter dropped remote candidate %s", remoteCandidate)
+		return nil
+	}
+
 	c, err := remoteCandidate.toICE()
 	if err != nil {
 		return err
 	}
-	err = t.gatherer.agent.AddRemoteCandidate(c)
+	err = t.gatherer.getAgent().AddRemoteCandidate(c)
 	if err != nil {
 		return err
 	}
@@ -4,11 +4,13 @@ package webrtc
 
 import (
 	"math/rand"
+	"net"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestICETransport_OnSelectedCandidatePairChange(t *testing.T) {
@@ -72,3 +74,67 @@ func TestICETransport_OnSelectedCandidatePairChange(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestICETransport_StartWithConn(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	type stack struct {
+		ice  *ICETransport
+		dtls *DTLSTransport
+		sctp *SCTPTransport
+	}
+	newStack := func(api *API) *stack {
+		ice := api.NewICETransport(nil)
+		dtls, err := api.NewDTLSTransport(ice, nil)
+		assert.NoError(t, err)
+		return &stack{ice: ice, dtls: dtls, sctp: api.NewSCTPTransport(dtls)}
+	}
+
+	// A pipe stands in for any packet transport that isn't ICE
+	connA, connB := net.Pipe()
+	apiA, apiB := NewAPI(), NewAPI()
+	stackA, stackB := newStack(apiA), newStack(apiB)
+
+	assert.NoError(t, stackA.ice.StartWithConn(connA, ICERoleControlling))
+	assert.NoError(t, stackB.ice.StartWithConn(connB, ICERoleControlled))
+	assert.Equal(t, ICETransportState(ICETransportStateConnected), stackA.ice.State())
+
+	paramsA, err := stackA.dtls.GetLocalParameters()
+	assert.NoError(t, err)
+	paramsB, err := stackB.dtls.GetLocalParameters()
+	assert.NoError(t, err)
+
+	start := func(s *stack, remote DTLSParameters, errs chan error) {
+		if err := s.dtls.Start(remote); err != nil {
+			errs <- err
+			return
+		}
+		errs <- s.sctp.Start(SCTPCapabilities{})
+	}
+	errs := make(chan error, 2)
+	go start(stackA, paramsB, errs)
+	go start(stackB, paramsA, errs)
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+
+	accepted := make(chan struct{})
+	messages := make(chan string, 1)
+	stackB.sctp.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			messages <- string(msg.Data)
+		})
+		close(accepted)
+	})
+
+	channel, err := apiA.NewDataChannel(stackA.sctp, &DataChannelParameters{Label: "custom", ID: 1})
+	assert.NoError(t, err)
+	<-accepted
+	assert.NoError(t, channel.SendText("hello"))
+	assert.Equal(t, "hello", <-messages)
+
+	assert.NoError(t, stackA.sctp.Stop())
+	assert.NoError(t, stackB.sctp.Stop())
+	assert.NoError(t, stackA.ice.Stop())
+	assert.NoError(t, stackB.ice.Stop())
+}
@@ -0,0 +1,57 @@
+// +build !js
+
+package webrtc
+
+import (
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// Interceptor inspects or modifies the RTP and RTCP of every PeerConnection
+// created by an API, e.g. to collect statistics or add header extensions.
+// The methods are called from the goroutines reading and writing the
+// packets and have to be safe for concurrent use.
+type Interceptor interface {
+	// WriteRTP is called before a RTPSender sends a packet and returns the
+	// payload to send. The payload is shared with the other senders of the
+	// track and must not be modified in place. An error is returned to the
+	// writer of the track and the packet isn't sent.
+	WriteRTP(header *rtp.Header, payload []byte) ([]byte, error)
+
+	// ReadRTP is called for every packet a RTPReceiver reads before it is
+	// handed to the track and returns the payload to hand out. An error is
+	// returned to the reader of the track.
+	ReadRTP(header *rtp.Header, payload []byte) ([]byte, error)
+
+	// WriteRTCP is called by PeerConnection.WriteRTCP and returns the
+	// packets to send.
+	WriteRTCP(pkts []rtcp.Packet) ([]rtcp.Packet, error)
+
+	// ReadRTCP is called for every compound packet read by a RTPSender or
+	// RTPReceiver and returns the packets to hand out.
+	ReadRTCP(pkts []rtcp.Packet) ([]rtcp.Packet, error)
+}
+
+// NoOpInterceptor passes every packet through unchanged. It can be embedded
+// by interceptors that only implement some of the methods.
+type NoOpInterceptor struct{}
+
+// WriteRTP returns payload unchanged
+func (NoOpInterceptor) WriteRTP(header *rtp.Header, payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+// ReadRTP returns payload unchanged
+func (NoOpInterceptor) ReadRTP(header *rtp.Header, payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+// WriteRTCP returns pkts unchanged
+func (NoOpInterceptor) WriteRTCP(pkts []rtcp.Packet) ([]rtcp.Packet, error) {
+	return pkts, nil
+}
+
+// ReadRTCP returns pkts unchanged
+func (NoOpInterceptor) ReadRTCP(pkts []rtcp.Packet) ([]rtcp.Packet, error) {
+	return pkts, nil
+}
@@ -0,0 +1,85 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// prefixInterceptor prepends a byte to sent payloads and strips it again
+// from received ones
+type prefixInterceptor struct {
+	NoOpInterceptor
+	read uint32
+}
+
+func (i *prefixInterceptor) WriteRTP(header *rtp.Header, payload []byte) ([]byte, error) {
+	return append([]byte{0xAA}, payload...), nil
+}
+
+func (i *prefixInterceptor) ReadRTP(header *rtp.Header, payload []byte) ([]byte, error) {
+	if len(payload) == 0 || payload[0] != 0xAA {
+		return nil, errors.New("payload without prefix")
+	}
+	atomic.AddUint32(&i.read, 1)
+	return payload[1:], nil
+}
+
+func TestInterceptor(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	interceptor := &prefixInterceptor{}
+	api := NewAPI(WithInterceptors(interceptor))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	payloads := make(chan []byte, 1)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		p, readErr := remote.ReadRTP()
+		if readErr != nil {
+			t.Error(readErr)
+			return
+		}
+		payloads <- p.Payload
+	})
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteRTP(&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: track.SSRC()}, Payload: []byte{0x10, 0x02}})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	assert.Equal(t, []byte{0x10, 0x02}, <-payloads)
+	assert.NotZero(t, atomic.LoadUint32(&interceptor.read))
+
+	close(done)
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
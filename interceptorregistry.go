@@ -0,0 +1,103 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// InterceptorRegistry is the ordered list of interceptors an API installs
+// into every PeerConnection. Packets pass the interceptors in the order
+// they were added, in both directions.
+type InterceptorRegistry struct {
+	interceptors []Interceptor
+}
+
+// Add appends an interceptor to the registry
+func (r *InterceptorRegistry) Add(interceptor Interceptor) {
+	r.interceptors = append(r.interceptors, interceptor)
+}
+
+func (r *InterceptorRegistry) empty() bool {
+	return len(r.interceptors) == 0
+}
+
+func (r *InterceptorRegistry) writeRTP(header *rtp.Header, payload []byte) ([]byte, error) {
+	var err error
+	for _, i := range r.interceptors {
+		if payload, err = i.WriteRTP(header, payload); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// readRTP runs the interceptors on the packet of n bytes in b and writes the
+// result back into b
+func (r *InterceptorRegistry) readRTP(b []byte, n int) (int, error) {
+	if r.empty() {
+		return n, nil
+	}
+
+	p := &rtp.Packet{}
+	if err := unmarshalRTP(p, b[:n]); err != nil {
+		return 0, err
+	}
+
+	var err error
+	for _, i := range r.interceptors {
+		if p.Payload, err = i.ReadRTP(&p.Header, p.Payload); err != nil {
+			return 0, err
+		}
+	}
+
+	raw, err := p.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	if len(raw) > len(b) {
+		return 0, io.ErrShortBuffer
+	}
+	return copy(b, raw), nil
+}
+
+func (r *InterceptorRegistry) writeRTCP(pkts []rtcp.Packet) ([]rtcp.Packet, error) {
+	var err error
+	for _, i := range r.interceptors {
+		if pkts, err = i.WriteRTCP(pkts); err != nil {
+			return nil, err
+		}
+	}
+	return pkts, nil
+}
+
+// readRTCP runs the interceptors on the compound packet of n bytes in b and
+// writes the result back into b
+func (r *InterceptorRegistry) readRTCP(b []byte, n int) (int, error) {
+	if r.empty() {
+		return n, nil
+	}
+
+	pkts, err := rtcp.Unmarshal(b[:n])
+	if err != nil {
+		return 0, err
+	}
+
+	for _, i := range r.interceptors {
+		if pkts, err = i.ReadRTCP(pkts); err != nil {
+			return 0, err
+		}
+	}
+
+	raw, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return 0, err
+	}
+	if len(raw) > len(b) {
+		return 0, io.ErrShortBuffer
+	}
+	return copy(b, raw), nil
+}
@@ -0,0 +1,101 @@
+// Package fakechannel provides an in-memory DataChannel for the tests of the
+// packages that send over DataChannels.
+package fakechannel
+
+import (
+	"sync"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// Channel delivers the messages sent on it to the OnMessage handler of its
+// peer, in order like a reliable DataChannel. Messages wait until the peer
+// has a handler.
+type Channel struct {
+	mu        sync.Mutex
+	peer      *Channel
+	onMessage func(webrtc.DataChannelMessage)
+	onClose   func()
+
+	queue     chan webrtc.DataChannelMessage
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// NewPair returns two channels connected to each other
+func NewPair() (*Channel, *Channel) {
+	a, b := newChannel(), newChannel()
+	a.peer, b.peer = b, a
+	go a.deliver()
+	go b.deliver()
+	return a, b
+}
+
+func newChannel() *Channel {
+	return &Channel{
+		queue: make(chan webrtc.DataChannelMessage, 1024),
+		ready: make(chan struct{}),
+	}
+}
+
+// deliver passes the queued messages to the handler once there is one
+func (c *Channel) deliver() {
+	<-c.ready
+	for msg := range c.queue {
+		c.mu.Lock()
+		f := c.onMessage
+		c.mu.Unlock()
+		if f != nil {
+			f(msg)
+		}
+	}
+}
+
+// Send sends a binary message to the peer
+func (c *Channel) Send(data []byte) error {
+	c.peer.queue <- webrtc.DataChannelMessage{Data: append([]byte{}, data...)}
+	return nil
+}
+
+// SendText sends a text message to the peer
+func (c *Channel) SendText(s string) error {
+	c.peer.queue <- webrtc.DataChannelMessage{IsString: true, Data: []byte(s)}
+	return nil
+}
+
+// OnMessage sets the handler of the messages of the peer
+func (c *Channel) OnMessage(f func(webrtc.DataChannelMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onMessage = f
+	if f != nil {
+		c.readyOnce.Do(func() { close(c.ready) })
+	}
+}
+
+// OnClose sets the handler that Close calls
+func (c *Channel) OnClose(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onClose = f
+}
+
+// Close calls the OnClose handler, as if the channel was closed
+func (c *Channel) Close() error {
+	c.mu.Lock()
+	f := c.onClose
+	c.mu.Unlock()
+	if f != nil {
+		f()
+	}
+	return nil
+}
+
+// BufferedAmount is always zero, messages are queued by the peer
+func (c *Channel) BufferedAmount() uint64 { return 0 }
+
+// SetBufferedAmountLowThreshold does nothing
+func (c *Channel) SetBufferedAmountLowThreshold(uint64) {}
+
+// OnBufferedAmountLow does nothing, the buffered amount is never high
+func (c *Channel) OnBufferedAmountLow(func()) {}
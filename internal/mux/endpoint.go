@@ -3,6 +3,7 @@ package mux
 import (
 	"io"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/ice"
@@ -13,6 +14,15 @@ import (
 type Endpoint struct {
 	mux    *Mux
 	buffer *packetio.Buffer
+
+	// dropped counts the packets that didn't fit into the buffer, accessed
+	// atomically
+	dropped uint64
+}
+
+// Dropped returns how many packets were dropped because the buffer was full
+func (e *Endpoint) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
 }
 
 // Close unregisters the endpoint from the Mux
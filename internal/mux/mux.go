@@ -3,6 +3,7 @@ package mux
 import (
 	"net"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pion/logging"
 	"github.com/pion/transport/packetio"
@@ -132,9 +133,10 @@ func (m *Mux) dispatch(buf []byte) error {
 	}
 
 	_, err := endpoint.buffer.Write(buf)
-	if err != nil {
-		return err
+	if err == packetio.ErrFull {
+		// A reader that doesn't keep up must not stop the other endpoints
+		atomic.AddUint64(&endpoint.dropped, 1)
+		return nil
 	}
-
-	return nil
+	return err
 }
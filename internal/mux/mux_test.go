@@ -102,3 +102,34 @@ func TestNoEndpoints(t *testing.T) {
 	}
 
 }
+
+func TestEndpointBufferFull(t *testing.T) {
+	ca, cb := net.Pipe()
+
+	config := Config{
+		Conn:          ca,
+		BufferSize:    8192,
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	}
+
+	m := NewMux(config)
+	e := m.NewEndpoint(func([]byte) bool { return true })
+	e.buffer.SetLimitSize(2)
+
+	// Packets that don't fit are dropped, the mux keeps dispatching
+	for i := 0; i < 3; i++ {
+		if err := m.dispatch([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e.Dropped() != 1 {
+		t.Fatalf("Expected 1 dropped packet, got %d", e.Dropped())
+	}
+
+	if err := cb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
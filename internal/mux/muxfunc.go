@@ -73,6 +73,35 @@ func MatchSRTPOrSRTCP(b []byte) bool {
 	return MatchRange(128, 191)(b)
 }
 
+// Sizes pion/srtp relies on without checking them, a shorter packet would
+// make it read past the end of the buffer
+const (
+	rtpFixedHeaderSize  = 12
+	rtcpFixedHeaderSize = 8 // header and SSRC of the sender
+	srtpAuthTagSize     = 10
+	srtcpIndexSize      = 4
+)
+
+// rtpHeaderSize returns the size of the RTP header at the start of buf,
+// including CSRCs and the extension, or 0 if buf can't hold it
+func rtpHeaderSize(buf []byte) int {
+	if len(buf) < rtpFixedHeaderSize {
+		return 0
+	}
+
+	size := rtpFixedHeaderSize + int(buf[0]&0x0F)*4
+	if buf[0]&0x10 != 0 {
+		if len(buf) < size+4 {
+			return 0
+		}
+		size += 4 + int(binary.BigEndian.Uint16(buf[size+2:]))*4
+	}
+	if len(buf) < size {
+		return 0
+	}
+	return size
+}
+
 func isRTCP(buf []byte) bool {
 	// Not long enough to determine RTP/RTCP
 	if len(buf) < 4 {
@@ -90,12 +119,18 @@ func isRTCP(buf []byte) bool {
 	return false
 }
 
-// MatchSRTP is a MatchFunc that only matches SRTP and not SRTCP
+// MatchSRTP is a MatchFunc that only matches SRTP and not SRTCP. Packets
+// too short for their header and authentication tag are not matched.
 func MatchSRTP(buf []byte) bool {
-	return MatchSRTPOrSRTCP(buf) && !isRTCP(buf)
+	if !MatchSRTPOrSRTCP(buf) || isRTCP(buf) {
+		return false
+	}
+	headerSize := rtpHeaderSize(buf)
+	return headerSize != 0 && len(buf) >= headerSize+srtpAuthTagSize
 }
 
-// MatchSRTCP is a MatchFunc that only matches SRTCP and not SRTP
+// MatchSRTCP is a MatchFunc that only matches SRTCP and not SRTP. Packets
+// too short for their header, index and authentication tag are not matched.
 func MatchSRTCP(buf []byte) bool {
-	return MatchSRTPOrSRTCP(buf) && isRTCP(buf)
+	return MatchSRTPOrSRTCP(buf) && isRTCP(buf) && len(buf) >= rtcpFixedHeaderSize+srtcpIndexSize+srtpAuthTagSize
 }
@@ -0,0 +1,36 @@
+package mux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchSRTPShortPackets(t *testing.T) {
+	rtp := func(size int, firstByte byte) []byte {
+		b := make([]byte, size)
+		b[0], b[1] = firstByte, 96
+		return b
+	}
+
+	assert.False(t, MatchSRTP(rtp(4, 0x80)), "shorter than the fixed header")
+	assert.False(t, MatchSRTP(rtp(12, 0x80)), "no room for the auth tag")
+	assert.True(t, MatchSRTP(rtp(22, 0x80)))
+	assert.False(t, MatchSRTP(rtp(22, 0x81)), "no room for the CSRC")
+	assert.True(t, MatchSRTP(rtp(26, 0x81)))
+
+	extension := rtp(26, 0x90)
+	extension[15] = 1
+	assert.False(t, MatchSRTP(extension), "no room for the extension")
+	assert.True(t, MatchSRTP(append(extension, 0, 0, 0, 0)))
+
+	rtcp := func(size int) []byte {
+		b := make([]byte, size)
+		b[0], b[1] = 0x80, 200
+		return b
+	}
+	assert.False(t, MatchSRTCP(rtcp(4)))
+	assert.False(t, MatchSRTCP(rtcp(21)))
+	assert.True(t, MatchSRTCP(rtcp(22)))
+	assert.False(t, MatchSRTP(rtcp(22)))
+}
@@ -0,0 +1,87 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// keyFrameRequester consolidates the key frame requests of an RTPReceiver,
+// e.g. the PLIs of every subscriber of a track in an SFU, into at most one
+// request per window. A request while a key frame is on its way is covered
+// by that key frame. A request within the window after a key frame arrived
+// is sent when the window ends, unless another key frame arrives first.
+type keyFrameRequester struct {
+	mu sync.Mutex
+
+	lastRequest time.Time
+	// pending is set from sending a request until a key frame arrives
+	pending bool
+	// trailing sends the request that was consolidated within the window
+	trailing *time.Timer
+	stopped  bool
+}
+
+// request asks for a key frame at now, send is called with the lock held
+func (k *keyFrameRequester) request(now time.Time, window time.Duration, send func() error) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	sinceLast := now.Sub(k.lastRequest)
+	switch {
+	case k.stopped || k.trailing != nil:
+		return nil
+	case k.lastRequest.IsZero() || sinceLast >= window:
+	case k.pending:
+		// The key frame that is on its way serves this request as well
+		return nil
+	default:
+		k.trailing = time.AfterFunc(window-sinceLast, func() {
+			k.mu.Lock()
+			defer k.mu.Unlock()
+
+			if k.trailing == nil || k.stopped {
+				return
+			}
+			k.trailing = nil
+			k.lastRequest = time.Now()
+			k.pending = true
+			// The transport failing is reported by the reads of the receiver
+			_ = send()
+		})
+		return nil
+	}
+
+	if err := send(); err != nil {
+		return err
+	}
+	k.lastRequest = now
+	k.pending = true
+	return nil
+}
+
+// keyFrameReceived is called for the first packet of every key frame, it
+// satisfies the pending and the consolidated requests
+func (k *keyFrameRequester) keyFrameReceived() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.pending = false
+	if k.trailing != nil {
+		k.trailing.Stop()
+		k.trailing = nil
+	}
+}
+
+// stop cancels the consolidated request, later requests are ignored
+func (k *keyFrameRequester) stop() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.stopped = true
+	if k.trailing != nil {
+		k.trailing.Stop()
+		k.trailing = nil
+	}
+}
@@ -0,0 +1,59 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyFrameRequester(t *testing.T) {
+	const window = 50 * time.Millisecond
+
+	k := keyFrameRequester{}
+	var sent int32
+	send := func() error {
+		atomic.AddInt32(&sent, 1)
+		return nil
+	}
+	request := func() {
+		assert.NoError(t, k.request(time.Now(), window, send))
+	}
+
+	// Many subscribers ask while the key frame is on its way
+	for i := 0; i < 10; i++ {
+		request()
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&sent))
+
+	// A subscriber that joins right after the key frame gets a trailing request
+	k.keyFrameReceived()
+	request()
+	request()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&sent))
+	for atomic.LoadInt32(&sent) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A key frame within the window makes the trailing request unnecessary
+	k.keyFrameReceived()
+	request()
+	k.keyFrameReceived()
+	time.Sleep(2 * window)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&sent))
+
+	// A lost request is repeated once the window is over
+	request()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&sent))
+	time.Sleep(window)
+	request()
+	assert.Equal(t, int32(4), atomic.LoadInt32(&sent))
+
+	k.stop()
+	time.Sleep(window)
+	request()
+	assert.Equal(t, int32(4), atomic.LoadInt32(&sent))
+}
@@ -0,0 +1,67 @@
+// +build !js
+
+package webrtc
+
+import "sync"
+
+// lossHistoryMaxLength is how many packets a lossHistory covers at most,
+// older ones are dropped from the next report
+const lossHistoryMaxLength = 1 << 14
+
+// lossHistory records which packets of a received RTP stream arrived since
+// the last Loss RLE report block
+type lossHistory struct {
+	mu       sync.Mutex
+	started  bool
+	begin    uint16
+	received []bool
+}
+
+// update accounts for a received packet, packets from before the last
+// report are ignored
+func (h *lossHistory) update(sequenceNumber uint16) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.started {
+		h.started = true
+		h.begin = sequenceNumber
+	}
+	offset := int(sequenceNumber - h.begin)
+	if offset >= 1<<15 {
+		return
+	}
+	if offset >= lossHistoryMaxLength {
+		drop := offset - lossHistoryMaxLength + 1
+		if drop > len(h.received) {
+			drop = len(h.received)
+		}
+		h.received = h.received[drop:]
+		h.begin = sequenceNumber - uint16(lossHistoryMaxLength-1)
+		offset = lossHistoryMaxLength - 1
+	}
+	for len(h.received) <= offset {
+		h.received = append(h.received, false)
+	}
+	h.received[offset] = true
+}
+
+// report returns the Loss RLE report block of the stream with ssrc for the
+// packets since the previous report, false if there are none
+func (h *lossHistory) report(ssrc uint32) (LossRLEReportBlock, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.received) == 0 {
+		return LossRLEReportBlock{}, false
+	}
+	block := LossRLEReportBlock{
+		SSRC:     ssrc,
+		BeginSeq: h.begin,
+		EndSeq:   h.begin + uint16(len(h.received)),
+		Received: h.received,
+	}
+	h.begin = block.EndSeq
+	h.received = nil
+	return block, true
+}
@@ -0,0 +1,42 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLossHistory(t *testing.T) {
+	h := lossHistory{}
+	_, ok := h.report(1)
+	assert.False(t, ok)
+
+	for _, seq := range []uint16{65534, 65535, 1, 0, 3} {
+		h.update(seq)
+	}
+	block, ok := h.report(1)
+	assert.True(t, ok)
+	assert.Equal(t, LossRLEReportBlock{
+		SSRC:     1,
+		BeginSeq: 65534,
+		EndSeq:   4,
+		Received: []bool{true, true, true, true, false, true},
+	}, block)
+
+	// Late packets of the previous report are ignored
+	h.update(2)
+	h.update(4)
+	block, ok = h.report(1)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(4), block.BeginSeq)
+	assert.Equal(t, []bool{true}, block.Received)
+
+	// A jump keeps only the most recent packets
+	h.update(5 + lossHistoryMaxLength + 100)
+	block, ok = h.report(1)
+	assert.True(t, ok)
+	assert.Len(t, block.Received, lossHistoryMaxLength)
+	assert.Equal(t, uint16(5+lossHistoryMaxLength+100+1), block.EndSeq)
+}
@@ -1,11 +1,11 @@
-// +build !js
-
 package webrtc
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
@@ -21,18 +21,173 @@ const (
 	DefaultPayloadTypeH264 = 102
 )
 
-// MediaEngine defines the codecs supported by a PeerConnection
+// Range of dynamic payload types, RFC 3551 Section 6
+const (
+	dynamicPayloadTypeMin = 96
+	dynamicPayloadTypeMax = 127
+)
+
+// MediaEngine defines the codecs supported by a PeerConnection. Registering
+// codecs never writes to memory shared with a copy of the MediaEngine, so a
+// base MediaEngine can be copied by value and each copy populated from the
+// SDP of its own PeerConnection concurrently. A single MediaEngine must not be
+// configured from multiple goroutines. Once it is passed to an API it is only
+// read and may be used by any number of PeerConnections.
 type MediaEngine struct {
 	codecs []*RTPCodec
+
+	// headerExtensions are offered with the ID they were registered with
+	headerExtensions []mediaEngineHeaderExtension
+
+	// passthroughUnknown makes PopulateFromSDP register codecs it has no
+	// Payloader for as passthrough codecs
+	passthroughUnknown bool
+}
+
+// clone returns a MediaEngine with the same codecs, registering codecs on
+// the clone doesn't change m
+func (m *MediaEngine) clone() *MediaEngine {
+	return &MediaEngine{
+		codecs:             append([]*RTPCodec{}, m.codecs...),
+		headerExtensions:   append([]mediaEngineHeaderExtension{}, m.headerExtensions...),
+		passthroughUnknown: m.passthroughUnknown,
+	}
 }
 
-// RegisterCodec registers a codec to a media engine
+// RegisterCodec registers a codec to a media engine. If the dynamic payload
+// type of the codec is already used by a different codec, the codec is moved
+// to a free dynamic payload type. The payload type the codec is registered
+// with is returned, it has to be used by codecs referencing this one, e.g. in
+// the apt parameter of RTX.
 func (m *MediaEngine) RegisterCodec(codec *RTPCodec) uint8 {
 	// pion/webrtc#43
-	m.codecs = append(m.codecs, codec)
+	if existing, err := m.getCodec(codec.PayloadType); err == nil && !sameCodec(existing, codec) && isDynamicPayloadType(codec.PayloadType) {
+		if payloadType, ok := m.freeDynamicPayloadType(); ok {
+			codec.PayloadType = payloadType
+		}
+	}
+
+	// Copies of m share the backing array of codecs, never append in place
+	m.codecs = append(m.codecs[:len(m.codecs):len(m.codecs)], codec)
 	return codec.PayloadType
 }
 
+// mediaEngineHeaderExtension is a header extension registered for a kind
+type mediaEngineHeaderExtension struct {
+	RTPHeaderExtensionParameter
+	kind RTPCodecType
+}
+
+// RegisterHeaderExtension registers an RTP header extension for the media
+// sections of a kind. It is offered with an ID of its own, or the one of the
+// remote offer when answering. Extensions the remote agreed to can be written
+// with Track.WriteRTPWithExtensions and read with Track.HeaderExtension.
+// Only one-byte header extensions are supported, registering more than 13
+// extensions fails with ErrNoFreeHeaderExtensionID. The rtp-stream-id
+// extension of simulcast is always supported and doesn't need to be
+// registered.
+func (m *MediaEngine) RegisterHeaderExtension(extension RTPHeaderExtensionCapability, kind RTPCodecType) error {
+	if extension.URI == sdesRTPStreamIDURI {
+		return nil
+	}
+
+	// An extension has the same ID in the sections of every kind
+	var id uint8
+	used := map[uint8]bool{defaultRTPStreamIDExtensionID: true}
+	for _, e := range m.headerExtensions {
+		if e.URI == extension.URI {
+			if e.kind == kind {
+				return nil
+			}
+			id = e.ID
+		}
+		used[e.ID] = true
+	}
+	for candidate := uint8(1); id == 0 && candidate <= oneByteHeaderExtensionMaxID; candidate++ {
+		if !used[candidate] {
+			id = candidate
+		}
+	}
+	if id == 0 {
+		return ErrNoFreeHeaderExtensionID
+	}
+
+	// Copies of m share the backing array, never append in place
+	m.headerExtensions = append(m.headerExtensions[:len(m.headerExtensions):len(m.headerExtensions)], mediaEngineHeaderExtension{
+		RTPHeaderExtensionParameter: RTPHeaderExtensionParameter{URI: extension.URI, ID: id},
+		kind:                        kind,
+	})
+	return nil
+}
+
+// getHeaderExtensionsByKind returns the header extensions registered for kind
+func (m *MediaEngine) getHeaderExtensionsByKind(kind RTPCodecType) []RTPHeaderExtensionParameter {
+	var extensions []RTPHeaderExtensionParameter
+	for _, e := range m.headerExtensions {
+		if e.kind == kind {
+			extensions = append(extensions, e.RTPHeaderExtensionParameter)
+		}
+	}
+	return extensions
+}
+
+// negotiatedHeaderExtensions returns the header extensions of a remote media
+// section of the given kind that are registered, with the IDs of the remote.
+// rtp-stream-id is always supported.
+func (m *MediaEngine) negotiatedHeaderExtensions(remote *sdp.MediaDescription, kind RTPCodecType) []RTPHeaderExtensionParameter {
+	var negotiated []RTPHeaderExtensionParameter
+	for _, e := range headerExtensions(remote) {
+		if e.URI == sdesRTPStreamIDURI {
+			negotiated = append(negotiated, e)
+			continue
+		}
+		for _, registered := range m.headerExtensions {
+			if registered.URI == e.URI && registered.kind == kind {
+				negotiated = append(negotiated, e)
+				break
+			}
+		}
+	}
+	return negotiated
+}
+
+// negotiatedCodecs returns the codecs of a remote media section that are
+// registered, with the payload types of the remote
+func (m *MediaEngine) negotiatedCodecs(parsed *sdp.SessionDescription, remote *sdp.MediaDescription) []RTPCodecParameters {
+	var negotiated []RTPCodecParameters
+	feedback := getRTCPFeedback(remote)
+	for _, format := range remote.MediaName.Formats {
+		payloadType, err := strconv.ParseUint(format, 10, 8)
+		if err != nil {
+			continue
+		}
+		codec, err := parsed.GetCodecForPayloadType(uint8(payloadType))
+		if err != nil {
+			continue
+		}
+		if _, err := m.getCodecSDP(codec); err != nil {
+			continue
+		}
+		negotiated = append(negotiated, newRTPCodecParameters(remote, codec, feedback))
+	}
+	return negotiated
+}
+
+func isDynamicPayloadType(payloadType uint8) bool {
+	return payloadType >= dynamicPayloadTypeMin && payloadType <= dynamicPayloadTypeMax
+}
+
+// freeDynamicPayloadType returns the lowest dynamic payload type that isn't
+// used by a registered codec
+func (m *MediaEngine) freeDynamicPayloadType() (uint8, bool) {
+	for payloadType := dynamicPayloadTypeMin; payloadType <= dynamicPayloadTypeMax; payloadType++ {
+		if _, err := m.getCodec(uint8(payloadType)); err == ErrCodecNotFound {
+			return uint8(payloadType), true
+		}
+	}
+	return 0, false
+}
+
 // RegisterDefaultCodecs is a helper that registers the default codecs supported by Pion WebRTC
 func (m *MediaEngine) RegisterDefaultCodecs() {
 	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
@@ -42,8 +197,18 @@ func (m *MediaEngine) RegisterDefaultCodecs() {
 	m.RegisterCodec(NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
 }
 
+// EnableUnknownCodecPassthrough makes PopulateFromSDP register the audio and
+// video codecs it doesn't know as passthrough codecs instead of skipping
+// them, see NewRTPPassthroughCodec.
+func (m *MediaEngine) EnableUnknownCodecPassthrough() {
+	m.passthroughUnknown = true
+}
+
 // PopulateFromSDP finds all codecs in a session description and adds them to a MediaEngine, using dynamic
-// payload types and parameters from the sdp.
+// payload types and parameters from the sdp. The rtcp-fb lines of a codec are kept as its RTCPFeedback.
+// Retransmission and FEC codecs are registered as passthrough codecs after the codecs they protect, their
+// apt and RED parameters follow the payload types the protected codecs are registered with. See
+// GetAssociatedCodecs.
 func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 	sdpsd := sdp.SessionDescription{}
 	err := sdpsd.Unmarshal([]byte(sd.SDP))
@@ -51,6 +216,17 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 		return err
 	}
 	for _, md := range sdpsd.MediaDescriptions {
+		// The formats of data sections aren't payload types
+		kind := NewRTPCodecType(md.MediaName.Media)
+		if kind == RTPCodecType(0) {
+			continue
+		}
+		ptime, maxPTime := getPTimes(md)
+		feedback := getRTCPFeedback(md)
+
+		// Payload types of the remote mapped to the ones registered here
+		registered := map[uint8]uint8{}
+		var repairCodecs []*RTPCodec
 		for _, format := range md.MediaName.Formats {
 			pt, err := strconv.Atoi(format)
 			if err != nil {
@@ -64,6 +240,7 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 			var codec *RTPCodec
 			clockRate := payloadCodec.ClockRate
 			parameters := payloadCodec.Fmtp
+			channels, _ := strconv.Atoi(payloadCodec.EncodingParameters)
 			switch payloadCodec.Name {
 			case G722:
 				codec = NewRTPG722Codec(payloadType, clockRate)
@@ -79,7 +256,27 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 				codec = NewRTPH264Codec(payloadType, clockRate)
 				codec.SDPFmtpLine = parameters
 			default:
-				// ignoring other codecs
+				if isRepairCodec(payloadCodec.Name) {
+					codec = NewRTPPassthroughCodec(kind, payloadCodec.Name, clockRate, uint16(channels), parameters, payloadType)
+					codec.RTCPFeedback = codecRTCPFeedback(feedback, format)
+					repairCodecs = append(repairCodecs, codec)
+					continue
+				}
+				if !m.passthroughUnknown {
+					// ignoring other codecs
+					continue
+				}
+				codec = NewRTPPassthroughCodec(kind, payloadCodec.Name, clockRate, uint16(channels), parameters, payloadType)
+			}
+			codec.PTime = ptime
+			codec.MaxPTime = maxPTime
+			codec.RTCPFeedback = codecRTCPFeedback(feedback, format)
+			registered[payloadType] = m.RegisterCodec(codec)
+		}
+
+		for _, codec := range repairCodecs {
+			if codec.SDPFmtpLine, err = remapAssociatedPayloadTypes(codec, registered); err != nil {
+				// The protected codec wasn't registered
 				continue
 			}
 			m.RegisterCodec(codec)
@@ -88,6 +285,167 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 	return nil
 }
 
+// GetAssociatedCodecs returns the codecs that protect the codec with the
+// given payload type: RTX codecs whose apt parameter names it and RED codecs
+// that carry it. ulpfec and flexfec aren't bound to a single codec, look
+// them up with GetCodecsByName.
+func (m *MediaEngine) GetAssociatedCodecs(payloadType uint8) []*RTPCodec {
+	var codecs []*RTPCodec
+	for _, codec := range m.codecs {
+		for _, associated := range associatedPayloadTypes(codec) {
+			if associated == payloadType {
+				codecs = append(codecs, codec)
+				break
+			}
+		}
+	}
+	return codecs
+}
+
+// redCodecFor returns the registered RED codec that carries codec, or nil
+func (m *MediaEngine) redCodecFor(codec *RTPCodec) *RTPCodec {
+	for _, c := range m.codecs {
+		if !sameCodec(c, codec) {
+			continue
+		}
+		for _, associated := range m.GetAssociatedCodecs(c.PayloadType) {
+			if strings.EqualFold(associated.Name, RED) {
+				return associated
+			}
+		}
+	}
+	return nil
+}
+
+// isRepairCodec tells if name is a retransmission or FEC codec that
+// protects the media of other codecs
+func isRepairCodec(name string) bool {
+	for _, repair := range []string{RTX, RED, ULPFEC, FlexFEC} {
+		if strings.EqualFold(name, repair) {
+			return true
+		}
+	}
+	return false
+}
+
+// associatedPayloadTypes returns the payload types named by the apt parameter
+// of an RTX codec or by the fmtp of a RED codec, e.g. a=fmtp:63 111/111
+func associatedPayloadTypes(codec *RTPCodec) []uint8 {
+	var formats []string
+	switch {
+	case strings.EqualFold(codec.Name, RTX):
+		if apt, ok := fmtpParameter(codec.SDPFmtpLine, "apt"); ok {
+			formats = []string{apt}
+		}
+	case strings.EqualFold(codec.Name, RED):
+		formats = strings.Split(codec.SDPFmtpLine, "/")
+	}
+
+	var payloadTypes []uint8
+	for _, format := range formats {
+		pt, err := strconv.ParseUint(strings.TrimSpace(format), 10, 8)
+		if err != nil {
+			return nil
+		}
+		payloadTypes = append(payloadTypes, uint8(pt))
+	}
+	return payloadTypes
+}
+
+// remapAssociatedPayloadTypes returns the fmtp of a repair codec with the
+// payload types of the protected codecs replaced by the registered ones. It
+// fails if one of them wasn't registered.
+func remapAssociatedPayloadTypes(codec *RTPCodec, registered map[uint8]uint8) (string, error) {
+	associated := associatedPayloadTypes(codec)
+	for _, pt := range associated {
+		if _, ok := registered[pt]; !ok {
+			return "", ErrCodecNotFound
+		}
+	}
+
+	switch {
+	case strings.EqualFold(codec.Name, RTX):
+		var parameters []string
+		for _, parameter := range strings.Split(codec.SDPFmtpLine, ";") {
+			if key := strings.SplitN(strings.TrimSpace(parameter), "=", 2)[0]; key == "apt" && len(associated) == 1 {
+				parameter = fmt.Sprintf("apt=%d", registered[associated[0]])
+			}
+			parameters = append(parameters, parameter)
+		}
+		return strings.Join(parameters, ";"), nil
+	case strings.EqualFold(codec.Name, RED) && len(associated) != 0:
+		formats := make([]string, len(associated))
+		for i, pt := range associated {
+			formats[i] = strconv.Itoa(int(registered[pt]))
+		}
+		return strings.Join(formats, "/"), nil
+	default:
+		return codec.SDPFmtpLine, nil
+	}
+}
+
+// fmtpParameter returns the value of a key=value parameter of an fmtp line
+func fmtpParameter(fmtp, key string) (string, bool) {
+	for _, parameter := range strings.Split(fmtp, ";") {
+		keyValue := strings.SplitN(strings.TrimSpace(parameter), "=", 2)
+		if len(keyValue) == 2 && keyValue[0] == key {
+			return keyValue[1], true
+		}
+	}
+	return "", false
+}
+
+// codecRTCPFeedback returns the feedback of a single format
+func codecRTCPFeedback(feedback map[string][]RTCPFeedback, format string) []RTCPFeedback {
+	var codecFeedback []RTCPFeedback
+	codecFeedback = append(codecFeedback, feedback["*"]...)
+	return append(codecFeedback, feedback[format]...)
+}
+
+// getRTCPFeedback returns the rtcp-fb attributes of a media section by
+// format, feedback for all formats is stored with the format "*"
+func getRTCPFeedback(md *sdp.MediaDescription) map[string][]RTCPFeedback {
+	feedback := map[string][]RTCPFeedback{}
+	for _, attr := range md.Attributes {
+		if attr.Key != sdpAttrKeyRTCPFeedback {
+			continue
+		}
+		// a=rtcp-fb:<format> <type> [<parameter>]
+		fields := strings.Fields(attr.Value)
+		if len(fields) < 2 {
+			continue
+		}
+		fb := RTCPFeedback{Type: fields[1]}
+		if len(fields) > 2 {
+			fb.Parameter = strings.Join(fields[2:], " ")
+		}
+		feedback[fields[0]] = append(feedback[fields[0]], fb)
+	}
+	return feedback
+}
+
+// getPTimes returns the values of the ptime and maxptime attributes of a media section
+func getPTimes(md *sdp.MediaDescription) (ptime, maxPTime time.Duration) {
+	parse := func(key string) time.Duration {
+		value, ok := md.Attribute(key)
+		if !ok {
+			return 0
+		}
+		// ptime may be fractional, e.g. a=ptime:2.5
+		ms, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || ms < 0 {
+			return 0
+		}
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	return parse(sdpAttrKeyPTime), parse(sdpAttrKeyMaxPTime)
+}
+
+// formatPTime formats a duration in milliseconds as used by ptime and maxptime
+func formatPTime(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
+
 func (m *MediaEngine) getCodec(payloadType uint8) (*RTPCodec, error) {
 	for _, codec := range m.codecs {
 		if codec.PayloadType == payloadType {
@@ -121,6 +479,14 @@ func (m *MediaEngine) GetCodecsByKind(kind RTPCodecType) []*RTPCodec {
 	return codecs
 }
 
+// SDP attributes describing the packetization of audio
+const (
+	sdpAttrKeyPTime    = "ptime"
+	sdpAttrKeyMaxPTime = "maxptime"
+)
+
+const sdpAttrKeyRTCPFeedback = "rtcp-fb"
+
 // Names for the default codecs supported by Pion WebRTC
 const (
 	G722 = "G722"
@@ -130,6 +496,14 @@ const (
 	H264 = "H264"
 )
 
+// Names of the codecs that protect the media of other codecs
+const (
+	RTX     = "rtx"
+	RED     = "red"
+	ULPFEC  = "ulpfec"
+	FlexFEC = "flexfec-03"
+)
+
 // GetCodecsByName returns all codecs of a chosen name in the codecs list
 func (m *MediaEngine) GetCodecsByName(codecName string) []*RTPCodec {
 	var codecs []*RTPCodec
@@ -141,6 +515,25 @@ func (m *MediaEngine) GetCodecsByName(codecName string) []*RTPCodec {
 	return codecs
 }
 
+// filterCodecsByName returns the codecs whose name matches one of names.
+// An empty names list matches every codec.
+func filterCodecsByName(codecs []*RTPCodec, names []string) []*RTPCodec {
+	if len(names) == 0 {
+		return codecs
+	}
+
+	var filtered []*RTPCodec
+	for _, codec := range codecs {
+		for _, name := range names {
+			if strings.EqualFold(codec.Name, name) {
+				filtered = append(filtered, codec)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
 // NewRTPG722Codec is a helper to create a G722 codec
 func NewRTPG722Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 	c := NewRTPCodec(RTPCodecTypeAudio,
@@ -201,6 +594,22 @@ func NewRTPH264Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 	return c
 }
 
+// NewRTPPassthroughCodec is a helper to create a codec Pion WebRTC has no
+// Payloader for. It is negotiated by name, clock rate, channels and fmtp
+// like any other codec, its tracks forward RTP as it is written with
+// WriteRTP or Write. WriteSample returns ErrCodecPayloaderNotSet.
+func NewRTPPassthroughCodec(codecType RTPCodecType, name string, clockrate uint32, channels uint16, fmtp string, payloadType uint8) *RTPCodec {
+	return NewRTPCodec(codecType, name, clockrate, channels, fmtp, payloadType, nil)
+}
+
+// NewRTPREDCodec is a helper to create a RED codec, RFC 2198, that carries
+// the packets of primary together with redundant copies of the previous ones,
+// e.g. for Opus. It has to be registered after primary, see Track.SetRedundancy.
+func NewRTPREDCodec(payloadType uint8, primary *RTPCodec) *RTPCodec {
+	fmtp := fmt.Sprintf("%d/%d", primary.PayloadType, primary.PayloadType)
+	return NewRTPPassthroughCodec(primary.Type, RED, primary.ClockRate, primary.Channels, fmtp, payloadType)
+}
+
 // RTPCodecType determines the type of a codec
 type RTPCodecType int
 
@@ -236,6 +645,25 @@ func NewRTPCodecType(r string) RTPCodecType {
 	}
 }
 
+// MarshalJSON enables JSON marshaling of a RTPCodecType
+func (t RTPCodecType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON enables JSON unmarshaling of a RTPCodecType
+func (t *RTPCodecType) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	codecType := NewRTPCodecType(s)
+	if codecType == RTPCodecType(0) {
+		return ErrUnknownType
+	}
+	*t = codecType
+	return nil
+}
+
 // RTPCodec represents a codec supported by the PeerConnection
 type RTPCodec struct {
 	RTPCodecCapability
@@ -243,6 +671,22 @@ type RTPCodec struct {
 	Name        string
 	PayloadType uint8
 	Payloader   rtp.Payloader
+
+	// PTime is the length of media in a single packet and MaxPTime the
+	// maximum amount of media a packet may contain. They are announced with
+	// a=ptime and a=maxptime, zero values are not announced.
+	// https://tools.ietf.org/html/rfc4566#section-6
+	PTime    time.Duration
+	MaxPTime time.Duration
+}
+
+// kind returns the Type of the codec, or the kind of its MimeType if Type
+// isn't set
+func (c *RTPCodec) kind() RTPCodecType {
+	if c.Type != 0 {
+		return c.Type
+	}
+	return NewRTPCodecType(strings.SplitN(c.MimeType, "/", 2)[0])
 }
 
 // NewRTPCodec is used to define a new codec
@@ -271,11 +715,11 @@ func NewRTPCodec(
 
 // RTPCodecCapability provides information about codec capabilities.
 type RTPCodecCapability struct {
-	MimeType     string
-	ClockRate    uint32
-	Channels     uint16
-	SDPFmtpLine  string
-	RTCPFeedback []RTCPFeedback
+	MimeType     string         `json:"mimeType"`
+	ClockRate    uint32         `json:"clockRate"`
+	Channels     uint16         `json:"channels,omitempty"`
+	SDPFmtpLine  string         `json:"sdpFmtpLine,omitempty"`
+	RTCPFeedback []RTCPFeedback `json:"rtcpFeedback,omitempty"`
 }
 
 // RTPHeaderExtensionCapability is used to define a RFC5285 RTP header extension supported by the codec.
@@ -3,7 +3,10 @@
 package webrtc
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/pion/sdp/v2"
 	"github.com/stretchr/testify/assert"
@@ -35,3 +38,229 @@ func TestCodecRegistration(t *testing.T) {
 	_, err := api.mediaEngine.getCodecSDP(sdp.Codec{PayloadType: invalidPT})
 	assert.Equal(t, err, ErrCodecNotFound)
 }
+
+func TestPopulateFromSDPPTime(t *testing.T) {
+	const remoteSDP = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=rtpmap:111 opus/48000/2
+a=fmtp:111 minptime=10;useinbandfec=1
+a=ptime:20
+a=maxptime:40
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: remoteSDP}))
+
+	codecs := m.GetCodecsByName(Opus)
+	if assert.Equal(t, 1, len(codecs)) {
+		assert.Equal(t, 20*time.Millisecond, codecs[0].PTime)
+		assert.Equal(t, 40*time.Millisecond, codecs[0].MaxPTime)
+	}
+}
+
+func TestRegisterCodecPayloadTypeCollision(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	// H264 with other parameters can't share the payload type of the default one
+	h264 := NewRTPH264Codec(DefaultPayloadTypeH264, 90000)
+	h264.SDPFmtpLine = "profile-level-id=42e01f"
+	assert.Equal(t, uint8(97), m.RegisterCodec(h264))
+	assert.Equal(t, uint8(97), h264.PayloadType)
+
+	// Registering the same codec again keeps its payload type
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)))
+
+	// Static payload types are never moved
+	assert.Equal(t, uint8(DefaultPayloadTypeG722), m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeG722, 48000)))
+}
+
+func TestAnswerPayloadTypeCollision(t *testing.T) {
+	// The remote uses the payload type of the local VP8 for H264
+	const offer = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96 100
+c=IN IP4 0.0.0.0
+a=mid:0
+a=sendrecv
+a=ice-ufrag:ufrag
+a=ice-pwd:pwdpwdpwdpwdpwdpwdpwdpwd
+a=fingerprint:sha-256 00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00:00
+a=setup:actpass
+a=rtcp-mux
+a=rtpmap:96 H264/90000
+a=rtpmap:100 VP8/90000
+`
+
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	m.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
+	pc, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: offer}))
+	answer, err := pc.CreateAnswer(nil)
+	assert.NoError(t, err)
+
+	parsed := &sdp.SessionDescription{}
+	assert.NoError(t, parsed.Unmarshal([]byte(answer.SDP)))
+	assert.Equal(t, []string{"102"}, parsed.MediaDescriptions[0].MediaName.Formats)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPopulateFromSDPPassthrough(t *testing.T) {
+	const remoteSDP = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96 45
+c=IN IP4 0.0.0.0
+a=rtpmap:96 VP8/90000
+a=rtpmap:45 AV1X/90000
+a=fmtp:45 profile=0
+m=application 9 DTLS/SCTP 5000
+c=IN IP4 0.0.0.0
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: remoteSDP}))
+	assert.Equal(t, 0, len(m.GetCodecsByName("AV1X")))
+
+	m = MediaEngine{}
+	m.EnableUnknownCodecPassthrough()
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: remoteSDP}))
+
+	codecs := m.GetCodecsByName("AV1X")
+	if assert.Equal(t, 1, len(codecs)) {
+		assert.Equal(t, RTPCodecTypeVideo, codecs[0].Type)
+		assert.Equal(t, uint8(45), codecs[0].PayloadType)
+		assert.Equal(t, uint32(90000), codecs[0].ClockRate)
+		assert.Equal(t, "profile=0", codecs[0].SDPFmtpLine)
+		assert.Nil(t, codecs[0].Payloader)
+	}
+	assert.Equal(t, 1, len(m.GetCodecsByName(VP8)))
+}
+
+func TestPopulateFromSDPRepairCodecs(t *testing.T) {
+	const remoteSDP = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96 97 100 101 102
+c=IN IP4 0.0.0.0
+a=rtpmap:96 VP8/90000
+a=rtcp-fb:96 nack
+a=rtcp-fb:96 nack pli
+a=rtcp-fb:* transport-cc
+a=rtpmap:97 rtx/90000
+a=fmtp:97 apt=96;rtx-time=3000
+a=rtpmap:100 red/90000
+a=rtpmap:101 ulpfec/90000
+a=rtpmap:102 rtx/90000
+a=fmtp:102 apt=45
+`
+
+	// VP8 can't keep payload type 96, its RTX has to follow
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPPassthroughCodec(RTPCodecTypeVideo, "AV1X", 90000, 0, "", 96))
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: remoteSDP}))
+
+	vp8 := m.GetCodecsByName(VP8)
+	if !assert.Equal(t, 1, len(vp8)) {
+		return
+	}
+	assert.Equal(t, uint8(97), vp8[0].PayloadType)
+	assert.Equal(t, []RTCPFeedback{
+		{Type: "transport-cc"},
+		{Type: "nack"},
+		{Type: "nack", Parameter: "pli"},
+	}, vp8[0].RTCPFeedback)
+
+	associated := m.GetAssociatedCodecs(vp8[0].PayloadType)
+	if assert.Equal(t, 1, len(associated)) {
+		assert.Equal(t, RTX, associated[0].Name)
+		assert.Equal(t, "apt=97;rtx-time=3000", associated[0].SDPFmtpLine)
+		assert.NotEqual(t, vp8[0].PayloadType, associated[0].PayloadType)
+	}
+
+	// FEC codecs are kept, RTX of a codec that wasn't registered is not
+	assert.Equal(t, 1, len(m.GetCodecsByName(RED)))
+	assert.Equal(t, 1, len(m.GetCodecsByName(ULPFEC)))
+	assert.Equal(t, 1, len(m.GetCodecsByName(RTX)))
+}
+
+func TestAssociatedPayloadTypes(t *testing.T) {
+	assert.Equal(t, []uint8{111, 111}, associatedPayloadTypes(NewRTPPassthroughCodec(RTPCodecTypeAudio, RED, 48000, 2, "111/111", 63)))
+	assert.Equal(t, []uint8{96}, associatedPayloadTypes(NewRTPPassthroughCodec(RTPCodecTypeVideo, RTX, 90000, 0, "apt=96", 97)))
+	assert.Nil(t, associatedPayloadTypes(NewRTPPassthroughCodec(RTPCodecTypeVideo, ULPFEC, 90000, 0, "", 101)))
+	assert.Nil(t, associatedPayloadTypes(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)))
+}
+
+func TestMediaEngineCopies(t *testing.T) {
+	const remoteSDP = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 100
+c=IN IP4 0.0.0.0
+a=rtpmap:100 VP8/90000
+`
+
+	base := MediaEngine{}
+	base.RegisterDefaultCodecs()
+	api := NewAPI(WithMediaEngine(base))
+
+	// Copies of the base are populated per PeerConnection
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			m := base
+			assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: remoteSDP}))
+			assert.Equal(t, 2, len(m.GetCodecsByName(VP8)))
+			_ = NewAPI(WithMediaEngine(m))
+
+			// The API is read concurrently
+			assert.Equal(t, 1, len(api.mediaEngine.GetCodecsByName(VP8)))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, len(base.GetCodecsByName(VP8)))
+
+	// The API keeps its snapshot
+	base.RegisterCodec(NewRTPVP8Codec(100, 90000))
+	assert.Equal(t, 1, len(api.mediaEngine.GetCodecsByName(VP8)))
+}
+
+func TestRegisterHeaderExtension(t *testing.T) {
+	m := MediaEngine{}
+	assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: "urn:a"}, RTPCodecTypeVideo))
+	assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: "urn:b"}, RTPCodecTypeVideo))
+	assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: "urn:b"}, RTPCodecTypeAudio))
+	assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: "urn:b"}, RTPCodecTypeAudio))
+	assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: sdesRTPStreamIDURI}, RTPCodecTypeVideo))
+
+	assert.Equal(t, []RTPHeaderExtensionParameter{{URI: "urn:a", ID: 1}, {URI: "urn:b", ID: 2}}, m.getHeaderExtensionsByKind(RTPCodecTypeVideo))
+	assert.Equal(t, []RTPHeaderExtensionParameter{{URI: "urn:b", ID: 2}}, m.getHeaderExtensionsByKind(RTPCodecTypeAudio))
+
+	// The ID of rtp-stream-id is skipped, one-byte extensions have 14 IDs
+	for i := 0; i < 11; i++ {
+		assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: fmt.Sprintf("urn:%d", i)}, RTPCodecTypeAudio))
+	}
+	for _, e := range m.getHeaderExtensionsByKind(RTPCodecTypeAudio) {
+		assert.NotEqual(t, uint8(defaultRTPStreamIDExtensionID), e.ID)
+	}
+	assert.Equal(t, ErrNoFreeHeaderExtensionID, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: "urn:full"}, RTPCodecTypeAudio))
+}
@@ -0,0 +1,18 @@
+package webrtc
+
+// MediaParameters describes an audio or video media section in
+// SignalingParameters: the codecs, the direction and the sources of the
+// section.
+type MediaParameters struct {
+	Mid       string                  `json:"mid"`
+	Kind      RTPCodecType            `json:"kind"`
+	Direction RTPTransceiverDirection `json:"direction"`
+	Codecs    []RTPCodecParameters    `json:"codecs"`
+
+	// SSRCs are the sources sent in the section, empty if nothing is sent
+	SSRCs []uint32 `json:"ssrcs,omitempty"`
+	// SSRCGroups groups the SSRCs, e.g. a media SSRC with its RTX SSRC
+	SSRCGroups []SSRCGroup `json:"ssrcGroups,omitempty"`
+	// CNAME is the RTCP canonical name of the sources
+	CNAME string `json:"cname,omitempty"`
+}
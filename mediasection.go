@@ -0,0 +1,86 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+// MediaSection describes a media section (m-line) of a SessionDescription.
+// Attributes that can be set on the session level, like the fingerprints
+// and the ICE credentials, are taken from there if the section doesn't
+// have them.
+type MediaSection struct {
+	// Kind is the media of the section, e.g. "audio", "video" or
+	// "application"
+	Kind string
+	MID  string
+
+	// Direction defaults to sendrecv if the section doesn't have one
+	Direction RTPTransceiverDirection
+	Rejected  bool
+
+	ICEParameters    ICEParameters
+	Fingerprints     []DTLSFingerprint
+	HeaderExtensions []RTPHeaderExtensionParameter
+
+	// SendRIDs and RecvRIDs are the RIDs of the simulcast encodings the
+	// author of the description sends and receives
+	SendRIDs []string
+	RecvRIDs []string
+}
+
+func newMediaSection(d *sdp.SessionDescription, m *sdp.MediaDescription) MediaSection {
+	section := MediaSection{
+		Kind:             m.MediaName.Media,
+		Direction:        RTPTransceiverDirectionSendrecv,
+		Rejected:         isRejectedMediaSection(m),
+		HeaderExtensions: headerExtensions(m),
+		SendRIDs:         ridsOf(m, "send"),
+		RecvRIDs:         ridsOf(m, "recv"),
+	}
+	section.MID, _ = m.Attribute(sdp.AttrKeyMID)
+	for _, a := range m.Attributes {
+		if direction := NewRTPTransceiverDirection(a.Key); direction != RTPTransceiverDirection(Unknown) {
+			section.Direction = direction
+			break
+		}
+	}
+
+	section.ICEParameters.UsernameFragment = mediaOrSessionAttribute(d, m, "ice-ufrag")
+	section.ICEParameters.Password = mediaOrSessionAttribute(d, m, "ice-pwd")
+	_, section.ICEParameters.ICELite = d.Attribute("ice-lite")
+
+	section.Fingerprints = fingerprintsOf(m.Attributes)
+	if len(section.Fingerprints) == 0 {
+		section.Fingerprints = fingerprintsOf(d.Attributes)
+	}
+	return section
+}
+
+// mediaOrSessionAttribute returns the value of the attribute key of m, or of
+// the session if m doesn't have it
+func mediaOrSessionAttribute(d *sdp.SessionDescription, m *sdp.MediaDescription, key string) string {
+	if value, ok := m.Attribute(key); ok {
+		return value
+	}
+	value, _ := d.Attribute(key)
+	return value
+}
+
+func fingerprintsOf(attributes []sdp.Attribute) []DTLSFingerprint {
+	var fingerprints []DTLSFingerprint
+	for _, a := range attributes {
+		if a.Key != "fingerprint" {
+			continue
+		}
+		fields := strings.Fields(a.Value)
+		if len(fields) != 2 {
+			continue
+		}
+		fingerprints = append(fingerprints, DTLSFingerprint{Algorithm: fields[0], Value: fields[1]})
+	}
+	return fingerprints
+}
@@ -0,0 +1,135 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// minMTU is the smallest MTU that can be configured, the size of the
+	// smallest datagram every IPv4 host accepts
+	minMTU = 576
+
+	// maxMTU is the largest MTU that can be configured, the size of the
+	// largest UDP payload
+	maxMTU = 65507
+
+	// relayMTUOverhead is what a TURN server adds to the packets it relays:
+	// a Send or Data indication with a STUN header, an IPv6
+	// XOR-PEER-ADDRESS, the header of the DATA attribute and its padding
+	relayMTUOverhead = 20 + 24 + 4 + 3
+
+	// tcpMTUOverhead is what TCP costs over UDP: the larger header and the
+	// RFC 4571 framing
+	tcpMTUOverhead = 20 - 8 + 2
+)
+
+// pathMTUOverhead returns how much smaller the packets have to be to be
+// sent over pair than over a direct UDP path
+func pathMTUOverhead(pair *ICECandidatePair) int {
+	if pair == nil {
+		return 0
+	}
+
+	overhead := 0
+	for _, c := range []*ICECandidate{pair.Local, pair.Remote} {
+		if c == nil {
+			continue
+		}
+		if c.Typ == ICECandidateTypeRelay {
+			overhead += relayMTUOverhead
+		}
+		if c.Protocol == ICEProtocolTCP {
+			overhead += tcpMTUOverhead
+		}
+	}
+	return overhead
+}
+
+// mtuPayloader splits the payloads of a Track into packets of the MTU the
+// track currently has instead of the fixed MTU of the packetizer, which
+// keeps the sequence numbers and timestamps going when the MTU changes
+type mtuPayloader struct {
+	track     *Track
+	payloader rtp.Payloader
+}
+
+func (p *mtuPayloader) Payload(_ int, payload []byte) [][]byte {
+	return p.payloader.Payload(p.track.MTU()-rtpHeaderSize, payload)
+}
+
+// newTrackPacketizer creates a packetizer for the codec of t
+func newTrackPacketizer(t *Track, payloadType uint8, ssrc uint32, codec *RTPCodec) rtp.Packetizer {
+	return rtp.NewPacketizer(
+		rtpOutboundMTU,
+		payloadType,
+		ssrc,
+		&mtuPayloader{track: t, payloader: codec.Payloader},
+		rtp.NewRandomSequencer(),
+		codec.ClockRate,
+	)
+}
+
+// SetMTU sets the size of the RTP packets the samples of a local track are
+// split into, in place of the MTU of the SettingEngine. The overhead of a
+// relayed or TCP path is still deducted. Zero restores the MTU of the
+// SettingEngine.
+func (t *Track) SetMTU(mtu int) error {
+	if mtu != 0 && (mtu < minMTU || mtu > maxMTU) {
+		return ErrInvalidMTU
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.receiver != nil {
+		return fmt.Errorf("the MTU can only be set on local tracks")
+	}
+	atomic.StoreInt32(&t.mtu, int32(mtu))
+	return nil
+}
+
+// MTU returns the size of the RTP packets the samples of the track are
+// split into: the smallest MTU of the paths the track is sent over
+func (t *Track) MTU() int {
+	mtu := int(atomic.LoadInt32(&t.mtu))
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.activeSenders) == 0 {
+		if mtu == 0 {
+			return rtpOutboundMTU
+		}
+		return mtu
+	}
+
+	smallest := 0
+	for _, s := range t.activeSenders {
+		senderMTU := s.transport.MTU()
+		if mtu != 0 {
+			senderMTU = mtu - s.transport.pathMTUOverhead()
+		}
+		if smallest == 0 || senderMTU < smallest {
+			smallest = senderMTU
+		}
+	}
+	return smallest
+}
+
+// MTU returns the size of the packets sent over the transport: the MTU of
+// the SettingEngine minus the overhead of the selected candidate pair
+func (t *DTLSTransport) MTU() int {
+	return t.api.settingEngine.getMTU() - t.pathMTUOverhead()
+}
+
+func (t *DTLSTransport) pathMTUOverhead() int {
+	return int(atomic.LoadInt32(&t.mtuOverhead))
+}
+
+// setSelectedCandidatePair adapts the MTU of the transport to pair
+func (t *DTLSTransport) setSelectedCandidatePair(pair *ICECandidatePair) {
+	atomic.StoreInt32(&t.mtuOverhead, int32(pathMTUOverhead(pair)))
+}
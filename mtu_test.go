@@ -0,0 +1,64 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackMTU(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, ErrInvalidMTU, s.SetMTU(100))
+	assert.NoError(t, s.SetMTU(1200))
+	api := NewAPI(WithSettingEngine(s))
+
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	assert.Equal(t, rtpOutboundMTU, track.MTU())
+
+	// Packets fit into the MTU of the transport the track is sent over
+	transport, err := api.NewDTLSTransport(nil, nil)
+	assert.NoError(t, err)
+	track.activeSenders = []*RTPSender{{transport: transport}}
+	assert.Equal(t, 1200, track.MTU())
+
+	sizes := func() []int {
+		var sizes []int
+		for _, p := range track.packetizer.Packetize(make([]byte, 5000), 90) {
+			sizes = append(sizes, rtpHeaderSize+len(p.Payload))
+		}
+		return sizes
+	}
+	for _, size := range sizes() {
+		assert.True(t, size <= 1200)
+	}
+
+	// A relayed TCP path lowers the MTU
+	transport.setSelectedCandidatePair(&ICECandidatePair{
+		Local:  &ICECandidate{Typ: ICECandidateTypeRelay, Protocol: ICEProtocolTCP},
+		Remote: &ICECandidate{Typ: ICECandidateTypeHost, Protocol: ICEProtocolUDP},
+	})
+	assert.Equal(t, 1200-relayMTUOverhead-tcpMTUOverhead, transport.MTU())
+	assert.Equal(t, transport.MTU(), track.MTU())
+	for _, size := range sizes() {
+		assert.True(t, size <= transport.MTU())
+	}
+
+	// The MTU of the track replaces the one of the SettingEngine
+	assert.Equal(t, ErrInvalidMTU, track.SetMTU(70000))
+	assert.NoError(t, track.SetMTU(800))
+	assert.Equal(t, 800-relayMTUOverhead-tcpMTUOverhead, track.MTU())
+	for _, size := range sizes() {
+		assert.True(t, size <= track.MTU())
+	}
+
+	transport.setSelectedCandidatePair(&ICECandidatePair{
+		Local:  &ICECandidate{Typ: ICECandidateTypeHost, Protocol: ICEProtocolUDP},
+		Remote: &ICECandidate{Typ: ICECandidateTypeSrflx, Protocol: ICEProtocolUDP},
+	})
+	assert.Equal(t, 800, track.MTU())
+	assert.NoError(t, track.SetMTU(0))
+	assert.Equal(t, 1200, track.MTU())
+}
@@ -6,12 +6,58 @@ type OfferAnswerOptions struct {
 	// VoiceActivityDetection allows the application to provide information
 	// about whether it wishes voice detection feature to be enabled or disabled.
 	VoiceActivityDetection bool
+
+	// Codecs restricts the codecs placed in the generated description to the
+	// registered codecs with a matching name, e.g. []string{"opus"} produces
+	// an audio only description from a MediaEngine that also has video codecs.
+	// Media sections left without any codec are rejected. When empty every
+	// registered codec is used.
+	Codecs []string
 }
 
 // AnswerOptions structure describes the options used to control the answer
 // creation process.
 type AnswerOptions struct {
 	OfferAnswerOptions
+
+	// Directions forces the direction of the answered media sections with a
+	// matching mid, e.g. an SFU answers a publisher with recvonly even if the
+	// matched transceiver could also send. The direction has to be allowed
+	// by the direction of the offer, RFC 3264 Section 6.1.
+	Directions map[string]RTPTransceiverDirection
+
+	// KindDirections forces the direction of the answered media sections of
+	// a kind. Directions takes precedence for sections listed in both.
+	KindDirections map[RTPCodecType]RTPTransceiverDirection
+
+	// DTLSRole forces the DTLS role of the answerer: DTLSRoleClient answers
+	// with setup:active, DTLSRoleServer with setup:passive, e.g. for devices
+	// that insist on being the DTLS client. The role has to be allowed by
+	// the setup attribute of the offer. By default the answerer is the
+	// client unless the offer is setup:active.
+	DTLSRole DTLSRole
+}
+
+// direction returns the direction forced for a media section, or Unknown
+func (o *AnswerOptions) direction(midValue string, kind RTPCodecType) RTPTransceiverDirection {
+	if o == nil {
+		return RTPTransceiverDirection(Unknown)
+	}
+	if direction, ok := o.Directions[midValue]; ok {
+		return direction
+	}
+	if direction, ok := o.KindDirections[kind]; ok {
+		return direction
+	}
+	return RTPTransceiverDirection(Unknown)
+}
+
+// dtlsRole returns the DTLS role forced for the answer, or DTLSRoleAuto
+func (o *AnswerOptions) dtlsRole() DTLSRole {
+	if o == nil || o.DTLSRole == DTLSRole(Unknown) {
+		return DTLSRoleAuto
+	}
+	return o.DTLSRole
 }
 
 // OfferOptions structure describes the options used to control the offer
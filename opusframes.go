@@ -0,0 +1,63 @@
+// +build !js
+
+package webrtc
+
+import "time"
+
+const (
+	// opusMaxFramesPerPacket is the most frames a single Opus packet can carry
+	// https://tools.ietf.org/html/rfc6716#section-3.2.5
+	opusMaxFramesPerPacket = 48
+
+	// opusMaxPacketDuration is the most media a single Opus packet can carry
+	// https://tools.ietf.org/html/rfc6716#section-3.2.5
+	opusMaxPacketDuration = 120 * time.Millisecond
+
+	// opusMaxFrameLength is the largest frame that can be described by a frame length
+	opusMaxFrameLength = 1275
+)
+
+// combineOpusFrames packs several single frame (code 0) Opus packets into one
+// variable bitrate code 3 packet, as described by
+// https://tools.ietf.org/html/rfc6716#section-3.2.5
+// The second return value is false if the packets can't be combined, because they
+// aren't code 0 packets or use a different configuration.
+func combineOpusFrames(packets [][]byte) ([]byte, bool) {
+	switch {
+	case len(packets) == 0 || len(packets) > opusMaxFramesPerPacket:
+		return nil, false
+	case len(packets) == 1:
+		return packets[0], true
+	}
+
+	size := 2
+	for _, p := range packets {
+		if len(p) < 1 || len(p)-1 > opusMaxFrameLength {
+			return nil, false
+		}
+		// All frames must be code 0 and share config and stereo flag
+		if p[0]&0x03 != 0 || p[0]&0xFC != packets[0][0]&0xFC {
+			return nil, false
+		}
+		size += 2 + len(p) - 1
+	}
+
+	out := make([]byte, 0, size)
+	out = append(out, packets[0][0]|0x03, 0x80|byte(len(packets)))
+
+	// The length of the last frame is implicit
+	for _, p := range packets[:len(packets)-1] {
+		frameLength := len(p) - 1
+		if frameLength < 252 {
+			out = append(out, byte(frameLength))
+			continue
+		}
+		first := 252 + frameLength&0x03
+		out = append(out, byte(first), byte((frameLength-first)>>2))
+	}
+
+	for _, p := range packets {
+		out = append(out, p[1:]...)
+	}
+	return out, true
+}
@@ -0,0 +1,48 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombineOpusFrames(t *testing.T) {
+	t.Run("Single", func(t *testing.T) {
+		combined, ok := combineOpusFrames([][]byte{{0xfc, 0x01, 0x02}})
+		assert.True(t, ok)
+		assert.Equal(t, []byte{0xfc, 0x01, 0x02}, combined)
+	})
+
+	t.Run("Multiple", func(t *testing.T) {
+		combined, ok := combineOpusFrames([][]byte{{0xfc, 0x01, 0x02}, {0xfc, 0x03}})
+		assert.True(t, ok)
+		assert.Equal(t, []byte{0xff, 0x82, 0x02, 0x01, 0x02, 0x03}, combined)
+	})
+
+	t.Run("Two byte frame length", func(t *testing.T) {
+		large := append([]byte{0x78}, bytes.Repeat([]byte{0xaa}, 300)...)
+		combined, ok := combineOpusFrames([][]byte{large, {0x78, 0xbb}})
+		assert.True(t, ok)
+		// 300 = 252 + 0 + 4*12
+		assert.Equal(t, []byte{0x7b, 0x82, 252, 12}, combined[:4])
+		assert.Equal(t, 4+300+1, len(combined))
+	})
+
+	t.Run("Mismatched configuration", func(t *testing.T) {
+		_, ok := combineOpusFrames([][]byte{{0xfc, 0x01}, {0x78, 0x01}})
+		assert.False(t, ok)
+	})
+
+	t.Run("Not code 0", func(t *testing.T) {
+		_, ok := combineOpusFrames([][]byte{{0xfd, 0x01}, {0xfd, 0x01}})
+		assert.False(t, ok)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		_, ok := combineOpusFrames(nil)
+		assert.False(t, ok)
+	})
+}
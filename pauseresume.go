@@ -0,0 +1,109 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtcp"
+)
+
+// formatPauseResume is the feedback message type of PAUSE and RESUME,
+// RFC 7728 8
+const formatPauseResume uint8 = 9
+
+// Types of the PAUSE and RESUME messages, RFC 7728 8.1
+const (
+	pauseResumeTypePause uint8 = iota
+	pauseResumeTypeResume
+	pauseResumeTypePaused
+	pauseResumeTypeRefused
+)
+
+const (
+	pauseResumeHeaderLength = 12
+	pauseResumeEntryLength  = 8
+)
+
+// pauseResumeEntry is an FCI entry of a pauseResume: a request to pause or
+// resume the stream of TargetSSRC, or the PAUSED indication of its sender
+type pauseResumeEntry struct {
+	TargetSSRC uint32
+	Type       uint8
+	PauseID    uint16
+}
+
+// pauseResume carries the PAUSE and RESUME requests and the PAUSED and
+// REFUSED indications of RFC 7728. pion/rtcp doesn't implement them yet.
+type pauseResume struct {
+	SenderSSRC uint32
+	Entries    []pauseResumeEntry
+}
+
+var _ rtcp.Packet = (*pauseResume)(nil) // assert is a Packet
+
+// Marshal encodes the pauseResume in binary
+func (p pauseResume) Marshal() ([]byte, error) {
+	length := pauseResumeHeaderLength + len(p.Entries)*pauseResumeEntryLength
+	h := rtcp.Header{
+		Count:  formatPauseResume,
+		Type:   rtcp.TypeTransportSpecificFeedback,
+		Length: uint16(length/4 - 1),
+	}
+	hData, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// The media SSRC of the common header is unused, the FCI entries name
+	// the streams. None of the entries carries type specific parameters.
+	rawPacket := make([]byte, length)
+	copy(rawPacket, hData)
+	binary.BigEndian.PutUint32(rawPacket[4:], p.SenderSSRC)
+	for i, e := range p.Entries {
+		if e.Type > pauseResumeTypeRefused {
+			return nil, errors.New("unknown pause and resume message type")
+		}
+		entry := rawPacket[pauseResumeHeaderLength+i*pauseResumeEntryLength:]
+		binary.BigEndian.PutUint32(entry, e.TargetSSRC)
+		entry[4] = e.Type << 4
+		binary.BigEndian.PutUint16(entry[6:], e.PauseID)
+	}
+	return rawPacket, nil
+}
+
+// Unmarshal decodes a pauseResume from binary, skipping the type specific
+// parameters of the entries
+func (p *pauseResume) Unmarshal(rawPacket []byte) error {
+	var h rtcp.Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+	if h.Type != rtcp.TypeTransportSpecificFeedback || h.Count != formatPauseResume {
+		return errors.New("packet is not a pause and resume message")
+	}
+	length := int(h.Length+1) * 4
+	if length < pauseResumeHeaderLength || len(rawPacket) < length {
+		return errors.New("packet too short to be a pause and resume message")
+	}
+
+	p.SenderSSRC = binary.BigEndian.Uint32(rawPacket[4:])
+	p.Entries = nil
+	for i := pauseResumeHeaderLength; i+pauseResumeEntryLength <= length; {
+		p.Entries = append(p.Entries, pauseResumeEntry{
+			TargetSSRC: binary.BigEndian.Uint32(rawPacket[i:]),
+			Type:       rawPacket[i+4] >> 4,
+			PauseID:    binary.BigEndian.Uint16(rawPacket[i+6:]),
+		})
+		i += pauseResumeEntryLength + int(rawPacket[i+5])*4
+	}
+	return nil
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (p *pauseResume) DestinationSSRC() []uint32 {
+	ssrcs := make([]uint32, 0, len(p.Entries))
+	for _, e := range p.Entries {
+		ssrcs = append(ssrcs, e.TargetSSRC)
+	}
+	return ssrcs
+}
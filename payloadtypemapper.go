@@ -0,0 +1,80 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pion/rtp"
+)
+
+// PayloadTypeMapper rewrites the payload type of RTP packets that are forwarded
+// between two peers which negotiated different dynamic payload types for the
+// same codec, e.g. from a publisher to the subscribers of an SFU.
+type PayloadTypeMapper struct {
+	mapping map[uint8]uint8
+}
+
+// NewPayloadTypeMapper creates a PayloadTypeMapper translating the payload types
+// of the codecs in from into the payload types of the same codecs in to.
+// Codecs are matched by name, clock rate, channels and fmtp line. If no codec
+// has an identical fmtp line the first codec with the same name, clock rate
+// and channels is used. A MediaEngine describing what a peer negotiated can be
+// created with MediaEngine.PopulateFromSDP.
+func NewPayloadTypeMapper(from, to *MediaEngine) *PayloadTypeMapper {
+	m := &PayloadTypeMapper{mapping: map[uint8]uint8{}}
+
+	for _, fromCodec := range from.codecs {
+		var match *RTPCodec
+		for _, toCodec := range to.codecs {
+			if sameCodec(fromCodec, toCodec) {
+				match = toCodec
+				break
+			} else if match == nil &&
+				strings.EqualFold(fromCodec.Name, toCodec.Name) &&
+				fromCodec.ClockRate == toCodec.ClockRate &&
+				fromCodec.Channels == toCodec.Channels {
+				match = toCodec
+			}
+		}
+
+		if match != nil {
+			m.mapping[fromCodec.PayloadType] = match.PayloadType
+		}
+	}
+
+	return m
+}
+
+// Map returns the payload type payloadType is translated to. The second
+// return value is false if the codec has no counterpart.
+func (m *PayloadTypeMapper) Map(payloadType uint8) (uint8, bool) {
+	mapped, ok := m.mapping[payloadType]
+	return mapped, ok
+}
+
+// Rewrite translates the payload type of the marshaled RTP packet in place.
+func (m *PayloadTypeMapper) Rewrite(b []byte) error {
+	if len(b) < rtpHeaderSize {
+		return fmt.Errorf("RTP packet too short: %d bytes", len(b))
+	}
+
+	mapped, ok := m.Map(b[1] & 0x7F)
+	if !ok {
+		return ErrCodecNotFound
+	}
+	// Keep the marker bit
+	b[1] = b[1]&0x80 | mapped
+	return nil
+}
+
+// RewritePacket translates the payload type of an RTP packet.
+func (m *PayloadTypeMapper) RewritePacket(p *rtp.Packet) error {
+	mapped, ok := m.Map(p.PayloadType)
+	if !ok {
+		return ErrCodecNotFound
+	}
+	p.PayloadType = mapped
+	return nil
+}
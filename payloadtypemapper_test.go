@@ -0,0 +1,61 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloadTypeMapper(t *testing.T) {
+	publisher := MediaEngine{}
+	publisher.RegisterCodec(NewRTPVP8Codec(100, 90000))
+	publisher.RegisterCodec(NewRTPOpusCodec(109, 48000))
+	publisher.RegisterCodec(NewRTPG722Codec(DefaultPayloadTypeG722, 8000))
+
+	h264 := NewRTPH264Codec(126, 90000)
+	h264.SDPFmtpLine = "packetization-mode=1"
+	publisher.RegisterCodec(h264)
+
+	subscriber := MediaEngine{}
+	subscriber.RegisterDefaultCodecs()
+
+	m := NewPayloadTypeMapper(&publisher, &subscriber)
+
+	for _, test := range []struct {
+		From     uint8
+		To       uint8
+		Expected bool
+	}{
+		{100, DefaultPayloadTypeVP8, true},
+		{109, DefaultPayloadTypeOpus, true},
+		{DefaultPayloadTypeG722, DefaultPayloadTypeG722, true},
+		{126, DefaultPayloadTypeH264, true}, // fmtp differs, matched by name
+		{0, 0, false},
+	} {
+		mapped, ok := m.Map(test.From)
+		assert.Equal(t, test.Expected, ok)
+		assert.Equal(t, test.To, mapped)
+	}
+
+	raw, err := (&rtp.Packet{Header: rtp.Header{Version: 2, Marker: true, PayloadType: 100}, Payload: []byte{0x00}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, m.Rewrite(raw))
+
+	p := &rtp.Packet{}
+	assert.NoError(t, p.Unmarshal(raw))
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), p.PayloadType)
+	assert.True(t, p.Marker)
+
+	p.PayloadType = 109
+	assert.NoError(t, m.RewritePacket(p))
+	assert.Equal(t, uint8(DefaultPayloadTypeOpus), p.PayloadType)
+
+	p.PayloadType = 0
+	assert.Equal(t, ErrCodecNotFound, m.RewritePacket(p))
+	assert.Error(t, m.Rewrite([]byte{0x80}))
+}
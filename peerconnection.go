@@ -1,14 +1,12 @@
+//go:build !js
 // +build !js
 
 // Package webrtc implements the WebRTC 1.0 as defined in W3C WebRTC specification document.
 package webrtc
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
+	"context"
 	"fmt"
-	mathRand "math/rand"
 	"regexp"
 	"strconv"
 	"strings"
@@ -45,9 +43,18 @@ type PeerConnection struct {
 	isClosed          bool
 	negotiationNeeded bool
 
+	// srtpOpened is set once the transports are connected and the receivers
+	// of the remote tracks are started, later tracks are started when the
+	// remote description is updated
+	srtpOpened bool
+
 	lastOffer  string
 	lastAnswer string
 
+	// sdesCrypto has the SRTP master key of local descriptions if
+	// SettingEngine.SetInsecureSDES is enabled
+	sdesCrypto *sdesCrypto
+
 	rtpTransceivers []*RTPTransceiver
 
 	// DataChannels
@@ -59,16 +66,45 @@ type PeerConnection struct {
 	onSignalingStateChangeHandler     func(SignalingState)
 	onICEConnectionStateChangeHandler func(ICEConnectionState)
 	onTrackHandler                    func(*Track, *RTPReceiver)
+	onDataChannelRequestHandler       func(DataChannelParameters) bool
 	onDataChannelHandler              func(*DataChannel)
+	onQualityScoreHandler             func(QualityScore)
+	onSSRCCollisionHandler            func(uint32)
+	onICECredentialRotationHandler    func(SessionDescription)
+
+	// scopes of the handlers that are bound to a context, see
+	// OnTrackContext
+	onTrackScope        *handlerScope
+	onDataChannelScope  *handlerScope
+	onICECandidateScope *handlerScope
+
+	// closed is closed by Close
+	closed chan struct{}
+
+	// ssrcs of the local and remote tracks, see NewTrack
+	ssrcs *ssrcAllocator
+
+	// qualityScoreDone stops the goroutine of OnQualityScore, it is nil
+	// until a handler is set
+	qualityScoreDone chan struct{}
+
+	// iceCredentialRotation restarts ICE periodically, see
+	// SettingEngine.SetICECredentialRotationInterval, and
+	// iceCredentialRotationAnswer rolls back a rotation the remote doesn't
+	// answer
+	iceCredentialRotation       *time.Timer
+	iceCredentialRotationAnswer *time.Timer
 
 	iceGatherer   *ICEGatherer
 	iceTransport  *ICETransport
 	dtlsTransport *DTLSTransport
 	sctpTransport *SCTPTransport
 
-	// A reference to the associated API state used by this connection
-	api *API
-	log logging.LeveledLogger
+	// A reference to the associated API state used by this connection, its
+	// LoggerFactory is loggerFactory
+	api           *API
+	loggerFactory *peerConnectionLoggerFactory
+	log           logging.LeveledLogger
 }
 
 // NewPeerConnection creates a peerconnection with the default
@@ -85,8 +121,10 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	// https://w3c.github.io/webrtc-pc/#constructor (Step #2)
 	// Some variables defined explicitly despite their implicit zero values to
 	// allow better readability to understand what is happening.
+	id := "PeerConnection-" + util.RandSeq(16)
+	loggerFactory := newPeerConnectionLoggerFactory(api.settingEngine.LoggerFactory, id)
 	pc := &PeerConnection{
-		statsID: fmt.Sprintf("PeerConnection-%d", time.Now().UnixNano()),
+		statsID: id,
 		configuration: Configuration{
 			ICEServers:           []ICEServer{},
 			ICETransportPolicy:   ICETransportPolicyAll,
@@ -103,9 +141,12 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		iceConnectionState: ICEConnectionStateNew,
 		connectionState:    PeerConnectionStateNew,
 		dataChannels:       make(map[uint16]*DataChannel),
+		ssrcs:              newSSRCAllocator(),
+		closed:             make(chan struct{}),
 
-		api: api,
-		log: api.settingEngine.LoggerFactory.NewLogger("pc"),
+		api:           api.withLoggerFactory(loggerFactory),
+		loggerFactory: loggerFactory,
+		log:           loggerFactory.NewLogger("pc"),
 	}
 
 	var err error
@@ -135,6 +176,13 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	}
 	pc.dtlsTransport = dtlsTransport
 
+	if api.settingEngine.insecureSDES {
+		if pc.sdesCrypto, err = newSDESCrypto(api.settingEngine.getCryptoProvider().Rand()); err != nil {
+			return nil, err
+		}
+	}
+
+	api.peerConnections.add(pc)
 	return pc, nil
 }
 
@@ -158,11 +206,7 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 			pc.configuration.Certificates = append(pc.configuration.Certificates, x509Cert)
 		}
 	} else {
-		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-		if err != nil {
-			return &rtcerr.UnknownError{Err: err}
-		}
-		certificate, err := GenerateCertificate(sk)
+		certificate, err := pc.api.generateCertificate()
 		if err != nil {
 			return err
 		}
@@ -229,18 +273,32 @@ func (pc *PeerConnection) onSignalingStateChange(newState SignalingState) (done
 	return
 }
 
+// OnDataChannelRequest sets a handler that decides whether a data channel the
+// remote opens is accepted, servers use it to enforce a naming policy or to
+// cap the number of channels of a peer. Refused channels are closed before a
+// DataChannel is created for them, see SCTPTransport.OnDataChannelRequest.
+func (pc *PeerConnection) OnDataChannelRequest(f func(DataChannelParameters) bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onDataChannelRequestHandler = f
+}
+
 // OnDataChannel sets an event handler which is invoked when a data
 // channel message arrives from a remote peer.
 func (pc *PeerConnection) OnDataChannel(f func(*DataChannel)) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 	pc.onDataChannelHandler = f
+	pc.onDataChannelScope = nil
 }
 
 // OnICECandidate sets an event handler which is invoked when a new ICE
 // candidate is found.
 func (pc *PeerConnection) OnICECandidate(f func(*ICECandidate)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
 	pc.iceGatherer.OnLocalCandidate(f)
+	pc.onICECandidateScope = nil
 }
 
 // OnICEGatheringStateChange sets an event handler which is invoked when the
@@ -255,6 +313,28 @@ func (pc *PeerConnection) OnTrack(f func(*Track, *RTPReceiver)) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 	pc.onTrackHandler = f
+	pc.onTrackScope = nil
+}
+
+// OnSSRCCollision sets an event handler which is called when the remote uses
+// the SSRC of a local track, announced in its description or as the sender
+// of RTCP, RFC 3550 8.2. The RTCP of the senders is only inspected while it
+// is read. The track has to be replaced by one with a new SSRC, see NewTrack.
+func (pc *PeerConnection) OnSSRCCollision(f func(ssrc uint32)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onSSRCCollisionHandler = f
+}
+
+func (pc *PeerConnection) ssrcCollision(ssrc uint32) {
+	pc.mu.RLock()
+	handler := pc.onSSRCCollisionHandler
+	pc.mu.RUnlock()
+
+	pc.log.Warnf("remote uses the SSRC %d of a local track", ssrc)
+	if handler != nil {
+		handler(ssrc)
+	}
 }
 
 func (pc *PeerConnection) onTrack(t *Track, r *RTPReceiver) (done chan struct{}) {
@@ -285,6 +365,11 @@ func (pc *PeerConnection) OnICEConnectionStateChange(f func(ICEConnectionState))
 	pc.onICEConnectionStateChangeHandler = f
 }
 
+func (pc *PeerConnection) selectedCandidatePairChange(pair *ICECandidatePair) {
+	pc.log.Infof("selected candidate pair changed: %s", pair)
+	pc.dtlsTransport.setSelectedCandidatePair(pair)
+}
+
 func (pc *PeerConnection) onICEConnectionStateChange(cs ICEConnectionState) (done chan struct{}) {
 	pc.mu.RLock()
 	hdlr := pc.onICEConnectionStateChangeHandler
@@ -308,16 +393,19 @@ func (pc *PeerConnection) onICEConnectionStateChange(cs ICEConnectionState) (don
 // SetConfiguration updates the configuration of this PeerConnection object.
 func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-setconfiguration (step #2)
-	if pc.isClosed {
+	if pc.hasClosed() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	// The configuration is only replaced once all of it is valid
+	updated := pc.configuration
+
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #3)
 	if configuration.PeerIdentity != "" {
 		if configuration.PeerIdentity != pc.configuration.PeerIdentity {
 			return &rtcerr.InvalidModificationError{Err: ErrModifyingPeerIdentity}
 		}
-		pc.configuration.PeerIdentity = configuration.PeerIdentity
+		updated.PeerIdentity = configuration.PeerIdentity
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #4)
@@ -331,7 +419,7 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 				return &rtcerr.InvalidModificationError{Err: ErrModifyingCertificates}
 			}
 		}
-		pc.configuration.Certificates = configuration.Certificates
+		updated.Certificates = configuration.Certificates
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #5)
@@ -339,7 +427,7 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 		if configuration.BundlePolicy != pc.configuration.BundlePolicy {
 			return &rtcerr.InvalidModificationError{Err: ErrModifyingBundlePolicy}
 		}
-		pc.configuration.BundlePolicy = configuration.BundlePolicy
+		updated.BundlePolicy = configuration.BundlePolicy
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #6)
@@ -347,7 +435,7 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 		if configuration.RTCPMuxPolicy != pc.configuration.RTCPMuxPolicy {
 			return &rtcerr.InvalidModificationError{Err: ErrModifyingRTCPMuxPolicy}
 		}
-		pc.configuration.RTCPMuxPolicy = configuration.RTCPMuxPolicy
+		updated.RTCPMuxPolicy = configuration.RTCPMuxPolicy
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #7)
@@ -356,12 +444,12 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 			pc.LocalDescription() != nil {
 			return &rtcerr.InvalidModificationError{Err: ErrModifyingICECandidatePoolSize}
 		}
-		pc.configuration.ICECandidatePoolSize = configuration.ICECandidatePoolSize
+		updated.ICECandidatePoolSize = configuration.ICECandidatePoolSize
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #8)
 	if configuration.ICETransportPolicy != ICETransportPolicy(Unknown) {
-		pc.configuration.ICETransportPolicy = configuration.ICETransportPolicy
+		updated.ICETransportPolicy = configuration.ICETransportPolicy
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11)
@@ -372,8 +460,20 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 				return err
 			}
 		}
-		pc.configuration.ICEServers = configuration.ICEServers
+		updated.ICEServers = cloneICEServers(configuration.ICEServers)
+	}
+
+	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.4)
+	// The servers and the policy are used the next time candidates are
+	// gathered, candidates that were gathered already are kept.
+	if err := pc.iceGatherer.setOptions(ICEGatherOptions{
+		ICEServers:      updated.ICEServers,
+		ICEGatherPolicy: updated.ICETransportPolicy,
+	}); err != nil {
+		return err
 	}
+
+	pc.configuration = updated
 	return nil
 }
 
@@ -383,7 +483,29 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 // has been called with Configuration passed as its only argument.
 // https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-getconfiguration
 func (pc *PeerConnection) GetConfiguration() Configuration {
-	return pc.configuration
+	configuration := pc.configuration
+	configuration.ICEServers = cloneICEServers(pc.configuration.ICEServers)
+	configuration.Certificates = append([]Certificate{}, pc.configuration.Certificates...)
+	return configuration
+}
+
+// ID returns the unique ID of the PeerConnection. The ID prefixes the log
+// messages of the PeerConnection and of its transports, and is the ID of its
+// PeerConnectionStats.
+func (pc *PeerConnection) ID() string {
+	return pc.statsID
+}
+
+// Label returns the label set with SetLabel
+func (pc *PeerConnection) Label() string {
+	return pc.loggerFactory.label.Load().(string)
+}
+
+// SetLabel sets a label that is logged along with the ID of the
+// PeerConnection and reported in its PeerConnectionStats, e.g. the user or
+// the session of a server the PeerConnection belongs to.
+func (pc *PeerConnection) SetLabel(label string) {
+	pc.loggerFactory.label.Store(label)
 }
 
 func (pc *PeerConnection) getStatsID() string {
@@ -396,14 +518,24 @@ func (pc *PeerConnection) getStatsID() string {
 func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription, error) {
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case options != nil:
-		return SessionDescription{}, fmt.Errorf("TODO handle options")
 	case useIdentity:
 		return SessionDescription{}, fmt.Errorf("TODO handle identity provider")
-	case pc.isClosed:
+	case pc.hasClosed():
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	// Before the first negotiation there are no credentials to replace
+	if options != nil && options.ICERestart && pc.currentRemoteDescription != nil {
+		if err := pc.iceTransport.restartGatherer(); err != nil {
+			return SessionDescription{}, err
+		}
+	}
+
+	var codecNames []string
+	if options != nil {
+		codecNames = options.Codecs
+	}
+
 	d := sdp.NewJSEPSessionDescription(useIdentity)
 	if err := pc.addFingerprint(d); err != nil {
 		return SessionDescription{}, err
@@ -420,10 +552,8 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 	}
 
 	bundleValue := "BUNDLE"
-	bundleCount := 0
 	appendBundle := func(midValue string) {
 		bundleValue += " " + midValue
-		bundleCount++
 	}
 
 	if pc.configuration.SDPSemantics == SDPSemanticsPlanB {
@@ -439,38 +569,49 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		}
 
 		if len(video) > 0 {
-			if err = pc.addTransceiverSDP(d, "video", iceParams, candidates, sdp.ConnectionRoleActpass, video...); err != nil {
+			if err = pc.addTransceiverSDP(d, "video", iceParams, candidates, sdp.ConnectionRoleActpass, codecNames, RTPTransceiverDirection(Unknown), video...); err != nil {
 				return SessionDescription{}, err
 			}
 			appendBundle("video")
 		}
 		if len(audio) > 0 {
-			if err = pc.addTransceiverSDP(d, "audio", iceParams, candidates, sdp.ConnectionRoleActpass, audio...); err != nil {
+			if err = pc.addTransceiverSDP(d, "audio", iceParams, candidates, sdp.ConnectionRoleActpass, codecNames, RTPTransceiverDirection(Unknown), audio...); err != nil {
 				return SessionDescription{}, err
 			}
 			appendBundle("audio")
 		}
+
+		addDataMediaSection(d, "data", iceParams, candidates, sdp.ConnectionRoleActpass)
+		appendBundle("data")
 	} else {
-		for _, t := range pc.GetTransceivers() {
-			midValue := strconv.Itoa(bundleCount)
-			if err = pc.addTransceiverSDP(d, midValue, iceParams, candidates, sdp.ConnectionRoleActpass, t); err != nil {
-				return SessionDescription{}, err
+		for _, section := range pc.offerSections() {
+			switch {
+			case section.rejected != nil:
+				addRejectedMediaSection(d, section.rejected, section.mid)
+				continue
+			case section.transceiver == nil:
+				addDataMediaSection(d, section.mid, iceParams, candidates, sdp.ConnectionRoleActpass)
+			default:
+				section.transceiver.mid = section.mid
+				if err = pc.addTransceiverSDP(d, section.mid, iceParams, candidates, sdp.ConnectionRoleActpass, codecNames, RTPTransceiverDirection(Unknown), section.transceiver); err != nil {
+					return SessionDescription{}, err
+				}
 			}
-			appendBundle(midValue)
+			appendBundle(section.mid)
 		}
 	}
 
-	midValue := strconv.Itoa(bundleCount)
-	if pc.configuration.SDPSemantics == SDPSemanticsPlanB {
-		midValue = "data"
-	}
-	pc.addDataMediaSection(d, midValue, iceParams, candidates, sdp.ConnectionRoleActpass)
-	appendBundle(midValue)
-
 	d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue)
+	pc.addSDESCrypto(d, nil)
 
-	for _, m := range d.MediaDescriptions {
+	for i, m := range d.MediaDescriptions {
 		m.WithPropertyAttribute("setup:actpass")
+
+		// With max-bundle only the first section can be used without BUNDLE, JSEP 5.2.1
+		if i != 0 && pc.configuration.BundlePolicy == BundlePolicyMaxBundle {
+			m.MediaName.Port.Value = 0
+			m.WithPropertyAttribute(sdpAttrKeyBundleOnly)
+		}
 	}
 
 	sdpBytes, err := d.Marshal()
@@ -525,6 +666,7 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 		}
 		pc.iceStateChange(cs)
 	})
+	t.OnSelectedCandidatePairChange(pc.selectedCandidatePairChange)
 
 	return t
 }
@@ -547,6 +689,21 @@ func (pc *PeerConnection) getMidValue(media *sdp.MediaDescription) string {
 	return ""
 }
 
+// remoteMediaByMid returns the media section of the remote description with
+// the given mid, or nil
+func (pc *PeerConnection) remoteMediaByMid(mid string) *sdp.MediaDescription {
+	remote := pc.RemoteDescription()
+	if mid == "" || remote == nil || remote.parsed == nil {
+		return nil
+	}
+	for _, media := range remote.parsed.MediaDescriptions {
+		if pc.getMidValue(media) == mid {
+			return media
+		}
+	}
+	return nil
+}
+
 // Given a direction+type pluck a transceiver from the passed list
 // if no entry satisfies the requested type+direction return a inactive Transceiver
 func satisfyTypeAndDirection(remoteKind RTPCodecType, remoteDirection RTPTransceiverDirection, localTransceivers []*RTPTransceiver) (*RTPTransceiver, []*RTPTransceiver) {
@@ -566,7 +723,7 @@ func satisfyTypeAndDirection(remoteKind RTPCodecType, remoteDirection RTPTransce
 	for _, possibleDirection := range getPreferredDirections() {
 		for i := range localTransceivers {
 			t := localTransceivers[i]
-			if t.kind != remoteKind || possibleDirection != t.Direction {
+			if t.isStopped() || t.kind != remoteKind || possibleDirection != t.Direction {
 				continue
 			}
 
@@ -580,7 +737,38 @@ func satisfyTypeAndDirection(remoteKind RTPCodecType, remoteDirection RTPTransce
 	}, localTransceivers
 }
 
-func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription) (*sdp.SessionDescription, error) {
+// answerDirectionAllowed reports if an answer can use direction for a media
+// section offered with offered, RFC 3264 Section 6.1
+func answerDirectionAllowed(offered, direction RTPTransceiverDirection) bool {
+	switch offered {
+	case RTPTransceiverDirectionSendrecv:
+		return true
+	case RTPTransceiverDirectionSendonly:
+		return direction == RTPTransceiverDirectionRecvonly || direction == RTPTransceiverDirectionInactive
+	case RTPTransceiverDirectionRecvonly:
+		return direction == RTPTransceiverDirectionSendonly || direction == RTPTransceiverDirectionInactive
+	default:
+		return direction == RTPTransceiverDirectionInactive
+	}
+}
+
+// takeTransceiverByMid removes the transceiver negotiated in the media
+// section midValue from localTransceivers, it returns nil if there is none
+func takeTransceiverByMid(midValue string, localTransceivers []*RTPTransceiver) (*RTPTransceiver, []*RTPTransceiver) {
+	for i, t := range localTransceivers {
+		if t.mid == midValue {
+			return t, append(localTransceivers[:i], localTransceivers[i+1:]...)
+		}
+	}
+	return nil, localTransceivers
+}
+
+func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription, options *AnswerOptions) (*sdp.SessionDescription, error) {
+	var codecNames []string
+	if options != nil {
+		codecNames = options.Codecs
+	}
+
 	iceParams, err := pc.iceGatherer.GetLocalParameters()
 	if err != nil {
 		return nil, err
@@ -591,34 +779,70 @@ func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription)
 		return nil, err
 	}
 
+	dtlsRole, err := answerConnectionRole(connectionSetup(pc.RemoteDescription().parsed), options.dtlsRole())
+	if err != nil {
+		return nil, err
+	}
+
 	bundleValue := "BUNDLE"
+	bundleCount := 0
 	appendBundle := func(midValue string) {
 		bundleValue += " " + midValue
+		bundleCount++
 	}
 
 	var t *RTPTransceiver
+	var answeredData bool
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
 	detectedPlanB := pc.descriptionIsPlanB(pc.RemoteDescription())
 
+	// A remote that isn't bundle-aware only gets a single media section with max-bundle
+	remoteBundle := descriptionHasBundle(pc.RemoteDescription().parsed)
+	singleSection := !remoteBundle && pc.configuration.BundlePolicy == BundlePolicyMaxBundle
+
+	// Every section of the offer is answered in the same order and with the
+	// same mid, the sections that can't be used are rejected
 	for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
 		midValue := pc.getMidValue(media)
 		if midValue == "" {
 			return nil, fmt.Errorf("RemoteDescription contained media section without mid value")
 		}
 
+		if singleSection && bundleCount != 0 {
+			addRejectedMediaSection(d, media, midValue)
+			continue
+		}
+
 		if media.MediaName.Media == "application" {
-			pc.addDataMediaSection(d, midValue, iceParams, candidates, sdp.ConnectionRoleActive)
+			// There is a single SCTP association, further data sections are rejected
+			if isRejectedMediaSection(media) || answeredData {
+				addRejectedMediaSection(d, media, midValue)
+				continue
+			}
+			addDataMediaSection(d, midValue, iceParams, candidates, dtlsRole)
 			appendBundle(midValue)
+			answeredData = true
+			continue
+		}
+
+		// A section that was negotiated before keeps its transceiver, unless
+		// one of the sides stopped it
+		t, localTransceivers = takeTransceiverByMid(midValue, localTransceivers)
+		if isRejectedMediaSection(media) || (t != nil && t.isStopped()) {
+			addRejectedMediaSection(d, media, midValue)
 			continue
 		}
 
 		kind := NewRTPCodecType(media.MediaName.Media)
 		direction := pc.getPeerDirection(media)
 		if kind == 0 || direction == RTPTransceiverDirection(Unknown) {
+			addRejectedMediaSection(d, media, midValue)
 			continue
 		}
 
-		t, localTransceivers = satisfyTypeAndDirection(kind, direction, localTransceivers)
+		if t == nil {
+			t, localTransceivers = satisfyTypeAndDirection(kind, direction, localTransceivers)
+		}
 		mediaTransceivers := []*RTPTransceiver{t}
 		switch pc.configuration.SDPSemantics {
 		case SDPSemanticsUnifiedPlanWithFallback:
@@ -647,7 +871,14 @@ func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription)
 				return nil, &rtcerr.TypeError{Err: ErrIncorrectSDPSemantics}
 			}
 		}
-		if err := pc.addTransceiverSDP(d, midValue, iceParams, candidates, sdp.ConnectionRoleActive, mediaTransceivers...); err != nil {
+		if !detectedPlanB {
+			t.mid = midValue
+		}
+		forcedDirection := options.direction(midValue, kind)
+		if forcedDirection != RTPTransceiverDirection(Unknown) && !answerDirectionAllowed(direction, forcedDirection) {
+			return nil, &rtcerr.InvalidAccessError{Err: ErrIncompatibleAnswerDirection}
+		}
+		if err := pc.addTransceiverSDP(d, midValue, iceParams, candidates, dtlsRole, codecNames, forcedDirection, mediaTransceivers...); err != nil {
 			return nil, err
 		}
 		appendBundle(midValue)
@@ -657,6 +888,9 @@ func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription)
 		pc.log.Info("Plan-B Offer detected; responding with Plan-B Answer")
 	}
 
+	if singleSection {
+		return d, nil
+	}
 	return d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue), nil
 }
 
@@ -664,25 +898,33 @@ func (pc *PeerConnection) addAnswerMediaTransceivers(d *sdp.SessionDescription)
 func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescription, error) {
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case options != nil:
-		return SessionDescription{}, fmt.Errorf("TODO handle options")
 	case pc.RemoteDescription() == nil:
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
 	case useIdentity:
 		return SessionDescription{}, fmt.Errorf("TODO handle identity provider")
-	case pc.isClosed:
+	case pc.hasClosed():
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-createanswer (step #4)
+	if state := pc.SignalingState(); state != SignalingStateHaveRemoteOffer && state != SignalingStateHaveLocalPranswer {
+		return SessionDescription{}, &rtcerr.InvalidStateError{Err: &SignalingStateError{
+			State:     state,
+			Operation: "CreateAnswer",
+			Expected:  []SignalingState{SignalingStateHaveRemoteOffer, SignalingStateHaveLocalPranswer},
+		}}
+	}
+
 	d := sdp.NewJSEPSessionDescription(useIdentity)
 	if err := pc.addFingerprint(d); err != nil {
 		return SessionDescription{}, err
 	}
 
-	d, err := pc.addAnswerMediaTransceivers(d)
+	d, err := pc.addAnswerMediaTransceivers(d, options)
 	if err != nil {
 		return SessionDescription{}, err
 	}
+	pc.addSDESCrypto(d, pc.RemoteDescription())
 
 	sdpBytes, err := d.Marshal()
 	if err != nil {
@@ -700,11 +942,11 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 
 // 4.4.1.6 Set the SessionDescription
 func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeOp) error {
-	if pc.isClosed {
+	if pc.hasClosed() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
-	cur := pc.signalingState
+	cur := pc.SignalingState()
 	setLocal := stateChangeOpSetLocal
 	setRemote := stateChangeOpSetRemote
 	newSDPDoesNotMatchOffer := &rtcerr.InvalidModificationError{Err: fmt.Errorf("new sdp does not match previous offer")}
@@ -717,21 +959,21 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 		switch sd.Type {
 		// stable->SetLocal(offer)->have-local-offer
 		case SDPTypeOffer:
-			if sd.SDP != pc.lastOffer {
-				return newSDPDoesNotMatchOffer
-			}
 			nextState, err = checkNextSignalingState(cur, SignalingStateHaveLocalOffer, setLocal, sd.Type)
 			if err == nil {
+				if sd.SDP != pc.lastOffer {
+					return newSDPDoesNotMatchOffer
+				}
 				pc.pendingLocalDescription = sd
 			}
 		// have-remote-offer->SetLocal(answer)->stable
 		// have-local-pranswer->SetLocal(answer)->stable
 		case SDPTypeAnswer:
-			if sd.SDP != pc.lastAnswer {
-				return newSDPDoesNotMatchAnswer
-			}
 			nextState, err = checkNextSignalingState(cur, SignalingStateStable, setLocal, sd.Type)
 			if err == nil {
+				if sd.SDP != pc.lastAnswer {
+					return newSDPDoesNotMatchAnswer
+				}
 				pc.currentLocalDescription = sd
 				pc.currentRemoteDescription = pc.pendingRemoteDescription
 				pc.pendingRemoteDescription = nil
@@ -744,11 +986,11 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 			}
 		// have-remote-offer->SetLocal(pranswer)->have-local-pranswer
 		case SDPTypePranswer:
-			if sd.SDP != pc.lastAnswer {
-				return newSDPDoesNotMatchAnswer
-			}
 			nextState, err = checkNextSignalingState(cur, SignalingStateHaveLocalPranswer, setLocal, sd.Type)
 			if err == nil {
+				if sd.SDP != pc.lastAnswer {
+					return newSDPDoesNotMatchAnswer
+				}
 				pc.pendingLocalDescription = sd
 			}
 		default:
@@ -772,11 +1014,10 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 				pc.pendingRemoteDescription = nil
 				pc.pendingLocalDescription = nil
 			}
+		// A remote offer can't be rolled back, this always fails with a
+		// SignalingStateError
 		case SDPTypeRollback:
 			nextState, err = checkNextSignalingState(cur, SignalingStateStable, setRemote, sd.Type)
-			if err == nil {
-				pc.pendingRemoteDescription = nil
-			}
 		// have-local-offer->SetRemote(pranswer)->have-remote-pranswer
 		case SDPTypePranswer:
 			nextState, err = checkNextSignalingState(cur, SignalingStateHaveRemotePranswer, setRemote, sd.Type)
@@ -791,7 +1032,9 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 	}
 
 	if err == nil {
+		pc.mu.Lock()
 		pc.signalingState = nextState
+		pc.mu.Unlock()
 		pc.onSignalingStateChange(nextState)
 	}
 	return err
@@ -799,10 +1042,31 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 
 // SetLocalDescription sets the SessionDescription of the local peer
 func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
-	if pc.isClosed {
+	return pc.SetLocalDescriptionContext(context.Background(), desc)
+}
+
+// SetLocalDescriptionContext is SetLocalDescription bounded by ctx. Without
+// trickle ICE the candidates are gathered and signaled before it returns, if
+// ctx is done before that the PeerConnection is closed and the error of ctx
+// is returned.
+func (pc *PeerConnection) SetLocalDescriptionContext(ctx context.Context, desc SessionDescription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if pc.hasClosed() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	// A rollback discards the pending offer, it has no SDP. The credentials
+	// of an ICE restart it offered are discarded as well.
+	if desc.Type == SDPTypeRollback {
+		if err := pc.setDescription(&desc, stateChangeOpSetLocal); err != nil {
+			return err
+		}
+		pc.iceTransport.cancelRestart()
+		return nil
+	}
+
 	// JSEP 5.4
 	if desc.SDP == "" {
 		switch desc.Type {
@@ -824,24 +1088,51 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 	if err := pc.setDescription(&desc, stateChangeOpSetLocal); err != nil {
 		return err
 	}
+	if desc.Type == SDPTypeAnswer {
+		pc.removeStoppedTransceivers()
+		pc.startRenegotiatedTransceivers()
+	}
 
 	// To support all unittests which are following the future trickle=true
 	// setup while also support the old trickle=false synchronous gathering
 	// process this is necessary to avoid calling Garther() in multiple
 	// pleces; which causes race conditions. (issue-707)
 	if !pc.iceGatherer.agentIsTrickle {
-		if err := pc.iceGatherer.SignalCandidates(); err != nil {
-			return err
-		}
-		return nil
+		return pc.waitContext(ctx, pc.iceGatherer.SignalCandidates)
 	}
 
-	if desc.Type == SDPTypeAnswer {
+	// The candidates of an ICE restart are gathered once the restart is
+	// offered or answered, the agent of a renegotiation without a restart
+	// has gathered already
+	if pc.iceGatherer.State() == ICEGathererStateNew && (desc.Type == SDPTypeAnswer || pc.currentRemoteDescription != nil) {
 		return pc.iceGatherer.Gather()
 	}
 	return nil
 }
 
+// setRemoteSSRCs reserves the SSRCs of the remote description that is set,
+// a remote that uses the SSRC of a local track is reported
+func (pc *PeerConnection) setRemoteSSRCs() {
+	ssrcs := map[uint32]bool{}
+	if desc := pc.RemoteDescription(); desc != nil && desc.parsed != nil {
+		ssrcs = newRemoteRTP(desc.parsed).ssrcs
+	}
+	for _, ssrc := range pc.ssrcs.setRemote(ssrcs) {
+		pc.ssrcCollision(ssrc)
+	}
+}
+
+// releaseTrack releases the SSRCs of track unless a transceiver that isn't
+// stopped still sends it
+func (pc *PeerConnection) releaseTrack(track *Track) {
+	for _, t := range pc.GetTransceivers() {
+		if !t.isStopped() && t.Sender != nil && t.Sender.track == track {
+			return
+		}
+	}
+	pc.ssrcs.removeTrack(track)
+}
+
 // LocalDescription returns pendingLocalDescription if it is not null and
 // otherwise it returns currentLocalDescription. This property is used to
 // determine if setLocalDescription has already been called.
@@ -853,22 +1144,55 @@ func (pc *PeerConnection) LocalDescription() *SessionDescription {
 	return pc.currentLocalDescription
 }
 
-// SetRemoteDescription sets the SessionDescription of the remote peer
-func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error { //nolint pion/webrtc#614
-	if pc.currentRemoteDescription != nil { // pion/webrtc#207
-		return fmt.Errorf("remoteDescription is already defined, SetRemoteDescription can only be called once")
+// SetRemoteDescription sets the SessionDescription of the remote peer.
+// Descriptions that can't be negotiated are rejected with an *SDPValidationError
+// pointing at the offending media section.
+func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
+	return pc.SetRemoteDescriptionContext(context.Background(), desc)
+}
+
+// SetRemoteDescriptionContext is SetRemoteDescription bounded by ctx. The ICE
+// and DTLS transports are connected in the background after the description
+// is set, if ctx is done before they are connected the PeerConnection is
+// closed. This allows to give up on a call attempt cleanly.
+func (pc *PeerConnection) SetRemoteDescriptionContext(ctx context.Context, desc SessionDescription) error { //nolint pion/webrtc#614
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	if pc.isClosed {
+	if pc.hasClosed() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
+	if desc.Type == SDPTypeRollback {
+		if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
+			return err
+		}
+		pc.setRemoteSSRCs()
+		return nil
+	}
 
 	desc.parsed = &sdp.SessionDescription{}
 	if err := desc.parsed.Unmarshal([]byte(desc.SDP)); err != nil {
 		return err
 	}
+	if err := validateRemoteDescription(desc.Type, desc.parsed, pc.sdesCrypto != nil); err != nil {
+		return err
+	}
+	if !supportsRTCPMux(desc.parsed) {
+		if pc.configuration.RTCPMuxPolicy == RTCPMuxPolicyRequire {
+			return &rtcerr.InvalidAccessError{Err: ErrRTCPMuxRequired}
+		}
+		pc.log.Warn("remote description does not support rtcp-mux, RTCP is still multiplexed since no RTCP candidates are gathered")
+	}
+	if pc.currentRemoteDescription != nil { // pion/webrtc#207
+		return pc.updateRemoteDescription(&desc)
+	}
 	if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
 		return err
 	}
+	pc.setRemoteSSRCs()
+	if desc.Type == SDPTypeAnswer {
+		pc.removeStoppedTransceivers()
+	}
 
 	weOffer := true
 	remoteUfrag := ""
@@ -888,16 +1212,16 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 			case a.IsICECandidate():
 				sdpCandidate, err := a.ToICECandidate()
 				if err != nil {
-					return err
+					return &ICECandidateError{Candidate: a.Value, Err: err}
 				}
 
 				candidate, err := newICECandidateFromSDP(sdpCandidate)
 				if err != nil {
-					return err
+					return &ICECandidateError{Candidate: a.Value, Err: err}
 				}
 
 				if err = pc.iceTransport.AddRemoteCandidate(candidate); err != nil {
-					return err
+					return &ICECandidateError{Candidate: a.Value, Err: err}
 				}
 			case strings.HasPrefix(*a.String(), "ice-ufrag"):
 				remoteUfrag = (*a.String())[len("ice-ufrag:"):]
@@ -907,28 +1231,47 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		}
 	}
 
-	if !haveFingerprint {
-		return fmt.Errorf("could not find fingerprint")
-	}
+	// Legacy endpoints key SRTP with SDES instead of DTLS
+	sdesKeys := pc.sdesKeys(desc.parsed, haveFingerprint)
 
-	parts := strings.Split(fingerprint, " ")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid fingerprint")
+	var fingerprintHash string
+	switch {
+	case sdesKeys != nil:
+	case !haveFingerprint:
+		return &SDPValidationError{MediaIndex: -1, Err: ErrSDPMissingFingerprint}
+	default:
+		parts := strings.Split(fingerprint, " ")
+		if len(parts) != 2 {
+			return &SDPValidationError{MediaIndex: -1, Detail: fingerprint, Err: ErrSDPInvalidFingerprint}
+		}
+		fingerprint = parts[1]
+		fingerprintHash = parts[0]
 	}
-	fingerprint = parts[1]
-	fingerprintHash := parts[0]
 
 	// Create the SCTP transport
 	sctp := pc.api.NewSCTPTransport(pc.dtlsTransport)
 	pc.sctpTransport = sctp
 
+	// Wire up the data channel request handler
+	sctp.OnDataChannelRequest(func(params DataChannelParameters) bool {
+		if max := pc.api.settingEngine.quotas.MaxDataChannels; max != 0 && pc.openDataChannels() >= max {
+			pc.log.Warnf("Refused data channel %q, the remote exceeds the quota of %d data channels", params.Label, max)
+			return false
+		}
+
+		pc.mu.RLock()
+		hdlr := pc.onDataChannelRequestHandler
+		pc.mu.RUnlock()
+		return hdlr == nil || hdlr(params)
+	})
+
 	// Wire up the on datachannel handler
 	sctp.OnDataChannel(func(d *DataChannel) {
-		pc.mu.RLock()
+		pc.mu.Lock()
 		hdlr := pc.onDataChannelHandler
 		pc.dataChannels[*d.ID()] = d
 		pc.dataChannelsAccepted++
-		pc.mu.RUnlock()
+		pc.mu.Unlock()
 		if hdlr != nil {
 			hdlr(d)
 		}
@@ -944,6 +1287,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	go func() {
 		// Star the networking in a new routine since it will block until
 		// the connection is actually established.
+		stopWatching := pc.closeOnDone(ctx)
 
 		// Start the ice transport
 		iceRole := ICERoleControlled
@@ -961,42 +1305,49 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		)
 
 		if err != nil {
+			stopWatching()
 			// pion/webrtc#614
 			pc.log.Warnf("Failed to start manager: %s", err)
 			return
 		}
 
 		// Start the dtls transport
-		err = pc.dtlsTransport.Start(DTLSParameters{
-			Role:         DTLSRoleAuto,
-			Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
-		})
+		if sdesKeys != nil {
+			err = pc.dtlsTransport.StartSRTP(*sdesKeys)
+		} else {
+			// The answer has been applied once ICE connected, the setup
+			// attributes of both descriptions decide the roles
+			var local *sdp.SessionDescription
+			if localDescription := pc.LocalDescription(); localDescription != nil {
+				local = localDescription.parsed
+			}
+			err = pc.dtlsTransport.StartContext(ctx, DTLSParameters{
+				Role:         remoteDTLSRole(desc.parsed, local),
+				Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
+			})
+		}
+		stopWatching()
 		if err != nil {
 			// pion/webrtc#614
 			pc.log.Warnf("Failed to start manager: %s", err)
 			return
 		}
 
+		pc.mu.Lock()
+		pc.srtpOpened = true
+		pc.mu.Unlock()
 		pc.openSRTP()
+		pc.scheduleICECredentialRotation(pc.api.settingEngine.timeout.ICECredentialRotation)
 
-		for _, tranceiver := range pc.GetTransceivers() {
-			if tranceiver.Sender != nil {
-				err = tranceiver.Sender.Send(RTPSendParameters{
-					Encodings: RTPEncodingParameters{
-						RTPCodingParameters{
-							SSRC:        tranceiver.Sender.track.SSRC(),
-							PayloadType: tranceiver.Sender.track.PayloadType(),
-						},
-					}})
-
-				if err != nil {
-					pc.log.Warnf("Failed to start Sender: %s", err)
-				}
-			}
-		}
+		pc.startRTPSenders()
 
 		go pc.drainSRTP()
 
+		// SCTP needs DTLS
+		if sdesKeys != nil {
+			return
+		}
+
 		// Start sctp
 		err = pc.sctpTransport.Start(SCTPCapabilities{
 			MaxMessageSize: 0,
@@ -1037,6 +1388,54 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	return nil
 }
 
+// waitContext returns the error of f, or the error of ctx if it is done
+// first. The PeerConnection is closed then, f is left to return in the
+// background.
+func (pc *PeerConnection) waitContext(ctx context.Context, f func() error) error {
+	if ctx.Done() == nil {
+		return f()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		go func() {
+			if err := pc.Close(); err != nil {
+				pc.log.Warnf("Failed to close PeerConnection: %s", err)
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+// closeOnDone closes the PeerConnection if ctx is done before the returned
+// function is called
+func (pc *PeerConnection) closeOnDone(ctx context.Context) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := pc.Close(); err != nil {
+				pc.log.Warnf("Failed to close PeerConnection: %s", err)
+			}
+		case <-stopped:
+		}
+	}()
+
+	return func() {
+		close(stopped)
+	}
+}
+
 func (pc *PeerConnection) descriptionIsPlanB(desc *SessionDescription) bool {
 	if desc == nil || desc.parsed == nil {
 		return false
@@ -1054,12 +1453,21 @@ func (pc *PeerConnection) descriptionIsPlanB(desc *SessionDescription) bool {
 // openSRTP opens knows inbound SRTP streams from the RemoteDescription
 func (pc *PeerConnection) openSRTP() {
 	type incomingTrack struct {
-		kind  RTPCodecType
-		label string
-		id    string
-		ssrc  uint32
+		kind     RTPCodecType
+		label    string
+		id       string
+		ssrc     uint32
+		ptime    time.Duration
+		maxPTime time.Duration
+		groups   []SSRCGroup
+
+		// headerExtensions and codecs negotiated for the media section of
+		// the track
+		headerExtensions []RTPHeaderExtensionParameter
+		codecs           []RTPCodecParameters
 	}
 	incomingTracks := map[uint32]incomingTrack{}
+	pc.dtlsTransport.setRemoteDescription(pc.RemoteDescription().parsed)
 
 	remoteIsPlanB := false
 	switch pc.configuration.SDPSemantics {
@@ -1069,7 +1477,37 @@ func (pc *PeerConnection) openSRTP() {
 		remoteIsPlanB = pc.descriptionIsPlanB(pc.RemoteDescription())
 	}
 
+	// Tracks that are already received are skipped when the remote
+	// description has been updated
+	receiving := map[uint32]bool{}
+	for _, t := range pc.GetTransceivers() {
+		if t.Receiver != nil && t.Receiver.hasReceived() {
+			receiving[t.Receiver.Track().SSRC()] = true
+		}
+	}
+
 	for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
+		ptime, maxPTime := getPTimes(media)
+
+		// RTX streams and simulcast layers are announced with their own
+		// a=ssrc lines, but belong to the track of the first SSRC of a group
+		var ssrcGroups []SSRCGroup
+		secondarySSRCs := map[uint32]bool{}
+		for _, attr := range media.Attributes {
+			if attr.Key != sdpAttrKeySSRCGroup {
+				continue
+			}
+			group, err := parseSSRCGroup(attr.Value)
+			if err != nil {
+				pc.log.Warnf("Failed to parse SSRC group: %v", err)
+				continue
+			}
+			ssrcGroups = append(ssrcGroups, group)
+			for _, ssrc := range group.secondarySSRCs() {
+				secondarySSRCs[ssrc] = true
+			}
+		}
+
 		for _, attr := range media.Attributes {
 
 			codecType := NewRTPCodecType(media.MediaName.Media)
@@ -1084,6 +1522,9 @@ func (pc *PeerConnection) openSRTP() {
 					pc.log.Warnf("Failed to parse SSRC: %v", err)
 					continue
 				}
+				if secondarySSRCs[uint32(ssrc)] || receiving[uint32(ssrc)] {
+					continue
+				}
 
 				trackID := ""
 				trackLabel := ""
@@ -1092,7 +1533,11 @@ func (pc *PeerConnection) openSRTP() {
 					trackID = split[2]
 				}
 
-				incomingTracks[uint32(ssrc)] = incomingTrack{codecType, trackLabel, trackID, uint32(ssrc)}
+				incomingTracks[uint32(ssrc)] = incomingTrack{
+					codecType, trackLabel, trackID, uint32(ssrc), ptime, maxPTime, groupsContaining(ssrcGroups, uint32(ssrc)),
+					pc.api.mediaEngine.negotiatedHeaderExtensions(media, codecType),
+					pc.api.mediaEngine.negotiatedCodecs(pc.RemoteDescription().parsed, media),
+				}
 				if trackID != "" && trackLabel != "" {
 					break // Remote provided Label+ID, we have all the information we need
 				}
@@ -1103,8 +1548,15 @@ func (pc *PeerConnection) openSRTP() {
 	startReceiver := func(incoming incomingTrack, receiver *RTPReceiver) {
 		err := receiver.Receive(RTPReceiveParameters{
 			Encodings: RTPDecodingParameters{
-				RTPCodingParameters{SSRC: incoming.ssrc},
-			}})
+				RTPCodingParameters: RTPCodingParameters{
+					SSRC: incoming.ssrc,
+					RTX:  RTPRtxParameters{SSRC: rtxSSRC(incoming.groups, incoming.ssrc)},
+				},
+			},
+			Codecs:           incoming.codecs,
+			SSRCGroups:       incoming.groups,
+			HeaderExtensions: incoming.headerExtensions,
+		})
 		if err != nil {
 			pc.log.Warnf("RTPReceiver Receive failed %s", err)
 			return
@@ -1135,11 +1587,38 @@ func (pc *PeerConnection) openSRTP() {
 			return
 		}
 
+		// Tracks sent with RED are read with the codec RED carries, the
+		// RED packets are unwrapped by the track
+		var red *redDecoder
+		sentWithRED := strings.EqualFold(codec.Name, RED)
+		if sentWithRED {
+			red = &redDecoder{payloadType: codec.PayloadType}
+			if codec, err = pc.redPrimaryCodec(codec); err != nil {
+				pc.log.Warnf("no codec could be found for the RED payload type %d", red.payloadType)
+				return
+			}
+		} else if redCodec := pc.api.mediaEngine.redCodecFor(codec); redCodec != nil {
+			red = &redDecoder{payloadType: redCodec.PayloadType}
+		}
+
+		// The codec is shared with the MediaEngine, only copy it when the
+		// remote packetization has to be recorded
+		if incoming.ptime != 0 || incoming.maxPTime != 0 {
+			negotiated := *codec
+			negotiated.PTime = incoming.ptime
+			negotiated.MaxPTime = incoming.maxPTime
+			codec = &negotiated
+		}
+
 		receiver.Track().mu.Lock()
 		receiver.Track().id = incoming.id
 		receiver.Track().label = incoming.label
 		receiver.Track().kind = codec.Type
 		receiver.Track().codec = codec
+		if sentWithRED {
+			receiver.Track().payloadType = codec.PayloadType
+		}
+		receiver.Track().red = red
 		receiver.Track().mu.Unlock()
 
 		if pc.onTrackHandler != nil {
@@ -1149,6 +1628,18 @@ func (pc *PeerConnection) openSRTP() {
 		}
 	}
 
+	// Tracks beyond the quota aren't received
+	maxTracks := pc.api.settingEngine.quotas.MaxInboundTracks
+	tracks := len(receiving)
+	quotaExceeded := func(ssrc uint32) bool {
+		if maxTracks == 0 || tracks < maxTracks {
+			tracks++
+			return false
+		}
+		pc.log.Warnf("Not receiving SSRC %d, the remote exceeds the quota of %d inbound tracks", ssrc, maxTracks)
+		return true
+	}
+
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
 	for ssrc, incoming := range incomingTracks {
 		for i := range localTransceivers {
@@ -1158,11 +1649,14 @@ func (pc *PeerConnection) openSRTP() {
 				continue
 			case t.Direction != RTPTransceiverDirectionRecvonly && t.Direction != RTPTransceiverDirectionSendrecv:
 				continue
-			case t.Receiver == nil:
+			case t.Receiver == nil || t.Receiver.hasReceived():
 				continue
 			}
 
 			delete(incomingTracks, ssrc)
+			if quotaExceeded(ssrc) {
+				break
+			}
 			localTransceivers = append(localTransceivers[:i], localTransceivers[i+1:]...)
 			go startReceiver(incoming, t.Receiver)
 			break
@@ -1171,6 +1665,9 @@ func (pc *PeerConnection) openSRTP() {
 
 	if remoteIsPlanB {
 		for ssrc, incoming := range incomingTracks {
+			if quotaExceeded(ssrc) {
+				continue
+			}
 			t, err := pc.AddTransceiver(incoming.kind, RtpTransceiverInit{
 				Direction: RTPTransceiverDirectionSendrecv,
 			})
@@ -1183,6 +1680,85 @@ func (pc *PeerConnection) openSRTP() {
 	}
 }
 
+// startRTPSenders starts the senders of all transceivers that have a track
+// and haven't been started yet
+func (pc *PeerConnection) startRTPSenders() {
+	remoteAudioPTime, remoteAudioMaxPTime := audioPTimes(pc.RemoteDescription().parsed)
+	for _, tranceiver := range pc.GetTransceivers() {
+		if tranceiver.Sender != nil && !tranceiver.Sender.hasSent() {
+			if tranceiver.kind == RTPCodecTypeAudio {
+				tranceiver.Sender.setRemotePTime(remoteAudioPTime)
+				tranceiver.Sender.setRemoteMaxPTime(remoteAudioMaxPTime)
+			}
+			var headerExtensions []RTPHeaderExtensionParameter
+			if media := pc.remoteMediaByMid(tranceiver.Mid()); media != nil {
+				headerExtensions = pc.api.mediaEngine.negotiatedHeaderExtensions(media, tranceiver.kind)
+				if red := pc.api.mediaEngine.redCodecFor(tranceiver.Sender.track.Codec()); red != nil && hasFormat(media, red.PayloadType) {
+					tranceiver.Sender.setREDPayloadType(red.PayloadType)
+				}
+			}
+
+			err := tranceiver.Sender.Send(RTPSendParameters{
+				Encodings: RTPEncodingParameters{
+					RTPCodingParameters: RTPCodingParameters{
+						SSRC:        tranceiver.Sender.track.SSRC(),
+						PayloadType: tranceiver.Sender.track.PayloadType(),
+					},
+				},
+				HeaderExtensions: headerExtensions,
+			})
+
+			if err != nil {
+				pc.log.Warnf("Failed to start Sender: %s", err)
+			}
+		}
+	}
+}
+
+// redPrimaryCodec returns the codec carried by a RED codec of the local
+// description. pc.mu has to be held.
+func (pc *PeerConnection) redPrimaryCodec(red *RTPCodec) (*RTPCodec, error) {
+	associated := associatedPayloadTypes(red)
+	if len(associated) == 0 {
+		return nil, ErrCodecNotFound
+	}
+	sdpCodec, err := pc.currentLocalDescription.parsed.GetCodecForPayloadType(associated[0])
+	if err != nil {
+		return nil, err
+	}
+	return pc.api.mediaEngine.getCodecSDP(sdpCodec)
+}
+
+// hasFormat tells if a media section lists the payload type
+func hasFormat(media *sdp.MediaDescription, payloadType uint8) bool {
+	for _, format := range media.MediaName.Formats {
+		if format == strconv.Itoa(int(payloadType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// audioPTimes returns the smallest ptime and maxptime of the audio sections
+// of d
+func audioPTimes(d *sdp.SessionDescription) (pTime, maxPTime time.Duration) {
+	smallest := func(current, candidate time.Duration) time.Duration {
+		if candidate != 0 && (current == 0 || candidate < current) {
+			return candidate
+		}
+		return current
+	}
+	for _, m := range d.MediaDescriptions {
+		if NewRTPCodecType(m.MediaName.Media) != RTPCodecTypeAudio {
+			continue
+		}
+		sectionPTime, sectionMaxPTime := getPTimes(m)
+		pTime = smallest(pTime, sectionPTime)
+		maxPTime = smallest(maxPTime, sectionMaxPTime)
+	}
+	return pTime, maxPTime
+}
+
 // drainSRTP pulls and discards RTP/RTCP packets that don't match any SRTP
 // These could be sent to the user, but right now we don't provide an API
 // to distribute orphaned RTCP messages. This is needed to make sure we don't block
@@ -1244,15 +1820,18 @@ func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
 	attribute := sdp.NewAttribute("candidate", candidateValue)
 	sdpCandidate, err := attribute.ToICECandidate()
 	if err != nil {
-		return err
+		return &ICECandidateError{Candidate: candidate.Candidate, Err: err}
 	}
 
 	iceCandidate, err := newICECandidateFromSDP(sdpCandidate)
 	if err != nil {
-		return err
+		return &ICECandidateError{Candidate: candidate.Candidate, Err: err}
 	}
 
-	return pc.iceTransport.AddRemoteCandidate(iceCandidate)
+	if err = pc.iceTransport.AddRemoteCandidate(iceCandidate); err != nil {
+		return &ICECandidateError{Candidate: candidate.Candidate, Err: err}
+	}
+	return nil
 }
 
 // ICEConnectionState returns the ICE connection state of the
@@ -1300,14 +1879,22 @@ func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
 	return pc.rtpTransceivers
 }
 
-// AddTrack adds a Track to the PeerConnection
+// AddTrack adds a Track to the PeerConnection. It fails with
+// ErrSSRCCollision if another track of the PeerConnection, local or remote,
+// uses one of the SSRCs of the track.
 func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
-	if pc.isClosed {
+	if pc.hasClosed() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
+	if kind := track.Kind(); kind != RTPCodecTypeAudio && kind != RTPCodecTypeVideo {
+		return nil, &rtcerr.TypeError{Err: ErrUnknownTrackKind}
+	}
+	if err := pc.ssrcs.addTrack(track); err != nil {
+		return nil, err
+	}
 	var transceiver *RTPTransceiver
 	for _, t := range pc.GetTransceivers() {
-		if !t.stopped &&
+		if !t.isStopped() &&
 			t.Sender != nil &&
 			!t.Sender.hasSent() &&
 			t.Receiver != nil &&
@@ -1318,9 +1905,13 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 		}
 	}
 	if transceiver != nil {
+		replaced := transceiver.Sender.track
 		if err := transceiver.setSendingTrack(track); err != nil {
 			return nil, err
 		}
+		if replaced != nil && replaced != track {
+			pc.releaseTrack(replaced)
+		}
 	} else {
 		receiver, err := pc.api.NewRTPReceiver(track.Kind(), pc.dtlsTransport)
 		if err != nil {
@@ -1369,10 +1960,13 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 			return nil, fmt.Errorf("no %s codecs found", kind.String())
 		}
 
-		track, err := pc.NewTrack(codecs[0].PayloadType, mathRand.Uint32(), util.RandSeq(trackDefaultIDLength), util.RandSeq(trackDefaultLabelLength))
+		track, err := pc.NewTrack(codecs[0].PayloadType, 0, util.RandSeq(trackDefaultIDLength), util.RandSeq(trackDefaultLabelLength))
 		if err != nil {
 			return nil, err
 		}
+		if err = pc.ssrcs.addTrack(track); err != nil {
+			return nil, err
+		}
 
 		sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
 		if err != nil {
@@ -1411,6 +2005,18 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 	} else if len(init) == 1 {
 		direction = init[0].Direction
 	}
+	if kind := track.Kind(); kind != RTPCodecTypeAudio && kind != RTPCodecTypeVideo {
+		return nil, &rtcerr.TypeError{Err: ErrUnknownTrackKind}
+	}
+
+	if len(init) == 1 && len(init[0].SendEncodings) != 0 {
+		if _, err := track.SetEncodings(init[0].SendEncodings); err != nil {
+			return nil, err
+		}
+	}
+	if err := pc.ssrcs.addTrack(track); err != nil {
+		return nil, err
+	}
 
 	switch direction {
 	case RTPTransceiverDirectionSendrecv:
@@ -1532,6 +2138,20 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 	return d, nil
 }
 
+// openDataChannels counts the data channels that aren't closed
+func (pc *PeerConnection) openDataChannels() int {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	open := 0
+	for _, d := range pc.dataChannels {
+		if d.ReadyState() != DataChannelStateClosed {
+			open++
+		}
+	}
+	return open
+}
+
 func (pc *PeerConnection) generateDataChannelID(client bool) (uint16, error) {
 	var id uint16
 	if !client {
@@ -1560,33 +2180,33 @@ func (pc *PeerConnection) SetIdentityProvider(provider string) error {
 // WriteRTCP sends a user provided RTCP packet to the connected peer
 // If no peer is connected the packet is discarded
 func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
-	raw, err := rtcp.Marshal(pkts)
+	pkts, err := pc.api.interceptors.writeRTCP(pkts)
 	if err != nil {
 		return err
 	}
 
-	srtcpSession, err := pc.dtlsTransport.getSRTCPSession()
-	if err != nil {
+	if _, err = pc.dtlsTransport.getSRTCPSession(); err != nil {
 		return nil
 	}
-
-	writeStream, err := srtcpSession.OpenWriteStream()
-	if err != nil {
-		return fmt.Errorf("WriteRTCP failed to open WriteStream: %v", err)
-	}
-
-	if _, err := writeStream.Write(raw); err != nil {
-		return err
-	}
-	return nil
+	return pc.dtlsTransport.writeRTCP(pkts)
 }
 
 // Close ends the PeerConnection
 func (pc *PeerConnection) Close() error {
+	// Close may be called concurrently, e.g. by the context of
+	// SetRemoteDescriptionContext, only the first call closes
+	pc.mu.Lock()
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #2)
 	if pc.isClosed {
+		pc.mu.Unlock()
 		return nil
 	}
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #3)
+	pc.isClosed = true
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
+	pc.signalingState = SignalingStateClosed
+	pc.mu.Unlock()
+
 	// Try closing everything and collect the errors
 	// Shutdown strategy:
 	// 1. All Conn close by closing their underlying Conn.
@@ -1595,11 +2215,23 @@ func (pc *PeerConnection) Close() error {
 	//    continue the chain the Mux has to be closed.
 	var closeErrs []error
 
-	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #3)
-	pc.isClosed = true
+	pc.api.peerConnections.remove(pc)
 
-	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
-	pc.signalingState = SignalingStateClosed
+	pc.mu.Lock()
+	// PeerConnections that weren't made by NewPeerConnection have no channel
+	if pc.closed != nil {
+		close(pc.closed)
+	}
+	if pc.qualityScoreDone != nil {
+		close(pc.qualityScoreDone)
+	}
+	if pc.iceCredentialRotation != nil {
+		pc.iceCredentialRotation.Stop()
+	}
+	if pc.iceCredentialRotationAnswer != nil {
+		pc.iceCredentialRotationAnswer.Stop()
+	}
+	pc.mu.Unlock()
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #11)
 	if pc.iceTransport != nil {
@@ -1609,7 +2241,9 @@ func (pc *PeerConnection) Close() error {
 	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #12)
+	pc.mu.Lock()
 	pc.connectionState = PeerConnectionStateClosed
+	pc.mu.Unlock()
 
 	if err := pc.dtlsTransport.Stop(); err != nil {
 		closeErrs = append(closeErrs, err)
@@ -1649,44 +2283,100 @@ func (pc *PeerConnection) addFingerprint(d *sdp.SessionDescription) error {
 	return nil
 }
 
-func (pc *PeerConnection) addTransceiverSDP(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, transceivers ...*RTPTransceiver) error {
+// removePayloadTypeCollisions drops the codecs whose payload type is used for a
+// different codec by the remote offer. Payload types are shared by all media
+// sections of a session, answering with them would make the remote decode the
+// media with the wrong codec.
+func (pc *PeerConnection) removePayloadTypeCollisions(codecs []*RTPCodec, remote *sdp.SessionDescription) []*RTPCodec {
+	var filtered []*RTPCodec
+	for _, codec := range codecs {
+		remoteCodec, err := remote.GetCodecForPayloadType(codec.PayloadType)
+		if err == nil && (!strings.EqualFold(remoteCodec.Name, codec.Name) || remoteCodec.ClockRate != codec.ClockRate) {
+			pc.log.Warnf("payload type %d of %s is used for %s by the remote, not answering with it", codec.PayloadType, codec.Name, remoteCodec.Name)
+			continue
+		}
+		filtered = append(filtered, codec)
+	}
+	return filtered
+}
+
+// addTransceiverSDP adds a media section for transceivers. The direction of
+// the section is the one of the first transceiver unless direction is known.
+func (pc *PeerConnection) addTransceiverSDP(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, codecNames []string, direction RTPTransceiverDirection, transceivers ...*RTPTransceiver) error {
 	if len(transceivers) < 1 {
 		return fmt.Errorf("addTransceiverSDP() called with 0 transceivers")
 	}
 	// Use the first transceiver to generate the section attributes
 	t := transceivers[0]
+	if direction == RTPTransceiverDirection(Unknown) {
+		direction = t.Direction
+	}
 	media := sdp.NewJSEPMediaDescription(t.kind.String(), []string{}).
 		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()). // pion/webrtc#494
 		WithValueAttribute(sdp.AttrKeyMID, midValue).
 		WithICECredentials(iceParams.UsernameFragment, iceParams.Password).
 		WithPropertyAttribute(sdp.AttrKeyRTCPMux).
 		WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
+	if pc.api.settingEngine.rtcpMux.Only {
+		media.WithPropertyAttribute(sdpAttrKeyRTCPMuxOnly)
+	}
 
-	codecs := pc.api.mediaEngine.GetCodecsByKind(t.kind)
+	codecs := filterCodecsByName(pc.api.mediaEngine.GetCodecsByKind(t.kind), codecNames)
+	if remote := pc.RemoteDescription(); remote != nil && remote.Type == SDPTypeOffer && remote.parsed != nil {
+		codecs = pc.removePayloadTypeCollisions(codecs, remote.parsed)
+	}
+	var ptime, maxPTime time.Duration
 	for _, codec := range codecs {
 		media.WithCodec(codec.PayloadType, codec.Name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
 
 		for _, feedback := range codec.RTPCodecCapability.RTCPFeedback {
-			media.WithValueAttribute("rtcp-fb", fmt.Sprintf("%d %s %s", codec.PayloadType, feedback.Type, feedback.Parameter))
+			media.WithValueAttribute(sdpAttrKeyRTCPFeedback, fmt.Sprintf("%d %s %s", codec.PayloadType, feedback.Type, feedback.Parameter))
+		}
+
+		// ptime and maxptime apply to the whole section, the first codec that sets them wins
+		if ptime == 0 {
+			ptime = codec.PTime
 		}
+		if maxPTime == 0 {
+			maxPTime = codec.MaxPTime
+		}
+	}
+	if ptime != 0 {
+		media.WithValueAttribute(sdpAttrKeyPTime, formatPTime(ptime))
+	}
+	if maxPTime != 0 {
+		media.WithValueAttribute(sdpAttrKeyMaxPTime, formatPTime(maxPTime))
 	}
 	if len(codecs) == 0 {
 		// Explicitly reject track if we don't have the codec
-		d.WithMedia(&sdp.MediaDescription{
+		d.WithMedia((&sdp.MediaDescription{
 			MediaName: sdp.MediaName{
 				Media:   t.kind.String(),
 				Port:    sdp.RangedPort{Value: 0},
 				Protos:  []string{"UDP", "TLS", "RTP", "SAVPF"},
 				Formats: []string{"0"},
 			},
-		})
+		}).WithValueAttribute(sdp.AttrKeyMID, midValue))
 		return nil
 	}
 
+	// Sources are only announced when the section sends
+	sends := direction == RTPTransceiverDirectionSendrecv || direction == RTPTransceiverDirectionSendonly
+	pc.addHeaderExtensionsSDP(media, midValue, t.kind)
+	if len(transceivers) == 1 {
+		pc.addSimulcastSDP(media, midValue, direction, t)
+	}
 	for _, mt := range transceivers {
-		if mt.Sender != nil && mt.Sender.track != nil {
+		if sends && mt.Sender != nil && mt.Sender.track != nil {
 			track := mt.Sender.track
+			ssrcGroups := track.SSRCGroups()
+			for _, group := range ssrcGroups {
+				media = media.WithValueAttribute(sdpAttrKeySSRCGroup, group.String())
+			}
 			media = media.WithMediaSource(track.SSRC(), track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+			for _, ssrc := range ssrcsOfGroups(ssrcGroups, track.SSRC()) {
+				media = media.WithMediaSource(ssrc, track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+			}
 			if pc.configuration.SDPSemantics == SDPSemanticsUnifiedPlan {
 				media = media.WithPropertyAttribute("msid:" + track.Label() + " " + track.ID())
 				break
@@ -1694,7 +2384,7 @@ func (pc *PeerConnection) addTransceiverSDP(d *sdp.SessionDescription, midValue
 		}
 	}
 
-	media = media.WithPropertyAttribute(t.Direction.String())
+	media = media.WithPropertyAttribute(direction.String())
 
 	addCandidatesToMediaDescriptions(candidates, media)
 	d.WithMedia(media)
@@ -1702,7 +2392,89 @@ func (pc *PeerConnection) addTransceiverSDP(d *sdp.SessionDescription, midValue
 	return nil
 }
 
-func (pc *PeerConnection) addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole) {
+// addHeaderExtensionsSDP announces the header extensions registered for kind,
+// an answer only the ones the remote offered
+func (pc *PeerConnection) addHeaderExtensionsSDP(media *sdp.MediaDescription, midValue string, kind RTPCodecType) {
+	extensions := pc.api.mediaEngine.getHeaderExtensionsByKind(kind)
+	if pc.SignalingState() == SignalingStateHaveRemoteOffer {
+		extensions = nil
+		if remote := pc.remoteMediaByMid(midValue); remote != nil {
+			extensions = pc.api.mediaEngine.negotiatedHeaderExtensions(remote, kind)
+		}
+	}
+
+	for _, e := range extensions {
+		// rtp-stream-id is announced with the simulcast encodings
+		if e.URI != sdesRTPStreamIDURI {
+			media.WithValueAttribute(sdpAttrKeyExtMap, fmt.Sprintf("%d %s", e.ID, e.URI))
+		}
+	}
+}
+
+// addSimulcastSDP announces the simulcast encodings of the track of t, and
+// accepts the encodings the remote offered to send. RIDs are only answered
+// with if the remote offered the rtp-stream-id header extension.
+func (pc *PeerConnection) addSimulcastSDP(media *sdp.MediaDescription, midValue string, direction RTPTransceiverDirection, t *RTPTransceiver) {
+	var sendRIDs, recvRIDs []string
+	if direction == RTPTransceiverDirectionSendrecv || direction == RTPTransceiverDirectionSendonly {
+		if t.Sender != nil && t.Sender.track != nil {
+			for _, e := range t.Sender.track.Encodings() {
+				sendRIDs = append(sendRIDs, e.RID())
+			}
+		}
+	}
+
+	extensionID := uint8(defaultRTPStreamIDExtensionID)
+	if pc.SignalingState() == SignalingStateHaveRemoteOffer {
+		remote := pc.remoteMediaByMid(midValue)
+		if remote == nil {
+			return
+		}
+		if extensionID = headerExtensionID(remote, sdesRTPStreamIDURI); extensionID == 0 {
+			return
+		}
+		if direction == RTPTransceiverDirectionSendrecv || direction == RTPTransceiverDirectionRecvonly {
+			recvRIDs = ridsOf(remote, "send")
+		}
+	}
+	if len(sendRIDs) == 0 && len(recvRIDs) == 0 {
+		return
+	}
+
+	media.WithValueAttribute(sdpAttrKeyExtMap, fmt.Sprintf("%d %s", extensionID, sdesRTPStreamIDURI))
+	for _, rid := range sendRIDs {
+		media.WithValueAttribute(sdpAttrKeyRID, rid+" send")
+	}
+	for _, rid := range recvRIDs {
+		media.WithValueAttribute(sdpAttrKeyRID, rid+" recv")
+	}
+
+	var simulcast []string
+	if len(sendRIDs) != 0 {
+		simulcast = append(simulcast, "send "+strings.Join(sendRIDs, ";"))
+	}
+	if len(recvRIDs) != 0 {
+		simulcast = append(simulcast, "recv "+strings.Join(recvRIDs, ";"))
+	}
+	media.WithValueAttribute(sdpAttrKeySimulcast, strings.Join(simulcast, " "))
+}
+
+// ridsOf returns the RIDs of a media section with the given direction
+func ridsOf(m *sdp.MediaDescription, direction string) []string {
+	var rids []string
+	for _, a := range m.Attributes {
+		if a.Key != sdpAttrKeyRID {
+			continue
+		}
+		fields := strings.Fields(a.Value)
+		if len(fields) >= 2 && fields[1] == direction && validRID(fields[0]) {
+			rids = append(rids, fields[0])
+		}
+	}
+	return rids
+}
+
+func addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole) {
 	media := (&sdp.MediaDescription{
 		MediaName: sdp.MediaName{
 			Media:   "application",
@@ -1728,13 +2500,15 @@ func (pc *PeerConnection) addDataMediaSection(d *sdp.SessionDescription, midValu
 	d.WithMedia(media)
 }
 
-// NewTrack Creates a new Track
+// NewTrack Creates a new Track. An SSRC of zero picks a random one that
+// none of the tracks of the PeerConnection uses, local or remote.
 func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
 	codec, err := pc.api.mediaEngine.getCodec(payloadType)
 	if err != nil {
 		return nil, err
-	} else if codec.Payloader == nil {
-		return nil, fmt.Errorf("codec payloader not set")
+	}
+	if ssrc == 0 {
+		ssrc = pc.ssrcs.allocate()
 	}
 
 	return NewTrack(payloadType, ssrc, id, label, codec)
@@ -1753,6 +2527,9 @@ func (pc *PeerConnection) newRTPTransceiver(
 		Direction: direction,
 		kind:      kind,
 	}
+	if sender != nil {
+		sender.peerConnection = pc
+	}
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 	pc.rtpTransceivers = append(pc.rtpTransceivers, t)
@@ -1771,7 +2548,7 @@ func (pc *PeerConnection) populateLocalCandidates(orig *SessionDescription) *Ses
 		return orig
 	}
 
-	parsed := pc.pendingLocalDescription.parsed
+	parsed := orig.parsed
 	for _, m := range parsed.MediaDescriptions {
 		addCandidatesToMediaDescriptions(candidates, m)
 	}
@@ -1781,8 +2558,9 @@ func (pc *PeerConnection) populateLocalCandidates(orig *SessionDescription) *Ses
 	}
 
 	return &SessionDescription{
-		SDP:  string(sdp),
-		Type: pc.pendingLocalDescription.Type,
+		SDP:    string(sdp),
+		Type:   orig.Type,
+		parsed: parsed,
 	}
 }
 
@@ -1822,9 +2600,18 @@ func (pc *PeerConnection) PendingRemoteDescription() *SessionDescription {
 // SignalingState attribute returns the signaling state of the
 // PeerConnection instance.
 func (pc *PeerConnection) SignalingState() SignalingState {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
 	return pc.signalingState
 }
 
+// hasClosed tells if Close has been called
+func (pc *PeerConnection) hasClosed() bool {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.isClosed
+}
+
 // ICEGatheringState attribute returns the ICE gathering state of the
 // PeerConnection instance.
 func (pc *PeerConnection) ICEGatheringState() ICEGatheringState {
@@ -1841,6 +2628,8 @@ func (pc *PeerConnection) ICEGatheringState() ICEGatheringState {
 // ConnectionState attribute returns the connection state of the
 // PeerConnection instance.
 func (pc *PeerConnection) ConnectionState() PeerConnectionState {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
 	return pc.connectionState
 }
 
@@ -1862,11 +2651,22 @@ func (pc *PeerConnection) GetStats() StatsReport {
 	}
 
 	pc.iceGatherer.collectStats(statsCollector)
+	pc.dtlsTransport.collectStats(statsCollector)
+
+	for _, t := range pc.rtpTransceivers {
+		if t.Receiver != nil {
+			t.Receiver.collectStats(statsCollector)
+		}
+		if t.Sender != nil {
+			t.Sender.collectStats(statsCollector)
+		}
+	}
 
 	stats := PeerConnectionStats{
 		Timestamp:             statsTimestampNow(),
 		Type:                  StatsTypePeerConnection,
 		ID:                    pc.statsID,
+		Label:                 pc.Label(),
 		DataChannelsOpened:    pc.dataChannelsOpened,
 		DataChannelsClosed:    dataChannelsClosed,
 		DataChannelsRequested: pc.dataChannelsRequested,
@@ -1891,3 +2691,120 @@ func addCandidatesToMediaDescriptions(candidates []ICECandidate, m *sdp.MediaDes
 		m.WithPropertyAttribute("end-of-candidates")
 	}
 }
+
+const (
+	// sdpAttrKeySSRCGroup groups SSRCs that belong together, RFC 5576
+	sdpAttrKeySSRCGroup = "ssrc-group"
+
+	// sdpAttrKeyRTCPMuxOnly signals that RTCP is only ever multiplexed, RFC 8858
+	sdpAttrKeyRTCPMuxOnly = "rtcp-mux-only"
+
+	// sdpAttrKeyBundleOnly marks a media section that is only usable when bundled
+	sdpAttrKeyBundleOnly = "bundle-only"
+
+	// sdpAttrKeyRID and sdpAttrKeySimulcast announce the simulcast encodings
+	// of a media section, RFC 8851 and RFC 8853
+	sdpAttrKeyRID       = "rid"
+	sdpAttrKeySimulcast = "simulcast"
+)
+
+// supportsRTCPMux returns false if an active audio or video section of the
+// description can't multiplex RTP and RTCP
+func supportsRTCPMux(d *sdp.SessionDescription) bool {
+	for _, m := range d.MediaDescriptions {
+		if m.MediaName.Port.Value == 0 || NewRTPCodecType(m.MediaName.Media) == RTPCodecType(0) {
+			continue
+		}
+		if _, ok := m.Attribute(sdp.AttrKeyRTCPMux); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// descriptionHasBundle returns true if the description contains a BUNDLE group
+func descriptionHasBundle(d *sdp.SessionDescription) bool {
+	for _, a := range d.Attributes {
+		if a.Key == sdp.AttrKeyGroup && strings.HasPrefix(a.Value, "BUNDLE ") {
+			return true
+		}
+	}
+	return false
+}
+
+// isRejectedMediaSection tells if a media section was rejected with port 0.
+// Sections that are only usable when bundled have port 0 as well.
+func isRejectedMediaSection(media *sdp.MediaDescription) bool {
+	_, bundleOnly := media.Attribute(sdpAttrKeyBundleOnly)
+	return media.MediaName.Port.Value == 0 && !bundleOnly
+}
+
+// addRejectedMediaSection answers the remote media section with port 0
+func addRejectedMediaSection(d *sdp.SessionDescription, remote *sdp.MediaDescription, midValue string) {
+	d.WithMedia((&sdp.MediaDescription{
+		MediaName: sdp.MediaName{
+			Media:   remote.MediaName.Media,
+			Port:    sdp.RangedPort{Value: 0},
+			Protos:  remote.MediaName.Protos,
+			Formats: remote.MediaName.Formats,
+		},
+	}).WithValueAttribute(sdp.AttrKeyMID, midValue))
+}
+
+// connectionSetup returns the setup attribute of the first media section of
+// d that has one, or of the session, RFC 4145 Section 4
+func connectionSetup(d *sdp.SessionDescription) string {
+	if d == nil {
+		return ""
+	}
+	for _, m := range d.MediaDescriptions {
+		if setup, ok := m.Attribute(sdp.AttrKeyConnectionSetup); ok {
+			return setup
+		}
+	}
+	setup, _ := d.Attribute(sdp.AttrKeyConnectionSetup)
+	return setup
+}
+
+// answerConnectionRole picks the setup attribute of an answer to an offer
+// with remoteSetup, role is the DTLS role forced by the AnswerOptions
+func answerConnectionRole(remoteSetup string, role DTLSRole) (sdp.ConnectionRole, error) {
+	switch role {
+	case DTLSRoleClient:
+		if remoteSetup == sdp.ConnectionRoleActive.String() {
+			return 0, &rtcerr.InvalidAccessError{Err: ErrIncompatibleAnswerDTLSRole}
+		}
+		return sdp.ConnectionRoleActive, nil
+	case DTLSRoleServer:
+		if remoteSetup == sdp.ConnectionRolePassive.String() {
+			return 0, &rtcerr.InvalidAccessError{Err: ErrIncompatibleAnswerDTLSRole}
+		}
+		return sdp.ConnectionRolePassive, nil
+	default:
+		if remoteSetup == sdp.ConnectionRoleActive.String() {
+			return sdp.ConnectionRolePassive, nil
+		}
+		return sdp.ConnectionRoleActive, nil
+	}
+}
+
+// remoteDTLSRole returns the DTLS role of the remote the setup attributes of
+// the descriptions agree on. The role of an actpass offer is the opposite of
+// the one of the answer. DTLSRoleAuto leaves the role to the ICE role.
+func remoteDTLSRole(remote, local *sdp.SessionDescription) DTLSRole {
+	switch connectionSetup(remote) {
+	case sdp.ConnectionRoleActive.String():
+		return DTLSRoleClient
+	case sdp.ConnectionRolePassive.String():
+		return DTLSRoleServer
+	}
+
+	switch connectionSetup(local) {
+	case sdp.ConnectionRoleActive.String():
+		return DTLSRoleServer
+	case sdp.ConnectionRolePassive.String():
+		return DTLSRoleClient
+	default:
+		return DTLSRoleAuto
+	}
+}
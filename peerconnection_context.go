@@ -0,0 +1,151 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// handlerScope binds an event handler to a context, see OnTrackContext
+type handlerScope struct {
+	ctx context.Context
+
+	mu       sync.Mutex
+	canceled bool
+	// stops end the reads of the tracks and DataChannels the handler was
+	// called with
+	stops []func()
+}
+
+// bind registers stop to be called once the context is done, it returns
+// false if it is done already
+func (s *handlerScope) bind(stop func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.canceled {
+		return false
+	}
+	s.stops = append(s.stops, stop)
+	return true
+}
+
+// active returns false once the context is done
+func (s *handlerScope) active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.canceled
+}
+
+func (s *handlerScope) cancel() {
+	s.mu.Lock()
+	s.canceled = true
+	stops := s.stops
+	s.stops = nil
+	s.mu.Unlock()
+
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+// OnTrackContext sets an event handler like OnTrack that is bound to ctx.
+// Once ctx is done the handler is removed, unless another one was set since,
+// and the reads of the tracks it was called with return ErrDeadlineExceeded,
+// which ends the read loops of the handler. Tracks that arrive later aren't
+// passed to it anymore.
+func (pc *PeerConnection) OnTrackContext(ctx context.Context, f func(*Track, *RTPReceiver)) {
+	scope := &handlerScope{ctx: ctx}
+
+	pc.mu.Lock()
+	pc.onTrackScope = scope
+	pc.onTrackHandler = func(t *Track, r *RTPReceiver) {
+		stop := func() {
+			if err := t.SetReadDeadline(time.Now()); err != nil {
+				pc.log.Warnf("failed to stop the reads of track %s: %v", t.ID(), err)
+			}
+		}
+		if scope.bind(stop) {
+			f(t, r)
+		}
+	}
+	pc.mu.Unlock()
+
+	go pc.watchHandlerScope(scope, func() {
+		if pc.onTrackScope == scope {
+			pc.onTrackScope = nil
+			pc.onTrackHandler = nil
+		}
+	})
+}
+
+// OnDataChannelContext sets an event handler like OnDataChannel that is bound
+// to ctx. Once ctx is done the handler is removed, unless another one was set
+// since, and the DataChannels it was called with stop delivering to it: their
+// event handlers are removed, and detached reads return ErrDeadlineExceeded,
+// see DataChannel.SetReadDeadline. The DataChannels stay open.
+func (pc *PeerConnection) OnDataChannelContext(ctx context.Context, f func(*DataChannel)) {
+	scope := &handlerScope{ctx: ctx}
+
+	pc.mu.Lock()
+	pc.onDataChannelScope = scope
+	pc.onDataChannelHandler = func(d *DataChannel) {
+		stop := func() {
+			if pc.api.settingEngine.detach.DataChannels {
+				d.readDeadline.setDeadline(time.Now())
+			} else {
+				d.unhook()
+			}
+		}
+		if scope.bind(stop) {
+			f(d)
+		}
+	}
+	pc.mu.Unlock()
+
+	go pc.watchHandlerScope(scope, func() {
+		if pc.onDataChannelScope == scope {
+			pc.onDataChannelScope = nil
+			pc.onDataChannelHandler = nil
+		}
+	})
+}
+
+// OnICECandidateContext sets an event handler like OnICECandidate that is
+// removed once ctx is done, unless another one was set since
+func (pc *PeerConnection) OnICECandidateContext(ctx context.Context, f func(*ICECandidate)) {
+	scope := &handlerScope{ctx: ctx}
+
+	pc.mu.Lock()
+	pc.onICECandidateScope = scope
+	pc.iceGatherer.OnLocalCandidate(func(c *ICECandidate) {
+		if scope.active() {
+			f(c)
+		}
+	})
+	pc.mu.Unlock()
+
+	go pc.watchHandlerScope(scope, func() {
+		if pc.onICECandidateScope == scope {
+			pc.onICECandidateScope = nil
+			pc.iceGatherer.OnLocalCandidate(nil)
+		}
+	})
+}
+
+// watchHandlerScope waits until the context of scope is done or the
+// PeerConnection is closed. remove is called with pc.mu held to remove the
+// handler, before the reads it started are stopped.
+func (pc *PeerConnection) watchHandlerScope(scope *handlerScope, remove func()) {
+	select {
+	case <-scope.ctx.Done():
+	case <-pc.closed:
+		return
+	}
+
+	pc.mu.Lock()
+	remove()
+	pc.mu.Unlock()
+	scope.cancel()
+}
@@ -0,0 +1,158 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForHandlerRemoval waits until removed returns true with pc.mu held
+func waitForHandlerRemoval(t *testing.T, pc *PeerConnection, removed func() bool) {
+	for {
+		pc.mu.RLock()
+		ok := removed()
+		pc.mu.RUnlock()
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPeerConnection_OnTrackContext(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reading := make(chan struct{})
+	readErr := make(chan error)
+	pcAnswer.OnTrackContext(ctx, func(remote *Track, _ *RTPReceiver) {
+		close(reading)
+		for {
+			if _, err := remote.ReadRTP(); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00, 0x01, 0x02}, Samples: 1})
+			}
+		}
+	}()
+
+	// Canceling the context ends the read loop of the handler
+	<-reading
+	cancel()
+	assert.Equal(t, ErrDeadlineExceeded, <-readErr)
+
+	waitForHandlerRemoval(t, pcAnswer, func() bool {
+		return pcAnswer.onTrackHandler == nil
+	})
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_OnDataChannelContext(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pcAnswer.OnDataChannelContext(ctx, func(*DataChannel) {
+		t.Error("handler called after its context was canceled")
+	})
+	cancel()
+	waitForHandlerRemoval(t, pcAnswer, func() bool {
+		return pcAnswer.onDataChannelHandler == nil
+	})
+
+	// DataChannels the handler was called with stop calling the handlers it set
+	d, err := pcAnswer.api.newDataChannel(&DataChannelParameters{Label: "data"}, pcAnswer.log)
+	assert.NoError(t, err)
+	ctx, cancel = context.WithCancel(context.Background())
+	pcAnswer.OnDataChannelContext(ctx, func(d *DataChannel) {
+		d.OnMessage(func(DataChannelMessage) {})
+	})
+	pcAnswer.mu.RLock()
+	handler := pcAnswer.onDataChannelHandler
+	pcAnswer.mu.RUnlock()
+	handler(d)
+	cancel()
+	for {
+		d.mu.RLock()
+		unhooked := d.onMessageHandler == nil
+		d.mu.RUnlock()
+		if unhooked {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A handler set later isn't removed by the canceled context
+	ctx, cancel = context.WithCancel(context.Background())
+	pcAnswer.OnDataChannelContext(ctx, func(*DataChannel) {})
+	pcAnswer.OnDataChannel(func(*DataChannel) {})
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	pcAnswer.mu.RLock()
+	assert.NotNil(t, pcAnswer.onDataChannelHandler)
+	pcAnswer.mu.RUnlock()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_OnICECandidateContext(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pc.OnICECandidateContext(ctx, func(*ICECandidate) {
+		t.Error("handler called after its context was canceled")
+	})
+	cancel()
+	waitForHandlerRemoval(t, pc, func() bool {
+		return pc.onICECandidateScope == nil
+	})
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	gatheringComplete := GatheringCompletePromise(pc)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	<-gatheringComplete
+
+	assert.NoError(t, pc.Close())
+}
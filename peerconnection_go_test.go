@@ -3,13 +3,17 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"math/big"
+	"net"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -232,6 +236,37 @@ func TestPeerConnection_SetConfiguration_Go(t *testing.T) {
 	}
 }
 
+func TestPeerConnection_SetConfiguration_ICE(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	stun := ICEServer{URLs: []string{"stun:stun.l.google.com:19302"}}
+	assert.NoError(t, pc.SetConfiguration(Configuration{
+		ICEServers:         []ICEServer{stun},
+		ICETransportPolicy: ICETransportPolicyRelay,
+	}))
+
+	// The gatherer uses the servers and policy for the next gathering
+	assert.Len(t, pc.iceGatherer.validatedServers, 1)
+	assert.Equal(t, []ice.CandidateType{ice.CandidateTypeRelay}, pc.iceGatherer.candidateTypes)
+
+	// An invalid configuration doesn't change anything
+	err = pc.SetConfiguration(Configuration{
+		ICETransportPolicy: ICETransportPolicyAll,
+		ICEServers:         []ICEServer{{URLs: []string{"turn:example.org"}}},
+	})
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrNoTurnCredencials}, err)
+	assert.Equal(t, ICETransportPolicyRelay, pc.GetConfiguration().ICETransportPolicy)
+	assert.Len(t, pc.iceGatherer.validatedServers, 1)
+
+	// Changing the returned configuration doesn't change the PeerConnection
+	configuration := pc.GetConfiguration()
+	configuration.ICEServers[0].URLs[0] = "stun:example.org"
+	assert.Equal(t, stun.URLs, pc.GetConfiguration().ICEServers[0].URLs)
+
+	assert.NoError(t, pc.Close())
+}
+
 // TODO - This unittest needs to be completed when CreateDataChannel is complete
 // func TestPeerConnection_CreateDataChannel(t *testing.T) {
 // 	pc, err := New(Configuration{})
@@ -341,6 +376,131 @@ func TestPeerConnection_ShutdownNoDTLS(t *testing.T) {
 	}
 }
 
+func TestPeerConnection_IDAndLabel(t *testing.T) {
+	messages := make(chan string, 100)
+	api := NewAPI(WithSettingEngine(SettingEngine{
+		LoggerFactory: testCatchAllLoggerFactory{
+			callback: func(msg string) {
+				select {
+				case messages <- msg:
+				default:
+				}
+			},
+		},
+	}))
+
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	other, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, pc.ID(), other.ID())
+
+	pc.SetLabel("alice")
+	assert.Equal(t, "alice", pc.Label())
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	for msg := range messages {
+		if strings.Contains(msg, "signaling state changed") {
+			assert.True(t, strings.HasPrefix(msg, "["+pc.ID()+" alice] "), msg)
+			break
+		}
+	}
+
+	stats, ok := pc.GetStats().GetConnectionStats(pc)
+	assert.True(t, ok)
+	assert.Equal(t, pc.ID(), stats.ID)
+	assert.Equal(t, "alice", stats.Label)
+
+	assert.NoError(t, pc.Close())
+	assert.NoError(t, other.Close())
+}
+
+func TestPeerConnection_SignalingStateMachine(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	states := make(chan SignalingState, 2)
+	pc.OnSignalingStateChange(func(s SignalingState) {
+		states <- s
+	})
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	assert.Equal(t, SignalingStateHaveLocalOffer, <-states)
+
+	// Only the remote peer answers an offer
+	_, err = pc.CreateAnswer(nil)
+	assert.Error(t, err)
+	err = pc.SetLocalDescription(SessionDescription{Type: SDPTypeAnswer, SDP: offer.SDP})
+	modErr, ok := err.(*rtcerr.InvalidModificationError)
+	assert.True(t, ok)
+	assert.Equal(t, &SignalingStateError{
+		State:     SignalingStateHaveLocalOffer,
+		Operation: "SetLocal(answer)",
+		Expected:  []SignalingState{SignalingStateHaveRemoteOffer, SignalingStateHaveLocalPranswer},
+	}, modErr.Err)
+	assert.Equal(t, SignalingStateHaveLocalOffer, pc.SignalingState())
+
+	assert.NoError(t, pc.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, SignalingStateStable, <-states)
+	assert.Nil(t, pc.PendingLocalDescription())
+
+	// A remote offer can't be rolled back
+	answerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+	err = answerer.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback})
+	modErr, ok = err.(*rtcerr.InvalidModificationError)
+	assert.True(t, ok)
+	assert.Equal(t, &SignalingStateError{
+		State:     SignalingStateHaveRemoteOffer,
+		Operation: "SetRemote(rollback)",
+	}, modErr.Err)
+	assert.Equal(t, SignalingStateHaveRemoteOffer, answerer.SignalingState())
+	assert.NotNil(t, answerer.PendingRemoteDescription())
+
+	assert.NoError(t, pc.Close())
+	assert.NoError(t, answerer.Close())
+}
+
+func TestPeerConnection_UDPPort(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	assert.NoError(t, err)
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	assert.NoError(t, conn.Close())
+
+	s := SettingEngine{}
+	s.SetUDPPort(uint16(port))
+	api := NewAPI(WithSettingEngine(s))
+	config := Configuration{
+		ICEServers: []ICEServer{{URLs: []string{"stun:stun.l.google.com:19302"}}},
+	}
+
+	pc, err := api.NewPeerConnection(config)
+	assert.NoError(t, err)
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	candidates := 0
+	for _, line := range strings.Split(offer.SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=candidate:") {
+			continue
+		}
+		candidates++
+		assert.Contains(t, line, fmt.Sprintf(" %d typ host", port))
+	}
+	assert.NotZero(t, candidates)
+
+	// The port can't be shared
+	_, err = api.NewPeerConnection(config)
+	assert.True(t, errors.Is(err, ErrUDPPortInUse))
+
+	assert.NoError(t, pc.Close())
+}
+
 func TestPeerConnection_PeropertyGetters(t *testing.T) {
 	pc := &PeerConnection{
 		currentLocalDescription:  &SessionDescription{},
@@ -361,6 +521,69 @@ func TestPeerConnection_PeropertyGetters(t *testing.T) {
 	assert.Equal(t, pc.connectionState, pc.ConnectionState(), "should match")
 }
 
+func TestPeerConnection_SetRemoteDescriptionContext(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// A context that is already done doesn't touch the PeerConnection
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, pcAnswer.SetRemoteDescriptionContext(canceled, offer))
+	assert.Nil(t, pcAnswer.RemoteDescription())
+
+	// The answer never reaches the offerer, so the connection can't be established
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if err = pcAnswer.SetRemoteDescriptionContext(ctx, offer); err != nil {
+		t.Fatal(err)
+	}
+
+	for pcAnswer.ConnectionState() != PeerConnectionStateClosed {
+		time.Sleep(time.Millisecond * 20)
+	}
+	assert.NoError(t, pcOffer.Close())
+}
+
+func TestPeerConnection_SetLocalDescriptionContext(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A context that is already done doesn't touch the PeerConnection
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, pc.SetLocalDescriptionContext(canceled, offer))
+	assert.Nil(t, pc.LocalDescription())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.NoError(t, pc.SetLocalDescriptionContext(ctx, offer))
+	assert.NotNil(t, pc.LocalDescription())
+	assert.NoError(t, pc.Close())
+}
+
 func TestPeerConnection_AnswerWithoutOffer(t *testing.T) {
 	pc, err := NewPeerConnection(Configuration{})
 	if err != nil {
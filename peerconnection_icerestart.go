@@ -0,0 +1,149 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/sdp/v2"
+)
+
+const (
+	// iceCredentialRotationRetry is the delay after which a rotation of the
+	// ICE credentials is tried again when another negotiation was in progress
+	iceCredentialRotationRetry = 5 * time.Second
+
+	// iceCredentialRotationAnswerTimeout is how long the offer of a rotation
+	// waits for the answer of the remote before it is rolled back
+	iceCredentialRotationAnswerTimeout = 30 * time.Second
+)
+
+// restartICE connects with the new ICE credentials of desc. A remote offer
+// makes the gatherer create new local credentials for the answer, an answer
+// completes an ICE restart this PeerConnection offered. Media and data keep
+// flowing over the previous candidate pair until the new one is connected.
+func (pc *PeerConnection) restartICE(desc *SessionDescription) error {
+	if desc.Type == SDPTypeOffer {
+		if err := pc.iceTransport.restartGatherer(); err != nil {
+			return err
+		}
+	}
+	if err := pc.addRemoteCandidates(desc.parsed); err != nil {
+		return err
+	}
+
+	params := ICEParameters{
+		UsernameFragment: descriptionAttribute(desc.parsed, "ice-ufrag"),
+		Password:         descriptionAttribute(desc.parsed, "ice-pwd"),
+	}
+	go func() {
+		if err := pc.iceTransport.restart(params); err != nil {
+			pc.log.Warnf("ICE restart failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// addRemoteCandidates adds the candidates in the media sections of parsed
+func (pc *PeerConnection) addRemoteCandidates(parsed *sdp.SessionDescription) error {
+	for _, m := range parsed.MediaDescriptions {
+		for _, a := range m.Attributes {
+			if !a.IsICECandidate() {
+				continue
+			}
+			sdpCandidate, err := a.ToICECandidate()
+			if err != nil {
+				return &ICECandidateError{Candidate: a.Value, Err: err}
+			}
+			candidate, err := newICECandidateFromSDP(sdpCandidate)
+			if err != nil {
+				return &ICECandidateError{Candidate: a.Value, Err: err}
+			}
+			if err = pc.iceTransport.AddRemoteCandidate(candidate); err != nil {
+				return &ICECandidateError{Candidate: a.Value, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// OnICECredentialRotation sets the handler the offers of the periodic ICE
+// restarts are signaled with, see SettingEngine.SetICECredentialRotationInterval.
+// The offer has been set as the local description already, the answer of the
+// remote is applied with SetRemoteDescription. An offer that isn't answered
+// within 30 seconds is rolled back, the connection keeps its credentials then.
+// Without a handler the credentials aren't rotated.
+func (pc *PeerConnection) OnICECredentialRotation(f func(offer SessionDescription)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onICECredentialRotationHandler = f
+}
+
+// scheduleICECredentialRotation rotates the ICE credentials after delay, a
+// zero delay doesn't rotate them
+func (pc *PeerConnection) scheduleICECredentialRotation(delay time.Duration) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if delay == 0 || pc.isClosed {
+		return
+	}
+	pc.iceCredentialRotation = time.AfterFunc(delay, pc.rotateICECredentials)
+}
+
+// rotateICECredentials offers an ICE restart and passes the offer to the
+// handler of OnICECredentialRotation
+func (pc *PeerConnection) rotateICECredentials() {
+	interval := pc.api.settingEngine.timeout.ICECredentialRotation
+
+	pc.mu.RLock()
+	hdlr := pc.onICECredentialRotationHandler
+	pc.mu.RUnlock()
+	if hdlr == nil {
+		pc.scheduleICECredentialRotation(interval)
+		return
+	}
+
+	if pc.SignalingState() != SignalingStateStable {
+		pc.log.Debug("Postponing the rotation of the ICE credentials, a negotiation is in progress")
+		pc.scheduleICECredentialRotation(iceCredentialRotationRetry)
+		return
+	}
+
+	offer, err := pc.CreateOffer(&OfferOptions{ICERestart: true})
+	if err == nil {
+		err = pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		pc.log.Warnf("Failed to rotate the ICE credentials: %v", err)
+		pc.scheduleICECredentialRotation(interval)
+		return
+	}
+
+	pc.mu.Lock()
+	if !pc.isClosed {
+		pc.iceCredentialRotationAnswer = time.AfterFunc(iceCredentialRotationAnswerTimeout, func() {
+			pc.rollbackICECredentialRotation(offer)
+		})
+	}
+	pc.mu.Unlock()
+
+	hdlr(offer)
+	pc.scheduleICECredentialRotation(interval)
+}
+
+// rollbackICECredentialRotation rolls back offer if it is still waiting for
+// the answer of the remote
+func (pc *PeerConnection) rollbackICECredentialRotation(offer SessionDescription) {
+	if pc.SignalingState() != SignalingStateHaveLocalOffer {
+		return
+	}
+	if pending := pc.pendingLocalDescription; pending == nil || pending.SDP != offer.SDP {
+		return
+	}
+
+	pc.log.Warn("The remote didn't answer the rotation of the ICE credentials, rolling it back")
+	if err := pc.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}); err != nil {
+		pc.log.Warnf("Failed to roll back the rotation of the ICE credentials: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/ice"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// iceRestarted waits until the ICE transport of pc is connected through the
+// agent of its gatherer instead of previous
+func iceRestarted(pc *PeerConnection, previous *ice.Agent) {
+	for {
+		pc.iceTransport.lock.RLock()
+		agent := pc.iceTransport.agent
+		pc.iceTransport.lock.RUnlock()
+		if agent != previous && agent == pc.iceGatherer.getAgent() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// echoDataChannel opens a data channel from pcOffer and returns it once
+// pcAnswer echoes its messages
+func echoDataChannel(t *testing.T, pcOffer, pcAnswer *PeerConnection) (*DataChannel, chan string) {
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.NoError(t, d.Send(msg.Data))
+		})
+	})
+
+	d, err := pcOffer.CreateDataChannel("echo", nil)
+	assert.NoError(t, err)
+	echoed := make(chan string, 1)
+	d.OnMessage(func(msg DataChannelMessage) {
+		echoed <- string(msg.Data)
+	})
+	opened := make(chan struct{})
+	d.OnOpen(func() {
+		close(opened)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	// The handler of signalPair would signal the offers of later restarts
+	pcOffer.OnICECandidate(nil)
+	<-opened
+	return d, echoed
+}
+
+func TestPeerConnection_ICERestart(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, echoed := echoDataChannel(t, pcOffer, pcAnswer)
+
+	offerAgent := pcOffer.iceGatherer.getAgent()
+	answerAgent := pcAnswer.iceGatherer.getAgent()
+	before, err := pcOffer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	assert.NoError(t, err)
+	after, err := pcOffer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+	assert.NotEqual(t, before.UsernameFragment, after.UsernameFragment)
+	assert.NotEqual(t, before.Password, after.Password)
+	assert.Equal(t, after.UsernameFragment, descriptionAttribute(offer.parsed, "ice-ufrag"))
+
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	// Both sides move to new agents, the data channel keeps working
+	iceRestarted(pcOffer, offerAgent)
+	iceRestarted(pcAnswer, answerAgent)
+	assert.Equal(t, ICEConnectionStateConnected, pcOffer.ICEConnectionState())
+
+	assert.NoError(t, d.SendText("after restart"))
+	assert.Equal(t, "after restart", <-echoed)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_ICECredentialRotation(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetICECredentialRotationInterval(time.Millisecond * 200)
+	pcOffer, pcAnswer, err := NewAPI(WithSettingEngine(s)).newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offerAgent := pcOffer.iceGatherer.getAgent()
+	// Only the first rotation is signaled
+	rotated := make(chan SessionDescription, 1)
+	pcOffer.OnICECredentialRotation(func(offer SessionDescription) {
+		pcOffer.OnICECredentialRotation(nil)
+		rotated <- offer
+	})
+
+	d, echoed := echoDataChannel(t, pcOffer, pcAnswer)
+
+	offer := <-rotated
+	assert.Equal(t, SignalingStateHaveLocalOffer, pcOffer.SignalingState())
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	iceRestarted(pcOffer, offerAgent)
+	assert.NoError(t, d.SendText("rotated"))
+	assert.Equal(t, "rotated", <-echoed)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_ICECredentialRotationRollback(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, echoed := echoDataChannel(t, pcOffer, pcAnswer)
+
+	offerAgent := pcOffer.iceGatherer.getAgent()
+	before, err := pcOffer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+
+	gathered := make(chan struct{})
+	pcOffer.OnICECandidate(func(c *ICECandidate) {
+		if c == nil {
+			close(gathered)
+		}
+	})
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-gathered
+
+	// Another offer is left alone
+	pcOffer.rollbackICECredentialRotation(SessionDescription{Type: SDPTypeOffer, SDP: "other"})
+	assert.Equal(t, SignalingStateHaveLocalOffer, pcOffer.SignalingState())
+
+	// The unanswered offer is rolled back with its credentials
+	pcOffer.rollbackICECredentialRotation(offer)
+	assert.Equal(t, SignalingStateStable, pcOffer.SignalingState())
+	assert.Equal(t, offerAgent, pcOffer.iceGatherer.getAgent())
+	after, err := pcOffer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	assert.NoError(t, d.SendText("rolled back"))
+	assert.Equal(t, "rolled back", <-echoed)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -305,13 +305,20 @@ func (pc *PeerConnection) OnICECandidate(f func(candidate *ICECandidate)) {
 
 // OnICEGatheringStateChange sets an event handler which is invoked when the
 // ICE candidate gathering state has changed.
-func (pc *PeerConnection) OnICEGatheringStateChange(f func()) {
+func (pc *PeerConnection) OnICEGatheringStateChange(f func(ICEGathererState)) {
 	if pc.onICEGatheringStateChangeHandler != nil {
 		oldHandler := pc.onICEGatheringStateChangeHandler
 		defer oldHandler.Release()
 	}
 	onICEGatheringStateChangeHandler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		go f()
+		state := ICEGathererStateNew
+		switch pc.ICEGatheringState() {
+		case ICEGatheringStateGathering:
+			state = ICEGathererStateGathering
+		case ICEGatheringStateComplete:
+			state = ICEGathererStateComplete
+		}
+		go f(state)
 		return js.Undefined()
 	})
 	pc.onICEGatheringStateChangeHandler = &onICEGatheringStateChangeHandler
@@ -8,6 +8,7 @@ import (
 	"io"
 	"math/rand"
 	"reflect"
+	"regexp"
 	"strings"
 	"sync"
 	"testing"
@@ -18,6 +19,7 @@ import (
 	"github.com/pion/sdp/v2"
 	"github.com/pion/transport/test"
 	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -632,6 +634,435 @@ func TestOfferRejectionMissingCodec(t *testing.T) {
 	if got, want := videoDesc.MediaName.Formats, []string{"0"}; !reflect.DeepEqual(got, want) {
 		t.Fatalf("rejecting unknown codec: sdp m=%s, want trailing 0", *videoDesc.MediaName.String())
 	}
+
+	assert.NoError(t, pc.Close())
+	assert.NoError(t, noCodecPC.Close())
+}
+
+func TestPeerConnection_Media_RTPKeepAlive(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetRTPKeepAliveInterval(time.Millisecond * 50)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	onTrackFired := make(chan struct{})
+	keepAliveReceived := make(chan error, 1)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		close(onTrackFired)
+
+		var lastSequenceNumber uint16
+		for {
+			p, routineErr := track.ReadRTP()
+			if routineErr != nil {
+				return
+			}
+			if !p.Padding {
+				lastSequenceNumber = p.SequenceNumber
+				continue
+			}
+
+			if lastSequenceNumber != 0 && p.SequenceNumber != lastSequenceNumber+1 {
+				keepAliveReceived <- fmt.Errorf("keep-alive sequence number %d does not follow %d", p.SequenceNumber, lastSequenceNumber)
+			} else {
+				keepAliveReceived <- nil
+			}
+			return
+		}
+	})
+
+	go func() {
+		for {
+			select {
+			case <-onTrackFired:
+				return
+			case <-time.After(time.Millisecond * 20):
+				if routineErr := vp8Track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}); routineErr != nil {
+					fmt.Println(routineErr)
+				}
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, <-keepAliveReceived)
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Media_TrackClone(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = vp8Track.Clone()
+	assert.Error(t, err, "local tracks can't be cloned")
+
+	const packetCount = 5
+	readSequenceNumbers := func(track *Track, result chan<- []uint16) {
+		var sequenceNumbers []uint16
+		for len(sequenceNumbers) < packetCount {
+			p, routineErr := track.ReadRTP()
+			if routineErr != nil {
+				break
+			}
+			sequenceNumbers = append(sequenceNumbers, p.SequenceNumber)
+		}
+		result <- sequenceNumbers
+	}
+
+	originalRead := make(chan []uint16, 1)
+	cloneRead := make(chan []uint16, 1)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		clone, cloneErr := track.Clone()
+		if cloneErr != nil {
+			t.Error(cloneErr)
+			return
+		}
+		assert.Equal(t, track.SSRC(), clone.SSRC())
+
+		go readSequenceNumbers(clone, cloneRead)
+		readSequenceNumbers(track, originalRead)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				if routineErr := vp8Track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}); routineErr != nil {
+					fmt.Println(routineErr)
+				}
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	original := <-originalRead
+	assert.Equal(t, packetCount, len(original))
+	assert.Equal(t, original, <-cloneRead)
+
+	close(done)
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestOfferCodecSubset(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pc.AddTransceiverFromKind(RTPCodecTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pc.AddTransceiverFromKind(RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pc.CreateOffer(&OfferOptions{
+		OfferAnswerOptions: OfferAnswerOptions{Codecs: []string{Opus}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range offer.parsed.MediaDescriptions {
+		switch m.MediaName.Media {
+		case "audio":
+			assert.Equal(t, []string{"111"}, m.MediaName.Formats)
+		case "video":
+			assert.Equal(t, 0, m.MediaName.Port.Value, "video should be rejected")
+		}
+	}
+
+	// The shared MediaEngine must be left untouched
+	offer, err = pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, offerMediaHasDirection(offer, RTPCodecTypeVideo, RTPTransceiverDirectionSendrecv))
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestAnswerMirrorsOfferSections(t *testing.T) {
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	for _, kind := range []RTPCodecType{RTPCodecTypeVideo, RTPCodecTypeAudio, RTPCodecTypeVideo} {
+		_, err = pcOffer.AddTransceiverFromKind(kind)
+		assert.NoError(t, err)
+	}
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	// A section of an unknown kind, a rejected data section and a second
+	// data section
+	ufrag, _ := offer.parsed.MediaDescriptions[0].Attribute("ice-ufrag")
+	pwd, _ := offer.parsed.MediaDescriptions[0].Attribute("ice-pwd")
+	offer.parsed.WithMedia((&sdp.MediaDescription{
+		MediaName: sdp.MediaName{
+			Media:   "text",
+			Port:    sdp.RangedPort{Value: 9},
+			Protos:  []string{"UDP", "TLS", "RTP", "SAVPF"},
+			Formats: []string{"98"},
+		},
+	}).WithValueAttribute(sdp.AttrKeyMID, "text").WithPropertyAttribute("sendrecv").WithICECredentials(ufrag, pwd))
+	for _, port := range []int{0, 9} {
+		offer.parsed.WithMedia((&sdp.MediaDescription{
+			MediaName: sdp.MediaName{
+				Media:   "application",
+				Port:    sdp.RangedPort{Value: port},
+				Protos:  []string{"DTLS", "SCTP"},
+				Formats: []string{"5000"},
+			},
+		}).WithValueAttribute(sdp.AttrKeyMID, fmt.Sprintf("data%d", port)).WithICECredentials(ufrag, pwd))
+	}
+	raw, err := offer.parsed.Marshal()
+	assert.NoError(t, err)
+
+	// The answerer only has a single video transceiver
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: string(raw)}))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+
+	offered := offer.parsed.MediaDescriptions
+	answered := answer.parsed.MediaDescriptions
+	if !assert.Len(t, answered, len(offered)) {
+		return
+	}
+	for i := range offered {
+		assert.Equal(t, offered[i].MediaName.Media, answered[i].MediaName.Media)
+		assert.Equal(t, pcOffer.getMidValue(offered[i]), pcAnswer.getMidValue(answered[i]))
+	}
+
+	rejected := func(i int) bool { return answered[i].MediaName.Port.Value == 0 }
+	assert.False(t, rejected(0), "video")
+	assert.False(t, rejected(1), "audio")
+	assert.False(t, rejected(2), "second video")
+	assert.False(t, rejected(3), "data")
+	assert.True(t, rejected(4), "text")
+	assert.True(t, rejected(5), "rejected data")
+	assert.True(t, rejected(6), "second data")
+
+	bundle, _ := answer.parsed.Attribute(sdp.AttrKeyGroup)
+	assert.Equal(t, "BUNDLE 0 1 2 3", bundle)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestAddTrackKind(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// The kind is taken from the MimeType of codecs without a Type
+	codec := &RTPCodec{RTPCodecCapability: RTPCodecCapability{MimeType: "video/VP8", ClockRate: 90000}, Name: VP8}
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", codec)
+	assert.NoError(t, err)
+	assert.Equal(t, RTPCodecTypeVideo, track.Kind())
+
+	codec = &RTPCodec{RTPCodecCapability: RTPCodecCapability{MimeType: "text/red"}, Name: "red"}
+	unknown, err := NewTrack(100, 1235, "text", "pion", codec)
+	assert.NoError(t, err)
+	_, err = pc.AddTrack(unknown)
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrUnknownTrackKind}, err)
+	_, err = pc.AddTransceiverFromTrack(unknown)
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrUnknownTrackKind}, err)
+
+	// A track is only bound to a transceiver of its kind
+	transceiver := &RTPTransceiver{Sender: &RTPSender{}, Direction: RTPTransceiverDirectionRecvonly, kind: RTPCodecTypeAudio}
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrTrackKindMismatch}, transceiver.setSendingTrack(track))
+	assert.Nil(t, transceiver.Sender.track)
+
+	_, err = pc.AddTrack(track)
+	assert.NoError(t, err)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestOfferPTime(t *testing.T) {
+	codec := NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000)
+	codec.PTime = 20 * time.Millisecond
+	codec.MaxPTime = 40 * time.Millisecond
+
+	m := MediaEngine{}
+	m.RegisterCodec(codec)
+	pc, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pc.AddTransceiverFromKind(RTPCodecTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ptime, maxPTime := getPTimes(offer.parsed.MediaDescriptions[0])
+	assert.Equal(t, 20*time.Millisecond, ptime)
+	assert.Equal(t, 40*time.Millisecond, maxPTime)
+	assert.NoError(t, pc.Close())
+}
+
+func TestRTCPMuxPolicy(t *testing.T) {
+	s := SettingEngine{}
+	s.SetRTCPMuxOnly(true)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rtcpMuxOnly := offer.parsed.MediaDescriptions[0].Attribute(sdpAttrKeyRTCPMuxOnly)
+	assert.True(t, rtcpMuxOnly)
+
+	// A remote that can't mux RTCP is rejected by the default policy
+	offer.SDP = strings.Replace(offer.SDP, "a=rtcp-mux\r\n", "", -1)
+
+	pcRequire, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pcRequire.SetRemoteDescription(offer)
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrRTCPMuxRequired}, err)
+
+	parsed := &sdp.SessionDescription{}
+	assert.NoError(t, parsed.Unmarshal([]byte(offer.SDP)))
+	assert.False(t, supportsRTCPMux(parsed))
+
+	// and accepted by the negotiate policy
+	pcNegotiate, err := api.NewPeerConnection(Configuration{RTCPMuxPolicy: RTCPMuxPolicyNegotiate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, pcNegotiate.SetRemoteDescription(offer))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcRequire.Close())
+	assert.NoError(t, pcNegotiate.Close())
+}
+
+func TestBundlePolicyMaxBundle(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, err := api.NewPeerConnection(Configuration{BundlePolicy: BundlePolicyMaxBundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []RTPCodecType{RTPCodecTypeVideo, RTPCodecTypeAudio} {
+		if _, err = pcOffer.AddTransceiverFromKind(kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range offer.parsed.MediaDescriptions {
+		_, bundleOnly := m.Attribute(sdpAttrKeyBundleOnly)
+		assert.Equal(t, i != 0, bundleOnly)
+		assert.Equal(t, i != 0, m.MediaName.Port.Value == 0)
+	}
+
+	// Without BUNDLE only the first section of the offer is accepted
+	offer.SDP = regexp.MustCompile("a=group:BUNDLE[^\\r]*\\r\\n").ReplaceAllString(offer.SDP, "")
+
+	pcAnswer, err := api.NewPeerConnection(Configuration{BundlePolicy: BundlePolicyMaxBundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, haveGroup := answer.parsed.Attribute(sdp.AttrKeyGroup)
+	assert.False(t, haveGroup)
+	assert.Equal(t, 3, len(answer.parsed.MediaDescriptions))
+	for i, m := range answer.parsed.MediaDescriptions {
+		assert.Equal(t, i != 0, m.MediaName.Port.Value == 0)
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
 }
 
 func TestAddTransceiverFromTrackSendOnly(t *testing.T) {
@@ -842,3 +1273,194 @@ func TestAddTransceiverFromTrackFailsRecvOnly(t *testing.T) {
 
 	assert.NotNil(t, err)
 }
+
+func TestAnswerOptionsDirections(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+		_, err = pcOffer.AddTransceiverFromKind(kind)
+		assert.NoError(t, err)
+	}
+
+	answerTrack, err := pcAnswer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTrack(answerTrack)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	// A sendrecv transceiver with a track is answered as recvonly without sources
+	answer, err := pcAnswer.CreateAnswer(&AnswerOptions{
+		KindDirections: map[RTPCodecType]RTPTransceiverDirection{RTPCodecTypeVideo: RTPTransceiverDirectionRecvonly},
+	})
+	assert.NoError(t, err)
+	assert.True(t, offerMediaHasDirection(answer, RTPCodecTypeVideo, RTPTransceiverDirectionRecvonly))
+	assert.NotContains(t, answer.SDP, "a=ssrc:")
+
+	// Directions by mid take precedence over KindDirections
+	audioMid := pcAnswer.getMidValue(offer.parsed.MediaDescriptions[0])
+	answer, err = pcAnswer.CreateAnswer(&AnswerOptions{
+		Directions:     map[string]RTPTransceiverDirection{audioMid: RTPTransceiverDirectionInactive},
+		KindDirections: map[RTPCodecType]RTPTransceiverDirection{RTPCodecTypeAudio: RTPTransceiverDirectionSendonly},
+	})
+	assert.NoError(t, err)
+	assert.True(t, offerMediaHasDirection(answer, RTPCodecTypeAudio, RTPTransceiverDirectionInactive))
+	assert.True(t, offerMediaHasDirection(answer, RTPCodecTypeVideo, RTPTransceiverDirectionSendrecv))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestAnswerOptionsIncompatibleDirection(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	_, err = pcAnswer.CreateAnswer(&AnswerOptions{
+		KindDirections: map[RTPCodecType]RTPTransceiverDirection{RTPCodecTypeVideo: RTPTransceiverDirectionRecvonly},
+	})
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrIncompatibleAnswerDirection}, err)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestAnswerOptionsDTLSRole(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	opened := make(chan struct{})
+	dc, err := pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	dc.OnOpen(func() { close(opened) })
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	// The answerer takes the server role and the offerer follows it
+	answer, err := pcAnswer.CreateAnswer(&AnswerOptions{DTLSRole: DTLSRoleServer})
+	assert.NoError(t, err)
+	assert.Equal(t, sdp.ConnectionRolePassive.String(), connectionSetup(answer.parsed))
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+	<-opened
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestAnswerConnectionRole(t *testing.T) {
+	active, passive, actpass := sdp.ConnectionRoleActive, sdp.ConnectionRolePassive, sdp.ConnectionRoleActpass
+	testCases := []struct {
+		remoteSetup sdp.ConnectionRole
+		role        DTLSRole
+		expected    sdp.ConnectionRole
+	}{
+		{actpass, DTLSRoleAuto, active},
+		{active, DTLSRoleAuto, passive},
+		{passive, DTLSRoleAuto, active},
+		{actpass, DTLSRoleClient, active},
+		{actpass, DTLSRoleServer, passive},
+		{active, DTLSRoleServer, passive},
+		{passive, DTLSRoleClient, active},
+	}
+
+	for i, testCase := range testCases {
+		role, err := answerConnectionRole(testCase.remoteSetup.String(), testCase.role)
+		assert.NoError(t, err, "testCase: %d %v", i, testCase)
+		assert.Equal(t, testCase.expected, role, "testCase: %d %v", i, testCase)
+	}
+
+	_, err := answerConnectionRole(active.String(), DTLSRoleClient)
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrIncompatibleAnswerDTLSRole}, err)
+	_, err = answerConnectionRole(passive.String(), DTLSRoleServer)
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrIncompatibleAnswerDTLSRole}, err)
+}
+
+func TestPeerConnection_Media_InboundTrackQuota(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetQuotas(Quotas{MaxInboundTracks: 1})
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tracks []*Track
+	for i := 0; i < 2; i++ {
+		if _, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo); err != nil {
+			t.Fatal(err)
+		}
+
+		track, trackErr := pcOffer.NewTrack(DefaultPayloadTypeVP8, 0, fmt.Sprintf("video%d", i), "pion")
+		if trackErr != nil {
+			t.Fatal(trackErr)
+		}
+		if _, err = pcOffer.AddTrack(track); err != nil {
+			t.Fatal(err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	onTrack := make(chan *Track, 2)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		onTrack <- track
+	})
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				for _, track := range tracks {
+					if routineErr := track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}); routineErr != nil {
+						fmt.Println(routineErr)
+					}
+				}
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only one of the tracks is received
+	<-onTrack
+	select {
+	case track := <-onTrack:
+		t.Fatalf("Track %s exceeding the quota was received", track.ID())
+	case <-time.After(time.Second):
+	}
+
+	close(done)
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
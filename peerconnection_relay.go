@@ -0,0 +1,165 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/pion/rtcp"
+)
+
+// Relay returns a local track that republishes remote, a track received by
+// this PeerConnection, e.g. to forward it to other PeerConnections in an
+// SFU. The local track has the codec of remote and an SSRC of its own, it
+// can be added to any number of PeerConnections.
+//
+// The RTP of remote is forwarded until it ends, with the SSRC of the local
+// track. Retransmissions and FEC of the remote aren't forwarded, nor are its
+// header extensions, the senders add the ones they negotiated. The relay
+// reads a clone of remote, the track itself can still be read, see
+// Track.Clone.
+//
+// The RTCP of the senders of the local track is read by the relay: the key
+// frame requests of the viewers are passed on to the remote, consolidated by
+// the RTPReceiver, and a key frame is requested when the track starts to be
+// sent to another viewer. Once remote ends, the transceivers of the senders
+// are stopped, the PeerConnections have to be renegotiated to remove the
+// track.
+func (pc *PeerConnection) Relay(remote *Track) (*Track, error) {
+	remote.mu.RLock()
+	receiver := remote.receiver
+	remote.mu.RUnlock()
+	if receiver == nil {
+		return nil, fmt.Errorf("this is a local track and can not be relayed")
+	}
+
+	source, err := remote.Clone()
+	if err != nil {
+		return nil, err
+	}
+	local, err := NewTrack(remote.PayloadType(), pc.ssrcs.allocate(), remote.ID(), remote.Label(), remote.Codec())
+	if err != nil {
+		return nil, err
+	}
+
+	r := &trackRelay{
+		source:   source,
+		local:    local,
+		receiver: receiver,
+		senders:  map[*RTPSender]struct{}{},
+		pc:       pc,
+	}
+	go r.forward()
+	return local, nil
+}
+
+// trackRelay forwards a remote track to a local one, see
+// PeerConnection.Relay
+type trackRelay struct {
+	source   *Track
+	local    *Track
+	receiver *RTPReceiver
+	pc       *PeerConnection
+
+	// senders are the senders of the local track whose RTCP is read
+	mu      sync.Mutex
+	senders map[*RTPSender]struct{}
+}
+
+// forward copies the packets of the source to the local track until the
+// source ends
+func (r *trackRelay) forward() {
+	defer r.stop()
+
+	payloadType := r.source.PayloadType()
+	ssrc := r.local.SSRC()
+	for {
+		pkt, err := r.source.ReadRTP()
+		if err != nil {
+			return
+		}
+		if pkt.PayloadType != payloadType {
+			continue
+		}
+
+		r.bindSenders()
+
+		pkt.SSRC = ssrc
+		pkt.Extension = false
+		pkt.ExtensionProfile = 0
+		pkt.ExtensionPayload = nil
+		if err := r.local.WriteRTP(pkt); err != nil && err != io.ErrClosedPipe {
+			r.pc.log.Debugf("failed to relay track %s: %v", r.local.ID(), err)
+		}
+	}
+}
+
+// bindSenders starts to read the RTCP of the senders the local track is
+// sent with since the last packet
+func (r *trackRelay) bindSenders() {
+	for _, binding := range r.local.Bindings() {
+		r.mu.Lock()
+		_, ok := r.senders[binding.Sender]
+		r.senders[binding.Sender] = struct{}{}
+		r.mu.Unlock()
+		if ok {
+			continue
+		}
+
+		if r.local.Kind() == RTPCodecTypeVideo {
+			r.requestKeyFrame()
+		}
+		go r.readRTCP(binding.Sender)
+	}
+}
+
+// readRTCP passes the key frame requests of a sender on to the remote until
+// the sender stops
+func (r *trackRelay) readRTCP(sender *RTPSender) {
+	defer func() {
+		r.mu.Lock()
+		delete(r.senders, sender)
+		r.mu.Unlock()
+	}()
+
+	b := make([]byte, receiveMTU)
+	for {
+		n, err := sender.Read(b)
+		if err != nil {
+			return
+		}
+		if len(feedbackMessages(b[:n], rtcp.TypePayloadSpecificFeedback, rtcp.FormatPLI)) != 0 ||
+			len(feedbackMessages(b[:n], rtcp.TypePayloadSpecificFeedback, formatFIR)) != 0 {
+			r.requestKeyFrame()
+		}
+	}
+}
+
+func (r *trackRelay) requestKeyFrame() {
+	if err := r.receiver.RequestKeyFrame(); err != nil {
+		r.pc.log.Debugf("failed to request a key frame of %s: %v", r.local.ID(), err)
+	}
+}
+
+// stop stops the transceivers of the senders of the local track
+func (r *trackRelay) stop() {
+	for _, binding := range r.local.Bindings() {
+		if binding.PeerConnection == nil {
+			if err := binding.Sender.Stop(); err != nil {
+				r.pc.log.Debugf("failed to stop a sender of %s: %v", r.local.ID(), err)
+			}
+			continue
+		}
+		for _, transceiver := range binding.PeerConnection.GetTransceivers() {
+			if transceiver.Sender != binding.Sender {
+				continue
+			}
+			if err := transceiver.Stop(); err != nil {
+				r.pc.log.Debugf("failed to stop the transceiver of %s: %v", r.local.ID(), err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConnection_Relay(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	// The publisher sends a track to the SFU, which relays it to the viewer
+	publisher, sfuIn, err := api.newPair()
+	assert.NoError(t, err)
+	sfuOut, viewer, err := api.newPair()
+	assert.NoError(t, err)
+
+	track, err := publisher.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := publisher.AddTrack(track)
+	assert.NoError(t, err)
+
+	_, err = sfuIn.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = viewer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	_, err = sfuIn.Relay(track)
+	assert.Error(t, err)
+
+	relayed := make(chan *Track, 1)
+	sfuIn.OnTrack(func(remote *Track, _ *RTPReceiver) {
+		local, relayErr := sfuIn.Relay(remote)
+		assert.NoError(t, relayErr)
+		relayed <- local
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{0xab, 0xbb, 0xcc}, Samples: 90})
+			}
+		}
+	}()
+	keyFrameRequests := make(chan struct{}, 10)
+	go func() {
+		for {
+			pkts, readErr := sender.ReadRTCP()
+			if readErr != nil {
+				return
+			}
+			for _, pkt := range pkts {
+				if _, ok := pkt.(*rtcp.PictureLossIndication); ok {
+					select {
+					case keyFrameRequests <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(publisher, sfuIn))
+	local := <-relayed
+	assert.NotEqual(t, track.SSRC(), local.SSRC())
+	assert.Equal(t, "video", local.ID())
+
+	_, err = sfuOut.AddTrack(local)
+	assert.NoError(t, err)
+	received := make(chan *Track, 1)
+	viewer.OnTrack(func(remote *Track, _ *RTPReceiver) {
+		received <- remote
+	})
+	assert.NoError(t, signalPair(sfuOut, viewer))
+
+	remote := <-received
+	assert.Equal(t, local.SSRC(), remote.SSRC())
+	p, err := remote.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, local.SSRC(), p.SSRC)
+
+	// Key frame requests of the viewer reach the publisher, at most one per
+	// key frame request interval
+	<-keyFrameRequests
+	for requested := false; !requested; {
+		assert.NoError(t, viewer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: remote.SSRC()}}))
+		select {
+		case <-keyFrameRequests:
+			requested = true
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+
+	// The relay stops sending once the remote track ended
+	assert.NoError(t, publisher.Close())
+	assert.NoError(t, sfuIn.Close())
+	for len(local.Bindings()) != 0 {
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	assert.NoError(t, sfuOut.Close())
+	assert.NoError(t, viewer.Close())
+}
@@ -0,0 +1,210 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strconv"
+
+	"github.com/pion/sdp/v2"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// updateRemoteDescription applies a remote description after the first one,
+// e.g. a re-offer from a browser that adds screenshare mid-call. Only
+// additive changes are supported: every negotiated media section has to be
+// kept with the same mid and kind, and DTLS must not be restarted. New ICE
+// credentials restart ICE. The transports keep running, receivers for new
+// tracks are started once the renegotiation is complete.
+func (pc *PeerConnection) updateRemoteDescription(desc *SessionDescription) error {
+	if err := checkAdditiveDescription(pc.currentRemoteDescription.parsed, desc.parsed); err != nil {
+		return err
+	}
+	iceRestart := iceCredentialsChanged(pc.currentRemoteDescription.parsed, desc.parsed)
+	if err := pc.setDescription(desc, stateChangeOpSetRemote); err != nil {
+		return err
+	}
+	pc.setRemoteSSRCs()
+	if iceRestart {
+		if err := pc.restartICE(desc); err != nil {
+			return err
+		}
+	}
+
+	// The remote stopped the transceivers of the sections it rejects
+	for _, media := range desc.parsed.MediaDescriptions {
+		if !isRejectedMediaSection(media) {
+			continue
+		}
+		for _, t := range pc.GetTransceivers() {
+			if t.mid != "" && t.mid == pc.getMidValue(media) {
+				if err := t.Stop(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if desc.Type == SDPTypeAnswer {
+		pc.removeStoppedTransceivers()
+		pc.startRenegotiatedTransceivers()
+	}
+	return nil
+}
+
+// removeStoppedTransceivers removes the stopped transceivers whose media
+// section has been rejected, or that were never negotiated
+func (pc *PeerConnection) removeStoppedTransceivers() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	var transceivers []*RTPTransceiver
+	for _, t := range pc.rtpTransceivers {
+		if t.isStopped() && (t.mid == "" || sectionRejected(pc.currentLocalDescription, t.mid)) {
+			continue
+		}
+		transceivers = append(transceivers, t)
+	}
+	pc.rtpTransceivers = transceivers
+}
+
+// sectionRejected tells if the media section midValue of desc is rejected
+func sectionRejected(desc *SessionDescription, midValue string) bool {
+	if desc == nil || desc.parsed == nil {
+		return false
+	}
+	for _, media := range desc.parsed.MediaDescriptions {
+		if mid, _ := media.Attribute(sdp.AttrKeyMID); mid == midValue {
+			return isRejectedMediaSection(media)
+		}
+	}
+	return false
+}
+
+// startRenegotiatedTransceivers starts the senders and receivers of tracks
+// that were added by a renegotiation. Before the transports are connected
+// there is nothing to do, all tracks are started once they are.
+func (pc *PeerConnection) startRenegotiatedTransceivers() {
+	pc.mu.RLock()
+	srtpOpened := pc.srtpOpened
+	pc.mu.RUnlock()
+
+	if srtpOpened {
+		pc.startRTPSenders()
+		pc.openSRTP()
+	}
+}
+
+// offerSection is a media section of a unified plan offer
+type offerSection struct {
+	mid string
+	// transceiver of the section, nil for the data section
+	transceiver *RTPTransceiver
+	// rejected is set for a negotiated section that has no transceiver anymore
+	// or whose transceiver has been stopped
+	rejected *sdp.MediaDescription
+}
+
+// offerSections returns the media sections of an offer. Sections that have
+// been negotiated keep their position and mid, new transceivers are added
+// after them. Before the first negotiation every transceiver gets a new
+// section, followed by the data section.
+func (pc *PeerConnection) offerSections() []offerSection {
+	var sections []offerSection
+	usedMids := map[string]bool{}
+	placed := map[*RTPTransceiver]bool{}
+	haveData := false
+
+	if pc.currentLocalDescription != nil {
+		for _, media := range pc.currentLocalDescription.parsed.MediaDescriptions {
+			midValue := pc.getMidValue(media)
+			usedMids[midValue] = true
+
+			if media.MediaName.Media == "application" {
+				sections = append(sections, offerSection{mid: midValue})
+				haveData = true
+				continue
+			}
+
+			section := offerSection{mid: midValue, rejected: media}
+			for _, t := range pc.GetTransceivers() {
+				if t.mid == midValue && !placed[t] {
+					placed[t] = true
+					if !t.isStopped() {
+						section = offerSection{mid: midValue, transceiver: t}
+					}
+					break
+				}
+			}
+			sections = append(sections, section)
+		}
+	}
+
+	nextMid := func() string {
+		for i := len(sections); ; i++ {
+			if midValue := strconv.Itoa(i); !usedMids[midValue] {
+				usedMids[midValue] = true
+				return midValue
+			}
+		}
+	}
+
+	for _, t := range pc.GetTransceivers() {
+		if !placed[t] && !t.isStopped() {
+			sections = append(sections, offerSection{mid: nextMid(), transceiver: t})
+		}
+	}
+	if !haveData {
+		sections = append(sections, offerSection{mid: nextMid()})
+	}
+	return sections
+}
+
+// checkAdditiveDescription checks that next only adds to current
+func checkAdditiveDescription(current, next *sdp.SessionDescription) error {
+	notAdditive := &rtcerr.InvalidModificationError{Err: ErrRenegotiationNotAdditive}
+
+	if len(next.MediaDescriptions) < len(current.MediaDescriptions) {
+		return notAdditive
+	}
+	for i, m := range current.MediaDescriptions {
+		n := next.MediaDescriptions[i]
+		if n.MediaName.Media != m.MediaName.Media {
+			return notAdditive
+		}
+
+		mid, _ := m.Attribute(sdp.AttrKeyMID)
+		nextMid, _ := n.Attribute(sdp.AttrKeyMID)
+		if mid != nextMid {
+			return notAdditive
+		}
+	}
+
+	if descriptionAttribute(current, "fingerprint") != descriptionAttribute(next, "fingerprint") {
+		return notAdditive
+	}
+	return nil
+}
+
+// iceCredentialsChanged tells if next restarts ICE
+func iceCredentialsChanged(current, next *sdp.SessionDescription) bool {
+	for _, key := range []string{"ice-ufrag", "ice-pwd"} {
+		if descriptionAttribute(current, key) != descriptionAttribute(next, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// descriptionAttribute returns the value of the session level attribute key,
+// or of the first media section that has it
+func descriptionAttribute(d *sdp.SessionDescription, key string) string {
+	if value, ok := d.Attribute(key); ok {
+		return value
+	}
+	for _, m := range d.MediaDescriptions {
+		if value, ok := m.Attribute(key); ok {
+			return value
+		}
+	}
+	return ""
+}
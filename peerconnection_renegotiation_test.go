@@ -0,0 +1,198 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+// renegotiate runs a second offer/answer exchange on a connected pair
+func renegotiate(pcOffer, pcAnswer *PeerConnection) (SessionDescription, error) {
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		return offer, err
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		return offer, err
+	}
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		return offer, err
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		return offer, err
+	}
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		return offer, err
+	}
+	return offer, pcOffer.SetRemoteDescription(answer)
+}
+
+func TestRenegotiationAddTrack(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	camera, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "camera", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(camera)
+	assert.NoError(t, err)
+
+	remoteTracks := make(chan *Track, 2)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		remoteTracks <- track
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	writeSamples := func(track *Track) {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1})
+			}
+		}
+	}
+	go writeSamples(camera)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	remoteCamera := <-remoteTracks
+	assert.Equal(t, camera.SSRC(), remoteCamera.SSRC())
+	pcOffer.OnICECandidate(func(*ICECandidate) {})
+
+	// Add screenshare mid-call
+	screen, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "screen", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(screen)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	offer, err := renegotiate(pcOffer, pcAnswer)
+	assert.NoError(t, err)
+
+	// Negotiated sections keep their position and mid
+	var mids []string
+	for _, m := range offer.parsed.MediaDescriptions {
+		mids = append(mids, pcOffer.getMidValue(m))
+	}
+	assert.Equal(t, []string{"0", "1", "2"}, mids)
+	assert.Equal(t, "application", offer.parsed.MediaDescriptions[1].MediaName.Media)
+
+	go writeSamples(screen)
+	assert.Equal(t, screen.SSRC(), (<-remoteTracks).SSRC())
+
+	// The camera keeps running
+	_, err = remoteCamera.ReadRTP()
+	assert.NoError(t, err)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRenegotiationNotAdditive(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// A second PeerConnection has different ICE credentials and a different
+	// fingerprint, its offer would restart ICE and DTLS
+	pcOther, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pcOther.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	offer, err := pcOther.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &rtcerr.InvalidModificationError{Err: ErrRenegotiationNotAdditive}, pcAnswer.SetRemoteDescription(offer))
+
+	assert.NoError(t, pcOther.Close())
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRenegotiationStopTransceiver(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	camera, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "camera", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(camera)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	pcOffer.OnICECandidate(func(*ICECandidate) {})
+	assert.Equal(t, 1, len(pcOffer.GetTransceivers()))
+	assert.Equal(t, 1, len(pcAnswer.GetTransceivers()))
+
+	// Churn: every round replaces the track with a new transceiver
+	for i := 0; i < 3; i++ {
+		transceiver := pcOffer.GetTransceivers()[0]
+		mid := transceiver.Mid()
+		assert.NoError(t, transceiver.Stop())
+
+		track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "camera", "pion")
+		assert.NoError(t, err)
+		_, err = pcOffer.AddTransceiverFromTrack(track, RtpTransceiverInit{Direction: RTPTransceiverDirectionSendonly})
+		assert.NoError(t, err)
+		_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+		assert.NoError(t, err)
+
+		offer, err := renegotiate(pcOffer, pcAnswer)
+		assert.NoError(t, err)
+
+		for _, m := range offer.parsed.MediaDescriptions {
+			if pcOffer.getMidValue(m) == mid {
+				assert.True(t, isRejectedMediaSection(m))
+			}
+		}
+		for _, m := range pcAnswer.CurrentLocalDescription().parsed.MediaDescriptions {
+			if pcAnswer.getMidValue(m) == mid {
+				assert.True(t, isRejectedMediaSection(m))
+			}
+		}
+
+		// Stopped transceivers are gone on both sides, the answerer's
+		// recvonly transceivers that weren't used remain
+		assert.Equal(t, 1, len(pcOffer.GetTransceivers()))
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			assert.NotEqual(t, mid, transceiver.Mid())
+			assert.False(t, transceiver.isStopped())
+		}
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
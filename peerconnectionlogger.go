@@ -0,0 +1,66 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/pion/logging"
+)
+
+// peerConnectionLoggerFactory creates the loggers of a PeerConnection and
+// of its transports. Every message is prefixed with the ID and the label of
+// the PeerConnection so that the logs of a session can be told apart from
+// the logs of the other sessions of a server.
+type peerConnectionLoggerFactory struct {
+	factory logging.LoggerFactory
+	id      string
+
+	// label is a string, it is changed with PeerConnection.SetLabel
+	label atomic.Value
+}
+
+func newPeerConnectionLoggerFactory(factory logging.LoggerFactory, id string) *peerConnectionLoggerFactory {
+	f := &peerConnectionLoggerFactory{factory: factory, id: id}
+	f.label.Store("")
+	return f
+}
+
+// NewLogger creates a logger for scope that prefixes the messages
+func (f *peerConnectionLoggerFactory) NewLogger(scope string) logging.LeveledLogger {
+	return &peerConnectionLogger{factory: f, log: f.factory.NewLogger(scope)}
+}
+
+func (f *peerConnectionLoggerFactory) prefix() string {
+	if label := f.label.Load().(string); label != "" {
+		return fmt.Sprintf("[%s %s] ", f.id, label)
+	}
+	return fmt.Sprintf("[%s] ", f.id)
+}
+
+type peerConnectionLogger struct {
+	factory *peerConnectionLoggerFactory
+	log     logging.LeveledLogger
+}
+
+func (l *peerConnectionLogger) Trace(msg string) { l.log.Trace(l.factory.prefix() + msg) }
+func (l *peerConnectionLogger) Tracef(format string, args ...interface{}) {
+	l.log.Trace(l.factory.prefix() + fmt.Sprintf(format, args...))
+}
+func (l *peerConnectionLogger) Debug(msg string) { l.log.Debug(l.factory.prefix() + msg) }
+func (l *peerConnectionLogger) Debugf(format string, args ...interface{}) {
+	l.log.Debug(l.factory.prefix() + fmt.Sprintf(format, args...))
+}
+func (l *peerConnectionLogger) Info(msg string) { l.log.Info(l.factory.prefix() + msg) }
+func (l *peerConnectionLogger) Infof(format string, args ...interface{}) {
+	l.log.Info(l.factory.prefix() + fmt.Sprintf(format, args...))
+}
+func (l *peerConnectionLogger) Warn(msg string) { l.log.Warn(l.factory.prefix() + msg) }
+func (l *peerConnectionLogger) Warnf(format string, args ...interface{}) {
+	l.log.Warn(l.factory.prefix() + fmt.Sprintf(format, args...))
+}
+func (l *peerConnectionLogger) Error(msg string) { l.log.Error(l.factory.prefix() + msg) }
+func (l *peerConnectionLogger) Errorf(format string, args ...interface{}) {
+	l.log.Error(l.factory.prefix() + fmt.Sprintf(format, args...))
+}
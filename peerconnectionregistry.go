@@ -0,0 +1,46 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sort"
+	"sync"
+)
+
+// peerConnectionRegistry keeps the PeerConnections of an API that aren't
+// closed, see API.HealthHandler
+type peerConnectionRegistry struct {
+	mu              sync.Mutex
+	peerConnections map[*PeerConnection]struct{}
+}
+
+func newPeerConnectionRegistry() *peerConnectionRegistry {
+	return &peerConnectionRegistry{peerConnections: map[*PeerConnection]struct{}{}}
+}
+
+func (r *peerConnectionRegistry) add(pc *PeerConnection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.peerConnections[pc] = struct{}{}
+}
+
+func (r *peerConnectionRegistry) remove(pc *PeerConnection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.peerConnections, pc)
+}
+
+// list returns the PeerConnections ordered by their ID
+func (r *peerConnectionRegistry) list() []*PeerConnection {
+	r.mu.Lock()
+	peerConnections := make([]*PeerConnection, 0, len(r.peerConnections))
+	for pc := range r.peerConnections {
+		peerConnections = append(peerConnections, pc)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(peerConnections, func(i, j int) bool {
+		return peerConnections[i].ID() < peerConnections[j].ID()
+	})
+	return peerConnections
+}
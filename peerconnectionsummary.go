@@ -0,0 +1,89 @@
+// +build !js
+
+package webrtc
+
+// PeerConnectionSummary is a snapshot of the state of a PeerConnection for
+// operational debugging, see API.HealthHandler. Unlike GetStats it only
+// covers what is needed to tell at a glance whether a connection is healthy.
+type PeerConnectionSummary struct {
+	ID                 string `json:"id"`
+	Label              string `json:"label,omitempty"`
+	ConnectionState    string `json:"connectionState"`
+	ICEConnectionState string `json:"iceConnectionState"`
+	SignalingState     string `json:"signalingState"`
+
+	// SelectedCandidatePair is nil until ICE selected a pair
+	SelectedCandidatePair *CandidatePairSummary `json:"selectedCandidatePair,omitempty"`
+
+	Tracks []TrackSummary `json:"tracks"`
+
+	// DataChannels is the number of data channels that aren't closed
+	DataChannels int `json:"dataChannels"`
+}
+
+// CandidatePairSummary describes the candidates of the selected pair of a
+// PeerConnection
+type CandidatePairSummary struct {
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// TrackSummary describes a track a PeerConnection sends or receives. Remote
+// tracks are reported once their first packet arrived.
+type TrackSummary struct {
+	ID    string       `json:"id"`
+	Kind  RTPCodecType `json:"kind"`
+	SSRC  uint32       `json:"ssrc"`
+	Codec string       `json:"codec"`
+
+	// Direction is "outbound" for local tracks and "inbound" for remote ones
+	Direction string `json:"direction"`
+
+	// Bitrate in bits per second, averaged over the last second
+	Bitrate float64 `json:"bitrate"`
+}
+
+// Summary returns a snapshot of the state of the PeerConnection
+func (pc *PeerConnection) Summary() PeerConnectionSummary {
+	summary := PeerConnectionSummary{
+		ID:                 pc.ID(),
+		Label:              pc.Label(),
+		ConnectionState:    pc.ConnectionState().String(),
+		ICEConnectionState: pc.ICEConnectionState().String(),
+		SignalingState:     pc.SignalingState().String(),
+		Tracks:             []TrackSummary{},
+		DataChannels:       pc.openDataChannels(),
+	}
+
+	if pair := pc.iceTransport.GetSelectedCandidatePair(); pair != nil {
+		summary.SelectedCandidatePair = &CandidatePairSummary{
+			Local:  pair.Local.String(),
+			Remote: pair.Remote.String(),
+		}
+	}
+
+	for _, t := range pc.GetTransceivers() {
+		if t.Sender != nil && t.Sender.track != nil {
+			summary.Tracks = append(summary.Tracks, newTrackSummary(t.Sender.track, "outbound"))
+		}
+		if t.Receiver == nil || !t.Receiver.hasReceived() {
+			continue
+		}
+		// The codec of a remote track is known once its first packet arrived
+		if track := t.Receiver.Track(); track != nil && track.Codec() != nil {
+			summary.Tracks = append(summary.Tracks, newTrackSummary(track, "inbound"))
+		}
+	}
+	return summary
+}
+
+func newTrackSummary(track *Track, direction string) TrackSummary {
+	return TrackSummary{
+		ID:        track.ID(),
+		Kind:      track.Kind(),
+		SSRC:      track.SSRC(),
+		Codec:     track.Codec().Name,
+		Direction: direction,
+		Bitrate:   track.Counters().Bitrate,
+	}
+}
@@ -0,0 +1,184 @@
+// Package capture provides a pluggable interface for cameras and screens that
+// produce encoded samples, so they can be sent with Track.WriteSample.
+//
+// Back-ends for a platform (v4l2, AVFoundation, DirectShow, ...) live in their
+// own packages and register a Driver in their init function, like
+// image formats and database/sql drivers do:
+//
+//	import _ "example.com/capture/v4l2"
+//
+//	device, err := capture.Open("v4l2", "/dev/video0", capture.Constraints{Codec: webrtc.VP8})
+//	go capture.Pump(track, device)
+package capture
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+var (
+	// ErrUnknownDriver indicates that no driver is registered with a name
+	ErrUnknownDriver = errors.New("unknown capture driver")
+
+	// ErrUnsupportedCodec indicates that a device can't produce samples in the
+	// requested codec
+	ErrUnsupportedCodec = errors.New("codec not supported by capture device")
+)
+
+// Kind is the kind of media a device captures
+type Kind int
+
+const (
+	// KindCamera is a video input, like a webcam
+	KindCamera Kind = iota + 1
+	// KindScreen is the content of a display or window
+	KindScreen
+	// KindMicrophone is an audio input
+	KindMicrophone
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindCamera:
+		return "camera"
+	case KindScreen:
+		return "screen"
+	case KindMicrophone:
+		return "microphone"
+	default:
+		return "unknown"
+	}
+}
+
+// DeviceInfo describes a device that can be opened
+type DeviceInfo struct {
+	// ID is passed to Driver.Open, e.g. /dev/video0
+	ID    string
+	Label string
+	Kind  Kind
+}
+
+// Constraints describe the samples a device should produce. Zero values leave
+// the choice to the device.
+type Constraints struct {
+	// Codec is the name of the codec, e.g. webrtc.VP8
+	Codec     string
+	Width     int
+	Height    int
+	FrameRate float64
+	// BitRate in bits per second
+	BitRate int
+}
+
+// Device is an opened capture device
+type Device interface {
+	// ReadSample blocks until the next encoded sample is available. The
+	// Samples field is the duration in units of the clock rate of the codec.
+	ReadSample() (media.Sample, error)
+
+	// RequestKeyFrame makes the next sample a key frame, e.g. when a
+	// PictureLossIndication is received. Audio devices ignore it.
+	RequestKeyFrame() error
+
+	Close() error
+}
+
+// Driver opens devices of one capture back-end
+type Driver interface {
+	Devices() ([]DeviceInfo, error)
+	Open(id string, constraints Constraints) (Device, error)
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// Register makes a driver available by name. It panics if called twice with
+// the same name or if driver is nil.
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if driver == nil {
+		panic("capture: Register driver is nil")
+	}
+	if _, ok := drivers[name]; ok {
+		panic("capture: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns the sorted names of the registered drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getDriver(name string) (Driver, error) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	driver, ok := drivers[name]
+	if !ok {
+		return nil, ErrUnknownDriver
+	}
+	return driver, nil
+}
+
+// Devices lists the devices of a registered driver
+func Devices(driverName string) ([]DeviceInfo, error) {
+	driver, err := getDriver(driverName)
+	if err != nil {
+		return nil, err
+	}
+	return driver.Devices()
+}
+
+// Open opens a device of a registered driver
+func Open(driverName, id string, constraints Constraints) (Device, error) {
+	driver, err := getDriver(driverName)
+	if err != nil {
+		return nil, err
+	}
+	return driver.Open(id, constraints)
+}
+
+// SampleWriter is implemented by *webrtc.Track
+type SampleWriter interface {
+	WriteSample(s media.Sample) error
+}
+
+// Pump writes the samples of device to w until either fails. The device is
+// closed on return. An io.EOF from the device is not an error.
+func Pump(w SampleWriter, device Device) error {
+	defer func() {
+		_ = device.Close()
+	}()
+
+	for {
+		sample, err := device.ReadSample()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// Tracks without bound senders return io.ErrClosedPipe, keep capturing
+		// so a sender added later gets media
+		if err = w.WriteSample(sample); err != nil && err != io.ErrClosedPipe {
+			return err
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDevice struct {
+	samples []media.Sample
+	closed  bool
+}
+
+func (d *fakeDevice) ReadSample() (media.Sample, error) {
+	if len(d.samples) == 0 {
+		return media.Sample{}, io.EOF
+	}
+	s := d.samples[0]
+	d.samples = d.samples[1:]
+	return s, nil
+}
+
+func (d *fakeDevice) RequestKeyFrame() error { return nil }
+
+func (d *fakeDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+type fakeDriver struct {
+	device *fakeDevice
+}
+
+func (d *fakeDriver) Devices() ([]DeviceInfo, error) {
+	return []DeviceInfo{{ID: "fake0", Label: "Fake Camera", Kind: KindCamera}}, nil
+}
+
+func (d *fakeDriver) Open(id string, constraints Constraints) (Device, error) {
+	if constraints.Codec != "VP8" {
+		return nil, ErrUnsupportedCodec
+	}
+	return d.device, nil
+}
+
+type sampleRecorder struct {
+	samples []media.Sample
+}
+
+func (r *sampleRecorder) WriteSample(s media.Sample) error {
+	r.samples = append(r.samples, s)
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	device := &fakeDevice{samples: []media.Sample{{Data: []byte{0x00}, Samples: 3000}, {Data: []byte{0x01}, Samples: 3000}}}
+	Register("fake", &fakeDriver{device: device})
+	assert.Panics(t, func() { Register("fake", &fakeDriver{}) })
+	assert.Contains(t, Drivers(), "fake")
+
+	devices, err := Devices("fake")
+	assert.NoError(t, err)
+	assert.Equal(t, "fake0", devices[0].ID)
+
+	_, err = Open("missing", "fake0", Constraints{Codec: "VP8"})
+	assert.Equal(t, ErrUnknownDriver, err)
+
+	_, err = Open("fake", "fake0", Constraints{Codec: "H264"})
+	assert.Equal(t, ErrUnsupportedCodec, err)
+
+	opened, err := Open("fake", "fake0", Constraints{Codec: "VP8"})
+	assert.NoError(t, err)
+
+	recorder := &sampleRecorder{}
+	assert.NoError(t, Pump(recorder, opened))
+	assert.Len(t, recorder.samples, 2)
+	assert.True(t, device.closed)
+}
@@ -0,0 +1,65 @@
+// +build !js
+
+// Package pipe connects tracks to external media tools like ffmpeg and
+// GStreamer through the stdin and stdout of a process. Media is framed in a
+// container that carries timestamps where the codec allows it: IVF for VP8,
+// Ogg for Opus and Annex-B for H264.
+package pipe
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// FFmpegInputArgs returns the ffmpeg arguments that read the output of a Sink
+// for the codec from stdin
+func FFmpegInputArgs(codec *webrtc.RTPCodec) ([]string, error) {
+	switch {
+	case strings.EqualFold(codec.Name, webrtc.VP8):
+		return []string{"-f", "ivf", "-i", "pipe:0"}, nil
+	case strings.EqualFold(codec.Name, webrtc.Opus):
+		return []string{"-f", "ogg", "-i", "pipe:0"}, nil
+	case strings.EqualFold(codec.Name, webrtc.H264):
+		// Annex-B has no timestamps, frames are stamped on arrival
+		return []string{"-use_wallclock_as_timestamps", "1", "-f", "h264", "-i", "pipe:0"}, nil
+	default:
+		return nil, fmt.Errorf("%s can not be piped to ffmpeg", codec.Name)
+	}
+}
+
+// GStreamerSourceElements returns the beginning of a GStreamer pipeline that
+// reads the output of a Sink for the codec from stdin, e.g. to be used with
+// gst-launch-1.0 followed by a decoder
+func GStreamerSourceElements(codec *webrtc.RTPCodec) (string, error) {
+	switch {
+	case strings.EqualFold(codec.Name, webrtc.VP8):
+		return "fdsrc fd=0 ! ivfparse ! video/x-vp8", nil
+	case strings.EqualFold(codec.Name, webrtc.Opus):
+		return "fdsrc fd=0 ! oggdemux ! audio/x-opus", nil
+	case strings.EqualFold(codec.Name, webrtc.H264):
+		return "fdsrc fd=0 ! h264parse ! video/x-h264,stream-format=byte-stream,alignment=au", nil
+	default:
+		return "", fmt.Errorf("%s can not be piped to GStreamer", codec.Name)
+	}
+}
+
+// FFmpegOutputArgs returns the ffmpeg arguments that encode to the codec and
+// write the result to stdout in the format expected by a Source
+func FFmpegOutputArgs(codec *webrtc.RTPCodec) ([]string, error) {
+	if !strings.EqualFold(codec.Name, webrtc.VP8) {
+		return nil, fmt.Errorf("%s can not be read from ffmpeg", codec.Name)
+	}
+	return []string{"-c:v", "libvpx", "-deadline", "realtime", "-f", "ivf", "pipe:1"}, nil
+}
+
+// GStreamerSinkElements returns the end of a GStreamer pipeline that encodes
+// to the codec and writes the result to stdout in the format expected by a
+// Source
+func GStreamerSinkElements(codec *webrtc.RTPCodec) (string, error) {
+	if !strings.EqualFold(codec.Name, webrtc.VP8) {
+		return "", fmt.Errorf("%s can not be read from GStreamer", codec.Name)
+	}
+	return "vp8enc deadline=1 ! avmux_ivf ! fdsink fd=1", nil
+}
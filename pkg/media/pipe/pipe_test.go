@@ -0,0 +1,117 @@
+// +build !js
+
+package pipe
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/ivfwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRemoteTrack struct {
+	codec   *webrtc.RTPCodec
+	packets []*rtp.Packet
+}
+
+func (t *fakeRemoteTrack) Codec() *webrtc.RTPCodec { return t.codec }
+
+func (t *fakeRemoteTrack) ReadRTP() (*rtp.Packet, error) {
+	if len(t.packets) == 0 {
+		return nil, io.EOF
+	}
+	p := t.packets[0]
+	t.packets = t.packets[1:]
+	return p, nil
+}
+
+func (t *fakeRemoteTrack) NewReader(webrtc.ElementaryStreamFormat) (io.Reader, error) {
+	return nil, io.ErrUnexpectedEOF
+}
+
+type fakeLocalTrack struct {
+	codec   *webrtc.RTPCodec
+	samples []media.Sample
+}
+
+func (t *fakeLocalTrack) Codec() *webrtc.RTPCodec { return t.codec }
+
+func (t *fakeLocalTrack) WriteSample(s media.Sample) error {
+	t.samples = append(t.samples, s)
+	return nil
+}
+
+func vp8Packet(seq uint16, timestamp uint32) *rtp.Packet {
+	return &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			SequenceNumber: seq,
+			Timestamp:      timestamp,
+		},
+		// VP8 descriptor with the start of partition bit set, then a key frame
+		Payload: []byte{0x10, 0x00, 0x00, 0x00, 0x9d, 0x01, 0x2a},
+	}
+}
+
+func TestSink(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+
+	track := &fakeRemoteTrack{
+		codec:   webrtc.NewRTPVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000),
+		packets: []*rtp.Packet{vp8Packet(1, 0), vp8Packet(2, 3000)},
+	}
+
+	out := &bytes.Buffer{}
+	cmd := exec.Command("cat")
+	cmd.Stdout = out
+
+	keyFrameRequests := 0
+	sink, err := StartSink(track, cmd, func() error {
+		keyFrameRequests++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Wait())
+
+	assert.Equal(t, 1, keyFrameRequests)
+	assert.True(t, bytes.HasPrefix(out.Bytes(), []byte("DKIF")))
+}
+
+func TestSink_UnsupportedCodec(t *testing.T) {
+	track := &fakeRemoteTrack{codec: webrtc.NewRTPVP9Codec(webrtc.DefaultPayloadTypeVP9, 90000)}
+	_, err := StartSink(track, exec.Command("cat"), nil)
+	assert.Error(t, err)
+}
+
+func TestSource(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+
+	// The IVF written by ivfwriter uses a timebase of 1/30
+	ivf := &bytes.Buffer{}
+	writer, err := ivfwriter.NewWith(ivf)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.WriteRTP(vp8Packet(1, 0)))
+	assert.NoError(t, writer.WriteRTP(vp8Packet(2, 3000)))
+	assert.NoError(t, writer.Close())
+
+	cmd := exec.Command("cat")
+	cmd.Stdin = ivf
+
+	track := &fakeLocalTrack{codec: webrtc.NewRTPVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000)}
+	source, err := StartSource(track, cmd)
+	assert.NoError(t, err)
+	assert.NoError(t, source.Wait())
+
+	assert.Len(t, track.samples, 2)
+}
@@ -0,0 +1,125 @@
+// +build !js
+
+package pipe
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media/ivfwriter"
+	"github.com/pion/webrtc/v2/pkg/media/opuswriter"
+)
+
+// RemoteTrack is the part of a remote *webrtc.Track used by a Sink
+type RemoteTrack interface {
+	Codec() *webrtc.RTPCodec
+	ReadRTP() (*rtp.Packet, error)
+	NewReader(format webrtc.ElementaryStreamFormat) (io.Reader, error)
+}
+
+// Sink writes the media of a remote track to the stdin of a process
+type Sink struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  chan error
+}
+
+// StartSink starts cmd and writes the media of track to its stdin until the
+// track ends or the process exits. requestKeyFrame is called once the process
+// runs, so decoding can start without waiting for the next key frame. It is
+// usually a PictureLossIndication written to the PeerConnection and may be
+// nil, e.g. for audio.
+func StartSink(track RemoteTrack, cmd *exec.Cmd, requestKeyFrame func() error) (*Sink, error) {
+	codec := track.Codec()
+	if _, err := FFmpegInputArgs(codec); err != nil {
+		return nil, err
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	s := &Sink{cmd: cmd, stdin: stdin, done: make(chan error, 1)}
+	go func() {
+		err := s.copy(track, codec)
+		if closeErr := stdin.Close(); err == nil {
+			err = closeErr
+		}
+		if waitErr := cmd.Wait(); err == nil {
+			err = waitErr
+		}
+		s.done <- err
+	}()
+
+	if requestKeyFrame != nil {
+		if err = requestKeyFrame(); err != nil {
+			return s, fmt.Errorf("failed to request key frame: %v", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *Sink) copy(track RemoteTrack, codec *webrtc.RTPCodec) error {
+	var writeRTP func(*rtp.Packet) error
+	switch {
+	case strings.EqualFold(codec.Name, webrtc.H264):
+		r, err := track.NewReader(webrtc.ElementaryStreamH264AnnexB)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(s.stdin, r)
+		return ignoreEOF(err)
+	case strings.EqualFold(codec.Name, webrtc.VP8):
+		w, err := ivfwriter.NewWith(s.stdin)
+		if err != nil {
+			return err
+		}
+		writeRTP = w.WriteRTP
+	default:
+		w, err := opuswriter.NewWith(s.stdin, codec.ClockRate, codec.Channels)
+		if err != nil {
+			return err
+		}
+		writeRTP = w.WriteRTP
+	}
+
+	for {
+		p, err := track.ReadRTP()
+		if err != nil {
+			return ignoreEOF(err)
+		}
+		if err = writeRTP(p); err != nil {
+			return err
+		}
+	}
+}
+
+// Wait blocks until the track ended and the process exited
+func (s *Sink) Wait() error {
+	err := <-s.done
+	s.done <- err
+	return err
+}
+
+// Close stops the process
+func (s *Sink) Close() error {
+	if err := s.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+// +build !js
+
+package pipe
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/ivfreader"
+)
+
+// LocalTrack is the part of a local *webrtc.Track used by a Source
+type LocalTrack interface {
+	Codec() *webrtc.RTPCodec
+	WriteSample(s media.Sample) error
+}
+
+// Source writes the media a process prints to stdout to a local track
+type Source struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+// StartSource starts cmd and writes the IVF it prints to stdout to track.
+// Only VP8 is supported, see FFmpegOutputArgs and GStreamerSinkElements.
+func StartSource(track LocalTrack, cmd *exec.Cmd) (*Source, error) {
+	codec := track.Codec()
+	if !strings.EqualFold(codec.Name, webrtc.VP8) {
+		return nil, fmt.Errorf("%s can not be read from a process", codec.Name)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	s := &Source{cmd: cmd, done: make(chan error, 1)}
+	go func() {
+		err := writeIVF(track, codec.ClockRate, stdout)
+		if err != nil {
+			// Unblock the process if the track failed
+			_ = cmd.Process.Kill()
+		}
+		if waitErr := cmd.Wait(); err == nil {
+			err = waitErr
+		}
+		s.done <- err
+	}()
+	return s, nil
+}
+
+func writeIVF(track LocalTrack, clockRate uint32, r io.Reader) error {
+	ivf, header, err := ivfreader.NewWith(r)
+	if err != nil {
+		return err
+	}
+	if header.TimebaseDenominator == 0 {
+		return fmt.Errorf("IVF has no timebase")
+	}
+
+	var lastTimestamp uint64
+	for i := 0; ; i++ {
+		frame, frameHeader, err := ivf.ParseNextFrame()
+		if err != nil {
+			return ignoreEOF(err)
+		}
+
+		// The duration of a frame is the distance to the previous one
+		var samples uint32
+		if i != 0 {
+			elapsed := frameHeader.Timestamp - lastTimestamp
+			samples = uint32(elapsed * uint64(header.TimebaseNumerator) * uint64(clockRate) / uint64(header.TimebaseDenominator))
+		}
+		lastTimestamp = frameHeader.Timestamp
+
+		if err = track.WriteSample(media.Sample{Data: frame, Samples: samples}); err != nil && err != io.ErrClosedPipe {
+			return err
+		}
+	}
+}
+
+// Wait blocks until the process exited
+func (s *Source) Wait() error {
+	err := <-s.done
+	s.done <- err
+	return err
+}
+
+// Close stops the process
+func (s *Source) Close() error {
+	return s.cmd.Process.Kill()
+}
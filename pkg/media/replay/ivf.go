@@ -0,0 +1,48 @@
+package replay
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/ivfreader"
+)
+
+// ivfSampleReader reads the frames of an IVF file as samples
+type ivfSampleReader struct {
+	ivf       *ivfreader.IVFReader
+	header    *ivfreader.IVFFileHeader
+	clockRate uint32
+
+	started       bool
+	lastTimestamp uint64
+}
+
+// NewIVFSampleReader returns a SampleReader of the frames of an IVF file.
+// The frames are due at their timestamps, their durations are given in the
+// clock rate of the codec they are sent with, e.g. 90000 for VP8.
+func NewIVFSampleReader(ivf *ivfreader.IVFReader, header *ivfreader.IVFFileHeader, clockRate uint32) (SampleReader, error) {
+	if header.TimebaseDenominator == 0 {
+		return nil, fmt.Errorf("IVF has no timebase")
+	}
+	return &ivfSampleReader{ivf: ivf, header: header, clockRate: clockRate}, nil
+}
+
+func (r *ivfSampleReader) ReadSample() (TimedSample, error) {
+	frame, frameHeader, err := r.ivf.ParseNextFrame()
+	if err != nil {
+		return TimedSample{}, err
+	}
+
+	// The duration of a frame is the distance to the previous one
+	var samples uint32
+	if r.started {
+		elapsed := frameHeader.Timestamp - r.lastTimestamp
+		samples = uint32(elapsed * uint64(r.header.TimebaseNumerator) * uint64(r.clockRate) / uint64(r.header.TimebaseDenominator))
+	}
+	r.started = true
+	r.lastTimestamp = frameHeader.Timestamp
+
+	due := time.Duration(frameHeader.Timestamp * uint64(r.header.TimebaseNumerator) * uint64(time.Second) / uint64(r.header.TimebaseDenominator))
+	return TimedSample{Sample: media.Sample{Data: frame, Samples: samples}, Time: due}, nil
+}
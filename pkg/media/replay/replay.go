@@ -0,0 +1,134 @@
+// Package replay writes the samples of media files to tracks at the rate
+// they were recorded. Samples are scheduled by their timestamps against the
+// clock instead of sleeping between them, so replay doesn't drift over long
+// files.
+package replay
+
+import (
+	"io"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// DefaultMaxLag is how far a Player may fall behind the schedule before it
+// is restarted from the current sample
+const DefaultMaxLag = 500 * time.Millisecond
+
+// TimedSample is a sample and the time it is due, relative to the start of
+// the media
+type TimedSample struct {
+	media.Sample
+	Time time.Duration
+}
+
+// SampleReader reads the samples of a file in order. It returns io.EOF once
+// there are no more samples.
+type SampleReader interface {
+	ReadSample() (TimedSample, error)
+}
+
+// SampleWriter is the part of a local *webrtc.Track used by a Player
+type SampleWriter interface {
+	WriteSample(s media.Sample) error
+}
+
+// clock is time, replaced in tests
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Player writes the samples of a SampleReader to a track when they are due
+type Player struct {
+	track  SampleWriter
+	reader SampleReader
+	clock  clock
+	maxLag time.Duration
+
+	closed chan struct{}
+	done   chan error
+}
+
+// Start writes the samples of reader to track, the first one right away and
+// every other one when it is due relative to the first. If writing falls
+// behind by more than DefaultMaxLag, e.g. because the track blocked, the
+// schedule restarts from the late sample instead of sending everything that
+// is due in a burst.
+func Start(track SampleWriter, reader SampleReader) *Player {
+	return start(track, reader, systemClock{}, DefaultMaxLag)
+}
+
+func start(track SampleWriter, reader SampleReader, c clock, maxLag time.Duration) *Player {
+	p := &Player{
+		track:  track,
+		reader: reader,
+		clock:  c,
+		maxLag: maxLag,
+		closed: make(chan struct{}),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		p.done <- p.play()
+	}()
+	return p
+}
+
+func (p *Player) play() error {
+	var base time.Time
+	for i := 0; ; i++ {
+		s, err := p.reader.ReadSample()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		now := p.clock.Now()
+		if i == 0 {
+			base = now.Add(-s.Time)
+		}
+		due := base.Add(s.Time)
+		if wait := due.Sub(now); wait > 0 {
+			select {
+			case <-p.clock.After(wait):
+			case <-p.closed:
+				return nil
+			}
+		} else if -wait > p.maxLag {
+			base = base.Add(-wait)
+		}
+
+		select {
+		case <-p.closed:
+			return nil
+		default:
+		}
+		if err = p.track.WriteSample(s.Sample); err != nil && err != io.ErrClosedPipe {
+			return err
+		}
+	}
+}
+
+// Wait blocks until all samples were written or the Player was closed. It
+// returns the error of the reader or the track that stopped the Player.
+func (p *Player) Wait() error {
+	err := <-p.done
+	p.done <- err
+	return err
+}
+
+// Close stops writing samples. It doesn't close the reader.
+func (p *Player) Close() error {
+	select {
+	case <-p.closed:
+	default:
+		close(p.closed)
+	}
+	return nil
+}
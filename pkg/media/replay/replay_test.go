@@ -0,0 +1,140 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/ivfreader"
+	"github.com/pion/webrtc/v2/pkg/media/ivfwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock advances when it is waited on
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+type fakeReader struct {
+	samples []TimedSample
+	err     error
+}
+
+func (r *fakeReader) ReadSample() (TimedSample, error) {
+	if len(r.samples) == 0 {
+		return TimedSample{}, r.err
+	}
+	s := r.samples[0]
+	r.samples = r.samples[1:]
+	return s, nil
+}
+
+// fakeTrack records when samples were written, writing takes writeTime
+type fakeTrack struct {
+	clock     *fakeClock
+	writeTime map[int]time.Duration
+	written   []time.Duration
+}
+
+func (t *fakeTrack) WriteSample(media.Sample) error {
+	t.written = append(t.written, t.clock.now.Sub(time.Time{}))
+	t.clock.now = t.clock.now.Add(t.writeTime[len(t.written)-1])
+	return nil
+}
+
+func samplesAt(times ...time.Duration) []TimedSample {
+	samples := make([]TimedSample, len(times))
+	for i, due := range times {
+		samples[i] = TimedSample{Sample: media.Sample{Data: []byte{byte(i)}}, Time: due}
+	}
+	return samples
+}
+
+func TestPlayer(t *testing.T) {
+	ms := time.Millisecond
+
+	t.Run("Schedule", func(t *testing.T) {
+		c := &fakeClock{}
+		// Slow writes don't delay the following samples
+		track := &fakeTrack{clock: c, writeTime: map[int]time.Duration{1: 20 * ms}}
+		reader := &fakeReader{samples: samplesAt(1000*ms, 1033*ms, 1066*ms, 1100*ms), err: io.EOF}
+
+		assert.NoError(t, start(track, reader, c, DefaultMaxLag).Wait())
+		assert.Equal(t, []time.Duration{0, 33 * ms, 66 * ms, 100 * ms}, track.written)
+	})
+
+	t.Run("Lag", func(t *testing.T) {
+		c := &fakeClock{}
+		// A stalled write restarts the schedule instead of bursting
+		track := &fakeTrack{clock: c, writeTime: map[int]time.Duration{1: time.Second}}
+		reader := &fakeReader{samples: samplesAt(0, 100*ms, 200*ms, 300*ms), err: io.EOF}
+
+		assert.NoError(t, start(track, reader, c, DefaultMaxLag).Wait())
+		assert.Equal(t, []time.Duration{0, 100 * ms, 1100 * ms, 1200 * ms}, track.written)
+	})
+
+	t.Run("ReaderError", func(t *testing.T) {
+		c := &fakeClock{}
+		errRead := errors.New("read failed")
+		track := &fakeTrack{clock: c}
+		reader := &fakeReader{samples: samplesAt(0), err: errRead}
+
+		assert.Equal(t, errRead, start(track, reader, c, DefaultMaxLag).Wait())
+		assert.Len(t, track.written, 1)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		track := &fakeTrack{clock: &fakeClock{}}
+		reader := &fakeReader{samples: samplesAt(0, time.Hour), err: io.EOF}
+
+		p := Start(track, reader)
+		assert.NoError(t, p.Close())
+		assert.NoError(t, p.Close())
+		assert.NoError(t, p.Wait())
+		assert.True(t, len(track.written) <= 1)
+	})
+}
+
+func TestIVFSampleReader(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := ivfwriter.NewWith(buffer)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		// A frame per packet, the IVF writer stamps 30 frames per second
+		assert.NoError(t, writer.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Marker: true, Timestamp: uint32(3000 * i)},
+			Payload: []byte{0x10, 0xAA, 0xBB, byte(i)},
+		}))
+	}
+	assert.NoError(t, writer.Close())
+
+	ivf, header, err := ivfreader.NewWith(buffer)
+	assert.NoError(t, err)
+	reader, err := NewIVFSampleReader(ivf, header, 90000)
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		s, err := reader.ReadSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xAA, 0xBB, byte(i)}, s.Data)
+		assert.Equal(t, time.Duration(i)*time.Second/30, s.Time)
+		if i != 0 {
+			assert.Equal(t, uint32(3000), s.Samples)
+		}
+	}
+	_, err = reader.ReadSample()
+	assert.Equal(t, io.EOF, err)
+}
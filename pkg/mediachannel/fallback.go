@@ -0,0 +1,119 @@
+package mediachannel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// LocalTrack is the part of a local *webrtc.Track used by a Fallback
+type LocalTrack interface {
+	WriteRTP(p *rtp.Packet) error
+}
+
+// Fallback writes RTP packets to a Track and switches to a Sender once the
+// media doesn't get through. The remote sends receiver reports for the media
+// it gets, so the switch happens when there were none for the report timeout
+// while packets were written. The RTCP of the RTPSender of the track has to
+// be passed to HandleRTCP.
+type Fallback struct {
+	track   LocalTrack
+	sender  *Sender
+	timeout time.Duration
+	now     func() time.Time
+
+	mu         sync.Mutex
+	lastReport time.Time
+	active     bool
+	onActive   func()
+}
+
+// NewFallback creates a Fallback from track to sender. Zero reportTimeout
+// uses DefaultReportTimeout.
+func NewFallback(track LocalTrack, sender *Sender, reportTimeout time.Duration) *Fallback {
+	if reportTimeout == 0 {
+		reportTimeout = DefaultReportTimeout
+	}
+	return &Fallback{
+		track:   track,
+		sender:  sender,
+		timeout: reportTimeout,
+		now:     time.Now,
+	}
+}
+
+// OnActive sets a handler that is called when the Fallback switches to the
+// channel, e.g. to tell the remote application to read from a Receiver
+func (f *Fallback) OnActive(handler func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onActive = handler
+}
+
+// Active tells if packets are written to the channel
+func (f *Fallback) Active() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active
+}
+
+// SetActive switches between the channel and the track, e.g. to try the
+// track again after the network changed
+func (f *Fallback) SetActive(active bool) {
+	f.mu.Lock()
+	f.active = active
+	f.lastReport = f.now()
+	f.mu.Unlock()
+}
+
+// HandleRTCP takes note of the receiver reports of the remote
+func (f *Fallback) HandleRTCP(pkts []rtcp.Packet) {
+	for _, p := range pkts {
+		switch p := p.(type) {
+		case *rtcp.ReceiverReport:
+			f.reportReceived()
+		case *rtcp.SenderReport:
+			if len(p.Reports) != 0 {
+				f.reportReceived()
+			}
+		}
+	}
+}
+
+func (f *Fallback) reportReceived() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastReport = f.now()
+}
+
+// WriteRTP writes a packet to the track, or to the channel once the
+// Fallback is active. Packets the Sender drops aren't reported as errors.
+func (f *Fallback) WriteRTP(p *rtp.Packet) error {
+	now := f.now()
+
+	f.mu.Lock()
+	if f.lastReport.IsZero() {
+		// The timeout starts with the first packet
+		f.lastReport = now
+	}
+	activated := !f.active && now.Sub(f.lastReport) > f.timeout
+	if activated {
+		f.active = true
+	}
+	active := f.active
+	onActive := f.onActive
+	f.mu.Unlock()
+
+	if activated && onActive != nil {
+		onActive()
+	}
+	if !active {
+		return f.track.WriteRTP(p)
+	}
+	if err := f.sender.WriteRTP(p); err != nil && err != ErrQueueFull {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Package mediachannel carries RTP over a DataChannel. It is a fallback for
+// restrictive networks where the DataChannel connects, e.g. over ICE-TCP or
+// TURN-TCP, but media doesn't get through: the call keeps working with a
+// higher latency instead of failing.
+//
+// Every RTP packet is sent as a binary message of its own, a channel carries
+// the packets of one direction. The channel should
+// be unordered and without retransmissions, see ChannelInit, so a lost packet
+// doesn't hold back the ones that follow. A Sender paces the packets to a
+// bitrate and drops packets instead of queuing them without bound, like the
+// network would. A Receiver returns the packets of the channel for a
+// media.Writer or a local Track. Fallback writes to a Track and switches to
+// a Sender once the remote stops sending receiver reports.
+package mediachannel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pion/webrtc/v2"
+)
+
+const (
+	// Label is the label of the DataChannels created with ChannelInit
+	Label = "pion-media"
+
+	// DefaultBitrate is the rate a Sender paces packets to, in bits per second
+	DefaultBitrate = 2000000
+
+	// DefaultQueueSize is the number of packets a Sender queues before it
+	// drops packets
+	DefaultQueueSize = 256
+
+	// MaxBufferedAmount is the buffered amount of the channel above which a
+	// Sender drops packets, the transport of the channel is congested then
+	MaxBufferedAmount = 256 * 1024
+
+	// DefaultReportTimeout is how long a Fallback waits for receiver reports
+	// before it switches to the channel
+	DefaultReportTimeout = 5 * time.Second
+
+	// maxBurst is how far a Sender may fall behind its pace before the time
+	// it lost isn't made up for anymore
+	maxBurst = 20 * time.Millisecond
+)
+
+var (
+	// ErrClosed indicates that the Sender or Receiver was closed, or that
+	// the channel closed
+	ErrClosed = errors.New("mediachannel: closed")
+
+	// ErrQueueFull indicates that a packet was dropped since the Sender
+	// couldn't keep up
+	ErrQueueFull = errors.New("mediachannel: queue full")
+)
+
+// Channel is the part of a *webrtc.DataChannel used to carry RTP
+type Channel interface {
+	Send(data []byte) error
+	OnMessage(f func(msg webrtc.DataChannelMessage))
+	OnClose(f func())
+	BufferedAmount() uint64
+}
+
+// ChannelInit returns the options of a DataChannel for RTP: unordered and
+// without retransmissions
+func ChannelInit() *webrtc.DataChannelInit {
+	ordered := false
+	maxRetransmits := uint16(0)
+	return &webrtc.DataChannelInit{
+		Ordered:        &ordered,
+		MaxRetransmits: &maxRetransmits,
+	}
+}
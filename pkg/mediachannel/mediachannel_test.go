@@ -0,0 +1,112 @@
+package mediachannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/internal/fakechannel"
+	"github.com/stretchr/testify/assert"
+)
+
+func packet(sequenceNumber uint16, size int) *rtp.Packet {
+	return &rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: sequenceNumber, SSRC: 1234},
+		Payload: make([]byte, size),
+	}
+}
+
+func TestSenderReceiver(t *testing.T) {
+	a, b := fakechannel.NewPair()
+	receiver := NewReceiver(b)
+	// 100 bytes of RTP take 10ms at 80 kbit/s
+	sender := NewSender(a, SenderConfig{Bitrate: 80000})
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, sender.WriteRTP(packet(uint16(i), 100-12)))
+	}
+	for i := 0; i < 10; i++ {
+		p, err := receiver.ReadRTP()
+		assert.NoError(t, err)
+		assert.Equal(t, uint16(i), p.SequenceNumber)
+		assert.Len(t, p.Payload, 100-12)
+	}
+	// The first packets are sent in a burst, the rest are paced
+	assert.True(t, time.Since(start) >= 70*time.Millisecond, time.Since(start))
+
+	assert.NoError(t, sender.Close())
+	assert.Equal(t, ErrClosed, sender.WriteRTP(packet(0, 1)))
+
+	// Closing the channel unblocks reads
+	assert.NoError(t, b.Close())
+	_, err := receiver.ReadRTP()
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestSenderQueueFull(t *testing.T) {
+	a, _ := fakechannel.NewPair()
+	sender := NewSender(a, SenderConfig{Bitrate: 8, QueueSize: 1})
+	defer func() {
+		assert.NoError(t, sender.Close())
+	}()
+
+	// The first packet is sent right away and the second waits for seconds
+	// in the queue, so the ones after it are dropped
+	var err error
+	for i := 0; i < 4 && err == nil; i++ {
+		err = sender.WriteRTP(packet(uint16(i), 1))
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, ErrQueueFull, err)
+}
+
+type fakeTrack struct {
+	packets []*rtp.Packet
+}
+
+func (t *fakeTrack) WriteRTP(p *rtp.Packet) error {
+	t.packets = append(t.packets, p)
+	return nil
+}
+
+func TestFallback(t *testing.T) {
+	a, b := fakechannel.NewPair()
+	receiver := NewReceiver(b)
+	sender := NewSender(a, SenderConfig{})
+	track := &fakeTrack{}
+
+	now := time.Now()
+	f := NewFallback(track, sender, time.Second)
+	f.now = func() time.Time { return now }
+	activated := 0
+	f.OnActive(func() { activated++ })
+
+	// Receiver reports keep the media on the track
+	assert.NoError(t, f.WriteRTP(packet(0, 1)))
+	now = now.Add(900 * time.Millisecond)
+	f.HandleRTCP([]rtcp.Packet{&rtcp.ReceiverReport{}})
+	now = now.Add(900 * time.Millisecond)
+	assert.NoError(t, f.WriteRTP(packet(1, 1)))
+	assert.False(t, f.Active())
+	assert.Len(t, track.packets, 2)
+
+	// Without them media switches to the channel
+	now = now.Add(1100 * time.Millisecond)
+	assert.NoError(t, f.WriteRTP(packet(2, 1)))
+	assert.True(t, f.Active())
+	assert.Equal(t, 1, activated)
+	assert.Len(t, track.packets, 2)
+	p, err := receiver.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(2), p.SequenceNumber)
+
+	// And back to the track on request
+	f.SetActive(false)
+	assert.NoError(t, f.WriteRTP(packet(3, 1)))
+	assert.Len(t, track.packets, 3)
+
+	assert.NoError(t, sender.Close())
+	assert.NoError(t, receiver.Close())
+}
@@ -0,0 +1,77 @@
+package mediachannel
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+// Receiver reads the RTP packets a Sender writes to a Channel
+type Receiver struct {
+	packets chan []byte
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewReceiver creates a Receiver of the packets of channel. Packets that
+// aren't read in time are dropped once DefaultQueueSize packets wait.
+func NewReceiver(channel Channel) *Receiver {
+	r := &Receiver{
+		packets: make(chan []byte, DefaultQueueSize),
+		done:    make(chan struct{}),
+	}
+	channel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		if msg.IsString {
+			return
+		}
+		select {
+		case r.packets <- msg.Data:
+		default:
+		}
+	})
+	channel.OnClose(func() {
+		_ = r.Close()
+	})
+	return r
+}
+
+// Read reads a marshaled RTP packet into b
+func (r *Receiver) Read(b []byte) (int, error) {
+	select {
+	case raw := <-r.packets:
+		return copy(b, raw), nil
+	case <-r.done:
+		return 0, ErrClosed
+	}
+}
+
+// ReadRTP reads the next RTP packet. Messages that aren't RTP are skipped.
+func (r *Receiver) ReadRTP() (*rtp.Packet, error) {
+	for {
+		select {
+		case raw := <-r.packets:
+			p := &rtp.Packet{}
+			// pion/rtp reads the fixed header without checking the length
+			if len(raw) < 12 || p.Unmarshal(raw) != nil {
+				continue
+			}
+			return p, nil
+		case <-r.done:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// Close unblocks reads. It doesn't close the channel.
+func (r *Receiver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		r.closed = true
+		close(r.done)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package mediachannel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// SenderConfig configures a Sender
+type SenderConfig struct {
+	// Bitrate the packets are paced to, in bits per second. Zero uses
+	// DefaultBitrate.
+	Bitrate int
+
+	// QueueSize is the number of packets waiting to be sent before packets
+	// are dropped. Zero uses DefaultQueueSize.
+	QueueSize int
+}
+
+// Sender writes RTP packets to a Channel, paced to a bitrate
+type Sender struct {
+	channel Channel
+	bitrate int
+	queue   chan []byte
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewSender creates a Sender that writes to channel until it is closed
+func NewSender(channel Channel, config SenderConfig) *Sender {
+	if config.Bitrate == 0 {
+		config.Bitrate = DefaultBitrate
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+
+	s := &Sender{
+		channel: channel,
+		bitrate: config.Bitrate,
+		queue:   make(chan []byte, config.QueueSize),
+		done:    make(chan struct{}),
+	}
+	channel.OnClose(func() {
+		_ = s.Close()
+	})
+	go s.run()
+	return s
+}
+
+// WriteRTP queues a packet. It returns ErrQueueFull if the packet was
+// dropped, which the caller can ignore like loss on the network.
+func (s *Sender) WriteRTP(p *rtp.Packet) error {
+	raw, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+	return s.enqueue(raw)
+}
+
+// Write queues a marshaled RTP packet, see WriteRTP
+func (s *Sender) Write(b []byte) (int, error) {
+	if err := s.enqueue(append([]byte{}, b...)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (s *Sender) enqueue(raw []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	select {
+	case s.queue <- raw:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops sending, queued packets are dropped. It doesn't close the
+// channel.
+func (s *Sender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	return nil
+}
+
+// run sends the queued packets, every packet is due once the previous ones
+// were sent at the bitrate
+func (s *Sender) run() {
+	next := time.Now()
+	for {
+		var raw []byte
+		select {
+		case raw = <-s.queue:
+		case <-s.done:
+			return
+		}
+
+		now := time.Now()
+		if wait := next.Sub(now); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-s.done:
+				return
+			}
+		} else if -wait > maxBurst {
+			next = now.Add(-maxBurst)
+		}
+		next = next.Add(time.Duration(len(raw)*8) * time.Second / time.Duration(s.bitrate))
+
+		// The transport of the channel is congested, queuing more only
+		// adds latency
+		if s.channel.BufferedAmount() > MaxBufferedAmount {
+			continue
+		}
+		if err := s.channel.Send(raw); err != nil {
+			_ = s.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// +build !js
+
+package mixer
+
+import "math"
+
+const (
+	// minGain and maxGain bound the gain applied to a participant, quiet
+	// microphones aren't amplified into noise
+	minGain = 0.25
+	maxGain = 4
+
+	// gainSmoothing is the fraction of the distance to its target the gain
+	// moves per frame, so levels change over a few hundred milliseconds
+	// instead of pumping with every syllable
+	gainSmoothing = 0.1
+
+	// silenceLevel is the RMS level, as a fraction of full scale, below
+	// which a frame is silence and doesn't change the gain
+	silenceLevel = 0.005
+)
+
+// levelFrame applies the automatic gain control to frame in place. gain is
+// the gain of the participant so far, the one for its next frame is
+// returned.
+func levelFrame(frame []int16, gain, targetLevel float64) float64 {
+	if level := rms(frame); level >= silenceLevel {
+		target := math.Min(math.Max(targetLevel/level, minGain), maxGain)
+		gain += (target - gain) * gainSmoothing
+	}
+
+	for i, v := range frame {
+		frame[i] = clip(int32(math.Round(float64(v) * gain)))
+	}
+	return gain
+}
+
+// rms returns the root mean square of frame as a fraction of full scale
+func rms(frame []int16) float64 {
+	if len(frame) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range frame {
+		s := float64(v) / 32768
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(len(frame)))
+}
@@ -0,0 +1,288 @@
+// +build !js
+
+// Package mixer mixes the audio of the participants of a conference on the
+// server, for MCU-style audio bridges that send every participant a single
+// track instead of forwarding the tracks of all the others.
+//
+// The audio of every participant is decoded, leveled by a simple automatic
+// gain control and mixed every frame. Each participant receives the mix of
+// everyone but itself, encoded for its own output track. The mixer doesn't
+// depend on an Opus implementation, Config.NewDecoder and Config.NewEncoder
+// plug in the codec of the application.
+//
+// The Mixer doesn't do signaling either. The application adds the output
+// track of a participant to its PeerConnection and passes the remote track
+// the participant sends to SetInput.
+package mixer
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+const (
+	// DefaultSampleRate is the sample rate of the PCM the mixer works with
+	DefaultSampleRate = 48000
+
+	// DefaultFrameDuration is how much audio is mixed at a time
+	DefaultFrameDuration = 20 * time.Millisecond
+
+	// DefaultTargetLevel is the RMS level, as a fraction of full scale, the
+	// automatic gain control levels the participants to
+	DefaultTargetLevel = 0.1
+
+	// maxQueuedFrames is how many decoded frames of a participant are kept
+	// until they are mixed, older ones are dropped
+	maxQueuedFrames = 5
+)
+
+var (
+	// ErrCodecRequired indicates that New was called without NewDecoder or
+	// NewEncoder
+	ErrCodecRequired = errors.New("mixer: decoder and encoder are required")
+
+	// ErrParticipantExists indicates that a participant joined with the ID
+	// of a participant that is already in the mixer
+	ErrParticipantExists = errors.New("mixer: participant already exists")
+
+	// ErrMixerClosed indicates that a participant joined a closed mixer
+	ErrMixerClosed = errors.New("mixer: closed")
+)
+
+// Decoder decodes the audio a participant sends
+type Decoder interface {
+	// Decode returns the interleaved PCM of an RTP payload
+	Decode(payload []byte) ([]int16, error)
+}
+
+// Encoder encodes the mix a participant receives
+type Encoder interface {
+	// Encode returns the payload of a sample of interleaved PCM
+	Encode(pcm []int16) ([]byte, error)
+}
+
+// Config configures a Mixer
+type Config struct {
+	// NewDecoder and NewEncoder create the codec of a participant, e.g.
+	// bindings of libopus. Every participant gets codecs of its own since
+	// they keep state between frames.
+	NewDecoder func() (Decoder, error)
+	NewEncoder func() (Encoder, error)
+
+	// SampleRate and Channels describe the PCM the codecs exchange with the
+	// mixer. Zero uses DefaultSampleRate and mono.
+	SampleRate int
+	Channels   int
+
+	// FrameDuration is how much audio is mixed and encoded at a time. Zero
+	// uses DefaultFrameDuration.
+	FrameDuration time.Duration
+
+	// TargetLevel is the RMS level, as a fraction of full scale, the gain
+	// control levels the participants to. Zero uses DefaultTargetLevel.
+	TargetLevel float64
+
+	// DisableGainControl mixes the audio of the participants as it is sent
+	DisableGainControl bool
+
+	// LoggerFactory creates the logger of the mixer, the default one if nil
+	LoggerFactory logging.LoggerFactory
+}
+
+// Mixer mixes the audio of its participants and sends each of them the mix
+// of the others
+type Mixer struct {
+	config Config
+	log    logging.LeveledLogger
+
+	// frameSize is the number of values of a frame of PCM, samples times
+	// channels
+	frameSize int
+
+	mu           sync.Mutex
+	participants map[string]*Participant
+	closed       bool
+
+	done chan struct{}
+}
+
+// New creates a Mixer and starts mixing
+func New(config Config) (*Mixer, error) {
+	m, err := newMixer(config)
+	if err != nil {
+		return nil, err
+	}
+	go m.run()
+	return m, nil
+}
+
+func newMixer(config Config) (*Mixer, error) {
+	if config.NewDecoder == nil || config.NewEncoder == nil {
+		return nil, ErrCodecRequired
+	}
+	if config.SampleRate == 0 {
+		config.SampleRate = DefaultSampleRate
+	}
+	if config.Channels == 0 {
+		config.Channels = 1
+	}
+	if config.FrameDuration == 0 {
+		config.FrameDuration = DefaultFrameDuration
+	}
+	if config.TargetLevel == 0 {
+		config.TargetLevel = DefaultTargetLevel
+	}
+	loggerFactory := config.LoggerFactory
+	if loggerFactory == nil {
+		loggerFactory = logging.NewDefaultLoggerFactory()
+	}
+
+	return &Mixer{
+		config:       config,
+		log:          loggerFactory.NewLogger("mixer"),
+		frameSize:    int(int64(config.SampleRate)*int64(config.FrameDuration)/int64(time.Second)) * config.Channels,
+		participants: map[string]*Participant{},
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// Join adds a participant to the mixer. The mix of the other participants
+// is written to output, a local Opus track the application sends to the
+// participant. A nil output makes a participant that only speaks.
+func (m *Mixer) Join(id string, output *webrtc.Track) (*Participant, error) {
+	p := &Participant{id: id, mixer: m, output: output, gain: 1}
+	if output != nil {
+		encoder, err := m.config.NewEncoder()
+		if err != nil {
+			return nil, err
+		}
+		p.encoder = encoder
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case m.closed:
+		return nil, ErrMixerClosed
+	case m.participants[id] != nil:
+		return nil, ErrParticipantExists
+	}
+	m.participants[id] = p
+	return p, nil
+}
+
+// Participant returns the participant with the given ID, or nil
+func (m *Mixer) Participant(id string) *Participant {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.participants[id]
+}
+
+// Close stops mixing and removes every participant
+func (m *Mixer) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	participants := m.participants
+	m.participants = map[string]*Participant{}
+	m.mu.Unlock()
+
+	close(m.done)
+	for _, p := range participants {
+		p.stopInput()
+	}
+	return nil
+}
+
+func (m *Mixer) run() {
+	ticker := time.NewTicker(m.config.FrameDuration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.mix()
+		}
+	}
+}
+
+// mix mixes the next frame of every participant and writes the mixes to
+// the outputs
+func (m *Mixer) mix() {
+	m.mu.Lock()
+	participants := make([]*Participant, 0, len(m.participants))
+	for _, p := range m.participants {
+		participants = append(participants, p)
+	}
+	m.mu.Unlock()
+
+	total := make([]int32, m.frameSize)
+	contributions := make(map[*Participant][]int16, len(participants))
+	for _, p := range participants {
+		frame := p.nextFrame()
+		if frame == nil {
+			continue
+		}
+		if !m.config.DisableGainControl {
+			p.gain = levelFrame(frame, p.gain, m.config.TargetLevel)
+		}
+		contributions[p] = frame
+		for i, v := range frame {
+			total[i] += int32(v)
+		}
+	}
+
+	mix := make([]int16, m.frameSize)
+	for _, p := range participants {
+		if p.output == nil {
+			continue
+		}
+		own := contributions[p]
+		for i, v := range total {
+			if own != nil {
+				v -= int32(own[i])
+			}
+			mix[i] = clip(v)
+		}
+		if err := p.write(mix, m.config.FrameDuration); err != nil {
+			m.log.Debugf("failed to write the mix of %s: %v", p.id, err)
+		}
+	}
+}
+
+func (m *Mixer) removeParticipant(p *Participant) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.participants[p.id] == p {
+		delete(m.participants, p.id)
+	}
+}
+
+// clip saturates a mixed value to the range of 16 bit PCM
+func clip(v int32) int16 {
+	switch {
+	case v > 32767:
+		return 32767
+	case v < -32768:
+		return -32768
+	default:
+		return int16(v)
+	}
+}
+
+// sample returns the media.Sample of an encoded frame of output
+func sample(output *webrtc.Track, data []byte, duration time.Duration) media.Sample {
+	return media.Sample{
+		Data:    data,
+		Samples: uint32(int64(output.Codec().ClockRate) * int64(duration) / int64(time.Second)),
+	}
+}
@@ -0,0 +1,171 @@
+// +build !js
+
+package mixer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// pcmCodec passes the PCM through, the mixes it encoded are recorded
+type pcmCodec struct {
+	mu    sync.Mutex
+	mixes [][]int16
+}
+
+func (c *pcmCodec) Decode(payload []byte) ([]int16, error) {
+	pcm := make([]int16, len(payload))
+	for i, b := range payload {
+		pcm[i] = int16(b)
+	}
+	return pcm, nil
+}
+
+func (c *pcmCodec) Encode(pcm []int16) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mixes = append(c.mixes, append([]int16{}, pcm...))
+	return []byte{0x00}, nil
+}
+
+func (c *pcmCodec) lastMix() []int16 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.mixes) == 0 {
+		return nil
+	}
+	return c.mixes[len(c.mixes)-1]
+}
+
+func newTestMixer(t *testing.T) *Mixer {
+	m, err := newMixer(Config{
+		NewDecoder:         func() (Decoder, error) { return &pcmCodec{}, nil },
+		NewEncoder:         func() (Encoder, error) { return &pcmCodec{}, nil },
+		SampleRate:         4000,
+		FrameDuration:      time.Millisecond,
+		DisableGainControl: true,
+	})
+	assert.NoError(t, err)
+	return m
+}
+
+func newOpusTrack(t *testing.T, id string) *webrtc.Track {
+	track, err := webrtc.NewTrack(webrtc.DefaultPayloadTypeOpus, 1234, id, "pion", webrtc.NewRTPOpusCodec(webrtc.DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+	return track
+}
+
+// join adds a participant whose input is set to a track that isn't read
+func join(t *testing.T, m *Mixer, id string) *Participant {
+	p, err := m.Join(id, newOpusTrack(t, id))
+	assert.NoError(t, err)
+	p.input = newOpusTrack(t, id+"-input")
+	return p
+}
+
+func frame(v int16) []int16 {
+	return []int16{v, v, v, v}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{})
+	assert.Equal(t, ErrCodecRequired, err)
+
+	m, err := New(Config{
+		NewDecoder: func() (Decoder, error) { return &pcmCodec{}, nil },
+		NewEncoder: func() (Encoder, error) { return &pcmCodec{}, nil },
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 960, m.frameSize)
+
+	p, err := m.Join("a", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, p, m.Participant("a"))
+	_, err = m.Join("a", nil)
+	assert.Equal(t, ErrParticipantExists, err)
+
+	p.Leave()
+	assert.Nil(t, m.Participant("a"))
+
+	assert.NoError(t, m.Close())
+	_, err = m.Join("b", nil)
+	assert.Equal(t, ErrMixerClosed, err)
+}
+
+func TestMixer_Mix(t *testing.T) {
+	m := newTestMixer(t)
+	a, b, c := join(t, m, "a"), join(t, m, "b"), join(t, m, "c")
+
+	// Every participant hears everyone but itself, c only listens
+	assert.True(t, a.push(a.input, frame(100)))
+	assert.True(t, b.push(b.input, frame(10)))
+	m.mix()
+	assert.Equal(t, frame(10), a.encoder.(*pcmCodec).lastMix())
+	assert.Equal(t, frame(100), b.encoder.(*pcmCodec).lastMix())
+	assert.Equal(t, frame(110), c.encoder.(*pcmCodec).lastMix())
+
+	// Mixes are clipped
+	assert.True(t, a.push(a.input, frame(30000)))
+	assert.True(t, b.push(b.input, frame(30000)))
+	m.mix()
+	assert.Equal(t, frame(32767), c.encoder.(*pcmCodec).lastMix())
+
+	// Silence is sent while nobody speaks
+	m.mix()
+	assert.Equal(t, frame(0), c.encoder.(*pcmCodec).lastMix())
+
+	// Frames of a previous input are dropped
+	assert.False(t, a.push(newOpusTrack(t, "old"), frame(100)))
+	input := b.input
+	b.Leave()
+	assert.False(t, b.push(input, frame(100)))
+
+	assert.NoError(t, m.Close())
+}
+
+func TestParticipant_Queue(t *testing.T) {
+	m := newTestMixer(t)
+	p := join(t, m, "a")
+
+	// Short frames are padded with silence
+	assert.True(t, p.push(p.input, []int16{1, 2}))
+	assert.Equal(t, []int16{1, 2, 0, 0}, p.nextFrame())
+	assert.Nil(t, p.nextFrame())
+
+	// The oldest frames are dropped when the mixer falls behind
+	for i := 0; i < maxQueuedFrames+2; i++ {
+		assert.True(t, p.push(p.input, frame(int16(i))))
+	}
+	assert.Equal(t, frame(2), p.nextFrame())
+
+	assert.NoError(t, m.Close())
+}
+
+func TestLevelFrame(t *testing.T) {
+	// Loud participants are turned down, quiet ones up
+	gain := 1.0
+	for i := 0; i < 100; i++ {
+		gain = levelFrame(frame(16384), gain, DefaultTargetLevel)
+	}
+	assert.InDelta(t, minGain, gain, 0.01)
+
+	gain = 1.0
+	for i := 0; i < 100; i++ {
+		gain = levelFrame(frame(1000), gain, DefaultTargetLevel)
+	}
+	assert.InDelta(t, DefaultTargetLevel/(1000.0/32768), gain, 0.01)
+
+	// Silence doesn't change the gain
+	f := frame(10)
+	assert.Equal(t, 2.0, levelFrame(f, 2, DefaultTargetLevel))
+	assert.Equal(t, frame(20), f)
+}
+
+func TestSample(t *testing.T) {
+	s := sample(newOpusTrack(t, "a"), []byte{0x00}, DefaultFrameDuration)
+	assert.Equal(t, uint32(960), s.Samples)
+}
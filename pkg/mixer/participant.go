@@ -0,0 +1,129 @@
+// +build !js
+
+package mixer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// Participant is a peer of a Mixer, its audio is mixed into the output of
+// every other participant
+type Participant struct {
+	id      string
+	mixer   *Mixer
+	output  *webrtc.Track
+	encoder Encoder
+
+	// gain the automatic gain control applies to the audio of the
+	// participant, only used by the mixer
+	gain float64
+
+	mu     sync.Mutex
+	input  *webrtc.Track
+	frames [][]int16
+}
+
+// ID returns the ID the participant joined with
+func (p *Participant) ID() string {
+	return p.id
+}
+
+// Output returns the track the mix of the other participants is written to
+func (p *Participant) Output() *webrtc.Track {
+	return p.output
+}
+
+// SetInput mixes the audio of track, the remote track of the participant's
+// PeerConnection, into the outputs of the others. The track is read until
+// it ends or SetInput is called again, its packets are decoded with a
+// decoder of Config.NewDecoder.
+func (p *Participant) SetInput(track *webrtc.Track) error {
+	decoder, err := p.mixer.config.NewDecoder()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.input = track
+	p.frames = nil
+	p.mu.Unlock()
+
+	go p.readInput(track, decoder)
+	return nil
+}
+
+// Leave removes the participant from the mixer, its input isn't read anymore
+func (p *Participant) Leave() {
+	p.mixer.removeParticipant(p)
+	p.stopInput()
+}
+
+func (p *Participant) stopInput() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.input = nil
+	p.frames = nil
+}
+
+func (p *Participant) readInput(track *webrtc.Track, decoder Decoder) {
+	for {
+		pkt, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+		if len(pkt.Payload) == 0 {
+			continue
+		}
+
+		pcm, err := decoder.Decode(pkt.Payload)
+		if err != nil {
+			p.mixer.log.Debugf("failed to decode the audio of %s: %v", p.id, err)
+			continue
+		}
+		if !p.push(track, pcm) {
+			return
+		}
+	}
+}
+
+// push queues a decoded frame of track to be mixed, it returns false once
+// track isn't the input of the participant anymore
+func (p *Participant) push(track *webrtc.Track, pcm []int16) bool {
+	frame := make([]int16, p.mixer.frameSize)
+	copy(frame, pcm)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.input == nil || p.input != track {
+		return false
+	}
+	if len(p.frames) == maxQueuedFrames {
+		p.frames = p.frames[1:]
+	}
+	p.frames = append(p.frames, frame)
+	return true
+}
+
+// nextFrame returns the oldest queued frame, or nil if there is none
+func (p *Participant) nextFrame() []int16 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.frames) == 0 {
+		return nil
+	}
+	frame := p.frames[0]
+	p.frames = p.frames[1:]
+	return frame
+}
+
+// write encodes a mix and writes it to the output
+func (p *Participant) write(mix []int16, duration time.Duration) error {
+	data, err := p.encoder.Encode(mix)
+	if err != nil {
+		return err
+	}
+	return p.output.WriteSample(sample(p.output, data, duration))
+}
@@ -0,0 +1,48 @@
+// +build !js
+
+package mobile
+
+import (
+	"github.com/pion/webrtc/v2"
+)
+
+// DataChannelObserver receives the events of a DataChannel
+type DataChannelObserver interface {
+	OnOpen()
+	OnClose()
+	OnMessage(data []byte, isString bool)
+}
+
+// DataChannel wraps a webrtc.DataChannel
+type DataChannel struct {
+	dc *webrtc.DataChannel
+}
+
+// SetObserver registers the observer for the events of the DataChannel
+func (d *DataChannel) SetObserver(observer DataChannelObserver) {
+	d.dc.OnOpen(observer.OnOpen)
+	d.dc.OnClose(observer.OnClose)
+	d.dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		observer.OnMessage(msg.Data, msg.IsString)
+	})
+}
+
+// Label returns the label of the DataChannel
+func (d *DataChannel) Label() string {
+	return d.dc.Label()
+}
+
+// Send sends binary data
+func (d *DataChannel) Send(data []byte) error {
+	return d.dc.Send(data)
+}
+
+// SendText sends a text message
+func (d *DataChannel) SendText(text string) error {
+	return d.dc.SendText(text)
+}
+
+// Close closes the DataChannel
+func (d *DataChannel) Close() error {
+	return d.dc.Close()
+}
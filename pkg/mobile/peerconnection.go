@@ -0,0 +1,174 @@
+// +build !js
+
+// Package mobile is a facade of the webrtc package that can be consumed from
+// Android and iOS via gomobile bind. Exported signatures only use types that
+// gomobile supports: callbacks are interfaces, session descriptions and ICE
+// candidates are passed as the JSON used by the browser API.
+package mobile
+
+import (
+	"encoding/json"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// PeerConnectionObserver receives the events of a PeerConnection. The methods
+// are called from goroutines of the PeerConnection and must not block.
+type PeerConnectionObserver interface {
+	// OnICECandidate is called with the JSON of a local ICECandidateInit, or
+	// an empty string once gathering is complete.
+	OnICECandidate(candidate string)
+	OnICEConnectionStateChange(state string)
+	OnDataChannel(dc *DataChannel)
+	OnTrack(track *RemoteTrack)
+}
+
+// PeerConnection wraps a webrtc.PeerConnection
+type PeerConnection struct {
+	pc          *webrtc.PeerConnection
+	mediaEngine *webrtc.MediaEngine
+}
+
+// NewPeerConnection creates a PeerConnection. configuration is the JSON of a
+// webrtc.Configuration and may be empty, observer may be nil.
+func NewPeerConnection(configuration string, observer PeerConnectionObserver) (*PeerConnection, error) {
+	config := webrtc.Configuration{}
+	if configuration != "" {
+		if err := json.Unmarshal([]byte(configuration), &config); err != nil {
+			return nil, err
+		}
+	}
+
+	m := &webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(*m)).NewPeerConnection(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if observer != nil {
+		pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+			if c == nil {
+				observer.OnICECandidate("")
+				return
+			}
+			candidate, err := json.Marshal(c.ToJSON())
+			if err != nil {
+				return
+			}
+			observer.OnICECandidate(string(candidate))
+		})
+		pc.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+			observer.OnICEConnectionStateChange(state.String())
+		})
+		pc.OnDataChannel(func(d *webrtc.DataChannel) {
+			observer.OnDataChannel(&DataChannel{dc: d})
+		})
+		pc.OnTrack(func(t *webrtc.Track, r *webrtc.RTPReceiver) {
+			observer.OnTrack(&RemoteTrack{track: t})
+		})
+	}
+
+	return &PeerConnection{pc: pc, mediaEngine: m}, nil
+}
+
+// CreateOffer returns the JSON of an offer
+func (p *PeerConnection) CreateOffer() (string, error) {
+	offer, err := p.pc.CreateOffer(nil)
+	if err != nil {
+		return "", err
+	}
+	return marshalDescription(offer)
+}
+
+// CreateAnswer returns the JSON of an answer
+func (p *PeerConnection) CreateAnswer() (string, error) {
+	answer, err := p.pc.CreateAnswer(nil)
+	if err != nil {
+		return "", err
+	}
+	return marshalDescription(answer)
+}
+
+// SetLocalDescription takes the JSON of a session description
+func (p *PeerConnection) SetLocalDescription(description string) error {
+	desc, err := unmarshalDescription(description)
+	if err != nil {
+		return err
+	}
+	return p.pc.SetLocalDescription(desc)
+}
+
+// SetRemoteDescription takes the JSON of a session description
+func (p *PeerConnection) SetRemoteDescription(description string) error {
+	desc, err := unmarshalDescription(description)
+	if err != nil {
+		return err
+	}
+	return p.pc.SetRemoteDescription(desc)
+}
+
+// LocalDescription returns the JSON of the local description, or an empty
+// string if it is not set
+func (p *PeerConnection) LocalDescription() (string, error) {
+	desc := p.pc.LocalDescription()
+	if desc == nil {
+		return "", nil
+	}
+	return marshalDescription(*desc)
+}
+
+// AddICECandidate takes the JSON of an ICECandidateInit
+func (p *PeerConnection) AddICECandidate(candidate string) error {
+	c := webrtc.ICECandidateInit{}
+	if err := json.Unmarshal([]byte(candidate), &c); err != nil {
+		return err
+	}
+	return p.pc.AddICECandidate(c)
+}
+
+// CreateDataChannel creates a reliable, ordered DataChannel
+func (p *PeerConnection) CreateDataChannel(label string) (*DataChannel, error) {
+	d, err := p.pc.CreateDataChannel(label, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &DataChannel{dc: d}, nil
+}
+
+// AddTrack creates a LocalTrack with the codec of the given name, e.g. "opus"
+// or "VP8", and adds it to the PeerConnection
+func (p *PeerConnection) AddTrack(codecName, id, label string) (*LocalTrack, error) {
+	track, err := p.newLocalTrack(codecName, id, label)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.pc.AddTrack(track.track); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// ConnectionState returns the connection state, e.g. "connected"
+func (p *PeerConnection) ConnectionState() string {
+	return p.pc.ConnectionState().String()
+}
+
+// Close ends the PeerConnection
+func (p *PeerConnection) Close() error {
+	return p.pc.Close()
+}
+
+func marshalDescription(desc webrtc.SessionDescription) (string, error) {
+	b, err := json.Marshal(desc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func unmarshalDescription(description string) (webrtc.SessionDescription, error) {
+	desc := webrtc.SessionDescription{}
+	err := json.Unmarshal([]byte(description), &desc)
+	return desc, err
+}
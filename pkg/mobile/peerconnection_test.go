@@ -0,0 +1,92 @@
+// +build !js
+
+package mobile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPeerConnectionObserver struct {
+	dataChannels chan *DataChannel
+}
+
+func (o *testPeerConnectionObserver) OnICECandidate(string)             {}
+func (o *testPeerConnectionObserver) OnICEConnectionStateChange(string) {}
+func (o *testPeerConnectionObserver) OnTrack(*RemoteTrack)              {}
+func (o *testPeerConnectionObserver) OnDataChannel(dc *DataChannel) {
+	o.dataChannels <- dc
+}
+
+type testDataChannelObserver struct {
+	opened   chan struct{}
+	messages chan string
+}
+
+func (o *testDataChannelObserver) OnOpen()  { close(o.opened) }
+func (o *testDataChannelObserver) OnClose() {}
+func (o *testDataChannelObserver) OnMessage(data []byte, isString bool) {
+	if isString {
+		o.messages <- string(data)
+	}
+}
+
+func TestPeerConnection(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerer, err := NewPeerConnection("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answererObserver := &testPeerConnectionObserver{dataChannels: make(chan *DataChannel, 1)}
+	answerer, err := NewPeerConnection(`{"iceServers": []}`, answererObserver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := offerer.CreateDataChannel("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dcObserver := &testDataChannelObserver{opened: make(chan struct{}), messages: make(chan string, 1)}
+	dc.SetObserver(dcObserver)
+
+	offer, err := offerer.CreateOffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, offerer.SetLocalDescription(offer))
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+
+	answer, err := answerer.CreateAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, answerer.SetLocalDescription(answer))
+	assert.NoError(t, offerer.SetRemoteDescription(answer))
+
+	<-dcObserver.opened
+	remoteDC := <-answererObserver.dataChannels
+	assert.Equal(t, "data", remoteDC.Label())
+	// OnDataChannel is called before the DataChannel is open, sending has to
+	// wait for OnOpen
+	remoteObserver := &testDataChannelObserver{opened: make(chan struct{}), messages: make(chan string)}
+	remoteDC.SetObserver(remoteObserver)
+	<-remoteObserver.opened
+
+	assert.NoError(t, remoteDC.SendText("hello"))
+	assert.Equal(t, "hello", <-dcObserver.messages)
+
+	_, err = offerer.AddTrack("unknown", "id", "label")
+	assert.Error(t, err)
+
+	assert.NoError(t, offerer.Close())
+	assert.NoError(t, answerer.Close())
+}
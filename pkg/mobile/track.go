@@ -0,0 +1,64 @@
+// +build !js
+
+package mobile
+
+import (
+	"fmt"
+
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// LocalTrack is a track media is written to
+type LocalTrack struct {
+	track *webrtc.Track
+}
+
+func (p *PeerConnection) newLocalTrack(codecName, id, label string) (*LocalTrack, error) {
+	codecs := p.mediaEngine.GetCodecsByName(codecName)
+	if len(codecs) == 0 {
+		return nil, fmt.Errorf("no %s codec registered", codecName)
+	}
+
+	track, err := p.pc.NewTrack(codecs[0].PayloadType, 0, id, label)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalTrack{track: track}, nil
+}
+
+// WriteSample packetizes and sends a frame. samples is the duration of the
+// frame in units of the codec clock rate.
+func (t *LocalTrack) WriteSample(data []byte, samples int) error {
+	return t.track.WriteSample(media.Sample{Data: data, Samples: uint32(samples)})
+}
+
+// RemoteTrack is a track received from the remote peer
+type RemoteTrack struct {
+	track *webrtc.Track
+}
+
+// ID returns the id of the track
+func (t *RemoteTrack) ID() string {
+	return t.track.ID()
+}
+
+// Kind returns "audio" or "video"
+func (t *RemoteTrack) Kind() string {
+	return t.track.Kind().String()
+}
+
+// Codec returns the name of the codec, e.g. "opus"
+func (t *RemoteTrack) Codec() string {
+	return t.track.Codec().Name
+}
+
+// ReadRTP blocks until the next RTP packet is received and returns it
+func (t *RemoteTrack) ReadRTP() ([]byte, error) {
+	b := make([]byte, 1460)
+	n, err := t.track.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	return b[:n], nil
+}
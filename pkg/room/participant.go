@@ -0,0 +1,421 @@
+// +build !js
+
+package room
+
+import (
+	"sync"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// Participant is a peer in a Room, it publishes the tracks its
+// PeerConnection receives and is subscribed to the tracks of the others
+type Participant struct {
+	id   string
+	room *Room
+	pc   *webrtc.PeerConnection
+
+	mu                  sync.Mutex
+	published           []*PublishedTrack
+	subscriptions       map[*PublishedTrack]*Subscription
+	audioOnly           bool
+	onNegotiationNeeded func()
+	onOffer             func(webrtc.SessionDescription)
+	left                bool
+
+	// negotiating is true while an offer of the room waits for its answer,
+	// changes meanwhile set pendingNegotiation and are offered afterwards
+	negotiating        bool
+	pendingNegotiation bool
+
+	// updates counts the calls of Update in progress, changes meanwhile set
+	// updateNegotiation and are negotiated once the last one returns
+	updates           int
+	updateNegotiation bool
+}
+
+// ID returns the ID the participant joined with
+func (p *Participant) ID() string {
+	return p.id
+}
+
+// PeerConnection returns the PeerConnection of the participant, which the
+// application negotiates with the remote peer
+func (p *Participant) PeerConnection() *webrtc.PeerConnection {
+	return p.pc
+}
+
+// OnNegotiationNeeded sets a handler that is called when subscriptions were
+// added or removed after the participant joined. The PeerConnection has to
+// be renegotiated for the subscriber to receive or stop receiving them.
+// It isn't called if an OnOffer handler is set.
+func (p *Participant) OnNegotiationNeeded(f func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onNegotiationNeeded = f
+}
+
+// OnOffer sets a handler that makes the room renegotiate by itself when
+// subscriptions change. The handler gets the offer, which is already set as
+// local description, and has to send it to the remote peer. Its answer is
+// applied with SetAnswer. Changes while an offer is out are collected in the
+// next offer.
+func (p *Participant) OnOffer(f func(offer webrtc.SessionDescription)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onOffer = f
+}
+
+// SetAnswer applies the answer of the remote peer to an offer passed to the
+// OnOffer handler
+func (p *Participant) SetAnswer(answer webrtc.SessionDescription) error {
+	if err := p.pc.SetRemoteDescription(answer); err != nil {
+		return err
+	}
+	p.negotiated()
+	return nil
+}
+
+// Subscribe forwards a track of another participant to this one, the
+// subscription is returned if it exists already. Subscribe is only needed
+// with Config.ManualSubscription or for video in audio only mode, otherwise
+// participants are subscribed to every track.
+func (p *Participant) Subscribe(t *PublishedTrack) (*Subscription, error) {
+	if t.publisher == p {
+		return nil, ErrOwnTrack
+	}
+	p.mu.Lock()
+	s := p.subscriptions[t]
+	p.mu.Unlock()
+	if s != nil {
+		return s, nil
+	}
+
+	s, err := t.subscribe(p)
+	if err != nil {
+		return nil, err
+	}
+	p.negotiationNeeded()
+	return s, nil
+}
+
+// Update makes the subscription changes of f, e.g. calls of Subscribe,
+// Unsubscribe and SetAudioOnly, and negotiates them together once f returns.
+// It avoids an offer or OnNegotiationNeeded call for every change, e.g. when
+// a participant subscribes to the tracks of many publishers at once. The
+// changes made before f returns an error or panics are negotiated as well.
+//
+// Only the room batches changes. A PeerConnection doesn't renegotiate by
+// itself, tracks added to it directly are negotiated together with the next
+// CreateOffer of the application.
+func (p *Participant) Update(f func() error) error {
+	p.mu.Lock()
+	p.updates++
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.updates--
+		needed := p.updates == 0 && p.updateNegotiation
+		if needed {
+			p.updateNegotiation = false
+		}
+		p.mu.Unlock()
+		if needed {
+			p.negotiationNeeded()
+		}
+	}()
+	return f()
+}
+
+// Unsubscribe stops forwarding a track to the participant
+func (p *Participant) Unsubscribe(t *PublishedTrack) error {
+	s := p.removeSubscription(t)
+	if s == nil {
+		return ErrNotSubscribed
+	}
+	t.removeSubscription(s)
+	s.stop()
+	p.negotiationNeeded()
+	return nil
+}
+
+// SetAudioOnly switches the audio only mode of the participant. In audio only
+// mode the participant is unsubscribed from all video and not subscribed to
+// video published later, Subscribe still adds video on request. Switching
+// back subscribes the participant to the video of the room again, unless the
+// room subscribes manually.
+func (p *Participant) SetAudioOnly(audioOnly bool) {
+	p.mu.Lock()
+	if p.audioOnly == audioOnly || p.left {
+		p.mu.Unlock()
+		return
+	}
+	p.audioOnly = audioOnly
+	var unsubscribe []*Subscription
+	if audioOnly {
+		for t, s := range p.subscriptions {
+			if t.Kind() == webrtc.RTPCodecTypeVideo {
+				unsubscribe = append(unsubscribe, s)
+				delete(p.subscriptions, t)
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	changed := false
+	for _, s := range unsubscribe {
+		s.track.removeSubscription(s)
+		s.stop()
+		changed = true
+	}
+	if !audioOnly {
+		for _, t := range p.room.Tracks() {
+			if !p.autoSubscribes(t) {
+				continue
+			}
+			p.mu.Lock()
+			subscribed := p.subscriptions[t] != nil
+			p.mu.Unlock()
+			if subscribed {
+				continue
+			}
+			if _, err := t.subscribe(p); err != nil {
+				p.room.log.Warnf("failed to subscribe %s to %s: %v", p.id, t.ID(), err)
+				continue
+			}
+			changed = true
+		}
+	}
+	if changed {
+		p.negotiationNeeded()
+	}
+}
+
+// AudioOnly tells if the participant is in audio only mode
+func (p *Participant) AudioOnly() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.audioOnly
+}
+
+// Answer applies an offer of the remote peer and returns the answer, which
+// is set as local description. A receiving transceiver is added for every
+// section the remote sends in, a PeerConnection only receives with
+// transceivers of its own.
+func (p *Participant) Answer(offer webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	params, err := webrtc.NewSignalingParameters(offer)
+	if err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+
+	negotiated := map[string]bool{}
+	for _, t := range p.pc.GetTransceivers() {
+		negotiated[t.Mid()] = true
+	}
+	for _, media := range params.Media {
+		sends := media.Direction == webrtc.RTPTransceiverDirectionSendrecv || media.Direction == webrtc.RTPTransceiverDirectionSendonly
+		if !sends || negotiated[media.Mid] {
+			continue
+		}
+		if _, err = p.pc.AddTransceiverFromKind(media.Kind, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}); err != nil {
+			return webrtc.SessionDescription{}, err
+		}
+	}
+
+	if err = p.pc.SetRemoteDescription(offer); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	answer, err := p.pc.CreateAnswer(nil)
+	if err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	if err = p.pc.SetLocalDescription(answer); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	// Subscriptions the offer had no room for are offered next
+	p.negotiated()
+	return answer, nil
+}
+
+// Published returns the tracks the participant publishes
+func (p *Participant) Published() []*PublishedTrack {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*PublishedTrack{}, p.published...)
+}
+
+// Subscriptions returns the tracks the participant is subscribed to
+func (p *Participant) Subscriptions() []*Subscription {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	subscriptions := make([]*Subscription, 0, len(p.subscriptions))
+	for _, s := range p.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions
+}
+
+// Leave removes the participant from the room, unpublishes its tracks and
+// closes its PeerConnection. Leave is called when the ICE connection of the
+// participant fails or is closed.
+func (p *Participant) Leave() error {
+	p.mu.Lock()
+	if p.left {
+		p.mu.Unlock()
+		return nil
+	}
+	p.left = true
+	published := p.published
+	p.published = nil
+	subscriptions := p.subscriptions
+	p.subscriptions = map[*PublishedTrack]*Subscription{}
+	p.mu.Unlock()
+
+	p.room.removeParticipant(p)
+	for _, t := range published {
+		t.unpublish()
+	}
+	for t, s := range subscriptions {
+		t.removeSubscription(s)
+	}
+	err := p.pc.Close()
+
+	if onLeft, _, _ := p.room.handlers(); onLeft != nil {
+		onLeft(p)
+	}
+	return err
+}
+
+// publish is the OnTrack handler of the participant, it forwards the track
+// until it ends
+func (p *Participant) publish(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+	codec := p.room.codecFor(track.Codec())
+	if codec == nil {
+		p.room.log.Warnf("%s published %s with codec %s, which the room doesn't forward", p.id, track.ID(), track.Codec().Name)
+		return
+	}
+
+	t := &PublishedTrack{
+		publisher: p,
+		remote:    track,
+		receiver:  receiver,
+		codec:     codec,
+		idle:      p.room.config.LazyForwarding,
+	}
+
+	p.mu.Lock()
+	if p.left {
+		p.mu.Unlock()
+		return
+	}
+	p.published = append(p.published, t)
+	p.mu.Unlock()
+
+	p.room.addTrack(t)
+	t.forward()
+	t.unpublish()
+}
+
+// autoSubscribes tells if the participant is subscribed to t without asking
+func (p *Participant) autoSubscribes(t *PublishedTrack) bool {
+	if p.room.config.ManualSubscription || t.publisher == p {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.audioOnly || t.Kind() != webrtc.RTPCodecTypeVideo
+}
+
+// addSubscription registers s
+func (p *Participant) addSubscription(s *Subscription) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.left:
+		return ErrRoomClosed
+	case p.subscriptions[s.track] != nil:
+		return ErrAlreadySubscribed
+	}
+	p.subscriptions[s.track] = s
+	return nil
+}
+
+// removeSubscription unregisters the subscription of t, returns it if the
+// participant had one
+func (p *Participant) removeSubscription(t *PublishedTrack) *Subscription {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.subscriptions[t]
+	delete(p.subscriptions, t)
+	return s
+}
+
+func (p *Participant) removePublished(t *PublishedTrack) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, published := range p.published {
+		if published == t {
+			p.published = append(p.published[:i], p.published[i+1:]...)
+			return
+		}
+	}
+}
+
+// negotiationNeeded renegotiates after subscriptions changed, or tells the
+// application to do it if the room doesn't offer by itself
+func (p *Participant) negotiationNeeded() {
+	p.mu.Lock()
+	if p.left {
+		p.mu.Unlock()
+		return
+	}
+	if p.updates != 0 {
+		p.updateNegotiation = true
+		p.mu.Unlock()
+		return
+	}
+	if p.onOffer == nil {
+		f := p.onNegotiationNeeded
+		p.mu.Unlock()
+		if f != nil {
+			f()
+		}
+		return
+	}
+	// Wait for the first negotiation and for offers that are out
+	if p.negotiating || p.pc.CurrentRemoteDescription() == nil || p.pc.SignalingState() != webrtc.SignalingStateStable {
+		p.pendingNegotiation = true
+		p.mu.Unlock()
+		return
+	}
+	p.negotiating = true
+	f := p.onOffer
+	p.mu.Unlock()
+
+	offer, err := p.pc.CreateOffer(nil)
+	if err == nil {
+		err = p.pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		p.room.log.Warnf("failed to renegotiate with %s: %v", p.id, err)
+		p.mu.Lock()
+		p.negotiating = false
+		p.mu.Unlock()
+		return
+	}
+	f(offer)
+}
+
+// negotiated is called when an offer/answer exchange completed, it offers
+// the changes made meanwhile
+func (p *Participant) negotiated() {
+	p.mu.Lock()
+	p.negotiating = false
+	pending := p.pendingNegotiation
+	p.pendingNegotiation = false
+	p.mu.Unlock()
+	if pending {
+		p.negotiationNeeded()
+	}
+}
@@ -0,0 +1,275 @@
+// +build !js
+
+package room
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// PublishedTrack is a track a participant publishes to the room
+type PublishedTrack struct {
+	publisher *Participant
+	remote    *webrtc.Track
+	receiver  *webrtc.RTPReceiver
+
+	// codec of the room the track is forwarded with
+	codec *webrtc.RTPCodec
+
+	mu sync.Mutex
+	// subscriptions and recordings are replaced on every change, forward
+	// uses them without holding the lock
+	subscriptions []*Subscription
+	recordings    []*Recording
+	ended         bool
+
+	// bound counts the senders of the subscriptions that are started. With
+	// Config.LazyForwarding the track is idle while no sender is bound and
+	// it isn't recorded.
+	bound int
+	idle  bool
+}
+
+// ID returns the ID of the track
+func (t *PublishedTrack) ID() string {
+	return t.remote.ID()
+}
+
+// Label returns the label of the track
+func (t *PublishedTrack) Label() string {
+	return t.remote.Label()
+}
+
+// Kind returns the kind of the track
+func (t *PublishedTrack) Kind() webrtc.RTPCodecType {
+	return t.remote.Kind()
+}
+
+// Publisher returns the participant that publishes the track
+func (t *PublishedTrack) Publisher() *Participant {
+	return t.publisher
+}
+
+// Track returns the remote track of the publisher's PeerConnection
+func (t *PublishedTrack) Track() *webrtc.Track {
+	return t.remote
+}
+
+// Subscriptions returns the subscriptions of the track
+func (t *PublishedTrack) Subscriptions() []*Subscription {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Subscription{}, t.subscriptions...)
+}
+
+// Idle tells if the track isn't forwarded because nobody receives it, see
+// Config.LazyForwarding
+func (t *PublishedTrack) Idle() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.idle
+}
+
+// subscribe adds a track forwarding t to the PeerConnection of p
+func (t *PublishedTrack) subscribe(p *Participant) (*Subscription, error) {
+	// The track keeps the codec of the room, AddTrack rejects an SSRC the
+	// PeerConnection of p uses already
+	var local *webrtc.Track
+	var sender *webrtc.RTPSender
+	var err error
+	for {
+		ssrc := rand.Uint32() // nolint:gosec
+		if ssrc == 0 {
+			continue
+		}
+		if local, err = webrtc.NewTrack(t.codec.PayloadType, ssrc, t.remote.ID(), t.remote.Label(), t.codec); err != nil {
+			return nil, err
+		}
+		if sender, err = p.pc.AddTrack(local); err == nil {
+			break
+		} else if err != webrtc.ErrSSRCCollision {
+			return nil, err
+		}
+	}
+	local.OnBind(func(webrtc.TrackBinding) { t.senderBound(1) })
+	local.OnUnbind(func(webrtc.TrackBinding) { t.senderBound(-1) })
+
+	config := t.publisher.room.config
+	s := &Subscription{
+		track:       t,
+		subscriber:  p,
+		local:       local,
+		sender:      sender,
+		suspendLoss: config.SuspendLoss,
+		resumeAfter: config.ResumeAfter,
+
+		vp8:              t.codec.Name == webrtc.VP8,
+		maxTemporalLayer: AllTemporalLayers,
+		temporalLayer:    AllTemporalLayers,
+	}
+
+	t.mu.Lock()
+	ended := t.ended
+	if !ended {
+		t.subscriptions = append(append([]*Subscription{}, t.subscriptions...), s)
+	}
+	t.mu.Unlock()
+	if ended {
+		err = ErrTrackEnded
+	} else {
+		err = p.addSubscription(s)
+	}
+	if err != nil {
+		t.removeSubscription(s)
+		s.stop()
+		return nil, err
+	}
+
+	go s.readRTCP()
+	return s, nil
+}
+
+// forward writes the packets of the remote track to every subscription until
+// the remote track ends. The packets of an idle track are dropped unparsed.
+func (t *PublishedTrack) forward() {
+	payloadType := t.remote.PayloadType()
+	buf := make([]byte, receiveMTU)
+	for {
+		if t.Idle() {
+			if _, err := t.remote.Read(buf); err != nil {
+				return
+			}
+			continue
+		}
+
+		pkt, err := t.remote.ReadRTP()
+		if err != nil {
+			return
+		}
+		// Retransmissions and FEC of the publisher aren't forwarded
+		if pkt.PayloadType != payloadType {
+			continue
+		}
+
+		t.mu.Lock()
+		subscriptions := t.subscriptions
+		recordings := t.recordings
+		t.mu.Unlock()
+
+		now := time.Now()
+		for _, rec := range recordings {
+			rec.write(pkt, now)
+		}
+		for _, s := range subscriptions {
+			s.write(pkt, now)
+		}
+	}
+}
+
+// unpublish removes the track from the room and stops its subscriptions and
+// recordings
+func (t *PublishedTrack) unpublish() {
+	r := t.publisher.room
+	if !r.removeTrack(t) {
+		return
+	}
+	t.publisher.removePublished(t)
+
+	t.mu.Lock()
+	t.ended = true
+	t.idle = false
+	subscriptions := t.subscriptions
+	t.subscriptions = nil
+	recordings := t.recordings
+	t.mu.Unlock()
+
+	for _, rec := range recordings {
+		if err := rec.Stop(); err != nil {
+			r.log.Warnf("failed to close the recording of %s: %v", t.ID(), err)
+		}
+	}
+
+	for _, s := range subscriptions {
+		if s.subscriber.removeSubscription(t) == s {
+			s.stop()
+			s.subscriber.negotiationNeeded()
+		}
+	}
+
+	if _, onUnpublished, _ := r.handlers(); onUnpublished != nil {
+		onUnpublished(t)
+	}
+}
+
+func (t *PublishedTrack) removeSubscription(s *Subscription) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, subscription := range t.subscriptions {
+		if subscription == s {
+			subscriptions := append([]*Subscription{}, t.subscriptions[:i]...)
+			t.subscriptions = append(subscriptions, t.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *PublishedTrack) removeRecording(rec *Recording) {
+	t.mu.Lock()
+	for i, recording := range t.recordings {
+		if recording == rec {
+			recordings := append([]*Recording{}, t.recordings[:i]...)
+			t.recordings = append(recordings, t.recordings[i+1:]...)
+			break
+		}
+	}
+	t.mu.Unlock()
+	t.updateIdle()
+}
+
+// senderBound accounts for a sender of a subscription that started or
+// stopped sending the track
+func (t *PublishedTrack) senderBound(delta int) {
+	t.mu.Lock()
+	t.bound += delta
+	t.mu.Unlock()
+	t.updateIdle()
+}
+
+// updateIdle stops or restarts forwarding with Config.LazyForwarding. A key
+// frame is requested for the subscribers when video is forwarded again.
+func (t *PublishedTrack) updateIdle() {
+	r := t.publisher.room
+	if !r.config.LazyForwarding {
+		return
+	}
+
+	t.mu.Lock()
+	idle := !t.ended && t.bound == 0 && len(t.recordings) == 0
+	changed := idle != t.idle
+	t.idle = idle
+	t.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if !idle && t.Kind() == webrtc.RTPCodecTypeVideo {
+		t.requestKeyFrame()
+	}
+	r.mu.Lock()
+	onIdle := r.onTrackIdle
+	r.mu.Unlock()
+	if onIdle != nil {
+		onIdle(t, idle)
+	}
+}
+
+// requestKeyFrame asks the publisher for a key frame. Requests of all
+// subscribers are consolidated by the RTPReceiver.
+func (t *PublishedTrack) requestKeyFrame() {
+	if err := t.receiver.RequestKeyFrame(); err != nil {
+		t.publisher.room.log.Debugf("failed to request a key frame of %s: %v", t.ID(), err)
+	}
+}
@@ -0,0 +1,404 @@
+// +build !js
+
+package room
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// Events of a ManifestEntry
+const (
+	ManifestEventStart = "start"
+	ManifestEventStop  = "stop"
+)
+
+// RecorderFactory creates the writer a published track is recorded with,
+// e.g. an ivfwriter for VP8 or an opuswriter for Opus, and the name the
+// recording has in the manifest, e.g. its file name. A nil writer leaves the
+// track unrecorded. With a Rotation it is called for every segment, the names
+// of the segments of a track have to differ.
+type RecorderFactory func(t *PublishedTrack) (w media.Writer, name string, err error)
+
+// Rotation splits the recordings of a room into segments, so that long
+// recordings can be uploaded while they are running and a crash only loses
+// the segments that weren't finished. Every segment is written by its own
+// writer of the RecorderFactory, which is closed when the next one starts.
+type Rotation struct {
+	// Duration starts a new segment once the segment has been recording
+	// for this long. Zero disables time based rotation.
+	Duration time.Duration
+
+	// Size starts a new segment once this many bytes of media have been
+	// written to the segment, the size of the writer's container is a bit
+	// larger. Zero disables size based rotation.
+	Size int
+
+	// OnSegment is called with the stop entry of every segment once its
+	// writer has been closed, including the last segment of a track
+	OnSegment func(ManifestEntry)
+}
+
+// ManifestEntry is a line of the manifest of a recorded room. The manifest is
+// the timeline of the recordings, it tells when each of them started and
+// stopped so that they can be composited later.
+type ManifestEntry struct {
+	Event       string              `json:"event"`
+	Time        time.Time           `json:"time"`
+	Name        string              `json:"name"`
+	Participant string              `json:"participant"`
+	Track       string              `json:"track"`
+	Kind        webrtc.RTPCodecType `json:"kind"`
+	MimeType    string              `json:"mimeType"`
+	ClockRate   uint32              `json:"clockRate"`
+
+	// Segment counts the segments of a rotated recording, starting at zero
+	Segment int `json:"segment"`
+
+	// RTPTimestamp of the first recorded packet on start, and of the last
+	// one on stop. It maps the timestamps of the recording to Time.
+	RTPTimestamp uint32 `json:"rtpTimestamp"`
+}
+
+// Recording writes the packets of a published track to a media.Writer
+type Recording struct {
+	track *PublishedTrack
+
+	// recorder is nil for recordings added with PublishedTrack.Record
+	recorder *roomRecorder
+
+	mu            sync.Mutex
+	writer        media.Writer
+	name          string
+	started       bool
+	stopped       bool
+	lastTimestamp uint32
+
+	// The current segment of a rotated recording, only used by write
+	segment           int
+	segmentStart      time.Time
+	segmentSize       int
+	keyFrameRequested bool
+}
+
+// manifest writes the entries of a room recording as JSON lines
+type manifest struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// roomRecorder records every track of a room
+type roomRecorder struct {
+	factory    RecorderFactory
+	manifest   *manifest
+	rotation   Rotation
+	recordings []*Recording
+}
+
+// Record writes the packets of the track to w until the track ends or the
+// recording is stopped, which closes w. w gets the packets as they are
+// received from the publisher and must not modify them. A key frame is
+// requested for the recording to start with.
+func (t *PublishedTrack) Record(w media.Writer) (*Recording, error) {
+	return t.record(w, "", nil)
+}
+
+func (t *PublishedTrack) record(w media.Writer, name string, recorder *roomRecorder) (*Recording, error) {
+	rec := &Recording{track: t, recorder: recorder, writer: w, name: name}
+
+	t.mu.Lock()
+	if t.ended {
+		t.mu.Unlock()
+		return nil, ErrTrackEnded
+	}
+	t.recordings = append(append([]*Recording{}, t.recordings...), rec)
+	t.mu.Unlock()
+	t.updateIdle()
+
+	if t.Kind() == webrtc.RTPCodecTypeVideo {
+		t.requestKeyFrame()
+	}
+	return rec, nil
+}
+
+// Track returns the recorded track
+func (rec *Recording) Track() *PublishedTrack {
+	return rec.track
+}
+
+// Name returns the name of the recording in the manifest, the name of the
+// current segment if the recording is rotated
+func (rec *Recording) Name() string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.name
+}
+
+// Stop stops recording and closes the writer
+func (rec *Recording) Stop() error {
+	rec.track.removeRecording(rec)
+
+	rec.mu.Lock()
+	if rec.stopped {
+		rec.mu.Unlock()
+		return nil
+	}
+	rec.stopped = true
+	started := rec.started
+	stop := rec.manifestEntry(ManifestEventStop, time.Now(), rec.lastTimestamp)
+	rec.mu.Unlock()
+
+	if started {
+		rec.writeManifest(stop)
+	}
+	if err := rec.writer.Close(); err != nil {
+		return err
+	}
+	if started {
+		rec.segmentDone(stop)
+	}
+	return nil
+}
+
+// write records a packet of the track, a failing writer stops the recording
+func (rec *Recording) write(pkt *rtp.Packet, now time.Time) {
+	rec.mu.Lock()
+	if rec.stopped {
+		rec.mu.Unlock()
+		return
+	}
+	rotate := rec.started && rec.rotationDue(pkt, now)
+	rec.mu.Unlock()
+
+	if rotate {
+		rec.rotate(now)
+	}
+
+	rec.mu.Lock()
+	if rec.stopped {
+		rec.mu.Unlock()
+		return
+	}
+	first := !rec.started
+	rec.started = true
+	rec.lastTimestamp = pkt.Timestamp
+	w := rec.writer
+	var start ManifestEntry
+	if first {
+		start = rec.manifestEntry(ManifestEventStart, now, pkt.Timestamp)
+	}
+	rec.mu.Unlock()
+
+	if first {
+		rec.segmentStart = now
+		rec.writeManifest(start)
+	}
+	rec.segmentSize += len(pkt.Payload)
+	if err := w.WriteRTP(pkt); err != nil {
+		rec.track.publisher.room.log.Warnf("failed to record %s, stopping: %v", rec.track.ID(), err)
+		if err := rec.Stop(); err != nil {
+			rec.track.publisher.room.log.Warnf("failed to close the recording of %s: %v", rec.track.ID(), err)
+		}
+	}
+}
+
+// rotationDue tells if the segment is due to be rotated and pkt may start
+// the next one. Segments of VP8 start with a key frame, which is requested
+// when the rotation is due, the ones of other codecs with a new frame. The
+// lock has to be held.
+func (rec *Recording) rotationDue(pkt *rtp.Packet, now time.Time) bool {
+	if rec.recorder == nil {
+		return false
+	}
+	rotation := rec.recorder.rotation
+	if (rotation.Duration == 0 || now.Sub(rec.segmentStart) < rotation.Duration) &&
+		(rotation.Size == 0 || rec.segmentSize < rotation.Size) {
+		return false
+	}
+
+	if rec.track.codec.Name != webrtc.VP8 {
+		return pkt.Timestamp != rec.lastTimestamp
+	}
+	if d, ok := parseVP8Descriptor(pkt.Payload); ok && d.keyFrame {
+		return true
+	}
+	if !rec.keyFrameRequested {
+		rec.keyFrameRequested = true
+		rec.track.requestKeyFrame()
+	}
+	return false
+}
+
+// rotate finishes the current segment and starts the next one with a writer
+// of the factory. The current segment continues if the factory fails.
+func (rec *Recording) rotate(now time.Time) {
+	log := rec.track.publisher.room.log
+	rec.segmentStart = now
+	rec.segmentSize = 0
+	rec.keyFrameRequested = false
+
+	w, name, err := rec.recorder.factory(rec.track)
+	if err != nil || w == nil {
+		log.Warnf("failed to create the next segment of %s, continuing %s: %v", rec.track.ID(), rec.Name(), err)
+		return
+	}
+
+	rec.mu.Lock()
+	if rec.stopped {
+		rec.mu.Unlock()
+		if err := w.Close(); err != nil {
+			log.Warnf("failed to close the recorder of %s: %v", rec.track.ID(), err)
+		}
+		return
+	}
+	stop := rec.manifestEntry(ManifestEventStop, now, rec.lastTimestamp)
+	previous := rec.writer
+	rec.writer = w
+	rec.name = name
+	rec.segment++
+	rec.started = false
+	rec.mu.Unlock()
+
+	rec.writeManifest(stop)
+	if err := previous.Close(); err != nil {
+		log.Warnf("failed to close the segment %s: %v", stop.Name, err)
+		return
+	}
+	rec.segmentDone(stop)
+}
+
+// manifestEntry returns the entry of an event of the current segment, the
+// lock has to be held
+func (rec *Recording) manifestEntry(event string, now time.Time, timestamp uint32) ManifestEntry {
+	codec := rec.track.remote.Codec()
+	return ManifestEntry{
+		Event:        event,
+		Time:         now,
+		Name:         rec.name,
+		Participant:  rec.track.publisher.id,
+		Track:        rec.track.ID(),
+		Kind:         rec.track.Kind(),
+		MimeType:     codec.MimeType,
+		ClockRate:    codec.ClockRate,
+		Segment:      rec.segment,
+		RTPTimestamp: timestamp,
+	}
+}
+
+func (rec *Recording) writeManifest(entry ManifestEntry) {
+	if rec.recorder == nil || rec.recorder.manifest == nil {
+		return
+	}
+
+	m := rec.recorder.manifest
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.encoder.Encode(entry); err != nil {
+		rec.track.publisher.room.log.Warnf("failed to write the manifest entry of %s: %v", entry.Name, err)
+	}
+}
+
+// segmentDone hands the stop entry of a finished segment to OnSegment
+func (rec *Recording) segmentDone(stop ManifestEntry) {
+	if rec.recorder != nil && rec.recorder.rotation.OnSegment != nil {
+		rec.recorder.rotation.OnSegment(stop)
+	}
+}
+
+// Record records every track published in the room, now and later, with
+// writers made by factory. The timeline of the recordings is written to
+// manifestWriter as one JSON encoded ManifestEntry per line, it may be nil.
+// Recording continues until StopRecording is called or the room is closed.
+func (r *Room) Record(factory RecorderFactory, manifestWriter io.Writer) error {
+	return r.RecordSegmented(factory, manifestWriter, Rotation{})
+}
+
+// RecordSegmented records the room like Record, splitting the recording of
+// every track into segments as rotation says. The manifest has a start and a
+// stop entry for every segment.
+func (r *Room) RecordSegmented(factory RecorderFactory, manifestWriter io.Writer, rotation Rotation) error {
+	recorder := &roomRecorder{factory: factory, rotation: rotation}
+	if manifestWriter != nil {
+		recorder.manifest = &manifest{encoder: json.NewEncoder(manifestWriter)}
+	}
+
+	r.mu.Lock()
+	if r.recorder != nil {
+		r.mu.Unlock()
+		return ErrAlreadyRecording
+	}
+	r.recorder = recorder
+	tracks := append([]*PublishedTrack{}, r.tracks...)
+	r.mu.Unlock()
+
+	for _, t := range tracks {
+		r.recordTrack(recorder, t)
+	}
+	return nil
+}
+
+// StopRecording stops the recording started with Record and closes its writers
+func (r *Room) StopRecording() error {
+	r.mu.Lock()
+	recorder := r.recorder
+	r.recorder = nil
+	r.mu.Unlock()
+	if recorder == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, rec := range recorder.takeRecordings() {
+		if err := rec.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return flattenErrs(errs...)
+}
+
+// recordTrack starts recording t if a room recording is running
+func (r *Room) recordTrack(recorder *roomRecorder, t *PublishedTrack) {
+	if recorder == nil {
+		return
+	}
+	w, name, err := recorder.factory(t)
+	if err != nil {
+		r.log.Warnf("failed to create the recorder of %s: %v", t.ID(), err)
+		return
+	}
+	if w == nil {
+		return
+	}
+	rec, err := t.record(w, name, recorder)
+	if err != nil {
+		if closeErr := w.Close(); closeErr != nil {
+			r.log.Warnf("failed to close the recorder of %s: %v", t.ID(), closeErr)
+		}
+		return
+	}
+
+	r.mu.Lock()
+	running := r.recorder == recorder
+	if running {
+		recorder.recordings = append(recorder.recordings, rec)
+	}
+	r.mu.Unlock()
+	if !running {
+		// StopRecording raced with us
+		if err := rec.Stop(); err != nil {
+			r.log.Warnf("failed to close the recorder of %s: %v", t.ID(), err)
+		}
+	}
+}
+
+func (rr *roomRecorder) takeRecordings() []*Recording {
+	recordings := rr.recordings
+	rr.recordings = nil
+	return recordings
+}
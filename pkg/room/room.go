@@ -0,0 +1,392 @@
+// +build !js
+
+// Package room forwards the tracks of publishing PeerConnections to
+// subscribing PeerConnections, the selective forwarding of the sfu-minimal
+// example as a reusable type.
+//
+// Every participant of a Room has one PeerConnection with the server. The
+// tracks a participant publishes are forwarded to every other participant.
+// Each subscriber gets a Track of its own, the SSRC and the sequence numbers
+// of the forwarded packets are rewritten, which allows to adapt what is
+// forwarded per subscriber: the video of a subscriber that reports high loss
+// is suspended for a while instead of congesting its link further, and the
+// upper temporal layers of VP8 can be dropped for constrained subscribers.
+//
+// Tracks can be recorded on the server without a subscribing PeerConnection,
+// single ones with PublishedTrack.Record or all of a room with Room.Record,
+// which also writes a manifest with the timeline of the recordings.
+//
+// Participants can also pick what they receive: with
+// Config.ManualSubscription they are only subscribed to the tracks they ask
+// for, and in audio only mode they don't receive video unless asked. With
+// Config.LazyForwarding tracks nobody receives aren't forwarded at all.
+//
+// The Room doesn't do signaling. Participants negotiate their PeerConnection
+// with the application's signaling. When subscriptions change, the room
+// creates the offer and passes it to the OnOffer handler of the participant,
+// or OnNegotiationNeeded leaves the renegotiation to the application.
+package room
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/webrtc/v2"
+)
+
+const (
+	// DefaultSuspendLoss is the fraction of lost packets above which the
+	// video of a subscription is suspended
+	DefaultSuspendLoss = 0.2
+
+	// DefaultResumeAfter is how long the video of a subscription stays
+	// suspended before it is forwarded again
+	DefaultResumeAfter = 5 * time.Second
+
+	// AllTemporalLayers is the highest temporal layer of VP8, a subscription
+	// with this limit forwards every layer
+	AllTemporalLayers uint8 = 3
+
+	// receiveMTU is the size of the buffer the packets of idle tracks are
+	// read into
+	receiveMTU = 8192
+)
+
+var (
+	// ErrParticipantExists indicates that a participant joined with the ID
+	// of a participant that is already in the room
+	ErrParticipantExists = errors.New("room: participant already exists")
+
+	// ErrRoomClosed indicates that a participant joined a closed room, or
+	// that a participant that left was subscribed
+	ErrRoomClosed = errors.New("room: closed")
+
+	// ErrTrackEnded indicates a subscription to a track that was unpublished
+	ErrTrackEnded = errors.New("room: track ended")
+
+	// ErrOwnTrack indicates that a participant subscribed to its own track
+	ErrOwnTrack = errors.New("room: participant subscribed to its own track")
+
+	// ErrAlreadySubscribed indicates that a participant was subscribed to a
+	// track twice at the same time
+	ErrAlreadySubscribed = errors.New("room: already subscribed")
+
+	// ErrNotSubscribed indicates that a participant unsubscribed from a track
+	// it isn't subscribed to
+	ErrNotSubscribed = errors.New("room: not subscribed")
+
+	// ErrAlreadyRecording indicates that Record was called on a room that is
+	// recorded already
+	ErrAlreadyRecording = errors.New("room: already recording")
+)
+
+// Config configures a Room
+type Config struct {
+	// MediaEngine has the codecs the PeerConnections of the room use.
+	// Published tracks are forwarded with the codec of the same name.
+	MediaEngine webrtc.MediaEngine
+
+	// SettingEngine and Configuration are used for the PeerConnections of
+	// the room
+	SettingEngine webrtc.SettingEngine
+	Configuration webrtc.Configuration
+
+	// SuspendLoss is the fraction of packets a subscriber may lose, as told
+	// by its receiver reports, before the video it is sent is suspended.
+	// Zero uses DefaultSuspendLoss, a negative value disables suspension.
+	SuspendLoss float64
+
+	// ResumeAfter is how long suspended video isn't forwarded. Zero uses
+	// DefaultResumeAfter.
+	ResumeAfter time.Duration
+
+	// ManualSubscription stops subscribing participants to every track of
+	// the room, they only receive the tracks passed to Subscribe
+	ManualSubscription bool
+
+	// LazyForwarding stops forwarding published tracks that aren't sent to
+	// any subscriber nor recorded. The packets of an idle track are still
+	// read, to notice when it ends, but dropped without being parsed. See
+	// Room.OnTrackIdle to ask the publisher to stop sending.
+	LazyForwarding bool
+}
+
+// Room manages the participants of a session and forwards the tracks they
+// publish to each other
+type Room struct {
+	api         *webrtc.API
+	mediaEngine webrtc.MediaEngine
+	config      Config
+	log         logging.LeveledLogger
+
+	mu           sync.Mutex
+	participants map[string]*Participant
+	tracks       []*PublishedTrack
+	recorder     *roomRecorder
+	closed       bool
+
+	onParticipantJoined     func(*Participant)
+	onParticipantLeft       func(*Participant)
+	onTrackPublished        func(*PublishedTrack)
+	onTrackUnpublished      func(*PublishedTrack)
+	onSubscriptionSuspended func(*Subscription, bool)
+	onTrackIdle             func(*PublishedTrack, bool)
+}
+
+// New creates a Room
+func New(config Config) *Room {
+	if config.SuspendLoss == 0 {
+		config.SuspendLoss = DefaultSuspendLoss
+	}
+	if config.ResumeAfter == 0 {
+		config.ResumeAfter = DefaultResumeAfter
+	}
+	loggerFactory := config.SettingEngine.LoggerFactory
+	if loggerFactory == nil {
+		loggerFactory = logging.NewDefaultLoggerFactory()
+	}
+
+	return &Room{
+		api: webrtc.NewAPI(
+			webrtc.WithMediaEngine(config.MediaEngine),
+			webrtc.WithSettingEngine(config.SettingEngine),
+		),
+		mediaEngine:  config.MediaEngine,
+		config:       config,
+		log:          loggerFactory.NewLogger("room"),
+		participants: map[string]*Participant{},
+	}
+}
+
+// OnParticipantJoined sets a handler that is called when a participant joins
+func (r *Room) OnParticipantJoined(f func(*Participant)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onParticipantJoined = f
+}
+
+// OnParticipantLeft sets a handler that is called when a participant left,
+// after its tracks have been unpublished
+func (r *Room) OnParticipantLeft(f func(*Participant)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onParticipantLeft = f
+}
+
+// OnTrackPublished sets a handler that is called when a participant starts
+// to publish a track, after it has been subscribed by the other participants
+func (r *Room) OnTrackPublished(f func(*PublishedTrack)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onTrackPublished = f
+}
+
+// OnTrackUnpublished sets a handler that is called when a published track
+// ended, e.g. because its publisher left
+func (r *Room) OnTrackUnpublished(f func(*PublishedTrack)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onTrackUnpublished = f
+}
+
+// OnSubscriptionSuspended sets a handler that is called when the video of a
+// subscription is suspended because of loss, and with false once it is
+// forwarded again
+func (r *Room) OnSubscriptionSuspended(f func(s *Subscription, suspended bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onSubscriptionSuspended = f
+}
+
+// OnTrackIdle sets a handler that is called with Config.LazyForwarding when
+// a published track becomes idle because it lost its last subscriber and
+// recording, and with false once it is forwarded again. The application can
+// use it to ask the publisher through its signaling to stop sending the
+// track, which saves the bandwidth in addition to the forwarding.
+func (r *Room) OnTrackIdle(f func(t *PublishedTrack, idle bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onTrackIdle = f
+}
+
+// Join adds a participant to the room. The participant is subscribed to the
+// tracks published so far unless subscriptions are manual, its
+// PeerConnection has to be negotiated next.
+func (r *Room) Join(id string) (*Participant, error) {
+	pc, err := r.api.NewPeerConnection(r.config.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	p := &Participant{
+		id:            id,
+		room:          r,
+		pc:            pc,
+		subscriptions: map[*PublishedTrack]*Subscription{},
+	}
+
+	r.mu.Lock()
+	switch {
+	case r.closed:
+		err = ErrRoomClosed
+	case r.participants[id] != nil:
+		err = ErrParticipantExists
+	}
+	if err != nil {
+		r.mu.Unlock()
+		return nil, flattenErrs(err, pc.Close())
+	}
+	r.participants[id] = p
+	tracks := append([]*PublishedTrack{}, r.tracks...)
+	onJoined := r.onParticipantJoined
+	r.mu.Unlock()
+
+	pc.OnTrack(p.publish)
+	pc.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+		if state == webrtc.ICEConnectionStateFailed || state == webrtc.ICEConnectionStateClosed {
+			if err := p.Leave(); err != nil {
+				r.log.Warnf("participant %s failed to leave: %v", id, err)
+			}
+		}
+	})
+
+	for _, t := range tracks {
+		if !p.autoSubscribes(t) {
+			continue
+		}
+		if _, err := t.subscribe(p); err != nil {
+			r.log.Warnf("failed to subscribe %s to %s: %v", id, t.ID(), err)
+		}
+	}
+
+	if onJoined != nil {
+		onJoined(p)
+	}
+	return p, nil
+}
+
+// Participant returns the participant with the given ID, or nil
+func (r *Room) Participant(id string) *Participant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.participants[id]
+}
+
+// Participants returns the participants of the room
+func (r *Room) Participants() []*Participant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	participants := make([]*Participant, 0, len(r.participants))
+	for _, p := range r.participants {
+		participants = append(participants, p)
+	}
+	return participants
+}
+
+// Tracks returns the tracks published in the room
+func (r *Room) Tracks() []*PublishedTrack {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*PublishedTrack{}, r.tracks...)
+}
+
+// Close makes every participant leave, stops recording and rejects
+// participants joining later
+func (r *Room) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	participants := make([]*Participant, 0, len(r.participants))
+	for _, p := range r.participants {
+		participants = append(participants, p)
+	}
+	r.mu.Unlock()
+
+	var errs []error
+	for _, p := range participants {
+		if err := p.Leave(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := r.StopRecording(); err != nil {
+		errs = append(errs, err)
+	}
+	return flattenErrs(errs...)
+}
+
+// addTrack registers a published track and subscribes the participants
+// that receive everything
+func (r *Room) addTrack(t *PublishedTrack) {
+	r.mu.Lock()
+	r.tracks = append(r.tracks, t)
+	participants := make([]*Participant, 0, len(r.participants))
+	for _, p := range r.participants {
+		participants = append(participants, p)
+	}
+	onPublished := r.onTrackPublished
+	recorder := r.recorder
+	r.mu.Unlock()
+
+	r.recordTrack(recorder, t)
+	for _, p := range participants {
+		if !p.autoSubscribes(t) {
+			continue
+		}
+		if _, err := t.subscribe(p); err != nil {
+			r.log.Warnf("failed to subscribe %s to %s: %v", p.id, t.ID(), err)
+			continue
+		}
+		p.negotiationNeeded()
+	}
+
+	if onPublished != nil {
+		onPublished(t)
+	}
+}
+
+// removeTrack unregisters a published track, returns false if it already was
+func (r *Room) removeTrack(t *PublishedTrack) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, track := range r.tracks {
+		if track == t {
+			r.tracks = append(r.tracks[:i], r.tracks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Room) removeParticipant(p *Participant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.participants[p.id] == p {
+		delete(r.participants, p.id)
+	}
+}
+
+// codecFor returns the codec of the room that has the name of codec
+func (r *Room) codecFor(codec *webrtc.RTPCodec) *webrtc.RTPCodec {
+	for _, c := range r.mediaEngine.GetCodecsByKind(codec.Type) {
+		if c.Name == codec.Name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (r *Room) handlers() (onLeft func(*Participant), onUnpublished func(*PublishedTrack), onSuspended func(*Subscription, bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.onParticipantLeft, r.onTrackUnpublished, r.onSubscriptionSuspended
+}
+
+// flattenErrs returns the first of errs that isn't nil
+func flattenErrs(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
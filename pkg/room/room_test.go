@@ -0,0 +1,664 @@
+// +build !js
+
+package room
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// negotiate makes the room offer the subscriptions of p to client
+func negotiate(t *testing.T, p *Participant, client *webrtc.PeerConnection) {
+	offer, err := p.PeerConnection().CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, p.PeerConnection().SetLocalDescription(offer))
+	assert.NoError(t, client.SetRemoteDescription(offer))
+	answer, err := client.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SetLocalDescription(answer))
+	assert.NoError(t, p.PeerConnection().SetRemoteDescription(answer))
+}
+
+func TestRoom(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
+	r := New(Config{MediaEngine: m})
+
+	published := make(chan *PublishedTrack, 1)
+	r.OnTrackPublished(func(track *PublishedTrack) { published <- track })
+	unpublished := make(chan *PublishedTrack, 1)
+	r.OnTrackUnpublished(func(track *PublishedTrack) { unpublished <- track })
+	left := make(chan *Participant, 2)
+	r.OnParticipantLeft(func(p *Participant) { left <- p })
+
+	// The publisher sends a video track
+	done := make(chan struct{})
+	defer close(done)
+	publisher, publisherClient := publish(t, api, r, "publisher", done)
+	_, err := r.Join("publisher")
+	assert.Equal(t, ErrParticipantExists, err)
+
+	publishedTrack := <-published
+	assert.Equal(t, "video", publishedTrack.ID())
+	assert.Equal(t, publisher, publishedTrack.Publisher())
+	assert.Equal(t, []*PublishedTrack{publishedTrack}, r.Tracks())
+
+	// A participant joining later is subscribed to the published track
+	subscriber, err := r.Join("subscriber")
+	assert.NoError(t, err)
+	subscriptions := subscriber.Subscriptions()
+	if !assert.Len(t, subscriptions, 1) {
+		return
+	}
+	subscription := subscriptions[0]
+	assert.Equal(t, publishedTrack, subscription.Track())
+	assert.Empty(t, publisher.Subscriptions())
+
+	subscriberClient, err := api.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	_, err = subscriberClient.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	received := make(chan *rtp.Packet, 1)
+	subscriberClient.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+		pkt, readErr := track.ReadRTP()
+		if readErr == nil {
+			received <- pkt
+		}
+	})
+	negotiate(t, subscriber, subscriberClient)
+
+	// The packets are rewritten to the SSRC of the subscription
+	pkt := <-received
+	assert.NotEqual(t, uint32(1234), pkt.SSRC)
+	assert.Equal(t, subscription.local.SSRC(), pkt.SSRC)
+
+	negotiationNeeded := make(chan struct{}, 1)
+	subscriber.OnNegotiationNeeded(func() { negotiationNeeded <- struct{}{} })
+
+	// The track ends with its publisher
+	assert.NoError(t, publisher.Leave())
+	assert.Equal(t, publisher, <-left)
+	assert.Equal(t, publishedTrack, <-unpublished)
+	<-negotiationNeeded
+	assert.Empty(t, subscriber.Subscriptions())
+	assert.Empty(t, r.Tracks())
+	assert.Nil(t, r.Participant("publisher"))
+
+	assert.NoError(t, r.Close())
+	assert.Equal(t, subscriber, <-left)
+	_, err = r.Join("late")
+	assert.Equal(t, ErrRoomClosed, err)
+
+	assert.NoError(t, publisherClient.Close())
+	assert.NoError(t, subscriberClient.Close())
+}
+
+// publish joins a participant that publishes a video track, samples are
+// written until done is closed
+func publish(t *testing.T, api *webrtc.API, r *Room, id string, done chan struct{}) (*Participant, *webrtc.PeerConnection) {
+	client, err := api.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	track, err := client.NewTrack(webrtc.DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	_, err = client.AddTrack(track)
+	assert.NoError(t, err)
+
+	p, err := r.Join(id)
+	assert.NoError(t, err)
+	offer, err := client.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SetLocalDescription(offer))
+	answer, err := p.Answer(offer)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SetRemoteDescription(answer))
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00, 0x01, 0x02}, Samples: 1})
+			}
+		}
+	}()
+	return p, client
+}
+
+func TestRoomSelectiveSubscription(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
+	r := New(Config{MediaEngine: m, ManualSubscription: true})
+
+	published := make(chan *PublishedTrack, 1)
+	r.OnTrackPublished(func(track *PublishedTrack) { published <- track })
+
+	done := make(chan struct{})
+	defer close(done)
+	_, publisherClient := publish(t, api, r, "publisher", done)
+	publishedTrack := <-published
+
+	// The subscriber negotiates a receiving section of its own, nothing is
+	// subscribed yet
+	subscriber, err := r.Join("subscriber")
+	assert.NoError(t, err)
+	assert.Empty(t, subscriber.Subscriptions())
+
+	subscriberClient, err := api.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	addReceiver := func() {
+		_, addErr := subscriberClient.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly})
+		assert.NoError(t, addErr)
+	}
+	addReceiver()
+	offer, err := subscriberClient.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, subscriberClient.SetLocalDescription(offer))
+	answer, err := subscriber.Answer(offer)
+	assert.NoError(t, err)
+	assert.NoError(t, subscriberClient.SetRemoteDescription(answer))
+
+	// The room renegotiates by itself
+	offers := make(chan struct{}, 2)
+	subscriber.OnOffer(func(offer webrtc.SessionDescription) {
+		go func() {
+			addReceiver()
+			assert.NoError(t, subscriberClient.SetRemoteDescription(offer))
+			answer, answerErr := subscriberClient.CreateAnswer(nil)
+			assert.NoError(t, answerErr)
+			assert.NoError(t, subscriberClient.SetLocalDescription(answer))
+			assert.NoError(t, subscriber.SetAnswer(answer))
+			offers <- struct{}{}
+		}()
+	})
+	received := make(chan uint32, 1)
+	subscriberClient.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+		if _, readErr := track.ReadRTP(); readErr == nil {
+			received <- track.SSRC()
+		}
+	})
+
+	_, err = publishedTrack.Publisher().Subscribe(publishedTrack)
+	assert.Equal(t, ErrOwnTrack, err)
+	subscription, err := subscriber.Subscribe(publishedTrack)
+	assert.NoError(t, err)
+	again, err := subscriber.Subscribe(publishedTrack)
+	assert.NoError(t, err)
+	assert.Equal(t, subscription, again)
+	<-offers
+	assert.Equal(t, subscription.local.SSRC(), <-received)
+
+	assert.NoError(t, subscriber.Unsubscribe(publishedTrack))
+	assert.Equal(t, ErrNotSubscribed, subscriber.Unsubscribe(publishedTrack))
+	<-offers
+	assert.Empty(t, subscriber.Subscriptions())
+	assert.Empty(t, publishedTrack.Subscriptions())
+
+	assert.NoError(t, r.Close())
+	assert.NoError(t, publisherClient.Close())
+	assert.NoError(t, subscriberClient.Close())
+}
+
+func TestRoomAudioOnly(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
+	r := New(Config{MediaEngine: m})
+
+	published := make(chan *PublishedTrack, 1)
+	r.OnTrackPublished(func(track *PublishedTrack) { published <- track })
+
+	done := make(chan struct{})
+	defer close(done)
+	_, publisherClient := publish(t, api, r, "publisher", done)
+	publishedTrack := <-published
+
+	subscriber, err := r.Join("subscriber")
+	assert.NoError(t, err)
+	assert.Len(t, subscriber.Subscriptions(), 1)
+
+	negotiationNeeded := make(chan struct{}, 2)
+	subscriber.OnNegotiationNeeded(func() { negotiationNeeded <- struct{}{} })
+
+	subscriber.SetAudioOnly(true)
+	assert.True(t, subscriber.AudioOnly())
+	assert.Empty(t, subscriber.Subscriptions())
+	<-negotiationNeeded
+
+	// Video can still be asked for
+	_, err = subscriber.Subscribe(publishedTrack)
+	assert.NoError(t, err)
+	<-negotiationNeeded
+	assert.NoError(t, subscriber.Unsubscribe(publishedTrack))
+	<-negotiationNeeded
+
+	subscriber.SetAudioOnly(false)
+	assert.Len(t, subscriber.Subscriptions(), 1)
+	<-negotiationNeeded
+
+	assert.NoError(t, r.Close())
+	assert.NoError(t, publisherClient.Close())
+}
+
+func TestSubscriptionSuspend(t *testing.T) {
+	codec := webrtc.NewRTPVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000)
+	remote, err := webrtc.NewTrack(codec.PayloadType, 1, "video", "pion", codec)
+	assert.NoError(t, err)
+	local, err := webrtc.NewTrack(codec.PayloadType, 2, "video", "pion", codec)
+	assert.NoError(t, err)
+
+	s := &Subscription{
+		track:       &PublishedTrack{remote: remote},
+		local:       local,
+		suspendLoss: DefaultSuspendLoss,
+		resumeAfter: time.Second,
+	}
+
+	now := time.Now()
+	pkt, resumed := s.rewrite(&rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: 10}}, now)
+	assert.NotNil(t, pkt)
+	assert.False(t, resumed)
+	assert.Equal(t, uint32(2), pkt.SSRC)
+	assert.Equal(t, uint16(10), pkt.SequenceNumber)
+
+	// Loss below the threshold is tolerated
+	assert.False(t, s.reportLoss(25, now))
+	assert.True(t, s.reportLoss(128, now))
+	assert.True(t, s.Suspended())
+	assert.False(t, s.reportLoss(128, now))
+
+	pkt, _ = s.rewrite(&rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: 11}}, now)
+	assert.Nil(t, pkt)
+	pkt, _ = s.rewrite(&rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: 12}}, now.Add(time.Second/2))
+	assert.Nil(t, pkt)
+
+	// Forwarding resumes without a gap in the sequence numbers
+	pkt, resumed = s.rewrite(&rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: 13}}, now.Add(time.Second))
+	assert.NotNil(t, pkt)
+	assert.True(t, resumed)
+	assert.False(t, s.Suspended())
+	assert.Equal(t, uint16(11), pkt.SequenceNumber)
+
+	s.paused = true
+	pkt, _ = s.rewrite(&rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: 14}}, now)
+	assert.Nil(t, pkt)
+	assert.True(t, s.Paused())
+}
+
+// vp8Packet returns a packet of a single packet VP8 frame with a 15 bit
+// picture ID, TL0PICIDX and TID
+func vp8Packet(sequenceNumber, pictureID uint16, tl0PICIDX, tid uint8, layerSync bool) *rtp.Packet {
+	tidByte := tid << 6
+	if layerSync {
+		tidByte |= 0x20
+	}
+	return &rtp.Packet{
+		Header:  rtp.Header{SSRC: 1, SequenceNumber: sequenceNumber, Marker: true},
+		Payload: []byte{0x90, 0xE0, 0x80 | byte(pictureID>>8), byte(pictureID), tl0PICIDX, tidByte, 0x01},
+	}
+}
+
+func TestSubscriptionTemporalLayers(t *testing.T) {
+	codec := webrtc.NewRTPVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000)
+	remote, err := webrtc.NewTrack(codec.PayloadType, 1, "video", "pion", codec)
+	assert.NoError(t, err)
+	local, err := webrtc.NewTrack(codec.PayloadType, 2, "video", "pion", codec)
+	assert.NoError(t, err)
+
+	s := &Subscription{
+		track:            &PublishedTrack{remote: remote},
+		local:            local,
+		vp8:              true,
+		maxTemporalLayer: AllTemporalLayers,
+		temporalLayer:    AllTemporalLayers,
+	}
+	now := time.Now()
+
+	// Two temporal layers: TID 0 on even, TID 1 on odd pictures
+	pkt, _ := s.rewrite(vp8Packet(100, 1000, 50, 0, false), now)
+	assert.NotNil(t, pkt)
+	pkt, _ = s.rewrite(vp8Packet(101, 1001, 50, 1, true), now)
+	assert.NotNil(t, pkt)
+
+	s.SetMaxTemporalLayer(0)
+	assert.Equal(t, uint8(0), s.MaxTemporalLayer())
+	pkt, _ = s.rewrite(vp8Packet(102, 1002, 51, 0, false), now)
+	assert.NotNil(t, pkt)
+	pkt, _ = s.rewrite(vp8Packet(103, 1003, 51, 1, true), now)
+	assert.Nil(t, pkt)
+
+	// The picture IDs and sequence numbers stay continuous
+	original := vp8Packet(104, 1004, 52, 0, false)
+	pkt, _ = s.rewrite(original, now)
+	assert.NotNil(t, pkt)
+	assert.Equal(t, uint16(103), pkt.SequenceNumber)
+	d, ok := parseVP8Descriptor(pkt.Payload)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(1003), d.pictureID)
+	assert.Equal(t, uint8(52), d.tl0PICIDX)
+	assert.Equal(t, vp8Packet(104, 1004, 52, 0, false).Payload, original.Payload)
+
+	// Upper layers come back at a layer sync frame
+	s.SetMaxTemporalLayer(AllTemporalLayers)
+	pkt, _ = s.rewrite(vp8Packet(105, 1005, 52, 1, false), now)
+	assert.Nil(t, pkt)
+	pkt, _ = s.rewrite(vp8Packet(106, 1006, 53, 0, false), now)
+	assert.NotNil(t, pkt)
+	pkt, _ = s.rewrite(vp8Packet(107, 1007, 53, 1, true), now)
+	assert.NotNil(t, pkt)
+	d, _ = parseVP8Descriptor(pkt.Payload)
+	assert.Equal(t, uint16(1005), d.pictureID)
+	assert.Equal(t, uint16(105), pkt.SequenceNumber)
+
+	// Dropped base layer pictures shift TL0PICIDX
+	s.paused = true
+	pkt, _ = s.rewrite(vp8Packet(108, 1008, 54, 0, false), now)
+	assert.Nil(t, pkt)
+	s.paused = false
+	pkt, _ = s.rewrite(vp8Packet(109, 1009, 55, 0, false), now)
+	assert.NotNil(t, pkt)
+	d, _ = parseVP8Descriptor(pkt.Payload)
+	assert.Equal(t, uint16(1006), d.pictureID)
+	assert.Equal(t, uint8(54), d.tl0PICIDX)
+}
+
+// memoryWriter is a media.Writer that counts the packets written to it
+type memoryWriter struct {
+	packets chan *rtp.Packet
+	closed  chan struct{}
+}
+
+func newMemoryWriter() *memoryWriter {
+	return &memoryWriter{packets: make(chan *rtp.Packet, 100), closed: make(chan struct{})}
+}
+
+func (w *memoryWriter) WriteRTP(pkt *rtp.Packet) error {
+	select {
+	case w.packets <- pkt:
+	default:
+	}
+	return nil
+}
+
+func (w *memoryWriter) Close() error {
+	select {
+	case <-w.closed:
+	default:
+		close(w.closed)
+	}
+	return nil
+}
+
+func TestRoomRecord(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
+	r := New(Config{MediaEngine: m})
+
+	writers := make(chan *memoryWriter, 1)
+	manifest := &bytes.Buffer{}
+	assert.NoError(t, r.Record(func(track *PublishedTrack) (media.Writer, string, error) {
+		w := newMemoryWriter()
+		writers <- w
+		return w, track.Publisher().ID() + "-" + track.ID() + ".ivf", nil
+	}, manifest))
+	assert.Equal(t, ErrAlreadyRecording, r.Record(nil, nil))
+
+	published := make(chan *PublishedTrack, 1)
+	r.OnTrackPublished(func(track *PublishedTrack) { published <- track })
+
+	done := make(chan struct{})
+	defer close(done)
+	publisher, publisherClient := publish(t, api, r, "publisher", done)
+	publishedTrack := <-published
+
+	// A track can be recorded by more than one writer
+	single := newMemoryWriter()
+	recording, err := publishedTrack.Record(single)
+	assert.NoError(t, err)
+	<-single.packets
+	assert.NoError(t, recording.Stop())
+	<-single.closed
+
+	w := <-writers
+	pkt := <-w.packets
+	assert.Equal(t, uint8(webrtc.DefaultPayloadTypeVP8), pkt.PayloadType)
+
+	// The recording ends with the track
+	assert.NoError(t, publisher.Leave())
+	<-w.closed
+	_, err = publishedTrack.Record(newMemoryWriter())
+	assert.Equal(t, ErrTrackEnded, err)
+
+	decoder := json.NewDecoder(manifest)
+	var start, stop ManifestEntry
+	assert.NoError(t, decoder.Decode(&start))
+	assert.NoError(t, decoder.Decode(&stop))
+	assert.Equal(t, ManifestEventStart, start.Event)
+	assert.Equal(t, ManifestEventStop, stop.Event)
+	assert.Equal(t, "publisher-video.ivf", start.Name)
+	assert.Equal(t, "publisher", start.Participant)
+	assert.Equal(t, "video", start.Track)
+	assert.Equal(t, webrtc.RTPCodecTypeVideo, start.Kind)
+	assert.Equal(t, "video/VP8", start.MimeType)
+	assert.Equal(t, uint32(90000), start.ClockRate)
+	assert.False(t, stop.Time.Before(start.Time))
+
+	assert.NoError(t, r.Close())
+	assert.NoError(t, publisherClient.Close())
+}
+
+func TestRoomRecordSegmented(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
+	r := New(Config{MediaEngine: m})
+
+	writers := make(chan *memoryWriter, 100)
+	segments := make(chan ManifestEntry, 100)
+	manifest := &bytes.Buffer{}
+	var count int32
+	assert.NoError(t, r.RecordSegmented(func(track *PublishedTrack) (media.Writer, string, error) {
+		w := newMemoryWriter()
+		select {
+		case writers <- w:
+		default:
+		}
+		return w, fmt.Sprintf("%s-%d.ivf", track.ID(), atomic.AddInt32(&count, 1)), nil
+	}, manifest, Rotation{
+		Duration: 100 * time.Millisecond,
+		OnSegment: func(entry ManifestEntry) {
+			select {
+			case segments <- entry:
+			default:
+			}
+		},
+	}))
+
+	done := make(chan struct{})
+	defer close(done)
+	publisher, publisherClient := publish(t, api, r, "publisher", done)
+
+	// Every segment is closed before the next one is handed out
+	first := <-writers
+	<-first.packets
+	second := <-writers
+	<-first.closed
+	segment := <-segments
+	assert.Equal(t, "video-1.ivf", segment.Name)
+	assert.Equal(t, ManifestEventStop, segment.Event)
+	assert.Equal(t, 0, segment.Segment)
+	<-second.packets
+
+	// The last segment ends with the track
+	assert.NoError(t, publisher.Leave())
+	assert.NoError(t, r.StopRecording())
+	var last ManifestEntry
+	for len(segments) != 0 {
+		last = <-segments
+	}
+	assert.True(t, last.Segment > 0)
+
+	// Every segment has a start and a stop entry
+	decoder := json.NewDecoder(manifest)
+	events := map[string][]string{}
+	for decoder.More() {
+		var entry ManifestEntry
+		assert.NoError(t, decoder.Decode(&entry))
+		events[entry.Name] = append(events[entry.Name], entry.Event)
+	}
+	for name, e := range events {
+		assert.Equal(t, []string{ManifestEventStart, ManifestEventStop}, e, name)
+	}
+
+	assert.NoError(t, r.Close())
+	assert.NoError(t, publisherClient.Close())
+}
+
+func TestRoomLazyForwarding(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
+	r := New(Config{MediaEngine: m, LazyForwarding: true})
+
+	published := make(chan *PublishedTrack, 1)
+	r.OnTrackPublished(func(track *PublishedTrack) { published <- track })
+	idle := make(chan bool, 2)
+	r.OnTrackIdle(func(_ *PublishedTrack, isIdle bool) { idle <- isIdle })
+
+	// Nobody receives the track yet
+	done := make(chan struct{})
+	defer close(done)
+	_, publisherClient := publish(t, api, r, "publisher", done)
+	publishedTrack := <-published
+	assert.True(t, publishedTrack.Idle())
+
+	// The track is forwarded once the sender of the subscriber starts
+	subscriber, err := r.Join("subscriber")
+	assert.NoError(t, err)
+	subscriberClient, err := api.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	_, err = subscriberClient.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	received := make(chan struct{}, 1)
+	subscriberClient.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+		if _, readErr := track.ReadRTP(); readErr == nil {
+			received <- struct{}{}
+		}
+	})
+	assert.True(t, publishedTrack.Idle())
+	negotiate(t, subscriber, subscriberClient)
+
+	assert.False(t, <-idle)
+	assert.False(t, publishedTrack.Idle())
+	<-received
+
+	// Unsubscribing the last subscriber makes the track idle again
+	assert.NoError(t, subscriber.Unsubscribe(publishedTrack))
+	assert.True(t, <-idle)
+	assert.True(t, publishedTrack.Idle())
+
+	assert.NoError(t, r.Close())
+	assert.NoError(t, publisherClient.Close())
+	assert.NoError(t, subscriberClient.Close())
+}
+
+func TestParticipantUpdate(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
+	r := New(Config{MediaEngine: m, ManualSubscription: true})
+
+	published := make(chan *PublishedTrack, 2)
+	r.OnTrackPublished(func(track *PublishedTrack) { published <- track })
+
+	done := make(chan struct{})
+	defer close(done)
+	_, firstClient := publish(t, api, r, "first", done)
+	_, secondClient := publish(t, api, r, "second", done)
+	tracks := []*PublishedTrack{<-published, <-published}
+
+	subscriber, err := r.Join("subscriber")
+	assert.NoError(t, err)
+	var negotiations int32
+	subscriber.OnNegotiationNeeded(func() { atomic.AddInt32(&negotiations, 1) })
+
+	// Both subscriptions are negotiated at once, the error of f is returned
+	errUpdate := fmt.Errorf("update failed")
+	assert.Equal(t, errUpdate, subscriber.Update(func() error {
+		for _, track := range tracks {
+			if _, subscribeErr := subscriber.Subscribe(track); subscribeErr != nil {
+				return subscribeErr
+			}
+		}
+		assert.Equal(t, int32(0), atomic.LoadInt32(&negotiations))
+		return errUpdate
+	}))
+	assert.Len(t, subscriber.Subscriptions(), 2)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&negotiations))
+
+	// Nested updates negotiate once the outer one returns
+	assert.NoError(t, subscriber.Update(func() error {
+		return subscriber.Update(func() error {
+			for _, track := range tracks {
+				if unsubscribeErr := subscriber.Unsubscribe(track); unsubscribeErr != nil {
+					return unsubscribeErr
+				}
+			}
+			return nil
+		})
+	}))
+	assert.Empty(t, subscriber.Subscriptions())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&negotiations))
+
+	// A panicking update still negotiates its changes and doesn't hold back
+	// later ones
+	assert.Panics(t, func() {
+		_ = subscriber.Update(func() error {
+			if _, subscribeErr := subscriber.Subscribe(tracks[0]); subscribeErr != nil {
+				return subscribeErr
+			}
+			panic("update panicked")
+		})
+	})
+	assert.Equal(t, int32(3), atomic.LoadInt32(&negotiations))
+	assert.NoError(t, subscriber.Unsubscribe(tracks[0]))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&negotiations))
+
+	assert.NoError(t, r.Close())
+	assert.NoError(t, firstClient.Close())
+	assert.NoError(t, secondClient.Close())
+}
@@ -0,0 +1,262 @@
+// +build !js
+
+package room
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+// Subscription forwards a PublishedTrack to a subscriber. The subscriber
+// receives it on a Track of its own, which allows to pause or suspend it
+// without affecting the other subscribers.
+type Subscription struct {
+	track      *PublishedTrack
+	subscriber *Participant
+	local      *webrtc.Track
+	sender     *webrtc.RTPSender
+
+	suspendLoss float64
+	resumeAfter time.Duration
+
+	mu     sync.Mutex
+	paused bool
+	// suspendedAt is zero unless forwarding is suspended because of loss
+	suspendedAt time.Time
+	// dropped counts the packets that weren't forwarded, the sequence
+	// numbers are shifted by it to hide the gap from the subscriber
+	dropped uint16
+
+	// vp8 subscriptions can drop temporal layers. temporalLayer is the
+	// highest layer forwarded, it rises to maxTemporalLayer at the next
+	// layer sync or key frame.
+	vp8              bool
+	maxTemporalLayer uint8
+	temporalLayer    uint8
+
+	// picture is the picture ID of the last VP8 packet, dropPicture tells
+	// if its packets are dropped. The picture IDs and TL0PICIDX forwarded
+	// are shifted by the dropped pictures, like the sequence numbers.
+	hasPicture     bool
+	picture        uint16
+	dropPicture    bool
+	pictureIDShift uint16
+	tl0PICIDXShift uint8
+}
+
+// Track returns the track the subscription forwards
+func (s *Subscription) Track() *PublishedTrack {
+	return s.track
+}
+
+// Subscriber returns the participant the track is forwarded to
+func (s *Subscription) Subscriber() *Participant {
+	return s.subscriber
+}
+
+// Sender returns the RTPSender of the subscriber's PeerConnection that sends
+// the track
+func (s *Subscription) Sender() *webrtc.RTPSender {
+	return s.sender
+}
+
+// SetPaused stops or restarts forwarding, e.g. when the subscriber doesn't
+// display the track. A key frame is requested when forwarding restarts.
+func (s *Subscription) SetPaused(paused bool) {
+	s.mu.Lock()
+	resumed := s.paused && !paused
+	s.paused = paused
+	s.mu.Unlock()
+
+	if resumed {
+		s.track.requestKeyFrame()
+	}
+}
+
+// SetMaxTemporalLayer limits the temporal layers of VP8 video that are
+// forwarded, e.g. 0 only forwards the base layer, which halves the bitrate of
+// a stream with two layers without waiting for a key frame. Lowering the
+// limit takes effect at once, upper layers are forwarded again from their
+// next layer sync or key frame. Video without temporal layers isn't affected.
+func (s *Subscription) SetMaxTemporalLayer(layer uint8) {
+	if layer > AllTemporalLayers {
+		layer = AllTemporalLayers
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxTemporalLayer = layer
+	if s.temporalLayer > layer {
+		s.temporalLayer = layer
+	}
+}
+
+// MaxTemporalLayer returns the limit set with SetMaxTemporalLayer
+func (s *Subscription) MaxTemporalLayer() uint8 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxTemporalLayer
+}
+
+// Paused tells if forwarding was stopped with SetPaused
+func (s *Subscription) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// Suspended tells if forwarding is suspended because the subscriber reported
+// high loss
+func (s *Subscription) Suspended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.suspendedAt.IsZero()
+}
+
+// write forwards a packet of the published track
+func (s *Subscription) write(pkt *rtp.Packet, now time.Time) {
+	out, resumed := s.rewrite(pkt, now)
+	if resumed {
+		s.track.requestKeyFrame()
+		if _, _, onSuspended := s.track.publisher.room.handlers(); onSuspended != nil {
+			onSuspended(s, false)
+		}
+	}
+	if out == nil {
+		return
+	}
+
+	if err := s.local.WriteRTP(out); err != nil && err != io.ErrClosedPipe {
+		s.track.publisher.room.log.Debugf("failed to forward %s to %s: %v", s.track.ID(), s.subscriber.id, err)
+	}
+}
+
+// rewrite decides if a packet is forwarded and moves it to the SSRC and
+// sequence numbers of the subscription. It returns nil if the packet is
+// dropped, resumed is true when a suspension ended. The packet is shared with
+// the other subscriptions, the header is copied and the payload only when it
+// changes.
+func (s *Subscription) rewrite(pkt *rtp.Packet, now time.Time) (_ *rtp.Packet, resumed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.suspendedAt.IsZero() && now.Sub(s.suspendedAt) >= s.resumeAfter {
+		s.suspendedAt = time.Time{}
+		resumed = true
+	}
+	drop := s.paused || !s.suspendedAt.IsZero()
+
+	var descriptor vp8Descriptor
+	vp8 := false
+	if s.vp8 {
+		descriptor, vp8 = parseVP8Descriptor(pkt.Payload)
+	}
+	if vp8 {
+		drop = s.dropVP8(descriptor, drop)
+	}
+
+	if drop {
+		s.dropped++
+		return nil, resumed
+	}
+
+	out := &rtp.Packet{Header: pkt.Header, Payload: pkt.Payload}
+	out.SSRC = s.local.SSRC()
+	out.SequenceNumber -= s.dropped
+	if vp8 && (s.pictureIDShift != 0 || s.tl0PICIDXShift != 0) {
+		pictureID := (descriptor.pictureID - s.pictureIDShift) & descriptor.pictureIDMask()
+		out.Payload = descriptor.rewrite(pkt.Payload, pictureID, descriptor.tl0PICIDX-s.tl0PICIDXShift)
+	}
+	return out, resumed
+}
+
+// dropVP8 decides if a VP8 packet is dropped, drop tells if it would be
+// dropped for other reasons. All packets of a picture share the decision of
+// its first one, every dropped picture shifts the picture IDs that follow.
+func (s *Subscription) dropVP8(d vp8Descriptor, drop bool) bool {
+	if d.hasPictureID && s.hasPicture && d.pictureID == s.picture {
+		return s.dropPicture
+	}
+
+	if d.hasTID && d.start {
+		switch {
+		case d.keyFrame:
+			s.temporalLayer = s.maxTemporalLayer
+		case d.layerSync && d.tid > s.temporalLayer && d.tid <= s.maxTemporalLayer:
+			s.temporalLayer = d.tid
+		}
+	}
+	drop = drop || (d.hasTID && d.tid > s.temporalLayer)
+
+	if d.hasPictureID {
+		s.hasPicture = true
+		s.picture = d.pictureID
+		s.dropPicture = drop
+		if drop {
+			s.pictureIDShift++
+			if d.hasTL0PICIDX && (!d.hasTID || d.tid == 0) {
+				s.tl0PICIDXShift++
+			}
+		}
+	}
+	return drop
+}
+
+// reportLoss handles the fraction of packets lost by the subscriber, as
+// carried by receiver reports, and returns true if it suspends forwarding
+func (s *Subscription) reportLoss(fractionLost uint8, now time.Time) bool {
+	if s.track.Kind() != webrtc.RTPCodecTypeVideo || s.suspendLoss < 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.suspendedAt.IsZero() || float64(fractionLost)/256 <= s.suspendLoss {
+		return false
+	}
+	s.suspendedAt = now
+	return true
+}
+
+// readRTCP handles the feedback of the subscriber until the sender stops
+func (s *Subscription) readRTCP() {
+	for {
+		pkts, err := s.sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		for _, pkt := range pkts {
+			switch pkt := pkt.(type) {
+			case *rtcp.PictureLossIndication:
+				s.track.requestKeyFrame()
+			case *rtcp.ReceiverReport:
+				for _, report := range pkt.Reports {
+					if report.SSRC != s.local.SSRC() || !s.reportLoss(report.FractionLost, time.Now()) {
+						continue
+					}
+					if _, _, onSuspended := s.track.publisher.room.handlers(); onSuspended != nil {
+						onSuspended(s, true)
+					}
+				}
+			}
+		}
+	}
+}
+
+// stop removes the track from the subscriber's PeerConnection
+func (s *Subscription) stop() {
+	for _, transceiver := range s.subscriber.pc.GetTransceivers() {
+		if transceiver.Sender != s.sender {
+			continue
+		}
+		if err := transceiver.Stop(); err != nil {
+			s.track.publisher.room.log.Debugf("failed to stop the transceiver of %s: %v", s.track.ID(), err)
+		}
+		return
+	}
+}
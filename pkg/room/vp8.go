@@ -0,0 +1,115 @@
+// +build !js
+
+package room
+
+// vp8Descriptor is the VP8 payload descriptor of a packet, RFC 7741 Section
+// 4.2, with the offsets of the fields the forwarder rewrites
+type vp8Descriptor struct {
+	// start is set on the first packet of a frame
+	start    bool
+	keyFrame bool
+
+	hasPictureID    bool
+	longPictureID   bool
+	pictureID       uint16
+	pictureIDOffset int
+
+	hasTL0PICIDX    bool
+	tl0PICIDX       uint8
+	tl0PICIDXOffset int
+
+	// tid is the temporal layer of the frame, layerSync tells that it only
+	// depends on frames of temporal layer 0
+	hasTID    bool
+	tid       uint8
+	layerSync bool
+}
+
+// parseVP8Descriptor parses the payload descriptor at the start of a VP8 RTP
+// payload, it returns false if the payload is too short
+func parseVP8Descriptor(payload []byte) (d vp8Descriptor, ok bool) {
+	if len(payload) < 1 {
+		return d, false
+	}
+	d.start = payload[0]&0x10 != 0 && payload[0]&0x07 == 0
+
+	offset := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 2 {
+			return d, false
+		}
+		ext := payload[1]
+		offset++
+
+		if ext&0x80 != 0 {
+			if len(payload) <= offset {
+				return d, false
+			}
+			d.hasPictureID = true
+			d.pictureIDOffset = offset
+			if payload[offset]&0x80 != 0 {
+				if len(payload) <= offset+1 {
+					return d, false
+				}
+				d.longPictureID = true
+				d.pictureID = uint16(payload[offset]&0x7F)<<8 | uint16(payload[offset+1])
+				offset += 2
+			} else {
+				d.pictureID = uint16(payload[offset])
+				offset++
+			}
+		}
+		if ext&0x40 != 0 {
+			if len(payload) <= offset {
+				return d, false
+			}
+			d.hasTL0PICIDX = true
+			d.tl0PICIDXOffset = offset
+			d.tl0PICIDX = payload[offset]
+			offset++
+		}
+		if ext&0x30 != 0 {
+			if len(payload) <= offset {
+				return d, false
+			}
+			if ext&0x20 != 0 {
+				d.hasTID = true
+				d.tid = payload[offset] >> 6
+				d.layerSync = payload[offset]&0x20 != 0
+			}
+			offset++
+		}
+	}
+
+	// The P bit of the VP8 payload header is zero on key frames
+	if d.start && len(payload) > offset {
+		d.keyFrame = payload[offset]&0x01 == 0
+	}
+	return d, true
+}
+
+// rewrite returns a copy of payload with the picture ID and TL0PICIDX of the
+// descriptor replaced
+func (d vp8Descriptor) rewrite(payload []byte, pictureID uint16, tl0PICIDX uint8) []byte {
+	out := append([]byte{}, payload...)
+	if d.hasPictureID {
+		if d.longPictureID {
+			out[d.pictureIDOffset] = 0x80 | byte(pictureID>>8)&0x7F
+			out[d.pictureIDOffset+1] = byte(pictureID)
+		} else {
+			out[d.pictureIDOffset] = byte(pictureID) & 0x7F
+		}
+	}
+	if d.hasTL0PICIDX {
+		out[d.tl0PICIDXOffset] = tl0PICIDX
+	}
+	return out
+}
+
+// pictureIDMask returns the bits a picture ID of the descriptor has
+func (d vp8Descriptor) pictureIDMask() uint16 {
+	if d.longPictureID {
+		return 0x7FFF
+	}
+	return 0x7F
+}
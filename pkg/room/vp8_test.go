@@ -0,0 +1,43 @@
+// +build !js
+
+package room
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVP8Descriptor(t *testing.T) {
+	// 7 bit picture ID, TL0PICIDX, TID 2 with layer sync, key frame
+	d, ok := parseVP8Descriptor([]byte{0x90, 0xE0, 0x12, 0x34, 0xA0, 0x00})
+	assert.True(t, ok)
+	assert.True(t, d.start)
+	assert.True(t, d.keyFrame)
+	assert.False(t, d.longPictureID)
+	assert.Equal(t, uint16(0x12), d.pictureID)
+	assert.Equal(t, uint8(0x34), d.tl0PICIDX)
+	assert.True(t, d.hasTID)
+	assert.Equal(t, uint8(2), d.tid)
+	assert.True(t, d.layerSync)
+
+	// 15 bit picture ID in a packet that continues a frame
+	d, ok = parseVP8Descriptor([]byte{0x80, 0x80, 0x81, 0x02, 0x01})
+	assert.True(t, ok)
+	assert.False(t, d.start)
+	assert.True(t, d.longPictureID)
+	assert.Equal(t, uint16(0x102), d.pictureID)
+	assert.False(t, d.hasTID)
+	assert.Equal(t, []byte{0x80, 0x80, 0xFF, 0xFF, 0x01}, d.rewrite([]byte{0x80, 0x80, 0x81, 0x02, 0x01}, 0x7FFF, 0))
+
+	// No extensions
+	d, ok = parseVP8Descriptor([]byte{0x10, 0x01})
+	assert.True(t, ok)
+	assert.False(t, d.keyFrame)
+	assert.False(t, d.hasPictureID)
+
+	for _, payload := range [][]byte{{}, {0x80}, {0x80, 0x80}, {0x80, 0x80, 0x80}, {0x80, 0x40}, {0x80, 0x20}} {
+		_, ok = parseVP8Descriptor(payload)
+		assert.False(t, ok, payload)
+	}
+}
@@ -0,0 +1,79 @@
+// Package sockopt sets kernel buffer sizes, DSCP marking and address reuse on
+// UDP sockets used for media.
+//
+// The sockets of a PeerConnection are created by the ICE agent of pion/ice,
+// which doesn't allow to inject sockets or options yet, so these options can
+// only be applied to sockets created by the application, e.g. with ListenUDP.
+package sockopt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// DSCP code points recommended for WebRTC by RFC 8837
+const (
+	DSCPDefault = 0
+	// DSCPAF41 is used for interactive video
+	DSCPAF41 = 34
+	// DSCPEF is used for interactive audio
+	DSCPEF = 46
+)
+
+// ErrUnsupported indicates that socket options can't be set on this platform
+var ErrUnsupported = errors.New("sockopt: not supported on this platform")
+
+// Options are the options applied to a socket, zero values leave the
+// defaults of the operating system
+type Options struct {
+	// ReceiveBufferSize sets SO_RCVBUF in bytes
+	ReceiveBufferSize int
+	// SendBufferSize sets SO_SNDBUF in bytes
+	SendBufferSize int
+	// DSCP sets the differentiated services code point of sent packets, e.g.
+	// DSCPEF. It is shifted into the TOS and traffic class fields.
+	DSCP int
+	// ReuseAddress sets SO_REUSEADDR, it only has an effect before binding,
+	// see ListenUDP
+	ReuseAddress bool
+}
+
+// Apply sets the options on conn, e.g. a *net.UDPConn
+func (o Options) Apply(conn syscall.Conn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return o.control(raw)
+}
+
+func (o Options) control(raw syscall.RawConn) error {
+	var setErr error
+	if err := raw.Control(func(fd uintptr) {
+		setErr = o.set(fd)
+	}); err != nil {
+		return err
+	}
+	return setErr
+}
+
+// ListenUDP creates a UDP socket with the options applied before it is bound
+func ListenUDP(network string, laddr *net.UDPAddr, o Options) (*net.UDPConn, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, raw syscall.RawConn) error {
+			return o.control(raw)
+		},
+	}
+
+	address := ""
+	if laddr != nil {
+		address = laddr.String()
+	}
+	conn, err := lc.ListenPacket(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
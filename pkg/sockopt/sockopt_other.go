@@ -0,0 +1,7 @@
+// +build !linux,!darwin
+
+package sockopt
+
+func (o Options) set(fd uintptr) error {
+	return ErrUnsupported
+}
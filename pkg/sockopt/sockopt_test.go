@@ -0,0 +1,48 @@
+// +build linux
+
+package sockopt
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getsockopt(t *testing.T, conn *net.UDPConn, level, opt int) int {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var value int
+	var getErr error
+	assert.NoError(t, raw.Control(func(fd uintptr) {
+		value, getErr = syscall.GetsockoptInt(int(fd), level, opt)
+	}))
+	assert.NoError(t, getErr)
+	return value
+}
+
+func TestListenUDP(t *testing.T) {
+	conn, err := ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}, Options{
+		ReceiveBufferSize: 64 * 1024,
+		DSCP:              DSCPEF,
+		ReuseAddress:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Linux doubles the requested buffer size for bookkeeping
+	assert.True(t, getsockopt(t, conn, syscall.SOL_SOCKET, syscall.SO_RCVBUF) >= 64*1024)
+	assert.Equal(t, DSCPEF<<2, getsockopt(t, conn, syscall.IPPROTO_IP, syscall.IP_TOS))
+	assert.Equal(t, 1, getsockopt(t, conn, syscall.SOL_SOCKET, syscall.SO_REUSEADDR))
+
+	// Options can be changed after binding as well
+	assert.NoError(t, Options{DSCP: DSCPAF41}.Apply(conn))
+	assert.Equal(t, DSCPAF41<<2, getsockopt(t, conn, syscall.IPPROTO_IP, syscall.IP_TOS))
+
+	assert.NoError(t, conn.Close())
+}
@@ -0,0 +1,37 @@
+// +build linux darwin
+
+package sockopt
+
+import (
+	"syscall"
+)
+
+func (o Options) set(fd uintptr) error {
+	s := int(fd)
+
+	if o.ReuseAddress {
+		if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
+			return err
+		}
+	}
+	if o.ReceiveBufferSize > 0 {
+		if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_RCVBUF, o.ReceiveBufferSize); err != nil {
+			return err
+		}
+	}
+	if o.SendBufferSize > 0 {
+		if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_SNDBUF, o.SendBufferSize); err != nil {
+			return err
+		}
+	}
+	if o.DSCP > 0 {
+		// The socket may be IPv4 or IPv6, only one of them is expected to work
+		tos := o.DSCP << 2
+		errV4 := syscall.SetsockoptInt(s, syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+		errV6 := syscall.SetsockoptInt(s, syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, tos)
+		if errV4 != nil && errV6 != nil {
+			return errV4
+		}
+	}
+	return nil
+}
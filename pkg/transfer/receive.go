@@ -0,0 +1,87 @@
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// File is the destination of a transfer
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// OpenFunc is called by Receive once the metadata of a file arrived. It
+// returns where to write the file and how many bytes of it are already
+// there, which is where the transfer resumes. Returning an error rejects the
+// transfer.
+type OpenFunc func(metadata Metadata) (f File, offset int64, err error)
+
+// Receive waits for a file sent over ch with Send and blocks until it was
+// received and verified
+func Receive(ch Channel, open OpenFunc) (*Metadata, error) {
+	in := newInbox(ch)
+	defer in.stop()
+
+	offer, err := expect(in, messageTypeOffer)
+	if err != nil {
+		return nil, err
+	}
+	if offer.Metadata == nil {
+		return nil, ErrUnexpectedMessage
+	}
+	metadata := offer.Metadata
+
+	f, offset, err := open(*metadata)
+	if err == nil && (offset < 0 || offset > metadata.Size) {
+		err = ErrInvalidOffset
+	}
+	if err != nil {
+		return nil, reject(ch, err)
+	}
+	if err = sendControl(ch, controlMessage{Type: messageTypeAccept, Offset: offset}); err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, m, err := in.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if m != nil {
+			if m.Type != messageTypeDone {
+				return nil, reject(ch, ErrUnexpectedMessage)
+			}
+			break
+		}
+
+		chunkOffset, data, err := unmarshalChunk(msg.Data)
+		if err != nil {
+			return nil, reject(ch, err)
+		}
+		if chunkOffset+int64(len(data)) > metadata.Size {
+			return nil, reject(ch, ErrInvalidOffset)
+		}
+		if _, err = f.WriteAt(data, chunkOffset); err != nil {
+			return nil, reject(ch, err)
+		}
+	}
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, io.NewSectionReader(f, 0, metadata.Size)); err != nil {
+		return nil, reject(ch, err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != metadata.SHA256 {
+		return nil, reject(ch, ErrChecksumMismatch)
+	}
+
+	return metadata, sendControl(ch, controlMessage{Type: messageTypeComplete})
+}
+
+// reject tells the sender why the transfer failed and returns err
+func reject(ch Channel, err error) error {
+	_ = sendControl(ch, controlMessage{Type: messageTypeError, Error: err.Error()})
+	return err
+}
@@ -0,0 +1,82 @@
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// Send transfers size bytes of content over ch and blocks until the receiver
+// verified the file. Only the part of the file the receiver doesn't have yet
+// is sent.
+func Send(ch Channel, name string, content io.ReaderAt, size int64) error {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(content, 0, size)); err != nil {
+		return err
+	}
+	metadata := &Metadata{Name: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}
+
+	drained := make(chan struct{}, 1)
+	ch.SetBufferedAmountLowThreshold(MaxBufferedAmount / 2)
+	ch.OnBufferedAmountLow(func() {
+		select {
+		case drained <- struct{}{}:
+		default:
+		}
+	})
+
+	in := newInbox(ch)
+	defer in.stop()
+
+	if err := sendControl(ch, controlMessage{Type: messageTypeOffer, Metadata: metadata}); err != nil {
+		return err
+	}
+
+	accept, err := expect(in, messageTypeAccept)
+	if err != nil {
+		return err
+	}
+	if accept.Offset < 0 || accept.Offset > size {
+		_ = sendControl(ch, controlMessage{Type: messageTypeError, Error: ErrInvalidOffset.Error()})
+		return ErrInvalidOffset
+	}
+
+	buf := make([]byte, ChunkSize)
+	for offset := accept.Offset; offset < size; {
+		n, err := content.ReadAt(buf, offset)
+		if n == 0 && err != nil {
+			return err
+		}
+
+		for ch.BufferedAmount() > MaxBufferedAmount {
+			select {
+			case <-drained:
+			case <-in.closed:
+				return ErrChannelClosed
+			}
+		}
+
+		if err := ch.Send(marshalChunk(offset, buf[:n])); err != nil {
+			return err
+		}
+		offset += int64(n)
+	}
+
+	if err := sendControl(ch, controlMessage{Type: messageTypeDone}); err != nil {
+		return err
+	}
+	_, err = expect(in, messageTypeComplete)
+	return err
+}
+
+// expect returns the next message if it is a control message of type t
+func expect(in *inbox, t string) (*controlMessage, error) {
+	_, m, err := in.next()
+	if err != nil {
+		return nil, err
+	}
+	if m == nil || m.Type != t {
+		return nil, ErrUnexpectedMessage
+	}
+	return m, nil
+}
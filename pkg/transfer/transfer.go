@@ -0,0 +1,176 @@
+// Package transfer sends files over a DataChannel. Files are split into
+// chunks that carry their offset and a CRC32, the whole file is verified with
+// SHA-256 once it arrived. A receiver that already has the beginning of a
+// file can resume the transfer from there.
+//
+// The sender only queues a chunk if the buffered amount of the channel is
+// below MaxBufferedAmount, so memory use stays bounded no matter the size of
+// the file. The channel should be ordered and reliable, which is the default
+// for PeerConnection.CreateDataChannel.
+package transfer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+
+	"github.com/pion/webrtc/v2"
+)
+
+const (
+	// ChunkSize is the amount of file data in every binary message
+	ChunkSize = 16 * 1024
+
+	// MaxBufferedAmount is the buffered amount of the channel the sender
+	// waits to drain before queuing more chunks
+	MaxBufferedAmount = 1024 * 1024
+
+	// chunkHeaderSize is the size of the offset and the CRC32 preceding the
+	// data of every chunk
+	chunkHeaderSize = 8 + 4
+)
+
+var (
+	// ErrChecksumMismatch indicates that a chunk or the complete file didn't
+	// match its checksum
+	ErrChecksumMismatch = errors.New("transfer: checksum mismatch")
+
+	// ErrChannelClosed indicates that the channel closed before the transfer
+	// finished
+	ErrChannelClosed = errors.New("transfer: channel closed")
+
+	// ErrInvalidOffset indicates that the receiver has more of the file than
+	// the sender offers
+	ErrInvalidOffset = errors.New("transfer: invalid offset")
+
+	// ErrUnexpectedMessage indicates that the remote side doesn't follow the
+	// transfer protocol
+	ErrUnexpectedMessage = errors.New("transfer: unexpected message")
+)
+
+// Channel is the part of a *webrtc.DataChannel used for transfers
+type Channel interface {
+	Send(data []byte) error
+	SendText(s string) error
+	OnMessage(f func(msg webrtc.DataChannelMessage))
+	OnClose(f func())
+	BufferedAmount() uint64
+	SetBufferedAmountLowThreshold(th uint64)
+	OnBufferedAmountLow(f func())
+}
+
+// Metadata describes the file being transferred
+type Metadata struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// SHA256 is the hex encoded hash of the complete file
+	SHA256 string `json:"sha256"`
+}
+
+// Control messages are sent as text, chunks as binary
+const (
+	messageTypeOffer    = "offer"
+	messageTypeAccept   = "accept"
+	messageTypeDone     = "done"
+	messageTypeComplete = "complete"
+	messageTypeError    = "error"
+)
+
+type controlMessage struct {
+	Type     string    `json:"type"`
+	Metadata *Metadata `json:"metadata,omitempty"`
+	Offset   int64     `json:"offset,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func sendControl(ch Channel, m controlMessage) error {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return ch.SendText(string(raw))
+}
+
+func marshalChunk(offset int64, data []byte) []byte {
+	chunk := make([]byte, chunkHeaderSize+len(data))
+	binary.BigEndian.PutUint64(chunk, uint64(offset))
+	binary.BigEndian.PutUint32(chunk[8:], crc32.ChecksumIEEE(data))
+	copy(chunk[chunkHeaderSize:], data)
+	return chunk
+}
+
+func unmarshalChunk(chunk []byte) (int64, []byte, error) {
+	if len(chunk) < chunkHeaderSize {
+		return 0, nil, ErrUnexpectedMessage
+	}
+	offset := int64(binary.BigEndian.Uint64(chunk))
+	data := chunk[chunkHeaderSize:]
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(chunk[8:]) {
+		return 0, nil, ErrChecksumMismatch
+	}
+	return offset, data, nil
+}
+
+// inbox hands the messages of a channel to the goroutine running a transfer
+type inbox struct {
+	messages chan webrtc.DataChannelMessage
+	closed   chan struct{}
+	done     chan struct{}
+}
+
+func newInbox(ch Channel) *inbox {
+	i := &inbox{
+		messages: make(chan webrtc.DataChannelMessage),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	ch.OnMessage(func(msg webrtc.DataChannelMessage) {
+		select {
+		case i.messages <- msg:
+		case <-i.done:
+		}
+	})
+	ch.OnClose(func() {
+		select {
+		case <-i.closed:
+		default:
+			close(i.closed)
+		}
+	})
+	return i
+}
+
+// next returns the next message, control messages are decoded
+func (i *inbox) next() (webrtc.DataChannelMessage, *controlMessage, error) {
+	select {
+	case msg := <-i.messages:
+		if !msg.IsString {
+			return msg, nil, nil
+		}
+		m := &controlMessage{}
+		if err := json.Unmarshal(msg.Data, m); err != nil {
+			return msg, nil, ErrUnexpectedMessage
+		}
+		if m.Type == messageTypeError {
+			return msg, nil, &RemoteError{Message: m.Error}
+		}
+		return msg, m, nil
+	case <-i.closed:
+		return webrtc.DataChannelMessage{}, nil, ErrChannelClosed
+	}
+}
+
+// stop releases message handlers blocked on a finished transfer
+func (i *inbox) stop() {
+	close(i.done)
+}
+
+// RemoteError is an error reported by the other side of a transfer
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return "transfer: remote error: " + e.Message
+}
@@ -0,0 +1,81 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/pion/webrtc/v2/internal/fakechannel"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryFile is a File backed by a byte slice
+type memoryFile struct {
+	data []byte
+}
+
+func (f *memoryFile) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(f.data).ReadAt(p, off)
+}
+
+func (f *memoryFile) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	return copy(f.data[off:], p), nil
+}
+
+func TestSendReceive(t *testing.T) {
+	content := make([]byte, 5*ChunkSize+123)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		name   string
+		offset int64
+	}{
+		{"full", 0},
+		{"resume", 2*ChunkSize + 7},
+		{"complete", int64(len(content))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sender, receiver := fakechannel.NewPair()
+			dst := &memoryFile{data: append([]byte{}, content[:test.offset]...)}
+
+			sendErr := make(chan error)
+			go func() {
+				sendErr <- Send(sender, "file.bin", bytes.NewReader(content), int64(len(content)))
+			}()
+
+			metadata, err := Receive(receiver, func(m Metadata) (File, int64, error) {
+				return dst, int64(len(dst.data)), nil
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, <-sendErr)
+			assert.Equal(t, "file.bin", metadata.Name)
+			assert.Equal(t, content, dst.data)
+		})
+	}
+}
+
+func TestReceive_ChecksumMismatch(t *testing.T) {
+	content := []byte("the content of the file")
+	sender, receiver := fakechannel.NewPair()
+
+	// The receiver claims to have a prefix that doesn't match the file
+	dst := &memoryFile{data: []byte("wrong")}
+
+	sendErr := make(chan error)
+	go func() {
+		sendErr <- Send(sender, "file.txt", bytes.NewReader(content), int64(len(content)))
+	}()
+
+	_, err := Receive(receiver, func(m Metadata) (File, int64, error) {
+		return dst, int64(len(dst.data)), nil
+	})
+	assert.Equal(t, ErrChecksumMismatch, err)
+
+	remoteErr, ok := (<-sendErr).(*RemoteError)
+	assert.True(t, ok)
+	assert.Equal(t, ErrChecksumMismatch.Error(), remoteErr.Message)
+}
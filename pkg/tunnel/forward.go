@@ -0,0 +1,106 @@
+// +build !js
+
+package tunnel
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// ForwardTCP accepts connections on l and forwards each of them to address
+// on the other peer, which has to run Serve or Handle. It blocks until l is
+// closed.
+func ForwardTCP(pc *webrtc.PeerConnection, l net.Listener, address string) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			d, err := openChannel(pc, "tcp", address)
+			if err != nil {
+				_ = conn.Close()
+				return
+			}
+			pipe(conn, d)
+		}()
+	}
+}
+
+// ForwardUDP forwards the datagrams received on conn to address on the other
+// peer, which has to run Serve or Handle. Every source address gets its own
+// channel, replies are sent back to it. It blocks until conn is closed.
+func ForwardUDP(pc *webrtc.PeerConnection, conn net.PacketConn, address string) error {
+	var mu sync.Mutex
+	flows := map[string]chan []byte{}
+
+	buf := make([]byte, messageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			mu.Lock()
+			for _, flow := range flows {
+				close(flow)
+			}
+			flows = map[string]chan []byte{}
+			mu.Unlock()
+			return err
+		}
+		datagram := append([]byte{}, buf[:n]...)
+
+		mu.Lock()
+		flow, ok := flows[addr.String()]
+		if !ok {
+			flow = make(chan []byte, 64)
+			flows[addr.String()] = flow
+			go func() {
+				forwardFlow(pc, conn, addr, address, flow)
+
+				mu.Lock()
+				if flows[addr.String()] == flow {
+					delete(flows, addr.String())
+				}
+				mu.Unlock()
+			}()
+		}
+		select {
+		case flow <- datagram:
+		default:
+			// The channel isn't open yet or can't keep up, drop like the
+			// network would
+		}
+		mu.Unlock()
+	}
+}
+
+// forwardFlow sends the datagrams of one source address over a channel and
+// writes the replies back to it
+func forwardFlow(pc *webrtc.PeerConnection, conn net.PacketConn, addr net.Addr, address string, flow chan []byte) {
+	d, err := openChannel(pc, "udp", address)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		buf := make([]byte, messageSize)
+		for {
+			n, err := d.Read(buf)
+			if err != nil {
+				return
+			}
+			if _, err = conn.WriteTo(buf[:n], addr); err != nil {
+				return
+			}
+		}
+	}()
+
+	for datagram := range flow {
+		if _, err := d.Write(datagram); err != nil {
+			break
+		}
+	}
+	_ = d.Close()
+}
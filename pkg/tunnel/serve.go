@@ -0,0 +1,50 @@
+// +build !js
+
+package tunnel
+
+import (
+	"net"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// Serve dials the addresses requested by the other peer if policy allows it.
+// It takes over the OnDataChannel handler of pc, use Handle from an own
+// handler if the PeerConnection has other channels as well.
+func Serve(pc *webrtc.PeerConnection, policy Policy) {
+	pc.OnDataChannel(func(d *webrtc.DataChannel) {
+		Handle(d, policy)
+	})
+}
+
+// Handle dials the address a channel created by ForwardTCP or ForwardUDP
+// requests if policy allows it. It returns false if d isn't a tunnel
+// channel.
+func Handle(d *webrtc.DataChannel, policy Policy) bool {
+	network, address, err := unmarshalLabel(d.Label())
+	if err != nil {
+		return false
+	}
+
+	d.OnOpen(func() {
+		rwc, err := d.Detach()
+		if err != nil {
+			return
+		}
+
+		if policy == nil || !policy(network, address) {
+			_ = rwc.Close()
+			return
+		}
+
+		go func() {
+			conn, err := net.Dial(network, address)
+			if err != nil {
+				_ = rwc.Close()
+				return
+			}
+			pipe(conn, rwc)
+		}()
+	})
+	return true
+}
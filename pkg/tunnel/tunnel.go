@@ -0,0 +1,137 @@
+// +build !js
+
+// Package tunnel forwards TCP connections and UDP flows over DataChannels,
+// which makes any service reachable through the NAT traversal of a
+// PeerConnection.
+//
+// Every forwarded connection gets its own DataChannel, so connections are
+// multiplexed over the SCTP streams of the association and a slow one doesn't
+// block the others. The remote side is named in the label of the channel and
+// dialed by Serve or Handle on the other peer. The label is used instead of
+// the subprotocol of the channel, as the subprotocol isn't sent to the other
+// peer yet.
+//
+// Forwarded channels are detached, so both PeerConnections have to be created
+// with an API whose SettingEngine has DetachDataChannels enabled. The
+// association has to be negotiated before forwarding, e.g. by creating a
+// DataChannel before the offer.
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// LabelPrefix starts the labels of the DataChannels created for forwarded
+// connections. Channels with other labels are ignored by Handle.
+const LabelPrefix = "pion-tunnel "
+
+// messageSize is the largest message sent over a forwarded channel. Reads
+// from a detached channel need a buffer as large as the incoming message.
+const messageSize = 16 * 1024
+
+// ErrInvalidLabel indicates that the label of a channel doesn't name a
+// network and address
+var ErrInvalidLabel = errors.New("tunnel: invalid label")
+
+// Policy decides if the address requested by the other peer may be dialed.
+// network is "tcp" or "udp".
+type Policy func(network, address string) bool
+
+// AllowAddresses returns a Policy that only allows the given addresses
+func AllowAddresses(addresses ...string) Policy {
+	allowed := map[string]bool{}
+	for _, a := range addresses {
+		allowed[a] = true
+	}
+	return func(network, address string) bool {
+		return allowed[address]
+	}
+}
+
+func marshalLabel(network, address string) string {
+	return LabelPrefix + network + " " + address
+}
+
+func unmarshalLabel(label string) (network, address string, err error) {
+	if !strings.HasPrefix(label, LabelPrefix) {
+		return "", "", ErrInvalidLabel
+	}
+	split := strings.SplitN(label[len(LabelPrefix):], " ", 2)
+	if len(split) != 2 || (split[0] != "tcp" && split[0] != "udp") {
+		return "", "", ErrInvalidLabel
+	}
+	return split[0], split[1], nil
+}
+
+// openChannel creates a DataChannel for a connection to address and waits
+// for it to be open
+func openChannel(pc *webrtc.PeerConnection, network, address string) (io.ReadWriteCloser, error) {
+	init := &webrtc.DataChannelInit{}
+	if network == "udp" {
+		// Datagrams are not retransmitted. The channel stays ordered, as
+		// unordered datagrams could overtake the message opening the channel.
+		maxRetransmits := uint16(0)
+		init.MaxRetransmits = &maxRetransmits
+	}
+
+	d, err := pc.CreateDataChannel(marshalLabel(network, address), init)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		rwc io.ReadWriteCloser
+		err error
+	}
+	opened := make(chan result, 2)
+	d.OnOpen(func() {
+		rwc, err := d.Detach()
+		opened <- result{rwc, err}
+	})
+	d.OnClose(func() {
+		opened <- result{nil, fmt.Errorf("tunnel: channel to %s closed before it was open", address)}
+	})
+
+	r := <-opened
+	return r.rwc, r.err
+}
+
+// pipe copies messages between a and b until one of them fails and closes
+// both. For UDP every read returns a single datagram, which is sent as a
+// single message, so datagram boundaries are kept.
+func pipe(a, b io.ReadWriteCloser) {
+	var once sync.Once
+	closeBoth := func() {
+		_ = a.Close()
+		_ = b.Close()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, dir := range [][2]io.ReadWriteCloser{{a, b}, {b, a}} {
+		go func(dst io.Writer, src io.Reader) {
+			defer wg.Done()
+			defer once.Do(closeBoth)
+
+			buf := make([]byte, messageSize)
+			for {
+				n, err := src.Read(buf)
+				if n > 0 {
+					if _, writeErr := dst.Write(buf[:n]); writeErr != nil {
+						return
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}(dir[0], dir[1])
+	}
+	wg.Wait()
+}
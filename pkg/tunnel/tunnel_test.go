@@ -0,0 +1,149 @@
+// +build !js
+
+package tunnel
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// newConnectedPair returns two connected PeerConnections with detached
+// DataChannels
+func newConnectedPair(t *testing.T) (*webrtc.PeerConnection, *webrtc.PeerConnection) {
+	s := webrtc.SettingEngine{}
+	s.DetachDataChannels()
+	api := webrtc.NewAPI(webrtc.WithSettingEngine(s))
+
+	offerer, err := api.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	answerer, err := api.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	// Negotiate the SCTP association
+	control, err := offerer.CreateDataChannel("control", nil)
+	assert.NoError(t, err)
+	opened := make(chan struct{})
+	control.OnOpen(func() { close(opened) })
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, offerer.SetLocalDescription(offer))
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, answerer.SetLocalDescription(answer))
+	assert.NoError(t, offerer.SetRemoteDescription(answer))
+
+	<-opened
+	return offerer, answerer
+}
+
+func TestForwardTCP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	offerer, answerer := newConnectedPair(t)
+	Serve(answerer, AllowAddresses(echo.Addr().String()))
+
+	local, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		_ = ForwardTCP(offerer, local, echo.Addr().String())
+	}()
+
+	conn, err := net.Dial("tcp", local.Addr().String())
+	assert.NoError(t, err)
+	_, err = conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, local.Close())
+	assert.NoError(t, echo.Close())
+	assert.NoError(t, offerer.Close())
+	assert.NoError(t, answerer.Close())
+}
+
+func TestForwardUDP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	offerer, answerer := newConnectedPair(t)
+	Serve(answerer, AllowAddresses(echo.LocalAddr().String()))
+
+	local, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		_ = ForwardUDP(offerer, local, echo.LocalAddr().String())
+	}()
+
+	conn, err := net.Dial("udp", local.LocalAddr().String())
+	assert.NoError(t, err)
+
+	// Datagrams sent before the channel is open are dropped, so retry
+	buf := make([]byte, 1500)
+	for {
+		_, err = conn.Write([]byte("hello"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+		n, err := conn.Read(buf)
+		if err == nil {
+			assert.Equal(t, "hello", string(buf[:n]))
+			break
+		}
+	}
+
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, local.Close())
+	assert.NoError(t, echo.Close())
+	assert.NoError(t, offerer.Close())
+	assert.NoError(t, answerer.Close())
+}
+
+func TestHandle_NotAllowed(t *testing.T) {
+	network, address, err := unmarshalLabel(marshalLabel("tcp", "10.0.0.1:22"))
+	assert.NoError(t, err)
+	assert.False(t, AllowAddresses("10.0.0.1:80")(network, address))
+
+	_, _, err = unmarshalLabel(LabelPrefix + "icmp 10.0.0.1")
+	assert.Equal(t, ErrInvalidLabel, err)
+}
@@ -0,0 +1,25 @@
+package webrtc
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProbeCluster describes a burst of RTP padding that is sent on top of the
+// media of an RTPSender. The extra traffic raises the bitrate seen by the
+// bandwidth estimator of the remote peer, allowing a sender to ramp up to its
+// target bitrate in seconds instead of waiting for additive increase.
+type ProbeCluster struct {
+	// Bitrate is the rate in bits per second the padding is sent at.
+	Bitrate uint64
+
+	// Duration is how long the padding is sent for.
+	Duration time.Duration
+}
+
+func (c ProbeCluster) validate() error {
+	if c.Bitrate == 0 || c.Duration <= 0 {
+		return fmt.Errorf("ProbeCluster must have a non-zero Bitrate and Duration")
+	}
+	return nil
+}
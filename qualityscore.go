@@ -0,0 +1,136 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+)
+
+// defaultQualityScoreInterval is the interval of OnQualityScore unless the
+// SettingEngine sets another one
+const defaultQualityScoreInterval = 2 * time.Second
+
+// QualityScore rates the media a PeerConnection received during the last
+// interval, see PeerConnection.OnQualityScore
+type QualityScore struct {
+	Timestamp time.Time
+
+	// MOS is a mean opinion score estimated with a simplified ITU-T G.107
+	// E-model, from 1 (bad) to 4.5 (excellent). Media that stalled during
+	// the interval always gets 1.
+	MOS float64
+
+	// PacketLoss is the fraction of packets lost during the interval, 0 to 1
+	PacketLoss float64
+	// Jitter is the largest interarrival jitter of all received tracks
+	Jitter time.Duration
+	// RoundTripTime of the selected ICE candidate pair, zero if unknown
+	RoundTripTime time.Duration
+	// Bitrate is the sum of the bitrates of all received tracks, in bits
+	// per second
+	Bitrate float64
+}
+
+// estimateMOS calculates the R factor of the E-model from the mouth to ear
+// delay and the loss, and maps it to a MOS
+func estimateMOS(packetLoss float64, jitter, roundTripTime time.Duration) float64 {
+	// The jitter buffer adds about twice the jitter, decoding another 10ms
+	latency := float64(roundTripTime/2+2*jitter)/float64(time.Millisecond) + 10
+
+	r := 93.2
+	if latency < 160 {
+		r -= latency / 40
+	} else {
+		r -= (latency - 120) / 10
+	}
+	r -= 2.5 * packetLoss * 100
+
+	switch {
+	case r <= 0:
+		return 1
+	case r >= 100:
+		return 4.5
+	}
+	return 1 + 0.035*r + 0.000007*r*(r-60)*(100-r)
+}
+
+// OnQualityScore sets an event handler which is called periodically with
+// the QualityScore of the media received by the PeerConnection, see
+// SettingEngine.SetQualityScoreInterval. It isn't called before the first
+// remote track starts.
+func (pc *PeerConnection) OnQualityScore(f func(QualityScore)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onQualityScoreHandler = f
+
+	if pc.qualityScoreDone != nil || pc.isClosed {
+		return
+	}
+	pc.qualityScoreDone = make(chan struct{})
+
+	interval := pc.api.settingEngine.timeout.QualityScore
+	if interval == 0 {
+		interval = defaultQualityScoreInterval
+	}
+	go pc.runQualityScore(interval, pc.qualityScoreDone)
+}
+
+func (pc *PeerConnection) runQualityScore(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			score, ok := pc.qualityScore(now)
+			if !ok {
+				continue
+			}
+
+			pc.mu.RLock()
+			hdlr := pc.onQualityScoreHandler
+			pc.mu.RUnlock()
+			if hdlr != nil {
+				hdlr(score)
+			}
+		}
+	}
+}
+
+// qualityScore combines the reception of all started receivers, it fails if
+// there is none
+func (pc *PeerConnection) qualityScore(now time.Time) (QualityScore, bool) {
+	score := QualityScore{Timestamp: now}
+	var receivers int
+	var expected, lost uint64
+	for _, t := range pc.GetTransceivers() {
+		if t.Receiver == nil || !t.Receiver.hasReceived() {
+			continue
+		}
+		receivers++
+
+		receiverExpected, receiverLost, jitter := t.Receiver.reception.interval()
+		expected += receiverExpected
+		lost += receiverLost
+		if jitter > score.Jitter {
+			score.Jitter = jitter
+		}
+		score.Bitrate += t.Receiver.counters.snapshot(now).Bitrate
+	}
+	if receivers == 0 {
+		return score, false
+	}
+
+	if expected != 0 {
+		score.PacketLoss = float64(lost) / float64(expected)
+	}
+	score.RoundTripTime = pc.iceGatherer.roundTripTime()
+
+	score.MOS = 1
+	if expected != 0 && score.Bitrate != 0 {
+		score.MOS = estimateMOS(score.PacketLoss, score.Jitter, score.RoundTripTime)
+	}
+	return score, true
+}
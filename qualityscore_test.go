@@ -0,0 +1,110 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateMOS(t *testing.T) {
+	perfect := estimateMOS(0, 0, 0)
+	assert.InDelta(t, 4.4, perfect, 0.05)
+
+	lossy := estimateMOS(0.05, 0, 0)
+	assert.True(t, lossy < perfect)
+	assert.True(t, estimateMOS(0, 30*time.Millisecond, 400*time.Millisecond) < perfect)
+	assert.Equal(t, 1.0, estimateMOS(1, 0, 0))
+}
+
+func TestReceptionStats(t *testing.T) {
+	s := receptionStats{}
+	now := time.Now()
+
+	// 65534, 65535, 1, 2: packet 0 is lost across the wrap
+	for i, sequenceNumber := range []uint16{65534, 65535, 1, 2} {
+		s.update(&rtp.Header{SequenceNumber: sequenceNumber, Timestamp: uint32(i * 900)}, 90000, now.Add(time.Duration(i)*10*time.Millisecond))
+	}
+	expected, lost, jitter := s.interval()
+	assert.Equal(t, uint64(5), expected)
+	assert.Equal(t, uint64(1), lost)
+	assert.Equal(t, time.Duration(0), jitter)
+
+	// A late packet adds jitter, the interval starts over
+	s.update(&rtp.Header{SequenceNumber: 3, Timestamp: 4 * 900}, 90000, now.Add(140*time.Millisecond))
+	expected, lost, jitter = s.interval()
+	assert.Equal(t, uint64(1), expected)
+	assert.Equal(t, uint64(0), lost)
+	assert.True(t, jitter > 0)
+}
+
+func TestPeerConnection_OnQualityScore(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetQualityScoreInterval(100 * time.Millisecond)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		for {
+			if _, readErr := remote.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	scores := make(chan QualityScore, 100)
+	pcAnswer.OnQualityScore(func(score QualityScore) {
+		select {
+		case scores <- score:
+		default:
+		}
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 10):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 900})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// The first interval may start before the track
+	for score := range scores {
+		if score.Bitrate == 0 {
+			continue
+		}
+		assert.Equal(t, 0.0, score.PacketLoss)
+		assert.True(t, score.MOS > 4, score.MOS)
+		break
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,225 @@
+// +build !js
+// +build quic
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/pion/quic"
+)
+
+const (
+	// quicDataChannelMaxLabelLength and quicDataChannelMaxMessageSize limit
+	// what a remote can make us allocate
+	quicDataChannelMaxLabelLength = 1<<16 - 1
+	quicDataChannelMaxMessageSize = 1 << 24
+
+	quicDataChannelMessageBinary = 0
+	quicDataChannelMessageString = 1
+)
+
+// QUICDataChannel is a message oriented channel with a label, carried by a
+// bidirectional stream of a QUICTransport. It is the counterpart of a
+// DataChannel on SCTP, messages are delivered reliably and in order.
+//
+// The stream starts with the length of the label as a 16 bit integer and the
+// label. Every message follows as a byte that tells if it is a string, its
+// length as a 32 bit integer and its data. Like the QUICTransport this is
+// experimental and not supported by any browsers.
+type QUICDataChannel struct {
+	stream *quic.BidirectionalStream
+	label  string
+
+	writeMu sync.Mutex
+
+	mu        sync.RWMutex
+	closed    bool
+	onMessage func(DataChannelMessage)
+	onClose   func()
+}
+
+// CreateDataChannel opens a QUICDataChannel with the given label. The remote
+// is told about it with OnDataChannel.
+func (t *QUICTransport) CreateDataChannel(label string) (*QUICDataChannel, error) {
+	if len(label) > quicDataChannelMaxLabelLength {
+		return nil, fmt.Errorf("label of %d bytes is longer than %d bytes", len(label), quicDataChannelMaxLabelLength)
+	}
+
+	stream, err := t.CreateBidirectionalStream()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 2+len(label))
+	binary.BigEndian.PutUint16(header, uint16(len(label)))
+	copy(header[2:], label)
+	if err = stream.Write(quic.StreamWriteParameters{Data: header}); err != nil {
+		return nil, err
+	}
+
+	d := &QUICDataChannel{stream: stream, label: label}
+	go d.readLoop(&quicStreamReader{stream: stream})
+	return d, nil
+}
+
+// OnDataChannel sets a handler that is called when the remote opens a
+// QUICDataChannel. It replaces the handler of OnBidirectionalStream, the
+// streams of the transport are used for data channels only.
+func (t *QUICTransport) OnDataChannel(f func(*QUICDataChannel)) {
+	t.OnBidirectionalStream(func(stream *quic.BidirectionalStream) {
+		go t.acceptDataChannel(stream, f)
+	})
+}
+
+func (t *QUICTransport) acceptDataChannel(stream *quic.BidirectionalStream, f func(*QUICDataChannel)) {
+	r := &quicStreamReader{stream: stream}
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		t.log.Warnf("failed to read the label of a data channel: %v", err)
+		return
+	}
+	label := make([]byte, binary.BigEndian.Uint16(header))
+	if _, err := io.ReadFull(r, label); err != nil {
+		t.log.Warnf("failed to read the label of a data channel: %v", err)
+		return
+	}
+
+	d := &QUICDataChannel{stream: stream, label: string(label)}
+	f(d)
+	d.readLoop(r)
+}
+
+// Label returns the label of the data channel
+func (d *QUICDataChannel) Label() string {
+	return d.label
+}
+
+// StreamID returns the ID of the QUIC stream that carries the data channel
+func (d *QUICDataChannel) StreamID() uint64 {
+	return d.stream.StreamID()
+}
+
+// OnMessage sets a handler that is called with every message received.
+// Messages that arrive before it is set are dropped, set it right away in
+// the handler of OnDataChannel.
+func (d *QUICDataChannel) OnMessage(f func(DataChannelMessage)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onMessage = f
+}
+
+// OnClose sets a handler that is called once the data channel is closed by
+// either side or the transport stopped
+func (d *QUICDataChannel) OnClose(f func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onClose = f
+}
+
+// Send sends a binary message
+func (d *QUICDataChannel) Send(data []byte) error {
+	return d.send(quicDataChannelMessageBinary, data)
+}
+
+// SendText sends a text message
+func (d *QUICDataChannel) SendText(s string) error {
+	return d.send(quicDataChannelMessageString, []byte(s))
+}
+
+func (d *QUICDataChannel) send(messageType byte, data []byte) error {
+	if len(data) > quicDataChannelMaxMessageSize {
+		return fmt.Errorf("message of %d bytes is larger than %d bytes", len(data), quicDataChannelMaxMessageSize)
+	}
+
+	d.mu.RLock()
+	closed := d.closed
+	d.mu.RUnlock()
+	if closed {
+		return io.ErrClosedPipe
+	}
+
+	message := make([]byte, 5+len(data))
+	message[0] = messageType
+	binary.BigEndian.PutUint32(message[1:], uint32(len(data)))
+	copy(message[5:], data)
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	return d.stream.Write(quic.StreamWriteParameters{Data: message})
+}
+
+// Close finishes the stream of the data channel. Messages sent before are
+// still delivered.
+func (d *QUICDataChannel) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	return d.stream.Write(quic.StreamWriteParameters{Finished: true})
+}
+
+func (d *QUICDataChannel) readLoop(r io.Reader) {
+	defer d.handleClose()
+
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if size > quicDataChannelMaxMessageSize {
+			return
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return
+		}
+
+		d.mu.RLock()
+		onMessage := d.onMessage
+		d.mu.RUnlock()
+		if onMessage != nil {
+			onMessage(DataChannelMessage{IsString: header[0] == quicDataChannelMessageString, Data: data})
+		}
+	}
+}
+
+func (d *QUICDataChannel) handleClose() {
+	d.mu.Lock()
+	d.closed = true
+	onClose := d.onClose
+	d.mu.Unlock()
+	if onClose != nil {
+		onClose()
+	}
+}
+
+// quicStreamReader reads a QUIC stream as an io.Reader
+type quicStreamReader struct {
+	stream   *quic.BidirectionalStream
+	finished bool
+}
+
+func (r *quicStreamReader) Read(b []byte) (int, error) {
+	if r.finished {
+		return 0, io.EOF
+	}
+	result, err := r.stream.ReadInto(b)
+	if result.Finished {
+		r.finished = true
+		if err == nil && result.Amount == 0 {
+			err = io.EOF
+		}
+	}
+	return result.Amount, err
+}
@@ -4,9 +4,6 @@
 package webrtc
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/x509"
 	"errors"
 	"strings"
@@ -56,11 +53,7 @@ func (api *API) NewQUICTransport(transport *ICETransport, certificates []Certifi
 			t.certificates = append(t.certificates, x509Cert)
 		}
 	} else {
-		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-		if err != nil {
-			return nil, &rtcerr.UnknownError{Err: err}
-		}
-		certificate, err := GenerateCertificate(sk)
+		certificate, err := api.generateCertificate()
 		if err != nil {
 			return nil, err
 		}
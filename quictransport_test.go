@@ -4,6 +4,7 @@
 package webrtc
 
 import (
+	"io"
 	"testing"
 	"time"
 
@@ -67,6 +68,68 @@ func TestQUICTransport_E2E(t *testing.T) {
 	}
 }
 
+func TestQUICDataChannel_E2E(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	stackA, stackB, err := newQuicPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make(chan DataChannelMessage, 2)
+	closed := make(chan struct{})
+	stackB.quic.OnDataChannel(func(d *QUICDataChannel) {
+		if d.Label() != "chat" {
+			t.Errorf("unexpected label %q", d.Label())
+		}
+		d.OnMessage(func(msg DataChannelMessage) {
+			messages <- msg
+		})
+		d.OnClose(func() {
+			close(closed)
+		})
+	})
+
+	if err = signalQuicPair(stackA, stackB); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := stackA.quic.CreateDataChannel("chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.SendText("Hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Send([]byte{0x00, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := <-messages; !msg.IsString || string(msg.Data) != "Hello" {
+		t.Errorf("unexpected message %v", msg)
+	}
+	if msg := <-messages; msg.IsString || len(msg.Data) != 2 {
+		t.Errorf("unexpected message %v", msg)
+	}
+
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-closed
+	if err = d.SendText("late"); err != io.ErrClosedPipe {
+		t.Errorf("expected io.ErrClosedPipe, got %v", err)
+	}
+
+	if err = stackA.close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = stackB.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func quicReadLoop(s *quic.BidirectionalStream) {
 	for {
 		buffer := make([]byte, 15)
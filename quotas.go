@@ -0,0 +1,22 @@
+// +build !js
+
+package webrtc
+
+// Quotas limit the resources the remote peer can make a PeerConnection
+// allocate, so a misbehaving client can't exhaust a server by opening
+// hundreds of streams. A zero value doesn't limit. See SettingEngine.SetQuotas.
+type Quotas struct {
+	// MaxInboundTracks is the number of tracks the remote can send. Tracks
+	// announced beyond it aren't received and OnTrack isn't invoked for them.
+	MaxInboundTracks int
+
+	// MaxDataChannels is the number of data channels that can be open at the
+	// same time, including the ones created locally. Channels the remote
+	// opens beyond it are refused.
+	MaxDataChannels int
+
+	// MaxInboundBitrate is the aggregate bitrate in bits per second of the
+	// RTP the remote can send. Packets exceeding it are dropped before they
+	// are decrypted, bursts of up to a second of the bitrate pass.
+	MaxInboundBitrate uint64
+}
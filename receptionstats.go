@@ -0,0 +1,98 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// receptionStats counts lost packets and the interarrival jitter of a
+// received RTP stream as described by RFC 3550 Appendix A.3 and A.8
+type receptionStats struct {
+	mu sync.Mutex
+
+	started     bool
+	baseSeq     uint32
+	maxSeq      uint16
+	cycles      uint32
+	received    uint64
+	firstPacket time.Time
+
+	// jitter is the interarrival jitter in RTP timestamp units, lastTransit
+	// the relative transit time of the previous packet
+	jitter      float64
+	haveTransit bool
+	lastTransit int64
+	clockRate   uint32
+
+	// Counts at the end of the previous interval
+	expectedPrior uint64
+	receivedPrior uint64
+}
+
+// update accounts for a packet that arrived at now, the jitter is only
+// estimated once the clock rate of the stream is known
+func (s *receptionStats) update(header *rtp.Header, clockRate uint32, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		s.started = true
+		s.baseSeq = uint32(header.SequenceNumber)
+		s.maxSeq = header.SequenceNumber
+		s.firstPacket = now
+	} else if delta := int16(header.SequenceNumber - s.maxSeq); delta > 0 {
+		if header.SequenceNumber < s.maxSeq {
+			s.cycles += 1 << 16
+		}
+		s.maxSeq = header.SequenceNumber
+	}
+	s.received++
+
+	if clockRate == 0 {
+		return
+	}
+	if clockRate != s.clockRate {
+		s.clockRate = clockRate
+		s.haveTransit = false
+	}
+
+	arrival := int64(now.Sub(s.firstPacket).Seconds() * float64(clockRate))
+	transit := arrival - int64(header.Timestamp)
+	if s.haveTransit {
+		d := math.Abs(float64(transit - s.lastTransit))
+		s.jitter += (d - s.jitter) / 16
+	}
+	s.haveTransit = true
+	s.lastTransit = transit
+}
+
+// interval returns the number of packets expected and lost since the
+// previous call, and the current jitter
+func (s *receptionStats) interval() (expected, lost uint64, jitter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return 0, 0, 0
+	}
+
+	expectedTotal := uint64(s.cycles) + uint64(s.maxSeq) - uint64(s.baseSeq) + 1
+	expected = expectedTotal - s.expectedPrior
+	received := s.received - s.receivedPrior
+	s.expectedPrior = expectedTotal
+	s.receivedPrior = s.received
+
+	// Duplicates may make up for lost packets
+	if expected > received {
+		lost = expected - received
+	}
+	if s.clockRate != 0 {
+		jitter = time.Duration(s.jitter / float64(s.clockRate) * float64(time.Second))
+	}
+	return expected, lost, jitter
+}
@@ -0,0 +1,169 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// redMaxDistance is the most previous packets a RED packet carries
+	redMaxDistance = 8
+
+	// redMaxTimestampOffset and redMaxBlockLength are the limits of the
+	// fields of a redundant block header, RFC 2198 Section 3
+	redMaxTimestampOffset = 1<<14 - 1
+	redMaxBlockLength     = 1<<10 - 1
+
+	redBlockHeaderSize   = 4
+	redPrimaryHeaderSize = 1
+)
+
+// redBlock is the payload of a packet carried by RED packets
+type redBlock struct {
+	payloadType uint8
+	timestamp   uint32
+	payload     []byte
+}
+
+// redEncoder wraps packets in RED, RFC 2198, with the payloads of the
+// previous packets as redundant blocks. It isn't safe for concurrent use.
+type redEncoder struct {
+	history []redBlock
+}
+
+// encode returns the RED payload of a packet, carrying up to distance of the
+// previously encoded packets that fit into the block headers
+func (e *redEncoder) encode(payloadType uint8, timestamp uint32, payload []byte, distance int) []byte {
+	var blocks []redBlock
+	for _, b := range e.history {
+		offset := timestamp - b.timestamp
+		if offset > 0 && offset <= redMaxTimestampOffset && len(b.payload) <= redMaxBlockLength {
+			blocks = append(blocks, b)
+		}
+	}
+	if len(blocks) > distance {
+		blocks = blocks[len(blocks)-distance:]
+	}
+
+	size := redPrimaryHeaderSize + len(payload)
+	for _, b := range blocks {
+		size += redBlockHeaderSize + len(b.payload)
+	}
+	out := make([]byte, 0, size)
+	for _, b := range blocks {
+		header := uint32(1)<<31 | uint32(b.payloadType&0x7F)<<24 | (timestamp-b.timestamp)<<10 | uint32(len(b.payload))
+		out = append(out, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(out[len(out)-redBlockHeaderSize:], header)
+	}
+	out = append(out, payloadType&0x7F)
+	for _, b := range blocks {
+		out = append(out, b.payload...)
+	}
+	out = append(out, payload...)
+
+	e.history = append(e.history, redBlock{payloadType, timestamp, append([]byte{}, payload...)})
+	if len(e.history) > distance {
+		e.history = e.history[len(e.history)-distance:]
+	}
+	return out
+}
+
+// parseRED splits a RED payload into its redundant blocks, oldest first,
+// and its primary block
+func parseRED(timestamp uint32, payload []byte) (redundant []redBlock, primary redBlock, ok bool) {
+	type header struct {
+		payloadType uint8
+		offset      uint32
+		length      int
+	}
+
+	var headers []header
+	i := 0
+	for {
+		if i >= len(payload) {
+			return nil, primary, false
+		}
+		if payload[i]&0x80 == 0 {
+			primary.payloadType = payload[i] & 0x7F
+			i++
+			break
+		}
+		if i+redBlockHeaderSize > len(payload) {
+			return nil, primary, false
+		}
+		h := binary.BigEndian.Uint32(payload[i:])
+		headers = append(headers, header{uint8(h>>24) & 0x7F, (h >> 10) & redMaxTimestampOffset, int(h & redMaxBlockLength)})
+		i += redBlockHeaderSize
+	}
+
+	for _, h := range headers {
+		if i+h.length > len(payload) {
+			return nil, primary, false
+		}
+		redundant = append(redundant, redBlock{h.payloadType, timestamp - h.offset, payload[i : i+h.length]})
+		i += h.length
+	}
+	primary.timestamp = timestamp
+	primary.payload = payload[i:]
+	return redundant, primary, true
+}
+
+// redDecoder unwraps the RED packets of a remote track. Packets that were
+// lost are recovered from the redundant blocks of the packets that follow.
+type redDecoder struct {
+	payloadType uint8
+
+	started            bool
+	lastSequenceNumber uint16
+}
+
+// decode returns the packets carried by p in order: the recovered packets
+// that were lost and the primary packet. Late RED packets are dropped,
+// packets that aren't RED are returned as they are.
+func (d *redDecoder) decode(p *rtp.Packet) []*rtp.Packet {
+	if p.PayloadType != d.payloadType {
+		d.update(p.SequenceNumber)
+		return []*rtp.Packet{p}
+	}
+
+	redundant, primary, ok := parseRED(p.Timestamp, p.Payload)
+	if !ok {
+		return nil
+	}
+	// Late packets were recovered from the redundancy of the ones that
+	// arrived before them
+	if d.started && int16(p.SequenceNumber-d.lastSequenceNumber) <= 0 {
+		return nil
+	}
+
+	var out []*rtp.Packet
+	// The redundant blocks are the packets right before p
+	for i, b := range redundant {
+		sequenceNumber := p.SequenceNumber - uint16(len(redundant)-i)
+		if d.started && int16(sequenceNumber-d.lastSequenceNumber) <= 0 {
+			continue
+		}
+		out = append(out, d.packet(p, sequenceNumber, b))
+	}
+	out = append(out, d.packet(p, p.SequenceNumber, primary))
+	d.update(p.SequenceNumber)
+	return out
+}
+
+func (d *redDecoder) packet(p *rtp.Packet, sequenceNumber uint16, b redBlock) *rtp.Packet {
+	h := p.Header
+	h.SequenceNumber = sequenceNumber
+	h.Timestamp = b.timestamp
+	h.PayloadType = b.payloadType
+	return &rtp.Packet{Header: h, Payload: b.payload}
+}
+
+func (d *redDecoder) update(sequenceNumber uint16) {
+	if !d.started || int16(sequenceNumber-d.lastSequenceNumber) > 0 {
+		d.lastSequenceNumber = sequenceNumber
+	}
+	d.started = true
+}
@@ -0,0 +1,138 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRED(t *testing.T) {
+	const redPayloadType, opusPayloadType = 63, 111
+
+	encoder := &redEncoder{}
+	var sent []*rtp.Packet
+	for i := 0; i < 4; i++ {
+		payload := []byte{byte(i), byte(i)}
+		sent = append(sent, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    redPayloadType,
+				SequenceNumber: uint16(100 + i),
+				Timestamp:      uint32(960 * i),
+				SSRC:           5000,
+			},
+			Payload: encoder.encode(opusPayloadType, uint32(960*i), payload, 2),
+		})
+	}
+
+	// The first packet has no redundancy, the others carry up to two blocks
+	assert.Equal(t, []byte{opusPayloadType, 0, 0}, sent[0].Payload)
+	assert.Equal(t, []byte{
+		0x80 | opusPayloadType, 0x0F, 0x00, 0x02,
+		opusPayloadType,
+		0, 0,
+		1, 1,
+	}, sent[1].Payload)
+	assert.Len(t, sent[3].Payload, 2*redBlockHeaderSize+redPrimaryHeaderSize+3*2)
+
+	// The second and third packet are lost and recovered from the fourth
+	decoder := &redDecoder{payloadType: redPayloadType}
+	var received []*rtp.Packet
+	received = append(received, decoder.decode(sent[0])...)
+	received = append(received, decoder.decode(sent[3])...)
+	if assert.Len(t, received, 4) {
+		for i, p := range received {
+			assert.Equal(t, uint8(opusPayloadType), p.PayloadType)
+			assert.Equal(t, uint16(100+i), p.SequenceNumber)
+			assert.Equal(t, uint32(960*i), p.Timestamp)
+			assert.Equal(t, []byte{byte(i), byte(i)}, p.Payload)
+		}
+	}
+
+	// Packets that arrived already aren't returned again
+	assert.Len(t, decoder.decode(sent[2]), 0)
+
+	// Packets of the primary codec pass through
+	opus := &rtp.Packet{Header: rtp.Header{PayloadType: opusPayloadType, SequenceNumber: 104}}
+	assert.Equal(t, []*rtp.Packet{opus}, decoder.decode(opus))
+
+	// Truncated RED packets are dropped
+	assert.Len(t, decoder.decode(&rtp.Packet{Header: rtp.Header{PayloadType: redPayloadType}, Payload: []byte{0x80 | opusPayloadType, 0x00}}), 0)
+}
+
+func TestTrackRedundancy(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	newAPI := func() *API {
+		m := MediaEngine{}
+		opus := NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000)
+		m.RegisterCodec(opus)
+		m.RegisterCodec(NewRTPREDCodec(63, opus))
+		return NewAPI(WithMediaEngine(m))
+	}
+	pcOffer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeAudio, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, 1000, "audio", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrInvalidRedundancy, track.SetRedundancy(redMaxDistance+1))
+	assert.NoError(t, track.SetRedundancy(1))
+
+	onTrack := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		onTrack <- remote
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{byte(i)}, Samples: 960})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	remote := <-onTrack
+	sender := pcOffer.GetSenders()[0]
+	<-sender.sendCalled
+	assert.Equal(t, uint8(63), sender.redPayloadType)
+	assert.Equal(t, Opus, remote.Codec().Name)
+	assert.Equal(t, uint8(DefaultPayloadTypeOpus), remote.PayloadType())
+	assert.Error(t, remote.SetRedundancy(1))
+
+	var last *rtp.Packet
+	for i := 0; i < 5; i++ {
+		pkt, err := remote.ReadRTP()
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(DefaultPayloadTypeOpus), pkt.PayloadType)
+		assert.Len(t, pkt.Payload, 1)
+		if last != nil {
+			assert.Equal(t, last.SequenceNumber+1, pkt.SequenceNumber)
+			assert.Equal(t, last.Payload[0]+1, pkt.Payload[0])
+		}
+		last = pkt
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
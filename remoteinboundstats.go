@@ -0,0 +1,135 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// remoteInboundReport is what the last receiver report of the remote told
+// about an SSRC an RTPSender sends
+type remoteInboundReport struct {
+	received      time.Time
+	packetsLost   int32
+	fractionLost  float64
+	jitter        uint32
+	roundTripTime time.Duration
+}
+
+// remoteInboundStats collects the reception reports about the SSRCs of an
+// RTPSender, the remote-inbound-rtp stats
+type remoteInboundStats struct {
+	mu      sync.Mutex
+	reports map[uint32]remoteInboundReport
+}
+
+// update accounts for a reception report received at now. The round trip
+// time is only known if the remote got a sender report, RFC 3550 6.4.1.
+func (s *remoteInboundStats) update(report rtcp.ReceptionReport, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reports == nil {
+		s.reports = map[uint32]remoteInboundReport{}
+	}
+
+	r := remoteInboundReport{
+		received: now,
+		// The cumulative number of packets lost is a 24 bit signed integer
+		packetsLost:   int32(report.TotalLost<<8) >> 8,
+		fractionLost:  float64(report.FractionLost) / 256,
+		jitter:        report.Jitter,
+		roundTripTime: s.reports[report.SSRC].roundTripTime,
+	}
+	if report.LastSenderReport != 0 {
+		// Middle 32 bits of the NTP timestamps in units of 1/65536 seconds
+		rtt := uint32(timeToNTP(now)>>16) - report.LastSenderReport - report.Delay
+		if int32(rtt) >= 0 {
+			r.roundTripTime = time.Duration(rtt) * time.Second / (1 << 16)
+		}
+	}
+	s.reports[report.SSRC] = r
+}
+
+// handleReceiverReports looks for reception reports about the SSRCs of the
+// track in the receiver and sender reports of the compound packet b
+func (r *RTPSender) handleReceiverReports(b []byte) {
+	now := time.Now()
+	ssrcs := r.ssrcs()
+	for len(b) >= 4 {
+		header := &rtcp.Header{}
+		if err := header.Unmarshal(b); err != nil {
+			return
+		}
+		size := (int(header.Length) + 1) * 4
+		if size > len(b) {
+			return
+		}
+
+		var reports []rtcp.ReceptionReport
+		switch header.Type {
+		case rtcp.TypeReceiverReport:
+			rr := &rtcp.ReceiverReport{}
+			if err := rr.Unmarshal(b[:size]); err == nil {
+				reports = rr.Reports
+			}
+		case rtcp.TypeSenderReport:
+			sr := &rtcp.SenderReport{}
+			if err := sr.Unmarshal(b[:size]); err == nil {
+				reports = sr.Reports
+			}
+		}
+		for _, report := range reports {
+			for _, ssrc := range ssrcs {
+				if report.SSRC == ssrc {
+					r.remoteInbound.update(report, now)
+				}
+			}
+		}
+		b = b[size:]
+	}
+}
+
+// remoteInboundStatsID is the ID of the remote-inbound-rtp stats of ssrc
+func (r *RTPSender) remoteInboundStatsID(ssrc uint32) string {
+	return fmt.Sprintf("%s-remote-inbound-%d", r.statsID, ssrc)
+}
+
+func (r *RTPSender) collectStats(collector *statsReportCollector) {
+	r.remoteInbound.mu.Lock()
+	defer r.remoteInbound.mu.Unlock()
+
+	ssrcs := make([]uint32, 0, len(r.remoteInbound.reports))
+	for ssrc := range r.remoteInbound.reports {
+		ssrcs = append(ssrcs, ssrc)
+	}
+	sort.Slice(ssrcs, func(i, j int) bool { return ssrcs[i] < ssrcs[j] })
+
+	var clockRate uint32
+	if codec := r.track.Codec(); codec != nil {
+		clockRate = codec.ClockRate
+	}
+	for _, ssrc := range ssrcs {
+		collector.Collecting()
+
+		report := r.remoteInbound.reports[ssrc]
+		stats := RemoteInboundRTPStreamStats{
+			Timestamp:     statsTimestampFrom(report.received),
+			Type:          StatsTypeRemoteInboundRTP,
+			ID:            r.remoteInboundStatsID(ssrc),
+			SSRC:          ssrc,
+			Kind:          r.track.Kind().String(),
+			PacketsLost:   report.packetsLost,
+			RoundTripTime: report.roundTripTime.Seconds(),
+			FractionLost:  report.fractionLost,
+		}
+		if clockRate != 0 {
+			stats.Jitter = float64(report.jitter) / float64(clockRate)
+		}
+		collector.Collect(stats.ID, stats)
+	}
+}
@@ -0,0 +1,73 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// restartableConn is the connection the mux of an ICETransport reads from.
+// An ICE restart moves it to the connection of a new agent, the DTLS and
+// SCTP associations on top keep running.
+type restartableConn struct {
+	mu   sync.RWMutex
+	conn net.Conn
+}
+
+func (c *restartableConn) current() net.Conn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn
+}
+
+// swap replaces the connection and returns the previous one
+func (c *restartableConn) swap(conn net.Conn) net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.conn
+	c.conn = conn
+	return previous
+}
+
+// Read reads from the current connection. A read that fails because the
+// connection has been replaced meanwhile continues on the new one.
+func (c *restartableConn) Read(b []byte) (int, error) {
+	for {
+		conn := c.current()
+		n, err := conn.Read(b)
+		if err != nil && c.current() != conn {
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *restartableConn) Write(b []byte) (int, error) {
+	return c.current().Write(b)
+}
+
+func (c *restartableConn) Close() error {
+	return c.current().Close()
+}
+
+func (c *restartableConn) LocalAddr() net.Addr {
+	return c.current().LocalAddr()
+}
+
+func (c *restartableConn) RemoteAddr() net.Addr {
+	return c.current().RemoteAddr()
+}
+
+func (c *restartableConn) SetDeadline(t time.Time) error {
+	return c.current().SetDeadline(t)
+}
+
+func (c *restartableConn) SetReadDeadline(t time.Time) error {
+	return c.current().SetReadDeadline(t)
+}
+
+func (c *restartableConn) SetWriteDeadline(t time.Time) error {
+	return c.current().SetWriteDeadline(t)
+}
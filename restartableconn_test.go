@@ -0,0 +1,51 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartableConn(t *testing.T) {
+	first, firstRemote := net.Pipe()
+	second, secondRemote := net.Pipe()
+	conn := &restartableConn{conn: first}
+
+	read := make(chan string)
+	go func() {
+		b := make([]byte, 16)
+		n, err := conn.Read(b)
+		assert.NoError(t, err)
+		read <- string(b[:n])
+	}()
+
+	// The read blocked on the first connection continues on the second
+	assert.Equal(t, first, conn.swap(second))
+	assert.NoError(t, first.Close())
+	go func() {
+		_, err := secondRemote.Write([]byte("second"))
+		assert.NoError(t, err)
+	}()
+	assert.Equal(t, "second", <-read)
+
+	go func() {
+		b := make([]byte, 16)
+		_, err := secondRemote.Read(b)
+		assert.NoError(t, err)
+		read <- string(b)
+	}()
+	_, err := conn.Write([]byte("reply"))
+	assert.NoError(t, err)
+	assert.Contains(t, <-read, "reply")
+
+	// Errors of the current connection are returned
+	assert.NoError(t, conn.Close())
+	_, err = conn.Read(make([]byte, 16))
+	assert.Error(t, err)
+
+	assert.NoError(t, firstRemote.Close())
+	assert.NoError(t, secondRemote.Close())
+}
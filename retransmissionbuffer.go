@@ -0,0 +1,187 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// retransmissionKey identifies a packet of a sender by its SSRC and sequence number
+type retransmissionKey struct {
+	ssrc           uint32
+	sequenceNumber uint16
+}
+
+// retransmissionPacket is a copy of a sent packet and the time it was sent
+type retransmissionPacket struct {
+	key     retransmissionKey
+	header  rtp.Header
+	payload []byte
+	sent    time.Time
+}
+
+// retransmissionBuffer keeps the packets an RTPSender sent to answer the
+// NACKs of the remote, see SettingEngine.SetRetransmissionBuffer. Packets are
+// evicted in the order they were sent once they are older than maxAge or the
+// payloads of the buffer exceed maxBytes, zero disables either limit.
+type retransmissionBuffer struct {
+	mu sync.Mutex
+
+	maxAge   time.Duration
+	maxBytes int
+
+	queue   []*retransmissionPacket
+	packets map[retransmissionKey]*retransmissionPacket
+	size    int
+}
+
+// newRetransmissionBuffer returns a buffer with the given limits, or nil if
+// both are zero
+func newRetransmissionBuffer(maxAge time.Duration, maxBytes int) *retransmissionBuffer {
+	if maxAge <= 0 && maxBytes <= 0 {
+		return nil
+	}
+	return &retransmissionBuffer{
+		maxAge:   maxAge,
+		maxBytes: maxBytes,
+		packets:  map[retransmissionKey]*retransmissionPacket{},
+	}
+}
+
+// add stores a copy of the packet sent at now. Packets that alone exceed
+// maxBytes aren't stored.
+func (b *retransmissionBuffer) add(h *rtp.Header, payload []byte, now time.Time) {
+	if b.maxBytes > 0 && len(payload) > b.maxBytes {
+		return
+	}
+
+	p := &retransmissionPacket{
+		key:     retransmissionKey{h.SSRC, h.SequenceNumber},
+		header:  *h,
+		payload: append([]byte{}, payload...),
+		sent:    now,
+	}
+	p.header.CSRC = append([]uint32{}, h.CSRC...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if previous, ok := b.packets[p.key]; ok {
+		// The sequence number wrapped around, the previous packet can't be
+		// told apart from the new one anymore
+		b.size -= len(previous.payload)
+		previous.payload = nil
+	}
+	b.packets[p.key] = p
+	b.queue = append(b.queue, p)
+	b.size += len(p.payload)
+	b.evict(now)
+}
+
+// get returns the packet with the given SSRC and sequence number, or nil if
+// it was never stored or expired
+func (b *retransmissionBuffer) get(ssrc uint32, sequenceNumber uint16, now time.Time) *retransmissionPacket {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.evict(now)
+	return b.packets[retransmissionKey{ssrc, sequenceNumber}]
+}
+
+// evict drops the oldest packets while they exceed one of the limits, mu has
+// to be held
+func (b *retransmissionBuffer) evict(now time.Time) {
+	for len(b.queue) != 0 {
+		p := b.queue[0]
+		expired := b.maxAge > 0 && now.Sub(p.sent) > b.maxAge
+		if !expired && (b.maxBytes <= 0 || b.size <= b.maxBytes) {
+			return
+		}
+
+		b.queue[0] = nil
+		b.queue = b.queue[1:]
+		b.size -= len(p.payload)
+		if b.packets[p.key] == p {
+			delete(b.packets, p.key)
+		}
+	}
+}
+
+// handleNack retransmits the packets the NACKs in the compound packet b ask
+// for, RFC 4585 6.2.1. Packets that expired from the retransmission buffer
+// are skipped, the remote would only discard them.
+func (r *RTPSender) handleNack(b []byte) {
+	if r.retransmissions == nil {
+		return
+	}
+
+	now := time.Now()
+	ssrcs := r.ssrcs()
+	for _, packet := range feedbackMessages(b, rtcp.TypeTransportSpecificFeedback, rtcp.FormatTLN) {
+		nack := &rtcp.TransportLayerNack{}
+		if err := nack.Unmarshal(packet); err != nil {
+			continue
+		}
+
+		own := false
+		for _, ssrc := range ssrcs {
+			own = own || ssrc == nack.MediaSSRC
+		}
+		if !own {
+			continue
+		}
+
+		for _, pair := range nack.Nacks {
+			for _, sequenceNumber := range pair.PacketList() {
+				if p := r.retransmissions.get(nack.MediaSSRC, sequenceNumber, now); p != nil {
+					if err := r.retransmit(p); err != nil {
+						r.log.Warnf("Failed to retransmit packet %d of %d: %v", sequenceNumber, nack.MediaSSRC, err)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// retransmit sends p again. It is sent on the RTX stream of its SSRC if the
+// track has one and the remote negotiated RTX for its payload type, RFC 4588,
+// otherwise as it was sent the first time.
+func (r *RTPSender) retransmit(p *retransmissionPacket) error {
+	r.injectMu.Lock()
+	defer r.injectMu.Unlock()
+
+	writeStream, _, err := r.writeStreamLocked()
+	if err != nil {
+		return err
+	}
+
+	h, payload := p.header, p.payload
+	if ssrc := rtxSSRC(r.track.SSRCGroups(), h.SSRC); ssrc != 0 {
+		for _, codec := range r.api.mediaEngine.GetAssociatedCodecs(h.PayloadType) {
+			if !strings.EqualFold(codec.Name, RTX) {
+				continue
+			}
+
+			// The RTX payload starts with the original sequence number
+			payload = make([]byte, 2+len(p.payload))
+			binary.BigEndian.PutUint16(payload, h.SequenceNumber)
+			copy(payload[2:], p.payload)
+
+			h.SSRC = ssrc
+			h.PayloadType = codec.PayloadType
+			h.SequenceNumber = r.rtxSequenceNumber
+			r.rtxSequenceNumber++
+			break
+		}
+	}
+
+	_, err = writeStream.WriteRTP(&h, payload)
+	return err
+}
@@ -0,0 +1,67 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetransmissionBuffer(t *testing.T) {
+	assert.Nil(t, newRetransmissionBuffer(0, 0))
+
+	t.Run("MaxAge", func(t *testing.T) {
+		b := newRetransmissionBuffer(500*time.Millisecond, 0)
+		start := time.Now()
+		b.add(&rtp.Header{SSRC: 1, SequenceNumber: 10}, []byte{0x01}, start)
+		b.add(&rtp.Header{SSRC: 1, SequenceNumber: 11}, []byte{0x02}, start.Add(300*time.Millisecond))
+
+		p := b.get(1, 10, start.Add(400*time.Millisecond))
+		if assert.NotNil(t, p) {
+			assert.Equal(t, []byte{0x01}, p.payload)
+		}
+		assert.Nil(t, b.get(2, 10, start.Add(400*time.Millisecond)))
+
+		// The first packet is past its deadline
+		assert.Nil(t, b.get(1, 10, start.Add(600*time.Millisecond)))
+		assert.NotNil(t, b.get(1, 11, start.Add(600*time.Millisecond)))
+		assert.Nil(t, b.get(1, 11, start.Add(time.Second)))
+		assert.Equal(t, 0, b.size)
+	})
+
+	t.Run("MaxBytes", func(t *testing.T) {
+		b := newRetransmissionBuffer(0, 4)
+		now := time.Now()
+		payload := []byte{0x01, 0x02}
+		b.add(&rtp.Header{SSRC: 1, SequenceNumber: 1}, payload, now)
+		b.add(&rtp.Header{SSRC: 1, SequenceNumber: 2}, []byte{0x03, 0x04}, now)
+		b.add(&rtp.Header{SSRC: 1, SequenceNumber: 3}, []byte{0x05}, now)
+		b.add(&rtp.Header{SSRC: 1, SequenceNumber: 4}, []byte{0x06, 0x07, 0x08, 0x09, 0x0a}, now)
+
+		// The packets are copies
+		payload[0] = 0xff
+
+		assert.Nil(t, b.get(1, 1, now))
+		if p := b.get(1, 2, now); assert.NotNil(t, p) {
+			assert.Equal(t, []byte{0x03, 0x04}, p.payload)
+		}
+		assert.NotNil(t, b.get(1, 3, now))
+		assert.Nil(t, b.get(1, 4, now))
+		assert.Equal(t, 3, b.size)
+	})
+
+	t.Run("Wraparound", func(t *testing.T) {
+		b := newRetransmissionBuffer(time.Minute, 0)
+		now := time.Now()
+		b.add(&rtp.Header{SSRC: 1, SequenceNumber: 1}, []byte{0x01}, now)
+		b.add(&rtp.Header{SSRC: 1, SequenceNumber: 1}, []byte{0x02}, now)
+
+		if p := b.get(1, 1, now); assert.NotNil(t, p) {
+			assert.Equal(t, []byte{0x02}, p.payload)
+		}
+		assert.Equal(t, 1, b.size)
+	})
+}
@@ -0,0 +1,117 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+)
+
+// rtcpCoalescer collects the RTCP written to a DTLSTransport for a short
+// interval and sends it as compound packets, instead of a datagram per
+// feedback message. Sender and receiver reports lead their compound packet,
+// a compound packet is at most as large as the MTU of the transport.
+type rtcpCoalescer struct {
+	interval time.Duration
+	mtu      func() int
+	write    func([]byte) error
+	log      logging.LeveledLogger
+
+	mu      sync.Mutex
+	pending []rtcp.Packet
+	timer   *time.Timer
+	closed  bool
+}
+
+func newRTCPCoalescer(interval time.Duration, mtu func() int, write func([]byte) error, log logging.LeveledLogger) *rtcpCoalescer {
+	return &rtcpCoalescer{
+		interval: interval,
+		mtu:      mtu,
+		write:    write,
+		log:      log,
+	}
+}
+
+// writeRTCP queues pkts, they are sent at the latest after the interval
+func (c *rtcpCoalescer) writeRTCP(pkts []rtcp.Packet) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return io.ErrClosedPipe
+	}
+	c.pending = append(c.pending, pkts...)
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.interval, c.flush)
+	}
+	return nil
+}
+
+func (c *rtcpCoalescer) flush() {
+	c.mu.Lock()
+	pkts := c.pending
+	c.pending = nil
+	c.timer = nil
+	c.mu.Unlock()
+
+	for _, raw := range c.compound(pkts) {
+		if err := c.write(raw); err != nil {
+			c.log.Warnf("failed to send coalesced RTCP: %v", err)
+			return
+		}
+	}
+}
+
+// compound marshals pkts into as few compound packets as fit into the MTU,
+// reports first
+func (c *rtcpCoalescer) compound(pkts []rtcp.Packet) [][]byte {
+	var reports, feedback [][]byte
+	for _, p := range pkts {
+		raw, err := p.Marshal()
+		if err != nil {
+			c.log.Warnf("dropping RTCP packet that failed to marshal: %v", err)
+			continue
+		}
+		switch p.(type) {
+		case *rtcp.SenderReport, *rtcp.ReceiverReport:
+			reports = append(reports, raw)
+		default:
+			feedback = append(feedback, raw)
+		}
+	}
+
+	mtu := c.mtu()
+	var compounds [][]byte
+	var current []byte
+	for _, raw := range append(reports, feedback...) {
+		if len(current) != 0 && len(current)+len(raw) > mtu {
+			compounds = append(compounds, current)
+			current = nil
+		}
+		current = append(current, raw...)
+	}
+	if len(current) != 0 {
+		compounds = append(compounds, current)
+	}
+	return compounds
+}
+
+// close sends what is pending, later writes fail
+func (c *rtcpCoalescer) close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.mu.Unlock()
+
+	c.flush()
+}
@@ -0,0 +1,64 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCPCoalescer(t *testing.T) {
+	written := make(chan []byte, 10)
+	c := newRTCPCoalescer(10*time.Millisecond, func() int { return rtpOutboundMTU }, func(raw []byte) error {
+		written <- raw
+		return nil
+	}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+
+	pli := &rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 2}
+	rr := &rtcp.ReceiverReport{SSRC: 1}
+	nack := &rtcp.TransportLayerNack{SenderSSRC: 1, MediaSSRC: 2, Nacks: []rtcp.NackPair{{PacketID: 5}}}
+	assert.NoError(t, c.writeRTCP([]rtcp.Packet{pli}))
+	assert.NoError(t, c.writeRTCP([]rtcp.Packet{rr, nack}))
+
+	// A single compound packet that starts with the report
+	pkts, err := rtcp.Unmarshal(<-written)
+	assert.NoError(t, err)
+	if assert.Len(t, pkts, 3) {
+		assert.IsType(t, rr, pkts[0])
+		assert.Equal(t, []rtcp.Packet{pli, nack}, pkts[1:])
+	}
+	select {
+	case <-written:
+		t.Fatal("RTCP wasn't coalesced")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Compound packets don't exceed the MTU
+	var many []rtcp.Packet
+	for i := 0; i < 200; i++ {
+		many = append(many, &rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: uint32(i)})
+	}
+	assert.NoError(t, c.writeRTCP(many))
+	var received int
+	for received < len(many) {
+		raw := <-written
+		assert.True(t, len(raw) <= rtpOutboundMTU)
+		pkts, err = rtcp.Unmarshal(raw)
+		assert.NoError(t, err)
+		received += len(pkts)
+	}
+	assert.Equal(t, len(many), received)
+
+	// Closing sends what is pending
+	assert.NoError(t, c.writeRTCP([]rtcp.Packet{pli}))
+	c.close()
+	pkts, err = rtcp.Unmarshal(<-written)
+	assert.NoError(t, err)
+	assert.Equal(t, []rtcp.Packet{pli}, pkts)
+	assert.Equal(t, io.ErrClosedPipe, c.writeRTCP([]rtcp.Packet{pli}))
+}
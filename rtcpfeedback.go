@@ -1,14 +1,38 @@
 package webrtc
 
+import "github.com/pion/rtcp"
+
 // RTCPFeedback signals the connection to use additional RTCP packet types.
 // https://draft.ortc.org/#dom-rtcrtcpfeedback
 type RTCPFeedback struct {
 	// Type is the type of feedback.
 	// see: https://draft.ortc.org/#dom-rtcrtcpfeedback
 	// valid: ack, ccm, nack, goog-remb, transport-cc
-	Type string
+	Type string `json:"type"`
 
 	// The parameter value depends on the type.
 	// For example, type="nack" parameter="pli" will send Picture Loss Indicator packets.
-	Parameter string
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// feedbackMessages returns the feedback messages of the given type and
+// format in the compound RTCP packet b, for the messages pion/rtcp doesn't
+// parse
+func feedbackMessages(b []byte, typ rtcp.PacketType, format uint8) [][]byte {
+	var messages [][]byte
+	for len(b) >= 4 {
+		header := &rtcp.Header{}
+		if err := header.Unmarshal(b); err != nil {
+			return messages
+		}
+		size := (int(header.Length) + 1) * 4
+		if size > len(b) {
+			return messages
+		}
+		if header.Type == typ && header.Count == format {
+			messages = append(messages, b[:size])
+		}
+		b = b[size:]
+	}
+	return messages
 }
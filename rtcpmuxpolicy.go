@@ -9,6 +9,10 @@ const (
 	// RTP and RTCP candidates. If the remote-endpoint is capable of
 	// multiplexing RTCP, multiplex RTCP on the RTP candidates. If it is not,
 	// use both the RTP and RTCP candidates separately.
+	//
+	// The ICE agent only gathers candidates for the RTP component yet, RTCP
+	// is multiplexed on the RTP candidates either way. A remote that can't
+	// multiplex RTCP is accepted, but doesn't get the RTCP sent to it.
 	RTCPMuxPolicyNegotiate RTCPMuxPolicy = iota + 1
 
 	// RTCPMuxPolicyRequire indicates to gather ICE candidates only for
@@ -0,0 +1,79 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+// the Unix epoch (1970)
+const ntpEpochOffset = 2208988800
+
+// RTPClockMapper converts between RTP timestamps and wall clock time. The
+// reference is the most recent RTCP SenderReport, which pairs an NTP time with
+// the RTP timestamp of the same instant. The remote clock isn't synchronized
+// with the local one, wall clock times are in the clock of the sender.
+type RTPClockMapper struct {
+	mu sync.RWMutex
+
+	haveReport   bool
+	ntpTime      time.Time
+	rtpTimestamp uint32
+}
+
+// UpdateSenderReport makes sr the reference for conversions
+func (m *RTPClockMapper) UpdateSenderReport(sr *rtcp.SenderReport) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.haveReport = true
+	m.ntpTime = ntpToTime(sr.NTPTime)
+	m.rtpTimestamp = sr.RTPTime
+}
+
+// WallClock returns the wall clock time of an RTP timestamp of a stream with
+// the given clock rate. It returns false until a SenderReport was received.
+// Timestamps up to half the RTP timestamp range away from the reference are
+// mapped, which is more than six hours at 90kHz.
+func (m *RTPClockMapper) WallClock(rtpTimestamp, clockRate uint32) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.haveReport || clockRate == 0 {
+		return time.Time{}, false
+	}
+
+	// The signed difference handles wraparound of the RTP timestamp
+	diff := int64(int32(rtpTimestamp - m.rtpTimestamp))
+	return m.ntpTime.Add(time.Duration(diff * int64(time.Second) / int64(clockRate))), true
+}
+
+// RTPTimestamp returns the RTP timestamp of a wall clock time, the inverse of
+// WallClock. It returns false until a SenderReport was received.
+func (m *RTPClockMapper) RTPTimestamp(t time.Time, clockRate uint32) (uint32, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.haveReport || clockRate == 0 {
+		return 0, false
+	}
+
+	diff := int64(t.Sub(m.ntpTime)) * int64(clockRate) / int64(time.Second)
+	return m.rtpTimestamp + uint32(diff), true
+}
+
+// ntpToTime converts a 64bit NTP timestamp as used in SenderReports
+func ntpToTime(ntp uint64) time.Time {
+	seconds := int64(ntp>>32) - ntpEpochOffset
+	nanos := int64((ntp & 0xFFFFFFFF) * uint64(time.Second) >> 32)
+	return time.Unix(seconds, nanos)
+}
+
+// timeToNTP converts t into a 64bit NTP timestamp
+func timeToNTP(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
@@ -0,0 +1,53 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPClockMapper(t *testing.T) {
+	m := &RTPClockMapper{}
+
+	_, ok := m.WallClock(0, 90000)
+	assert.False(t, ok)
+
+	// 2020-01-01 00:00:00.5 UTC in NTP format
+	reference := time.Date(2020, 1, 1, 0, 0, 0, 500000000, time.UTC)
+	ntp := uint64(reference.Unix()+ntpEpochOffset)<<32 | 1<<31
+	m.UpdateSenderReport(&rtcp.SenderReport{NTPTime: ntp, RTPTime: 0xFFFFFFFF - 44999})
+
+	// Half a second after the reference, across the wraparound
+	wallClock, ok := m.WallClock(0, 90000)
+	assert.True(t, ok)
+	assert.True(t, reference.Add(500*time.Millisecond).Equal(wallClock))
+
+	// Before the reference
+	wallClock, ok = m.WallClock(0xFFFFFFFF-44999-90000, 90000)
+	assert.True(t, ok)
+	assert.True(t, reference.Add(-time.Second).Equal(wallClock))
+
+	rtpTimestamp, ok := m.RTPTimestamp(reference.Add(500*time.Millisecond), 90000)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(0), rtpTimestamp)
+}
+
+func TestRTPReceiver_UpdateClockMapper(t *testing.T) {
+	r := &RTPReceiver{}
+	raw, err := rtcp.Marshal([]rtcp.Packet{
+		&rtcp.SenderReport{SSRC: 1, NTPTime: uint64(ntpEpochOffset) << 32, RTPTime: 1000},
+		&rtcp.SourceDescription{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.updateClockMapper(raw)
+	wallClock, ok := r.clockMapper.WallClock(1000, 48000)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), wallClock.Unix())
+}
@@ -0,0 +1,26 @@
+package webrtc
+
+// sameCodec indicates if two codecs match in type, parameters,
+// etc, not checking payload type, so it is useful for comparing
+// codecs from different MediaEngines
+func sameCodec(codecA, codecB *RTPCodec) bool {
+	if codecA.Name != codecB.Name {
+		return false
+	}
+	if codecA.Type != codecB.Type {
+		return false
+	}
+	if codecA.SDPFmtpLine != codecB.SDPFmtpLine {
+		return false
+	}
+	if codecA.Channels != codecB.Channels {
+		return false
+	}
+	if codecA.ClockRate != codecB.ClockRate {
+		return false
+	}
+	if codecA.MimeType != codecB.MimeType {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,9 @@
+package webrtc
+
+// RTPCodecParameters is a codec as it is negotiated with the remote, the
+// capability together with the payload type it is sent with.
+// https://draft.ortc.org/#dom-rtcrtpcodecparameters
+type RTPCodecParameters struct {
+	RTPCodecCapability
+	PayloadType uint8 `json:"payloadType"`
+}
@@ -5,4 +5,8 @@ package webrtc
 // http://draft.ortc.org/#dom-rtcrtpdecodingparameters
 type RTPDecodingParameters struct {
 	RTPCodingParameters
+
+	// RID of the encoding, known once a packet tagged with the rtp-stream-id
+	// header extension has been received
+	RID string `json:"rid,omitempty"`
 }
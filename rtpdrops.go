@@ -0,0 +1,161 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/sdp/v2"
+)
+
+// srtpAuthFailure is how pion/srtp reports a packet that failed the
+// authentication, it only logs the packets it drops
+const srtpAuthFailure = "failed to verify auth tag"
+
+// rtpDrops counts the RTP packets a DTLSTransport received that no
+// RTPReceiver got, or that are of an unknown payload type, accessed
+// atomically
+type rtpDrops struct {
+	unknownSSRC        uint64
+	unknownPayloadType uint64
+	srtpAuthFailed     uint64
+	bufferFull         uint64
+	quotaExceeded      uint64
+}
+
+// remoteRTP is what the remote description announced the RTP it sends with
+type remoteRTP struct {
+	ssrcs        map[uint32]bool
+	payloadTypes map[uint8]bool
+}
+
+func newRemoteRTP(parsed *sdp.SessionDescription) *remoteRTP {
+	r := &remoteRTP{ssrcs: map[uint32]bool{}, payloadTypes: map[uint8]bool{}}
+	for _, media := range parsed.MediaDescriptions {
+		if NewRTPCodecType(media.MediaName.Media) == 0 {
+			continue
+		}
+		for _, format := range media.MediaName.Formats {
+			if payloadType, err := strconv.ParseUint(format, 10, 8); err == nil {
+				r.payloadTypes[uint8(payloadType)] = true
+			}
+		}
+		for _, a := range media.Attributes {
+			if a.Key != sdp.AttrKeySSRC {
+				continue
+			}
+			if ssrc, err := strconv.ParseUint(strings.Fields(a.Value + " ")[0], 10, 32); err == nil {
+				r.ssrcs[uint32(ssrc)] = true
+			}
+		}
+	}
+	return r
+}
+
+// rtpDemuxConn is the connection the SRTP session reads from. It counts the
+// packets of SSRCs the remote didn't announce, which no receiver reads, and
+// the packets of payload types that weren't negotiated. Packets exceeding
+// the inbound bitrate quota are dropped.
+type rtpDemuxConn struct {
+	net.Conn
+	transport *DTLSTransport
+}
+
+func (c *rtpDemuxConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(b)
+		if err != nil {
+			return n, err
+		}
+		if policer := c.transport.policer; policer != nil && !policer.allow(n, time.Now()) {
+			atomic.AddUint64(&c.transport.drops.quotaExceeded, 1)
+			continue
+		}
+		c.transport.inspectRTP(b[:n])
+		return n, nil
+	}
+}
+
+// srtpLoggerFactory creates the loggers of the SRTP sessions of a
+// DTLSTransport, which count the packets that failed the authentication
+type srtpLoggerFactory struct {
+	factory logging.LoggerFactory
+	drops   *rtpDrops
+}
+
+func (f *srtpLoggerFactory) NewLogger(scope string) logging.LeveledLogger {
+	return &srtpLogger{LeveledLogger: f.factory.NewLogger(scope), drops: f.drops}
+}
+
+type srtpLogger struct {
+	logging.LeveledLogger
+	drops *rtpDrops
+}
+
+func (l *srtpLogger) Infof(format string, args ...interface{}) {
+	if strings.Contains(fmt.Sprintf(format, args...), srtpAuthFailure) {
+		atomic.AddUint64(&l.drops.srtpAuthFailed, 1)
+	}
+	l.LeveledLogger.Infof(format, args...)
+}
+
+// setRemoteDescription sets the SSRCs and payload types received packets
+// are expected to have
+func (t *DTLSTransport) setRemoteDescription(parsed *sdp.SessionDescription) {
+	t.remoteRTP.Store(newRemoteRTP(parsed))
+}
+
+func (t *DTLSTransport) inspectRTP(b []byte) {
+	remote, ok := t.remoteRTP.Load().(*remoteRTP)
+	if !ok || len(b) < rtpHeaderSize {
+		return
+	}
+
+	switch {
+	case !remote.ssrcs[binary.BigEndian.Uint32(b[8:12])]:
+		atomic.AddUint64(&t.drops.unknownSSRC, 1)
+	case !remote.payloadTypes[b[1]&0x7F]:
+		atomic.AddUint64(&t.drops.unknownPayloadType, 1)
+	}
+}
+
+// countBufferFull accounts for a packet dropped because a reader didn't
+// keep up
+func (t *DTLSTransport) countBufferFull() {
+	atomic.AddUint64(&t.drops.bufferFull, 1)
+}
+
+func (t *DTLSTransport) srtpLoggerFactory() logging.LoggerFactory {
+	return &srtpLoggerFactory{factory: t.api.settingEngine.LoggerFactory, drops: &t.drops}
+}
+
+func (t *DTLSTransport) collectStats(collector *statsReportCollector) {
+	collector.Collecting()
+
+	t.lock.RLock()
+	stats := TransportStats{
+		Timestamp: statsTimestampNow(),
+		Type:      StatsTypeTransport,
+		ID:        t.statsID,
+		DTLSState: t.state,
+
+		PacketsDiscardedUnknownSSRC: atomic.LoadUint64(&t.drops.unknownSSRC),
+		PacketsUnknownPayloadType:   atomic.LoadUint64(&t.drops.unknownPayloadType),
+		PacketsDiscardedSRTPAuth:    atomic.LoadUint64(&t.drops.srtpAuthFailed),
+		PacketsDiscardedBufferFull:  atomic.LoadUint64(&t.drops.bufferFull),
+		PacketsDiscardedQuota:       atomic.LoadUint64(&t.drops.quotaExceeded),
+	}
+	if t.srtpEndpoint != nil {
+		stats.PacketsDiscardedBufferFull += t.srtpEndpoint.Dropped()
+	}
+	t.lock.RUnlock()
+
+	collector.Collect(stats.ID, stats)
+}
@@ -0,0 +1,54 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDTLSTransportDrops(t *testing.T) {
+	api := NewAPI()
+	transport, err := api.NewDTLSTransport(nil, nil)
+	assert.NoError(t, err)
+
+	raw := func(ssrc uint32, payloadType uint8) []byte {
+		b, err := (&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: ssrc, PayloadType: payloadType}}).Marshal()
+		assert.NoError(t, err)
+		return b
+	}
+
+	// Nothing is counted before the remote description is known
+	transport.inspectRTP(raw(1, 96))
+	assert.Zero(t, transport.drops.unknownSSRC)
+
+	parsed := &sdp.SessionDescription{}
+	assert.NoError(t, parsed.Unmarshal([]byte("v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n"+
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\na=rtpmap:111 opus/48000/2\r\na=ssrc:1000 cname:pion\r\n")))
+	transport.setRemoteDescription(parsed)
+
+	transport.inspectRTP(raw(1000, 111))
+	transport.inspectRTP(raw(1000, 42))
+	transport.inspectRTP(raw(2000, 111))
+	transport.inspectRTP([]byte{0x80})
+	transport.countBufferFull()
+
+	// pion/srtp only logs the packets that fail the authentication
+	log := transport.srtpLoggerFactory().NewLogger("srtp")
+	log.Infof("%v \n", errors.New(srtpAuthFailure))
+	log.Infof("%v \n", errors.New("something else"))
+
+	report := newStatsReportCollector()
+	transport.collectStats(report)
+	stats, ok := report.Ready()[transport.statsID].(TransportStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint64(1), stats.PacketsDiscardedUnknownSSRC)
+		assert.Equal(t, uint64(1), stats.PacketsUnknownPayloadType)
+		assert.Equal(t, uint64(1), stats.PacketsDiscardedSRTPAuth)
+		assert.Equal(t, uint64(1), stats.PacketsDiscardedBufferFull)
+	}
+}
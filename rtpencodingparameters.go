@@ -5,4 +5,15 @@ package webrtc
 // http://draft.ortc.org/#dom-rtcrtpencodingparameters
 type RTPEncodingParameters struct {
 	RTPCodingParameters
+
+	// RID identifies a simulcast encoding, RFC 8851. Packets of the encoding
+	// are tagged with it when the remote negotiated the rtp-stream-id header
+	// extension.
+	RID string `json:"rid,omitempty"`
+
+	// ScaleResolutionDownBy and MaxBitrate are hints for the application
+	// that encodes the media of the encoding, e.g. 4 and 150000 for the
+	// lowest layer of a 720p stream. Pion doesn't encode media itself.
+	ScaleResolutionDownBy float64 `json:"scaleResolutionDownBy,omitempty"`
+	MaxBitrate            uint64  `json:"maxBitrate,omitempty"`
 }
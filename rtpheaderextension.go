@@ -0,0 +1,143 @@
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
+)
+
+const (
+	// sdpAttrKeyExtMap maps an RTP header extension to its ID, RFC 8285
+	sdpAttrKeyExtMap = "extmap"
+
+	// sdesRTPStreamIDURI is the header extension carrying the RID of a
+	// simulcast encoding, RFC 8852
+	sdesRTPStreamIDURI = "urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id"
+
+	// defaultRTPStreamIDExtensionID is the ID the rtp-stream-id extension is
+	// offered with
+	defaultRTPStreamIDExtensionID = 10
+
+	// oneByteHeaderExtensionProfile marks the one-byte header extensions of
+	// RFC 8285 Section 4.2
+	oneByteHeaderExtensionProfile = 0xBEDE
+
+	// oneByteHeaderExtensionMaxLength is the largest element of a one-byte
+	// header extension
+	oneByteHeaderExtensionMaxLength = 16
+
+	// oneByteHeaderExtensionMaxID is the highest ID of a one-byte header
+	// extension, 15 is reserved
+	oneByteHeaderExtensionMaxID = 14
+)
+
+// headerExtensionID returns the ID the header extension with the given URI
+// has in a media section, or zero if it isn't announced
+func headerExtensionID(m *sdp.MediaDescription, uri string) uint8 {
+	for _, e := range headerExtensions(m) {
+		if e.URI == uri {
+			return e.ID
+		}
+	}
+	return 0
+}
+
+// headerExtensions returns the one-byte header extensions announced by a
+// media section
+func headerExtensions(m *sdp.MediaDescription) []RTPHeaderExtensionParameter {
+	var extensions []RTPHeaderExtensionParameter
+	for _, a := range m.Attributes {
+		if a.Key != sdpAttrKeyExtMap {
+			continue
+		}
+		fields := strings.Fields(a.Value)
+		if len(fields) < 2 {
+			continue
+		}
+		// The ID may be followed by a direction, e.g. 3/sendonly
+		id, err := strconv.ParseUint(strings.SplitN(fields[0], "/", 2)[0], 10, 8)
+		if err != nil || id < 1 || id > oneByteHeaderExtensionMaxID {
+			// Two-byte header extensions aren't supported
+			continue
+		}
+		extensions = append(extensions, RTPHeaderExtensionParameter{URI: fields[1], ID: uint8(id)})
+	}
+	return extensions
+}
+
+// setOneByteHeaderExtension adds an element to the one-byte header extension
+// of h, RFC 8285. Elements of an existing one-byte extension are kept, h
+// isn't changed if it carries a different kind of extension.
+func setOneByteHeaderExtension(h *rtp.Header, id uint8, payload []byte) bool {
+	if id < 1 || id > oneByteHeaderExtensionMaxID || len(payload) < 1 || len(payload) > oneByteHeaderExtensionMaxLength {
+		return false
+	}
+
+	var elements []byte
+	if h.Extension {
+		if h.ExtensionProfile != oneByteHeaderExtensionProfile {
+			return false
+		}
+		elements = trimHeaderExtensionPadding(h.ExtensionPayload)
+	}
+
+	out := make([]byte, 0, len(elements)+len(payload)+4)
+	out = append(out, elements...)
+	out = append(out, id<<4|byte(len(payload)-1))
+	out = append(out, payload...)
+	for len(out)%4 != 0 {
+		out = append(out, 0)
+	}
+
+	h.Extension = true
+	h.ExtensionProfile = oneByteHeaderExtensionProfile
+	h.ExtensionPayload = out
+	return true
+}
+
+// oneByteHeaderExtension returns the element with the given ID of the
+// one-byte header extension of h
+func oneByteHeaderExtension(h *rtp.Header, id uint8) ([]byte, bool) {
+	if !h.Extension || h.ExtensionProfile != oneByteHeaderExtensionProfile {
+		return nil, false
+	}
+
+	payload := h.ExtensionPayload
+	for i := 0; i < len(payload); {
+		if payload[i] == 0 {
+			// Padding between elements
+			i++
+			continue
+		}
+		elementID := payload[i] >> 4
+		length := int(payload[i]&0x0F) + 1
+		if elementID == 15 || i+1+length > len(payload) {
+			return nil, false
+		}
+		if elementID == id {
+			return payload[i+1 : i+1+length], true
+		}
+		i += 1 + length
+	}
+	return nil, false
+}
+
+// trimHeaderExtensionPadding returns the elements of a one-byte header
+// extension without the padding at its end
+func trimHeaderExtensionPadding(payload []byte) []byte {
+	end := 0
+	for i := 0; i < len(payload); {
+		if payload[i] == 0 {
+			i++
+			continue
+		}
+		i += 1 + int(payload[i]&0x0F) + 1
+		if i > len(payload) {
+			break
+		}
+		end = i
+	}
+	return payload[:end]
+}
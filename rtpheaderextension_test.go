@@ -0,0 +1,119 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOneByteHeaderExtension(t *testing.T) {
+	h := &rtp.Header{}
+	assert.True(t, setOneByteHeaderExtension(h, 10, []byte("hi")))
+	assert.Equal(t, uint16(oneByteHeaderExtensionProfile), h.ExtensionProfile)
+	assert.Equal(t, []byte{0xA1, 'h', 'i', 0x00}, h.ExtensionPayload)
+
+	// Elements are appended to an existing extension
+	assert.True(t, setOneByteHeaderExtension(h, 3, []byte("abcd")))
+	assert.Equal(t, []byte{0xA1, 'h', 'i', 0x33, 'a', 'b', 'c', 'd'}, h.ExtensionPayload)
+
+	value, ok := oneByteHeaderExtension(h, 10)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("hi"), value)
+	value, ok = oneByteHeaderExtension(h, 3)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("abcd"), value)
+	_, ok = oneByteHeaderExtension(h, 4)
+	assert.False(t, ok)
+
+	// The extension survives marshaling
+	raw, err := h.Marshal()
+	assert.NoError(t, err)
+	parsed := &rtp.Header{}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	value, ok = oneByteHeaderExtension(parsed, 3)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("abcd"), value)
+
+	assert.False(t, setOneByteHeaderExtension(h, 15, []byte("x")))
+	assert.False(t, setOneByteHeaderExtension(h, 1, make([]byte, 17)))
+	assert.False(t, setOneByteHeaderExtension(&rtp.Header{Extension: true, ExtensionProfile: 0x1000}, 1, []byte("x")))
+}
+
+func TestTrackHeaderExtensions(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	const negotiated, offerOnly = "urn:example:negotiated", "urn:example:offer-only"
+
+	offerEngine := MediaEngine{}
+	offerEngine.RegisterDefaultCodecs()
+	assert.NoError(t, offerEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: offerOnly}, RTPCodecTypeVideo))
+	assert.NoError(t, offerEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: negotiated}, RTPCodecTypeVideo))
+	pcOffer, err := NewAPI(WithMediaEngine(offerEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerEngine := MediaEngine{}
+	answerEngine.RegisterDefaultCodecs()
+	assert.NoError(t, answerEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: negotiated}, RTPCodecTypeVideo))
+	pcAnswer, err := NewAPI(WithMediaEngine(answerEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrack := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		onTrack <- remote
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSampleWithExtensions(media.Sample{Data: []byte{0x00}, Samples: 1}, map[string][]byte{
+					negotiated: []byte("meta"),
+					offerOnly:  []byte("x"),
+				})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	offer := pcAnswer.RemoteDescription().SDP
+	assert.Contains(t, offer, "a=extmap:1 "+offerOnly+"\r\n")
+	assert.Contains(t, offer, "a=extmap:2 "+negotiated+"\r\n")
+	answer := pcOffer.RemoteDescription().SDP
+	assert.Contains(t, answer, "a=extmap:2 "+negotiated+"\r\n")
+	assert.NotContains(t, answer, offerOnly)
+
+	remote := <-onTrack
+	pkt, err := remote.ReadRTP()
+	assert.NoError(t, err)
+	payload, ok := remote.HeaderExtension(pkt, negotiated)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("meta"), payload)
+
+	// Extensions the remote didn't agree to aren't sent
+	_, ok = oneByteHeaderExtension(&pkt.Header, 1)
+	assert.False(t, ok)
+	_, ok = remote.HeaderExtension(pkt, offerOnly)
+	assert.False(t, ok)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
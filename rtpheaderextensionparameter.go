@@ -0,0 +1,8 @@
+package webrtc
+
+// RTPHeaderExtensionParameter is a negotiated RTP header extension, RFC 8285
+// http://draft.ortc.org/#dom-rtcrtpheaderextensionparameters
+type RTPHeaderExtensionParameter struct {
+	URI string `json:"uri"`
+	ID  uint8  `json:"id"`
+}
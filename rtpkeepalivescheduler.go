@@ -0,0 +1,110 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+)
+
+// rtpKeepAliveScheduler sends the RTP keep-alives of every RTPSender of an
+// API and the APIs derived from it from a single goroutine, instead of one
+// goroutine and ticker per sender. The goroutine only runs while there are
+// senders to serve, it ticks at the smallest keep-alive interval of them.
+//
+// A keep-alive is sent from a short lived goroutine, so a sender whose write
+// blocks doesn't hold back the others. The sender is skipped until its
+// keep-alive returned.
+//
+// Only the send side is shared. The receive side still waits for the first
+// packet of every track in a goroutine of its own, see startReceiver, and
+// drainSRTP accepts the unhandled SRTP and SRTCP streams in one goroutine
+// each, as pion/srtp only offers blocking reads.
+type rtpKeepAliveScheduler struct {
+	log logging.LeveledLogger
+
+	mu      sync.Mutex
+	senders map[*RTPSender]*rtpKeepAliveEntry
+	running bool
+}
+
+type rtpKeepAliveEntry struct {
+	interval time.Duration
+	sending  bool
+}
+
+func newRTPKeepAliveScheduler(log logging.LeveledLogger) *rtpKeepAliveScheduler {
+	return &rtpKeepAliveScheduler{
+		log:     log,
+		senders: map[*RTPSender]*rtpKeepAliveEntry{},
+	}
+}
+
+func (s *rtpKeepAliveScheduler) add(r *RTPSender, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.senders[r] = &rtpKeepAliveEntry{interval: interval}
+	if !s.running {
+		s.running = true
+		go s.run()
+	}
+}
+
+func (s *rtpKeepAliveScheduler) remove(r *RTPSender) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.senders, r)
+}
+
+// next returns the smallest interval of the senders, or false once there are
+// no senders left and run has to exit
+func (s *rtpKeepAliveScheduler) next() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var interval time.Duration
+	for _, e := range s.senders {
+		if interval == 0 || e.interval < interval {
+			interval = e.interval
+		}
+	}
+	if interval == 0 {
+		s.running = false
+		return 0, false
+	}
+	return interval, true
+}
+
+func (s *rtpKeepAliveScheduler) run() {
+	for {
+		interval, ok := s.next()
+		if !ok {
+			return
+		}
+		time.Sleep(interval)
+
+		s.mu.Lock()
+		for r, e := range s.senders {
+			if e.sending {
+				continue
+			}
+			e.sending = true
+			go s.keepAlive(r, e)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *rtpKeepAliveScheduler) keepAlive(r *RTPSender, e *rtpKeepAliveEntry) {
+	if err := r.keepAlive(e.interval); err != nil {
+		s.log.Warnf("Failed to send RTP keep-alive: %v", err)
+	}
+
+	s.mu.Lock()
+	e.sending = false
+	s.mu.Unlock()
+}
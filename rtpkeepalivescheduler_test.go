@@ -0,0 +1,69 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPKeepAliveSchedulerStopsWhenEmpty(t *testing.T) {
+	s := newRTPKeepAliveScheduler(logging.NewDefaultLoggerFactory().NewLogger("test"))
+
+	senders := []*RTPSender{
+		{stopCalled: make(chan interface{})},
+		{stopCalled: make(chan interface{})},
+	}
+	for _, r := range senders {
+		s.add(r, time.Millisecond)
+	}
+
+	running := func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.running
+	}
+	assert.True(t, running())
+
+	// A single goroutine serves every sender and exits once all of them are gone
+	for _, r := range senders {
+		s.remove(r)
+	}
+	deadline := time.Now().Add(time.Second)
+	for running() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.False(t, running())
+}
+
+func TestRTPKeepAliveSchedulerBlockedSender(t *testing.T) {
+	s := newRTPKeepAliveScheduler(logging.NewDefaultLoggerFactory().NewLogger("test"))
+	api := &API{rtpKeepAlive: s}
+
+	// The keep-alive of blocked waits for its injection lock, stopped removes
+	// itself with its next keep-alive
+	blocked := &RTPSender{api: api, stopCalled: make(chan interface{})}
+	stopped := &RTPSender{api: api, stopCalled: make(chan interface{})}
+	close(stopped.stopCalled)
+	blocked.injectMu.Lock()
+	s.add(blocked, time.Millisecond)
+	s.add(stopped, 2*time.Millisecond)
+
+	served := func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_, ok := s.senders[stopped]
+		return !ok
+	}
+	deadline := time.Now().Add(time.Second)
+	for !served() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, served())
+
+	blocked.injectMu.Unlock()
+	s.remove(blocked)
+}
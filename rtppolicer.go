@@ -0,0 +1,52 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// rtpPolicer is a token bucket that limits the aggregate bitrate of the RTP
+// received by a DTLSTransport, see Quotas.MaxInboundBitrate
+type rtpPolicer struct {
+	mu sync.Mutex
+
+	// rate the bucket is filled with and its size, in bytes
+	rate, burst float64
+
+	tokens float64
+	last   time.Time
+}
+
+// newRTPPolicer returns a policer for bitrate that starts with a full
+// bucket, or nil if bitrate is 0
+func newRTPPolicer(bitrate uint64) *rtpPolicer {
+	if bitrate == 0 {
+		return nil
+	}
+
+	rate := float64(bitrate) / 8
+	return &rtpPolicer{rate: rate, burst: rate, tokens: rate}
+}
+
+// allow takes size bytes from the bucket and reports whether the packet
+// conforms to the bitrate. Dropped packets don't take tokens.
+func (p *rtpPolicer) allow(size int, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.last.IsZero() {
+		p.tokens += now.Sub(p.last).Seconds() * p.rate
+		if p.tokens > p.burst {
+			p.tokens = p.burst
+		}
+	}
+	p.last = now
+
+	if float64(size) > p.tokens {
+		return false
+	}
+	p.tokens -= float64(size)
+	return true
+}
@@ -0,0 +1,68 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPPolicer(t *testing.T) {
+	assert.Nil(t, newRTPPolicer(0))
+
+	// 8000 bits per second are 1000 bytes, the bucket starts full
+	p := newRTPPolicer(8000)
+	now := time.Now()
+	assert.True(t, p.allow(600, now))
+	assert.True(t, p.allow(400, now))
+	assert.False(t, p.allow(1, now))
+
+	// Dropped packets don't take tokens
+	now = now.Add(time.Second / 2)
+	assert.False(t, p.allow(600, now))
+	assert.True(t, p.allow(500, now))
+
+	// The bucket doesn't fill beyond a second of the bitrate
+	now = now.Add(time.Minute)
+	assert.False(t, p.allow(1001, now))
+	assert.True(t, p.allow(1000, now))
+}
+
+func TestRTPDemuxConnQuota(t *testing.T) {
+	s := SettingEngine{}
+	s.SetQuotas(Quotas{MaxInboundBitrate: 8000})
+	transport, err := NewAPI(WithSettingEngine(s)).NewDTLSTransport(nil, nil)
+	assert.NoError(t, err)
+
+	local, remote := net.Pipe()
+	conn := &rtpDemuxConn{Conn: local, transport: transport}
+	go func() {
+		// The second packet exceeds the quota and is skipped
+		for _, size := range []int{800, 800, 100} {
+			if _, writeErr := remote.Write(make([]byte, size)); writeErr != nil {
+				return
+			}
+		}
+	}()
+
+	b := make([]byte, receiveMTU)
+	n, err := conn.Read(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 800, n)
+	n, err = conn.Read(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+
+	report := newStatsReportCollector()
+	transport.collectStats(report)
+	stats, ok := report.Ready()[transport.statsID].(TransportStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint64(1), stats.PacketsDiscardedQuota)
+	}
+
+	assert.NoError(t, local.Close())
+	assert.NoError(t, remote.Close())
+}
@@ -3,4 +3,16 @@ package webrtc
 // RTPReceiveParameters contains the RTP stack settings used by receivers
 type RTPReceiveParameters struct {
 	Encodings RTPDecodingParameters
+
+	// Codecs are the codecs negotiated for the receiver with the payload
+	// types of the remote, Track.Codec is the one the track is sent with
+	Codecs []RTPCodecParameters
+
+	// SSRCGroups are the groups the SSRC of the encoding is part of, e.g. the
+	// simulcast layers announced by the remote
+	SSRCGroups []SSRCGroup
+
+	// HeaderExtensions are the header extensions negotiated for the
+	// receiver, see Track.HeaderExtension
+	HeaderExtensions []RTPHeaderExtensionParameter
 }
@@ -5,11 +5,23 @@ package webrtc
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/srtp"
+	"github.com/pion/transport/packetio"
 )
 
+// trackCloneBufferSize is the amount of RTP that is buffered for every Track
+// handle of a cloned track before packets are dropped for that handle
+const trackCloneBufferSize = 1000 * 1000 // 1MB
+
+// defaultKeyFrameRequestInterval is the window key frame requests of an
+// RTPReceiver are consolidated in unless the SettingEngine sets another one
+const defaultKeyFrameRequestInterval = time.Second / 2
+
 // RTPReceiver allows an application to inspect the receipt of a Track
 type RTPReceiver struct {
 	kind      RTPCodecType
@@ -20,9 +32,35 @@ type RTPReceiver struct {
 	closed, received chan interface{}
 	mu               sync.RWMutex
 
+	parameters RTPReceiveParameters
+
 	rtpReadStream  *srtp.ReadStreamSRTP
 	rtcpReadStream *srtp.ReadStreamSRTCP
 
+	statsID    string
+	frameStats videoFrameStats
+	counters   trackCounters
+	reception  receptionStats
+	losses     lossHistory
+
+	clockMapper RTPClockMapper
+
+	// paused is set by a PAUSED indication of the remote and cleared by the
+	// next packet, accessed atomically
+	paused                int32
+	onPausedChangeHandler func(paused bool)
+
+	keyFrames keyFrameRequester
+	// firSequenceNumber is only used by the requests of keyFrames, which
+	// are sent with its lock held
+	firSequenceNumber uint8
+
+	// readMu is held by the Track handle that reads the RTP stream. Packets are
+	// copied into the buffers of all other handles, see Track.Clone
+	readMu    sync.Mutex
+	handlesMu sync.Mutex
+	handles   []*Track
+
 	// A reference to the associated api object
 	api *API
 }
@@ -36,6 +74,7 @@ func (api *API) NewRTPReceiver(kind RTPCodecType, transport *DTLSTransport) (*RT
 	return &RTPReceiver{
 		kind:      kind,
 		transport: transport,
+		statsID:   fmt.Sprintf("RTPReceiver-%d", time.Now().UnixNano()),
 		api:       api,
 		closed:    make(chan interface{}),
 		received:  make(chan interface{}),
@@ -57,6 +96,47 @@ func (r *RTPReceiver) Track() *Track {
 	return r.track
 }
 
+// GetParameters returns the parameters Receive was called with, including
+// the RTX stream and the SSRC groups announced by the remote
+func (r *RTPReceiver) GetParameters() RTPReceiveParameters {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.parameters
+}
+
+// headerExtension returns the payload of a negotiated header extension of p
+func (r *RTPReceiver) headerExtension(p *rtp.Packet, uri string) ([]byte, bool) {
+	for _, e := range r.GetParameters().HeaderExtensions {
+		if e.URI == uri {
+			return oneByteHeaderExtension(&p.Header, e.ID)
+		}
+	}
+	return nil, false
+}
+
+// setFirstPacket completes the parameters with what is only known once the
+// first packet has been received: its payload type and its RID
+func (r *RTPReceiver) setFirstPacket(p *rtp.Packet) {
+	rid, hasRID := r.headerExtension(p, sdesRTPStreamIDURI)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parameters.Encodings.PayloadType = p.PayloadType
+	if hasRID {
+		r.parameters.Encodings.RID = string(rid)
+	}
+}
+
+// hasReceived tells if Receive has been called
+func (r *RTPReceiver) hasReceived() bool {
+	select {
+	case <-r.received:
+		return true
+	default:
+		return false
+	}
+}
+
 // Receive initialize the track and starts all the transports
 func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 	r.mu.Lock()
@@ -68,6 +148,7 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 	}
 	close(r.received)
 
+	r.parameters = parameters
 	r.track = &Track{
 		kind:     r.kind,
 		ssrc:     parameters.Encodings.SSRC,
@@ -97,10 +178,97 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 	return nil
 }
 
-// Read reads incoming RTCP for this RTPReceiver
+// Read reads incoming RTCP for this RTPReceiver. SenderReports are passed to
+// the RTPClockMapper of the track as well.
 func (r *RTPReceiver) Read(b []byte) (n int, err error) {
 	<-r.received
-	return r.rtcpReadStream.Read(b)
+	if n, err = r.rtcpReadStream.Read(b); err != nil {
+		return n, err
+	}
+	if n, err = r.api.interceptors.readRTCP(b, n); err != nil {
+		return n, err
+	}
+	r.updateClockMapper(b[:n])
+	r.handlePausedIndication(b[:n])
+	return n, nil
+}
+
+// updateClockMapper looks for a SenderReport at the start of a compound
+// packet, where RFC 3550 puts it
+func (r *RTPReceiver) updateClockMapper(b []byte) {
+	const packetTypeOffset = 1
+	if len(b) <= packetTypeOffset || rtcp.PacketType(b[packetTypeOffset]) != rtcp.TypeSenderReport {
+		return
+	}
+
+	header := &rtcp.Header{}
+	if err := header.Unmarshal(b); err != nil {
+		return
+	}
+	size := (int(header.Length) + 1) * 4
+	if size > len(b) {
+		return
+	}
+
+	sr := &rtcp.SenderReport{}
+	if err := sr.Unmarshal(b[:size]); err != nil {
+		return
+	}
+	r.clockMapper.UpdateSenderReport(sr)
+}
+
+// handlePausedIndication looks for a PAUSED indication of RFC 7728 for the
+// track in the compound packet b
+func (r *RTPReceiver) handlePausedIndication(b []byte) {
+	for _, packet := range feedbackMessages(b, rtcp.TypeTransportSpecificFeedback, formatPauseResume) {
+		indication := &pauseResume{}
+		if err := indication.Unmarshal(packet); err != nil {
+			continue
+		}
+		for _, e := range indication.Entries {
+			if e.Type == pauseResumeTypePaused && e.TargetSSRC == r.parameters.Encodings.SSRC {
+				r.setPaused(true)
+			}
+		}
+	}
+}
+
+func (r *RTPReceiver) setPaused(paused bool) {
+	value := int32(0)
+	if paused {
+		value = 1
+	}
+	if atomic.SwapInt32(&r.paused, value) == value {
+		return
+	}
+
+	if !paused {
+		// The time the remote paused for isn't a freeze
+		r.frameStats.resume()
+	}
+
+	r.mu.RLock()
+	handler := r.onPausedChangeHandler
+	r.mu.RUnlock()
+	if handler != nil {
+		handler(paused)
+	}
+}
+
+// Paused tells if the remote paused sending the track with a PAUSED
+// indication, see RTPSender.Pause. The receiver is paused until the next
+// packet arrives. Indications are only seen while the RTCP of the receiver
+// is read.
+func (r *RTPReceiver) Paused() bool {
+	return atomic.LoadInt32(&r.paused) == 1
+}
+
+// OnPausedChange sets an event handler which is called when the remote
+// pauses sending the track and when it resumes, see Paused.
+func (r *RTPReceiver) OnPausedChange(f func(paused bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onPausedChangeHandler = f
 }
 
 // ReadRTCP is a convenience method that wraps Read and unmarshals for you
@@ -114,6 +282,89 @@ func (r *RTPReceiver) ReadRTCP() ([]rtcp.Packet, error) {
 	return rtcp.Unmarshal(b[:i])
 }
 
+// RequestKeyFrame asks the remote sender of the track for a key frame. A Full
+// Intra Request is sent if the RTCPFeedback of the codec lists "ccm fir" but
+// not "nack pli", a Picture Loss Indication otherwise.
+//
+// Requests are consolidated so every subscriber of a track can call it
+// without loading the encoder of the remote: at most one request is sent per
+// key frame request interval, see SettingEngine.SetKeyFrameRequestInterval.
+// A call while a requested key frame hasn't arrived yet is served by that key
+// frame. A call within the interval after it arrived is sent when the
+// interval ends, unless another key frame arrives first. Key frames are
+// detected for VP8 and H264, other codecs are requested once per interval.
+// Nothing is requested while the remote paused the track, see Paused.
+func (r *RTPReceiver) RequestKeyFrame() error {
+	if !r.hasReceived() {
+		return ErrRTPReceiverNotStarted
+	}
+	if r.Paused() {
+		return nil
+	}
+	track := r.Track()
+
+	interval := r.api.settingEngine.timeout.KeyFrameRequest
+	if interval == 0 {
+		interval = defaultKeyFrameRequestInterval
+	}
+
+	return r.keyFrames.request(time.Now(), interval, func() error {
+		var pkt rtcp.Packet = &rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}
+		if requestsFullIntra(track.Codec()) {
+			r.firSequenceNumber++
+			pkt = &fullIntraRequest{MediaSSRC: track.SSRC(), SequenceNumber: r.firSequenceNumber}
+		}
+		return r.writeRTCP([]rtcp.Packet{pkt})
+	})
+}
+
+// ExtendedReport returns an RTCP XR of the track, RFC 3611, with a Loss RLE
+// report block of the packets received since the previous call and a
+// receiver reference time, which the sender answers with a DLRR block. The
+// application writes it along with its receiver reports: pion/srtp only
+// delivers RTCP about a stream that has a report or source description in
+// the same compound packet, so a lone XR doesn't reach senders of this
+// package.
+func (r *RTPReceiver) ExtendedReport() (*ExtendedReport, error) {
+	if !r.hasReceived() {
+		return nil, ErrRTPReceiverNotStarted
+	}
+
+	report := &ExtendedReport{ReceiverReferenceTime: timeToNTP(time.Now())}
+	if block, ok := r.losses.report(r.Track().SSRC()); ok {
+		report.LossRLE = append(report.LossRLE, block)
+	}
+	return report, nil
+}
+
+// requestsFullIntra tells if key frames of codec have to be requested with
+// a FIR because PLI isn't enabled for it
+func requestsFullIntra(codec *RTPCodec) bool {
+	if codec == nil {
+		return false
+	}
+
+	fir := false
+	for _, feedback := range codec.RTCPFeedback {
+		switch {
+		case feedback.Type == "nack" && feedback.Parameter == "pli":
+			return false
+		case feedback.Type == "ccm" && feedback.Parameter == "fir":
+			fir = true
+		}
+	}
+	return fir
+}
+
+// writeRTCP sends RTCP for this RTPReceiver through the API interceptors
+func (r *RTPReceiver) writeRTCP(pkts []rtcp.Packet) error {
+	pkts, err := r.api.interceptors.writeRTCP(pkts)
+	if err != nil {
+		return err
+	}
+	return r.transport.writeRTCP(pkts)
+}
+
 // Stop irreversibly stops the RTPReceiver
 func (r *RTPReceiver) Stop() error {
 	r.mu.Lock()
@@ -136,12 +387,134 @@ func (r *RTPReceiver) Stop() error {
 	default:
 	}
 
+	r.keyFrames.stop()
 	close(r.closed)
 	return nil
 }
 
 // readRTP should only be called by a track, this only exists so we can keep state in one place
-func (r *RTPReceiver) readRTP(b []byte) (n int, err error) {
+func (r *RTPReceiver) readRTP(b []byte, reader *Track) (n int, err error) {
 	<-r.received
-	return r.rtpReadStream.Read(b)
+
+	for {
+		buffer := reader.getReadBuffer()
+		if buffer != nil && buffer.Count() != 0 {
+			return buffer.Read(b)
+		}
+
+		r.readMu.Lock()
+		// Another handle may have filled our buffer while we were waiting
+		if buffer = reader.getReadBuffer(); buffer != nil && buffer.Count() != 0 {
+			r.readMu.Unlock()
+			continue
+		}
+
+		n, err = r.rtpReadStream.Read(b)
+		if err == nil {
+			n, err = r.api.interceptors.readRTP(b, n)
+		}
+		if err == nil {
+			r.distribute(b[:n], reader)
+		}
+		r.readMu.Unlock()
+
+		if err == nil {
+			r.updateStats(b[:n])
+		}
+		return n, err
+	}
+}
+
+// distribute copies a packet read by reader into the buffers of all other handles
+func (r *RTPReceiver) distribute(b []byte, reader *Track) {
+	r.handlesMu.Lock()
+	handles := r.handles
+	r.handlesMu.Unlock()
+
+	for _, h := range handles {
+		if h == reader {
+			continue
+		}
+		// A full buffer only drops packets for the handle that doesn't keep up
+		if _, err := h.getReadBuffer().Write(b); err == packetio.ErrFull {
+			r.transport.countBufferFull()
+		}
+	}
+}
+
+// addHandle registers a Track handle that receives a copy of every packet.
+// The original track gets a buffer as well once the first clone exists.
+func (r *RTPReceiver) addHandle(original, clone *Track) {
+	r.handlesMu.Lock()
+	defer r.handlesMu.Unlock()
+
+	if original.getReadBuffer() == nil {
+		original.setReadBuffer(newTrackReadBuffer())
+		r.handles = append(r.handles, original)
+	}
+
+	clone.setReadBuffer(newTrackReadBuffer())
+	r.handles = append(r.handles, clone)
+}
+
+func newTrackReadBuffer() *packetio.Buffer {
+	b := packetio.NewBuffer()
+	b.SetLimitSize(trackCloneBufferSize)
+	return b
+}
+
+// updateStats accounts for a packet read from the stream
+func (r *RTPReceiver) updateStats(b []byte) {
+	p := &rtp.Packet{}
+	if err := unmarshalRTP(p, b); err != nil {
+		return
+	}
+
+	codecName := ""
+	var clockRate uint32
+	if track := r.Track(); track != nil {
+		if codec := track.Codec(); codec != nil {
+			codecName = codec.Name
+			clockRate = codec.ClockRate
+		}
+	}
+
+	// Padding-only packets are keep-alives, the sender is paused until it
+	// sends media again
+	if r.Paused() && !isPaddingOnly(p) {
+		r.setPaused(false)
+	}
+
+	now := time.Now()
+	r.counters.update(p.SequenceNumber, len(b), now)
+	r.reception.update(&p.Header, clockRate, now)
+	r.losses.update(p.SequenceNumber)
+	if r.kind != RTPCodecTypeVideo {
+		return
+	}
+	if r.frameStats.update(&p.Header, p.Payload, codecName, now) {
+		r.keyFrames.keyFrameReceived()
+	}
+}
+
+// isPaddingOnly reports whether p carries no media, e.g. an RTP keep-alive.
+// The last byte of the payload of a padded packet is the padding length.
+func isPaddingOnly(p *rtp.Packet) bool {
+	return p.Padding && len(p.Payload) != 0 && int(p.Payload[len(p.Payload)-1]) >= len(p.Payload)
+}
+
+func (r *RTPReceiver) collectStats(collector *statsReportCollector) {
+	if r.kind != RTPCodecTypeVideo {
+		return
+	}
+	collector.Collecting()
+
+	stats := VideoReceiverStats{
+		Timestamp: statsTimestampNow(),
+		Type:      StatsTypeReceiver,
+		ID:        r.statsID,
+	}
+	r.frameStats.fill(&stats, time.Now())
+
+	collector.Collect(stats.ID, stats)
 }
@@ -0,0 +1,113 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPReceiver_RequestKeyFrame(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetKeyFrameRequestInterval(time.Hour)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	receivers := make(chan *RTPReceiver, 1)
+	pcAnswer.OnTrack(func(_ *Track, receiver *RTPReceiver) {
+		receivers <- receiver
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	receiver := <-receivers
+
+	assert.NoError(t, receiver.RequestKeyFrame())
+	assert.NoError(t, receiver.RequestKeyFrame())
+
+	for {
+		pkts, err := sender.ReadRTCP()
+		assert.NoError(t, err)
+
+		if pli, ok := pkts[0].(*rtcp.PictureLossIndication); ok {
+			assert.Equal(t, track.SSRC(), pli.MediaSSRC)
+			break
+		}
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPReceiver_RequestKeyFrameNotStarted(t *testing.T) {
+	api := NewAPI()
+	receiver, err := api.NewRTPReceiver(RTPCodecTypeVideo, &DTLSTransport{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrRTPReceiverNotStarted, receiver.RequestKeyFrame())
+}
+
+func TestRequestsFullIntra(t *testing.T) {
+	codec := NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)
+	assert.False(t, requestsFullIntra(codec))
+
+	codec.RTCPFeedback = []RTCPFeedback{{Type: "ccm", Parameter: "fir"}}
+	assert.True(t, requestsFullIntra(codec))
+
+	codec.RTCPFeedback = append(codec.RTCPFeedback, RTCPFeedback{Type: "nack", Parameter: "pli"})
+	assert.False(t, requestsFullIntra(codec))
+
+	assert.False(t, requestsFullIntra(nil))
+}
+
+func TestFullIntraRequest(t *testing.T) {
+	fir := fullIntraRequest{SenderSSRC: 1, MediaSSRC: 0x902f9e2e, SequenceNumber: 7}
+	raw, err := fir.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x84, 0xce, 0x00, 0x04,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x90, 0x2f, 0x9e, 0x2e,
+		0x07, 0x00, 0x00, 0x00,
+	}, raw)
+
+	parsed := fullIntraRequest{}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Equal(t, fir, parsed)
+	assert.Equal(t, []uint32{0x902f9e2e}, parsed.DestinationSSRC())
+
+	assert.Error(t, parsed.Unmarshal(raw[:12]))
+}
@@ -0,0 +1,8 @@
+package webrtc
+
+// RTPRtxParameters dictionary contains information relating to retransmission (RTX) settings.
+// http://draft.ortc.org/#dom-rtcrtprtxparameters
+type RTPRtxParameters struct {
+	// SSRC of the RTX stream, zero if there is none
+	SSRC uint32 `json:"ssrc"`
+}
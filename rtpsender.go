@@ -3,14 +3,32 @@
 package webrtc
 
 import (
+	"encoding/binary"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/pion/logging"
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/srtp"
 )
 
+const (
+	// rtpKeepAlivePaddingSize is the amount of padding carried by RTP keep-alive packets
+	rtpKeepAlivePaddingSize = 1
+
+	// rtpMaxPaddingSize is the most padding a single RTP packet can carry
+	rtpMaxPaddingSize = 255
+
+	// rtpHeaderSize is the size of an RTP header without CSRCs or extensions
+	rtpHeaderSize = 12
+
+	// probeInterval is how often padding is sent while a ProbeCluster is running
+	probeInterval = 10 * time.Millisecond
+)
+
 // RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
 type RTPSender struct {
 	track          *Track
@@ -18,6 +36,10 @@ type RTPSender struct {
 
 	transport *DTLSTransport
 
+	// peerConnection the sender was created by, nil for senders of the ORTC
+	// API. Set before the sender is started.
+	peerConnection *PeerConnection
+
 	// A reference to the associated api object
 	api *API
 
@@ -25,6 +47,75 @@ type RTPSender struct {
 	sendCalled, stopCalled chan interface{}
 
 	payloadType *uint8 // Senders should have a codec parameter dictionary at some point
+
+	// Packets that don't originate from the track (keep-alives, padding) are
+	// injected into the stream of the SSRC of lastHeader, the last packet
+	// sent. sendStreams keep the sequence of every SSRC continuous, the one of
+	// the track and the ones of its simulcast encodings.
+	injectMu    sync.Mutex
+	writeStream *srtp.WriteStreamSRTP
+	lastHeader  *rtp.Header
+	lastSent    time.Time
+	sendStreams map[uint32]*rtpSendStream
+
+	// retransmissions keeps the sent packets to answer NACKs, nil unless
+	// enabled by SettingEngine.SetRetransmissionBuffer. rtxSequenceNumber
+	// is the sequence of the RTX stream, guarded by injectMu.
+	retransmissions   *retransmissionBuffer
+	rtxSequenceNumber uint16
+
+	// Packets written to the track while paused are dropped, pauseID counts
+	// the pauses as RFC 7728 asks for
+	paused  bool
+	pauseID uint16
+
+	probing bool
+
+	// headerExtensionIDs are the IDs of the negotiated header extensions by
+	// their URI. Simulcast encodings of the track are tagged with their RID
+	// if rtp-stream-id is negotiated, rids maps the SSRCs of the encodings
+	// to their RID. All of them are set by Send.
+	headerExtensionIDs map[string]uint8
+	ridExtensionID     uint8
+	rids               map[uint32]string
+
+	// encodingRTCPStreams read the RTCP of the encodings after the first
+	encodingRTCPStreams map[string]*srtp.ReadStreamSRTCP
+
+	// redPayloadType is the payload type of the RED codec the remote
+	// negotiated for the codec of the track, zero if it didn't. Packets are
+	// wrapped by red while the track has a redundancy, see SetRedundancy.
+	redPayloadType uint8
+	red            redEncoder
+
+	// ptime and maxptime announced by the remote for the media section of
+	// this sender, accessed atomically since they are read by the track while
+	// holding its lock
+	remotePTime    int64
+	remoteMaxPTime int64
+
+	// maxBitrate is the limit of the last TMMBR the remote sent for the
+	// track, accessed atomically
+	maxBitrate                 uint64
+	onMaxBitrateRequestHandler func(bitrate uint64)
+
+	// estimatedBitrate is the share of the track of the last REMB, and
+	// targetBitrate the last target handed to the handler, accessed
+	// atomically
+	estimatedBitrate             uint64
+	targetBitrate                uint64
+	onTargetBitrateChangeHandler func(TargetBitrate)
+
+	// congested is 1 while the target bitrate is below the bitrate the track
+	// is sent with, accessed atomically
+	congested int32
+
+	// remoteInbound has the reception reports of the remote about the
+	// track, the remote-inbound-rtp stats of the sender
+	statsID       string
+	remoteInbound remoteInboundStats
+
+	log logging.LeveledLogger
 }
 
 // NewRTPSender constructs a new RTPSender
@@ -48,6 +139,13 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) (*RTPSender
 		api:        api,
 		sendCalled: make(chan interface{}),
 		stopCalled: make(chan interface{}),
+		statsID:    fmt.Sprintf("RTPSender-%d", time.Now().UnixNano()),
+		log:        api.settingEngine.LoggerFactory.NewLogger("RTPSender"),
+
+		retransmissions: newRetransmissionBuffer(
+			api.settingEngine.retransmission.MaxAge,
+			api.settingEngine.retransmission.MaxBytes,
+		),
 	}, nil
 }
 
@@ -78,11 +176,41 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 		return err
 	}
 
+	r.headerExtensionIDs = map[string]uint8{}
+	for _, e := range parameters.HeaderExtensions {
+		r.headerExtensionIDs[e.URI] = e.ID
+	}
+	r.ridExtensionID = r.headerExtensionIDs[sdesRTPStreamIDURI]
+
 	r.track.mu.Lock()
+	encodings := r.track.encodings
 	r.track.activeSenders = append(r.track.activeSenders, r)
+	onBindHandler := r.track.onBindHandler
 	r.track.mu.Unlock()
 
+	if onBindHandler != nil {
+		onBindHandler(r.binding())
+	}
+
+	if len(encodings) != 0 {
+		r.rids = map[uint32]string{}
+		r.encodingRTCPStreams = map[string]*srtp.ReadStreamSRTCP{}
+	}
+	for i, e := range encodings {
+		r.rids[e.SSRC()] = e.RID()
+		if i == 0 {
+			continue
+		}
+		if r.encodingRTCPStreams[e.RID()], err = srtcpSession.OpenReadStream(e.SSRC()); err != nil {
+			return err
+		}
+	}
+
 	close(r.sendCalled)
+
+	if interval := r.api.settingEngine.timeout.RTPKeepAlive; interval != 0 {
+		r.api.rtpKeepAlive.add(r, interval)
+	}
 	return nil
 }
 
@@ -98,29 +226,55 @@ func (r *RTPSender) Stop() error {
 	}
 
 	r.track.mu.Lock()
-	defer r.track.mu.Unlock()
 	filtered := []*RTPSender{}
+	unbound := false
 	for _, s := range r.track.activeSenders {
 		if s != r {
 			filtered = append(filtered, s)
 		} else {
 			r.track.totalSenderCount--
+			unbound = true
 		}
 	}
 	r.track.activeSenders = filtered
+	onUnbindHandler := r.track.onUnbindHandler
+	r.track.mu.Unlock()
+
+	if unbound && onUnbindHandler != nil {
+		onUnbindHandler(r.binding())
+	}
 	close(r.stopCalled)
+	r.api.rtpKeepAlive.remove(r)
 
 	if r.hasSent() {
+		for _, stream := range r.encodingRTCPStreams {
+			if err := stream.Close(); err != nil {
+				return err
+			}
+		}
 		return r.rtcpReadStream.Close()
 	}
 
 	return nil
 }
 
-// Read reads incoming RTCP for this RTPReceiver
+// Read reads incoming RTCP for this RTPReceiver. The reception reports
+// about the track are collected as its remote-inbound-rtp stats, see
+// StatsReport.GetRemoteInboundRTPStreamStats.
 func (r *RTPSender) Read(b []byte) (n int, err error) {
 	<-r.sendCalled
-	return r.rtcpReadStream.Read(b)
+	if n, err = r.rtcpReadStream.Read(b); err != nil {
+		return n, err
+	}
+	if n, err = r.api.interceptors.readRTCP(b, n); err != nil {
+		return n, err
+	}
+	r.handleBitrateFeedback(b[:n])
+	r.handleNack(b[:n])
+	r.handleSSRCCollision(b[:n])
+	r.handleExtendedReport(b[:n])
+	r.handleReceiverReports(b[:n])
+	return n, nil
 }
 
 // ReadRTCP is a convenience method that wraps Read and unmarshals for you
@@ -134,84 +288,612 @@ func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, error) {
 	return rtcp.Unmarshal(b[:i])
 }
 
+// ReadEncodingRTCP reads and unmarshals the incoming RTCP of a simulcast
+// encoding of the track, see Track.SetEncodings. The RTCP of the first
+// encoding is read with ReadRTCP.
+func (r *RTPSender) ReadEncodingRTCP(rid string) ([]rtcp.Packet, error) {
+	<-r.sendCalled
+	stream, ok := r.encodingRTCPStreams[rid]
+	if !ok {
+		if _, err := r.track.Encoding(rid); err != nil {
+			return nil, err
+		}
+		return r.ReadRTCP()
+	}
+
+	b := make([]byte, receiveMTU)
+	n, err := stream.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	if n, err = r.api.interceptors.readRTCP(b, n); err != nil {
+		return nil, err
+	}
+	r.handleBitrateFeedback(b[:n])
+	r.handleNack(b[:n])
+	r.handleSSRCCollision(b[:n])
+	r.handleExtendedReport(b[:n])
+	r.handleReceiverReports(b[:n])
+	return rtcp.Unmarshal(b[:n])
+}
+
+// writeStreamLocked returns the SRTP stream and the payload type the sender
+// writes with. Both are looked up on the first call and cached, so the hot
+// path doesn't pay for them on every packet. injectMu has to be held.
+func (r *RTPSender) writeStreamLocked() (*srtp.WriteStreamSRTP, uint8, error) {
+	if r.writeStream == nil {
+		srtpSession, err := r.transport.getSRTPSession()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if r.writeStream, err = srtpSession.OpenWriteStream(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Hopefully this next part is temporary and will be removed when senders obtain payload
+	// types from their session instead of the track.
+	// Obtain payload type for this sender. Currently taken from the sender's MediaEngine
+	// to match the track's codec, which could have a different payload type.
+	// (But tracks should not have codecs - this should be set here by the
+	// peer connection or transceiver...)
+	if r.payloadType == nil {
+		// this setup should only happen on the first call to sendRTP
+		codecs := r.api.mediaEngine.GetCodecsByName(r.track.codec.Name)
+		if len(codecs) == 0 {
+			return nil, 0, fmt.Errorf("no %s codecs in media engine", r.track.codec.Name)
+		}
+		for _, c := range codecs {
+			if sameCodec(c, r.track.codec) {
+				r.payloadType = &c.PayloadType
+				break
+			}
+		}
+		if r.payloadType == nil {
+			return nil, 0, fmt.Errorf("could not match %s codec from track to media engine", r.track.codec.Name)
+		}
+	}
+
+	return r.writeStream, *r.payloadType, nil
+}
+
 // sendRTP should only be called by a track, this only exists so we can keep state in one place.
-// Overwrites the payload type field in the rtp header.
-func (r *RTPSender) sendRTP(header *rtp.Header, payload []byte) (int, error) {
+// Overwrites the payload type field in the rtp header and adds the negotiated
+// extensions to every packet. The packets are written in one go, the locks and
+// lookups of the sender are only paid once per call.
+func (r *RTPSender) sendRTP(pkts []*rtp.Packet, extensions map[string][]byte) (int, error) {
 	select {
 	case <-r.stopCalled:
 		return 0, fmt.Errorf("RTPSender has been stopped")
 	case <-r.sendCalled:
-		srtpSession, err := r.transport.getSRTPSession()
+	}
+
+	r.injectMu.Lock()
+	writeStream, payloadType, err := r.writeStreamLocked()
+	if err != nil {
+		r.injectMu.Unlock()
+		return 0, err
+	}
+
+	firstPacket := r.lastHeader == nil
+	total := 0
+	sent := false
+	for _, p := range pkts {
+		stream := r.sendStreamLocked(p.SSRC)
+		if r.paused {
+			// The remote sees a continuous sequence when the sender resumes
+			stream.sequenceShift--
+			continue
+		}
+
+		// The header is shared with every other sender of the track, so
+		// modifications are done on a copy.
+		h := p.Header
+		h.PayloadType = payloadType
+		if rid, ok := r.rids[h.SSRC]; ok && r.ridExtensionID != 0 {
+			setOneByteHeaderExtension(&h, r.ridExtensionID, []byte(rid))
+		}
+		for uri, payload := range extensions {
+			if id := r.headerExtensionIDs[uri]; id != 0 {
+				setOneByteHeaderExtension(&h, id, payload)
+			}
+		}
+
+		payload := p.Payload
+		if r.redPayloadType != 0 {
+			if distance := r.track.getRedundancy(); distance != 0 {
+				payload = r.red.encode(payloadType, h.Timestamp, payload, distance)
+				h.PayloadType = r.redPayloadType
+			} else {
+				r.red.history = nil
+			}
+		}
+
+		// Interceptors may call back into the sender, they run unlocked
+		r.injectMu.Unlock()
+		payload, err = r.api.interceptors.writeRTP(&h, payload)
+		r.injectMu.Lock()
 		if err != nil {
-			return 0, err
+			break
+		}
+		if r.paused {
+			stream.sequenceShift--
+			continue
 		}
 
-		writeStream, err := srtpSession.OpenWriteStream()
+		h.SequenceNumber += stream.sequenceShift
+		stream.lastSequenceNumber = h.SequenceNumber
+		r.lastHeader = &h
+
+		var n int
+		n, err = writeStream.WriteRTP(&h, payload)
+		total += n
 		if err != nil {
-			return 0, err
-		}
-		// Hopefully this next part is temporary and will be removed when senders obtain payload
-		// types from their session instead of the track.
-		// Obtain payload type for this sender. Currently taken from the sender's MediaEngine
-		// to match the track's codec, which could have a different payload type.
-		// (But tracks should not have codecs - this should be set here by the
-		// peer connection or transceiver...)
-		if r.payloadType == nil {
-			// this setup should only happen on the first call to sendRTP
-			codecs := r.api.mediaEngine.GetCodecsByName(r.track.codec.Name)
-			if len(codecs) == 0 {
-				return 0, fmt.Errorf("no %s codecs in media engine", r.track.codec.Name)
+			break
+		}
+		sent = true
+		if r.retransmissions != nil {
+			r.retransmissions.add(&h, payload, time.Now())
+		}
+	}
+	if sent {
+		r.lastSent = time.Now()
+	}
+	r.injectMu.Unlock()
+
+	if startupProbe := r.api.settingEngine.startupProbe; firstPacket && startupProbe != nil {
+		// The cluster was validated by the SettingEngine, the only possible
+		// failures are a stopped sender or a probe started by the user.
+		_ = r.Probe(*startupProbe)
+	}
+	return total, err
+}
+
+// sendPadding injects a padding only RTP packet of the given size into the stream,
+// reusing the SSRC, payload type and timestamp of the last packet sent.
+func (r *RTPSender) sendPadding(size byte) (int, error) {
+	if size == 0 {
+		return 0, fmt.Errorf("padding size must be non-zero")
+	}
+
+	select {
+	case <-r.stopCalled:
+		return 0, fmt.Errorf("RTPSender has been stopped")
+	case <-r.sendCalled:
+	}
+
+	r.injectMu.Lock()
+	defer r.injectMu.Unlock()
+
+	if r.lastHeader == nil {
+		return 0, fmt.Errorf("no RTP has been sent yet")
+	}
+
+	writeStream, _, err := r.writeStreamLocked()
+	if err != nil {
+		return 0, err
+	}
+
+	stream := r.sendStreamLocked(r.lastHeader.SSRC)
+	h := rtp.Header{
+		Version:        2,
+		Padding:        true,
+		PayloadType:    r.lastHeader.PayloadType,
+		SequenceNumber: stream.lastSequenceNumber + 1,
+		Timestamp:      r.lastHeader.Timestamp,
+		SSRC:           r.lastHeader.SSRC,
+	}
+	payload := make([]byte, size)
+	payload[size-1] = size
+
+	n, err := writeStream.WriteRTP(&h, payload)
+	if err != nil {
+		return n, err
+	}
+
+	stream.sequenceShift++
+	stream.lastSequenceNumber = h.SequenceNumber
+	r.lastHeader = &h
+	r.lastSent = time.Now()
+	return n, nil
+}
+
+// rtpSendStream is the sequence of an SSRC the sender writes.
+// sequenceShift is added to every packet from the track, it counts the
+// packets injected into the stream minus the ones dropped while paused.
+type rtpSendStream struct {
+	lastSequenceNumber uint16
+	sequenceShift      uint16
+}
+
+// sendStreamLocked returns the rtpSendStream of ssrc, injectMu has to be held
+func (r *RTPSender) sendStreamLocked(ssrc uint32) *rtpSendStream {
+	stream, ok := r.sendStreams[ssrc]
+	if !ok {
+		if r.sendStreams == nil {
+			r.sendStreams = map[uint32]*rtpSendStream{}
+		}
+		stream = &rtpSendStream{}
+		r.sendStreams[ssrc] = stream
+	}
+	return stream
+}
+
+// Probe sends RTP padding at the bitrate of the cluster for its duration, in
+// addition to the media written to the track. It is meant to be driven by a
+// congestion controller that wants to find out quickly whether more bandwidth
+// is available. Probe returns immediately, padding is only sent once the track
+// has written its first packet.
+func (r *RTPSender) Probe(cluster ProbeCluster) error {
+	if err := cluster.validate(); err != nil {
+		return err
+	}
+
+	select {
+	case <-r.stopCalled:
+		return fmt.Errorf("RTPSender has been stopped")
+	default:
+	}
+
+	r.injectMu.Lock()
+	defer r.injectMu.Unlock()
+	if r.probing {
+		return ErrProbeInProgress
+	}
+	r.probing = true
+
+	go r.probe(cluster)
+	return nil
+}
+
+func (r *RTPSender) probe(cluster ProbeCluster) {
+	defer func() {
+		r.injectMu.Lock()
+		r.probing = false
+		r.injectMu.Unlock()
+	}()
+
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+
+	bytesPerInterval := int(cluster.Bitrate / 8 * uint64(probeInterval) / uint64(time.Second))
+	deadline := time.Now().Add(cluster.Duration)
+	for {
+		select {
+		case <-r.stopCalled:
+			return
+		case now := <-ticker.C:
+			if now.After(deadline) {
+				return
 			}
-			for _,c := range codecs {
-				if sameCodec(c, r.track.codec) {
-					r.payloadType = &c.PayloadType
-					break
-				}
+		}
+
+		for remaining := bytesPerInterval; remaining > 0; {
+			size := remaining - rtpHeaderSize
+			if size > rtpMaxPaddingSize {
+				size = rtpMaxPaddingSize
+			} else if size < 1 {
+				size = 1
 			}
-			if r.payloadType == nil {
-				return 0, fmt.Errorf("could not match %s codec from track to media engine", r.track.codec.Name)
+
+			if _, err := r.sendPadding(byte(size)); err != nil {
+				break
 			}
+			remaining -= size + rtpHeaderSize
 		}
-		// Overwrite the payload type in the RTP header.
-		if r.payloadType != nil {
-			header.PayloadType = *r.payloadType
-		}
-		return writeStream.WriteRTP(header, payload)
 	}
 }
 
-// hasSent tells if data has been ever sent for this instance
-func (r *RTPSender) hasSent() bool {
+// keepAlive is called by the rtpKeepAliveScheduler of the API every interval
+// and sends a small padding packet if nothing has been sent for interval, so
+// NAT bindings survive periods of silence (audio DTX, paused video).
+// See https://tools.ietf.org/html/rfc6263
+func (r *RTPSender) keepAlive(interval time.Duration) error {
 	select {
-	case <-r.sendCalled:
-		return true
+	case <-r.stopCalled:
+		r.api.rtpKeepAlive.remove(r)
+		return nil
 	default:
-		return false
 	}
+
+	r.injectMu.Lock()
+	idle := r.lastHeader != nil && time.Since(r.lastSent) >= interval
+	r.injectMu.Unlock()
+
+	if !idle {
+		return nil
+	}
+	_, err := r.sendPadding(rtpKeepAlivePaddingSize)
+	return err
 }
 
+func (r *RTPSender) setRemotePTime(pTime time.Duration) {
+	atomic.StoreInt64(&r.remotePTime, int64(pTime))
+}
 
-// sameCodec indicates if two codecs match in type, parameters,
-// etc, not checking payload type, so it is useful for comparing
-// codecs from different MediaEngines
-func sameCodec(codecA, codecB *RTPCodec) bool {
-	if codecA.Name != codecB.Name {
-		return false
+func (r *RTPSender) getRemotePTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.remotePTime))
+}
+
+func (r *RTPSender) setRemoteMaxPTime(maxPTime time.Duration) {
+	atomic.StoreInt64(&r.remoteMaxPTime, int64(maxPTime))
+}
+
+func (r *RTPSender) getRemoteMaxPTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.remoteMaxPTime))
+}
+
+// MaxBitrate returns the bitrate in bits per second the remote last asked the
+// track to be limited to with a TMMBR, zero if it didn't. TMMBRs are only
+// seen while the RTCP of the sender is read.
+func (r *RTPSender) MaxBitrate() uint64 {
+	return atomic.LoadUint64(&r.maxBitrate)
+}
+
+// OnMaxBitrateRequest sets an event handler which is called when the remote
+// asks for the bitrate of the track to be limited with a TMMBR, the way
+// endpoints that implement neither REMB nor transport-cc control the
+// bitrate. The request is acknowledged with a TMMBN. The handler is called
+// by Read, ReadRTCP and ReadEncodingRTCP.
+func (r *RTPSender) OnMaxBitrateRequest(f func(bitrate uint64)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onMaxBitrateRequestHandler = f
+}
+
+// OnTargetBitrateChange sets an event handler which is called when the
+// bitrate the remote wants the track to be sent with changes, so that the
+// encoder of the application can adapt. The target is the lowest of the
+// bitrate of the last REMB and the one of the last TMMBR, see MaxBitrate. A
+// REMB applies to all the SSRCs it lists, the track gets the share of its
+// SSRCs. A lowered target of video comes with recommendations how to reach
+// it. The handler is called by Read, ReadRTCP and ReadEncodingRTCP.
+func (r *RTPSender) OnTargetBitrateChange(f func(TargetBitrate)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onTargetBitrateChangeHandler = f
+}
+
+// handleBitrateFeedback looks for the REMBs and TMMBRs of the track in the
+// compound packet b and updates the target bitrate
+func (r *RTPSender) handleBitrateFeedback(b []byte) {
+	r.handleTemporaryMaxBitrate(b)
+
+	for _, packet := range feedbackMessages(b, rtcp.TypePayloadSpecificFeedback, rtcp.FormatREMB) {
+		remb := &rtcp.ReceiverEstimatedMaximumBitrate{}
+		if err := remb.Unmarshal(packet); err != nil || len(remb.SSRCs) == 0 {
+			continue
+		}
+		own := 0
+		for _, ssrc := range remb.SSRCs {
+			if _, ok := r.rids[ssrc]; ok || ssrc == r.track.SSRC() {
+				own++
+			}
+		}
+		if own != 0 {
+			atomic.StoreUint64(&r.estimatedBitrate, remb.Bitrate*uint64(own)/uint64(len(remb.SSRCs)))
+		}
 	}
-	if codecA.Type != codecB.Type {
-		return false
+
+	target := atomic.LoadUint64(&r.estimatedBitrate)
+	if maxBitrate := atomic.LoadUint64(&r.maxBitrate); maxBitrate != 0 && (target == 0 || maxBitrate < target) {
+		target = maxBitrate
 	}
-	if codecA.SDPFmtpLine != codecB.SDPFmtpLine {
-		return false
+	if target == 0 {
+		return
 	}
-	if codecA.Channels != codecB.Channels {
-		return false
+	sendBitrate := r.track.Counters().Bitrate
+	r.setCongested(float64(target) < sendBitrate)
+	if atomic.SwapUint64(&r.targetBitrate, target) == target {
+		return
 	}
-	if codecA.ClockRate != codecB.ClockRate {
-		return false
+
+	r.mu.RLock()
+	handler := r.onTargetBitrateChangeHandler
+	r.mu.RUnlock()
+	if handler != nil {
+		handler(newTargetBitrate(r.track.Kind(), target, sendBitrate))
+	}
+}
+
+// Congested tells if the remote can't keep up with the track, the target
+// bitrate of its last REMB or TMMBR is below the bitrate the track is sent
+// with. Like the target, it is only updated while the RTCP of the sender is
+// read. See Track.OnSenderCongestion.
+func (r *RTPSender) Congested() bool {
+	return atomic.LoadInt32(&r.congested) == 1
+}
+
+// setCongested updates the status returned by Congested and tells the track
+// when it changes
+func (r *RTPSender) setCongested(congested bool) {
+	var value int32
+	if congested {
+		value = 1
+	}
+	if atomic.SwapInt32(&r.congested, value) == value {
+		return
 	}
-	if codecA.MimeType != codecB.MimeType {
+
+	r.track.mu.RLock()
+	handler := r.track.onSenderCongestionHandler
+	r.track.mu.RUnlock()
+	if handler != nil {
+		handler(r, congested)
+	}
+}
+
+// handleSSRCCollision looks for sender reports and source descriptions in
+// the compound packet b that the remote sent with an SSRC of the track,
+// which collides with it, RFC 3550 8.2
+func (r *RTPSender) handleSSRCCollision(b []byte) {
+	if r.peerConnection == nil {
+		return
+	}
+	for len(b) >= 8 {
+		header := &rtcp.Header{}
+		if err := header.Unmarshal(b); err != nil {
+			return
+		}
+		size := (int(header.Length) + 1) * 4
+		if size > len(b) {
+			return
+		}
+		if header.Type == rtcp.TypeSenderReport || (header.Type == rtcp.TypeSourceDescription && header.Count != 0) {
+			source := binary.BigEndian.Uint32(b[4:])
+			for _, ssrc := range r.ssrcs() {
+				if ssrc == source {
+					r.peerConnection.ssrcCollision(source)
+					return
+				}
+			}
+		}
+		b = b[size:]
+	}
+}
+
+// handleExtendedReport answers the receiver reference times of the RTCP XRs
+// in the compound packet b with a DLRR block, RFC 3611 4.5
+func (r *RTPSender) handleExtendedReport(b []byte) {
+	received := time.Now()
+	for _, packet := range feedbackMessages(b, typeExtendedReport, 0) {
+		report := &ExtendedReport{}
+		if err := report.Unmarshal(packet); err != nil || report.ReceiverReferenceTime == 0 {
+			continue
+		}
+
+		answer := &ExtendedReport{
+			SenderSSRC: r.track.SSRC(),
+			DLRR: []DLRRReportBlock{{
+				SSRC:             report.SenderSSRC,
+				LastRR:           uint32(report.ReceiverReferenceTime >> 16),
+				DelaySinceLastRR: uint32(time.Since(received) * (1 << 16) / time.Second),
+			}},
+		}
+		if err := r.transport.writeRTCP([]rtcp.Packet{answer}); err != nil {
+			r.log.Warnf("Failed to send DLRR: %v", err)
+		}
+	}
+}
+
+// handleTemporaryMaxBitrate looks for TMMBRs for the track in the compound
+// packet b and acknowledges them, RFC 5104 4.2.1
+func (r *RTPSender) handleTemporaryMaxBitrate(b []byte) {
+	for _, packet := range feedbackMessages(b, rtcp.TypeTransportSpecificFeedback, formatTMMBR) {
+		request := &temporaryMaxBitrate{}
+		if err := request.Unmarshal(packet); err != nil {
+			continue
+		}
+		for _, e := range request.Entries {
+			if _, ok := r.rids[e.SSRC]; e.SSRC != r.track.SSRC() && !ok {
+				continue
+			}
+			atomic.StoreUint64(&r.maxBitrate, e.Bitrate)
+
+			r.mu.RLock()
+			handler := r.onMaxBitrateRequestHandler
+			r.mu.RUnlock()
+			if handler != nil {
+				handler(e.Bitrate)
+			}
+
+			// The TMMBN lists the requests the sender is bound by, each owned
+			// by the SSRC of its requester
+			notification := &temporaryMaxBitrate{
+				Notification: true,
+				SenderSSRC:   e.SSRC,
+				Entries: []temporaryMaxBitrateEntry{{
+					SSRC:     request.SenderSSRC,
+					Bitrate:  e.Bitrate,
+					Overhead: e.Overhead,
+				}},
+			}
+			if err := r.transport.writeRTCP([]rtcp.Packet{notification}); err != nil {
+				r.log.Warnf("Failed to send TMMBN: %v", err)
+			}
+		}
+	}
+}
+
+// Pause stops sending the media written to the track until Resume is called,
+// without a renegotiation, and tells the remote with a PAUSED indication of
+// RFC 7728. Receivers of this package don't count the pause as a freeze and
+// don't request key frames while paused. RTP keep-alives, see
+// SettingEngine.SetRTPKeepAliveInterval, keep the path open.
+func (r *RTPSender) Pause() error {
+	select {
+	case <-r.stopCalled:
+		return fmt.Errorf("RTPSender has been stopped")
+	default:
+	}
+
+	r.injectMu.Lock()
+	if r.paused {
+		r.injectMu.Unlock()
+		return nil
+	}
+	r.paused = true
+	pauseID := r.pauseID
+	started := r.lastHeader != nil
+	r.injectMu.Unlock()
+
+	// A remote that hasn't received anything yet has nothing to freeze
+	if !started {
+		return nil
+	}
+
+	// The SDES routes the indication to the RTCP of the paused streams
+	indication := &pauseResume{SenderSSRC: r.track.SSRC()}
+	sdes := &rtcp.SourceDescription{}
+	for _, ssrc := range r.ssrcs() {
+		indication.Entries = append(indication.Entries, pauseResumeEntry{
+			TargetSSRC: ssrc,
+			Type:       pauseResumeTypePaused,
+			PauseID:    pauseID,
+		})
+		sdes.Chunks = append(sdes.Chunks, rtcp.SourceDescriptionChunk{
+			Source: ssrc,
+			Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: r.track.Label()}},
+		})
+	}
+	return r.transport.writeRTCP([]rtcp.Packet{sdes, indication})
+}
+
+// Resume sends the media written to the track again after Pause. The remote
+// learns about it from the media, which should start with a key frame.
+func (r *RTPSender) Resume() {
+	r.injectMu.Lock()
+	defer r.injectMu.Unlock()
+	if r.paused {
+		r.paused = false
+		r.pauseID++
+	}
+}
+
+// ssrcs returns the SSRCs the track is sent with, the SSRC of the track
+// followed by the ones of its simulcast encodings
+func (r *RTPSender) ssrcs() []uint32 {
+	ssrcs := []uint32{r.track.SSRC()}
+	for ssrc := range r.rids {
+		if ssrc != ssrcs[0] {
+			ssrcs = append(ssrcs, ssrc)
+		}
+	}
+	return ssrcs
+}
+
+// setREDPayloadType has to be called before Send
+func (r *RTPSender) setREDPayloadType(payloadType uint8) {
+	r.redPayloadType = payloadType
+}
+
+// hasSent tells if data has been ever sent for this instance
+func (r *RTPSender) hasSent() bool {
+	select {
+	case <-r.sendCalled:
+		return true
+	default:
 		return false
 	}
-	return true
-}
\ No newline at end of file
+}
@@ -0,0 +1,601 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPSender_Probe(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	dtlsTransport, err := api.NewDTLSTransport(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	track, err := NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := api.NewRTPSender(track, dtlsTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Error(t, sender.Probe(ProbeCluster{Duration: time.Second}))
+	assert.Error(t, sender.Probe(ProbeCluster{Bitrate: 1000000}))
+
+	cluster := ProbeCluster{Bitrate: 1000000, Duration: time.Minute}
+	assert.NoError(t, sender.Probe(cluster))
+	assert.Equal(t, ErrProbeInProgress, sender.Probe(cluster))
+
+	assert.NoError(t, sender.Stop())
+	assert.Error(t, sender.Probe(cluster))
+}
+
+func TestTemporaryMaxBitrate(t *testing.T) {
+	tmmbr := temporaryMaxBitrate{
+		SenderSSRC: 1,
+		Entries:    []temporaryMaxBitrateEntry{{SSRC: 0x902f9e2e, Bitrate: 256000, Overhead: 40}},
+	}
+	raw, err := tmmbr.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x83, 0xcd, 0x00, 0x04,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x90, 0x2f, 0x9e, 0x2e,
+		0x07, 0xe8, 0x00, 0x28,
+	}, raw)
+
+	parsed := temporaryMaxBitrate{}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Equal(t, tmmbr, parsed)
+	assert.Equal(t, []uint32{0x902f9e2e}, parsed.DestinationSSRC())
+	assert.Error(t, parsed.Unmarshal(raw[:12]))
+
+	// Bitrates that don't fit into the mantissa lose their low bits
+	tmmbr.Notification = true
+	tmmbr.Entries[0].Bitrate = 1<<17 + 1
+	raw, err = tmmbr.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(0x84), raw[0])
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.True(t, parsed.Notification)
+	assert.Equal(t, uint64(1<<17), parsed.Entries[0].Bitrate)
+
+	tmmbr.Entries[0].Overhead = 512
+	_, err = tmmbr.Marshal()
+	assert.Error(t, err)
+}
+
+func TestRTPSender_MaxBitrateRequest(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), sender.MaxBitrate())
+
+	requested := make(chan uint64, 1)
+	sender.OnMaxBitrateRequest(func(bitrate uint64) {
+		select {
+		case requested <- bitrate:
+		default:
+		}
+	})
+	targets := make(chan TargetBitrate, 10)
+	sender.OnTargetBitrateChange(func(target TargetBitrate) {
+		targets <- target
+	})
+
+	tracks := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		tracks <- track
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	remoteTrack := <-tracks
+
+	// Legacy endpoints send the TMMBR in a compound packet with a report.
+	// The REMB is shared with a stream of another sender.
+	go func() {
+		for {
+			_ = pcAnswer.WriteRTCP([]rtcp.Packet{
+				&rtcp.ReceiverReport{SSRC: 5, Reports: []rtcp.ReceptionReport{{SSRC: remoteTrack.SSRC()}}},
+				&temporaryMaxBitrate{SenderSSRC: 5, Entries: []temporaryMaxBitrateEntry{{SSRC: remoteTrack.SSRC(), Bitrate: 300000}}},
+				&rtcp.ReceiverEstimatedMaximumBitrate{SenderSSRC: 5, Bitrate: 400000, SSRCs: []uint32{remoteTrack.SSRC(), 6}},
+			})
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+			}
+		}
+	}()
+	go func() {
+		for {
+			if _, err := sender.ReadRTCP(); err != nil {
+				return
+			}
+		}
+	}()
+
+	assert.Equal(t, uint64(300000), <-requested)
+	assert.Equal(t, uint64(300000), sender.MaxBitrate())
+
+	// The target is the lower of the two, it only changes once
+	target := <-targets
+	assert.Equal(t, uint64(200000), target.Bitrate)
+	assert.False(t, target.DropLayer)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, targets, 0)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPSender_Congested(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	sender := &RTPSender{track: track}
+
+	var changes []bool
+	track.OnSenderCongestion(func(s *RTPSender, congested bool) {
+		assert.Equal(t, sender, s)
+		changes = append(changes, congested)
+	})
+
+	// The track is sent with about 100kbit/s
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		track.counters.update(uint16(i), 125, now.Add(time.Duration(i-99)*10*time.Millisecond))
+	}
+
+	remb := func(bitrate uint64) []byte {
+		b, err := (&rtcp.ReceiverEstimatedMaximumBitrate{SenderSSRC: 5, Bitrate: bitrate, SSRCs: []uint32{1234}}).Marshal()
+		assert.NoError(t, err)
+		return b
+	}
+
+	assert.False(t, sender.Congested())
+	sender.handleBitrateFeedback(remb(50000))
+	assert.True(t, sender.Congested())
+	sender.handleBitrateFeedback(remb(40000))
+	sender.handleBitrateFeedback(remb(1000000))
+	assert.False(t, sender.Congested())
+
+	// The handler is only called on changes
+	assert.Equal(t, []bool{true, false}, changes)
+}
+
+func TestPauseResume(t *testing.T) {
+	paused := pauseResume{
+		SenderSSRC: 1,
+		Entries:    []pauseResumeEntry{{TargetSSRC: 0x902f9e2e, Type: pauseResumeTypePaused, PauseID: 3}},
+	}
+	raw, err := paused.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x89, 0xcd, 0x00, 0x04,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x90, 0x2f, 0x9e, 0x2e,
+		0x20, 0x00, 0x00, 0x03,
+	}, raw)
+
+	parsed := pauseResume{}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Equal(t, paused, parsed)
+	assert.Equal(t, []uint32{0x902f9e2e}, parsed.DestinationSSRC())
+	assert.Error(t, parsed.Unmarshal(raw[:12]))
+
+	// Type specific parameters are skipped
+	assert.NoError(t, parsed.Unmarshal([]byte{
+		0x89, 0xcd, 0x00, 0x07,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x90, 0x2f, 0x9e, 0x2e,
+		0x20, 0x01, 0x00, 0x03,
+		0x00, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x02,
+		0x10, 0x00, 0x00, 0x04,
+	}))
+	assert.Equal(t, []pauseResumeEntry{
+		{TargetSSRC: 0x902f9e2e, Type: pauseResumeTypePaused, PauseID: 3},
+		{TargetSSRC: 2, Type: pauseResumeTypeResume, PauseID: 4},
+	}, parsed.Entries)
+}
+
+func TestRTPSender_Pause(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	type remote struct {
+		track    *Track
+		receiver *RTPReceiver
+	}
+	remotes := make(chan remote, 1)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		remotes <- remote{track, receiver}
+	})
+
+	// Media written right before the pause may arrive after the PAUSED
+	// indication and end the pause, nothing is written around it
+	writing := int32(1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				if atomic.LoadInt32(&writing) == 1 {
+					_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1})
+				}
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	r := <-remotes
+
+	pausedChanges := make(chan bool, 2)
+	r.receiver.OnPausedChange(func(paused bool) {
+		pausedChanges <- paused
+	})
+	go func() {
+		for {
+			if _, err := r.receiver.ReadRTCP(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The sequence stays continuous across the pause
+	gaps := make(chan uint16, 1)
+	go func() {
+		var last uint16
+		for i := 0; ; i++ {
+			p, err := r.track.ReadRTP()
+			if err != nil {
+				return
+			}
+			if i != 0 && p.SequenceNumber != last+1 {
+				gaps <- p.SequenceNumber
+			}
+			last = p.SequenceNumber
+		}
+	}()
+
+	atomic.StoreInt32(&writing, 0)
+	time.Sleep(time.Millisecond * 100)
+	assert.NoError(t, sender.Pause())
+	assert.True(t, <-pausedChanges)
+	assert.True(t, r.receiver.Paused())
+	assert.NoError(t, r.receiver.RequestKeyFrame())
+
+	// Keep-alives don't end the pause
+	for i := 0; i < 3; i++ {
+		_, err = sender.sendPadding(rtpKeepAlivePaddingSize)
+		assert.NoError(t, err)
+	}
+	time.Sleep(time.Millisecond * 100)
+	assert.True(t, r.receiver.Paused())
+	assert.Len(t, pausedChanges, 0)
+
+	time.Sleep(time.Millisecond * 100)
+	sender.Resume()
+	atomic.StoreInt32(&writing, 1)
+	assert.False(t, <-pausedChanges)
+	assert.False(t, r.receiver.Paused())
+
+	time.Sleep(time.Millisecond * 100)
+	select {
+	case seq := <-gaps:
+		t.Fatalf("Sequence number %d isn't continuous", seq)
+	default:
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPSender_SimulcastSequence(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	transceiver, err := pcOffer.AddTransceiverFromTrack(track, RtpTransceiverInit{
+		Direction:     RTPTransceiverDirectionSendonly,
+		SendEncodings: []RTPEncodingParameters{{RID: "q"}, {RID: "f"}},
+	})
+	assert.NoError(t, err)
+	sender := transceiver.Sender
+	encodings := track.Encodings()
+
+	remotes := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(remote *Track, _ *RTPReceiver) {
+		remotes <- remote
+	})
+
+	// The last packet of every tick is one of "f", padding is injected into
+	// its stream
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := uint16(0); ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				for _, e := range encodings {
+					_ = e.WriteRTP(&rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: i}, Payload: []byte{0x00}})
+				}
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// The track is the stream of "q", SRTP drops packets whose sequence
+	// number jumps back, a gap or a stall fails the test
+	remote := <-remotes
+	var received int32
+	gaps := make(chan uint16, 1)
+	go func() {
+		var last uint16
+		for i := 0; ; i++ {
+			p, err := remote.ReadRTP()
+			if err != nil {
+				return
+			}
+			if i != 0 && p.SequenceNumber != last+1 {
+				select {
+				case gaps <- p.SequenceNumber:
+				default:
+				}
+			}
+			last = p.SequenceNumber
+			atomic.AddInt32(&received, 1)
+		}
+	}()
+
+	// waitForPackets waits until n more packets of "q" have been read
+	waitForPackets := func(n int32) {
+		start := atomic.LoadInt32(&received)
+		deadline := time.Now().Add(2 * time.Second)
+		for atomic.LoadInt32(&received) < start+n {
+			if time.Now().After(deadline) {
+				t.Fatal("The stream of q stalled")
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+	}
+
+	waitForPackets(3)
+	for i := 0; i < 3; i++ {
+		_, err = sender.sendPadding(rtpKeepAlivePaddingSize)
+		assert.NoError(t, err)
+	}
+	waitForPackets(3)
+	assert.NoError(t, sender.Pause())
+	time.Sleep(time.Millisecond * 100)
+	sender.Resume()
+	waitForPackets(5)
+
+	select {
+	case seq := <-gaps:
+		t.Fatalf("Sequence number %d isn't continuous", seq)
+	default:
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPSender_RemoteInboundStats(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 0, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			_ = pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.ReceiverReport{SSRC: 5, Reports: []rtcp.ReceptionReport{{
+				SSRC:             track.SSRC(),
+				FractionLost:     64,
+				TotalLost:        3,
+				Jitter:           900,
+				LastSenderReport: uint32(timeToNTP(time.Now()) >> 16),
+			}}}})
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+			}
+		}
+	}()
+	go func() {
+		for {
+			if _, err := sender.ReadRTCP(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		stats, ok := pcOffer.GetStats().GetRemoteInboundRTPStreamStats(sender, track.SSRC())
+		if !ok {
+			time.Sleep(time.Millisecond * 20)
+			continue
+		}
+		assert.Equal(t, StatsTypeRemoteInboundRTP, stats.Type)
+		assert.Equal(t, "video", stats.Kind)
+		assert.Equal(t, int32(3), stats.PacketsLost)
+		assert.Equal(t, 0.25, stats.FractionLost)
+		assert.Equal(t, 0.01, stats.Jitter)
+		assert.True(t, stats.RoundTripTime >= 0 && stats.RoundTripTime < 1, "round trip time %f", stats.RoundTripTime)
+		break
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRemoteInboundStats(t *testing.T) {
+	s := remoteInboundStats{}
+	now := time.Now()
+	s.update(rtcp.ReceptionReport{
+		SSRC:             1,
+		TotalLost:        0xFFFFFF,
+		LastSenderReport: uint32(timeToNTP(now.Add(-time.Second)) >> 16),
+		Delay:            1 << 15,
+	}, now)
+
+	report := s.reports[1]
+	assert.Equal(t, int32(-1), report.packetsLost)
+	assert.InDelta(t, float64(500*time.Millisecond), float64(report.roundTripTime), float64(time.Millisecond))
+}
+
+func TestRTPSender_Retransmission(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetRetransmissionBuffer(500*time.Millisecond, 0)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	tracks := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		tracks <- track
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1})
+			}
+		}
+	}()
+	go func() {
+		for {
+			if _, err := sender.ReadRTCP(); err != nil {
+				return
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	remoteTrack := <-tracks
+
+	lost, err := remoteTrack.ReadRTP()
+	assert.NoError(t, err)
+	nack := &rtcp.TransportLayerNack{
+		SenderSSRC: 5,
+		MediaSSRC:  remoteTrack.SSRC(),
+		Nacks:      []rtcp.NackPair{{PacketID: lost.SequenceNumber}},
+	}
+	assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{nack}))
+
+	for {
+		p, err := remoteTrack.ReadRTP()
+		assert.NoError(t, err)
+		if p.SequenceNumber == lost.SequenceNumber {
+			assert.Equal(t, lost.Payload, p.Payload)
+			break
+		}
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
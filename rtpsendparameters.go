@@ -3,4 +3,7 @@ package webrtc
 // RTPSendParameters contains the RTP stack settings used by receivers
 type RTPSendParameters struct {
 	Encodings RTPEncodingParameters
+
+	// HeaderExtensions are the header extensions the sender writes
+	HeaderExtensions []RTPHeaderExtensionParameter
 }
@@ -4,10 +4,15 @@ package webrtc
 
 import (
 	"fmt"
+	"sync"
+
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
 // RTPTransceiver represents a combination of an RTPSender and an RTPReceiver that share a common mid.
 type RTPTransceiver struct {
+	mu sync.RWMutex
+
 	Sender    *RTPSender
 	Receiver  *RTPReceiver
 	Direction RTPTransceiverDirection
@@ -16,12 +21,31 @@ type RTPTransceiver struct {
 	// receptive bool
 	stopped bool
 	kind    RTPCodecType
+
+	// mid of the media section the transceiver was negotiated in, keeps the
+	// transceiver in the same section when the remote renegotiates
+	mid string
+}
+
+// Mid returns the mid of the media section the transceiver was negotiated
+// in, or an empty string if it hasn't been negotiated yet
+func (t *RTPTransceiver) Mid() string {
+	return t.mid
+}
+
+func (t *RTPTransceiver) isStopped() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.stopped
 }
 
 func (t *RTPTransceiver) setSendingTrack(track *Track) error {
 	if track == nil {
 		return fmt.Errorf("track must not be nil")
 	}
+	if track.Kind() != t.kind {
+		return &rtcerr.TypeError{Err: ErrTrackKindMismatch}
+	}
 
 	t.Sender.track = track
 
@@ -36,12 +60,21 @@ func (t *RTPTransceiver) setSendingTrack(track *Track) error {
 	return nil
 }
 
-// Stop irreversibly stops the RTPTransceiver
+// Stop irreversibly stops the RTPTransceiver. Its media section is rejected
+// in the next negotiation, once that is complete the transceiver is removed
+// from the PeerConnection.
 func (t *RTPTransceiver) Stop() error {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+
 	if t.Sender != nil {
 		if err := t.Sender.Stop(); err != nil {
 			return err
 		}
+		if pc := t.Sender.peerConnection; pc != nil && t.Sender.track != nil {
+			pc.releaseTrack(t.Sender.track)
+		}
 	}
 	if t.Receiver != nil {
 		if err := t.Receiver.Stop(); err != nil {
@@ -1,5 +1,9 @@
 package webrtc
 
+import (
+	"encoding/json"
+)
+
 // RTPTransceiverDirection indicates the direction of the RTPTransceiver.
 type RTPTransceiverDirection int
 
@@ -60,3 +64,22 @@ func (t RTPTransceiverDirection) String() string {
 		return ErrUnknownType.Error()
 	}
 }
+
+// MarshalJSON enables JSON marshaling of a RTPTransceiverDirection
+func (t RTPTransceiverDirection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON enables JSON unmarshaling of a RTPTransceiverDirection
+func (t *RTPTransceiverDirection) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	direction := NewRTPTransceiverDirection(s)
+	if direction == RTPTransceiverDirection(Unknown) {
+		return ErrUnknownType
+	}
+	*t = direction
+	return nil
+}
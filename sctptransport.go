@@ -34,9 +34,10 @@ type SCTPTransport struct {
 
 	// OnStateChange  func()
 
-	association                *sctp.Association
-	onDataChannelHandler       func(*DataChannel)
-	onDataChannelOpenedHandler func(*DataChannel)
+	association                 *sctp.Association
+	onDataChannelRequestHandler func(DataChannelParameters) bool
+	onDataChannelHandler        func(*DataChannel)
+	onDataChannelOpenedHandler  func(*DataChannel)
 
 	api *API
 	log logging.LeveledLogger
@@ -167,14 +168,22 @@ func (r *SCTPTransport) acceptDataChannels() {
 		default:
 		}
 
-		sid := dc.StreamIdentifier()
-		rtcDC, err := r.api.newDataChannel(&DataChannelParameters{
-			ID:                sid,
+		params := &DataChannelParameters{
+			ID:                dc.StreamIdentifier(),
 			Label:             dc.Config.Label,
 			Ordered:           ordered,
 			MaxPacketLifeTime: maxPacketLifeTime,
 			MaxRetransmits:    maxRetransmits,
-		}, r.api.settingEngine.LoggerFactory.NewLogger("ortc"))
+		}
+		if !r.dataChannelRequested(*params) {
+			r.log.Debugf("Refused data channel %d %q", params.ID, params.Label)
+			if err := dc.Close(); err != nil {
+				r.log.Warnf("Failed to close refused data channel: %v", err)
+			}
+			continue
+		}
+
+		rtcDC, err := r.api.newDataChannel(params, r.api.settingEngine.LoggerFactory.NewLogger("ortc"))
 
 		if err != nil {
 			r.log.Errorf("Failed to accept data channel: %v", err)
@@ -195,6 +204,26 @@ func (r *SCTPTransport) acceptDataChannels() {
 	}
 }
 
+// OnDataChannelRequest sets a handler that decides whether a data channel the
+// remote opens is accepted. Channels the handler returns false for are closed
+// right away, no DataChannel is created for them and OnDataChannel isn't
+// invoked. The open message was acknowledged already, the remote sees the
+// channel open and close again. Other channels aren't accepted until the
+// handler returns.
+func (r *SCTPTransport) OnDataChannelRequest(f func(DataChannelParameters) bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.onDataChannelRequestHandler = f
+}
+
+func (r *SCTPTransport) dataChannelRequested(params DataChannelParameters) bool {
+	r.lock.RLock()
+	hdlr := r.onDataChannelRequestHandler
+	r.lock.RUnlock()
+
+	return hdlr == nil || hdlr(params)
+}
+
 // OnDataChannel sets an event handler which is invoked when a data
 // channel message arrives from a remote peer.
 func (r *SCTPTransport) OnDataChannel(f func(*DataChannel)) {
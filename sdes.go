@@ -0,0 +1,135 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+const (
+	// sdesCryptoSuite is the only crypto suite supported, the one of the
+	// SRTP protection profile negotiated with DTLS
+	sdesCryptoSuite = "AES_CM_128_HMAC_SHA1_80"
+
+	sdesMasterKeyLength  = 16
+	sdesMasterSaltLength = 14
+
+	sdesDefaultTag = 1
+)
+
+// sdesCrypto is an a=crypto attribute, e.g.
+// a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:<base64 of key and salt>
+type sdesCrypto struct {
+	tag        int
+	masterKey  []byte
+	masterSalt []byte
+}
+
+// newSDESCrypto generates a master key and salt with random, the Rand of the
+// CryptoProvider
+func newSDESCrypto(random io.Reader) (*sdesCrypto, error) {
+	keyAndSalt := make([]byte, sdesMasterKeyLength+sdesMasterSaltLength)
+	if _, err := io.ReadFull(random, keyAndSalt); err != nil {
+		return nil, err
+	}
+	return &sdesCrypto{
+		tag:        sdesDefaultTag,
+		masterKey:  keyAndSalt[:sdesMasterKeyLength],
+		masterSalt: keyAndSalt[sdesMasterKeyLength:],
+	}, nil
+}
+
+func (c *sdesCrypto) attributeValue(tag int) string {
+	keyAndSalt := append(append([]byte{}, c.masterKey...), c.masterSalt...)
+	return fmt.Sprintf("%d %s inline:%s", tag, sdesCryptoSuite, base64.StdEncoding.EncodeToString(keyAndSalt))
+}
+
+// parseSDESCrypto parses the value of an a=crypto attribute. Lifetimes and
+// MKIs aren't supported, key parameters that have them are rejected.
+func parseSDESCrypto(value string) (*sdesCrypto, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return nil, ErrSDESInvalidCrypto
+	}
+	tag, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, ErrSDESInvalidCrypto
+	}
+	if fields[1] != sdesCryptoSuite || !strings.HasPrefix(fields[2], "inline:") || strings.Contains(fields[2], "|") {
+		return nil, ErrSDESInvalidCrypto
+	}
+	keyAndSalt, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(fields[2], "inline:"))
+	if err != nil || len(keyAndSalt) != sdesMasterKeyLength+sdesMasterSaltLength {
+		return nil, ErrSDESInvalidCrypto
+	}
+	return &sdesCrypto{
+		tag:        tag,
+		masterKey:  keyAndSalt[:sdesMasterKeyLength],
+		masterSalt: keyAndSalt[sdesMasterKeyLength:],
+	}, nil
+}
+
+// remoteSDESCrypto returns the first supported a=crypto attribute of the
+// media sections of d, or nil
+func remoteSDESCrypto(d *sdp.SessionDescription) *sdesCrypto {
+	for _, m := range d.MediaDescriptions {
+		for _, a := range m.Attributes {
+			if a.Key != sdpAttrKeyCrypto {
+				continue
+			}
+			if crypto, err := parseSDESCrypto(a.Value); err == nil {
+				return crypto
+			}
+		}
+	}
+	return nil
+}
+
+// addSDESCrypto adds the a=crypto attribute of the PeerConnection to the
+// audio and video sections of d. Answers only have it if the offer had one,
+// with the tag of the offer.
+func (pc *PeerConnection) addSDESCrypto(d *sdp.SessionDescription, remote *SessionDescription) {
+	if pc.sdesCrypto == nil {
+		return
+	}
+	tag := sdesDefaultTag
+	if remote != nil {
+		crypto := remoteSDESCrypto(remote.parsed)
+		if crypto == nil {
+			return
+		}
+		tag = crypto.tag
+	}
+
+	for _, m := range d.MediaDescriptions {
+		if NewRTPCodecType(m.MediaName.Media) == RTPCodecType(0) {
+			continue
+		}
+		m.WithValueAttribute(sdpAttrKeyCrypto, pc.sdesCrypto.attributeValue(tag))
+	}
+}
+
+// sdesKeys returns the SRTP keys of a remote description that is keyed
+// with SDES instead of DTLS: it has an a=crypto attribute but no
+// fingerprint. It returns nil if SDES isn't enabled.
+func (pc *PeerConnection) sdesKeys(remote *sdp.SessionDescription, haveFingerprint bool) *SRTPKeys {
+	if pc.sdesCrypto == nil || haveFingerprint {
+		return nil
+	}
+	crypto := remoteSDESCrypto(remote)
+	if crypto == nil {
+		return nil
+	}
+	return &SRTPKeys{
+		LocalMasterKey:   pc.sdesCrypto.masterKey,
+		LocalMasterSalt:  pc.sdesCrypto.masterSalt,
+		RemoteMasterKey:  crypto.masterKey,
+		RemoteMasterSalt: crypto.masterSalt,
+	}
+}
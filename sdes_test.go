@@ -0,0 +1,116 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSDESCrypto(t *testing.T) {
+	// The key and salt are read from the Rand of the CryptoProvider
+	random := bytes.Repeat([]byte{0xAB}, sdesMasterKeyLength+sdesMasterSaltLength)
+	crypto, err := newSDESCrypto(bytes.NewReader(random))
+	assert.NoError(t, err)
+	assert.Equal(t, random[:sdesMasterKeyLength], crypto.masterKey)
+
+	_, err = newSDESCrypto(bytes.NewReader(random[:1]))
+	assert.Error(t, err)
+
+	parsed, err := parseSDESCrypto(crypto.attributeValue(3))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, parsed.tag)
+	assert.Equal(t, crypto.masterKey, parsed.masterKey)
+	assert.Equal(t, crypto.masterSalt, parsed.masterSalt)
+
+	for _, value := range []string{
+		"",
+		"1 AES_CM_128_HMAC_SHA1_80",
+		"x AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz",
+		"1 AES_CM_128_HMAC_SHA1_32 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz",
+		"1 AES_CM_128_HMAC_SHA1_80 inline:c2hvcnQ=",
+		"1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz|2^20|1:4",
+	} {
+		_, err := parseSDESCrypto(value)
+		assert.Equal(t, ErrSDESInvalidCrypto, err, value)
+	}
+}
+
+func TestPeerConnectionSDES(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	// Without the setting SRTP can't be keyed without DTLS
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	err = pc.dtlsTransport.StartSRTP(SRTPKeys{})
+	assert.IsType(t, &rtcerr.InvalidAccessError{}, err)
+	assert.NoError(t, pc.Close())
+
+	s := SettingEngine{}
+	s.SetInsecureSDES(true)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrack := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		onTrack <- remote
+	})
+
+	// Legacy endpoints don't send a fingerprint
+	noFingerprint := regexp.MustCompile(`a=fingerprint:[^\r]*\r\n`)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:")
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	offer.SDP = noFingerprint.ReplaceAllString(offer.SDP, "")
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:")
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	answer.SDP = noFingerprint.ReplaceAllString(answer.SDP, "")
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1})
+			}
+		}
+	}()
+
+	remote := <-onTrack
+	_, err = remote.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, DTLSTransportStateConnected, pcAnswer.dtlsTransport.State())
+	assert.Nil(t, pcAnswer.dtlsTransport.GetRemoteCertificate())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
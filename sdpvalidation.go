@@ -0,0 +1,113 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+// sdpAttrKeyCrypto carries SDES keys, RFC 4568
+const sdpAttrKeyCrypto = "crypto"
+
+// SDPValidationError describes a problem found in a remote description
+type SDPValidationError struct {
+	// MediaIndex is the index of the offending media section, or -1 if the
+	// problem is on the session level
+	MediaIndex int
+	// Detail is the offending attribute value, if any
+	Detail string
+	Err    error
+}
+
+func (e *SDPValidationError) Error() string {
+	location := "session"
+	if e.MediaIndex >= 0 {
+		location = fmt.Sprintf("media section %d", e.MediaIndex)
+	}
+	if e.Detail == "" {
+		return fmt.Sprintf("invalid SDP: %s: %v", location, e.Err)
+	}
+	return fmt.Sprintf("invalid SDP: %s: %v (%s)", location, e.Err, e.Detail)
+}
+
+// Unwrap returns the underlying Err, e.g. ErrSDPMissingFingerprint
+func (e *SDPValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validateRemoteDescription checks a remote description for problems that
+// would otherwise only surface later during negotiation. The first problem
+// found is returned as *SDPValidationError. With sdes media sections may be
+// keyed with an a=crypto attribute instead of a fingerprint.
+func validateRemoteDescription(sdpType SDPType, d *sdp.SessionDescription, sdes bool) error {
+	fingerprint, sessionFingerprint := d.Attribute("fingerprint")
+	if sessionFingerprint && len(strings.Split(fingerprint, " ")) != 2 {
+		return &SDPValidationError{MediaIndex: -1, Detail: fingerprint, Err: ErrSDPInvalidFingerprint}
+	}
+	_, sessionUfrag := d.Attribute("ice-ufrag")
+	_, sessionPwd := d.Attribute("ice-pwd")
+
+	payloadTypes := map[string]string{}
+	for i, m := range d.MediaDescriptions {
+		// Rejected sections don't need a transport
+		if m.MediaName.Port.Value == 0 {
+			continue
+		}
+
+		if fingerprint, ok := m.Attribute("fingerprint"); ok {
+			if len(strings.Split(fingerprint, " ")) != 2 {
+				return &SDPValidationError{MediaIndex: i, Detail: fingerprint, Err: ErrSDPInvalidFingerprint}
+			}
+		} else if !sessionFingerprint && !(sdes && sdesKeyed(m)) {
+			return &SDPValidationError{MediaIndex: i, Err: ErrSDPMissingFingerprint}
+		}
+
+		_, ufrag := m.Attribute("ice-ufrag")
+		_, pwd := m.Attribute("ice-pwd")
+		if !(ufrag || sessionUfrag) || !(pwd || sessionPwd) {
+			return &SDPValidationError{MediaIndex: i, Err: ErrSDPMissingICECredentials}
+		}
+
+		if setup, ok := m.Attribute(sdp.AttrKeyConnectionSetup); ok {
+			switch setup {
+			case sdp.ConnectionRoleActive.String(), sdp.ConnectionRolePassive.String():
+			case sdp.ConnectionRoleActpass.String():
+				// The answerer has to pick a role, RFC 5763 Section 5
+				if sdpType == SDPTypeAnswer {
+					return &SDPValidationError{MediaIndex: i, Detail: setup, Err: ErrSDPUnsupportedSetup}
+				}
+			default:
+				return &SDPValidationError{MediaIndex: i, Detail: setup, Err: ErrSDPUnsupportedSetup}
+			}
+		}
+
+		for _, a := range m.Attributes {
+			if a.Key != "rtpmap" {
+				continue
+			}
+
+			split := strings.SplitN(a.Value, " ", 2)
+			if len(split) != 2 {
+				continue
+			}
+			payloadType, codec := split[0], strings.ToLower(split[1])
+			if existing, ok := payloadTypes[payloadType]; ok && existing != codec {
+				return &SDPValidationError{MediaIndex: i, Detail: a.Value, Err: ErrSDPConflictingPayloadType}
+			}
+			payloadTypes[payloadType] = codec
+		}
+	}
+
+	return nil
+}
+
+// sdesKeyed tells if a media section can be used without a fingerprint when
+// SRTP is keyed with SDES. Data sections can't, they are left unused.
+func sdesKeyed(m *sdp.MediaDescription) bool {
+	if NewRTPCodecType(m.MediaName.Media) == RTPCodecType(0) {
+		return true
+	}
+	_, crypto := m.Attribute(sdpAttrKeyCrypto)
+	return crypto
+}
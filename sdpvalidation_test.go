@@ -0,0 +1,59 @@
+package webrtc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pion/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRemoteDescription(t *testing.T) {
+	const (
+		session = "v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n"
+		audio   = "m=audio 9 UDP/TLS/RTP/SAVPF 111\r\nc=IN IP4 0.0.0.0\r\na=mid:0\r\na=rtpmap:111 opus/48000/2\r\n"
+		video   = "m=video 9 UDP/TLS/RTP/SAVPF 96\r\nc=IN IP4 0.0.0.0\r\na=mid:1\r\na=rtpmap:96 VP8/90000\r\n"
+		creds   = "a=ice-ufrag:ufrag\r\na=ice-pwd:pwd\r\n"
+		fp      = "a=fingerprint:sha-256 00:11\r\n"
+	)
+
+	for _, test := range []struct {
+		name       string
+		sdpType    SDPType
+		sdp        string
+		mediaIndex int
+		err        error
+	}{
+		{"Valid", SDPTypeOffer, session + fp + creds + audio + video, 0, nil},
+		{"MediaLevelAttributes", SDPTypeOffer, session + audio + fp + creds, 0, nil},
+		{"MissingFingerprint", SDPTypeOffer, session + creds + audio + fp + video, 1, ErrSDPMissingFingerprint},
+		{"MissingICECredentials", SDPTypeOffer, session + fp + audio + "a=ice-ufrag:ufrag\r\n", 0, ErrSDPMissingICECredentials},
+		{"RejectedSection", SDPTypeOffer, session + fp + creds + strings.Replace(audio, "9", "0", 1), 0, nil},
+		{
+			"ConflictingPayloadType", SDPTypeOffer,
+			session + fp + creds + audio + strings.Replace(video, "96", "111", -1),
+			1, ErrSDPConflictingPayloadType,
+		},
+		{"ActpassAnswer", SDPTypeAnswer, session + fp + creds + audio + "a=setup:actpass\r\n", 0, ErrSDPUnsupportedSetup},
+		{"ActpassOffer", SDPTypeOffer, session + fp + creds + audio + "a=setup:actpass\r\n", 0, nil},
+		{"HoldconnSetup", SDPTypeOffer, session + fp + creds + audio + "a=setup:holdconn\r\n", 0, ErrSDPUnsupportedSetup},
+	} {
+		parsed := &sdp.SessionDescription{}
+		if err := parsed.Unmarshal([]byte(test.sdp)); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		err := validateRemoteDescription(test.sdpType, parsed, false)
+		if test.err == nil {
+			assert.NoError(t, err, test.name)
+			continue
+		}
+
+		validationErr, ok := err.(*SDPValidationError)
+		if !ok {
+			t.Fatalf("%s: expected *SDPValidationError, got %v", test.name, err)
+		}
+		assert.Equal(t, test.err, validationErr.Err, test.name)
+		assert.Equal(t, test.mediaIndex, validationErr.MediaIndex, test.name)
+	}
+}
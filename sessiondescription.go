@@ -12,3 +12,31 @@ type SessionDescription struct {
 	// This will never be initialized by callers, internal use only
 	parsed *sdp.SessionDescription
 }
+
+// Unmarshal parses the SDP of the description. The result is kept, SDP must
+// not be changed afterwards.
+func (sd *SessionDescription) Unmarshal() (*sdp.SessionDescription, error) {
+	if sd.parsed == nil {
+		parsed := &sdp.SessionDescription{}
+		if err := parsed.Unmarshal([]byte(sd.SDP)); err != nil {
+			return nil, err
+		}
+		sd.parsed = parsed
+	}
+	return sd.parsed, nil
+}
+
+// MediaSections returns the media sections of the description in the order
+// of their m-lines
+func (sd *SessionDescription) MediaSections() ([]MediaSection, error) {
+	parsed, err := sd.Unmarshal()
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make([]MediaSection, 0, len(parsed.MediaDescriptions))
+	for _, m := range parsed.MediaDescriptions {
+		sections = append(sections, newMediaSection(parsed, m))
+	}
+	return sections, nil
+}
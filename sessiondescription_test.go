@@ -57,3 +57,62 @@ func TestSessionDescription_JSON(t *testing.T) {
 		)
 	}
 }
+
+func TestSessionDescription_MediaSections(t *testing.T) {
+	desc := SessionDescription{Type: SDPTypeOffer, SDP: `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=ice-lite
+a=fingerprint:sha-256 AA:BB
+a=group:BUNDLE 0 1
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=mid:0
+a=ice-ufrag:ufrag
+a=ice-pwd:pwd
+a=sendonly
+a=rtpmap:96 VP8/90000
+a=extmap:4 urn:ietf:params:rtp-hdrext:sdes:mid
+a=extmap:10/sendonly urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id
+a=rid:f send
+a=rid:h send
+a=rid:r recv
+a=simulcast:send f;h recv r
+m=application 0 DTLS/SCTP 5000
+c=IN IP4 0.0.0.0
+a=mid:1
+a=ice-ufrag:other
+a=ice-pwd:secret
+a=fingerprint:sha-1 CC:DD
+`}
+
+	sections, err := desc.MediaSections()
+	assert.NoError(t, err)
+	assert.Equal(t, []MediaSection{
+		{
+			Kind:          "video",
+			MID:           "0",
+			Direction:     RTPTransceiverDirectionSendonly,
+			ICEParameters: ICEParameters{UsernameFragment: "ufrag", Password: "pwd", ICELite: true},
+			Fingerprints:  []DTLSFingerprint{{Algorithm: "sha-256", Value: "AA:BB"}},
+			HeaderExtensions: []RTPHeaderExtensionParameter{
+				{URI: "urn:ietf:params:rtp-hdrext:sdes:mid", ID: 4},
+				{URI: "urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id", ID: 10},
+			},
+			SendRIDs: []string{"f", "h"},
+			RecvRIDs: []string{"r"},
+		},
+		{
+			Kind:          "application",
+			MID:           "1",
+			Direction:     RTPTransceiverDirectionSendrecv,
+			Rejected:      true,
+			ICEParameters: ICEParameters{UsernameFragment: "other", Password: "secret", ICELite: true},
+			Fingerprints:  []DTLSFingerprint{{Algorithm: "sha-1", Value: "CC:DD"}},
+		},
+	}, sections)
+
+	_, err = (&SessionDescription{SDP: "invalid"}).MediaSections()
+	assert.Error(t, err)
+}
@@ -28,12 +28,31 @@ type SettingEngine struct {
 		ICESrflxAcceptanceMinWait    *time.Duration
 		ICEPrflxAcceptanceMinWait    *time.Duration
 		ICERelayAcceptanceMinWait    *time.Duration
+		RTPKeepAlive                 time.Duration
+		KeyFrameRequest              time.Duration
+		QualityScore                 time.Duration
+		RTCPCoalescing               time.Duration
+		ICECredentialRotation        time.Duration
 	}
 	candidates struct {
-		ICETrickle      bool
-		ICENetworkTypes []NetworkType
+		ICETrickle          bool
+		ICENetworkTypes     []NetworkType
+		Filter              func(ICECandidate) bool
+		ICEMulticastDNSMode ICEMulticastDNSMode
 	}
-	LoggerFactory logging.LoggerFactory
+	rtcpMux struct {
+		Only bool
+	}
+	retransmission struct {
+		MaxAge   time.Duration
+		MaxBytes int
+	}
+	quotas         Quotas
+	mtu            int
+	insecureSDES   bool
+	startupProbe   *ProbeCluster
+	cryptoProvider CryptoProvider
+	LoggerFactory  logging.LoggerFactory
 }
 
 // DetachDataChannels enables detaching data channels. When enabled
@@ -75,6 +94,120 @@ func (e *SettingEngine) SetRelayAcceptanceMinWait(t time.Duration) {
 	e.timeout.ICERelayAcceptanceMinWait = &t
 }
 
+// SetAggressiveNomination makes the controlling ICE agent nominate the first
+// candidate pair whose check succeeded, instead of waiting the acceptance
+// wait of its candidate types for a pair of a preferred type. It is the same
+// as SetICENominationStrategy(ICENominationStrategyAggressive).
+func (e *SettingEngine) SetAggressiveNomination() {
+	_ = e.SetICENominationStrategy(ICENominationStrategyAggressive)
+}
+
+// SetICENominationStrategy chooses when the controlling ICE agent nominates a
+// candidate pair. Aggressive nomination suits clients that want media to
+// start quickly, e.g. behind NATs where only relayed pairs work. Regular
+// nomination, the default, suits deployments where most peers connect
+// directly and a relayed path costs bandwidth on the TURN server. The
+// strategy replaces the acceptance waits set before, the Set*AcceptanceMinWait
+// setters tune it afterwards.
+//
+// Both strategies nominate like regular nomination of ICE: the pairs are
+// checked first and a single pair is nominated with a further check. The
+// aggressive strategy only sets the acceptance waits to zero, it doesn't set
+// USE-CANDIDATE on every check like the aggressive nomination of RFC 5245.
+func (e *SettingEngine) SetICENominationStrategy(strategy ICENominationStrategy) error {
+	var wait *time.Duration
+	switch strategy {
+	case ICENominationStrategyRegular:
+	case ICENominationStrategyAggressive:
+		wait = new(time.Duration)
+	default:
+		return ErrUnknownType
+	}
+
+	e.timeout.ICEHostAcceptanceMinWait = wait
+	e.timeout.ICESrflxAcceptanceMinWait = wait
+	e.timeout.ICEPrflxAcceptanceMinWait = wait
+	e.timeout.ICERelayAcceptanceMinWait = wait
+	return nil
+}
+
+// SetRTPKeepAliveInterval enables RTP keep-alives. Every RTPSender that has been
+// silent for the given interval sends a small padding only RTP packet, keeping
+// NAT bindings for the stream open during audio DTX or paused video.
+// An interval of 0 (the default) disables keep-alives.
+func (e *SettingEngine) SetRTPKeepAliveInterval(interval time.Duration) {
+	e.timeout.RTPKeepAlive = interval
+}
+
+// SetKeyFrameRequestInterval sets the window the key frame requests of an
+// RTPReceiver are consolidated in, at most one request is sent per window.
+// See RTPReceiver.RequestKeyFrame. The default is half a second.
+func (e *SettingEngine) SetKeyFrameRequestInterval(interval time.Duration) {
+	e.timeout.KeyFrameRequest = interval
+}
+
+// SetQualityScoreInterval sets how often the QualityScore handler of a
+// PeerConnection is called, see PeerConnection.OnQualityScore. The default
+// is two seconds.
+func (e *SettingEngine) SetQualityScoreInterval(interval time.Duration) {
+	e.timeout.QualityScore = interval
+}
+
+// SetRTCPCoalescingInterval makes the DTLSTransport collect the RTCP written
+// within the interval, e.g. with PeerConnection.WriteRTCP or by key frame
+// requests, and send it as compound packets with the reports first. This
+// reduces the packet rate of feedback heavy sessions at the cost of up to an
+// interval of delay. An interval of 0 (the default) sends RTCP right away.
+func (e *SettingEngine) SetRTCPCoalescingInterval(interval time.Duration) {
+	e.timeout.RTCPCoalescing = interval
+}
+
+// SetStartupProbe makes every RTPSender send the given ProbeCluster as soon
+// as its track writes the first packet, so new sessions reach their target
+// bitrate quickly. See RTPSender.Probe for details.
+func (e *SettingEngine) SetStartupProbe(cluster ProbeCluster) error {
+	if err := cluster.validate(); err != nil {
+		return err
+	}
+
+	e.startupProbe = &cluster
+	return nil
+}
+
+// SetRTCPMuxOnly adds a=rtcp-mux-only (RFC 8858) to the media sections of
+// local descriptions. This tells the remote that RTCP will never be sent on a
+// separate port, so it doesn't have to gather candidates for RTCP.
+func (e *SettingEngine) SetRTCPMuxOnly(only bool) {
+	e.rtcpMux.Only = only
+}
+
+// SetCryptoProvider sets the CryptoProvider used for certificates generated by
+// PeerConnections and for the DTLS handshake, e.g. NewAESGCMCryptoProvider.
+func (e *SettingEngine) SetCryptoProvider(provider CryptoProvider) {
+	e.cryptoProvider = provider
+}
+
+func (e *SettingEngine) getCryptoProvider() CryptoProvider {
+	if e.cryptoProvider == nil {
+		return defaultCryptoProvider{}
+	}
+	return e.cryptoProvider
+}
+
+// SetInsecureSDES allows SRTP to be keyed without DTLS, for interop with
+// legacy SIP endpoints and gateways that can't do DTLS-SRTP. Local
+// descriptions carry an a=crypto attribute with the SRTP master key of the
+// PeerConnection (SDES, RFC 4568), and a remote description that has one
+// but no fingerprint is keyed with it instead of DTLS. Data channels aren't
+// available then. External key management like MIKEY can key an ORTC
+// DTLSTransport with DTLSTransport.StartSRTP.
+//
+// This is insecure: anyone who can read the signaling can decrypt the
+// media, and the keys aren't bound to the ICE connection.
+func (e *SettingEngine) SetInsecureSDES(enable bool) {
+	e.insecureSDES = enable
+}
+
 // SetEphemeralUDPPortRange limits the pool of ephemeral ports that
 // ICE UDP connections can allocate from. This affects both host candidates,
 // and the local address of server reflexive candidates.
@@ -88,6 +221,17 @@ func (e *SettingEngine) SetEphemeralUDPPortRange(portMin, portMax uint16) error
 	return nil
 }
 
+// SetUDPPort makes the host candidates of ICE use port, so the port is
+// known before the offer is created, e.g. to publish it as a NodePort. The
+// port has to be free when the candidates are gathered, otherwise gathering
+// fails with ErrUDPPortInUse, so every PeerConnection needs a port of its
+// own. No server reflexive candidates are gathered, the host candidates
+// are expected to be reachable. Zero restores ephemeral ports.
+func (e *SettingEngine) SetUDPPort(port uint16) {
+	e.ephemeralUDP.PortMin = port
+	e.ephemeralUDP.PortMax = port
+}
+
 // SetTrickle configures whether or not the ice agent should gather candidates
 // via the trickle method or synchronously.
 func (e *SettingEngine) SetTrickle(trickle bool) {
@@ -99,3 +243,81 @@ func (e *SettingEngine) SetTrickle(trickle bool) {
 func (e *SettingEngine) SetNetworkTypes(candidateTypes []NetworkType) {
 	e.candidates.ICENetworkTypes = candidateTypes
 }
+
+// SetICECredentialRotationInterval makes PeerConnections restart ICE with new
+// credentials every interval once they are connected, for deployments whose
+// security policy requires credentials to be rotated on sessions that last
+// for days. The restart offers are signaled with the handler of
+// PeerConnection.OnICECredentialRotation, DTLS and the media keep running.
+// Zero, the default, doesn't rotate the credentials.
+func (e *SettingEngine) SetICECredentialRotationInterval(interval time.Duration) {
+	e.timeout.ICECredentialRotation = interval
+}
+
+// SetICEMulticastDNSMode controls how the ICE agent uses mDNS. Desktop
+// applications that shouldn't reveal the addresses of the local network in
+// their session descriptions can use ICEMulticastDNSModeQueryAndGather, which
+// signals host candidates with a generated .local name like browsers do.
+// Remotes that don't resolve mDNS can only connect through server reflexive
+// or relay candidates then.
+func (e *SettingEngine) SetICEMulticastDNSMode(mode ICEMulticastDNSMode) error {
+	switch mode {
+	case ICEMulticastDNSModeDisabled, ICEMulticastDNSModeQueryOnly, ICEMulticastDNSModeQueryAndGather:
+		e.candidates.ICEMulticastDNSMode = mode
+		return nil
+	default:
+		return ErrUnknownType
+	}
+}
+
+// SetQuotas limits the resources the remote peer can make a PeerConnection
+// allocate. Every PeerConnection of the API enforces the quotas on its own.
+func (e *SettingEngine) SetQuotas(quotas Quotas) {
+	e.quotas = quotas
+}
+
+// SetRetransmissionBuffer makes RTPSenders keep the packets they sent to
+// answer the NACKs of the remote. Packets are kept for maxAge and as long as
+// all of them add up to at most maxBytes, zero disables either limit.
+// Packets that are past their playout deadline at the remote aren't worth
+// the bandwidth of a retransmission, e.g. 500ms is enough for most
+// interactive video. Retransmissions are disabled by default.
+func (e *SettingEngine) SetRetransmissionBuffer(maxAge time.Duration, maxBytes int) {
+	e.retransmission.MaxAge = maxAge
+	e.retransmission.MaxBytes = maxBytes
+}
+
+// SetMTU sets the size of the RTP packets that samples written to tracks are
+// split into and of the compound RTCP packets, rtpOutboundMTU by default.
+// The overhead of the selected candidate pair is deducted: TURN relays wrap
+// the packets they forward and TCP has a larger header, so large packets
+// aren't fragmented or dropped on those paths. Tracks can override it with
+// Track.SetMTU.
+func (e *SettingEngine) SetMTU(mtu int) error {
+	if mtu < minMTU || mtu > maxMTU {
+		return ErrInvalidMTU
+	}
+	e.mtu = mtu
+	return nil
+}
+
+func (e *SettingEngine) getMTU() int {
+	if e.mtu == 0 {
+		return rtpOutboundMTU
+	}
+	return e.mtu
+}
+
+// SetCandidateFilter sets a function that decides which candidates are kept,
+// candidates it returns false for are dropped. Local candidates that are
+// dropped are never signaled, neither with OnICECandidate nor in a session
+// description. Remote candidates that are dropped are never paired, whether
+// they come from the remote description or AddICECandidate.
+//
+// The ICE agent still sends connectivity checks from dropped local
+// candidates to the remote candidates, the remote may learn them as peer
+// reflexive candidates. Filter the remote candidates on both sides to keep
+// a network out of use.
+func (e *SettingEngine) SetCandidateFilter(filter func(ICECandidate) bool) {
+	e.candidates.Filter = filter
+}
@@ -48,6 +48,71 @@ func TestSetConnectionTimeout(t *testing.T) {
 	}
 }
 
+func TestSetAggressiveNomination(t *testing.T) {
+	s := SettingEngine{}
+	s.SetRelayAcceptanceMinWait(2 * time.Second)
+
+	s.SetAggressiveNomination()
+
+	for _, wait := range []*time.Duration{
+		s.timeout.ICEHostAcceptanceMinWait,
+		s.timeout.ICESrflxAcceptanceMinWait,
+		s.timeout.ICEPrflxAcceptanceMinWait,
+		s.timeout.ICERelayAcceptanceMinWait,
+	} {
+		if wait == nil || *wait != 0 {
+			t.Fatalf("Acceptance waits aren't disabled.")
+		}
+	}
+}
+
+func TestSetICENominationStrategy(t *testing.T) {
+	s := SettingEngine{}
+	if err := s.SetICENominationStrategy(ICENominationStrategy(Unknown)); err != ErrUnknownType {
+		t.Fatalf("Unknown strategy accepted: %v", err)
+	}
+
+	s.SetRelayAcceptanceMinWait(2 * time.Second)
+	if err := s.SetICENominationStrategy(ICENominationStrategyAggressive); err != nil {
+		t.Fatal(err)
+	}
+	for _, wait := range []*time.Duration{
+		s.timeout.ICEHostAcceptanceMinWait,
+		s.timeout.ICESrflxAcceptanceMinWait,
+		s.timeout.ICEPrflxAcceptanceMinWait,
+		s.timeout.ICERelayAcceptanceMinWait,
+	} {
+		if wait == nil || *wait != 0 {
+			t.Fatalf("Acceptance waits aren't disabled.")
+		}
+	}
+
+	// Regular nomination restores the acceptance waits of pion/ice
+	if err := s.SetICENominationStrategy(ICENominationStrategyRegular); err != nil {
+		t.Fatal(err)
+	}
+	if s.timeout.ICEHostAcceptanceMinWait != nil ||
+		s.timeout.ICESrflxAcceptanceMinWait != nil ||
+		s.timeout.ICEPrflxAcceptanceMinWait != nil ||
+		s.timeout.ICERelayAcceptanceMinWait != nil {
+		t.Fatalf("Acceptance waits aren't the defaults.")
+	}
+}
+
+func TestSetICEMulticastDNSMode(t *testing.T) {
+	s := SettingEngine{}
+	if err := s.SetICEMulticastDNSMode(ICEMulticastDNSMode(Unknown)); err != ErrUnknownType {
+		t.Fatalf("Unknown mode accepted: %v", err)
+	}
+
+	if err := s.SetICEMulticastDNSMode(ICEMulticastDNSModeQueryAndGather); err != nil {
+		t.Fatal(err)
+	}
+	if s.candidates.ICEMulticastDNSMode != ICEMulticastDNSModeQueryAndGather {
+		t.Fatalf("Mode wasn't set.")
+	}
+}
+
 func TestDetachDataChannels(t *testing.T) {
 	s := SettingEngine{}
 
@@ -61,3 +126,35 @@ func TestDetachDataChannels(t *testing.T) {
 		t.Fatalf("Failed to enable detached data channels.")
 	}
 }
+
+func TestSetRTPKeepAliveInterval(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.timeout.RTPKeepAlive != 0 {
+		t.Fatalf("RTP keep-alives should be disabled by default.")
+	}
+
+	s.SetRTPKeepAliveInterval(15 * time.Second)
+
+	if s.timeout.RTPKeepAlive != 15*time.Second {
+		t.Fatalf("RTP keep-alive interval does not reflect requested value.")
+	}
+}
+
+func TestSetStartupProbe(t *testing.T) {
+	s := SettingEngine{}
+
+	if err := s.SetStartupProbe(ProbeCluster{}); err == nil {
+		t.Fatalf("Setting engine should fail an empty ProbeCluster.")
+	}
+
+	if err := s.SetStartupProbe(ProbeCluster{Bitrate: 1000000, Duration: 2 * time.Second}); err != nil {
+		t.Fatalf("Setting engine failed valid ProbeCluster: %s", err)
+	}
+
+	if s.startupProbe == nil ||
+		s.startupProbe.Bitrate != 1000000 ||
+		s.startupProbe.Duration != 2*time.Second {
+		t.Fatalf("Startup probe does not reflect requested value.")
+	}
+}
@@ -0,0 +1,309 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+const (
+	sdpAttrKeyICELite        = "ice-lite"
+	sdpAttrKeyMaxMessageSize = "max-message-size"
+)
+
+// SignalingParameters is what a SessionDescription negotiates as structured
+// data: ICE parameters and candidates, DTLS parameters and the media sections.
+// It is meant for signaling protocols that don't exchange SDP, it marshals to
+// JSON and its fields can be passed to the ICETransport, DTLSTransport and
+// SCTPTransport directly. NewSignalingParameters and SessionDescription convert
+// it from and to SDP to interoperate with peers that only understand SDP.
+type SignalingParameters struct {
+	ICEParameters  ICEParameters     `json:"iceParameters"`
+	ICECandidates  []ICECandidate    `json:"iceCandidates,omitempty"`
+	DTLSParameters DTLSParameters    `json:"dtlsParameters"`
+	Media          []MediaParameters `json:"media,omitempty"`
+
+	// SCTPCapabilities is set if data channels are negotiated, DataMid is
+	// the mid of their media section
+	SCTPCapabilities *SCTPCapabilities `json:"sctpCapabilities,omitempty"`
+	DataMid          string            `json:"dataMid,omitempty"`
+}
+
+// NewSignalingParameters extracts the SignalingParameters of a session
+// description. All media sections are expected to share one transport as they
+// do with BUNDLE, the first ICE credentials and fingerprints found are used.
+// Rejected media sections are left out.
+func NewSignalingParameters(desc SessionDescription) (*SignalingParameters, error) {
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(desc.SDP)); err != nil {
+		return nil, err
+	}
+
+	params := &SignalingParameters{}
+	params.ICEParameters.UsernameFragment, _ = parsed.Attribute("ice-ufrag")
+	params.ICEParameters.Password, _ = parsed.Attribute("ice-pwd")
+	_, params.ICEParameters.ICELite = parsed.Attribute(sdpAttrKeyICELite)
+	setup, _ := parsed.Attribute(sdp.AttrKeyConnectionSetup)
+
+	fingerprints, err := sdpFingerprints(parsed.Attributes, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := map[string]bool{}
+	for i, media := range parsed.MediaDescriptions {
+		if isRejectedMediaSection(media) {
+			continue
+		}
+
+		if params.ICEParameters.UsernameFragment == "" {
+			params.ICEParameters.UsernameFragment, _ = media.Attribute("ice-ufrag")
+		}
+		if params.ICEParameters.Password == "" {
+			params.ICEParameters.Password, _ = media.Attribute("ice-pwd")
+		}
+		if setup == "" {
+			setup, _ = media.Attribute(sdp.AttrKeyConnectionSetup)
+		}
+		if len(fingerprints) == 0 {
+			if fingerprints, err = sdpFingerprints(media.Attributes, i); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, a := range media.Attributes {
+			if !a.IsICECandidate() {
+				continue
+			}
+			sdpCandidate, err := a.ToICECandidate()
+			if err != nil {
+				return nil, &ICECandidateError{Candidate: a.Value, Err: err}
+			}
+			// RTCP is muxed, the candidates of the other components and of
+			// the other bundled sections are the same
+			if sdpCandidate.Component != 1 || candidates[a.Value] {
+				continue
+			}
+			candidates[a.Value] = true
+
+			candidate, err := newICECandidateFromSDP(sdpCandidate)
+			if err != nil {
+				return nil, &ICECandidateError{Candidate: a.Value, Err: err}
+			}
+			params.ICECandidates = append(params.ICECandidates, candidate)
+		}
+
+		midValue, _ := media.Attribute(sdp.AttrKeyMID)
+		kind := NewRTPCodecType(media.MediaName.Media)
+		if kind == RTPCodecType(0) {
+			if media.MediaName.Media == "application" {
+				params.DataMid = midValue
+				params.SCTPCapabilities = &SCTPCapabilities{}
+				if value, ok := media.Attribute(sdpAttrKeyMaxMessageSize); ok {
+					size, err := strconv.ParseUint(value, 10, 32)
+					if err != nil {
+						return nil, &SDPValidationError{MediaIndex: i, Detail: value, Err: err}
+					}
+					params.SCTPCapabilities.MaxMessageSize = uint32(size)
+				}
+			}
+			continue
+		}
+
+		mediaParams, err := newMediaParameters(parsed, media, i)
+		if err != nil {
+			return nil, err
+		}
+		mediaParams.Mid = midValue
+		mediaParams.Kind = kind
+		params.Media = append(params.Media, mediaParams)
+	}
+
+	if params.ICEParameters.UsernameFragment == "" || params.ICEParameters.Password == "" {
+		return nil, &SDPValidationError{MediaIndex: -1, Err: ErrSDPMissingICECredentials}
+	}
+	if len(fingerprints) == 0 {
+		return nil, &SDPValidationError{MediaIndex: -1, Err: ErrSDPMissingFingerprint}
+	}
+	params.DTLSParameters.Fingerprints = fingerprints
+
+	switch setup {
+	case sdp.ConnectionRoleActive.String():
+		params.DTLSParameters.Role = DTLSRoleClient
+	case sdp.ConnectionRolePassive.String():
+		params.DTLSParameters.Role = DTLSRoleServer
+	default:
+		params.DTLSParameters.Role = DTLSRoleAuto
+	}
+
+	return params, nil
+}
+
+// newRTPCodecParameters converts a codec of media
+func newRTPCodecParameters(media *sdp.MediaDescription, codec sdp.Codec, feedback map[string][]RTCPFeedback) RTPCodecParameters {
+	channels, _ := strconv.Atoi(codec.EncodingParameters)
+	return RTPCodecParameters{
+		RTPCodecCapability: RTPCodecCapability{
+			MimeType:     media.MediaName.Media + "/" + codec.Name,
+			ClockRate:    codec.ClockRate,
+			Channels:     uint16(channels),
+			SDPFmtpLine:  codec.Fmtp,
+			RTCPFeedback: codecRTCPFeedback(feedback, strconv.Itoa(int(codec.PayloadType))),
+		},
+		PayloadType: codec.PayloadType,
+	}
+}
+
+// newMediaParameters reads the codecs, direction and sources of an audio or
+// video section
+func newMediaParameters(parsed *sdp.SessionDescription, media *sdp.MediaDescription, mediaIndex int) (MediaParameters, error) {
+	params := MediaParameters{Direction: RTPTransceiverDirectionSendrecv}
+
+	feedback := getRTCPFeedback(media)
+	for _, format := range media.MediaName.Formats {
+		payloadType, err := strconv.ParseUint(format, 10, 8)
+		if err != nil {
+			return MediaParameters{}, &SDPValidationError{MediaIndex: mediaIndex, Detail: format, Err: err}
+		}
+		codec, err := parsed.GetCodecForPayloadType(uint8(payloadType))
+		if err != nil {
+			return MediaParameters{}, &SDPValidationError{MediaIndex: mediaIndex, Detail: format, Err: err}
+		}
+		params.Codecs = append(params.Codecs, newRTPCodecParameters(media, codec, feedback))
+	}
+
+	seen := map[uint32]bool{}
+	for _, a := range media.Attributes {
+		switch a.Key {
+		case RTPTransceiverDirectionSendrecv.String(), RTPTransceiverDirectionSendonly.String(),
+			RTPTransceiverDirectionRecvonly.String(), RTPTransceiverDirectionInactive.String():
+			params.Direction = NewRTPTransceiverDirection(a.Key)
+		case sdpAttrKeySSRCGroup:
+			group, err := parseSSRCGroup(a.Value)
+			if err != nil {
+				return MediaParameters{}, &SDPValidationError{MediaIndex: mediaIndex, Detail: a.Value, Err: err}
+			}
+			params.SSRCGroups = append(params.SSRCGroups, group)
+		case sdp.AttrKeySSRC:
+			split := strings.Fields(a.Value)
+			if len(split) == 0 {
+				continue
+			}
+			ssrc, err := strconv.ParseUint(split[0], 10, 32)
+			if err != nil {
+				return MediaParameters{}, &SDPValidationError{MediaIndex: mediaIndex, Detail: a.Value, Err: err}
+			}
+			if !seen[uint32(ssrc)] {
+				seen[uint32(ssrc)] = true
+				params.SSRCs = append(params.SSRCs, uint32(ssrc))
+			}
+			if len(split) > 1 && strings.HasPrefix(split[1], "cname:") {
+				params.CNAME = split[1][len("cname:"):]
+			}
+		}
+	}
+
+	return params, nil
+}
+
+// sdpFingerprints returns the fingerprints among attributes
+func sdpFingerprints(attributes []sdp.Attribute, mediaIndex int) ([]DTLSFingerprint, error) {
+	var fingerprints []DTLSFingerprint
+	for _, a := range attributes {
+		if a.Key != "fingerprint" {
+			continue
+		}
+		parts := strings.Split(a.Value, " ")
+		if len(parts) != 2 {
+			return nil, &SDPValidationError{MediaIndex: mediaIndex, Detail: a.Value, Err: ErrSDPInvalidFingerprint}
+		}
+		fingerprints = append(fingerprints, DTLSFingerprint{Algorithm: parts[0], Value: strings.ToLower(parts[1])})
+	}
+	return fingerprints, nil
+}
+
+// SessionDescription converts the parameters into a session description of
+// the given type, e.g. to pass them to SetRemoteDescription of a
+// PeerConnection. All media sections are bundled. An offer whose DTLS role is
+// DTLSRoleAuto leaves the role to the answerer, an answer with DTLSRoleAuto
+// takes the client role.
+func (p *SignalingParameters) SessionDescription(sdpType SDPType) (SessionDescription, error) {
+	d := sdp.NewJSEPSessionDescription(false)
+	for _, fingerprint := range p.DTLSParameters.Fingerprints {
+		d.WithFingerprint(fingerprint.Algorithm, strings.ToUpper(fingerprint.Value))
+	}
+	if p.ICEParameters.ICELite {
+		d.WithPropertyAttribute(sdpAttrKeyICELite)
+	}
+
+	var dtlsRole sdp.ConnectionRole
+	switch p.DTLSParameters.Role {
+	case DTLSRoleClient:
+		dtlsRole = sdp.ConnectionRoleActive
+	case DTLSRoleServer:
+		dtlsRole = sdp.ConnectionRolePassive
+	default:
+		dtlsRole = sdp.ConnectionRoleActive
+		if sdpType == SDPTypeOffer {
+			dtlsRole = sdp.ConnectionRoleActpass
+		}
+	}
+
+	bundleValue := "BUNDLE"
+	for i, m := range p.Media {
+		if m.Kind != RTPCodecTypeAudio && m.Kind != RTPCodecTypeVideo {
+			return SessionDescription{}, &SDPValidationError{MediaIndex: i, Detail: m.Mid, Err: ErrUnknownType}
+		}
+		direction := m.Direction
+		if direction == RTPTransceiverDirection(Unknown) {
+			direction = RTPTransceiverDirectionSendrecv
+		}
+
+		media := sdp.NewJSEPMediaDescription(m.Kind.String(), []string{}).
+			WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()).
+			WithValueAttribute(sdp.AttrKeyMID, m.Mid).
+			WithICECredentials(p.ICEParameters.UsernameFragment, p.ICEParameters.Password).
+			WithPropertyAttribute(sdp.AttrKeyRTCPMux).
+			WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
+		for _, codec := range m.Codecs {
+			name := codec.MimeType
+			if i := strings.Index(name, "/"); i != -1 {
+				name = name[i+1:]
+			}
+			media.WithCodec(codec.PayloadType, name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
+			for _, feedback := range codec.RTCPFeedback {
+				media.WithValueAttribute(sdpAttrKeyRTCPFeedback, strings.TrimSpace(fmt.Sprintf("%d %s %s", codec.PayloadType, feedback.Type, feedback.Parameter)))
+			}
+		}
+		for _, group := range m.SSRCGroups {
+			media.WithValueAttribute(sdpAttrKeySSRCGroup, group.String())
+		}
+		for _, ssrc := range m.SSRCs {
+			media.WithValueAttribute(sdp.AttrKeySSRC, fmt.Sprintf("%d cname:%s", ssrc, m.CNAME))
+		}
+		media.WithPropertyAttribute(direction.String())
+		addCandidatesToMediaDescriptions(p.ICECandidates, media)
+		d.WithMedia(media)
+		bundleValue += " " + m.Mid
+	}
+
+	if p.SCTPCapabilities != nil {
+		addDataMediaSection(d, p.DataMid, p.ICEParameters, p.ICECandidates, dtlsRole)
+		if p.SCTPCapabilities.MaxMessageSize != 0 {
+			media := d.MediaDescriptions[len(d.MediaDescriptions)-1]
+			media.WithValueAttribute(sdpAttrKeyMaxMessageSize, strconv.FormatUint(uint64(p.SCTPCapabilities.MaxMessageSize), 10))
+		}
+		bundleValue += " " + p.DataMid
+	}
+	d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue)
+
+	out, err := d.Marshal()
+	if err != nil {
+		return SessionDescription{}, err
+	}
+	return SessionDescription{Type: sdpType, SDP: string(out)}, nil
+}
@@ -0,0 +1,102 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignalingParameters(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	params, err := NewSignalingParameters(offer)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, params.ICEParameters.UsernameFragment)
+	assert.NotEmpty(t, params.ICEParameters.Password)
+	assert.Equal(t, DTLSRoleAuto, params.DTLSParameters.Role)
+	assert.NotEmpty(t, params.DTLSParameters.Fingerprints)
+	assert.NotNil(t, params.SCTPCapabilities)
+	assert.Equal(t, "1", params.DataMid)
+
+	if assert.Len(t, params.Media, 1) {
+		media := params.Media[0]
+		assert.Equal(t, "0", media.Mid)
+		assert.Equal(t, RTPCodecTypeVideo, media.Kind)
+		assert.Equal(t, RTPTransceiverDirectionSendrecv, media.Direction)
+		assert.Equal(t, []uint32{1234}, media.SSRCs)
+		assert.Equal(t, "pion", media.CNAME)
+		if assert.NotEmpty(t, media.Codecs) {
+			assert.Equal(t, "video/VP8", media.Codecs[0].MimeType)
+			assert.Equal(t, uint8(DefaultPayloadTypeVP8), media.Codecs[0].PayloadType)
+		}
+	}
+
+	raw, err := json.Marshal(params)
+	assert.NoError(t, err)
+	assert.Contains(t, string(raw), `"kind":"video"`)
+	assert.Contains(t, string(raw), `"direction":"sendrecv"`)
+
+	var decoded SignalingParameters
+	assert.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Equal(t, *params, decoded)
+
+	// The converted offer is accepted by a PeerConnection and converts back
+	// to the same parameters
+	feedback := RTCPFeedback{Type: "nack", Parameter: "pli"}
+	params.Media[0].Codecs[0].RTCPFeedback = append(params.Media[0].Codecs[0].RTCPFeedback, feedback)
+	decoded.Media[0].Codecs[0].RTCPFeedback = append(decoded.Media[0].Codecs[0].RTCPFeedback, feedback)
+	converted, err := decoded.SessionDescription(SDPTypeOffer)
+	assert.NoError(t, err)
+	reparsed, err := NewSignalingParameters(converted)
+	assert.NoError(t, err)
+	assert.Equal(t, params, reparsed)
+
+	assert.NoError(t, pcAnswer.SetRemoteDescription(converted))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	answerParams, err := NewSignalingParameters(answer)
+	assert.NoError(t, err)
+	assert.Equal(t, DTLSRoleClient, answerParams.DTLSParameters.Role)
+	if assert.Len(t, answerParams.Media, 1) {
+		assert.Equal(t, "0", answerParams.Media[0].Mid)
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestSignalingParametersInvalid(t *testing.T) {
+	params := &SignalingParameters{
+		ICEParameters: ICEParameters{UsernameFragment: "ufrag", Password: "pwd"},
+		Media:         []MediaParameters{{Mid: "0"}},
+	}
+	_, err := params.SessionDescription(SDPTypeOffer)
+	assert.True(t, errors.Is(err, ErrUnknownType))
+
+	params.Media = nil
+	params.SCTPCapabilities = &SCTPCapabilities{}
+	params.DataMid = "0"
+	desc, err := params.SessionDescription(SDPTypeOffer)
+	assert.NoError(t, err)
+
+	// Without certificate fingerprints the description can't be used
+	_, err = NewSignalingParameters(desc)
+	assert.True(t, errors.Is(err, ErrSDPMissingFingerprint))
+}
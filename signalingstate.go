@@ -103,76 +103,49 @@ func (t SignalingState) String() string {
 	}
 }
 
-func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType SDPType) (SignalingState, error) {
-	// Special case for rollbacks
-	if sdpType == SDPTypeRollback && cur == SignalingStateStable {
-		return cur, &rtcerr.InvalidModificationError{
-			Err: fmt.Errorf("can't rollback from stable state"),
-		}
-	}
+// signalingStateTransition is a valid transition of the signaling state,
+// https://www.w3.org/TR/webrtc/#rtcsignalingstate-enum. A rollback of a
+// remote offer isn't supported, the transceivers and transports it set up
+// can't be undone.
+type signalingStateTransition struct {
+	from    SignalingState
+	op      stateChangeOp
+	sdpType SDPType
+	to      SignalingState
+}
 
-	// 4.3.1 valid state transitions
-	switch cur {
-	case SignalingStateStable:
-		switch op {
-		case stateChangeOpSetLocal:
-			// stable->SetLocal(offer)->have-local-offer
-			if sdpType == SDPTypeOffer && next == SignalingStateHaveLocalOffer {
-				return next, nil
-			}
-		case stateChangeOpSetRemote:
-			// stable->SetRemote(offer)->have-remote-offer
-			if sdpType == SDPTypeOffer && next == SignalingStateHaveRemoteOffer {
-				return next, nil
-			}
-		}
-	case SignalingStateHaveLocalOffer:
-		if op == stateChangeOpSetRemote {
-			switch sdpType {
-			// have-local-offer->SetRemote(answer)->stable
-			case SDPTypeAnswer:
-				if next == SignalingStateStable {
-					return next, nil
-				}
-			// have-local-offer->SetRemote(pranswer)->have-remote-pranswer
-			case SDPTypePranswer:
-				if next == SignalingStateHaveRemotePranswer {
-					return next, nil
-				}
-			}
-		}
-	case SignalingStateHaveRemotePranswer:
-		if op == stateChangeOpSetRemote && sdpType == SDPTypeAnswer {
-			// have-remote-pranswer->SetRemote(answer)->stable
-			if next == SignalingStateStable {
-				return next, nil
-			}
-		}
-	case SignalingStateHaveRemoteOffer:
-		if op == stateChangeOpSetLocal {
-			switch sdpType {
-			// have-remote-offer->SetLocal(answer)->stable
-			case SDPTypeAnswer:
-				if next == SignalingStateStable {
-					return next, nil
-				}
-			// have-remote-offer->SetLocal(pranswer)->have-local-pranswer
-			case SDPTypePranswer:
-				if next == SignalingStateHaveLocalPranswer {
-					return next, nil
-				}
-			}
+var signalingStateTransitions = []signalingStateTransition{
+	{SignalingStateStable, stateChangeOpSetLocal, SDPTypeOffer, SignalingStateHaveLocalOffer},
+	{SignalingStateStable, stateChangeOpSetRemote, SDPTypeOffer, SignalingStateHaveRemoteOffer},
+	{SignalingStateHaveLocalOffer, stateChangeOpSetRemote, SDPTypeAnswer, SignalingStateStable},
+	{SignalingStateHaveLocalOffer, stateChangeOpSetRemote, SDPTypePranswer, SignalingStateHaveRemotePranswer},
+	{SignalingStateHaveLocalOffer, stateChangeOpSetLocal, SDPTypeRollback, SignalingStateStable},
+	{SignalingStateHaveRemotePranswer, stateChangeOpSetRemote, SDPTypeAnswer, SignalingStateStable},
+	{SignalingStateHaveRemoteOffer, stateChangeOpSetLocal, SDPTypeAnswer, SignalingStateStable},
+	{SignalingStateHaveRemoteOffer, stateChangeOpSetLocal, SDPTypePranswer, SignalingStateHaveLocalPranswer},
+	{SignalingStateHaveLocalPranswer, stateChangeOpSetLocal, SDPTypeAnswer, SignalingStateStable},
+}
+
+// checkNextSignalingState returns next if op with a description of sdpType
+// moves the signaling state from cur to next. Otherwise it returns cur and
+// an *rtcerr.InvalidModificationError wrapping a *SignalingStateError.
+func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType SDPType) (SignalingState, error) {
+	var expected []SignalingState
+	for _, t := range signalingStateTransitions {
+		if t.op != op || t.sdpType != sdpType {
+			continue
 		}
-	case SignalingStateHaveLocalPranswer:
-		if op == stateChangeOpSetLocal && sdpType == SDPTypeAnswer {
-			// have-local-pranswer->SetLocal(answer)->stable
-			if next == SignalingStateStable {
-				return next, nil
-			}
+		if t.from == cur && t.to == next {
+			return next, nil
 		}
+		expected = append(expected, t.from)
 	}
 
 	return cur, &rtcerr.InvalidModificationError{
-		Err: fmt.Errorf("invalid proposed signaling state transition %s->%s(%s)->%s", cur, op, sdpType, next),
+		Err: &SignalingStateError{
+			State:     cur,
+			Operation: fmt.Sprintf("%s(%s)", op, sdpType),
+			Expected:  expected,
+		},
 	}
 }
@@ -127,6 +127,30 @@ func TestSignalingState_Transitions(t *testing.T) {
 			SDPTypeAnswer,
 			nil,
 		},
+		{
+			"have-local-offer->SetLocal(rollback)->stable",
+			SignalingStateHaveLocalOffer,
+			SignalingStateStable,
+			stateChangeOpSetLocal,
+			SDPTypeRollback,
+			nil,
+		},
+		{
+			"(invalid) have-remote-offer->SetRemote(rollback)->stable",
+			SignalingStateHaveRemoteOffer,
+			SignalingStateStable,
+			stateChangeOpSetRemote,
+			SDPTypeRollback,
+			&rtcerr.InvalidModificationError{},
+		},
+		{
+			"(invalid) have-local-offer->SetRemote(rollback)->stable",
+			SignalingStateHaveLocalOffer,
+			SignalingStateStable,
+			stateChangeOpSetRemote,
+			SDPTypeRollback,
+			&rtcerr.InvalidModificationError{},
+		},
 		{
 			"(invalid) stable->SetRemote(pranswer)->have-remote-pranswer",
 			SignalingStateStable,
@@ -159,3 +183,18 @@ func TestSignalingState_Transitions(t *testing.T) {
 		}
 	}
 }
+
+func TestSignalingStateError(t *testing.T) {
+	_, err := checkNextSignalingState(SignalingStateStable, SignalingStateStable, stateChangeOpSetRemote, SDPTypeAnswer)
+	modErr, ok := err.(*rtcerr.InvalidModificationError)
+	assert.True(t, ok)
+	assert.Equal(t, &SignalingStateError{
+		State:     SignalingStateStable,
+		Operation: "SetRemote(answer)",
+		Expected:  []SignalingState{SignalingStateHaveLocalOffer, SignalingStateHaveRemotePranswer},
+	}, modErr.Err)
+	assert.Equal(t,
+		"SetRemote(answer) is not allowed in signaling state stable, expected have-local-offer or have-remote-pranswer",
+		modErr.Err.Error(),
+	)
+}
@@ -0,0 +1,32 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SignalingStateError is returned when a description is set in a signaling
+// state that doesn't allow it, e.g. an answer in the stable state
+type SignalingStateError struct {
+	// State is the signaling state the description was set in
+	State SignalingState
+	// Operation is the attempted operation and the type of the
+	// description, e.g. "SetRemote(answer)"
+	Operation string
+	// Expected are the signaling states that allow Operation, it is empty
+	// if no state does
+	Expected []SignalingState
+}
+
+func (e *SignalingStateError) Error() string {
+	if len(e.Expected) == 0 {
+		return fmt.Sprintf("%s is never allowed, signaling state is %s", e.Operation, e.State)
+	}
+
+	expected := make([]string, len(e.Expected))
+	for i, s := range e.Expected {
+		expected[i] = s.String()
+	}
+	return fmt.Sprintf("%s is not allowed in signaling state %s, expected %s",
+		e.Operation, e.State, strings.Join(expected, " or "))
+}
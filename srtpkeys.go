@@ -0,0 +1,12 @@
+// +build !js
+
+package webrtc
+
+// SRTPKeys are the master keys and salts of an SRTP session that is keyed
+// without DTLS, e.g. by SDES or MIKEY. See DTLSTransport.StartSRTP.
+type SRTPKeys struct {
+	LocalMasterKey   []byte
+	LocalMasterSalt  []byte
+	RemoteMasterKey  []byte
+	RemoteMasterSalt []byte
+}
@@ -0,0 +1,105 @@
+// +build !js
+
+package webrtc
+
+import (
+	mathRand "math/rand"
+	"sync"
+)
+
+// ssrcAllocator keeps track of the SSRCs of a PeerConnection: the ones its
+// local tracks are sent with and the ones the remote announced. New SSRCs
+// are picked so that none of them collides, RFC 3550 8.1.
+type ssrcAllocator struct {
+	mu     sync.Mutex
+	local  map[uint32]*Track
+	remote map[uint32]bool
+}
+
+func newSSRCAllocator() *ssrcAllocator {
+	return &ssrcAllocator{local: map[uint32]*Track{}, remote: map[uint32]bool{}}
+}
+
+// allocate returns a random SSRC that is neither zero nor used
+func (a *ssrcAllocator) allocate() uint32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.allocateLocked(nil)
+}
+
+// allocateLocked returns a random SSRC that is neither zero, used nor in
+// exclude. a.mu must be held.
+func (a *ssrcAllocator) allocateLocked(exclude map[uint32]bool) uint32 {
+	for {
+		ssrc := mathRand.Uint32()
+		if _, ok := a.local[ssrc]; ssrc != 0 && !ok && !a.remote[ssrc] && !exclude[ssrc] {
+			return ssrc
+		}
+	}
+}
+
+// addTrack reserves the SSRCs of a local track, the ones of its simulcast
+// encodings and of its SSRC groups included. Encodings whose SSRC was picked
+// by SetEncodings get a new one if theirs is in use, as long as the track
+// isn't sent yet. It fails if another track or the remote uses one of the
+// other SSRCs already. A track can be added again.
+func (a *ssrcAllocator) addTrack(track *Track) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	track.reassignEncodingSSRCs(func(ssrc uint32, used map[uint32]bool) uint32 {
+		if a.inUse(ssrc, track) || used[ssrc] {
+			return a.allocateLocked(used)
+		}
+		return ssrc
+	})
+
+	ssrcs := []uint32{track.SSRC()}
+	for _, e := range track.Encodings() {
+		ssrcs = append(ssrcs, e.SSRC())
+	}
+	ssrcs = append(ssrcs, ssrcsOfGroups(track.SSRCGroups(), track.SSRC())...)
+	for _, ssrc := range ssrcs {
+		if a.inUse(ssrc, track) {
+			return ErrSSRCCollision
+		}
+	}
+	for _, ssrc := range ssrcs {
+		a.local[ssrc] = track
+	}
+	return nil
+}
+
+// removeTrack releases the SSRCs of a local track that isn't sent anymore
+func (a *ssrcAllocator) removeTrack(track *Track) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for ssrc, owner := range a.local {
+		if owner == track {
+			delete(a.local, ssrc)
+		}
+	}
+}
+
+// inUse tells if another track than track or the remote uses ssrc. a.mu must
+// be held.
+func (a *ssrcAllocator) inUse(ssrc uint32, track *Track) bool {
+	owner, ok := a.local[ssrc]
+	return (ok && owner != track) || a.remote[ssrc]
+}
+
+// setRemote replaces the SSRCs the remote announced and returns the ones
+// that collide with a local track
+func (a *ssrcAllocator) setRemote(ssrcs map[uint32]bool) []uint32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.remote = ssrcs
+
+	var collisions []uint32
+	for ssrc := range ssrcs {
+		if _, ok := a.local[ssrc]; ok {
+			collisions = append(collisions, ssrc)
+		}
+	}
+	return collisions
+}
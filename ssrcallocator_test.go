@@ -0,0 +1,102 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSRCAllocator(t *testing.T) {
+	codec := NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1, "video", "pion", codec)
+	assert.NoError(t, err)
+	sameSSRC, err := NewTrack(DefaultPayloadTypeVP8, 1, "other", "pion", codec)
+	assert.NoError(t, err)
+	remoteSSRC, err := NewTrack(DefaultPayloadTypeVP8, 2, "remote", "pion", codec)
+	assert.NoError(t, err)
+
+	a := newSSRCAllocator()
+	assert.Empty(t, a.setRemote(map[uint32]bool{2: true}))
+	assert.NoError(t, a.addTrack(track))
+	assert.NoError(t, a.addTrack(track))
+	assert.Equal(t, ErrSSRCCollision, a.addTrack(sameSSRC))
+	assert.Equal(t, ErrSSRCCollision, a.addTrack(remoteSSRC))
+
+	for i := 0; i < 100; i++ {
+		ssrc := a.allocate()
+		assert.NotContains(t, []uint32{0, 1, 2}, ssrc)
+	}
+
+	// An SSRC SetEncodings picked is replaced if it is in use, one that was
+	// set explicitly collides
+	simulcast, err := NewTrack(DefaultPayloadTypeVP8, 4, "simulcast", "pion", codec)
+	assert.NoError(t, err)
+	encodings, err := simulcast.SetEncodings([]RTPEncodingParameters{{RID: "q"}, {RID: "h"}, {RID: "f"}})
+	assert.NoError(t, err)
+	encodings[1].parameters.SSRC = 1
+	encodings[2].parameters.SSRC = 2
+	assert.NoError(t, a.addTrack(simulcast))
+	assert.NotContains(t, []uint32{0, 1, 2, 4}, encodings[1].SSRC())
+	assert.NotContains(t, []uint32{0, 1, 2, 4, encodings[1].SSRC()}, encodings[2].SSRC())
+
+	explicit, err := NewTrack(DefaultPayloadTypeVP8, 5, "explicit", "pion", codec)
+	assert.NoError(t, err)
+	_, err = explicit.SetEncodings([]RTPEncodingParameters{{RID: "q"}, {RID: "f", RTPCodingParameters: RTPCodingParameters{SSRC: 1}}})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrSSRCCollision, a.addTrack(explicit))
+
+	assert.Equal(t, []uint32{1}, a.setRemote(map[uint32]bool{1: true, 3: true}))
+
+	// The SSRCs of a removed track can be used again
+	a.removeTrack(simulcast)
+	reuse, err := NewTrack(DefaultPayloadTypeVP8, 4, "reuse", "pion", codec)
+	assert.NoError(t, err)
+	assert.NoError(t, a.addTrack(reuse))
+}
+
+func TestPeerConnection_SSRCCollision(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	offerTrack, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(offerTrack)
+	assert.NoError(t, err)
+
+	// A second track with the same SSRC isn't added
+	duplicate, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1234, "duplicate", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(duplicate)
+	assert.Equal(t, ErrSSRCCollision, err)
+
+	answerTrack, err := pcAnswer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTrack(answerTrack)
+	assert.NoError(t, err)
+
+	collisions := make(chan uint32, 1)
+	pcAnswer.OnSSRCCollision(func(ssrc uint32) { collisions <- ssrc })
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	assert.Equal(t, uint32(1234), <-collisions)
+
+	// New tracks avoid the SSRCs of the remote
+	track, err := pcAnswer.NewTrack(DefaultPayloadTypeVP8, 0, "video", "pion")
+	assert.NoError(t, err)
+	assert.NotEqual(t, uint32(1234), track.SSRC())
+	assert.NotEqual(t, uint32(0), track.SSRC())
+
+	// Stopping the transceiver releases the SSRC of its track
+	for _, transceiver := range pcOffer.GetTransceivers() {
+		assert.NoError(t, transceiver.Stop())
+	}
+	_, err = pcOffer.AddTrack(duplicate)
+	assert.NoError(t, err)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,119 @@
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Semantics of SSRC groups
+const (
+	// SSRCGroupSemanticsFID groups a stream with its retransmission (RTX)
+	// stream, RFC 4588 Section 8.1
+	SSRCGroupSemanticsFID = "FID"
+
+	// SSRCGroupSemanticsSIM groups the layers of a simulcast stream, from the
+	// lowest to the highest resolution
+	SSRCGroupSemanticsSIM = "SIM"
+)
+
+// SSRCGroup is a group of SSRCs announced with a=ssrc-group, RFC 5576
+// Section 4.2
+type SSRCGroup struct {
+	Semantics string   `json:"semantics"`
+	SSRCs     []uint32 `json:"ssrcs"`
+}
+
+// String returns the value of the a=ssrc-group attribute
+func (g SSRCGroup) String() string {
+	out := g.Semantics
+	for _, ssrc := range g.SSRCs {
+		out += " " + strconv.FormatUint(uint64(ssrc), 10)
+	}
+	return out
+}
+
+// secondarySSRCs returns the SSRCs of the group that don't carry a track of
+// their own: the RTX stream of FID and the upper layers of SIM
+func (g SSRCGroup) secondarySSRCs() []uint32 {
+	switch g.Semantics {
+	case SSRCGroupSemanticsFID, SSRCGroupSemanticsSIM:
+		return g.SSRCs[1:]
+	default:
+		return nil
+	}
+}
+
+func (g SSRCGroup) contains(ssrc uint32) bool {
+	for _, s := range g.SSRCs {
+		if s == ssrc {
+			return true
+		}
+	}
+	return false
+}
+
+func (g SSRCGroup) validate() error {
+	if g.Semantics == "" || len(g.SSRCs) < 2 {
+		return ErrInvalidSSRCGroup
+	}
+	for _, ssrc := range g.SSRCs {
+		if ssrc == 0 {
+			return ErrInvalidSSRCGroup
+		}
+	}
+	return nil
+}
+
+// parseSSRCGroup parses the value of an a=ssrc-group attribute
+func parseSSRCGroup(value string) (SSRCGroup, error) {
+	split := strings.Fields(value)
+	if len(split) < 3 {
+		return SSRCGroup{}, ErrInvalidSSRCGroup
+	}
+
+	group := SSRCGroup{Semantics: split[0]}
+	for _, s := range split[1:] {
+		ssrc, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return SSRCGroup{}, ErrInvalidSSRCGroup
+		}
+		group.SSRCs = append(group.SSRCs, uint32(ssrc))
+	}
+	return group, group.validate()
+}
+
+// groupsContaining returns the groups that contain ssrc
+func groupsContaining(groups []SSRCGroup, ssrc uint32) []SSRCGroup {
+	var out []SSRCGroup
+	for _, g := range groups {
+		if g.contains(ssrc) {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// rtxSSRC returns the SSRC of the RTX stream of ssrc, or zero if it has none
+func rtxSSRC(groups []SSRCGroup, ssrc uint32) uint32 {
+	for _, g := range groups {
+		if g.Semantics == SSRCGroupSemanticsFID && g.SSRCs[0] == ssrc {
+			return g.SSRCs[1]
+		}
+	}
+	return 0
+}
+
+// ssrcsOfGroups returns the SSRCs of all groups except primary, each once
+func ssrcsOfGroups(groups []SSRCGroup, primary uint32) []uint32 {
+	seen := map[uint32]bool{primary: true}
+	var out []uint32
+	for _, g := range groups {
+		for _, ssrc := range g.SSRCs {
+			if !seen[ssrc] {
+				seen[ssrc] = true
+				out = append(out, ssrc)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSSRCGroup(t *testing.T) {
+	group, err := parseSSRCGroup("FID 1234 5678")
+	assert.NoError(t, err)
+	assert.Equal(t, SSRCGroup{Semantics: SSRCGroupSemanticsFID, SSRCs: []uint32{1234, 5678}}, group)
+	assert.Equal(t, "FID 1234 5678", group.String())
+
+	for _, value := range []string{"", "FID", "FID 1234", "SIM 1 two 3", "FID 0 1"} {
+		_, err = parseSSRCGroup(value)
+		assert.Equal(t, ErrInvalidSSRCGroup, err, value)
+	}
+}
+
+func TestSSRCGroupFID(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	const ssrc, rtx = 1000, 2000
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, ssrc, "video", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, ErrInvalidSSRCGroup, track.SetSSRCGroups([]SSRCGroup{{Semantics: SSRCGroupSemanticsFID, SSRCs: []uint32{ssrc}}}))
+
+	groups := []SSRCGroup{{Semantics: SSRCGroupSemanticsFID, SSRCs: []uint32{ssrc, rtx}}}
+	assert.NoError(t, track.SetSSRCGroups(groups))
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=ssrc-group:FID 1000 2000\r\n")
+	assert.Contains(t, offer.SDP, "a=ssrc:2000 cname:pion\r\n")
+
+	onTrack := make(chan *RTPReceiver, 2)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		onTrack <- receiver
+	})
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	receiver := <-onTrack
+	assert.Equal(t, uint32(ssrc), receiver.Track().SSRC())
+	assert.Equal(t, groups, receiver.Track().SSRCGroups())
+	assert.Equal(t, uint32(rtx), receiver.GetParameters().Encodings.RTX.SSRC)
+
+	// The RTX stream must not be announced as a track of its own
+	assert.Equal(t, 1, strings.Count(pcAnswer.RemoteDescription().SDP, "a=ssrc-group:"))
+	select {
+	case <-onTrack:
+		t.Fatal("RTX stream fired OnTrack")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	close(done)
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
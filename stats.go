@@ -717,6 +717,10 @@ type PeerConnectionStats struct {
 	// by inspecting the same underlying object.
 	ID string `json:"id"`
 
+	// Label is the label of the PeerConnection, see PeerConnection.SetLabel.
+	// It isn't part of the W3C stats.
+	Label string `json:"label,omitempty"`
+
 	// DataChannelsOpened represents the number of unique DataChannels that have
 	// entered the "open" state during their lifetime.
 	DataChannelsOpened uint32 `json:"dataChannelsOpened"`
@@ -1116,6 +1120,18 @@ type VideoReceiverStats struct {
 
 	// FullFramesLost is the cumulative number of full frames lost.
 	FullFramesLost uint32 `json:"fullFramesLost"`
+
+	// FreezeCount is the total number of video freezes experienced by this receiver.
+	// A freeze is counted when the time between two frames exceeds
+	// Max(3 * average frame duration, average frame duration + 150ms).
+	FreezeCount uint32 `json:"freezeCount"`
+
+	// TotalFreezesDuration is the total time, in seconds, this receiver spent frozen.
+	TotalFreezesDuration float64 `json:"totalFreezesDuration"`
+
+	// KeyFrameInterval is the time, in seconds, between the two most recently
+	// received key frames.
+	KeyFrameInterval float64 `json:"keyFrameInterval"`
 }
 
 // TransportStats contains transport statistics related to the PeerConnection object.
@@ -1177,6 +1193,30 @@ type TransportStats struct {
 	// transport, as defined in the "Profile" column of the IANA DTLS-SRTP protection
 	// profile registry.
 	SRTPCipher string `json:"srtpCipher"`
+
+	// PacketsDiscardedUnknownSSRC is the number of RTP packets received with
+	// an SSRC the remote description doesn't announce. A large number of
+	// them without media flowing points at noise from other hosts, or at a
+	// remote that doesn't announce its SSRCs.
+	PacketsDiscardedUnknownSSRC uint64 `json:"packetsDiscardedUnknownSsrc"`
+
+	// PacketsUnknownPayloadType is the number of RTP packets of announced
+	// SSRCs with a payload type that wasn't negotiated, which points at a
+	// misconfigured negotiation. They are passed to the track, but can't be
+	// depacketized.
+	PacketsUnknownPayloadType uint64 `json:"packetsUnknownPayloadType"`
+
+	// PacketsDiscardedSRTPAuth is the number of RTP packets that failed the
+	// SRTP authentication.
+	PacketsDiscardedSRTPAuth uint64 `json:"packetsDiscardedSrtpAuth"`
+
+	// PacketsDiscardedBufferFull is the number of RTP packets dropped
+	// because the application didn't read them fast enough.
+	PacketsDiscardedBufferFull uint64 `json:"packetsDiscardedBufferFull"`
+
+	// PacketsDiscardedQuota is the number of RTP packets dropped because
+	// they exceeded Quotas.MaxInboundBitrate.
+	PacketsDiscardedQuota uint64 `json:"packetsDiscardedQuota"`
 }
 
 // StatsICECandidatePairState is the state of an ICE candidate pair used in the
@@ -1198,7 +1238,7 @@ func toStatsICECandidatePairState(state ice.CandidatePairState) (StatsICECandida
 		err := fmt.Errorf(
 			"cannot convert to StatsICECandidatePairStateSucceeded invalid ice candidate state: %s",
 			state.String())
-		return StatsICECandidatePairState(Unknown), err
+		return StatsICECandidatePairState(""), err
 	}
 }
 
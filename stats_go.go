@@ -61,3 +61,32 @@ func (r StatsReport) GetICECandidatePairStats(c *ICECandidatePair) (ICECandidate
 	}
 	return candidateStats, true
 }
+
+// GetVideoReceiverStats is a helper method to return the associated stats for a given video RTPReceiver
+func (r StatsReport) GetVideoReceiverStats(receiver *RTPReceiver) (VideoReceiverStats, bool) {
+	stats, ok := r[receiver.statsID]
+	if !ok {
+		return VideoReceiverStats{}, false
+	}
+
+	receiverStats, ok := stats.(VideoReceiverStats)
+	if !ok {
+		return VideoReceiverStats{}, false
+	}
+	return receiverStats, true
+}
+
+// GetRemoteInboundRTPStreamStats is a helper method to return what the remote
+// reported about an SSRC the given RTPSender sends
+func (r StatsReport) GetRemoteInboundRTPStreamStats(sender *RTPSender, ssrc uint32) (RemoteInboundRTPStreamStats, bool) {
+	stats, ok := r[sender.remoteInboundStatsID(ssrc)]
+	if !ok {
+		return RemoteInboundRTPStreamStats{}, false
+	}
+
+	remoteInboundStats, ok := stats.(RemoteInboundRTPStreamStats)
+	if !ok {
+		return RemoteInboundRTPStreamStats{}, false
+	}
+	return remoteInboundStats, true
+}
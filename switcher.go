@@ -0,0 +1,304 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// Switcher feeds a local track from one of several remote tracks at a time,
+// e.g. the current speaker of a stage or the program output of a production.
+// The viewers receive a single continuous stream: the packets of the active
+// source are rewritten to the SSRC, payload type, sequence numbers and
+// timestamps of the output, so a switch looks like a key frame of the same
+// stream.
+//
+// Switching is gapless. The previous source is forwarded until the new one
+// sends a key frame, which is requested from its remote when Switch is
+// called. Audio and video of codecs other than VP8 and H264 switch at the
+// next packet of the new source.
+type Switcher struct {
+	output *Track
+
+	mu      sync.Mutex
+	sources map[*Track]*switcherSource
+	active  *switcherSource
+	// next is the source that becomes active with its next key frame
+	next   *switcherSource
+	closed bool
+
+	// The output stream so far. started is set by the first packet that is
+	// written, bindings is the number of senders of the output then.
+	started       bool
+	lastSeq       uint16
+	lastTimestamp uint32
+	lastWritten   time.Time
+	bindings      int
+}
+
+// switcherSource is a remote track a Switcher reads from
+type switcherSource struct {
+	remote   *Track
+	reader   *Track
+	receiver *RTPReceiver
+
+	// seqOffset and timestampOffset move the packets of the source to the
+	// numbering of the output, they are set when it becomes active
+	seqOffset       uint16
+	timestampOffset uint32
+}
+
+// NewSwitcher creates a Switcher that writes to output, a local track. The
+// sources have to have the codec of the output.
+func NewSwitcher(output *Track) (*Switcher, error) {
+	output.mu.RLock()
+	isRemote := output.receiver != nil
+	output.mu.RUnlock()
+	if isRemote {
+		return nil, fmt.Errorf("this is a remote track and must not be written to")
+	}
+
+	return &Switcher{
+		output:  output,
+		sources: map[*Track]*switcherSource{},
+	}, nil
+}
+
+// Output returns the local track the Switcher writes to
+func (s *Switcher) Output() *Track {
+	return s.output
+}
+
+// AddSource starts to read remote, a track received by a PeerConnection, so
+// the Switcher can switch to it without delay. Its packets are dropped while
+// another source is active. The Switcher reads a clone of remote, see
+// Track.Clone. The source is removed when the remote ends.
+func (s *Switcher) AddSource(remote *Track) error {
+	remote.mu.RLock()
+	receiver := remote.receiver
+	codec := remote.codec
+	remote.mu.RUnlock()
+	switch {
+	case receiver == nil:
+		return fmt.Errorf("this is a local track and can not be switched to")
+	case codec == nil || !strings.EqualFold(codec.Name, s.output.Codec().Name):
+		return fmt.Errorf("the codec of track %s doesn't match the output", remote.ID())
+	}
+
+	reader, err := remote.Clone()
+	if err != nil {
+		return err
+	}
+	// A deadline makes the reads of the clone interruptible, see stop
+	if err := reader.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+	source := &switcherSource{remote: remote, reader: reader, receiver: receiver}
+
+	s.mu.Lock()
+	switch {
+	case s.closed:
+		s.mu.Unlock()
+		return io.ErrClosedPipe
+	case s.sources[remote] != nil:
+		s.mu.Unlock()
+		return fmt.Errorf("track %s is a source already", remote.ID())
+	}
+	s.sources[remote] = source
+	s.mu.Unlock()
+
+	go s.read(source)
+	return nil
+}
+
+// RemoveSource stops reading remote. If it is the active source, nothing is
+// written until the next Switch.
+func (s *Switcher) RemoveSource(remote *Track) {
+	s.mu.Lock()
+	source := s.sources[remote]
+	s.mu.Unlock()
+	if source != nil {
+		s.remove(source)
+	}
+}
+
+// Switch makes remote, a source added with AddSource, the active source with
+// its next key frame. Until then the current source is forwarded.
+func (s *Switcher) Switch(remote *Track) error {
+	s.mu.Lock()
+	source := s.sources[remote]
+	switch {
+	case source == nil:
+		s.mu.Unlock()
+		return fmt.Errorf("track %s isn't a source", remote.ID())
+	case source == s.active:
+		s.next = nil
+		s.mu.Unlock()
+		return nil
+	}
+	s.next = source
+	s.mu.Unlock()
+
+	source.requestKeyFrame()
+	return nil
+}
+
+// Active returns the remote track that is forwarded, nil before the first
+// switch completed and after the active source was removed
+func (s *Switcher) Active() *Track {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == nil {
+		return nil
+	}
+	return s.active.remote
+}
+
+// Close stops reading the sources, the output isn't written anymore
+func (s *Switcher) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	sources := s.sources
+	s.sources = map[*Track]*switcherSource{}
+	s.active, s.next = nil, nil
+	s.mu.Unlock()
+
+	for _, source := range sources {
+		source.stop()
+	}
+	return nil
+}
+
+// read forwards the packets of a source until it ends or is removed
+func (s *Switcher) read(source *switcherSource) {
+	defer s.remove(source)
+
+	payloadType := source.reader.PayloadType()
+	for {
+		pkt, err := source.reader.ReadRTP()
+		if err != nil {
+			return
+		}
+		// Retransmissions and FEC of the source aren't forwarded
+		if pkt.PayloadType != payloadType {
+			continue
+		}
+
+		out, requestKeyFrame := s.rewrite(source, pkt, time.Now())
+		if requestKeyFrame {
+			source.requestKeyFrame()
+		}
+		if out == nil {
+			continue
+		}
+		if err := s.output.WriteRTP(out); err != nil && err != io.ErrClosedPipe {
+			return
+		}
+	}
+}
+
+// rewrite moves a packet of source to the stream of the output, it returns
+// nil if the packet isn't forwarded. requestKeyFrame tells that a key frame
+// of source is needed, to switch to it or for a new viewer of the output.
+func (s *Switcher) rewrite(source *switcherSource, pkt *rtp.Packet, now time.Time) (_ *rtp.Packet, requestKeyFrame bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if source == s.next {
+		if !s.switchPoint(pkt) {
+			// A lost key frame is requested again, the receiver
+			// consolidates the requests
+			return nil, true
+		}
+		s.activate(source, pkt, now)
+	}
+	if source != s.active {
+		return nil, false
+	}
+
+	out := &rtp.Packet{Header: pkt.Header, Payload: pkt.Payload}
+	out.SSRC = s.output.SSRC()
+	out.PayloadType = s.output.PayloadType()
+	out.SequenceNumber += source.seqOffset
+	out.Timestamp += source.timestampOffset
+	out.Extension = false
+	out.ExtensionProfile = 0
+	out.ExtensionPayload = nil
+
+	if !s.started || int16(out.SequenceNumber-s.lastSeq) > 0 {
+		s.lastSeq = out.SequenceNumber
+		s.lastTimestamp = out.Timestamp
+		s.lastWritten = now
+	}
+	s.started = true
+
+	bindings := len(s.output.Bindings())
+	requestKeyFrame = bindings > s.bindings && s.output.Kind() == RTPCodecTypeVideo
+	s.bindings = bindings
+	return out, requestKeyFrame
+}
+
+// switchPoint reports whether the output can switch to the source of pkt
+func (s *Switcher) switchPoint(pkt *rtp.Packet) bool {
+	codec := s.output.Codec()
+	if s.output.Kind() != RTPCodecTypeVideo || (codec.Name != VP8 && codec.Name != H264) {
+		return true
+	}
+	return isKeyFrameStart(codec.Name, pkt.Payload)
+}
+
+// activate makes source the active one, starting with pkt. Its packets
+// continue the sequence numbers of the output, the timestamps advance by the
+// time since the last packet written.
+func (s *Switcher) activate(source *switcherSource, pkt *rtp.Packet, now time.Time) {
+	s.active, s.next = source, nil
+	if !s.started {
+		return
+	}
+
+	elapsed := uint32(int64(now.Sub(s.lastWritten)) * int64(s.output.Codec().ClockRate) / int64(time.Second))
+	if elapsed == 0 {
+		elapsed = 1
+	}
+	source.seqOffset = s.lastSeq + 1 - pkt.SequenceNumber
+	source.timestampOffset = s.lastTimestamp + elapsed - pkt.Timestamp
+}
+
+// remove stops reading a source and drops it from the Switcher
+func (s *Switcher) remove(source *switcherSource) {
+	s.mu.Lock()
+	if s.sources[source.remote] == source {
+		delete(s.sources, source.remote)
+	}
+	if s.active == source {
+		s.active = nil
+	}
+	if s.next == source {
+		s.next = nil
+	}
+	s.mu.Unlock()
+
+	source.stop()
+}
+
+func (source *switcherSource) requestKeyFrame() {
+	if source.receiver == nil {
+		return
+	}
+	// The remote may not have sent anything yet, the first key frame is then
+	// on its way anyway
+	_ = source.receiver.RequestKeyFrame()
+}
+
+// stop ends the reads of the source
+func (source *switcherSource) stop() {
+	if source.reader != nil {
+		_ = source.reader.SetReadDeadline(time.Now())
+	}
+}
@@ -0,0 +1,89 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSwitcherPacket(sequenceNumber uint16, timestamp uint32, keyFrame bool) *rtp.Packet {
+	// VP8 payload descriptor with the S bit, the P bit of the payload header
+	// is 0 for key frames
+	payload := []byte{0x10, 0x01}
+	if keyFrame {
+		payload[1] = 0x00
+	}
+	return &rtp.Packet{
+		Header:  rtp.Header{Version: 2, SSRC: 1, PayloadType: 100, SequenceNumber: sequenceNumber, Timestamp: timestamp},
+		Payload: payload,
+	}
+}
+
+func TestSwitcher(t *testing.T) {
+	output, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	s, err := NewSwitcher(output)
+	assert.NoError(t, err)
+
+	// Local tracks can't be sources
+	assert.Error(t, s.AddSource(output))
+
+	a := &switcherSource{remote: &Track{id: "a"}}
+	b := &switcherSource{remote: &Track{id: "b"}}
+	s.sources[a.remote] = a
+	s.sources[b.remote] = b
+	assert.Error(t, s.Switch(&Track{id: "c"}))
+
+	now := time.Now()
+
+	// The first source starts with a key frame
+	assert.NoError(t, s.Switch(a.remote))
+	out, requestKeyFrame := s.rewrite(a, newSwitcherPacket(100, 3000, false), now)
+	assert.Nil(t, out)
+	assert.True(t, requestKeyFrame)
+	out, _ = s.rewrite(a, newSwitcherPacket(101, 6000, true), now)
+	assert.Equal(t, uint16(101), out.SequenceNumber)
+	assert.Equal(t, uint32(6000), out.Timestamp)
+	assert.Equal(t, uint32(1234), out.SSRC)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), out.PayloadType)
+	assert.Equal(t, a.remote, s.Active())
+
+	// Other sources are dropped
+	out, requestKeyFrame = s.rewrite(b, newSwitcherPacket(5000, 90000, true), now)
+	assert.Nil(t, out)
+	assert.False(t, requestKeyFrame)
+
+	// The previous source is forwarded until the next one sends a key frame
+	assert.NoError(t, s.Switch(b.remote))
+	out, _ = s.rewrite(b, newSwitcherPacket(5001, 93000, false), now)
+	assert.Nil(t, out)
+	out, _ = s.rewrite(a, newSwitcherPacket(102, 9000, false), now)
+	assert.Equal(t, uint16(102), out.SequenceNumber)
+	assert.Equal(t, a.remote, s.Active())
+
+	// The key frame continues the sequence numbers and timestamps of the
+	// output, advanced by the time since the last packet
+	out, _ = s.rewrite(b, newSwitcherPacket(5002, 96000, true), now.Add(time.Second/10))
+	assert.Equal(t, uint16(103), out.SequenceNumber)
+	assert.Equal(t, uint32(9000+9000), out.Timestamp)
+	assert.Equal(t, b.remote, s.Active())
+
+	out, _ = s.rewrite(b, newSwitcherPacket(5003, 99000, false), now)
+	assert.Equal(t, uint16(104), out.SequenceNumber)
+	assert.Equal(t, uint32(21000), out.Timestamp)
+
+	out, _ = s.rewrite(a, newSwitcherPacket(103, 12000, true), now)
+	assert.Nil(t, out)
+
+	// Removing the active source stops the output until the next switch
+	s.RemoveSource(b.remote)
+	assert.Nil(t, s.Active())
+	assert.Error(t, s.Switch(b.remote))
+
+	assert.NoError(t, s.Close())
+}
@@ -0,0 +1,48 @@
+package webrtc
+
+const (
+	// targetBitrateReduceFramerate is the fraction of the bitrate a video
+	// track is sent with below which a lower framerate is recommended
+	targetBitrateReduceFramerate = 0.75
+
+	// targetBitrateDropLayer is the fraction of the bitrate a video track is
+	// sent with below which dropping a layer is recommended
+	targetBitrateDropLayer = 0.5
+)
+
+// TargetBitrate tells the encoder of a track how to react to the bitrate
+// the remote estimates for it, see RTPSender.OnTargetBitrateChange
+type TargetBitrate struct {
+	// Bitrate in bits per second the track should be sent with
+	Bitrate uint64
+
+	// SendBitrate is the bitrate in bits per second the track was sent with
+	// when the target changed, averaged over the last second
+	SendBitrate float64
+
+	// DropLayer recommends to stop sending the highest simulcast or
+	// temporal layer of a video track, the target is less than half of the
+	// bitrate the track is sent with. Lowering the bitrate of the encoder
+	// wouldn't be enough without visible artifacts.
+	DropLayer bool
+
+	// ReduceFramerate recommends to encode a video track with a lower
+	// framerate, the target is less than three quarters of the bitrate the
+	// track is sent with. Fewer frames of the same quality are usually
+	// preferred over blurry ones.
+	ReduceFramerate bool
+}
+
+// newTargetBitrate gives the guidance for a new target of a track of kind
+// that is sent with sendBitrate. Only lowered targets of video come with
+// recommendations.
+func newTargetBitrate(kind RTPCodecType, target uint64, sendBitrate float64) TargetBitrate {
+	t := TargetBitrate{Bitrate: target, SendBitrate: sendBitrate}
+	if kind != RTPCodecTypeVideo {
+		return t
+	}
+
+	t.ReduceFramerate = float64(target) < sendBitrate*targetBitrateReduceFramerate
+	t.DropLayer = float64(target) < sendBitrate*targetBitrateDropLayer
+	return t
+}
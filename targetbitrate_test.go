@@ -0,0 +1,29 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTargetBitrate(t *testing.T) {
+	testCases := []struct {
+		kind     RTPCodecType
+		target   uint64
+		expected TargetBitrate
+	}{
+		{RTPCodecTypeVideo, 1200000, TargetBitrate{Bitrate: 1200000, SendBitrate: 1000000}},
+		{RTPCodecTypeVideo, 800000, TargetBitrate{Bitrate: 800000, SendBitrate: 1000000}},
+		{RTPCodecTypeVideo, 600000, TargetBitrate{Bitrate: 600000, SendBitrate: 1000000, ReduceFramerate: true}},
+		{RTPCodecTypeVideo, 400000, TargetBitrate{Bitrate: 400000, SendBitrate: 1000000, ReduceFramerate: true, DropLayer: true}},
+		{RTPCodecTypeAudio, 400000, TargetBitrate{Bitrate: 400000, SendBitrate: 1000000}},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expected,
+			newTargetBitrate(testCase.kind, testCase.target, 1000000),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -0,0 +1,126 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtcp"
+)
+
+// Feedback message types of the Temporary Maximum Media Stream Bit Rate
+// Request and Notification, RFC 5104 4.2
+const (
+	formatTMMBR uint8 = 3
+	formatTMMBN uint8 = 4
+)
+
+const (
+	temporaryMaxBitrateHeaderLength = 12
+	temporaryMaxBitrateEntryLength  = 8
+
+	// The bitrate of an entry is a 17 bit mantissa with a 6 bit exponent
+	temporaryMaxBitrateMantissaBits = 17
+	temporaryMaxBitrateMaxExponent  = 63
+)
+
+// temporaryMaxBitrateEntry is an FCI entry of a TMMBR or TMMBN: the limit
+// the owner of SSRC requests, or the limit the sender is bound by
+type temporaryMaxBitrateEntry struct {
+	SSRC     uint32
+	Bitrate  uint64
+	Overhead uint16
+}
+
+// temporaryMaxBitrate is a TMMBR, or a TMMBN if Notification is set. The
+// TMMBR asks the senders of the SSRCs of its entries to limit their bitrate,
+// the TMMBN tells which requests the sender follows. pion/rtcp doesn't
+// implement them yet.
+type temporaryMaxBitrate struct {
+	Notification bool
+	SenderSSRC   uint32
+	Entries      []temporaryMaxBitrateEntry
+}
+
+var _ rtcp.Packet = (*temporaryMaxBitrate)(nil) // assert is a Packet
+
+func (t temporaryMaxBitrate) format() uint8 {
+	if t.Notification {
+		return formatTMMBN
+	}
+	return formatTMMBR
+}
+
+// Marshal encodes the temporaryMaxBitrate in binary
+func (t temporaryMaxBitrate) Marshal() ([]byte, error) {
+	length := temporaryMaxBitrateHeaderLength + len(t.Entries)*temporaryMaxBitrateEntryLength
+	h := rtcp.Header{
+		Count:  t.format(),
+		Type:   rtcp.TypeTransportSpecificFeedback,
+		Length: uint16(length/4 - 1),
+	}
+	hData, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// The media SSRC of the common header is unused, the FCI entries name
+	// the streams
+	rawPacket := make([]byte, length)
+	copy(rawPacket, hData)
+	binary.BigEndian.PutUint32(rawPacket[4:], t.SenderSSRC)
+	for i, e := range t.Entries {
+		if e.Overhead > 0x1FF {
+			return nil, errors.New("temporary maximum bitrate overhead doesn't fit into 9 bits")
+		}
+		exponent, mantissa := uint32(0), e.Bitrate
+		for mantissa >= 1<<temporaryMaxBitrateMantissaBits {
+			if exponent == temporaryMaxBitrateMaxExponent {
+				return nil, errors.New("temporary maximum bitrate is too large")
+			}
+			exponent++
+			mantissa >>= 1
+		}
+
+		entry := rawPacket[temporaryMaxBitrateHeaderLength+i*temporaryMaxBitrateEntryLength:]
+		binary.BigEndian.PutUint32(entry, e.SSRC)
+		binary.BigEndian.PutUint32(entry[4:], exponent<<26|uint32(mantissa)<<9|uint32(e.Overhead))
+	}
+	return rawPacket, nil
+}
+
+// Unmarshal decodes a temporaryMaxBitrate from binary
+func (t *temporaryMaxBitrate) Unmarshal(rawPacket []byte) error {
+	var h rtcp.Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+	if h.Type != rtcp.TypeTransportSpecificFeedback || (h.Count != formatTMMBR && h.Count != formatTMMBN) {
+		return errors.New("packet is not a temporary maximum bitrate request or notification")
+	}
+	length := int(h.Length+1) * 4
+	if length < temporaryMaxBitrateHeaderLength || len(rawPacket) < length {
+		return errors.New("packet too short to be a temporary maximum bitrate request or notification")
+	}
+
+	t.Notification = h.Count == formatTMMBN
+	t.SenderSSRC = binary.BigEndian.Uint32(rawPacket[4:])
+	t.Entries = nil
+	for i := temporaryMaxBitrateHeaderLength; i+temporaryMaxBitrateEntryLength <= length; i += temporaryMaxBitrateEntryLength {
+		value := binary.BigEndian.Uint32(rawPacket[i+4:])
+		t.Entries = append(t.Entries, temporaryMaxBitrateEntry{
+			SSRC:     binary.BigEndian.Uint32(rawPacket[i:]),
+			Bitrate:  uint64(value>>9&(1<<temporaryMaxBitrateMantissaBits-1)) << (value >> 26),
+			Overhead: uint16(value & 0x1FF),
+		})
+	}
+	return nil
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (t *temporaryMaxBitrate) DestinationSSRC() []uint32 {
+	ssrcs := make([]uint32, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		ssrcs = append(ssrcs, e.SSRC)
+	}
+	return ssrcs
+}
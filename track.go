@@ -5,9 +5,13 @@ package webrtc
 import (
 	"fmt"
 	"io"
+	mathRand "math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pion/rtp"
+	"github.com/pion/transport/packetio"
 	"github.com/pion/webrtc/v2/pkg/media"
 )
 
@@ -33,6 +37,53 @@ type Track struct {
 	receiver         *RTPReceiver
 	activeSenders    []*RTPSender
 	totalSenderCount int // count of all senders (accounts for senders that have not been started yet)
+
+	onSenderErrorHandler      func(*RTPSender, error)
+	onSenderCongestionHandler func(*RTPSender, bool)
+	onBindHandler             func(TrackBinding)
+	onUnbindHandler           func(TrackBinding)
+
+	frameTransform FrameTransform
+
+	// ssrcGroups of a local track, remote tracks use the parameters of their receiver
+	ssrcGroups []SSRCGroup
+
+	// encodings of a local track that is sent with simulcast, the first
+	// one is sent with the SSRC of the track
+	encodings []*TrackEncoding
+
+	// counters of a local track, remote tracks use the counters of their receiver
+	counters trackCounters
+
+	// Opus samples waiting to be sent in a single packet, see writeOpusSample,
+	// with the header extensions they were written with. The timer sends them
+	// if no further sample completes the packet in time, the packet counter
+	// tells a timer that fires late that its samples were sent already.
+	// opusWriteMu keeps the packets of the timer and of WriteSample in order.
+	pendingOpusSamples    []media.Sample
+	pendingOpusExtensions map[string][]byte
+	pendingOpusTimer      *time.Timer
+	opusPackets           uint64
+	opusWriteMu           sync.Mutex
+
+	// redundancy is the RED distance of a local track, accessed atomically
+	// since it is read by the senders
+	redundancy int32
+
+	// mtu of a local track set with SetMTU, zero if it isn't, accessed
+	// atomically since it is read while packetizing
+	mtu int32
+
+	// red unwraps the RED packets of a remote track, the packets it
+	// recovered are returned by the next calls to ReadRTP
+	red          *redDecoder
+	redRecovered []*rtp.Packet
+
+	// readBuffer holds packets for this handle once a remote track has been cloned
+	readBuffer *packetio.Buffer
+
+	// readDeadline of a remote track, see SetReadDeadline
+	readDeadline deadlineReader
 }
 
 // ID gets the ID of the track
@@ -49,7 +100,8 @@ func (t *Track) PayloadType() uint8 {
 	return t.payloadType
 }
 
-// Kind gets the Kind of the track
+// Kind gets the Kind of the track. Local tracks have the kind of their
+// codec, given by its Type or otherwise its MimeType.
 func (t *Track) Kind() RTPCodecType {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -87,28 +139,155 @@ func (t *Track) Read(b []byte) (n int, err error) {
 	r := t.receiver
 	t.mu.RUnlock()
 
-	return r.readRTP(b)
+	n, _, err = t.readDeadline.read(b, receiveMTU, func(b []byte) (int, bool, error) {
+		n, err := r.readRTP(b, t)
+		return n, false, err
+	})
+	return n, err
+}
+
+// SetReadDeadline sets the deadline of the reads of a remote track. Once it
+// passed, Read and ReadRTP return ErrDeadlineExceeded instead of waiting for
+// a packet, a waiting read included. A zero value disables the deadline.
+//
+// Reads that started before the first deadline was set can't be interrupted.
+// A read that timed out keeps waiting for the next packet in the background,
+// the packet is returned by the next read. Every handle of a track, see
+// Clone, has a deadline of its own.
+func (t *Track) SetReadDeadline(deadline time.Time) error {
+	t.mu.RLock()
+	isLocal := t.receiver == nil
+	t.mu.RUnlock()
+	if isLocal {
+		return fmt.Errorf("this is a local track and must not be read from")
+	}
+
+	t.readDeadline.setDeadline(deadline)
+	return nil
+}
+
+// Clone returns a new handle on a remote track. Every handle has its own read
+// position and buffer, so a single track can feed multiple consumers, e.g. a
+// recorder and a forwarder, without one of them starving the other. A handle
+// that doesn't keep up loses packets once its buffer is full. Local tracks
+// can't be cloned.
+func (t *Track) Clone() (*Track, error) {
+	t.mu.RLock()
+	if t.receiver == nil {
+		t.mu.RUnlock()
+		return nil, fmt.Errorf("this is a local track and can not be cloned")
+	}
+
+	clone := &Track{
+		id:          t.id,
+		payloadType: t.payloadType,
+		kind:        t.kind,
+		label:       t.label,
+		ssrc:        t.ssrc,
+		codec:       t.codec,
+		receiver:    t.receiver,
+	}
+	if t.red != nil {
+		clone.red = &redDecoder{payloadType: t.red.payloadType}
+	}
+	t.mu.RUnlock()
+
+	t.receiver.addHandle(t, clone)
+	return clone, nil
+}
+
+func (t *Track) getReadBuffer() *packetio.Buffer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.readBuffer
+}
+
+func (t *Track) setReadBuffer(b *packetio.Buffer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.readBuffer = b
 }
 
-// ReadRTP is a convenience method that wraps Read and unmarshals for you
+// ReadRTP is a convenience method that wraps Read and unmarshals for you.
+// If the remote sends the track with RED, the packets are unwrapped and
+// packets that were lost are recovered from the redundancy of the following
+// ones, see SetRedundancy.
 func (t *Track) ReadRTP() (*rtp.Packet, error) {
-	b := make([]byte, receiveMTU)
-	i, err := t.Read(b)
-	if err != nil {
-		return nil, err
+	for {
+		t.mu.Lock()
+		if len(t.redRecovered) != 0 {
+			r := t.redRecovered[0]
+			t.redRecovered = t.redRecovered[1:]
+			t.mu.Unlock()
+			return r, nil
+		}
+		red := t.red
+		t.mu.Unlock()
+
+		b := make([]byte, receiveMTU)
+		i, err := t.Read(b)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &rtp.Packet{}
+		if err := unmarshalRTP(r, b[:i]); err != nil {
+			return nil, err
+		}
+		if red == nil {
+			return r, nil
+		}
+
+		t.mu.Lock()
+		pkts := red.decode(r)
+		if len(pkts) == 0 {
+			// Malformed and late RED packets are dropped
+			t.mu.Unlock()
+			continue
+		}
+		t.redRecovered = append(t.redRecovered, pkts[1:]...)
+		t.mu.Unlock()
+		return pkts[0], nil
 	}
+}
 
-	r := &rtp.Packet{}
-	if err := r.Unmarshal(b[:i]); err != nil {
-		return nil, err
+// SetRedundancy sends the packets of a local track with RED, RFC 2198, to
+// the remotes that negotiated a RED codec for the codec of the track. Each
+// packet carries up to distance of the previous packets, which allows to
+// survive bursty loss at the cost of bandwidth, e.g. for voice. Zero
+// disables redundancy. The MediaEngine needs a RED codec for the codec of
+// the track, see NewRTPREDCodec.
+func (t *Track) SetRedundancy(distance int) error {
+	if distance < 0 || distance > redMaxDistance {
+		return ErrInvalidRedundancy
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.receiver != nil {
+		return fmt.Errorf("RED redundancy can only be set on local tracks")
+	}
+	atomic.StoreInt32(&t.redundancy, int32(distance))
+	return nil
+}
+
+func (t *Track) getRedundancy() int {
+	return int(atomic.LoadInt32(&t.redundancy))
+}
+
+// unmarshalRTP is rtp.Packet.Unmarshal for untrusted data. pion/rtp reads
+// the fixed header before checking that b is long enough to hold it.
+func unmarshalRTP(p *rtp.Packet, b []byte) error {
+	if len(b) < rtpHeaderSize {
+		return fmt.Errorf("RTP packet of %d bytes is shorter than the header", len(b))
 	}
-	return r, nil
+	return p.Unmarshal(b)
 }
 
 // Write writes data to the track. If this is a remote track this will error
 func (t *Track) Write(b []byte) (n int, err error) {
 	packet := &rtp.Packet{}
-	err = packet.Unmarshal(b)
+	err = unmarshalRTP(packet, b)
 	if err != nil {
 		return 0, err
 	}
@@ -121,11 +300,60 @@ func (t *Track) Write(b []byte) (n int, err error) {
 	return len(b), nil
 }
 
-// WriteSample packetizes and writes to the track
+// WriteSample packetizes and writes to the track. If the codec of the track
+// is Opus and has a PTime, or the remote announced a ptime, samples are
+// collected until they add up to that packet length and sent together in a
+// single packet. The remote ptime takes precedence over PTime. A packet never
+// exceeds the remote maxptime nor the 120ms Opus allows, and collected samples
+// are sent once they have waited a packet length.
 func (t *Track) WriteSample(s media.Sample) error {
-	packets := t.packetizer.Packetize(s.Data, s.Samples)
-	for _, p := range packets {
-		err := t.WriteRTP(p)
+	return t.WriteSampleWithExtensions(s, nil)
+}
+
+// WriteSampleWithExtensions packetizes and writes to the track like
+// WriteSample, every packet of the sample carries the given header
+// extensions, see WriteRTPWithExtensions. Opus samples that are sent together
+// carry the extensions of all of them.
+//
+// The packets of video samples are marked with the frame marking extension,
+// unless extensions has one, for the senders that negotiated it. Key frames
+// are detected for VP8 and H264, see FrameMarkingURI.
+func (t *Track) WriteSampleWithExtensions(s media.Sample, extensions map[string][]byte) error {
+	if t.packetizer == nil {
+		return ErrCodecPayloaderNotSet
+	}
+	if packetDuration := t.opusPacketDuration(); packetDuration != 0 {
+		return t.writeOpusSample(s, extensions, packetDuration)
+	}
+
+	return t.writeSample(t.packetizer, s, extensions)
+}
+
+func (t *Track) writeSample(packetizer rtp.Packetizer, s media.Sample, extensions map[string][]byte) error {
+	data, err := t.transformFrame(s.Data)
+	if err != nil {
+		return err
+	}
+
+	packets := packetizer.Packetize(data, s.Samples)
+	markFrames := t.marksFrames(extensions)
+	independent := false
+	for i, p := range packets {
+		packetExtensions := extensions
+		if markFrames {
+			if i == 0 {
+				independent = isKeyFrameStart(t.Codec().Name, p.Payload)
+			}
+			if packetExtensions, err = withFrameMarking(extensions, FrameMarking{
+				StartOfFrame: i == 0,
+				EndOfFrame:   i == len(packets)-1,
+				Independent:  independent,
+			}); err != nil {
+				return err
+			}
+		}
+
+		err := t.writeRTPBatch([]*rtp.Packet{p}, packetExtensions)
 		if err != nil {
 			return err
 		}
@@ -134,8 +362,218 @@ func (t *Track) WriteSample(s media.Sample) error {
 	return nil
 }
 
-// WriteRTP writes RTP packets to the track
+// marksFrames tells if the packets of samples get a frame marking extension:
+// for video, if a sender of the track negotiated it and the application
+// didn't pass its own
+func (t *Track) marksFrames(extensions map[string][]byte) bool {
+	if _, ok := extensions[FrameMarkingURI]; ok {
+		return false
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.kind != RTPCodecTypeVideo {
+		return false
+	}
+	for _, s := range t.activeSenders {
+		if s.headerExtensionIDs[FrameMarkingURI] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withFrameMarking returns a copy of extensions with the frame marking set
+func withFrameMarking(extensions map[string][]byte, marking FrameMarking) (map[string][]byte, error) {
+	payload, err := marking.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]byte, len(extensions)+1)
+	for uri, p := range extensions {
+		out[uri] = p
+	}
+	out[FrameMarkingURI] = payload
+	return out, nil
+}
+
+// opusPacketDuration returns how much media a single Opus packet should
+// contain, or 0 if samples are sent as they are written
+func (t *Track) opusPacketDuration() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.codec == nil || t.codec.Name != Opus {
+		return 0
+	}
+
+	// The remote ptime is the packet length the remote wants to receive
+	duration := t.codec.PTime
+	var remotePTime time.Duration
+	for _, s := range t.activeSenders {
+		if pTime := s.getRemotePTime(); pTime != 0 && (remotePTime == 0 || pTime < remotePTime) {
+			remotePTime = pTime
+		}
+	}
+	if remotePTime != 0 {
+		duration = remotePTime
+	}
+	if duration == 0 {
+		return 0
+	}
+
+	if duration > opusMaxPacketDuration {
+		duration = opusMaxPacketDuration
+	}
+	for _, s := range t.activeSenders {
+		if maxPTime := s.getRemoteMaxPTime(); maxPTime != 0 && maxPTime < duration {
+			duration = maxPTime
+		}
+	}
+	return duration
+}
+
+// writeOpusSample collects s until the pending samples add up to
+// packetDuration. The pending samples are sent before s if s would make the
+// packet longer than packetDuration, a single longer sample is sent alone.
+// Samples that are still pending after packetDuration are sent by a timer.
+func (t *Track) writeOpusSample(s media.Sample, extensions map[string][]byte, packetDuration time.Duration) error {
+	t.opusWriteMu.Lock()
+	defer t.opusWriteMu.Unlock()
+
+	t.mu.Lock()
+	clockRate := t.codec.ClockRate
+	duration := func(sampleCount uint32) time.Duration {
+		if clockRate == 0 {
+			return packetDuration
+		}
+		return time.Duration(sampleCount) * time.Second / time.Duration(clockRate)
+	}
+	var pendingCount uint32
+	for _, pending := range t.pendingOpusSamples {
+		pendingCount += pending.Samples
+	}
+
+	var packets [][]media.Sample
+	var packetExtensions []map[string][]byte
+	takePending := func() {
+		samples, extensions := t.takePendingOpusLocked()
+		packets = append(packets, samples)
+		packetExtensions = append(packetExtensions, extensions)
+	}
+
+	if len(t.pendingOpusSamples) != 0 && duration(pendingCount+s.Samples) > packetDuration {
+		takePending()
+		pendingCount = 0
+	}
+	t.pendingOpusSamples = append(t.pendingOpusSamples, s)
+	pendingCount += s.Samples
+	for uri, payload := range extensions {
+		if t.pendingOpusExtensions == nil {
+			t.pendingOpusExtensions = map[string][]byte{}
+		}
+		t.pendingOpusExtensions[uri] = payload
+	}
+	if duration(pendingCount) >= packetDuration {
+		takePending()
+	} else if t.pendingOpusTimer == nil {
+		packet := t.opusPackets
+		t.pendingOpusTimer = time.AfterFunc(packetDuration, func() {
+			t.flushOpusSamples(packet)
+		})
+	}
+	t.mu.Unlock()
+
+	var firstErr error
+	for i, samples := range packets {
+		if err := t.writeOpusPacket(samples, packetExtensions[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// takePendingOpusLocked returns the pending Opus samples and their
+// extensions and stops the timer that would send them. t.mu must be held.
+func (t *Track) takePendingOpusLocked() ([]media.Sample, map[string][]byte) {
+	samples, extensions := t.pendingOpusSamples, t.pendingOpusExtensions
+	t.pendingOpusSamples = nil
+	t.pendingOpusExtensions = nil
+	t.opusPackets++
+	if t.pendingOpusTimer != nil {
+		t.pendingOpusTimer.Stop()
+		t.pendingOpusTimer = nil
+	}
+	return samples, extensions
+}
+
+// flushOpusSamples sends the pending Opus samples of packet in a single
+// packet, no sample completed it in time. Errors of the senders are reported
+// to the OnSenderError handler.
+func (t *Track) flushOpusSamples(packet uint64) {
+	t.opusWriteMu.Lock()
+	defer t.opusWriteMu.Unlock()
+
+	t.mu.Lock()
+	if packet != t.opusPackets {
+		t.mu.Unlock()
+		return
+	}
+	samples, extensions := t.takePendingOpusLocked()
+	t.mu.Unlock()
+
+	_ = t.writeOpusPacket(samples, extensions)
+}
+
+// writeOpusPacket sends samples in a single packet, or one by one if their
+// frames can't be combined
+func (t *Track) writeOpusPacket(samples []media.Sample, extensions map[string][]byte) error {
+	var sampleCount uint32
+	frames := make([][]byte, 0, len(samples))
+	for _, pending := range samples {
+		sampleCount += pending.Samples
+		frames = append(frames, pending.Data)
+	}
+
+	if combined, ok := combineOpusFrames(frames); ok {
+		return t.writeSample(t.packetizer, media.Sample{Data: combined, Samples: sampleCount}, extensions)
+	}
+
+	// The frames can't be packed together, send them one by one
+	for _, pending := range samples {
+		if err := t.writeSample(t.packetizer, pending, extensions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRTP writes RTP packets to the track. The packet is written to every
+// RTPSender of the track, if any of them fails the first error is returned
+// unless an OnSenderError handler is set.
 func (t *Track) WriteRTP(p *rtp.Packet) error {
+	return t.WriteRTPBatch([]*rtp.Packet{p})
+}
+
+// WriteRTPBatch writes multiple RTP packets to the track, e.g. all packets
+// of a frame that an SFU forwards. Every RTPSender writes the whole batch in
+// one go, which saves the per packet locking and lookups of WriteRTP. Errors
+// are handled like in WriteRTP, a sender stops writing the batch at its
+// first error.
+func (t *Track) WriteRTPBatch(pkts []*rtp.Packet) error {
+	return t.writeRTPBatch(pkts, nil)
+}
+
+// WriteRTPWithExtensions writes an RTP packet to the track like WriteRTP.
+// Every RTPSender adds the given header extensions, keyed by their URI, with
+// the ID the remote negotiated. Extensions the remote of a sender didn't
+// negotiate aren't sent to it, see MediaEngine.RegisterHeaderExtension.
+func (t *Track) WriteRTPWithExtensions(p *rtp.Packet, extensions map[string][]byte) error {
+	return t.writeRTPBatch([]*rtp.Packet{p}, extensions)
+}
+
+func (t *Track) writeRTPBatch(pkts []*rtp.Packet, extensions map[string][]byte) error {
 	t.mu.RLock()
 	if t.receiver != nil {
 		t.mu.RUnlock()
@@ -143,46 +581,356 @@ func (t *Track) WriteRTP(p *rtp.Packet) error {
 	}
 	senders := t.activeSenders
 	totalSenderCount := t.totalSenderCount
+	onSenderErrorHandler := t.onSenderErrorHandler
 	t.mu.RUnlock()
 
 	if totalSenderCount == 0 {
 		return io.ErrClosedPipe
 	}
+	now := time.Now()
+	for _, p := range pkts {
+		t.counters.update(p.SequenceNumber, p.MarshalSize(), now)
+	}
 
+	var firstErr error
 	for _, s := range senders {
-		_, err := s.sendRTP(&p.Header, p.Payload)
-		if err != nil {
+		if _, err := s.sendRTP(pkts, extensions); err != nil {
+			if onSenderErrorHandler != nil {
+				onSenderErrorHandler(s, err)
+			} else if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// OnSenderError sets an event handler which is called when writing to one of
+// the RTPSenders of this track fails, e.g. because its PeerConnection has been
+// closed. A failing RTPSender doesn't stop the packet from being written to the
+// remaining RTPSenders. Once a handler is set, WriteRTP, Write and WriteSample
+// no longer return these per sender errors.
+func (t *Track) OnSenderError(f func(*RTPSender, error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onSenderErrorHandler = f
+}
+
+// Bindings returns the RTPSenders a local track is currently sent with, and
+// their PeerConnections. A sender is bound once it is started, which is when
+// its PeerConnection has completed the negotiation, until it is stopped.
+func (t *Track) Bindings() []TrackBinding {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	bindings := make([]TrackBinding, 0, len(t.activeSenders))
+	for _, s := range t.activeSenders {
+		bindings = append(bindings, s.binding())
+	}
+	return bindings
+}
+
+// OnSenderCongestion sets an event handler which is called when one of the
+// RTPSenders of this track becomes congested or recovers, see
+// RTPSender.Congested. Writes to the track keep reaching every sender, the
+// handler lets the application send less to a congested one, e.g. a lower
+// layer, without holding back the others. The handler is called by Read,
+// ReadRTCP and ReadEncodingRTCP of the sender.
+func (t *Track) OnSenderCongestion(f func(sender *RTPSender, congested bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onSenderCongestionHandler = f
+}
+
+// OnBind sets an event handler which is called when an RTPSender starts
+// sending this track
+func (t *Track) OnBind(f func(TrackBinding)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onBindHandler = f
+}
+
+// OnUnbind sets an event handler which is called when an RTPSender of this
+// track is stopped, e.g. because its transceiver was stopped or its
+// PeerConnection was closed. Bindings no longer includes the sender once the
+// handler is called.
+func (t *Track) OnUnbind(f func(TrackBinding)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onUnbindHandler = f
+}
+
+// SetSSRCGroups sets the SSRC groups announced for a local track, e.g. an
+// FID group with the SSRC of the track and the SSRC of its RTX stream, or a
+// SIM group with the SSRCs of its simulcast layers.
+func (t *Track) SetSSRCGroups(groups []SSRCGroup) error {
+	for _, group := range groups {
+		if err := group.validate(); err != nil {
 			return err
 		}
 	}
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.receiver != nil {
+		return fmt.Errorf("this is a remote track and its SSRC groups are set by the remote")
+	}
+	t.ssrcGroups = append([]SSRCGroup{}, groups...)
 	return nil
 }
 
+// SSRCGroups returns the SSRC groups of the track. For remote tracks these
+// are the groups announced by the remote that contain the SSRC of the track.
+// Local tracks with encodings have a SIM group of the SSRCs of the encodings
+// in addition to the groups set with SetSSRCGroups.
+func (t *Track) SSRCGroups() []SSRCGroup {
+	t.mu.RLock()
+	receiver := t.receiver
+	groups := t.ssrcGroups
+	encodings := t.encodings
+	t.mu.RUnlock()
+
+	if receiver != nil {
+		return receiver.GetParameters().SSRCGroups
+	}
+	if len(encodings) > 1 {
+		sim := SSRCGroup{Semantics: SSRCGroupSemanticsSIM}
+		for _, e := range encodings {
+			sim.SSRCs = append(sim.SSRCs, e.SSRC())
+		}
+		groups = append(append([]SSRCGroup{}, groups...), sim)
+	}
+	return groups
+}
+
+// SetEncodings configures a local track to be sent with simulcast, with one
+// stream for every encoding, ordered from the lowest to the highest quality.
+// Every encoding needs a unique RID. Encodings without an SSRC get a random
+// one, which the PeerConnection the track is added to replaces if it is in
+// use there. The first encoding is always sent with the SSRC of the track.
+// Encodings have to be set before the track is added to a PeerConnection.
+// The streams are announced with a=rid and a=simulcast, and with a SIM
+// group for remotes that don't support RIDs.
+func (t *Track) SetEncodings(encodings []RTPEncodingParameters) ([]*TrackEncoding, error) {
+	seen := map[string]bool{}
+	for _, e := range encodings {
+		if !validRID(e.RID) || seen[e.RID] {
+			return nil, ErrInvalidRID
+		}
+		seen[e.RID] = true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.receiver != nil {
+		return nil, fmt.Errorf("this is a remote track and its encodings are set by the remote")
+	}
+	if t.totalSenderCount != 0 {
+		return nil, fmt.Errorf("encodings must be set before the track is added to a PeerConnection")
+	}
+	if len(encodings) != 0 && encodings[0].SSRC != 0 && encodings[0].SSRC != t.ssrc {
+		return nil, fmt.Errorf("the first encoding must be sent with the SSRC of the track")
+	}
+
+	trackEncodings := make([]*TrackEncoding, 0, len(encodings))
+	for i, parameters := range encodings {
+		randomSSRC := i != 0 && parameters.SSRC == 0
+		switch {
+		case i == 0:
+			parameters.SSRC = t.ssrc
+		case randomSSRC:
+			parameters.SSRC = mathRand.Uint32()
+		}
+		parameters.PayloadType = t.payloadType
+
+		e := &TrackEncoding{track: t, parameters: parameters, randomSSRC: randomSSRC}
+		if t.codec.Payloader != nil {
+			e.packetizer = newTrackPacketizer(t, t.payloadType, parameters.SSRC, t.codec)
+		}
+		trackEncodings = append(trackEncodings, e)
+	}
+	t.encodings = trackEncodings
+	return append([]*TrackEncoding{}, trackEncodings...), nil
+}
+
+// reassignEncodingSSRCs replaces the SSRCs SetEncodings picked with the ones
+// returned by replace, as long as the track isn't sent yet. used has the
+// SSRCs of the track that replace must not return.
+func (t *Track) reassignEncodingSSRCs(replace func(ssrc uint32, used map[uint32]bool) uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.totalSenderCount != 0 {
+		return
+	}
+
+	used := map[uint32]bool{t.ssrc: true}
+	for _, e := range t.encodings {
+		if !e.randomSSRC {
+			used[e.parameters.SSRC] = true
+		}
+	}
+	for _, e := range t.encodings {
+		if !e.randomSSRC {
+			continue
+		}
+		ssrc := replace(e.parameters.SSRC, used)
+		used[ssrc] = true
+		if ssrc == e.parameters.SSRC {
+			continue
+		}
+		e.parameters.SSRC = ssrc
+		if e.packetizer != nil {
+			e.packetizer = newTrackPacketizer(t, t.payloadType, ssrc, t.codec)
+		}
+	}
+}
+
+// HeaderExtension returns the payload of the header extension with the given
+// URI of a packet read from a remote track. Only the extensions negotiated
+// for the track are found, see MediaEngine.RegisterHeaderExtension.
+func (t *Track) HeaderExtension(p *rtp.Packet, uri string) ([]byte, bool) {
+	t.mu.RLock()
+	receiver := t.receiver
+	t.mu.RUnlock()
+	if receiver == nil {
+		return nil, false
+	}
+	return receiver.headerExtension(p, uri)
+}
+
+// FrameMarking returns the frame marking header extension of a packet read
+// from a remote track, if it was negotiated and the packet carries one
+func (t *Track) FrameMarking(p *rtp.Packet) (FrameMarking, bool) {
+	var marking FrameMarking
+	payload, ok := t.HeaderExtension(p, FrameMarkingURI)
+	if !ok || marking.Unmarshal(payload) != nil {
+		return marking, false
+	}
+	return marking, true
+}
+
+// Encodings returns the simulcast encodings of a local track
+func (t *Track) Encodings() []*TrackEncoding {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]*TrackEncoding{}, t.encodings...)
+}
+
+// Encoding returns the simulcast encoding of a local track with the given RID
+func (t *Track) Encoding(rid string) (*TrackEncoding, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, e := range t.encodings {
+		if e.RID() == rid {
+			return e, nil
+		}
+	}
+	return nil, ErrUnknownRID
+}
+
+// validRID tells if rid is a valid rid-id of RFC 8851 that fits into a
+// one-byte header extension
+func validRID(rid string) bool {
+	if len(rid) == 0 || len(rid) > oneByteHeaderExtensionMaxLength {
+		return false
+	}
+	for _, c := range rid {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Counters returns a snapshot of the packet counters of the track. Unlike
+// PeerConnection.GetStats it only takes a lock, so it can be called for every
+// packet, e.g. to pick a simulcast layer in an SFU. Local tracks count the
+// packets written to their senders, remote tracks the packets read from the
+// network, shared by all clones.
+func (t *Track) Counters() TrackCounters {
+	t.mu.RLock()
+	receiver := t.receiver
+	t.mu.RUnlock()
+
+	if receiver != nil {
+		return receiver.counters.snapshot(time.Now())
+	}
+	return t.counters.snapshot(time.Now())
+}
+
+// ClockMapper returns the RTPClockMapper of a remote track, which is updated
+// by the SenderReports read with RTPReceiver.Read or ReadRTCP. It returns nil
+// for local tracks.
+func (t *Track) ClockMapper() *RTPClockMapper {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.receiver == nil {
+		return nil
+	}
+	return &t.receiver.clockMapper
+}
+
+// WallClock returns the wall clock time of the sender at which the media
+// with the given RTP timestamp was captured, see ClockMapper. It returns false
+// for local tracks and until the first SenderReport was read.
+func (t *Track) WallClock(rtpTimestamp uint32) (time.Time, bool) {
+	mapper := t.ClockMapper()
+	codec := t.Codec()
+	if mapper == nil || codec == nil {
+		return time.Time{}, false
+	}
+	return mapper.WallClock(rtpTimestamp, codec.ClockRate)
+}
+
+// SetFrameTransform sets a FrameTransform for the frames of this track. On a
+// local track it is applied to every sample passed to WriteSample, RTP written
+// with WriteRTP is sent unchanged. On a remote track it is applied to the
+// frames returned by the readers of NewReader and passed to a Transcoder,
+// ReadRTP returns the packets as received, so tracks can be forwarded without
+// transforming them. Pass nil to remove the transform.
+func (t *Track) SetFrameTransform(f FrameTransform) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.frameTransform = f
+}
+
+// transformFrame applies the FrameTransform of the track, if any
+func (t *Track) transformFrame(data []byte) ([]byte, error) {
+	t.mu.RLock()
+	transform := t.frameTransform
+	frame := EncodedFrame{Data: data, SSRC: t.ssrc, PayloadType: t.payloadType, Kind: t.kind}
+	t.mu.RUnlock()
+
+	if transform == nil {
+		return data, nil
+	}
+	return transform(frame)
+}
+
 // NewTrack initializes a new *Track
 func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec) (*Track, error) {
 	if ssrc == 0 {
 		return nil, fmt.Errorf("SSRC supplied to NewTrack() must be non-zero")
 	}
 
-	packetizer := rtp.NewPacketizer(
-		rtpOutboundMTU,
-		payloadType,
-		ssrc,
-		codec.Payloader,
-		rtp.NewRandomSequencer(),
-		codec.ClockRate,
-	)
-
-	return &Track{
+	t := &Track{
 		id:          id,
 		payloadType: payloadType,
-		kind:        codec.Type,
+		kind:        codec.kind(),
 		label:       label,
 		ssrc:        ssrc,
 		codec:       codec,
-		packetizer:  packetizer,
-	}, nil
+	}
+
+	// Tracks of passthrough codecs only forward RTP
+	if codec.Payloader != nil {
+		t.packetizer = newTrackPacketizer(t, payloadType, ssrc, codec)
+	}
+	return t, nil
 }
 
 // determinePayloadType blocks and reads a single packet to determine the PayloadType for this Track
@@ -195,7 +943,11 @@ func (t *Track) determinePayloadType() error {
 
 	t.mu.Lock()
 	t.payloadType = r.PayloadType
-	defer t.mu.Unlock()
+	receiver := t.receiver
+	t.mu.Unlock()
 
+	if receiver != nil {
+		receiver.setFirstPacket(r)
+	}
 	return nil
 }
@@ -3,8 +3,16 @@
 package webrtc
 
 import (
+	"errors"
+	"io"
 	"math/rand"
 	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewVideoTrack(t *testing.T) {
@@ -117,3 +125,353 @@ func TestNewTracksWrite(t *testing.T) {
 	}
 
 }
+
+func TestTrackOpusPTime(t *testing.T) {
+	codec := NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000)
+	codec.PTime = 40 * time.Millisecond
+
+	track, err := NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 20ms frames are held back until a packet worth of media is available
+	frame := media.Sample{Data: []byte{0xfc, 0x01}, Samples: 960}
+	assert.NoError(t, track.WriteSample(frame))
+	assert.Equal(t, 1, len(track.pendingOpusSamples))
+
+	// The track has no senders, so the combined packet is dropped
+	assert.Equal(t, io.ErrClosedPipe, track.WriteSample(frame))
+	assert.Equal(t, 0, len(track.pendingOpusSamples))
+
+	// The remote maxptime takes precedence over a larger PTime
+	sender := &RTPSender{}
+	sender.setRemoteMaxPTime(20 * time.Millisecond)
+	track.activeSenders = []*RTPSender{sender}
+	assert.Equal(t, 20*time.Millisecond, track.opusPacketDuration())
+
+	// The remote ptime takes precedence over PTime, also when it is larger
+	sender = &RTPSender{}
+	sender.setRemotePTime(60 * time.Millisecond)
+	track.activeSenders = []*RTPSender{sender}
+	assert.Equal(t, 60*time.Millisecond, track.opusPacketDuration())
+
+	// A packet doesn't exceed the packet duration, the pending frame is sent
+	// before the frame that doesn't fit anymore
+	track.activeSenders = nil
+	track.codec.PTime = 30 * time.Millisecond
+	assert.NoError(t, track.WriteSample(frame))
+	assert.Equal(t, io.ErrClosedPipe, track.WriteSample(frame))
+	assert.Equal(t, 1, pendingOpusSamples(track))
+
+	// A frame that waited a packet duration is sent without further frames
+	for i := 0; i < 100 && pendingOpusSamples(track) != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 0, pendingOpusSamples(track))
+
+	// Opus packets carry at most 120ms
+	track.codec.PTime = 200 * time.Millisecond
+	assert.Equal(t, opusMaxPacketDuration, track.opusPacketDuration())
+}
+
+func pendingOpusSamples(track *Track) int {
+	track.mu.RLock()
+	defer track.mu.RUnlock()
+	return len(track.pendingOpusSamples)
+}
+
+func TestTrackOnSenderError(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newStoppedSender := func() *RTPSender {
+		s := &RTPSender{stopCalled: make(chan interface{}), sendCalled: make(chan interface{})}
+		close(s.stopCalled)
+		return s
+	}
+	senders := []*RTPSender{newStoppedSender(), newStoppedSender()}
+	track.activeSenders = senders
+	track.totalSenderCount = len(senders)
+
+	// Without a handler the first error is returned
+	assert.Error(t, track.WriteRTP(&rtp.Packet{}))
+
+	// With a handler every sender is written to and reported separately
+	var failed []*RTPSender
+	track.OnSenderError(func(s *RTPSender, senderErr error) {
+		assert.Error(t, senderErr)
+		failed = append(failed, s)
+	})
+	assert.NoError(t, track.WriteRTP(&rtp.Packet{}))
+	assert.Equal(t, senders, failed)
+}
+
+func TestTrackFrameTransform(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errTransform := errors.New("transform failed")
+	var transformed []EncodedFrame
+	track.SetFrameTransform(func(frame EncodedFrame) ([]byte, error) {
+		transformed = append(transformed, frame)
+		return nil, errTransform
+	})
+
+	// The frame is transformed before it is packetized
+	assert.Equal(t, errTransform, track.WriteSample(media.Sample{Data: []byte{0x01}, Samples: 3000}))
+	assert.Equal(t, []EncodedFrame{{Data: []byte{0x01}, SSRC: 1234, PayloadType: DefaultPayloadTypeVP8, Kind: RTPCodecTypeVideo}}, transformed)
+
+	// Without a transform the frame reaches the senders
+	track.SetFrameTransform(nil)
+	assert.Equal(t, io.ErrClosedPipe, track.WriteSample(media.Sample{Data: []byte{0x01}, Samples: 3000}))
+}
+
+// newForwardingPair returns a local track that is connected to a remote
+// PeerConnection, the remote track is delivered on the returned channel
+func newForwardingPair(tb testing.TB) (*Track, chan *Track, func()) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(tb, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(tb, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(tb, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(tb, err)
+
+	remoteTracks := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		remoteTracks <- remote
+	})
+
+	assert.NoError(tb, signalPair(pcOffer, pcAnswer))
+
+	return track, remoteTracks, func() {
+		assert.NoError(tb, pcOffer.Close())
+		assert.NoError(tb, pcAnswer.Close())
+	}
+}
+
+func newForwardingBatch(ssrc uint32, size int) []*rtp.Packet {
+	batch := make([]*rtp.Packet, size)
+	for i := range batch {
+		batch[i] = &rtp.Packet{
+			Header:  rtp.Header{Version: 2, SSRC: ssrc, SequenceNumber: uint16(i)},
+			Payload: make([]byte, 1000),
+		}
+		batch[i].Payload[0] = byte(i)
+	}
+	return batch
+}
+
+func TestTrackWriteRTPBatch(t *testing.T) {
+	track, remoteTracks, closePair := newForwardingPair(t)
+	defer closePair()
+
+	batch := newForwardingBatch(track.SSRC(), 3)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteRTPBatch(batch)
+			}
+		}
+	}()
+	defer close(done)
+
+	remote := <-remoteTracks
+
+	// The track is announced by any packet of a batch, the next batches
+	// arrive in order and complete
+	for {
+		p, err := remote.ReadRTP()
+		assert.NoError(t, err)
+		if int(p.Payload[0]) == len(batch)-1 {
+			break
+		}
+	}
+	for round := 0; round < 2; round++ {
+		for i := range batch {
+			p, err := remote.ReadRTP()
+			assert.NoError(t, err)
+			assert.Equal(t, batch[i].Payload, p.Payload)
+		}
+	}
+}
+
+func TestTrackReadDeadline(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	track, remoteTracks, closePair := newForwardingPair(t)
+	defer closePair()
+
+	assert.Error(t, track.SetReadDeadline(time.Now()))
+
+	batch := newForwardingBatch(track.SSRC(), 3)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteRTPBatch(batch)
+			}
+		}
+	}()
+	remote := <-remoteTracks
+	close(done)
+
+	// Once the packets in flight are read, reads time out
+	for {
+		assert.NoError(t, remote.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+		if _, err := remote.ReadRTP(); err != nil {
+			assert.Equal(t, ErrDeadlineExceeded, err)
+			break
+		}
+	}
+
+	// The packet that arrives after the timeout is returned by the next read
+	assert.NoError(t, track.WriteRTP(batch[1]))
+	assert.NoError(t, remote.SetReadDeadline(time.Time{}))
+	p, err := remote.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, batch[1].Payload, p.Payload)
+}
+
+func benchmarkTrackForwarding(b *testing.B, batchSize int) {
+	track, _, closePair := newForwardingPair(b)
+	defer closePair()
+
+	batch := newForwardingBatch(track.SSRC(), batchSize)
+
+	// The first write blocks until the senders have been started
+	assert.NoError(b, track.WriteRTPBatch(batch))
+
+	b.SetBytes(int64(batchSize * len(batch[0].Payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batchSize == 1 {
+			if err := track.WriteRTP(batch[0]); err != nil {
+				b.Fatal(err)
+			}
+		} else if err := track.WriteRTPBatch(batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTrackWriteRTP measures forwarding packet by packet, compare with
+// BenchmarkTrackWriteRTPBatch which forwards a frame of packets at once.
+// Bytes per second are comparable between the two.
+func BenchmarkTrackWriteRTP(b *testing.B) {
+	benchmarkTrackForwarding(b, 1)
+}
+
+func BenchmarkTrackWriteRTPBatch(b *testing.B) {
+	benchmarkTrackForwarding(b, 10)
+}
+
+func TestTrackPassthroughCodec(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	const payloadType = 45
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPPassthroughCodec(RTPCodecTypeVideo, "AV1X", 90000, 0, "profile=0", payloadType))
+	api := NewAPI(WithMediaEngine(m))
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiver(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(payloadType, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrCodecPayloaderNotSet, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+
+	remoteTracks := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		remoteTracks <- remote
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	payload := []byte{0xde, 0xad, 0xbe, 0xef}
+	done := make(chan struct{})
+	go func() {
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = track.WriteRTP(&rtp.Packet{
+					Header:  rtp.Header{Version: 2, PayloadType: payloadType, SSRC: track.SSRC(), SequenceNumber: sequenceNumber},
+					Payload: payload,
+				})
+			}
+		}
+	}()
+	defer close(done)
+
+	remote := <-remoteTracks
+	assert.Equal(t, "AV1X", remote.Codec().Name)
+
+	p, err := remote.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, payload, p.Payload)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestTrackBindings(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+
+	bound, unbound := make(chan TrackBinding, 1), make(chan TrackBinding, 1)
+	track.OnBind(func(b TrackBinding) { bound <- b })
+	track.OnUnbind(func(b TrackBinding) { unbound <- b })
+
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.Empty(t, track.Bindings())
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	expected := TrackBinding{PeerConnection: pcOffer, Sender: sender}
+	assert.Equal(t, expected, <-bound)
+	assert.Equal(t, []TrackBinding{expected}, track.Bindings())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.Equal(t, expected, <-unbound)
+	assert.Empty(t, track.Bindings())
+
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,15 @@
+// +build !js
+
+package webrtc
+
+// TrackBinding is an RTPSender a local Track is sent with, and the
+// PeerConnection the sender belongs to. PeerConnection is nil for senders
+// created with API.NewRTPSender.
+type TrackBinding struct {
+	PeerConnection *PeerConnection
+	Sender         *RTPSender
+}
+
+func (r *RTPSender) binding() TrackBinding {
+	return TrackBinding{PeerConnection: r.peerConnection, Sender: r}
+}
@@ -0,0 +1,110 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// trackCountersBuckets is the number of buckets of the bitrate window
+	trackCountersBuckets = 10
+
+	// trackCountersBucketDuration is the duration of a bucket, the bitrate is
+	// averaged over trackCountersBuckets * trackCountersBucketDuration
+	trackCountersBucketDuration = 100 * time.Millisecond
+)
+
+// TrackCounters is a snapshot of the counters of a Track, see Track.Counters
+type TrackCounters struct {
+	// Packets is the number of RTP packets sent or received
+	Packets uint64
+	// Bytes is the size of these packets including the RTP header
+	Bytes uint64
+	// LastSequenceNumber is the sequence number of the most recent packet
+	LastSequenceNumber uint16
+	// LastPacket is the time the most recent packet was sent or received,
+	// zero if there wasn't any yet
+	LastPacket time.Time
+	// Bitrate in bits per second, averaged over the last second
+	Bitrate float64
+}
+
+// trackCounters counts the packets of a track. Bytes are summed up in buckets
+// of a sliding window, so the bitrate can be calculated without keeping
+// every packet.
+type trackCounters struct {
+	mu sync.Mutex
+
+	packets            uint64
+	bytes              uint64
+	lastSequenceNumber uint16
+	lastPacket         time.Time
+
+	buckets [trackCountersBuckets]uint64
+	// bucketStart is the start of the bucket the last packet was added to
+	bucketStart time.Time
+	bucket      int
+}
+
+// update accounts for a packet of size bytes that was sent or received at now
+func (c *trackCounters) update(sequenceNumber uint16, size int, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.packets++
+	c.bytes += uint64(size)
+	c.lastSequenceNumber = sequenceNumber
+	c.lastPacket = now
+
+	c.advance(now)
+	c.buckets[c.bucket] += uint64(size)
+}
+
+// advance moves the current bucket to the one containing now, buckets that
+// are skipped are cleared
+func (c *trackCounters) advance(now time.Time) {
+	if c.bucketStart.IsZero() {
+		c.bucketStart = now
+		return
+	}
+
+	elapsed := int(now.Sub(c.bucketStart) / trackCountersBucketDuration)
+	if elapsed <= 0 {
+		return
+	}
+	if elapsed > trackCountersBuckets {
+		elapsed = trackCountersBuckets
+	}
+	for i := 0; i < elapsed; i++ {
+		c.bucket = (c.bucket + 1) % trackCountersBuckets
+		c.buckets[c.bucket] = 0
+	}
+	c.bucketStart = c.bucketStart.Add(time.Duration(elapsed) * trackCountersBucketDuration)
+	if now.Sub(c.bucketStart) >= trackCountersBucketDuration {
+		// The window was skipped completely
+		c.bucketStart = now
+	}
+}
+
+func (c *trackCounters) snapshot(now time.Time) TrackCounters {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.advance(now)
+
+	var windowBytes uint64
+	for _, b := range c.buckets {
+		windowBytes += b
+	}
+	window := time.Duration(trackCountersBuckets) * trackCountersBucketDuration
+
+	return TrackCounters{
+		Packets:            c.packets,
+		Bytes:              c.bytes,
+		LastSequenceNumber: c.lastSequenceNumber,
+		LastPacket:         c.lastPacket,
+		Bitrate:            float64(windowBytes*8) / window.Seconds(),
+	}
+}
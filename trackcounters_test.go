@@ -0,0 +1,37 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackCounters(t *testing.T) {
+	c := &trackCounters{}
+	start := time.Now()
+
+	// 125 bytes every 10ms is 100kbit/s
+	for i := 0; i < 100; i++ {
+		c.update(uint16(i), 125, start.Add(time.Duration(i)*10*time.Millisecond))
+	}
+
+	now := start.Add(time.Second - time.Millisecond)
+	snapshot := c.snapshot(now)
+	assert.Equal(t, uint64(100), snapshot.Packets)
+	assert.Equal(t, uint64(12500), snapshot.Bytes)
+	assert.Equal(t, uint16(99), snapshot.LastSequenceNumber)
+	assert.Equal(t, start.Add(990*time.Millisecond), snapshot.LastPacket)
+	assert.Equal(t, float64(100000), snapshot.Bitrate)
+
+	// Half a second later half of the window has expired
+	snapshot = c.snapshot(now.Add(500 * time.Millisecond))
+	assert.Equal(t, float64(50000), snapshot.Bitrate)
+
+	// After a pause longer than the window only the totals remain
+	snapshot = c.snapshot(now.Add(time.Minute))
+	assert.Equal(t, float64(0), snapshot.Bitrate)
+	assert.Equal(t, uint64(100), snapshot.Packets)
+}
@@ -0,0 +1,53 @@
+// +build !js
+
+package webrtc
+
+import (
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// TrackEncoding is one of the simulcast encodings of a local Track, see
+// Track.SetEncodings. Every encoding is sent with an SSRC of its own, the
+// application writes the media it encoded for the encoding to it.
+type TrackEncoding struct {
+	track      *Track
+	parameters RTPEncodingParameters
+	packetizer rtp.Packetizer
+	// randomSSRC is set if SetEncodings picked the SSRC, the ssrcAllocator
+	// of a PeerConnection replaces it if it is in use there
+	randomSSRC bool
+}
+
+// RID returns the RID of the encoding
+func (e *TrackEncoding) RID() string {
+	return e.parameters.RID
+}
+
+// SSRC returns the SSRC the encoding is sent with
+func (e *TrackEncoding) SSRC() uint32 {
+	return e.parameters.SSRC
+}
+
+// Parameters returns the parameters of the encoding
+func (e *TrackEncoding) Parameters() RTPEncodingParameters {
+	return e.parameters
+}
+
+// WriteRTP writes an RTP packet of the encoding to every RTPSender of the
+// track. The SSRC of the packet is replaced with the one of the encoding,
+// errors are handled like in Track.WriteRTP.
+func (e *TrackEncoding) WriteRTP(p *rtp.Packet) error {
+	pkt := *p
+	pkt.SSRC = e.parameters.SSRC
+	return e.track.WriteRTPBatch([]*rtp.Packet{&pkt})
+}
+
+// WriteSample packetizes a sample of the encoding and writes it to every
+// RTPSender of the track
+func (e *TrackEncoding) WriteSample(s media.Sample) error {
+	if e.packetizer == nil {
+		return ErrCodecPayloaderNotSet
+	}
+	return e.track.writeSample(e.packetizer, s, nil)
+}
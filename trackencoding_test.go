@@ -0,0 +1,145 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrack_SetEncodings(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1000, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	for _, rids := range [][]string{{""}, {"a", "a"}, {"a b"}, {"01234567890123456"}} {
+		var encodings []RTPEncodingParameters
+		for _, rid := range rids {
+			encodings = append(encodings, RTPEncodingParameters{RID: rid})
+		}
+		_, err = track.SetEncodings(encodings)
+		assert.Equal(t, ErrInvalidRID, err, rids)
+	}
+
+	_, err = track.SetEncodings([]RTPEncodingParameters{{RID: "q", RTPCodingParameters: RTPCodingParameters{SSRC: 2000}}})
+	assert.Error(t, err)
+
+	encodings, err := track.SetEncodings([]RTPEncodingParameters{
+		{RID: "q", ScaleResolutionDownBy: 4},
+		{RID: "h", ScaleResolutionDownBy: 2},
+		{RID: "f", RTPCodingParameters: RTPCodingParameters{SSRC: 3000}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(encodings))
+	assert.Equal(t, uint32(1000), encodings[0].SSRC())
+	assert.NotZero(t, encodings[1].SSRC())
+	assert.Equal(t, uint32(3000), encodings[2].SSRC())
+	assert.Equal(t, float64(2), encodings[1].Parameters().ScaleResolutionDownBy)
+
+	assert.Equal(t, []SSRCGroup{{
+		Semantics: SSRCGroupSemanticsSIM,
+		SSRCs:     []uint32{1000, encodings[1].SSRC(), 3000},
+	}}, track.SSRCGroups())
+
+	e, err := track.Encoding("h")
+	assert.NoError(t, err)
+	assert.Equal(t, encodings[1], e)
+	_, err = track.Encoding("x")
+	assert.Equal(t, ErrUnknownRID, err)
+}
+
+func TestTrackEncodingsSimulcast(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1000, "video", "pion")
+	assert.NoError(t, err)
+	transceiver, err := pcOffer.AddTransceiverFromTrack(track, RtpTransceiverInit{
+		Direction: RTPTransceiverDirectionSendonly,
+		SendEncodings: []RTPEncodingParameters{
+			{RID: "q", ScaleResolutionDownBy: 4},
+			{RID: "h", ScaleResolutionDownBy: 2},
+			{RID: "f"},
+		},
+	})
+	assert.NoError(t, err)
+	encodings := track.Encodings()
+	assert.Equal(t, 3, len(encodings))
+
+	onTrack := make(chan *RTPReceiver, 1)
+	pcAnswer.OnTrack(func(remote *Track, receiver *RTPReceiver) {
+		onTrack <- receiver
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				for _, e := range encodings {
+					_ = e.WriteRTP(&rtp.Packet{Header: rtp.Header{Version: 2}, Payload: []byte{0x00}})
+				}
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	offer := pcAnswer.RemoteDescription().SDP
+	assert.Contains(t, offer, "a=extmap:10 "+sdesRTPStreamIDURI+"\r\n")
+	assert.Contains(t, offer, "a=rid:h send\r\n")
+	assert.Contains(t, offer, "a=simulcast:send q;h;f\r\n")
+	assert.Contains(t, offer, "a=ssrc-group:SIM 1000 ")
+	answer := pcOffer.RemoteDescription().SDP
+	assert.Contains(t, answer, "a=extmap:10 "+sdesRTPStreamIDURI+"\r\n")
+	assert.Contains(t, answer, "a=rid:f recv\r\n")
+	assert.Contains(t, answer, "a=simulcast:recv q;h;f\r\n")
+
+	// The lowest encoding is received as the track, tagged with its RID
+	receiver := <-onTrack
+	remote := receiver.Track()
+	assert.Equal(t, uint32(1000), remote.SSRC())
+
+	// The receiver has the negotiated parameters of the stream
+	parameters := receiver.GetParameters()
+	assert.Equal(t, "q", parameters.Encodings.RID)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), parameters.Encodings.PayloadType)
+	assert.Contains(t, parameters.HeaderExtensions, RTPHeaderExtensionParameter{URI: sdesRTPStreamIDURI, ID: 10})
+	var vp8 *RTPCodecParameters
+	for i, c := range parameters.Codecs {
+		if c.PayloadType == remote.PayloadType() {
+			vp8 = &parameters.Codecs[i]
+		}
+	}
+	if assert.NotNil(t, vp8) {
+		assert.Equal(t, "video/VP8", vp8.MimeType)
+		assert.Equal(t, uint32(90000), vp8.ClockRate)
+	}
+
+	pkt, err := remote.ReadRTP()
+	assert.NoError(t, err)
+	rid, ok := oneByteHeaderExtension(&pkt.Header, 10)
+	assert.True(t, ok)
+	assert.Equal(t, "q", string(rid))
+
+	_, err = transceiver.Sender.ReadEncodingRTCP("x")
+	assert.Equal(t, ErrUnknownRID, err)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,148 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v2/pkg/media/samplebuilder"
+)
+
+// ElementaryStreamFormat is the format of the media returned by Track.NewReader
+type ElementaryStreamFormat int
+
+const (
+	// ElementaryStreamH264AnnexB is H264 with every NAL unit prefixed by a
+	// start code, as expected by ffmpeg -f h264 and the GStreamer h264parse.
+	ElementaryStreamH264AnnexB ElementaryStreamFormat = iota + 1
+
+	// ElementaryStreamVP8 is raw VP8 frames. Frame boundaries are not
+	// preserved, use pkg/media/ivfwriter if the consumer needs a container.
+	ElementaryStreamVP8
+)
+
+// trackReaderMaxLate is the amount of packets a frame waits for missing packets
+const trackReaderMaxLate = 50
+
+// NewReader returns an io.Reader of the depacketized media of a remote track,
+// which can be piped straight into a transcoder. The format has to match the
+// codec of the track. The reader consumes the RTP of the track, it shouldn't
+// be read by other means at the same time, see Track.Clone.
+func (t *Track) NewReader(format ElementaryStreamFormat) (io.Reader, error) {
+	t.mu.RLock()
+	isRemote := t.receiver != nil
+	codecName := t.codec.Name
+	t.mu.RUnlock()
+
+	if !isRemote {
+		return nil, fmt.Errorf("this is a local track and can not be read")
+	}
+
+	var depacketizer rtp.Depacketizer
+	switch {
+	case format == ElementaryStreamH264AnnexB && strings.EqualFold(codecName, H264):
+		depacketizer = &h264Depacketizer{}
+	case format == ElementaryStreamVP8 && strings.EqualFold(codecName, VP8):
+		depacketizer = &codecs.VP8Packet{}
+	default:
+		return nil, fmt.Errorf("format %d can not be produced from %s", format, codecName)
+	}
+
+	return &trackReader{
+		readRTP:        t.ReadRTP,
+		transformFrame: t.transformFrame,
+		builder:        samplebuilder.New(trackReaderMaxLate, depacketizer),
+	}, nil
+}
+
+// trackReader reassembles frames from RTP and hands them out as a stream
+type trackReader struct {
+	readRTP        func() (*rtp.Packet, error)
+	transformFrame func([]byte) ([]byte, error)
+	builder        *samplebuilder.SampleBuilder
+	pending        []byte
+}
+
+func (r *trackReader) Read(b []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if sample := r.builder.Pop(); sample != nil {
+			data, err := r.transformFrame(sample.Data)
+			if err != nil {
+				return 0, err
+			}
+			r.pending = data
+			continue
+		}
+
+		p, err := r.readRTP()
+		if err != nil {
+			return 0, err
+		}
+		r.builder.Push(p)
+	}
+
+	n := copy(b, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// H264 NAL unit types that need to be handled when depacketizing, RFC 6184
+const (
+	h264NALUTypeSTAPA = 24
+	h264NALUTypeFUA   = 28
+
+	h264NALUTypeBitmask = 0x1F
+	h264NRIBitmask      = 0xE0
+	h264FUStartBitmask  = 0x80
+)
+
+var annexBStartCode = []byte{0x00, 0x00, 0x00, 0x01}
+
+// h264Depacketizer converts H264 RTP payloads to Annex-B. The payloads of a
+// frame are concatenated by the SampleBuilder, so fragmented NAL units only
+// need a start code in front of the first fragment.
+type h264Depacketizer struct{}
+
+func (d *h264Depacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	if len(payload) < 2 {
+		return nil, fmt.Errorf("H264 payload is too short: %d bytes", len(payload))
+	}
+
+	switch naluType := payload[0] & h264NALUTypeBitmask; {
+	case naluType > 0 && naluType < h264NALUTypeSTAPA:
+		return append(append([]byte{}, annexBStartCode...), payload...), nil
+
+	case naluType == h264NALUTypeSTAPA:
+		var out []byte
+		for offset := 1; offset < len(payload); {
+			if offset+2 > len(payload) {
+				return nil, fmt.Errorf("STAP-A is truncated")
+			}
+			size := int(binary.BigEndian.Uint16(payload[offset:]))
+			offset += 2
+			if offset+size > len(payload) {
+				return nil, fmt.Errorf("STAP-A NAL unit of %d bytes is truncated", size)
+			}
+			out = append(out, annexBStartCode...)
+			out = append(out, payload[offset:offset+size]...)
+			offset += size
+		}
+		return out, nil
+
+	case naluType == h264NALUTypeFUA:
+		if payload[1]&h264FUStartBitmask == 0 {
+			return payload[2:], nil
+		}
+		out := append([]byte{}, annexBStartCode...)
+		out = append(out, (payload[0]&h264NRIBitmask)|(payload[1]&h264NALUTypeBitmask))
+		return append(out, payload[2:]...), nil
+
+	default:
+		return nil, fmt.Errorf("H264 NAL unit type %d is not supported", naluType)
+	}
+}
@@ -0,0 +1,88 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media/samplebuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestH264Depacketizer(t *testing.T) {
+	d := &h264Depacketizer{}
+
+	// Single NAL unit
+	out, err := d.Unmarshal([]byte{0x65, 0x01, 0x02})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0x01, 0x02}, out)
+
+	// STAP-A with SPS and PPS
+	out, err = d.Unmarshal([]byte{0x78, 0x00, 0x02, 0x67, 0x01, 0x00, 0x01, 0x68})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x01, 0x00, 0x00, 0x00, 0x01, 0x68}, out)
+
+	_, err = d.Unmarshal([]byte{0x78, 0x00, 0x05, 0x67})
+	assert.Error(t, err)
+
+	// FU-A, only the first fragment gets a start code and the NAL header
+	out, err = d.Unmarshal([]byte{0x7c, 0x85, 0xaa})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0xaa}, out)
+
+	out, err = d.Unmarshal([]byte{0x7c, 0x45, 0xbb})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xbb}, out)
+
+	_, err = d.Unmarshal([]byte{0x7f, 0x00})
+	assert.Error(t, err)
+}
+
+func TestTrackReader(t *testing.T) {
+	packets := []*rtp.Packet{
+		{Header: rtp.Header{SequenceNumber: 0, Timestamp: 0}, Payload: []byte{0x41, 0x00}},
+		{Header: rtp.Header{SequenceNumber: 1, Timestamp: 10}, Payload: []byte{0x7c, 0x85, 0xaa}},
+		{Header: rtp.Header{SequenceNumber: 2, Timestamp: 10}, Payload: []byte{0x7c, 0x45, 0xbb}},
+		{Header: rtp.Header{SequenceNumber: 3, Timestamp: 20}, Payload: []byte{0x41, 0xcc}},
+		{Header: rtp.Header{SequenceNumber: 4, Timestamp: 30}, Payload: []byte{0x41, 0xdd}},
+	}
+
+	r := &trackReader{
+		readRTP: func() (*rtp.Packet, error) {
+			if len(packets) == 0 {
+				return nil, io.EOF
+			}
+			p := packets[0]
+			packets = packets[1:]
+			return p, nil
+		},
+		transformFrame: func(frame []byte) ([]byte, error) {
+			return frame, nil
+		},
+		builder: samplebuilder.New(trackReaderMaxLate, &h264Depacketizer{}),
+	}
+
+	// The first frame may be incomplete and is dropped, the last frame is
+	// held back until the frame after it starts
+	stream, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, 0x01, 0x65, 0xaa, 0xbb,
+		0x00, 0x00, 0x00, 0x01, 0x41, 0xcc,
+	}, stream)
+
+	// Only remote tracks with a matching codec can be read
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	_, err = track.NewReader(ElementaryStreamVP8)
+	assert.Error(t, err)
+
+	track.receiver = &RTPReceiver{}
+	_, err = track.NewReader(ElementaryStreamH264AnnexB)
+	assert.Error(t, err)
+	_, err = track.NewReader(ElementaryStreamVP8)
+	assert.NoError(t, err)
+}
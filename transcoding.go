@@ -0,0 +1,199 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/samplebuilder"
+)
+
+// Transcoder re-encodes the frames of a track with another codec, e.g. VP8
+// to H264 for viewers that only decode H264. The application supplies the
+// codec, a Transcoding takes care of the RTP on both sides.
+type Transcoder interface {
+	// Transcode decodes a frame of the input track and returns the frames
+	// re-encoded with the codec of the output track. The Samples of the
+	// input are in the clock rate of its codec, the ones returned in the
+	// clock rate of the output codec. Encoders that buffer frames may return
+	// none or several at a time.
+	Transcode(frame media.Sample) ([]media.Sample, error)
+
+	// RequestKeyFrame makes the encoder produce a key frame next. It is
+	// called when the output track starts to be sent with another
+	// RTPSender, a new viewer can't decode anything before a key frame.
+	RequestKeyFrame()
+
+	// Close releases the codec, it is called once the transcoding ended
+	Close() error
+}
+
+// Transcoding feeds the frames of a remote track through a Transcoder and
+// writes the re-encoded frames to a local track
+type Transcoding struct {
+	input      *Track
+	output     *Track
+	transcoder Transcoder
+	builder    *samplebuilder.SampleBuilder
+
+	mu      sync.Mutex
+	stopped bool
+	err     error
+	done    chan struct{}
+}
+
+// NewTranscoding starts to transcode input, a remote track, into output, a
+// local track with the codec the transcoder encodes. The input has to be VP8,
+// H264 or Opus, its frames are passed to the transcoder after the
+// FrameTransform of the input, H264 in Annex-B format. A key frame is
+// requested from the remote for the decoder to start with.
+//
+// The transcoding consumes the RTP of the input, it shouldn't be read by
+// other means at the same time, see Track.Clone. It runs until the input
+// ends or Stop is called, then the transcoder is closed.
+func NewTranscoding(input *Track, transcoder Transcoder, output *Track) (*Transcoding, error) {
+	input.mu.RLock()
+	receiver := input.receiver
+	codecName := input.codec.Name
+	input.mu.RUnlock()
+	if receiver == nil {
+		return nil, fmt.Errorf("this is a local track and can not be read")
+	}
+
+	output.mu.RLock()
+	isRemote := output.receiver != nil
+	output.mu.RUnlock()
+	if isRemote {
+		return nil, fmt.Errorf("this is a remote track and must not be written to")
+	}
+
+	var depacketizer rtp.Depacketizer
+	switch {
+	case strings.EqualFold(codecName, VP8):
+		depacketizer = &codecs.VP8Packet{}
+	case strings.EqualFold(codecName, H264):
+		depacketizer = &h264Depacketizer{}
+	case strings.EqualFold(codecName, Opus):
+		depacketizer = &codecs.OpusPacket{}
+	default:
+		return nil, fmt.Errorf("%s can not be transcoded", codecName)
+	}
+
+	t := &Transcoding{
+		input:      input,
+		output:     output,
+		transcoder: transcoder,
+		builder:    samplebuilder.New(trackReaderMaxLate, depacketizer),
+		done:       make(chan struct{}),
+	}
+
+	// The remote may not have sent anything yet, the first key frame is
+	// then on its way anyway
+	_ = receiver.RequestKeyFrame()
+
+	go t.run()
+	return t, nil
+}
+
+// Input returns the remote track that is transcoded
+func (t *Transcoding) Input() *Track {
+	return t.input
+}
+
+// Output returns the local track the transcoded frames are written to
+func (t *Transcoding) Output() *Track {
+	return t.output
+}
+
+// Stop ends the transcoding. It is stopped with the next packet of the
+// input, Done is closed once the transcoder is closed.
+func (t *Transcoding) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+}
+
+// Done is closed when the transcoding ended
+func (t *Transcoding) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err returns the error the transcoding ended with, nil while it runs and
+// if the input ended or it was stopped
+func (t *Transcoding) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *Transcoding) run() {
+	err := t.transcode()
+	if closeErr := t.transcoder.Close(); err == nil {
+		err = closeErr
+	}
+
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// transcode reads the input until it ends or the transcoding is stopped
+func (t *Transcoding) transcode() error {
+	bindings := 0
+	for {
+		p, err := t.input.ReadRTP()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		t.mu.Lock()
+		stopped := t.stopped
+		t.mu.Unlock()
+		if stopped {
+			return nil
+		}
+
+		t.builder.Push(p)
+		for frame := t.builder.Pop(); frame != nil; frame = t.builder.Pop() {
+			n := len(t.output.Bindings())
+			if n > bindings {
+				t.transcoder.RequestKeyFrame()
+			}
+			bindings = n
+
+			if err := t.write(*frame); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// write transcodes a frame of the input and writes the result to the output
+func (t *Transcoding) write(frame media.Sample) error {
+	data, err := t.input.transformFrame(frame.Data)
+	if err != nil {
+		return err
+	}
+	frame.Data = data
+
+	samples, err := t.transcoder.Transcode(frame)
+	if err != nil {
+		return err
+	}
+	for _, s := range samples {
+		// Frames written while the output isn't sent are dropped
+		if err := t.output.WriteSample(s); err != nil && err != io.ErrClosedPipe {
+			return err
+		}
+	}
+	return nil
+}
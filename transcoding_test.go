@@ -0,0 +1,120 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTranscoder wraps the VP8 frames it gets into H264 NAL units
+type fakeTranscoder struct {
+	mu               sync.Mutex
+	frames           [][]byte
+	keyFrameRequests int
+	closed           chan struct{}
+}
+
+func (f *fakeTranscoder) Transcode(frame media.Sample) ([]media.Sample, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.frames = append(f.frames, frame.Data)
+	data := append([]byte{0x00, 0x00, 0x00, 0x01, 0x65}, frame.Data...)
+	return []media.Sample{{Data: data, Samples: frame.Samples}}, nil
+}
+
+func (f *fakeTranscoder) RequestKeyFrame() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keyFrameRequests++
+}
+
+func (f *fakeTranscoder) Close() error {
+	close(f.closed)
+	return nil
+}
+
+func TestTranscoding(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	assert.NoError(t, err)
+
+	// The offerer sends VP8 and gets it back as H264
+	input, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(input)
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	output, err := pcAnswer.NewTrack(DefaultPayloadTypeH264, 5678, "transcoded", "pion")
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTrack(output)
+	assert.NoError(t, err)
+
+	_, err = NewTranscoding(output, &fakeTranscoder{}, output)
+	assert.Error(t, err)
+
+	transcoder := &fakeTranscoder{closed: make(chan struct{})}
+	transcodings := make(chan *Transcoding, 1)
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		transcoding, transcodingErr := NewTranscoding(track, transcoder, output)
+		assert.NoError(t, transcodingErr)
+		transcodings <- transcoding
+	})
+	received := make(chan []byte, 10)
+	pcOffer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		for {
+			p, readErr := track.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			select {
+			case received <- p.Payload:
+			default:
+			}
+		}
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				_ = input.WriteSample(media.Sample{Data: []byte{0xaa, 0xbb, 0xcc}, Samples: 90})
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	transcoding := <-transcodings
+	assert.Equal(t, output, transcoding.Output())
+
+	assert.Equal(t, []byte{0x65, 0xaa, 0xbb, 0xcc}, <-received)
+
+	transcoder.mu.Lock()
+	assert.Equal(t, []byte{0xaa, 0xbb, 0xcc}, transcoder.frames[0])
+	assert.Equal(t, 1, transcoder.keyFrameRequests)
+	transcoder.mu.Unlock()
+
+	// The transcoder is closed once stopped
+	transcoding.Stop()
+	<-transcoding.Done()
+	<-transcoder.closed
+	assert.NoError(t, transcoding.Err())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,233 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// videoFrameStatsWindow is the number of frame intervals used for the
+	// average frame duration when detecting freezes
+	videoFrameStatsWindow = 30
+
+	// videoFreezeMinExtraDuration is the minimum amount a frame interval has to
+	// exceed the average by before it counts as a freeze
+	videoFreezeMinExtraDuration = 150 * time.Millisecond
+)
+
+// videoFrameStats derives frame level metrics from inbound video RTP without
+// decoding it. Frame boundaries are detected by a change of the RTP timestamp.
+type videoFrameStats struct {
+	mu sync.Mutex
+
+	haveTimestamp bool
+	lastTimestamp uint32
+	lastFrame     time.Time
+	lastKeyFrame  time.Time
+	// keyFrame is set once the frame of lastTimestamp was counted as a key
+	// frame, e.g. by the SPS before the IDR slices of H264
+	keyFrame bool
+
+	// Arrival times of frames in the last second, used for FramesPerSecond
+	recentFrames []time.Time
+	// Durations between the most recent frames, used for freeze detection
+	intervals []time.Duration
+
+	framesReceived       uint32
+	keyFramesReceived    uint32
+	keyFrameInterval     time.Duration
+	freezeCount          uint32
+	totalFreezesDuration time.Duration
+}
+
+// update accounts for a single RTP packet that arrived at now. It returns
+// true for the first packet of a key frame, a frame is counted once however
+// many of its packets start a key frame.
+func (s *videoFrameStats) update(header *rtp.Header, payload []byte, codecName string, now time.Time) (keyFrame bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newFrame := !s.haveTimestamp || header.Timestamp != s.lastTimestamp
+	s.haveTimestamp = true
+	s.lastTimestamp = header.Timestamp
+
+	if newFrame {
+		s.keyFrame = false
+		s.framesReceived++
+		if !s.lastFrame.IsZero() {
+			s.addInterval(now.Sub(s.lastFrame))
+		}
+		s.lastFrame = now
+
+		s.recentFrames = append(s.recentFrames, now)
+		s.pruneRecentFrames(now)
+	}
+
+	if s.keyFrame || !isKeyFrameStart(codecName, payload) {
+		return false
+	}
+	s.keyFrame = true
+	s.keyFramesReceived++
+	if !s.lastKeyFrame.IsZero() {
+		s.keyFrameInterval = now.Sub(s.lastKeyFrame)
+	}
+	s.lastKeyFrame = now
+	return true
+}
+
+// addInterval records the time between two frames, counting it as a freeze if it
+// is longer than Max(3 * average, average + 150ms) as described by
+// https://w3c.github.io/webrtc-stats/#dom-rtcinboundrtpstreamstats-freezecount
+func (s *videoFrameStats) addInterval(interval time.Duration) {
+	if len(s.intervals) != 0 {
+		var sum time.Duration
+		for _, i := range s.intervals {
+			sum += i
+		}
+		average := sum / time.Duration(len(s.intervals))
+
+		threshold := 3 * average
+		if minThreshold := average + videoFreezeMinExtraDuration; minThreshold > threshold {
+			threshold = minThreshold
+		}
+		if interval > threshold {
+			s.freezeCount++
+			s.totalFreezesDuration += interval
+		}
+	}
+
+	s.intervals = append(s.intervals, interval)
+	if len(s.intervals) > videoFrameStatsWindow {
+		s.intervals = s.intervals[1:]
+	}
+}
+
+// resume forgets the last frame, so the interval until the next one isn't
+// counted as a freeze
+func (s *videoFrameStats) resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastFrame = time.Time{}
+}
+
+func (s *videoFrameStats) pruneRecentFrames(now time.Time) {
+	i := 0
+	for ; i < len(s.recentFrames); i++ {
+		if now.Sub(s.recentFrames[i]) < time.Second {
+			break
+		}
+	}
+	s.recentFrames = s.recentFrames[i:]
+}
+
+// fill copies the current metrics into stats.
+func (s *videoFrameStats) fill(stats *VideoReceiverStats, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneRecentFrames(now)
+	stats.FramesPerSecond = float64(len(s.recentFrames))
+	stats.FramesReceived = s.framesReceived
+	stats.KeyFramesReceived = s.keyFramesReceived
+	stats.KeyFrameInterval = s.keyFrameInterval.Seconds()
+	stats.FreezeCount = s.freezeCount
+	stats.TotalFreezesDuration = s.totalFreezesDuration.Seconds()
+}
+
+// isKeyFrameStart reports whether payload is the first packet of a key frame.
+// Only VP8 and H264 are inspected, other codecs always return false.
+func isKeyFrameStart(codecName string, payload []byte) bool {
+	switch codecName {
+	case VP8:
+		return isVP8KeyFrameStart(payload)
+	case H264:
+		return isH264KeyFrameStart(payload)
+	default:
+		return false
+	}
+}
+
+// https://tools.ietf.org/html/rfc7741#section-4.2
+func isVP8KeyFrameStart(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+
+	// Only the start of the first partition carries the VP8 payload header
+	start := payload[0]&0x10 != 0
+	partitionID := payload[0] & 0x07
+	if !start || partitionID != 0 {
+		return false
+	}
+
+	offset := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 2 {
+			return false
+		}
+		ext := payload[1]
+		offset++
+
+		if ext&0x80 != 0 { // I: PictureID present
+			if len(payload) <= offset {
+				return false
+			}
+			if payload[offset]&0x80 != 0 { // M: 15 bit PictureID
+				offset++
+			}
+			offset++
+		}
+		if ext&0x40 != 0 { // L: TL0PICIDX present
+			offset++
+		}
+		if ext&0x30 != 0 { // T or K: TID/KEYIDX present
+			offset++
+		}
+	}
+
+	if len(payload) <= offset {
+		return false
+	}
+
+	// https://tools.ietf.org/html/rfc7741#section-4.3 P bit is 0 for key frames
+	return payload[offset]&0x01 == 0
+}
+
+// https://tools.ietf.org/html/rfc6184#section-5.2
+func isH264KeyFrameStart(payload []byte) bool {
+	const (
+		naluTypeIDR  = 5
+		naluTypeSPS  = 7
+		naluTypeSTAP = 24
+		naluTypeFU   = 28
+	)
+
+	if len(payload) < 1 {
+		return false
+	}
+
+	switch naluType := payload[0] & 0x1F; naluType {
+	case naluTypeIDR, naluTypeSPS:
+		return true
+	case naluTypeSTAP:
+		for offset := 1; offset+2 < len(payload); {
+			size := int(payload[offset])<<8 | int(payload[offset+1])
+			offset += 2
+			if t := payload[offset] & 0x1F; t == naluTypeIDR || t == naluTypeSPS {
+				return true
+			}
+			offset += size
+		}
+	case naluTypeFU:
+		if len(payload) < 2 {
+			return false
+		}
+		start := payload[1]&0x80 != 0
+		return start && payload[1]&0x1F == naluTypeIDR
+	}
+	return false
+}
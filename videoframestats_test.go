@@ -0,0 +1,107 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsKeyFrameStart(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Codec    string
+		Payload  []byte
+		KeyFrame bool
+	}{
+		{"VP8 key frame", VP8, []byte{0x10, 0x00}, true},
+		{"VP8 delta frame", VP8, []byte{0x10, 0x01}, false},
+		{"VP8 continuation", VP8, []byte{0x00, 0x00}, false},
+		{"VP8 key frame with PictureID", VP8, []byte{0x90, 0x80, 0x81, 0x23, 0x00}, true},
+		{"VP8 truncated", VP8, []byte{0x90, 0x80}, false},
+		{"H264 IDR", H264, []byte{0x65, 0x00}, true},
+		{"H264 non-IDR", H264, []byte{0x41, 0x00}, false},
+		{"H264 STAP-A with SPS", H264, []byte{0x78, 0x00, 0x01, 0x67, 0x00, 0x01, 0x68}, true},
+		{"H264 FU-A IDR start", H264, []byte{0x7c, 0x85}, true},
+		{"H264 FU-A IDR middle", H264, []byte{0x7c, 0x05}, false},
+		{"Unknown codec", Opus, []byte{0x10, 0x00}, false},
+		{"Empty", VP8, []byte{}, false},
+	} {
+		assert.Equal(t, test.KeyFrame, isKeyFrameStart(test.Codec, test.Payload), test.Name)
+	}
+}
+
+func TestVideoFrameStats(t *testing.T) {
+	s := videoFrameStats{}
+	now := time.Now()
+
+	var timestamp uint32
+	addFrame := func(interval time.Duration, payload []byte) {
+		now = now.Add(interval)
+		timestamp += 3000
+		// Two packets per frame, only the timestamp change starts a new frame
+		s.update(&rtp.Header{Timestamp: timestamp}, payload, VP8, now)
+		s.update(&rtp.Header{Timestamp: timestamp}, []byte{0x00, 0x00}, VP8, now)
+	}
+
+	addFrame(0, []byte{0x10, 0x00})
+	for i := 0; i < 29; i++ {
+		addFrame(time.Second/30, []byte{0x10, 0x01})
+	}
+
+	stats := VideoReceiverStats{}
+	s.fill(&stats, now)
+	assert.Equal(t, uint32(30), stats.FramesReceived)
+	assert.Equal(t, uint32(1), stats.KeyFramesReceived)
+	assert.Equal(t, uint32(0), stats.FreezeCount)
+	assert.InDelta(t, 30, stats.FramesPerSecond, 1)
+
+	// A 500ms gap is a freeze, the key frame after it sets the interval
+	addFrame(500*time.Millisecond, []byte{0x10, 0x00})
+
+	s.fill(&stats, now)
+	assert.Equal(t, uint32(31), stats.FramesReceived)
+	assert.Equal(t, uint32(2), stats.KeyFramesReceived)
+	assert.Equal(t, uint32(1), stats.FreezeCount)
+	assert.InDelta(t, 0.5, stats.TotalFreezesDuration, 0.001)
+	assert.InDelta(t, 29.0/30+0.5, stats.KeyFrameInterval, 0.001)
+
+	// No frames for a second means no frame rate
+	s.fill(&stats, now.Add(time.Second))
+	assert.Equal(t, float64(0), stats.FramesPerSecond)
+}
+
+func TestVideoFrameStats_H264KeyFrame(t *testing.T) {
+	s := videoFrameStats{}
+	now := time.Now()
+
+	// Browsers send SPS and PPS in a STAP-A, then the IDR slices in FU-As,
+	// all with the timestamp of the frame
+	addKeyFrame := func(timestamp uint32) {
+		for i, payload := range [][]byte{
+			{0x78, 0x00, 0x01, 0x67, 0x00, 0x01, 0x68},
+			{0x7c, 0x85},
+			{0x7c, 0x45},
+			{0x7c, 0x85},
+			{0x7c, 0x45},
+		} {
+			keyFrame := s.update(&rtp.Header{Timestamp: timestamp}, payload, H264, now)
+			assert.Equal(t, i == 0, keyFrame)
+		}
+	}
+
+	addKeyFrame(3000)
+	now = now.Add(time.Second / 30)
+	s.update(&rtp.Header{Timestamp: 6000}, []byte{0x41, 0x00}, H264, now)
+	now = now.Add(time.Second)
+	addKeyFrame(96000)
+
+	stats := VideoReceiverStats{}
+	s.fill(&stats, now)
+	assert.Equal(t, uint32(3), stats.FramesReceived)
+	assert.Equal(t, uint32(2), stats.KeyFramesReceived)
+	assert.InDelta(t, 1+1.0/30, stats.KeyFrameInterval, 0.001)
+}